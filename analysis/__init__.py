@@ -1,5 +1,9 @@
 from .recommender import OptionsRecommender
 from .visualizer import Visualizer
 from .backtester import StrategyBacktester
+from .options_backtester import OptionsBacktester
+from .costs import CostModel
+from .portfolio import PortfolioBacktester
+from .downsample import lttb
 
-__all__ = ['OptionsRecommender', 'Visualizer', 'StrategyBacktester']
\ No newline at end of file
+__all__ = ['OptionsRecommender', 'Visualizer', 'StrategyBacktester', 'OptionsBacktester', 'CostModel', 'PortfolioBacktester', 'lttb']
\ No newline at end of file
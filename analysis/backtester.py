@@ -4,6 +4,8 @@ from datetime import datetime
 import matplotlib.pyplot as plt
 from collections import defaultdict
 
+from .costs import CostModel
+
 
 class StrategyBacktester:
     """
@@ -21,10 +23,13 @@ class StrategyBacktester:
         self.results = {}
         self.trades = []
         self.summary_stats = {}
+        self.costs = CostModel()
+        self._progress = None
 
     def backtest(self, df, profit_targets=None, risk_reward_ratios=None,
                  profit_targets_dollar=None, commission=0.0, slippage=0.0,
-                 position_size=1.0, contracts=1, contract_value=100):
+                 position_size=1.0, contracts=1, contract_value=100, costs=None,
+                 session_windows=None, progress=None):
         """
         Run a backtest on a DataFrame with entry signals
 
@@ -38,12 +43,20 @@ class StrategyBacktester:
         position_size (float): Position size as a percentage of account
         contracts (int): Number of contracts per trade
         contract_value (float): Dollar value per point/tick movement per contract
+        costs (CostModel): Execution friction applied to each fill; ideal fills when omitted
+        session_windows (dict): Entry window per session date ('YYYY-MM-DD' to a
+            (start, end) pair of minutes after midnight, exchange time); entries on
+            other dates or outside their window are skipped. All bars may enter when omitted
+        progress (callable): Called as trades are simulated with a dict of bars_processed,
+            total_bars, test, trades, win_rate and pnl so far; bars are counted across every
+            test. An exception it raises stops the backtest
 
         Returns:
         dict: Dictionary with backtest results
         """
         # Make a copy to avoid modifying the original DataFrame
         df = df.copy()
+        self.costs = costs or CostModel()
 
         # Ensure required columns exist
         required_columns = ['entry_signal', 'signal_type', 'stoploss']
@@ -51,30 +64,43 @@ class StrategyBacktester:
         if missing_columns:
             raise ValueError(f"Missing required columns: {missing_columns}")
 
+        # Only enter during the strategy's trading hours
+        if session_windows is not None:
+            df['entry_signal'] = df['entry_signal'] & self._in_session(df.index, session_windows)
+
         # Default profit targets if nothing is provided
         if profit_targets is None and risk_reward_ratios is None and profit_targets_dollar is None:
             profit_targets = [5, 10, 15]  # Default profit targets in percentage
 
         # Initialize results for each profit target or risk-reward ratio
         test_results = {}
+        total_tests = len(profit_targets or []) + len(risk_reward_ratios or []) + len(profit_targets_dollar or [])
+        self._progress = progress and {
+            'callback': progress, 'df': df, 'tests_done': 0, 'total_tests': total_tests, 'test': None
+        }
 
         # Process based on profit targets (percentage)
         if profit_targets is not None:
             for target in profit_targets:
+                self._start_test(f"Target_{target}%")
                 results = self._process_profit_target(df, target, commission, slippage, position_size, contracts, contract_value)
                 test_results[f"Target_{target}%"] = results
 
         # Process based on risk-reward ratios
         if risk_reward_ratios is not None:
             for rr_ratio in risk_reward_ratios:
+                self._start_test(f"RR_1:{rr_ratio}")
                 results = self._process_risk_reward(df, rr_ratio, commission, slippage, position_size, contracts, contract_value)
                 test_results[f"RR_1:{rr_ratio}"] = results
 
         # Process based on dollar profit targets
         if profit_targets_dollar is not None:
             for dollar_target in profit_targets_dollar:
+                self._start_test(f"${dollar_target}")
                 results = self._process_dollar_target(df, dollar_target, commission, slippage, position_size, contracts, contract_value)
                 test_results[f"${dollar_target}"] = results
+        self._start_test(None)
+        self._progress = None
 
         # Store and return results
         self.results = test_results
@@ -228,23 +254,21 @@ class StrategyBacktester:
                 exit_price = df.iloc[-1]['close']
                 exit_type = 'OPEN'
 
+            # Fill at the modeled prices and size rather than ideal ones
+            fill = self._fill(signal_type, entry_price, exit_price, entry_row, contracts, contract_value)
+            if fill['contracts'] == 0:
+                results['total_trades'] -= 1
+                continue
+
             # Calculate profit/loss
             if signal_type == 'LONG':
-                trade_pl_pct = ((exit_price - entry_price) / entry_price * 100) - commission - slippage
+                trade_pl_pct = ((fill['exit_price'] - fill['entry_price']) / entry_price * 100) - fill['commission_pct'] - commission - slippage
             else:  # SHORT
-                trade_pl_pct = ((entry_price - exit_price) / entry_price * 100) - commission - slippage
+                trade_pl_pct = ((fill['entry_price'] - fill['exit_price']) / entry_price * 100) - fill['commission_pct'] - commission - slippage
 
-            # Calculate dollar value of profit/loss
-            price_change = abs(exit_price - entry_price)
-            dollar_value_per_point = contract_value
-            dollar_value_per_contract = price_change * dollar_value_per_point
-            total_dollar_value = dollar_value_per_contract * contracts
-
-            # Determine profit/loss direction
-            if (signal_type == 'LONG' and exit_price > entry_price) or (signal_type == 'SHORT' and exit_price < entry_price):
-                dollar_pl = total_dollar_value
-            else:
-                dollar_pl = -total_dollar_value
+            # Calculate dollar value of profit/loss, net of commissions
+            direction = 1 if signal_type == 'LONG' else -1
+            dollar_pl = direction * (fill['exit_price'] - fill['entry_price']) * contract_value * fill['contracts'] - fill['commission']
 
             # Record trade details
             trade = {
@@ -261,12 +285,16 @@ class StrategyBacktester:
                 'profit_loss_dollar': dollar_pl,
                 'contracts': contracts,
                 'contract_value': contract_value,
+                'filled_contracts': fill['contracts'],
+                'commission_dollar': fill['commission'],
+                'slippage_dollar': fill['slippage'],
                 'max_adverse_excursion': max_excursion,
                 'max_favorable_excursion': max_favorable,
                 'hold_time': (exit_date - entry_date).total_seconds() / 86400  # Days
             }
 
             trades.append(trade)
+            self._report_progress(entry_date, trades)
 
             # Update statistics
             total_profit += trade['profit_loss_amount']
@@ -499,22 +527,21 @@ class StrategyBacktester:
                 exit_price = df.iloc[-1]['close']
                 exit_type = 'OPEN'
 
+            # Fill at the modeled prices and size rather than ideal ones
+            fill = self._fill(signal_type, entry_price, exit_price, entry_row, contracts, contract_value)
+            if fill['contracts'] == 0:
+                results['total_trades'] -= 1
+                continue
+
             # Calculate profit/loss
             if signal_type == 'LONG':
-                trade_pl_pct = ((exit_price - entry_price) / entry_price * 100) - commission - slippage
+                trade_pl_pct = ((fill['exit_price'] - fill['entry_price']) / entry_price * 100) - fill['commission_pct'] - commission - slippage
             else:  # SHORT
-                trade_pl_pct = ((entry_price - exit_price) / entry_price * 100) - commission - slippage
+                trade_pl_pct = ((fill['entry_price'] - fill['exit_price']) / entry_price * 100) - fill['commission_pct'] - commission - slippage
 
-            # Calculate dollar value of profit/loss
-            price_change = abs(exit_price - entry_price)
-            dollar_value_per_contract = price_change * contract_value
-            total_dollar_value = dollar_value_per_contract * contracts
-
-            # Determine profit/loss direction
-            if (signal_type == 'LONG' and exit_price > entry_price) or (signal_type == 'SHORT' and exit_price < entry_price):
-                dollar_pl = total_dollar_value
-            else:
-                dollar_pl = -total_dollar_value
+            # Calculate dollar value of profit/loss, net of commissions
+            direction = 1 if signal_type == 'LONG' else -1
+            dollar_pl = direction * (fill['exit_price'] - fill['entry_price']) * contract_value * fill['contracts'] - fill['commission']
 
             # Record trade details
             trade = {
@@ -532,12 +559,16 @@ class StrategyBacktester:
                 'profit_loss_dollar': dollar_pl,
                 'contracts': contracts,
                 'contract_value': contract_value,
+                'filled_contracts': fill['contracts'],
+                'commission_dollar': fill['commission'],
+                'slippage_dollar': fill['slippage'],
                 'max_adverse_excursion': max_excursion,
                 'max_favorable_excursion': max_favorable,
                 'hold_time': (exit_date - entry_date).total_seconds() / 86400  # Days
             }
 
             trades.append(trade)
+            self._report_progress(entry_date, trades)
 
             # Update statistics
             total_profit += trade['profit_loss_amount']
@@ -704,23 +735,21 @@ class StrategyBacktester:
                 exit_price = df.iloc[-1]['close']
                 exit_type = 'OPEN'
 
-            # Calculate profit/loss percentage
+            # Fill at the modeled prices and size rather than ideal ones
+            fill = self._fill(signal_type, entry_price, exit_price, entry_row, contracts, contract_value)
+            if fill['contracts'] == 0:
+                results['total_trades'] -= 1
+                continue
+
+            # Calculate profit/loss
             if signal_type == 'LONG':
-                trade_pl_pct = ((exit_price - entry_price) / entry_price * 100) - commission - slippage
+                trade_pl_pct = ((fill['exit_price'] - fill['entry_price']) / entry_price * 100) - fill['commission_pct'] - commission - slippage
             else:  # SHORT
-                trade_pl_pct = ((entry_price - exit_price) / entry_price * 100) - commission - slippage
-
-            # Calculate dollar value of profit/loss
-            price_change = abs(exit_price - entry_price)
-            dollar_value_per_point = contract_value
-            dollar_value_per_contract = price_change * dollar_value_per_point
-            total_dollar_value = dollar_value_per_contract * contracts
+                trade_pl_pct = ((fill['entry_price'] - fill['exit_price']) / entry_price * 100) - fill['commission_pct'] - commission - slippage
 
-            # Determine profit/loss direction
-            if (signal_type == 'LONG' and exit_price > entry_price) or (signal_type == 'SHORT' and exit_price < entry_price):
-                dollar_pl = total_dollar_value
-            else:
-                dollar_pl = -total_dollar_value
+            # Calculate dollar value of profit/loss, net of commissions
+            direction = 1 if signal_type == 'LONG' else -1
+            dollar_pl = direction * (fill['exit_price'] - fill['entry_price']) * contract_value * fill['contracts'] - fill['commission']
 
             # Record trade details
             trade = {
@@ -738,12 +767,16 @@ class StrategyBacktester:
                 'profit_loss_dollar': dollar_pl,
                 'contracts': contracts,
                 'contract_value': contract_value,
+                'filled_contracts': fill['contracts'],
+                'commission_dollar': fill['commission'],
+                'slippage_dollar': fill['slippage'],
                 'max_adverse_excursion': max_excursion,
                 'max_favorable_excursion': max_favorable,
                 'hold_time': (exit_date - entry_date).total_seconds() / 86400  # Days
             }
 
             trades.append(trade)
+            self._report_progress(entry_date, trades)
 
             # Update statistics
             total_profit += trade['profit_loss_amount']
@@ -807,6 +840,96 @@ class StrategyBacktester:
 
         return results
 
+    def _start_test(self, test_name):
+        """Move progress reporting on to the next test, or past the last if test_name is None."""
+        if not self._progress:
+            return
+        if self._progress['test'] is not None:
+            self._progress['tests_done'] += 1
+        self._progress['test'] = test_name
+        if test_name is not None:
+            self._report_progress(None, [])
+
+    def _report_progress(self, entry_date, trades):
+        """Report the current test's trades so far, through entry_date or from its start if None."""
+        if not self._progress:
+            return
+        df = self._progress['df']
+        bars = 0 if entry_date is None else int(df.index.searchsorted(entry_date)) + 1
+        pnl = sum(trade['profit_loss_dollar'] for trade in trades)
+        wins = sum(1 for trade in trades if trade['profit_loss_pct'] > 0)
+        self._progress['callback']({
+            'test': self._progress['test'],
+            'bars_processed': self._progress['tests_done'] * len(df) + bars,
+            'total_bars': self._progress['total_tests'] * len(df),
+            'trades': len(trades),
+            'win_rate': wins / len(trades) if trades else 0.0,
+            'pnl': float(pnl),
+        })
+
+    @staticmethod
+    def _in_session(index, session_windows):
+        """
+        Whether each bar time falls in its date's session window
+
+        Parameters:
+        index (pandas.DatetimeIndex): Bar times; naive times are exchange time
+        session_windows (dict): Entry window per session date
+
+        Returns:
+        numpy.ndarray: Boolean mask over the index
+        """
+        times = pd.DatetimeIndex(index)
+        if times.tz is not None:
+            times = times.tz_convert('America/New_York')
+
+        mask = np.zeros(len(times), dtype=bool)
+        for i, t in enumerate(times):
+            window = session_windows.get(t.strftime('%Y-%m-%d'))
+            if window:
+                minute = t.hour * 60 + t.minute
+                mask[i] = window[0] <= minute < window[1]
+        return mask
+
+    def _fill(self, signal_type, entry_price, exit_price, entry_row, contracts, contract_value):
+        """
+        Apply the cost model to a trade's entry and exit
+
+        The entry bar's volume caps the filled contracts; the exit fills
+        whatever was entered.
+
+        Parameters:
+        signal_type (str): LONG or SHORT
+        entry_price (float): Quoted entry price
+        exit_price (float): Quoted exit price
+        entry_row (pandas.Series): Bar the trade entered on
+        contracts (int): Requested number of contracts
+        contract_value (float): Dollar value per point/tick movement per contract
+
+        Returns:
+        dict: Fill prices, filled contracts, commission and slippage in dollars,
+        and commission as a percentage of the entry's notional
+        """
+        buying = signal_type == 'LONG'
+        filled = self.costs.fill_quantity(contracts, entry_row.get('volume', 0))
+        entry_fill = self.costs.fill_price(entry_price, buying)
+        exit_fill = self.costs.fill_price(exit_price, not buying)
+
+        units = contract_value * filled
+        commission = 0.0
+        if filled > 0:
+            commission = self.costs.commission(entry_fill * units) + self.costs.commission(exit_fill * units)
+        notional = entry_price * units
+
+        return {
+            'entry_price': entry_fill,
+            'exit_price': exit_fill,
+            'contracts': filled,
+            'commission': commission,
+            'commission_pct': commission / notional * 100 if notional > 0 else 0.0,
+            'slippage': (abs(entry_fill - entry_price) + abs(exit_fill - exit_price)) * units
+        }
+
     def get_summary_stats(self):
         """
         Calculate and return summary statistics across all tests
@@ -833,13 +956,140 @@ class StrategyBacktester:
                 'avg_profit': test_results['avg_profit'],
                 'avg_loss': test_results['avg_loss'],
                 'max_drawdown': test_results.get('max_drawdown', 0),
-                'max_drawdown_pct': test_results.get('max_drawdown_pct', 0)
+                'max_drawdown_pct': test_results.get('max_drawdown_pct', 0),
+                'commission': sum(trade.get('commission_dollar', 0) for trade in test_results['trades']),
+                'slippage': sum(trade.get('slippage_dollar', 0) for trade in test_results['trades']),
+                'partial_fills': sum(1 for trade in test_results['trades'] if trade.get('filled_contracts', trade['contracts']) < trade['contracts'])
             }
 
         # Store summary stats
         self.summary_stats = summary
         return summary
 
+    def equity_curve(self, df, test_name):
+        """
+        Mark-to-market equity of a test at every bar: the dollar profit of
+        trades closed by the bar plus that of trades still open at its close
+
+        Open trades are valued from their ideal entry price; closed trades
+        count their profit net of costs.
+
+        Parameters:
+        df (pandas.DataFrame): Price data the backtest ran on, in time order
+        test_name (str): Name of the test, e.g. Target_5%
+
+        Returns:
+        pandas.Series: Dollar equity from zero, indexed like df
+        """
+        n = len(df)
+        realized = np.zeros(n + 1)
+        exposure = np.zeros(n + 1)  # Dollars per point of the trades open
+        basis = np.zeros(n + 1)     # Exposure times entry price
+
+        # Each trade adds to the bars from its entry until its exit
+        for trade in self.results.get(test_name, {'trades': []})['trades']:
+            entry = df.index.searchsorted(trade['entry_date'])
+            exit_ = df.index.searchsorted(trade['exit_date'])
+            direction = 1 if trade['signal_type'] == 'LONG' else -1
+            size = direction * trade['contract_value'] * trade.get('filled_contracts', trade['contracts'])
+            exposure[entry] += size
+            exposure[exit_] -= size
+            basis[entry] += size * trade['entry_price']
+            basis[exit_] -= size * trade['entry_price']
+            realized[exit_] += trade['profit_loss_dollar']
+
+        open_pnl = np.cumsum(exposure[:n]) * df['close'].to_numpy(dtype=float) - np.cumsum(basis[:n])
+        return pd.Series(np.cumsum(realized[:n]) + open_pnl, index=df.index)
+
+    @staticmethod
+    def sharpe_ratio(equity, periods_per_year=252):
+        """
+        Annualized Sharpe ratio of an equity curve's daily changes
+
+        Changes are in dollars rather than returns, as single-ticker
+        backtests have no capital base; the risk-free rate is taken as zero.
+
+        Parameters:
+        equity (pandas.Series): Dollar equity indexed by time
+        periods_per_year (int): Trading days per year
+
+        Returns:
+        float: Sharpe ratio, 0 with fewer than two days of changes
+        """
+        if equity.empty:
+            return 0.0
+        daily = equity.groupby(pd.DatetimeIndex(equity.index).normalize()).last()
+        changes = daily.diff().dropna()
+        if len(changes) < 2 or changes.std() == 0:
+            return 0.0
+        return float(changes.mean() / changes.std() * np.sqrt(periods_per_year))
+
+    def benchmark_metrics(self, df, benchmark_df, benchmark='SPY', periods_per_year=252):
+        """
+        Measure each test against a benchmark held over the same period
+
+        Strategy returns are booked on the day each trade exits and compared
+        with the benchmark's daily close-to-close returns.
+
+        Parameters:
+        df (pandas.DataFrame): Price data the backtest ran on
+        benchmark_df (pandas.DataFrame): Price data of the benchmark
+        benchmark (str): Benchmark ticker
+        periods_per_year (int): Trading days per year, used to annualize alpha
+
+        Returns:
+        dict: Benchmark metrics by test name, empty when the benchmark does
+        not cover the backtest period
+        """
+        if not self.results or df.empty or benchmark_df.empty:
+            return {}
+
+        days = self._daily_closes(df).index
+        closes = self._daily_closes(benchmark_df)
+        closes = closes[(closes.index >= days[0]) & (closes.index <= days[-1])]
+        benchmark_returns = closes.pct_change().dropna()
+        if len(benchmark_returns) < 2:
+            return {}
+
+        benchmark_return_pct = (closes.iloc[-1] / closes.iloc[0] - 1) * 100
+        benchmark_variance = benchmark_returns.var()
+        benchmark_equity = (1 + benchmark_returns).cumprod()
+
+        metrics = {}
+        for test_name, test_results in self.results.items():
+            realized = defaultdict(float)
+            for trade in test_results['trades']:
+                realized[pd.Timestamp(trade['exit_date']).normalize()] += trade['profit_loss_amount']
+            strategy_returns = pd.Series(realized, dtype=float).reindex(benchmark_returns.index, fill_value=0.0)
+
+            beta = strategy_returns.cov(benchmark_returns) / benchmark_variance if benchmark_variance > 0 else 0.0
+            alpha = (strategy_returns.mean() - beta * benchmark_returns.mean()) * periods_per_year
+
+            # Drawdown of the strategy's equity relative to the benchmark's
+            relative = (1 + strategy_returns).cumprod() / benchmark_equity
+            relative_drawdown = (1 - relative / relative.cummax()).max()
+
+            metrics[test_name] = {
+                'ticker': benchmark,
+                'alpha': float(alpha * 100),  # Convert to percentage
+                'beta': float(beta),
+                'benchmark_return_pct': float(benchmark_return_pct),
+                'excess_return_pct': float(test_results['total_return_pct'] - benchmark_return_pct),
+                'relative_max_drawdown_pct': float(relative_drawdown * 100)
+            }
+
+        return metrics
+
+    @staticmethod
+    def _daily_closes(df):
+        """
+        Last close of each day in a price DataFrame indexed, or with a
+        'date' column, by timestamp
+        """
+        if not isinstance(df.index, pd.DatetimeIndex):
+            df = df.set_index(pd.to_datetime(df['date']))
+        return df['close'].groupby(df.index.normalize()).last()
+
     def plot_results(self, figsize=(15, 10)):
         """
         Plot backtest results
@@ -0,0 +1,65 @@
+import math
+
+
+class CostModel:
+    """
+    Execution friction applied to simulated fills. The defaults fill every
+    order in full at the quoted price without commission.
+    """
+
+    def __init__(self, commission_fixed=0.0, commission_percent=0.0,
+                 spread_percent=0.0, max_volume_percent=0.0):
+        """
+        Initialize the cost model
+
+        Parameters:
+        commission_fixed (float): Commission in dollars per order
+        commission_percent (float): Commission as a percentage of the order's notional
+        spread_percent (float): Bid-ask spread as a percentage of price; orders cross half of it
+        max_volume_percent (float): Largest fill as a percentage of the bar's volume, 0 for no cap
+        """
+        self.commission_fixed = commission_fixed
+        self.commission_percent = commission_percent
+        self.spread_percent = spread_percent
+        self.max_volume_percent = max_volume_percent
+
+    def fill_price(self, price, buying):
+        """
+        Price an order fills at after crossing half the spread
+
+        Parameters:
+        price (float): Quoted price
+        buying (bool): True for orders that buy, opening a long or covering a short
+
+        Returns:
+        float: Fill price
+        """
+        half = price * self.spread_percent / 200
+        return price + half if buying else price - half
+
+    def fill_quantity(self, quantity, volume):
+        """
+        Quantity of an order that fills within the bar's volume
+
+        Parameters:
+        quantity (int): Requested quantity
+        volume (float): Volume of the bar the order fills in, 0 or NaN when unknown
+
+        Returns:
+        int: Filled quantity
+        """
+        if self.max_volume_percent <= 0 or not volume or math.isnan(volume) or volume <= 0:
+            return quantity
+        return min(quantity, int(math.floor(volume * self.max_volume_percent / 100)))
+
+    def commission(self, notional):
+        """
+        Commission on an order
+
+        Parameters:
+        notional (float): Dollar value of the filled order
+
+        Returns:
+        float: Commission in dollars
+        """
+        return self.commission_fixed + notional * self.commission_percent / 100
@@ -0,0 +1,48 @@
+import numpy as np
+
+
+def lttb(values, threshold):
+    """
+    Downsample a series with Largest-Triangle-Three-Buckets, keeping the
+    points that best preserve its shape when charted
+
+    Points are treated as evenly spaced, as bars are. The first and last
+    points are always kept.
+
+    Parameters:
+    values (array-like): Series values in order
+    threshold (int): Most points to keep; 0 or fewer keeps every point
+
+    Returns:
+    list: Indices of the points kept, in order
+    """
+    y = np.asarray(values, dtype=float)
+    n = len(y)
+    if threshold <= 0 or n <= threshold or n <= 2:
+        return list(range(n))
+    if threshold < 3:
+        return [0, n - 1]
+
+    kept = [0]
+    bucket_size = (n - 2) / (threshold - 2)
+    a = 0
+    for i in range(threshold - 2):
+        start = int(i * bucket_size) + 1
+        end = int((i + 1) * bucket_size) + 1
+
+        # Average of the next bucket, or the last point for the final bucket
+        next_start, next_end = end, min(int((i + 2) * bucket_size) + 1, n)
+        if next_start >= n - 1:
+            next_start, next_end = n - 1, n
+        avg_x = (next_start + next_end - 1) / 2
+        avg_y = y[next_start:next_end].mean()
+
+        # Keep the point forming the largest triangle with the last kept
+        # point and the next bucket's average
+        xs = np.arange(start, end)
+        areas = np.abs((a - avg_x) * (y[start:end] - y[a]) - (a - xs) * (avg_y - y[a]))
+        a = start + int(np.argmax(areas))
+        kept.append(a)
+
+    kept.append(n - 1)
+    return kept
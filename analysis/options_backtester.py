@@ -0,0 +1,380 @@
+import math
+from datetime import timedelta
+from statistics import NormalDist
+
+import numpy as np
+import pandas as pd
+
+from .recommender import OptionsRecommender
+
+_NORMAL = NormalDist()
+
+# Calendar days per year used for time to expiration
+DAYS_PER_YEAR = 365.0
+
+# Shares per equity option contract
+CONTRACT_MULTIPLIER = 100
+
+# Volatility assumed when there is not enough history to estimate it
+DEFAULT_IV = 0.25
+
+
+def black_scholes_price(option_type, spot, strike, years, iv, rate=0.0):
+    """
+    Price a European option with Black-Scholes
+
+    Parameters:
+    option_type (str): CALL or PUT
+    spot (float): Underlying price
+    strike (float): Strike price
+    years (float): Time to expiration in years
+    iv (float): Annualized implied volatility
+    rate (float): Annualized risk-free rate
+
+    Returns:
+    float: Option premium per share
+    """
+    if years <= 0 or iv <= 0:
+        if option_type == 'CALL':
+            return max(spot - strike, 0.0)
+        return max(strike - spot, 0.0)
+
+    sqrt_t = math.sqrt(years)
+    d1 = (math.log(spot / strike) + (rate + iv * iv / 2) * years) / (iv * sqrt_t)
+    d2 = d1 - iv * sqrt_t
+    discount = math.exp(-rate * years)
+
+    if option_type == 'CALL':
+        return spot * _NORMAL.cdf(d1) - strike * discount * _NORMAL.cdf(d2)
+    return strike * discount * _NORMAL.cdf(-d2) - spot * _NORMAL.cdf(-d1)
+
+
+def black_scholes_delta(option_type, spot, strike, years, iv, rate=0.0):
+    """Return the Black-Scholes delta of an option (negative for puts)"""
+    if years <= 0 or iv <= 0:
+        if option_type == 'CALL':
+            return 1.0 if spot > strike else 0.0
+        return -1.0 if spot < strike else 0.0
+
+    d1 = (math.log(spot / strike) + (rate + iv * iv / 2) * years) / (iv * math.sqrt(years))
+    if option_type == 'CALL':
+        return _NORMAL.cdf(d1)
+    return _NORMAL.cdf(d1) - 1
+
+
+def strike_for_delta(option_type, spot, years, iv, target_delta, rate=0.0):
+    """
+    Find the strike whose delta is closest to a target, rounded to a listed increment
+
+    Parameters:
+    option_type (str): CALL or PUT
+    spot (float): Underlying price
+    years (float): Time to expiration in years
+    iv (float): Annualized implied volatility
+    target_delta (float): Absolute target delta, e.g. 0.45
+
+    Returns:
+    float: Strike price
+    """
+    call_delta = target_delta if option_type == 'CALL' else 1 - target_delta
+    d1 = _NORMAL.inv_cdf(call_delta)
+    strike = spot * math.exp(-(d1 * iv * math.sqrt(years)) + (rate + iv * iv / 2) * years)
+
+    increment = _strike_increment(spot)
+    return round(strike / increment) * increment
+
+
+def _strike_increment(spot):
+    """Return the usual strike spacing for an underlying price"""
+    if spot < 25:
+        return 0.5
+    if spot < 200:
+        return 1.0
+    return 5.0
+
+
+def historical_volatility(df, window=20):
+    """
+    Estimate annualized volatility from daily closes
+
+    Parameters:
+    df (pandas.DataFrame): Price data with a datetime index and close column
+    window (int): Number of most recent daily returns to use
+
+    Returns:
+    float: Annualized volatility, or DEFAULT_IV when there is too little history
+    """
+    daily = df['close'].resample('1D').last().dropna()
+    returns = np.log(daily / daily.shift(1)).dropna().tail(window)
+    if len(returns) < 5:
+        return DEFAULT_IV
+
+    vol = float(returns.std() * math.sqrt(252))
+    return vol if vol > 0 else DEFAULT_IV
+
+
+class OptionsBacktester:
+    """
+    Class for backtesting options trades opened on strategy signals
+
+    Contracts are chosen like the options recommender does. Premiums come from
+    historical chain snapshots when provided, otherwise from a Black-Scholes
+    model at a constant implied volatility so that theta decay is captured.
+    """
+
+    def __init__(self, days_to_expiration=30, target_delta=0.45, risk_reward_ratio=2.0,
+                 premium_target_pct=0.0, premium_stop_pct=0.0, contracts=1, commission=0.0):
+        """
+        Initialize the options backtester
+
+        Parameters:
+        days_to_expiration (int): Calendar days to expiration for modeled contracts
+        target_delta (float): Absolute delta to target when choosing strikes
+        risk_reward_ratio (float): Underlying target as a multiple of the distance to stoploss
+        premium_target_pct (float): Exit when the premium gains this percentage (0 disables)
+        premium_stop_pct (float): Exit when the premium loses this percentage (0 disables)
+        contracts (int): Number of contracts per trade
+        commission (float): Commission per contract, per side
+        """
+        self.days_to_expiration = days_to_expiration
+        self.target_delta = target_delta
+        self.risk_reward_ratio = risk_reward_ratio
+        self.premium_target_pct = premium_target_pct
+        self.premium_stop_pct = premium_stop_pct
+        self.contracts = contracts
+        self.commission = commission
+        self.trades = []
+        self.summary = {}
+
+    def backtest(self, df, options_df=None):
+        """
+        Run an options backtest on a DataFrame with entry signals
+
+        Parameters:
+        df (pandas.DataFrame): DataFrame with entry signals and stoploss values
+        options_df (pandas.DataFrame): Historical chain snapshots with tradeDate,
+            expirDate, strike, delta, callValue and putValue columns. When None,
+            premiums are modeled.
+
+        Returns:
+        dict: Summary metrics and the simulated trades
+        """
+        required_columns = ['entry_signal', 'signal_type', 'stoploss', 'close', 'high', 'low']
+        missing_columns = [col for col in required_columns if col not in df.columns]
+        if missing_columns:
+            raise ValueError(f"Missing required columns: {missing_columns}")
+
+        use_chain = options_df is not None and not options_df.empty
+        iv = historical_volatility(df)
+
+        if use_chain:
+            recommender = OptionsRecommender(target_delta=self.target_delta)
+            recommendations = recommender.generate_recommendations(df, options_df)
+        else:
+            recommendations = self._model_recommendations(df, iv)
+
+        trades = []
+        for rec in recommendations:
+            trade = self._simulate(df, rec, options_df if use_chain else None, iv)
+            if trade is not None:
+                trades.append(trade)
+
+        self.trades = trades
+        self.summary = self._summarize(trades)
+        return {
+            'pricing': 'chain' if use_chain else 'model',
+            'summary': self.summary,
+            'trades': trades
+        }
+
+    def _model_recommendations(self, df, iv):
+        """Build recommendations for each entry signal from modeled contracts"""
+        recommendations = []
+        years = self.days_to_expiration / DAYS_PER_YEAR
+
+        for entry_time, row in df[df['entry_signal']].iterrows():
+            if row['signal_type'] not in ('LONG', 'SHORT') or pd.isna(row['stoploss']):
+                continue
+
+            option_type = 'CALL' if row['signal_type'] == 'LONG' else 'PUT'
+            spot = float(row['close'])
+            strike = strike_for_delta(option_type, spot, years, iv, self.target_delta)
+            expiration = (entry_time + timedelta(days=self.days_to_expiration)).normalize() + timedelta(hours=16)
+
+            recommendations.append({
+                'date': entry_time.strftime('%Y-%m-%d'),
+                'entry_time': entry_time,
+                'signal_type': row['signal_type'],
+                'stock_price': spot,
+                'stoploss': float(row['stoploss']),
+                'option_type': option_type,
+                'strike': strike,
+                'expiration': expiration.strftime('%Y-%m-%d'),
+                'delta': black_scholes_delta(option_type, spot, strike, years, iv),
+                'iv': iv,
+                'price': black_scholes_price(option_type, spot, strike, years, iv)
+            })
+
+        return recommendations
+
+    def _simulate(self, df, rec, options_df, iv):
+        """Walk forward from a recommendation's entry until an exit condition is met"""
+        entry_time = rec.get('entry_time', pd.Timestamp(rec['date']))
+        if entry_time not in df.index:
+            return None
+
+        option_type = rec['option_type']
+        strike = float(rec['strike'])
+        expiration = pd.Timestamp(rec['expiration'])
+        if expiration.tzinfo is None and entry_time.tzinfo is not None:
+            expiration = expiration.tz_localize(entry_time.tzinfo)
+        expiration = expiration.normalize() + timedelta(hours=16)
+
+        iv = float(rec['iv']) if rec.get('iv') else iv
+        entry_price = float(rec['stock_price'])
+        stoploss = float(rec['stoploss'])
+        risk = abs(entry_price - stoploss)
+        long_underlying = rec['signal_type'] == 'LONG'
+        target_price = entry_price + risk * self.risk_reward_ratio if long_underlying \
+            else entry_price - risk * self.risk_reward_ratio
+
+        def premium(at, spot):
+            years = max((expiration - at).total_seconds(), 0) / 86400 / DAYS_PER_YEAR
+            if options_df is not None:
+                quoted = self._chain_premium(options_df, at, option_type, strike, rec['expiration'])
+                if quoted is not None:
+                    return quoted
+            return black_scholes_price(option_type, spot, strike, years, iv)
+
+        entry_premium = rec.get('price')
+        if entry_premium is None or pd.isna(entry_premium) or entry_premium <= 0:
+            entry_premium = premium(entry_time, entry_price)
+        if entry_premium <= 0:
+            return None
+
+        exit_time, exit_spot, exit_premium, exit_reason = None, None, None, None
+        entry_idx = df.index.get_loc(entry_time)
+
+        for i in range(entry_idx + 1, len(df)):
+            bar_time = df.index[i]
+            bar = df.iloc[i]
+
+            if bar_time >= expiration:
+                exit_time, exit_spot = expiration, float(df.iloc[i - 1]['close'])
+                exit_premium = black_scholes_price(option_type, exit_spot, strike, 0, iv)
+                exit_reason = 'EXPIRED'
+                break
+
+            # Underlying stop and target are checked the same way as the equity backtester
+            stopped = bar['low'] <= stoploss if long_underlying else bar['high'] >= stoploss
+            targeted = bar['high'] >= target_price if long_underlying else bar['low'] <= target_price
+            if stopped:
+                exit_time, exit_spot, exit_reason = bar_time, stoploss, 'STOP'
+            elif targeted:
+                exit_time, exit_spot, exit_reason = bar_time, target_price, 'TARGET'
+
+            if exit_reason is not None:
+                exit_premium = premium(bar_time, exit_spot)
+                break
+
+            # Premium exits are checked on the bar's close
+            close = float(bar['close'])
+            value = premium(bar_time, close)
+            change_pct = (value - entry_premium) / entry_premium * 100
+            if self.premium_target_pct > 0 and change_pct >= self.premium_target_pct:
+                exit_time, exit_spot, exit_premium, exit_reason = bar_time, close, value, 'PREMIUM_TARGET'
+                break
+            if self.premium_stop_pct > 0 and change_pct <= -self.premium_stop_pct:
+                exit_time, exit_spot, exit_premium, exit_reason = bar_time, close, value, 'PREMIUM_STOP'
+                break
+
+        # Still open at the end of the data: mark to the last close
+        if exit_reason is None:
+            exit_time, exit_spot = df.index[-1], float(df.iloc[-1]['close'])
+            exit_premium = premium(exit_time, exit_spot)
+            exit_reason = 'OPEN'
+
+        # Decay alone: the same contract repriced at the entry underlying price on the exit date
+        years_at_entry = max((expiration - entry_time).total_seconds(), 0) / 86400 / DAYS_PER_YEAR
+        years_at_exit = max((expiration - exit_time).total_seconds(), 0) / 86400 / DAYS_PER_YEAR
+        theta_cost = black_scholes_price(option_type, entry_price, strike, years_at_entry, iv) - \
+            black_scholes_price(option_type, entry_price, strike, years_at_exit, iv)
+
+        fees = self.commission * self.contracts * 2
+        pnl = (exit_premium - entry_premium) * CONTRACT_MULTIPLIER * self.contracts - fees
+
+        return {
+            'entry_date': entry_time,
+            'exit_date': exit_time,
+            'signal_type': rec['signal_type'],
+            'option_type': option_type,
+            'strike': strike,
+            'expiration': rec['expiration'],
+            'entry_premium': entry_premium,
+            'exit_premium': exit_premium,
+            'underlying_entry': entry_price,
+            'underlying_exit': exit_spot,
+            'exit_reason': exit_reason,
+            'pnl': pnl,
+            'return_pct': pnl / (entry_premium * CONTRACT_MULTIPLIER * self.contracts) * 100,
+            'hold_days': (exit_time - entry_time).total_seconds() / 86400,
+            'theta_cost': max(theta_cost, 0.0),
+            'iv': iv
+        }
+
+    @staticmethod
+    def _chain_premium(options_df, at, option_type, strike, expiration):
+        """Return the chain value for a contract on a date, or None when not quoted"""
+        rows = options_df[
+            (options_df['tradeDate'] == at.strftime('%Y-%m-%d')) &
+            (options_df['expirDate'] == expiration) &
+            (options_df['strike'] == strike)
+            ]
+        column = 'callValue' if option_type == 'CALL' else 'putValue'
+        if rows.empty or column not in rows.columns or pd.isna(rows.iloc[0][column]):
+            return None
+        return float(rows.iloc[0][column])
+
+    def _summarize(self, trades):
+        """Compute option-specific metrics across trades"""
+        summary = {
+            'total_trades': len(trades),
+            'winning_trades': 0,
+            'losing_trades': 0,
+            'win_rate': 0.0,
+            'total_pnl': 0.0,
+            'avg_pnl': 0.0,
+            'avg_premium': 0.0,
+            'avg_return_pct': 0.0,
+            'profit_factor': 0.0,
+            'max_drawdown': 0.0,
+            'expired_worthless': 0,
+            'avg_hold_days': 0.0,
+            'total_theta_cost': 0.0
+        }
+        if not trades:
+            return summary
+
+        gross_profit = sum(t['pnl'] for t in trades if t['pnl'] > 0)
+        gross_loss = sum(-t['pnl'] for t in trades if t['pnl'] <= 0)
+
+        summary['winning_trades'] = sum(1 for t in trades if t['pnl'] > 0)
+        summary['losing_trades'] = len(trades) - summary['winning_trades']
+        summary['win_rate'] = summary['winning_trades'] / len(trades)
+        summary['total_pnl'] = sum(t['pnl'] for t in trades)
+        summary['avg_pnl'] = summary['total_pnl'] / len(trades)
+        summary['avg_premium'] = sum(t['entry_premium'] for t in trades) / len(trades)
+        summary['avg_return_pct'] = sum(t['return_pct'] for t in trades) / len(trades)
+        summary['profit_factor'] = gross_profit / gross_loss if gross_loss > 0 else float('inf')
+        summary['expired_worthless'] = sum(1 for t in trades if t['exit_reason'] == 'EXPIRED' and t['exit_premium'] <= 0)
+        summary['avg_hold_days'] = sum(t['hold_days'] for t in trades) / len(trades)
+        summary['total_theta_cost'] = sum(t['theta_cost'] for t in trades) * CONTRACT_MULTIPLIER * self.contracts
+
+        # Drawdown of the cumulative P&L in entry order
+        equity, peak = 0.0, 0.0
+        for trade in sorted(trades, key=lambda t: t['entry_date']):
+            equity += trade['pnl']
+            peak = max(peak, equity)
+            summary['max_drawdown'] = max(summary['max_drawdown'], peak - equity)
+
+        return summary
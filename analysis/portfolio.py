@@ -0,0 +1,186 @@
+import numpy as np
+import pandas as pd
+from collections import defaultdict
+
+from .backtester import StrategyBacktester
+
+# Capital allocation methods
+ALLOCATION_EQUAL = 'equal'
+ALLOCATION_WEIGHTS = 'weights'
+ALLOCATION_INVERSE_VOLATILITY = 'inverse_volatility'
+
+ALLOCATIONS = (ALLOCATION_EQUAL, ALLOCATION_WEIGHTS, ALLOCATION_INVERSE_VOLATILITY)
+
+
+class PortfolioBacktester:
+    """
+    Class for backtesting a strategy across several tickers sharing one pool of capital
+
+    Each ticker is backtested on its own, then its trades are sized by the
+    capital allocated to it and combined into one equity curve. Every trade
+    commits the ticker's whole allocation.
+    """
+
+    def __init__(self, strategy_name="Default Strategy", initial_capital=100000.0,
+                 allocation=ALLOCATION_EQUAL, weights=None):
+        """
+        Initialize the portfolio backtester
+
+        Parameters:
+        strategy_name (str): Name of the strategy being tested
+        initial_capital (float): Starting equity in dollars
+        allocation (str): How capital is divided: equal, weights or inverse_volatility
+        weights (dict): Weight by ticker for the weights method, normalized to sum to one
+        """
+        if allocation not in ALLOCATIONS:
+            raise ValueError(f"Unknown allocation {allocation}, expected one of {', '.join(ALLOCATIONS)}")
+
+        self.strategy_name = strategy_name
+        self.initial_capital = initial_capital
+        self.allocation = allocation
+        self.weights = weights or {}
+        self.allocations = {}
+        self.backtesters = {}
+        self.summaries = {}
+        self.results = {}
+
+    def allocate(self, frames):
+        """
+        Divide capital between tickers
+
+        Parameters:
+        frames (dict): Price data by ticker
+
+        Returns:
+        dict: Share of capital by ticker, summing to one
+        """
+        tickers = sorted(frames)
+        if self.allocation == ALLOCATION_WEIGHTS:
+            raw = {ticker: max(float(self.weights.get(ticker, 0)), 0.0) for ticker in tickers}
+        elif self.allocation == ALLOCATION_INVERSE_VOLATILITY:
+            raw = {}
+            for ticker in tickers:
+                volatility = StrategyBacktester._daily_closes(frames[ticker]).pct_change().std()
+                raw[ticker] = 1 / volatility if volatility > 0 and not np.isnan(volatility) else 0.0
+        else:
+            raw = {ticker: 1.0 for ticker in tickers}
+
+        total = sum(raw.values())
+        if total <= 0:
+            # Nothing could be weighted, so split evenly rather than sit in cash
+            return {ticker: 1 / len(tickers) for ticker in tickers}
+        return {ticker: weight / total for ticker, weight in raw.items()}
+
+    def backtest(self, frames, **kwargs):
+        """
+        Run the backtest on every ticker and combine the results
+
+        Parameters:
+        frames (dict): DataFrames with entry signals and stoploss values by ticker
+        **kwargs: Backtest settings passed to StrategyBacktester.backtest for every ticker
+
+        Returns:
+        dict: Portfolio results by test name
+        """
+        if not frames:
+            raise ValueError("No tickers to backtest")
+
+        self.allocations = self.allocate(frames)
+
+        # Progress counts bars across every ticker, which are run in turn
+        progress = kwargs.pop('progress', None)
+        total_rows = sum(len(df) for df in frames.values())
+        rows_done = 0
+
+        for ticker, df in frames.items():
+            backtester = StrategyBacktester(strategy_name=f"{self.strategy_name} {ticker}")
+            backtester.backtest(df=df, progress=progress and self._ticker_progress(
+                progress, ticker, rows_done, len(df), total_rows), **kwargs)
+            rows_done += len(df)
+            self.backtesters[ticker] = backtester
+            self.summaries[ticker] = backtester.get_summary_stats()
+
+        test_names = sorted({name for backtester in self.backtesters.values() for name in backtester.results})
+        self.results = {name: self._combine(name) for name in test_names}
+        return self.results
+
+    @staticmethod
+    def _ticker_progress(progress, ticker, rows_before, rows, total_rows):
+        """Wrap a progress callback so one ticker's bar counts are reported across the portfolio."""
+        def report(update):
+            tests = update['total_bars'] // rows if rows else 0
+            progress(dict(update, ticker=ticker,
+                          bars_processed=rows_before * tests + update['bars_processed'],
+                          total_bars=total_rows * tests))
+        return report
+
+    def _combine(self, test_name):
+        """
+        Combine the tickers' trades for one test into the portfolio's equity curve
+
+        Parameters:
+        test_name (str): Name of the test, e.g. Target_5%
+
+        Returns:
+        dict: Portfolio statistics, equity curve and per-ticker breakdown
+        """
+        daily = defaultdict(float)
+        symbols = {}
+        trade_pnls = []
+        partial_fills = 0
+
+        for ticker, backtester in self.backtesters.items():
+            capital = self.initial_capital * self.allocations[ticker]
+            pnl = 0.0
+            for trade in backtester.results.get(test_name, {'trades': []})['trades']:
+                trade_pnl = capital * trade['profit_loss_pct'] / 100
+                daily[pd.Timestamp(trade['exit_date']).strftime('%Y-%m-%d')] += trade_pnl
+                trade_pnls.append(trade_pnl)
+                pnl += trade_pnl
+
+            stats = self.summaries[ticker].get(test_name)
+            if stats:
+                partial_fills += stats.get('partial_fills', 0)
+            symbols[ticker] = {
+                'weight': self.allocations[ticker],
+                'capital': capital,
+                'pnl': pnl,
+                'stats': stats
+            }
+
+        # Equity after each day with closed trades
+        equity = self.initial_capital
+        peak_equity = equity
+        max_drawdown = 0.0
+        max_drawdown_pct = 0.0
+        equity_curve = []
+        for day in sorted(daily):
+            equity += daily[day]
+            peak_equity = max(peak_equity, equity)
+            max_drawdown = max(max_drawdown, peak_equity - equity)
+            max_drawdown_pct = max(max_drawdown_pct, (peak_equity - equity) / peak_equity if peak_equity > 0 else 0)
+            equity_curve.append({'date': day, 'equity': equity})
+
+        total_profit = sum(pnl for pnl in trade_pnls if pnl > 0)
+        total_loss = sum(-pnl for pnl in trade_pnls if pnl <= 0)
+        winning_trades = sum(1 for pnl in trade_pnls if pnl > 0)
+
+        return {
+            'initial_capital': self.initial_capital,
+            'final_equity': equity,
+            'win_rate': winning_trades / len(trade_pnls) * 100 if trade_pnls else 0,  # Percentage
+            'profit_factor': total_profit / total_loss if total_loss > 0 else float('inf'),
+            'total_return': equity - self.initial_capital,
+            'total_return_pct': (equity / self.initial_capital - 1) * 100 if self.initial_capital > 0 else 0,
+            'total_trades': len(trade_pnls),
+            'winning_trades': winning_trades,
+            'losing_trades': len(trade_pnls) - winning_trades,
+            'max_drawdown': max_drawdown,
+            'max_drawdown_pct': max_drawdown_pct * 100,  # Convert to percentage
+            'partial_fills': partial_fills,
+            'sharpe_ratio': StrategyBacktester.sharpe_ratio(pd.Series(
+                [point['equity'] for point in equity_curve],
+                index=pd.to_datetime([point['date'] for point in equity_curve]))),
+            'equity_curve': equity_curve,
+            'symbols': symbols
+        }
@@ -42,7 +42,7 @@ class OptionsRecommender:
             risk = abs(current_price - stoploss)
 
             # Filter options for the current date
-            if 'tradeDate' in options_df.columns:
+            if options_df is not None and 'tradeDate' in options_df.columns:
                 day_options = options_df[options_df['tradeDate'] == date_str]
 
                 # If we have options data for this date
@@ -85,6 +85,7 @@ class OptionsRecommender:
 
                         recommendation = {
                             'date': date_str,
+                            'entry_time': date,
                             'signal_type': signal_type,
                             'stock_price': current_price,
                             'stoploss': stoploss,
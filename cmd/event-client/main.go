@@ -8,21 +8,18 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/myapp/tradinglab/pkg/clock"
 	"github.com/myapp/tradinglab/pkg/events"
 	"github.com/myapp/tradinglab/pkg/utils"
 )
 
-func init() {
-	// Set timezone to PST
-	loc, err := time.LoadLocation("America/Los_Angeles")
+func main() {
+	// Show times in Pacific time unless DISPLAY_TIMEZONE says otherwise
+	clk, err := clock.FromEnv("America/Los_Angeles")
 	if err != nil {
-		utils.Error("Failed to load PST timezone: %v", err)
-		return
+		utils.Fatal("Invalid time zone configuration: %v", err)
 	}
-	time.Local = loc
-}
 
-func main() {
 	// Get NATS URL from environment or use default
 	natsURL := os.Getenv("NATS_URL")
 	if natsURL == "" {
@@ -84,7 +81,7 @@ func main() {
 				// Create example live market data
 				liveData := map[string]interface{}{
 					"ticker":    "SPY",
-					"timestamp": t.Format(time.RFC3339),
+					"timestamp": clk.Format(t, time.RFC3339),
 					"price":     420.69,
 					"open":      419.50,
 					"high":      421.25,
@@ -104,7 +101,7 @@ func main() {
 				if t.Second()%30 == 0 {
 					dailyData := map[string]interface{}{
 						"ticker":    "SPY",
-						"timestamp": t.Format(time.RFC3339),
+						"timestamp": clk.Format(t, time.RFC3339),
 						"price":     421.42,
 						"open":      418.75,
 						"high":      422.50,
@@ -0,0 +1,54 @@
+// cmd/event-hub/anomaly.go
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// anomalyConfigFromEnv reads the live data anomaly thresholds:
+// HUB_ANOMALY_PRICE_SIGMA and HUB_ANOMALY_VOLUME_SIGMA (0 disables either),
+// HUB_ANOMALY_WINDOW and HUB_ANOMALY_MIN_SAMPLES in bars, and
+// HUB_ANOMALY_MAX_AGE, how old a bar may be on arrival (unset disables the
+// stale check). Invalid values are fatal.
+func anomalyConfigFromEnv() analytics.AnomalyConfig {
+	cfg := analytics.DefaultAnomalyConfig()
+	floatEnv := func(name string, target *float64) {
+		if value := os.Getenv(name); value != "" {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				utils.Fatal("Invalid %s %q", name, value)
+			}
+			*target = f
+		}
+	}
+	intEnv := func(name string, target *int) {
+		if value := os.Getenv(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				utils.Fatal("Invalid %s %q", name, value)
+			}
+			*target = n
+		}
+	}
+	floatEnv("HUB_ANOMALY_PRICE_SIGMA", &cfg.PriceSigma)
+	floatEnv("HUB_ANOMALY_VOLUME_SIGMA", &cfg.VolumeSigma)
+	intEnv("HUB_ANOMALY_WINDOW", &cfg.Window)
+	intEnv("HUB_ANOMALY_MIN_SAMPLES", &cfg.MinSamples)
+	if value := os.Getenv("HUB_ANOMALY_MAX_AGE"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			utils.Fatal("Invalid HUB_ANOMALY_MAX_AGE %q", value)
+		}
+		cfg.MaxAge = d
+	}
+
+	if err := cfg.Validate(); err != nil {
+		utils.Fatal("Invalid anomaly detection configuration: %v", err)
+	}
+	return cfg
+}
@@ -12,21 +12,18 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/myapp/tradinglab/pkg/auth"
+	"github.com/myapp/tradinglab/pkg/buildinfo"
+	"github.com/myapp/tradinglab/pkg/clock"
 	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/profiling"
+	"github.com/myapp/tradinglab/pkg/scheduler"
 	"github.com/myapp/tradinglab/pkg/utils"
 	eventhub "github.com/myapp/tradinglab/pkg/hub"
 )
 
-func init() {
-	// Set timezone to ET (Eastern Time) for market hours
-	loc, err := time.LoadLocation("America/New_York")
-	if err != nil {
-		utils.Error("Failed to load ET timezone: %v", err)
-	} else {
-		time.Local = loc
-	}
-}
-
 func main() {
 	// Get NATS URL from environment or use default
 	natsURL := os.Getenv("NATS_URL")
@@ -50,7 +47,7 @@ func main() {
 		tickers = []string{"SPY", "AAPL", "MSFT", "GOOGL", "AMZN"}
 	}
 
-	utils.Info("Event Hub starting, connecting to NATS server at %s", natsURL)
+	utils.Info("Event Hub %s starting, connecting to NATS server at %s", buildinfo.Get().Version, natsURL)
 	utils.Info("Watching tickers: %v", tickers)
 
 	// Create event client
@@ -80,6 +77,46 @@ func main() {
 	// Set watched tickers
 	hub.SetWatchedTickers(tickers)
 
+	// Announce the hub with a digest of its event counts
+	client.StartHeartbeat(ctx, "event-hub", events.HeartbeatInterval(), func() map[string]interface{} {
+		stats := hub.GetStats()
+		return map[string]interface{}{
+			"total_events": stats.TotalEvents,
+			"requests":     stats.Requests,
+			"errors":       stats.ErrorCount,
+		}
+	})
+
+	// Evaluate sessions in market time and show times in ET unless configured
+	clk, err := clock.FromEnv("America/New_York")
+	if err != nil {
+		utils.Fatal("Invalid time zone configuration: %v", err)
+	}
+	hub.SetClock(clk)
+
+	// Hold live bars briefly so out-of-order bars are processed in order
+	if window := os.Getenv("HUB_REORDER_WINDOW"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil || d < 0 {
+			utils.Fatal("Invalid HUB_REORDER_WINDOW %q", window)
+		}
+		hub.SetReorderWindow(d)
+	}
+
+	// Flag suspect live bars and keep them out of analytics
+	anomalies := anomalyConfigFromEnv()
+	hub.SetAnomalyConfig(anomalies)
+	utils.Info("Flagging live price jumps beyond %.1f and volume beyond %.1f standard deviations",
+		anomalies.PriceSigma, anomalies.VolumeSigma)
+
+	// Report watched tickers whose live data stops in market hours
+	stale := staleConfigFromEnv()
+	hub.SetStaleConfig(stale)
+	if stale.Threshold > 0 {
+		utils.Info("Reporting watched tickers stale after %s without live data (restart streams: %v)",
+			stale.Threshold, stale.Restart)
+	}
+
 	// Start the event hub with retry for critical components
 	maxRetries := 10
 	retryDelay := 5 * time.Second
@@ -116,6 +153,11 @@ func main() {
 			"Will continue to retry in the background.")
 	}
 
+	// Compile the cross-ticker daily summary after the close
+	jobs := scheduler.New()
+	scheduleDailySummary(hub, jobs)
+	jobs.Start()
+
 	// Setup HTTP server for health checks and API endpoints
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		stats := hub.GetStats()
@@ -133,9 +175,12 @@ func main() {
 		response := map[string]interface{}{
 			"status":        status,
 			"timestamp":     time.Now(),
+			"version":       buildinfo.Get().Version,
+			"commit":        buildinfo.Get().Commit,
 			"stats":         stats,
 			"streams":       streamStatus,
 			"failedStreams": []string{},
+			"staleTickers":  hub.StaleTickers(), // Watched tickers without live data for too long
 		}
 
 		// Add list of failed streams for easier monitoring
@@ -151,6 +196,10 @@ func main() {
 		json.NewEncoder(w).Encode(response)
 	})
 
+	// Event counts per stream and ticker for Prometheus
+	hub.RegisterMetrics(metrics.Default)
+	http.Handle("/metrics", metrics.Handler())
+
 	// API endpoint to request historical data
 	http.HandleFunc("/api/historical", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -176,13 +225,23 @@ func main() {
 			return
 		}
 
+		// Tickers and timeframes become NATS subject tokens
+		params, err := market.NormalizeHistoricalParams(ticker, timeframe, days)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		ticker, timeframe = params.Ticker, params.Interval
+
 		// Create request data
-		requestID := fmt.Sprintf("%s-%d", r.RemoteAddr, time.Now().UnixNano())
+		requestID := events.NewRequestID("hub")
 
 		// Process the request through the client directly
 		err = client.RequestHistoricalData(r.Context(), ticker, timeframe, days, map[string]interface{}{
 			"request_id": requestID,
 			"source":     "hub_api",
+			"priority":   events.ParsePriority(r.URL.Query().Get("priority")).String(),
 			"timestamp":  time.Now().Format(time.RFC3339),
 		})
 
@@ -198,14 +257,24 @@ func main() {
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status":     "accepted",
 			"request_id": requestID,
+			"progress":   fmt.Sprintf(events.SubjectRequestProgress, requestID),
 			"message":    fmt.Sprintf("Historical data request for %s (%s, %d days) has been submitted", ticker, timeframe, days),
 		})
 	})
 
+	// Profiling endpoints need DEBUG_ENDPOINTS=true and an admin credential
+	authn, err := auth.FromEnv()
+	if err != nil {
+		utils.Fatal("Invalid access control configuration: %v", err)
+	}
+	handler := profiling.Guard(http.DefaultServeMux, profiling.EnabledFromEnv(), func(w http.ResponseWriter, r *http.Request) bool {
+		return authn.Require(w, r, auth.PermConfig)
+	})
+
 	// Start HTTP server in a goroutine
 	go func() {
 		utils.Info("Starting HTTP server on %s", healthAddr)
-		if err := http.ListenAndServe(healthAddr, nil); err != nil {
+		if err := http.ListenAndServe(healthAddr, handler); err != nil {
 			utils.Fatal("HTTP server error: %v", err)
 		}
 	}()
@@ -215,6 +284,8 @@ func main() {
 	<-ctx.Done()
 	utils.Info("Shutting down Event Hub")
 
-	// Allow time for clean shutdown
-	time.Sleep(500 * time.Millisecond)
+	// Drain the hub's subscriptions; the deferred client.Close then flushes
+	// publishes and deletes temporary consumers
+	jobs.Stop()
+	hub.Close()
 }
\ No newline at end of file
@@ -0,0 +1,50 @@
+// cmd/event-hub/summary.go
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	eventhub "github.com/myapp/tradinglab/pkg/hub"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/scheduler"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// dailySummaryJob is the scheduler job name for the daily summary
+const dailySummaryJob = "daily-summary"
+
+// defaultSummarySchedule compiles the summary shortly after the close on weekdays
+const defaultSummarySchedule = "5 16 * * 1-5"
+
+// summaryTimeout bounds compiling and publishing one summary
+const summaryTimeout = time.Minute
+
+// scheduleDailySummary registers the daily summary job. Set SUMMARY_SCHEDULE
+// to a cron expression in exchange time to change when it runs, or to "off"
+// to disable it; SUMMARY_MOVERS sets how many gainers and losers it lists.
+func scheduleDailySummary(hub *eventhub.EventHub, jobs *scheduler.Scheduler) {
+	if n, err := strconv.Atoi(os.Getenv("SUMMARY_MOVERS")); err == nil && n > 0 {
+		hub.SetSummaryMovers(n)
+	}
+
+	schedule := os.Getenv("SUMMARY_SCHEDULE")
+	if schedule == "" {
+		schedule = defaultSummarySchedule
+	}
+	if schedule == "off" {
+		return
+	}
+
+	err := jobs.Add(dailySummaryJob, schedule, market.ExchangeLocation(), summaryTimeout, func(ctx context.Context) error {
+		_, err := hub.PublishDailySummary(ctx)
+		return err
+	})
+	if err != nil {
+		utils.Error("Failed to schedule daily summary: %v", err)
+		return
+	}
+	utils.Info("Scheduled daily summary (%s)", schedule)
+}
@@ -0,0 +1,32 @@
+// cmd/event-hub/watchdog.go
+package main
+
+import (
+	"os"
+	"time"
+
+	eventhub "github.com/myapp/tradinglab/pkg/hub"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// staleConfigFromEnv reads the stale data watchdog's settings:
+// HUB_STALE_THRESHOLD, how long a watched ticker may go without live data
+// in market hours (0 disables the watchdog), HUB_STALE_RESTART=true to ask
+// the market data service to restart stale streams, and
+// HUB_STALE_ALL_HOURS=true to watch outside market hours too. Invalid
+// values are fatal.
+func staleConfigFromEnv() eventhub.StaleConfig {
+	cfg := eventhub.StaleConfig{
+		Threshold: eventhub.DefaultStaleThreshold,
+		Restart:   os.Getenv("HUB_STALE_RESTART") == "true",
+		AllHours:  os.Getenv("HUB_STALE_ALL_HOURS") == "true",
+	}
+	if value := os.Getenv("HUB_STALE_THRESHOLD"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			utils.Fatal("Invalid HUB_STALE_THRESHOLD %q", value)
+		}
+		cfg.Threshold = d
+	}
+	return cfg
+}
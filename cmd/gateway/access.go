@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/myapp/tradinglab/pkg/auth"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// newAccessPolicy configures which routes are limited to allowlisted
+// networks. Every route but metrics and the debug endpoints is public
+// unless ACCESS_DEFAULT or ACCESS_PRIVATE_ROUTES say otherwise; the health
+// check always is. The ingress forwards from inside the cluster, so its
+// X-Forwarded-For is believed by default.
+func newAccessPolicy() (*auth.AccessPolicy, error) {
+	policy, err := auth.AccessPolicyFromEnv(auth.AccessPolicy{
+		Allow:          auth.MustParsePrefixes(auth.InternalNetworks...),
+		TrustedProxies: auth.MustParsePrefixes(auth.InternalNetworks...),
+		Public:         []string{"/api/health"},
+		Private:        []string{"/metrics", "/debug/*"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if policy.DefaultPrivate || len(policy.Private) > 0 {
+		utils.Info("Limiting private routes %v to %v", policy.Private, policy.Allow)
+	}
+	return policy, nil
+}
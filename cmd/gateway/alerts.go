@@ -0,0 +1,540 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/alerts"
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/notify"
+	"github.com/myapp/tradinglab/pkg/recommendation"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// alertDigestJob is the scheduler job name for daily digest emails
+const alertDigestJob = "alert-digest"
+
+// defaultAlertDigestSchedule sends digests after the close, once the
+// day's recommendations have been expired
+const defaultAlertDigestSchedule = "45 16 * * 1-5"
+
+// alertSendTimeout bounds sending one immediate alert
+const alertSendTimeout = 30 * time.Second
+
+// alertDigestTimeout bounds sending every digest for a day
+const alertDigestTimeout = 10 * time.Minute
+
+// newSubscriberStore creates the email subscriber store, persisted to
+// ALERT_SUBSCRIBERS_PATH when set
+func newSubscriberStore() *alerts.Store {
+	store, err := alerts.NewStore(os.Getenv("ALERT_SUBSCRIBERS_PATH"))
+	if err != nil {
+		utils.Error("Failed to load alert subscribers, starting empty: %v", err)
+		store, _ = alerts.NewStore("")
+	}
+	return store
+}
+
+// newAlertRuleStore creates the alert rule store, persisted to
+// ALERT_RULES_PATH when set
+func newAlertRuleStore() *alerts.RuleStore {
+	store, err := alerts.NewRuleStore(os.Getenv("ALERT_RULES_PATH"))
+	if err != nil {
+		utils.Error("Failed to load alert rules, starting empty: %v", err)
+		store, _ = alerts.NewRuleStore("")
+	}
+	return store
+}
+
+// newPushers creates the configured push platforms: FCM from
+// FCM_CREDENTIALS_FILE and APNs from APNS_KEY_FILE and related settings
+func newPushers() map[string]notify.Pusher {
+	pushers := make(map[string]notify.Pusher)
+	if fcm, err := notify.FCMFromEnv(); err != nil {
+		utils.Error("Failed to configure FCM push: %v", err)
+	} else if fcm != nil {
+		pushers[notify.PlatformFCM] = fcm
+	}
+	if apns, err := notify.APNsFromEnv(); err != nil {
+		utils.Error("Failed to configure APNs push: %v", err)
+	} else if apns != nil {
+		pushers[notify.PlatformAPNs] = apns
+	}
+	return pushers
+}
+
+// newAlertTemplates loads email templates, overridden by files in
+// ALERT_TEMPLATES_DIR (alert, digest and price, each .html and .txt)
+func newAlertTemplates() *alerts.Templates {
+	templates, err := alerts.LoadTemplates(os.Getenv("ALERT_TEMPLATES_DIR"))
+	if err != nil {
+		utils.Error("Failed to load alert templates, using built-ins: %v", err)
+		templates, _ = alerts.LoadTemplates("")
+	}
+	return templates
+}
+
+// subscribeAlertSignals collects published signals for digests and sends
+// immediate alerts for them, including to subscribed Slack channels. Signals
+// for tickers in anomaly quarantine are dropped.
+func (g *APIGateway) subscribeAlertSignals() {
+	_, err := g.natsClient.GetNATS().Subscribe(events.SubjectSignalsAll, func(msg *nats.Msg) {
+		data, err := events.Decode(msg)
+		if err != nil {
+			utils.Debug("Dropping undecodable signal on %s: %v", msg.Subject, err)
+			return
+		}
+		var signal alerts.Signal
+		if err := json.Unmarshal(data, &signal); err != nil || signal.Ticker == "" {
+			utils.Debug("Ignoring malformed signal on %s", msg.Subject)
+			return
+		}
+		signal.Ticker = market.NormalizeTicker(signal.Ticker)
+		signal.ReceivedAt = time.Now()
+		if signal.Date == "" && signal.Timestamp != "" {
+			// The streaming strategy engine only sends a timestamp
+			if t, err := market.ParseTimestamp(signal.Timestamp, market.ExchangeLocation()); err == nil {
+				signal.Date = t.In(market.ExchangeLocation()).Format("2006-01-02 15:04:05")
+			}
+		}
+		if g.quarantine.Active(signal.Ticker, signal.ReceivedAt) {
+			withheldSignalAlerts.With().Inc()
+			utils.Info("Withholding %s signal for %s: its live data was recently flagged as anomalous",
+				signal.SignalType, signal.Ticker)
+			return
+		}
+
+		g.alertSignals.Add(signal)
+		go g.sendSignalAlert(signal)
+		go g.postSlackSignal(signal)
+	})
+	if err != nil {
+		utils.Error("Failed to subscribe to signals for alerts: %v", err)
+	}
+}
+
+// sendSignalAlert delivers a signal to subscribers watching its ticker: by
+// email to those who opted in to immediate alerts when it is high priority,
+// and on each matching rule's channels
+func (g *APIGateway) sendSignalAlert(signal alerts.Signal) {
+	rules := g.alertRules.MatchSignal(signal)
+	if !signal.HighPriority() && len(rules) == 0 {
+		return
+	}
+
+	msg, err := g.alertTemplates.AlertMessage(signal)
+	if err != nil {
+		utils.Error("Failed to render alert for %s: %v", signal.Ticker, err)
+		return
+	}
+	data := map[string]string{"type": alerts.RuleSignal, "ticker": signal.Ticker, "signal_type": signal.SignalType, "strategy": signal.Strategy}
+	if signal.Confidence != nil {
+		data["confidence"] = strconv.FormatFloat(*signal.Confidence, 'f', -1, 64)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), alertSendTimeout)
+	defer cancel()
+	alerted := make(map[string]bool)
+	if signal.HighPriority() {
+		for _, sub := range g.subscribers.List() {
+			if sub.Immediate && sub.Watches(signal.Ticker) {
+				g.deliverAlert(ctx, sub, []string{alerts.ChannelEmail}, msg, data)
+				alerted[sub.ID] = true
+			}
+		}
+	}
+	for _, rule := range rules {
+		if sub, ok := g.subscribers.Get(rule.SubscriberID); ok {
+			g.deliverAlert(ctx, sub, rule.Channels, msg, data)
+			alerted[sub.ID] = true
+		}
+	}
+	for id := range alerted {
+		g.publishAlert(id, alerts.RuleSignal, signal.Ticker, msg, data)
+	}
+}
+
+// subscribeAlertPrices checks live ticks against price rules
+func (g *APIGateway) subscribeAlertPrices() {
+	_, err := g.natsClient.GetNATS().Subscribe(events.SubjectMarketLiveAll, func(msg *nats.Msg) {
+		data, err := events.Decode(msg)
+		if err != nil {
+			return
+		}
+		var tick struct {
+			Ticker string  `json:"ticker"`
+			Price  float64 `json:"price"`
+			Close  float64 `json:"close"`
+		}
+		if err := json.Unmarshal(data, &tick); err != nil {
+			return
+		}
+		if tick.Ticker == "" {
+			tick.Ticker = strings.TrimPrefix(msg.Subject, "market.live.")
+		}
+		price := tick.Price
+		if price <= 0 {
+			price = tick.Close
+		}
+
+		now := time.Now()
+		rules, err := g.alertRules.TriggerPrice(market.NormalizeTicker(tick.Ticker), price, now)
+		if err != nil {
+			utils.Warn("Failed to record triggered price alerts: %v", err)
+		}
+		for _, rule := range rules {
+			go g.sendPriceAlert(rule, alerts.PriceAlert{
+				RuleID:    rule.ID,
+				Ticker:    rule.Ticker,
+				Condition: rule.Condition,
+				Level:     rule.Price,
+				Price:     price,
+				At:        now,
+			})
+		}
+	})
+	if err != nil {
+		utils.Error("Failed to subscribe to live data for price alerts: %v", err)
+	}
+}
+
+// sendPriceAlert delivers a triggered price rule on its channels
+func (g *APIGateway) sendPriceAlert(rule alerts.Rule, alert alerts.PriceAlert) {
+	sub, ok := g.subscribers.Get(rule.SubscriberID)
+	if !ok {
+		return
+	}
+	msg, err := g.alertTemplates.PriceMessage(alert)
+	if err != nil {
+		utils.Error("Failed to render price alert for %s: %v", alert.Ticker, err)
+		return
+	}
+	data := map[string]string{"type": alerts.RulePrice, "ticker": alert.Ticker, "rule_id": rule.ID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), alertSendTimeout)
+	defer cancel()
+	g.deliverAlert(ctx, sub, rule.Channels, msg, data)
+	g.publishAlert(sub.ID, alerts.RulePrice, alert.Ticker, msg, data)
+}
+
+// publishAlert relays an alert delivered to a subscriber to WebSocket
+// clients following their watchlist
+func (g *APIGateway) publishAlert(subscriberID, kind, ticker string, msg notify.Message, data map[string]string) {
+	err := g.natsClient.PublishAlert(events.Alert{
+		SubscriberID: subscriberID,
+		Kind:         kind,
+		Ticker:       ticker,
+		Title:        msg.Subject,
+		Body:         msg.Text,
+		Data:         data,
+	})
+	if err != nil {
+		utils.Warn("Failed to publish %s alert for %s: %v", kind, subscriberID, err)
+	}
+}
+
+// deliverAlert sends an alert to a subscriber on the given channels. Push
+// goes to every registered device; tokens the platform reports invalid
+// are unregistered.
+func (g *APIGateway) deliverAlert(ctx context.Context, sub alerts.Subscriber, channels []string, msg notify.Message, data map[string]string) {
+	for _, channel := range channels {
+		switch channel {
+		case alerts.ChannelEmail:
+			email := notify.EmailFromEnv(sub.Email)
+			if email == nil {
+				utils.Debug("SMTP is not configured, skipping email alert for %s", sub.Email)
+				continue
+			}
+			if err := email.Notify(ctx, msg); err != nil {
+				utils.Warn("Failed to email alert to %s: %v", sub.Email, err)
+			}
+		case alerts.ChannelPush:
+			push := alerts.PushMessage(msg, data)
+			for _, device := range sub.Devices {
+				pusher, ok := g.pushers[device.Platform]
+				if !ok {
+					utils.Debug("%s push is not configured, skipping device of %s", device.Platform, sub.Email)
+					continue
+				}
+				err := pusher.Push(ctx, device.Token, push)
+				if errors.Is(err, notify.ErrInvalidToken) {
+					utils.Info("Unregistering invalid %s device of %s", device.Platform, sub.Email)
+					g.subscribers.UnregisterDevice(device.Token)
+				} else if err != nil {
+					utils.Warn("Failed to push alert to %s device of %s: %v", device.Platform, sub.Email, err)
+				}
+			}
+		}
+	}
+}
+
+// scheduleAlertDigest registers the daily digest job. Set
+// ALERT_DIGEST_SCHEDULE to a cron expression to change when it runs, or to
+// "off" to disable it.
+func (g *APIGateway) scheduleAlertDigest() {
+	schedule := os.Getenv("ALERT_DIGEST_SCHEDULE")
+	if schedule == "" {
+		schedule = defaultAlertDigestSchedule
+	}
+	if schedule == "off" {
+		return
+	}
+
+	err := g.scheduler.Add(alertDigestJob, schedule, market.ExchangeLocation(), alertDigestTimeout, func(ctx context.Context) error {
+		_, err := g.sendDigests(ctx, market.SessionDate(time.Now()))
+		return err
+	})
+	if err != nil {
+		utils.Error("Failed to schedule alert digests: %v", err)
+		return
+	}
+	utils.Info("Scheduled alert digests (%s)", schedule)
+}
+
+// digestFor builds a subscriber's digest for a session date
+func (g *APIGateway) digestFor(sub alerts.Subscriber, date string) alerts.Digest {
+	var recs []recommendation.Recommendation
+	for _, rec := range g.recommendations.Find(recommendation.Query{}) {
+		if market.SessionDate(rec.CreatedAt) == date {
+			recs = append(recs, rec)
+		}
+	}
+	return alerts.NewDigest(date, sub, g.alertSignals.Signals(date), recs)
+}
+
+// sendDigests emails the digest for a date to every subscriber who opted
+// in, skipping empty digests, and returns how many were sent
+func (g *APIGateway) sendDigests(ctx context.Context, date string) (int, error) {
+	sent, failed := 0, 0
+	for _, sub := range g.subscribers.List() {
+		if !sub.Digest {
+			continue
+		}
+		digest := g.digestFor(sub, date)
+		if digest.Empty() {
+			continue
+		}
+		msg, err := g.alertTemplates.DigestMessage(digest)
+		if err != nil {
+			return sent, err
+		}
+		email := notify.EmailFromEnv(sub.Email)
+		if email == nil {
+			return sent, fmt.Errorf("SMTP is not configured")
+		}
+		if err := email.Notify(ctx, msg); err != nil {
+			utils.Warn("Failed to email digest to %s: %v", sub.Email, err)
+			failed++
+			continue
+		}
+		sent++
+	}
+	utils.Info("Sent %d alert digests for %s", sent, date)
+	if failed > 0 {
+		return sent, fmt.Errorf("%d digests failed to send", failed)
+	}
+	return sent, nil
+}
+
+// alertSubscribersHandler lists email subscribers
+func (g *APIGateway) alertSubscribersHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.subscribers.List())
+}
+
+// alertSubscribeHandler opts an email address in to alerts and digests, or
+// updates the preferences of a known address
+func (g *APIGateway) alertSubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	var sub alerts.Subscriber
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		http.Error(w, "invalid subscriber payload", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := g.subscribers.Save(sub)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// alertSubscriberHandler returns one subscriber
+func (g *APIGateway) alertSubscriberHandler(w http.ResponseWriter, r *http.Request) {
+	sub, ok := g.subscribers.Get(mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, "subscriber not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+// alertUnsubscribeHandler removes a subscriber and their alert rules
+func (g *APIGateway) alertUnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := g.subscribers.Delete(id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err := g.alertRules.DeleteSubscriber(id); err != nil {
+		utils.Warn("Failed to delete alert rules of %s: %v", id, err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// alertDigestPreviewHandler renders a subscriber's digest for a date
+// (default today) as JSON, or as the email HTML with format=html
+func (g *APIGateway) alertDigestPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	sub, ok := g.subscribers.Get(mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, "subscriber not found", http.StatusNotFound)
+		return
+	}
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = market.SessionDate(time.Now())
+	}
+	digest := g.digestFor(sub, date)
+
+	if r.URL.Query().Get("format") == "html" {
+		msg, err := g.alertTemplates.DigestMessage(digest)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(msg.HTML))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(digest)
+}
+
+// alertDigestSendHandler sends the digests for a date (default today) now
+func (g *APIGateway) alertDigestSendHandler(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = market.SessionDate(time.Now())
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), alertDigestTimeout)
+	defer cancel()
+	sent, err := g.sendDigests(ctx, date)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error sending digests: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"date": date, "sent": sent})
+}
+
+// alertDeviceRegisterHandler registers a subscriber's device for push notifications
+func (g *APIGateway) alertDeviceRegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var device alerts.Device
+	if err := json.NewDecoder(r.Body).Decode(&device); err != nil {
+		http.Error(w, "invalid device payload", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := g.subscribers.RegisterDevice(mux.Vars(r)["id"], device)
+	if err != nil {
+		status := http.StatusBadRequest
+		if _, ok := g.subscribers.Get(mux.Vars(r)["id"]); !ok {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sub)
+}
+
+// alertDeviceUnregisterHandler removes a device token
+func (g *APIGateway) alertDeviceUnregisterHandler(w http.ResponseWriter, r *http.Request) {
+	if err := g.subscribers.UnregisterDevice(mux.Vars(r)["token"]); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// alertRulesHandler lists alert rules, optionally for one subscriber
+func (g *APIGateway) alertRulesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.alertRules.List(r.URL.Query().Get("subscriber_id")))
+}
+
+// alertRuleCreateHandler adds a signal or price alert rule with its delivery channels
+func (g *APIGateway) alertRuleCreateHandler(w http.ResponseWriter, r *http.Request) {
+	var rule alerts.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "invalid alert rule payload", http.StatusBadRequest)
+		return
+	}
+	if _, ok := g.subscribers.Get(rule.SubscriberID); !ok {
+		http.Error(w, "subscriber not found", http.StatusBadRequest)
+		return
+	}
+
+	created, err := g.alertRules.Add(rule)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// alertRuleHandler returns one alert rule
+func (g *APIGateway) alertRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule, ok := g.alertRules.Get(mux.Vars(r)["id"])
+	if !ok {
+		http.Error(w, "alert rule not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// alertRuleDeleteHandler removes an alert rule
+func (g *APIGateway) alertRuleDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if err := g.alertRules.Delete(mux.Vars(r)["id"]); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// alertRuleRearmHandler lets a triggered price rule fire again
+func (g *APIGateway) alertRuleRearmHandler(w http.ResponseWriter, r *http.Request) {
+	rule, err := g.alertRules.Rearm(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// defaultAnomalyQuarantine is how long signal alerts for a ticker are withheld
+// after the hub flags its live data, unless ANOMALY_QUARANTINE is set
+const defaultAnomalyQuarantine = 5 * time.Minute
+
+// withheldSignalAlerts counts signals not alerted on because their ticker's
+// live data was flagged
+var withheldSignalAlerts = metrics.Default.NewCounterVec("gateway_withheld_signal_alerts_total",
+	"Signals not alerted on because their ticker's live data was flagged as anomalous")
+
+// anomalyQuarantine tracks tickers whose live data was recently flagged, so
+// signals computed from a bad print do not reach subscribers
+type anomalyQuarantine struct {
+	mu     sync.Mutex
+	window time.Duration
+	until  map[string]time.Time
+}
+
+// newAnomalyQuarantine creates a quarantine lasting window after each
+// anomaly; zero disables it
+func newAnomalyQuarantine(window time.Duration) *anomalyQuarantine {
+	return &anomalyQuarantine{window: window, until: make(map[string]time.Time)}
+}
+
+// Flag quarantines a ticker from at
+func (q *anomalyQuarantine) Flag(ticker string, at time.Time) {
+	if q.window <= 0 {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.until[ticker] = at.Add(q.window)
+}
+
+// Active reports whether a ticker is quarantined at now
+func (q *anomalyQuarantine) Active(ticker string, now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	until, ok := q.until[ticker]
+	if ok && !now.Before(until) {
+		delete(q.until, ticker)
+		return false
+	}
+	return ok
+}
+
+// anomalyQuarantineFromEnv reads ANOMALY_QUARANTINE; 0 disables it
+func anomalyQuarantineFromEnv() time.Duration {
+	if value := os.Getenv("ANOMALY_QUARANTINE"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			return d
+		}
+		utils.Warn("Invalid ANOMALY_QUARANTINE %q, using default %s", value, defaultAnomalyQuarantine)
+	}
+	return defaultAnomalyQuarantine
+}
+
+// subscribeAnomalies quarantines tickers the hub flags anomalies for
+func (g *APIGateway) subscribeAnomalies() {
+	_, err := g.natsClient.SubscribeMarketAnomalies(func(anomaly events.MarketAnomaly) {
+		ticker := market.NormalizeTicker(anomaly.Ticker)
+		kind := ""
+		if len(anomaly.Reasons) > 0 {
+			kind = anomaly.Reasons[0].Kind
+		}
+		utils.Warn("Live data for %s flagged as %s at %s, withholding signal alerts for %s",
+			ticker, kind, anomaly.Timestamp, g.quarantine.window)
+		g.quarantine.Flag(ticker, time.Now())
+	})
+	if err != nil {
+		utils.Error("Failed to subscribe to market anomalies: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/artifacts"
+	"github.com/myapp/tradinglab/pkg/config"
+	"github.com/myapp/tradinglab/pkg/storage"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// artifactRetentionJob is the scheduler job name for artifact retention
+const artifactRetentionJob = "artifact-retention"
+
+// defaultArtifactRetentionSchedule applies retention nightly
+const defaultArtifactRetentionSchedule = "45 2 * * *"
+
+// Artifact kinds managed by the gateway
+const (
+	artifactReports   = "reports"
+	artifactBacktests = "backtests"
+	artifactExports   = "exports"
+)
+
+// unsafeArtifactChars are replaced in values used in artifact names
+var unsafeArtifactChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// artifactsDir returns ARTIFACTS_DIR, where backtests and exports are kept
+func artifactsDir() string {
+	if dir := os.Getenv("ARTIFACTS_DIR"); dir != "" {
+		return dir
+	}
+	return "artifacts"
+}
+
+// newArtifactManager manages reports, backtest results and exports with the
+// policies from ARTIFACT_POLICY_PATH, archiving to the store from
+// newArtifactArchive
+func newArtifactManager() *artifacts.Manager {
+	policies, err := config.LoadArtifactPolicies()
+	if err != nil {
+		utils.Error("Failed to load artifact policies, using defaults: %v", err)
+		policies = config.DefaultArtifactPolicies
+	}
+
+	archive := newArtifactArchive()
+	if archive != nil {
+		utils.Info("Archiving artifacts to %s", archive.Name())
+	}
+	return artifacts.NewManager(archive,
+		artifacts.Collection{Kind: artifactReports, Dir: reportsDir(), Pattern: "daily-*.json", Policy: policies[artifactReports]},
+		artifacts.Collection{Kind: artifactBacktests, Dir: filepath.Join(artifactsDir(), artifactBacktests), Pattern: "*.json", Policy: policies[artifactBacktests]},
+		artifacts.Collection{Kind: artifactExports, Dir: filepath.Join(artifactsDir(), artifactExports), Pattern: "*", Policy: policies[artifactExports]},
+	)
+}
+
+// newArtifactArchive returns the archive store: the S3 bucket in
+// ARTIFACT_ARCHIVE_BUCKET (with S3_REGION, S3_ENDPOINT, S3_PATH_STYLE and
+// ARTIFACT_ARCHIVE_PREFIX), or the directory ARTIFACT_ARCHIVE_DIR. Nil when
+// neither is set, which disables archival.
+func newArtifactArchive() storage.Store {
+	if bucket := os.Getenv("ARTIFACT_ARCHIVE_BUCKET"); bucket != "" {
+		pathStyle, _ := strconv.ParseBool(os.Getenv("S3_PATH_STYLE"))
+		store, err := storage.NewS3Store(storage.S3Config{
+			Bucket:    bucket,
+			Region:    os.Getenv("S3_REGION"),
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Prefix:    os.Getenv("ARTIFACT_ARCHIVE_PREFIX"),
+			PathStyle: pathStyle,
+		})
+		if err != nil {
+			utils.Error("Invalid artifact archive, archival disabled: %v", err)
+			return nil
+		}
+		return store
+	}
+	if dir := os.Getenv("ARTIFACT_ARCHIVE_DIR"); dir != "" {
+		return storage.NewFileStore(dir)
+	}
+	return nil
+}
+
+// scheduleArtifactRetention registers the nightly retention pass. Set
+// ARTIFACT_RETENTION_SCHEDULE to a cron expression to change when it runs,
+// or to "off" to disable it.
+func (g *APIGateway) scheduleArtifactRetention() {
+	schedule := os.Getenv("ARTIFACT_RETENTION_SCHEDULE")
+	if schedule == "" {
+		schedule = defaultArtifactRetentionSchedule
+	}
+	if schedule == "off" {
+		return
+	}
+
+	err := g.scheduler.Add(artifactRetentionJob, schedule, time.UTC, 30*time.Minute, func(ctx context.Context) error {
+		return logRetention(g.artifacts.Apply(ctx, time.Now()))
+	})
+	if err != nil {
+		utils.Error("Failed to schedule artifact retention: %v", err)
+		return
+	}
+	utils.Info("Scheduled artifact retention (%s)", schedule)
+}
+
+// logRetention logs what a retention pass did, returning an error if any
+// artifact failed
+func logRetention(summary artifacts.Summary) error {
+	utils.Info("Artifact retention archived %d, deleted %d and purged %d artifacts",
+		len(summary.Archived), len(summary.Deleted), len(summary.Purged))
+	for _, failure := range summary.Errors {
+		utils.Warn("Artifact retention: %s", failure)
+	}
+	if len(summary.Errors) > 0 {
+		return fmt.Errorf("%d artifacts failed retention", len(summary.Errors))
+	}
+	return nil
+}
+
+// saveBacktestArtifact keeps a backtest's request, results and equity
+// curves, returning the artifact name
+func (g *APIGateway) saveBacktestArtifact(request, results interface{}, curves map[string][]equityPoint, ticker, strategy string) (string, error) {
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-%s-%s.json", now.Format("20060102T150405.000000000"),
+		unsafeArtifactChars.ReplaceAllString(ticker, "_"), unsafeArtifactChars.ReplaceAllString(strategy, "_"))
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"created_at":    now,
+		"request":       request,
+		"results":       results,
+		"equity_curves": curves,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return name, g.artifacts.Save(artifactBacktests, name, data)
+}
+
+// artifactError writes the response for an artifact operation error
+func artifactError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, artifacts.ErrUnknownKind), errors.Is(err, artifacts.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, artifacts.ErrExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// artifactsHandler lists stored artifacts, including those in the trash,
+// optionally for one kind, with each kind's policy
+func (g *APIGateway) artifactsHandler(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get("kind")
+	list, err := g.artifacts.List(kind)
+	if err != nil {
+		artifactError(w, err)
+		return
+	}
+
+	policies := make(map[string]config.ArtifactPolicy)
+	for _, k := range g.artifacts.Kinds() {
+		if kind == "" || k == kind {
+			policies[k], _ = g.artifacts.Policy(k)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"artifacts": list,
+		"policies":  policies,
+	})
+}
+
+// artifactDeleteHandler moves an artifact to the trash, or removes it for
+// good with purge=true
+func (g *APIGateway) artifactDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	var err error
+	if r.URL.Query().Get("purge") == "true" {
+		err = g.artifacts.Remove(vars["kind"], vars["name"])
+	} else {
+		err = g.artifacts.Delete(vars["kind"], vars["name"])
+	}
+	if err != nil {
+		artifactError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// artifactRestoreHandler moves an artifact back out of the trash
+func (g *APIGateway) artifactRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := g.artifacts.Restore(vars["kind"], vars["name"]); err != nil {
+		artifactError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// artifactPurgeHandler empties the trash, optionally for one kind and only
+// of artifacts deleted over older_than (e.g. 24h) ago
+func (g *APIGateway) artifactPurgeHandler(w http.ResponseWriter, r *http.Request) {
+	var olderThan time.Duration
+	if value := r.URL.Query().Get("older_than"); value != "" {
+		var err error
+		if olderThan, err = time.ParseDuration(value); err != nil || olderThan < 0 {
+			http.Error(w, "older_than must be a duration such as 24h", http.StatusBadRequest)
+			return
+		}
+	}
+
+	purged, err := g.artifacts.Purge(r.URL.Query().Get("kind"), olderThan)
+	if err != nil && len(purged) == 0 {
+		artifactError(w, err)
+		return
+	}
+	utils.Info("Purged %d artifacts from the trash", len(purged))
+
+	response := map[string]interface{}{"purged": purged}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// artifactRetentionHandler runs a retention pass now and reports what it did
+func (g *APIGateway) artifactRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Minute)
+	defer cancel()
+	summary := g.artifacts.Apply(ctx, time.Now())
+	logRetention(summary)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/audit"
+	"github.com/myapp/tradinglab/pkg/auth"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// auditedRoutes names the action recorded for each audited method and route:
+// admin operations, watchlist and alert rule changes, and anything that
+// opens, closes or fills positions
+var auditedRoutes = map[string]string{
+	"POST /api/ops/streams/{name}/purge":            "ops.stream.purge",
+	"POST /api/ops/retention":                       "ops.retention.apply",
+	"POST /api/ops/consumers/prune":                 "ops.consumers.prune",
+	"POST /api/ops/artifacts/purge":                 "ops.artifacts.purge",
+	"POST /api/ops/artifacts/retention":             "ops.artifacts.retention",
+	"DELETE /api/ops/artifacts/{kind}/{name}":       "ops.artifacts.delete",
+	"POST /api/ops/artifacts/{kind}/{name}/restore": "ops.artifacts.restore",
+	"POST /api/ops/snapshots":                       "ops.snapshots.create",
+	"POST /api/ops/snapshots/{id}/restore":          "ops.snapshots.restore",
+	"POST /api/strategies":                          "strategies.define",
+	"PUT /api/strategies/{name}":                    "strategies.define",
+	"DELETE /api/strategies/{name}":                 "strategies.delete",
+	"POST /api/scans/{name}/run":                    "scans.run",
+	"POST /api/reports/daily/{date}":                "reports.generate",
+	"POST /api/alerts/subscribers":                  "watchlist.subscribe",
+	"DELETE /api/alerts/subscribers/{id}":           "watchlist.unsubscribe",
+	"POST /api/alerts/subscribers/{id}/devices":     "alerts.device.register",
+	"DELETE /api/alerts/devices/{token}":            "alerts.device.unregister",
+	"POST /api/alerts/digest":                       "alerts.digest.send",
+	"POST /api/alerts/rules":                        "alerts.rule.create",
+	"DELETE /api/alerts/rules/{id}":                 "alerts.rule.delete",
+	"POST /api/alerts/rules/{id}/rearm":             "alerts.rule.rearm",
+	"POST /api/risk/positions":                      "execution.position.open",
+	"DELETE /api/risk/positions/{id}":               "execution.position.close",
+	"POST /api/recommendations/{id}/fill":           "execution.recommendation.fill",
+	"POST /api/recommendations/{id}/close":          "execution.recommendation.close",
+	"POST /api/journal":                             "execution.journal.create",
+	"PATCH /api/journal/{id}":                       "execution.journal.annotate",
+	"PUT /api/journal/{id}":                         "execution.journal.annotate",
+	"DELETE /api/journal/{id}":                      "execution.journal.delete",
+	"POST /api/webhooks/tradingview":                "signals.ingest",
+	"GET /api/audit":                                "audit.query",
+}
+
+// newAuditLog opens the audit log at AUDIT_LOG_PATH, keeping recent entries
+// in memory when it is unset or cannot be opened
+func newAuditLog() *audit.Log {
+	log, err := audit.Open(os.Getenv("AUDIT_LOG_PATH"))
+	if err != nil {
+		utils.Error("Failed to open audit log, keeping entries in memory: %v", err)
+		log, _ = audit.Open("")
+	}
+	return log
+}
+
+// auditStatusWriter captures the status code of an audited response
+type auditStatusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status before writing it
+func (w *auditStatusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// auditMiddleware records audited API calls once they complete, including
+// those rejected for lack of authorization
+func (g *APIGateway) auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		template, _ := route.GetPathTemplate()
+		action := auditedRoutes[r.Method+" "+template]
+		if action == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &auditStatusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		entry := audit.Entry{
+			Actor:      auditActor(r),
+			Action:     action,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Resource:   auditResource(mux.Vars(r)),
+			Status:     recorder.status,
+			RemoteAddr: clientAddr(r),
+			UserAgent:  r.UserAgent(),
+		}
+		switch {
+		case recorder.status == http.StatusUnauthorized || recorder.status == http.StatusForbidden:
+			entry.Outcome = audit.OutcomeDenied
+		case recorder.status >= 400:
+			entry.Outcome = audit.OutcomeFailed
+		}
+		g.recordAudit(entry)
+	})
+}
+
+// recordAudit appends an entry to the audit log
+func (g *APIGateway) recordAudit(entry audit.Entry) {
+	if err := g.audit.Record(entry); err != nil {
+		utils.Error("Failed to record audit entry for %s by %s: %v", entry.Action, entry.Actor, err)
+	}
+}
+
+// auditActor identifies who made a request: the authenticated user, or the
+// name an untrusted proxy header gives for an anonymous caller
+func auditActor(r *http.Request) string {
+	return auth.FromContext(r.Context()).User
+}
+
+// auditResource copies route variables, masking device tokens
+func auditResource(vars map[string]string) map[string]string {
+	if len(vars) == 0 {
+		return nil
+	}
+	resource := make(map[string]string, len(vars))
+	for name, value := range vars {
+		if name == "token" && len(value) > 8 {
+			value = value[:8] + "…"
+		}
+		resource[name] = value
+	}
+	return resource
+}
+
+// clientAddr returns the client's IP address, taken from X-Forwarded-For
+// when the gateway is behind the ingress
+func clientAddr(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// auditHandler queries the audit log, newest first, filtered by actor,
+// action (a trailing "." matches a category such as execution.), since,
+// until and limit (default 100)
+func (g *APIGateway) auditHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	since, err := parseDateParam(r, "since", false)
+	if err != nil {
+		http.Error(w, "invalid since parameter", http.StatusBadRequest)
+		return
+	}
+	until, err := parseDateParam(r, "until", true)
+	if err != nil {
+		http.Error(w, "invalid until parameter", http.StatusBadRequest)
+		return
+	}
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			http.Error(w, "invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := g.audit.Query(audit.Filter{
+		Actor:  r.URL.Query().Get("actor"),
+		Action: r.URL.Query().Get("action"),
+		Since:  since,
+		Until:  until,
+		Limit:  limit,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// defaultBacktestCacheTTL is how long identical backtests are served from
+// the cache unless BACKTEST_CACHE_TTL says otherwise
+const defaultBacktestCacheTTL = 15 * time.Minute
+
+// formingBarVersion is how often a backtest on daily or longer bars is
+// rerun while today's bar is still forming
+const formingBarVersion = 15 * time.Minute
+
+// backtestRun is a backtest's results and where they came from
+type backtestRun struct {
+	Results  map[string]interface{} `json:"results"`
+	Artifact string                 `json:"artifact,omitempty"`  // Stored artifact of the run that produced the results
+	CachedAt *time.Time             `json:"cached_at,omitempty"` // When the results were computed, if served from the cache
+}
+
+// backtestCacheTTLFromEnv reads BACKTEST_CACHE_TTL, a duration; zero turns
+// the cache off
+func backtestCacheTTLFromEnv() time.Duration {
+	value := os.Getenv("BACKTEST_CACHE_TTL")
+	if value == "" {
+		return defaultBacktestCacheTTL
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		utils.Warn("Invalid BACKTEST_CACHE_TTL %q, using default %s", value, defaultBacktestCacheTTL)
+		return defaultBacktestCacheTTL
+	}
+	return ttl
+}
+
+// backtestCacheKey hashes a backtest's parameters with the version of the
+// data it would run on, so identical requests share results until a new
+// bar closes. Keys are per user, as each run is charged to its caller.
+func backtestCacheKey(req *pb.BacktestRequest, user string, now time.Time) (string, error) {
+	params, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(params)
+	h.Write([]byte("\x00" + backtestDataVersion(req, now) + "\x00" + user))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// backtestDataVersion names the latest data a backtest would see. Data as
+// of a past time never changes. Otherwise, while the market is open it is
+// the start of the bar in progress, or of the current formingBarVersion
+// period for daily and longer bars; once it closes, data stops changing
+// until the next session, so it is the exchange date.
+func backtestDataVersion(req *pb.BacktestRequest, now time.Time) string {
+	if req.AsOf != "" {
+		return "as-of/" + req.AsOf
+	}
+
+	ticker := req.Ticker
+	if len(req.Tickers) > 0 {
+		ticker = req.Tickers[0]
+	}
+	et := now.In(market.ExchangeLocation())
+	if !market.InSession(ticker, now) {
+		return et.Format("2006-01-02") + "/closed"
+	}
+
+	period, err := market.IntervalDuration(req.Interval)
+	if err != nil || period >= 24*time.Hour {
+		period = formingBarVersion
+	}
+	midnight := time.Date(et.Year(), et.Month(), et.Day(), 0, 0, 0, 0, et.Location())
+	return midnight.Add(et.Sub(midnight).Truncate(period)).Format(time.RFC3339)
+}
+
+// CacheBacktestResults caches a backtest run, dropping runs older than ttl
+func (c *DataCache) CacheBacktestResults(key string, run backtestRun, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for k, cached := range c.backtestResults {
+		if now.Sub(cached.Timestamp) > ttl {
+			delete(c.backtestResults, k)
+		}
+	}
+	c.backtestResults[key] = CachedData{
+		Data:      run,
+		Timestamp: now,
+		Source:    "live",
+	}
+}
+
+// GetCachedBacktestResults retrieves a backtest run cached within ttl
+func (c *DataCache) GetCachedBacktestResults(key string, ttl time.Duration) (backtestRun, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	cached, exists := c.backtestResults[key]
+	if !exists || time.Since(cached.Timestamp) > ttl {
+		return backtestRun{}, false
+	}
+	run := cached.Data.(backtestRun)
+	run.CachedAt = &cached.Timestamp
+	return run, true
+}
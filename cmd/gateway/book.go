@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// orderBookHandler serves the latest order book snapshot for a ticker with its
+// spread and liquidity figures
+func (g *APIGateway) orderBookHandler(w http.ResponseWriter, r *http.Request) {
+	ticker, err := market.ValidateTicker(r.URL.Query().Get("ticker"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := g.natsClient.LatestOrderBook(ticker)
+	if err != nil {
+		if errors.Is(err, nats.ErrMsgNotFound) {
+			http.Error(w, fmt.Sprintf("no order book available for %s", ticker), http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("error reading order book: %v", err), http.StatusServiceUnavailable)
+		}
+		return
+	}
+
+	var book market.OrderBook
+	if err := json.Unmarshal(data, &book); err != nil {
+		http.Error(w, "invalid order book snapshot", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Data-Age", fmt.Sprintf("%.1f seconds", time.Since(book.Timestamp).Seconds()))
+	json.NewEncoder(w).Encode(book)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// fetchCandles retrieves historical candles from the trading service and converts
+// them to analytics candles, oldest first
+func (g *APIGateway) fetchCandles(ctx context.Context, ticker, interval string, days int) ([]analytics.Candle, error) {
+	resp, err := g.tradingClient.GetHistoricalData(ctx, &pb.HistoricalDataRequest{
+		Ticker:   ticker,
+		Days:     int32(days),
+		Interval: interval,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch historical data for %s: %w", ticker, err)
+	}
+
+	candles := make([]analytics.Candle, 0, len(resp.Candles))
+	for _, c := range resp.Candles {
+		t, err := analytics.ParseCandleTime(c.Date)
+		if err != nil {
+			continue
+		}
+		candles = append(candles, analytics.Candle{
+			Time:   t,
+			Open:   c.Open,
+			High:   c.High,
+			Low:    c.Low,
+			Close:  c.Close,
+			Volume: float64(c.Volume),
+		})
+	}
+
+	if len(candles) == 0 {
+		return nil, fmt.Errorf("no historical data available for %s", ticker)
+	}
+
+	return candles, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/strategy"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// confirmSpecFromRequest reads multi-timeframe confirmation from the confirm,
+// confirm_period and confirm_mode query parameters. Returns nil when confirmation
+// was not requested.
+func confirmSpecFromRequest(r *http.Request) (*strategy.ConfirmSpec, error) {
+	query := r.URL.Query()
+	interval := query.Get("confirm")
+	if interval == "" {
+		return nil, nil
+	}
+
+	spec := &strategy.ConfirmSpec{Interval: interval}
+	if period := query.Get("confirm_period"); period != "" {
+		value, err := strconv.Atoi(period)
+		if err != nil || value < 2 {
+			return nil, fmt.Errorf("invalid confirm_period parameter")
+		}
+		spec.Period = value
+	}
+
+	switch query.Get("confirm_mode") {
+	case "", "require":
+	case "annotate":
+		spec.AnnotateOnly = true
+	default:
+		return nil, fmt.Errorf("confirm_mode must be require or annotate")
+	}
+	return spec, nil
+}
+
+// validateConfirmSpec normalizes the confirmation interval and checks it is
+// longer than the entry interval
+func validateConfirmSpec(spec *strategy.ConfirmSpec, entryInterval string) error {
+	interval, err := market.NormalizeInterval(spec.Interval)
+	if err != nil {
+		return fmt.Errorf("invalid confirm interval: %w", err)
+	}
+
+	entry, _ := market.IntervalDuration(entryInterval)
+	confirm, _ := market.IntervalDuration(interval)
+	if confirm <= entry {
+		return fmt.Errorf("confirm interval %s must be longer than the %s entry interval", interval, entryInterval)
+	}
+
+	spec.Interval = interval
+	if spec.Period == 0 {
+		spec.Period = strategy.DefaultConfirmPeriod
+	}
+	return nil
+}
+
+// confirmSignals attaches the higher timeframe confirmation to each signal,
+// dropping unconfirmed signals unless the spec only annotates. Higher timeframe
+// bars are aggregated from the entry interval so both views share one data set.
+func (g *APIGateway) confirmSignals(ctx context.Context, params market.HistoricalParams, spec strategy.ConfirmSpec, signals []map[string]interface{}) []map[string]interface{} {
+	if len(signals) == 0 {
+		return signals
+	}
+
+	entryLength, _ := market.IntervalDuration(params.Interval)
+	barLength, _ := market.IntervalDuration(spec.Interval)
+
+	var bars []analytics.Candle
+	candles, err := g.fetchCandles(ctx, params.Ticker, params.Interval, params.Days)
+	if err != nil {
+		utils.Error("Confirmation data unavailable for %s: %v", params.Ticker, err)
+	} else {
+		bars = analytics.AggregateCandles(candles, barLength, market.SessionOpenMinute*time.Minute)
+	}
+
+	confirmed := make([]map[string]interface{}, 0, len(signals))
+	for _, signal := range signals {
+		date, _ := signal["date"].(string)
+		signalType, _ := signal["signal_type"].(string)
+
+		confirmation := strategy.Confirmation{Trend: strategy.TrendUnknown, Reason: "confirmation data unavailable"}
+		if signalTime, err := analytics.ParseCandleTime(date); err != nil {
+			confirmation.Reason = "invalid signal date"
+		} else if bars != nil {
+			// The signal is known once its entry bar closes
+			confirmation = strategy.Confirm(bars, barLength, spec.Period, signalType, signalTime.Add(entryLength))
+		}
+		confirmation.Interval = spec.Interval
+
+		if !confirmation.Confirmed && !spec.AnnotateOnly {
+			continue
+		}
+		signal["confirmation"] = confirmation
+		confirmed = append(confirmed, signal)
+	}
+	return confirmed
+}
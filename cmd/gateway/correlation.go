@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// correlationJob is the scheduler job name for the nightly correlation refresh
+const correlationJob = "correlation-refresh"
+
+// defaultCorrelationSchedule refreshes correlations each weekday evening,
+// once the day's daily bars are stored
+const defaultCorrelationSchedule = "30 18 * * 1-5"
+
+// correlationTimeout bounds one correlation computation, or the nightly refresh
+const correlationTimeout = 5 * time.Minute
+
+// correlationMaxAge is how long cached correlations are served if the
+// nightly refresh does not run
+const correlationMaxAge = 24 * time.Hour
+
+// Correlation request limits
+const (
+	maxCorrelationTickers = 50
+	maxCorrelationWindow  = 240 // Sessions; the history it needs stays within MaxHistoricalDays
+	maxCorrelationEntries = 100 // Cached ticker and window combinations
+)
+
+// defaultCorrelationWindows are the windows, in sessions, computed unless
+// the request names others
+var defaultCorrelationWindows = []int{20, 60, 120}
+
+// correlationReport is the correlation matrices of a set of tickers over
+// each window
+type correlationReport struct {
+	Tickers    []string                      `json:"tickers"` // Tickers with daily data
+	Missing    []string                      `json:"missing"` // Tickers without daily data
+	Matrices   []analytics.CorrelationMatrix `json:"matrices"`
+	ComputedAt time.Time                     `json:"computed_at"`
+}
+
+// correlationCache keeps computed reports until the nightly refresh
+type correlationCache struct {
+	mu      sync.Mutex
+	reports map[string]correlationReport
+}
+
+// newCorrelationCache creates an empty cache
+func newCorrelationCache() *correlationCache {
+	return &correlationCache{reports: make(map[string]correlationReport)}
+}
+
+// Get returns a cached report younger than correlationMaxAge
+func (c *correlationCache) Get(key string) (correlationReport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	report, ok := c.reports[key]
+	if !ok || time.Since(report.ComputedAt) > correlationMaxAge {
+		return correlationReport{}, false
+	}
+	return report, true
+}
+
+// Set caches a report, starting over when the cache is full
+func (c *correlationCache) Set(key string, report correlationReport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.reports[key]; !ok && len(c.reports) >= maxCorrelationEntries {
+		c.reports = make(map[string]correlationReport)
+	}
+	c.reports[key] = report
+}
+
+// Reset drops every cached report
+func (c *correlationCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reports = make(map[string]correlationReport)
+}
+
+// correlationKey identifies a report by its tickers and windows
+func correlationKey(tickers []string, windows []int) string {
+	parts := make([]string, len(windows))
+	for i, w := range windows {
+		parts[i] = strconv.Itoa(w)
+	}
+	return strings.Join(tickers, ",") + "|" + strings.Join(parts, ",")
+}
+
+// parseCorrelationWindows reads a comma-separated list of windows in sessions
+func parseCorrelationWindows(value string) ([]int, error) {
+	if value == "" {
+		return defaultCorrelationWindows, nil
+	}
+	seen := make(map[int]bool)
+	var windows []int
+	for _, part := range strings.Split(value, ",") {
+		w, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || w < 2 || w > maxCorrelationWindow {
+			return nil, fmt.Errorf("invalid window %q, expected 2 to %d sessions", part, maxCorrelationWindow)
+		}
+		if !seen[w] {
+			seen[w] = true
+			windows = append(windows, w)
+		}
+	}
+	sort.Ints(windows)
+	return windows, nil
+}
+
+// computeCorrelations fetches daily candles for the tickers and computes
+// their correlation matrix over each window
+func (g *APIGateway) computeCorrelations(ctx context.Context, tickers []string, windows []int) correlationReport {
+	longest := windows[len(windows)-1]
+	// Calendar days holding the longest window's sessions, with room for holidays
+	days := min(longest*7/5+10, market.MaxHistoricalDays)
+
+	report := correlationReport{Tickers: []string{}, Missing: []string{}, ComputedAt: time.Now().UTC()}
+	candles := make(map[string][]analytics.Candle, len(tickers))
+	for _, ticker := range tickers {
+		data, err := g.fetchCandles(ctx, ticker, market.Interval1Day, days)
+		if err != nil {
+			utils.Debug("No daily data for correlations of %s: %v", ticker, err)
+			report.Missing = append(report.Missing, ticker)
+			continue
+		}
+		candles[ticker] = data
+		report.Tickers = append(report.Tickers, ticker)
+	}
+
+	for _, window := range windows {
+		report.Matrices = append(report.Matrices, analytics.Correlations(candles, report.Tickers, window))
+	}
+	return report
+}
+
+// correlationsFor returns the cached report for the tickers and windows,
+// computing it on first use
+func (g *APIGateway) correlationsFor(ctx context.Context, tickers []string, windows []int) correlationReport {
+	key := correlationKey(tickers, windows)
+	if report, ok := g.correlations.Get(key); ok {
+		return report
+	}
+	report := g.computeCorrelations(ctx, tickers, windows)
+	g.correlations.Set(key, report)
+	return report
+}
+
+// scheduleCorrelationRefresh registers the nightly refresh, which drops
+// cached correlations and recomputes the watchlist's. Set
+// CORRELATION_SCHEDULE to a cron expression to change when it runs, or to
+// "off" to disable it.
+func (g *APIGateway) scheduleCorrelationRefresh() {
+	schedule := os.Getenv("CORRELATION_SCHEDULE")
+	if schedule == "" {
+		schedule = defaultCorrelationSchedule
+	}
+	if schedule == "off" {
+		return
+	}
+
+	err := g.scheduler.Add(correlationJob, schedule, market.ExchangeLocation(), correlationTimeout, func(ctx context.Context) error {
+		g.correlations.Reset()
+		tickers := defaultWatchlist()
+		sort.Strings(tickers)
+		report := g.correlationsFor(ctx, tickers, defaultCorrelationWindows)
+		utils.Info("Refreshed correlations of %d watched tickers, %d without daily data", len(report.Tickers), len(report.Missing))
+		return nil
+	})
+	if err != nil {
+		utils.Error("Failed to schedule correlation refresh: %v", err)
+		return
+	}
+	utils.Info("Scheduled correlation refresh (%s)", schedule)
+}
+
+// correlationHandler serves correlation matrices of daily returns between
+// the watched tickers, or ?tickers=, over ?windows= sessions (default
+// 20, 60 and 120)
+func (g *APIGateway) correlationHandler(w http.ResponseWriter, r *http.Request) {
+	tickers := defaultWatchlist()
+	if list := r.URL.Query().Get("tickers"); list != "" {
+		var err error
+		if tickers, err = market.ValidateTickers(strings.Split(list, ",")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(tickers) < 2 || len(tickers) > maxCorrelationTickers {
+		http.Error(w, fmt.Sprintf("correlations need 2 to %d tickers", maxCorrelationTickers), http.StatusBadRequest)
+		return
+	}
+	sort.Strings(tickers)
+
+	windows, err := parseCorrelationWindows(r.URL.Query().Get("windows"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Cached reports are shared by every caller, so computing them is not
+	// charged to this one
+	ctx, cancel := context.WithTimeout(context.Background(), correlationTimeout)
+	defer cancel()
+	report := g.correlationsFor(ctx, tickers, windows)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
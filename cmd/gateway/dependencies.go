@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// Dependencies whose failures are tracked separately
+const (
+	depGRPC     = "grpc"     // The trading service
+	depNATS     = "nats"     // The NATS connection
+	depProvider = "provider" // The market data service and the provider behind it
+)
+
+// dependencies lists the tracked dependencies in the order they are reported
+var dependencies = []string{depGRPC, depNATS, depProvider}
+
+// Defaults for when a dependency counts as failing, overridden by
+// SERVICE_FAILURE_WINDOW, SERVICE_FAILURE_RATE and SERVICE_MIN_FAILURES
+const (
+	defaultFailureWindow = time.Minute
+	defaultFailureRate   = 0.5
+	defaultMinFailures   = 3
+)
+
+// maxWindowOutcomes bounds the outcomes held per dependency under heavy load
+const maxWindowOutcomes = 1000
+
+// modeSeverity orders the service modes from best to worst
+var modeSeverity = map[string]int{"normal": 0, "degraded": 1, "readonly": 2}
+
+// failurePolicy sets when a dependency counts as failing: at least
+// MinFailures of its calls in the last Window failed, and at least Rate of
+// them
+type failurePolicy struct {
+	Window      time.Duration
+	Rate        float64
+	MinFailures int
+}
+
+// failurePolicyFromEnv reads the failure policy
+func failurePolicyFromEnv() failurePolicy {
+	policy := failurePolicy{
+		Window:      defaultFailureWindow,
+		Rate:        defaultFailureRate,
+		MinFailures: defaultMinFailures,
+	}
+	if value := os.Getenv("SERVICE_FAILURE_WINDOW"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			policy.Window = d
+		} else {
+			utils.Warn("Invalid SERVICE_FAILURE_WINDOW %q, using default %s", value, defaultFailureWindow)
+		}
+	}
+	if value := os.Getenv("SERVICE_FAILURE_RATE"); value != "" {
+		if rate, err := strconv.ParseFloat(value, 64); err == nil && rate > 0 && rate <= 1 {
+			policy.Rate = rate
+		} else {
+			utils.Warn("Invalid SERVICE_FAILURE_RATE %q, using default %g", value, defaultFailureRate)
+		}
+	}
+	if value := os.Getenv("SERVICE_MIN_FAILURES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			policy.MinFailures = n
+		} else {
+			utils.Warn("Invalid SERVICE_MIN_FAILURES %q, using default %d", value, defaultMinFailures)
+		}
+	}
+	return policy
+}
+
+// callOutcome is one call to a dependency
+type callOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+// dependencyWindow holds a dependency's recent call outcomes
+type dependencyWindow struct {
+	outcomes    []callOutcome // Oldest first
+	lastError   string
+	lastFailure time.Time
+}
+
+// add records an outcome, dropping those older than the window
+func (d *dependencyWindow) add(now time.Time, err error, window time.Duration) {
+	d.outcomes = append(d.outcomes, callOutcome{at: now, failed: err != nil})
+	if err != nil {
+		d.lastError = err.Error()
+		d.lastFailure = now
+	}
+
+	cutoff := now.Add(-window)
+	drop := 0
+	for drop < len(d.outcomes) && (d.outcomes[drop].at.Before(cutoff) || len(d.outcomes)-drop > maxWindowOutcomes) {
+		drop++
+	}
+	d.outcomes = d.outcomes[drop:]
+}
+
+// counts returns the calls and failures within the window
+func (d *dependencyWindow) counts(now time.Time, window time.Duration) (calls, failures int) {
+	cutoff := now.Add(-window)
+	for _, o := range d.outcomes {
+		if o.at.Before(cutoff) {
+			continue
+		}
+		calls++
+		if o.failed {
+			failures++
+		}
+	}
+	return calls, failures
+}
+
+// failing reports whether the counts breach the policy
+func (p failurePolicy) failing(calls, failures int) bool {
+	return failures >= p.MinFailures && float64(failures) >= p.Rate*float64(calls)
+}
+
+// RecordDependency records the outcome of one call to a dependency and
+// re-derives the service mode. Failures step the mode down at once; on the
+// way back up the recovery policy holds it so it does not flap.
+func (c *DataCache) RecordDependency(dep string, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	window, ok := c.dependencies[dep]
+	if !ok {
+		window = &dependencyWindow{}
+		c.dependencies[dep] = window
+	}
+	window.add(now, err, c.failures.Window)
+	if err != nil {
+		c.healthyStreak = 0
+	} else {
+		c.healthyStreak++
+	}
+
+	mode, failing := c.targetMode(now)
+	description := "System operating normally"
+	if mode != "normal" {
+		description = fmt.Sprintf("System in %s mode: %s failing", mode, strings.Join(failing, ", "))
+	}
+	switch {
+	case modeSeverity[mode] > modeSeverity[c.serviceMode]:
+		c.setMode(mode, description)
+	case modeSeverity[mode] < modeSeverity[c.serviceMode] &&
+		c.healthyStreak >= c.recovery.Successes &&
+		now.Sub(c.lastStatusChange) >= c.recovery.MinDuration:
+		c.setMode(mode, description)
+	}
+}
+
+// targetMode derives the mode from the failing dependencies: read-only when
+// neither the trading service nor NATS can be reached, so nothing fresh can be
+// served, and degraded while any dependency is failing. The caller holds
+// c.mutex.
+func (c *DataCache) targetMode(now time.Time) (string, []string) {
+	var failing []string
+	for _, dep := range dependencies {
+		if window, ok := c.dependencies[dep]; ok && c.failures.failing(window.counts(now, c.failures.Window)) {
+			failing = append(failing, dep)
+		}
+	}
+	switch {
+	case len(failing) == 0:
+		return "normal", nil
+	case hasDependency(failing, depGRPC) && hasDependency(failing, depNATS):
+		return "readonly", failing
+	default:
+		return "degraded", failing
+	}
+}
+
+// hasDependency reports whether dep is in deps
+func hasDependency(deps []string, dep string) bool {
+	for _, f := range deps {
+		if f == dep {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyStatus reports each dependency's calls and failures within the
+// window. The caller holds c.mutex.
+func (c *DataCache) dependencyStatus() map[string]interface{} {
+	now := time.Now()
+	status := make(map[string]interface{}, len(dependencies))
+	for _, dep := range dependencies {
+		report := map[string]interface{}{"status": "ok", "calls": 0, "failures": 0, "failure_rate": 0.0}
+		if window, ok := c.dependencies[dep]; ok {
+			calls, failures := window.counts(now, c.failures.Window)
+			report["calls"] = calls
+			report["failures"] = failures
+			if calls > 0 {
+				report["failure_rate"] = float64(failures) / float64(calls)
+			}
+			if c.failures.failing(calls, failures) {
+				report["status"] = "failing"
+			}
+			if !window.lastFailure.IsZero() {
+				report["last_error"] = window.lastError
+				report["last_failure"] = window.lastFailure.Format(time.RFC3339)
+			}
+		}
+		status[dep] = report
+	}
+	return status
+}
+
+// recordRequest records the outcome of a request over NATS. A failed request
+// counts against NATS only while the connection is down; otherwise it was
+// the service answering it, which for provider requests is the provider.
+func (g *APIGateway) recordRequest(err error, provider bool) {
+	if err != nil && !g.natsClient.GetNATS().IsConnected() {
+		g.cache.RecordDependency(depNATS, err)
+		return
+	}
+	g.cache.RecordDependency(depNATS, nil)
+	if provider {
+		g.cache.RecordDependency(depProvider, err)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// diagnosticTimeout bounds each probe, so one hung dependency does not
+// hold up the report
+const diagnosticTimeout = 5 * time.Second
+
+// diagnosticCacheKey is the sentinel entry written by the cache probe
+const diagnosticCacheKey = "__diagnostics__"
+
+// diagnosticCheck is the outcome of one probe
+type diagnosticCheck struct {
+	Name      string      `json:"name"`
+	Pass      bool        `json:"pass"`
+	LatencyMS float64     `json:"latency_ms"`
+	Error     string      `json:"error,omitempty"`
+	Detail    interface{} `json:"detail,omitempty"`
+}
+
+// diagnosticProbe actively checks one dependency, returning details to report
+type diagnosticProbe struct {
+	name string
+	run  func(ctx context.Context) (interface{}, error)
+}
+
+// diagnosticProbes lists the probes run by /api/diagnostics
+func (g *APIGateway) diagnosticProbes() []diagnosticProbe {
+	return []diagnosticProbe{
+		{"nats", g.probeNATS},
+		{"jetstream", g.probeJetStream},
+		{"grpc", g.probeGRPC},
+		{"provider_clock", g.probeProviderClock},
+		{"cache", g.probeCache},
+	}
+}
+
+// diagnosticsHandler runs every probe concurrently and reports each one's
+// latency and result. Responds 503 when any probe fails.
+func (g *APIGateway) diagnosticsHandler(w http.ResponseWriter, r *http.Request) {
+	probes := g.diagnosticProbes()
+	checks := make([]diagnosticCheck, len(probes))
+
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe diagnosticProbe) {
+			defer wg.Done()
+			checks[i] = runDiagnosticProbe(r.Context(), probe)
+		}(i, probe)
+	}
+	wg.Wait()
+
+	overall, code := "pass", http.StatusOK
+	for _, check := range checks {
+		if !check.Pass {
+			overall, code = "fail", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    overall,
+		"checks":    checks,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// runDiagnosticProbe runs a probe under diagnosticTimeout and times it
+func runDiagnosticProbe(ctx context.Context, probe diagnosticProbe) diagnosticCheck {
+	ctx, cancel := context.WithTimeout(ctx, diagnosticTimeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := probe.run(ctx)
+	check := diagnosticCheck{
+		Name:      probe.name,
+		Pass:      err == nil,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Detail:    detail,
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// probeNATS measures a round trip to the NATS server
+func (g *APIGateway) probeNATS(ctx context.Context) (interface{}, error) {
+	if g.natsClient == nil {
+		return nil, errors.New("NATS is not connected")
+	}
+	nc := g.natsClient.GetNATS()
+	rtt, err := nc.RTT()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"server": nc.ConnectedUrlRedacted(),
+		"rtt_ms": float64(rtt.Microseconds()) / 1000,
+	}, nil
+}
+
+// probeJetStream reads the info of every managed stream
+func (g *APIGateway) probeJetStream(ctx context.Context) (interface{}, error) {
+	if g.natsClient == nil {
+		return nil, errors.New("NATS is not connected")
+	}
+	usage, err := g.natsClient.StreamUsage()
+	if err != nil {
+		return nil, err
+	}
+	streams := make(map[string]uint64, len(usage))
+	for _, u := range usage {
+		streams[u.Name] = u.Messages
+	}
+	return map[string]interface{}{"streams": streams}, nil
+}
+
+// probeGRPC calls the trading service's gRPC health check. A server without
+// the health service still answered, so it passes with its connection state.
+func (g *APIGateway) probeGRPC(ctx context.Context) (interface{}, error) {
+	if g.tradingConn == nil {
+		return nil, errors.New("trading service is not connected")
+	}
+	detail := map[string]interface{}{"state": g.tradingConn.GetState().String()}
+
+	resp, err := healthpb.NewHealthClient(g.tradingConn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		detail["health_service"] = "not implemented"
+		return detail, nil
+	}
+	if err != nil {
+		return detail, err
+	}
+	detail["serving_status"] = resp.GetStatus().String()
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return detail, fmt.Errorf("trading service is %s", resp.GetStatus())
+	}
+	return detail, nil
+}
+
+// probeProviderClock asks the market data service for the provider's market
+// clock, which exercises NATS request/reply, the service and the provider
+// API, and reports the skew between the provider's clock and ours
+func (g *APIGateway) probeProviderClock(ctx context.Context) (interface{}, error) {
+	if g.natsClient == nil {
+		return nil, errors.New("NATS is not connected")
+	}
+	var clock market.MarketClock
+	if err := g.natsClient.Request(ctx, events.SubjectReferenceMarketClock, struct{}{}, &clock); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"clock":   clock,
+		"skew_ms": time.Since(clock.Timestamp).Milliseconds(),
+	}, nil
+}
+
+// probeCache writes a sentinel entry to the response cache and reads it back
+func (g *APIGateway) probeCache(ctx context.Context) (interface{}, error) {
+	sentinel := time.Now().UnixNano()
+	g.cache.CacheHistoricalData(diagnosticCacheKey, sentinel)
+	cached, ok := g.cache.GetCachedHistoricalData(diagnosticCacheKey)
+
+	g.cache.mutex.Lock()
+	delete(g.cache.historicalData, diagnosticCacheKey)
+	entries := len(g.cache.historicalData) + len(g.cache.signals) +
+		len(g.cache.recommendations) + len(g.cache.backtestResults)
+	g.cache.mutex.Unlock()
+
+	if !ok || cached.Data != sentinel {
+		return nil, errors.New("cache did not return the entry just written")
+	}
+	return map[string]interface{}{"entries": entries}, nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+const (
+	// defaultChartPoints suits a chart a few hundred pixels wide
+	defaultChartPoints = 500
+
+	// maxChartPoints matches the most points the trading service returns
+	maxChartPoints = 5000
+)
+
+// equityPoint is a backtest's equity at a date or bar time
+type equityPoint struct {
+	Date   string  `json:"date"`
+	Equity float64 `json:"equity"`
+}
+
+// drawdownPoint is how far equity is below its running peak
+type drawdownPoint struct {
+	Date        string  `json:"date"`
+	Drawdown    float64 `json:"drawdown"`     // Dollars below the peak
+	DrawdownPct float64 `json:"drawdown_pct"` // Percent of the peak; zero while the peak is not above zero
+}
+
+// backtestCurves collects each test's equity curve from a backtest's
+// response. Portfolio curves are equity from the initial capital, by day;
+// single-ticker ones are mark-to-market profit from zero, by bar.
+func backtestCurves(resp *pb.BacktestResponse) map[string][]equityPoint {
+	curves := make(map[string][]equityPoint)
+	add := func(name string, points []*pb.EquityPoint) {
+		if len(points) == 0 {
+			return
+		}
+		curve := make([]equityPoint, 0, len(points))
+		for _, point := range points {
+			curve = append(curve, equityPoint{Date: point.Date, Equity: point.Equity})
+		}
+		sort.SliceStable(curve, func(i, j int) bool { return curve[i].Date < curve[j].Date })
+		curves[name] = curve
+	}
+
+	if len(resp.Portfolio) > 0 {
+		for name, result := range resp.Portfolio {
+			add(name, result.EquityCurve)
+		}
+		return curves
+	}
+	for name, result := range resp.Results {
+		add(name, result.EquityCurve)
+	}
+	return curves
+}
+
+// backtestEquityHandler returns the equity curve of each test of a stored
+// backtest, or of the one named by test, downsampled to at most points
+// points for charting
+func (g *APIGateway) backtestEquityHandler(w http.ResponseWriter, r *http.Request) {
+	curves, points, ok := g.backtestChartCurves(w, r)
+	if !ok {
+		return
+	}
+
+	series := make(map[string][]equityPoint, len(curves))
+	for name, curve := range curves {
+		values := make([]float64, len(curve))
+		for i, point := range curve {
+			values[i] = point.Equity
+		}
+		kept := make([]equityPoint, 0, points)
+		for _, i := range lttb(values, points) {
+			kept = append(kept, curve[i])
+		}
+		series[name] = kept
+	}
+	writeBacktestChart(w, mux.Vars(r)["id"], points, series)
+}
+
+// backtestDrawdownHandler returns how far below its running peak the equity
+// of each test of a stored backtest was, or of the one named by test.
+// Drawdowns are measured on the whole stored curve before downsampling.
+func (g *APIGateway) backtestDrawdownHandler(w http.ResponseWriter, r *http.Request) {
+	curves, points, ok := g.backtestChartCurves(w, r)
+	if !ok {
+		return
+	}
+
+	series := make(map[string][]drawdownPoint, len(curves))
+	for name, curve := range curves {
+		drawdowns := drawdownSeries(curve)
+		values := make([]float64, len(drawdowns))
+		for i, point := range drawdowns {
+			values[i] = point.Drawdown
+		}
+		kept := make([]drawdownPoint, 0, points)
+		for _, i := range lttb(values, points) {
+			kept = append(kept, drawdowns[i])
+		}
+		series[name] = kept
+	}
+	writeBacktestChart(w, mux.Vars(r)["id"], points, series)
+}
+
+// backtestChartCurves reads the equity curves of the stored backtest named
+// in the path, and the points parameter, writing the error response if
+// either cannot be had
+func (g *APIGateway) backtestChartCurves(w http.ResponseWriter, r *http.Request) (map[string][]equityPoint, int, bool) {
+	points := defaultChartPoints
+	if value := r.URL.Query().Get("points"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 3 || n > maxChartPoints {
+			http.Error(w, fmt.Sprintf("points must be between 3 and %d", maxChartPoints), http.StatusBadRequest)
+			return nil, 0, false
+		}
+		points = n
+	}
+
+	id := mux.Vars(r)["id"]
+	data, err := g.artifacts.Load(artifactBacktests, id)
+	if err != nil {
+		artifactError(w, err)
+		return nil, 0, false
+	}
+	var stored struct {
+		Curves map[string][]equityPoint `json:"equity_curves"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil {
+		http.Error(w, fmt.Sprintf("backtest %s is unreadable: %v", id, err), http.StatusInternalServerError)
+		return nil, 0, false
+	}
+	if len(stored.Curves) == 0 {
+		http.Error(w, fmt.Sprintf("backtest %s has no equity curve", id), http.StatusNotFound)
+		return nil, 0, false
+	}
+
+	if test := r.URL.Query().Get("test"); test != "" {
+		curve, exists := stored.Curves[test]
+		if !exists {
+			http.Error(w, fmt.Sprintf("backtest %s has no test %s", id, test), http.StatusNotFound)
+			return nil, 0, false
+		}
+		return map[string][]equityPoint{test: curve}, points, true
+	}
+	return stored.Curves, points, true
+}
+
+// writeBacktestChart writes downsampled series of a stored backtest
+func writeBacktestChart(w http.ResponseWriter, id string, points int, series interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":     id,
+		"points": points,
+		"series": series,
+	})
+}
+
+// drawdownSeries measures how far each point of an equity curve is below
+// the highest equity before it
+func drawdownSeries(curve []equityPoint) []drawdownPoint {
+	drawdowns := make([]drawdownPoint, len(curve))
+	peak := math.Inf(-1)
+	for i, point := range curve {
+		peak = math.Max(peak, point.Equity)
+		drawdowns[i] = drawdownPoint{Date: point.Date, Drawdown: peak - point.Equity}
+		if peak > 0 {
+			drawdowns[i].DrawdownPct = (peak - point.Equity) / peak * 100
+		}
+	}
+	return drawdowns
+}
+
+// lttb downsamples a series to at most threshold points with
+// Largest-Triangle-Three-Buckets, returning the indices kept in order. It
+// keeps the points that best preserve the series' shape when charted,
+// treating points as evenly spaced as bars are, and always keeps the first
+// and last.
+func lttb(values []float64, threshold int) []int {
+	n := len(values)
+	if threshold <= 0 || n <= threshold || n <= 2 {
+		kept := make([]int, n)
+		for i := range kept {
+			kept[i] = i
+		}
+		return kept
+	}
+	if threshold < 3 {
+		return []int{0, n - 1}
+	}
+
+	kept := make([]int, 0, threshold)
+	kept = append(kept, 0)
+	bucketSize := float64(n-2) / float64(threshold-2)
+	a := 0
+	for i := 0; i < threshold-2; i++ {
+		start := int(float64(i)*bucketSize) + 1
+		end := int(float64(i+1)*bucketSize) + 1
+
+		// Average of the next bucket, or the last point for the final bucket
+		nextStart, nextEnd := end, min(int(float64(i+2)*bucketSize)+1, n)
+		if nextStart >= n-1 {
+			nextStart, nextEnd = n-1, n
+		}
+		avgX := float64(nextStart+nextEnd-1) / 2
+		avgY := 0.0
+		for _, v := range values[nextStart:nextEnd] {
+			avgY += v
+		}
+		avgY /= float64(nextEnd - nextStart)
+
+		// Keep the point forming the largest triangle with the last kept
+		// point and the next bucket's average
+		best, bestArea := start, -1.0
+		for j := start; j < end; j++ {
+			area := math.Abs((float64(a)-avgX)*(values[j]-values[a]) - (float64(a)-float64(j))*(avgY-values[a]))
+			if area > bestArea {
+				best, bestArea = j, area
+			}
+		}
+		a = best
+		kept = append(kept, a)
+	}
+	return append(kept, n-1)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// exportTimeout bounds fetching and storing one export
+const exportTimeout = 2 * time.Minute
+
+// exportHistoricalHandler writes a ticker's candles to the object store as CSV
+// and returns a link to download it, so large ranges don't go through the
+// JSON API
+func (g *APIGateway) exportHistoricalHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	days := 30
+	if value := query.Get("days"); value != "" {
+		var err error
+		if days, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "invalid days parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = market.Interval1Day
+	}
+	params, err := market.NormalizeHistoricalParams(query.Get("ticker"), interval, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !g.allowUsage(w, r, usageCandles) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), exportTimeout)
+	defer cancel()
+	candles, err := g.fetchCandles(ctx, params.Ticker, params.Interval, params.Days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	var buf bytes.Buffer
+	out := csv.NewWriter(&buf)
+	out.Write([]string{"time", "open", "high", "low", "close", "volume"})
+	for _, c := range candles {
+		out.Write([]string{
+			c.Time.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(c.Open, 'f', -1, 64),
+			strconv.FormatFloat(c.High, 'f', -1, 64),
+			strconv.FormatFloat(c.Low, 'f', -1, 64),
+			strconv.FormatFloat(c.Close, 'f', -1, 64),
+			strconv.FormatFloat(c.Volume, 'f', -1, 64),
+		})
+	}
+	out.Flush()
+
+	key := fmt.Sprintf("%s%s-%s-%s-%dd.csv", objectExports, time.Now().UTC().Format("20060102T150405.000000000"),
+		unsafeArtifactChars.ReplaceAllString(params.Ticker, "_"), params.Interval, params.Days)
+	if err := g.putObject(ctx, key, buf.Bytes(), "text/csv"); err != nil {
+		utils.Error("Failed to store export %s: %v", key, err)
+		http.Error(w, "failed to store export", http.StatusInternalServerError)
+		return
+	}
+	link, err := g.downloadLink(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	utils.Info("Exported %d %s candles for %s to %s", len(candles), params.Interval, params.Ticker, key)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"download": link,
+		"rows":     len(candles),
+		"size":     buf.Len(),
+	})
+}
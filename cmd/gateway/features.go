@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/features"
+	"github.com/myapp/tradinglab/pkg/jobs"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/parquet"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// Formats feature matrices are exported in
+const (
+	featureFormatCSV     = "csv"
+	featureFormatParquet = "parquet"
+)
+
+// maxFeaturesBody bounds a feature export's request body
+const maxFeaturesBody = 1 << 16
+
+// featureExport is the outcome of a feature export job
+type featureExport struct {
+	Download downloadLink  `json:"download"`
+	Format   string        `json:"format"`
+	Rows     int           `json:"rows"`
+	Columns  []string      `json:"columns"`
+	Size     int           `json:"size"`
+	Spec     features.Spec `json:"spec"`
+	Strategy string        `json:"strategy,omitempty"`
+	Signals  int           `json:"signals,omitempty"` // Signals the strategy generated on the bars
+}
+
+// exportFeaturesHandler queues a job that turns a ticker's bars, indicators
+// and, given a strategy, its signals and their outcomes into a feature
+// matrix for training models outside TradingLab. It takes ticker, interval,
+// days, format (csv or parquet) and strategy parameters, and a JSON body
+// of features.Spec fields plus the strategy's params, e.g. {"lookback": 10,
+// "windows": [5, 20, 50], "label": "direction", "horizon": 3}. The finished
+// job links to the file in the object store.
+func (g *APIGateway) exportFeaturesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	days := 365
+	if value := query.Get("days"); value != "" {
+		var err error
+		if days, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "invalid days parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = market.Interval1Day
+	}
+	params, err := market.NormalizeHistoricalParams(query.Get("ticker"), interval, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = featureFormatCSV
+	}
+	if format != featureFormatCSV && format != featureFormatParquet {
+		http.Error(w, "format must be csv or parquet", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxFeaturesBody))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	var spec features.Spec
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &spec); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := spec.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	spec = spec.WithDefaults()
+
+	// Signals, and their outcomes as labels, need a strategy. The body
+	// also carries its parameters.
+	strategy := query.Get("strategy")
+	var strategyParams map[string]string
+	switch {
+	case strategy != "":
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if strategyParams, err = g.strategyParams(r, strategy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case spec.Label == features.LabelSignalOutcome:
+		http.Error(w, "a strategy is required to label signal outcomes", http.StatusBadRequest)
+		return
+	}
+
+	if !g.allowUsage(w, r, usageCandles) {
+		return
+	}
+
+	job, err := g.backtestJobs.Submit(r.Context(), usageUser(r.Context()), "features", func(jobCtx context.Context) (interface{}, error) {
+		id := jobs.IDFrom(jobCtx)
+		g.publishBacktestProgress(events.BacktestProgress{JobID: id, Stage: events.BacktestRunning})
+
+		ctx, cancel := context.WithTimeout(jobCtx, exportTimeout)
+		defer cancel()
+
+		export, err := g.exportFeatures(ctx, params, format, spec, strategy, strategyParams)
+		switch {
+		case jobCtx.Err() != nil:
+			// Cancellation was announced by whoever cancelled the job
+			return nil, jobCtx.Err()
+		case err != nil:
+			err = fmt.Errorf("error exporting features for %s: %w", params.Ticker, err)
+			g.publishBacktestProgress(events.BacktestProgress{JobID: id, Stage: events.BacktestFailed, Error: err.Error()})
+			return nil, err
+		}
+		g.publishBacktestProgress(events.BacktestProgress{JobID: id, Stage: events.BacktestDone, Percent: 100})
+		return export, nil
+	})
+	switch {
+	case errors.Is(err, jobs.ErrOwnerLimit):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	case err != nil:
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/backtest/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// exportFeatures builds a feature matrix and stores it under exports/
+func (g *APIGateway) exportFeatures(ctx context.Context, params market.HistoricalParams, format string,
+	spec features.Spec, strategy string, strategyParams map[string]string) (featureExport, error) {
+	candles, err := g.fetchCandles(ctx, params.Ticker, params.Interval, params.Days)
+	if err != nil {
+		return featureExport{}, err
+	}
+
+	var signals []features.Signal
+	if strategy != "" {
+		resp, err := g.strategySignals(ctx, strategy, params, strategyParams)
+		if err != nil {
+			return featureExport{}, fmt.Errorf("failed to generate %s signals: %w", strategy, err)
+		}
+		signals = make([]features.Signal, 0, len(resp.Signals))
+		for _, s := range resp.Signals {
+			t, err := analytics.ParseCandleTime(s.Date)
+			if err != nil {
+				continue
+			}
+			direction := 1
+			if strings.EqualFold(s.SignalType, "SHORT") {
+				direction = -1
+			}
+			signals = append(signals, features.Signal{Time: t, Direction: direction, Entry: s.EntryPrice, Stop: s.Stoploss})
+		}
+	}
+
+	matrix, err := features.Build(candles, signals, spec)
+	if err != nil {
+		return featureExport{}, err
+	}
+
+	var buf bytes.Buffer
+	contentType := "text/csv"
+	if format == featureFormatParquet {
+		contentType = parquet.ContentType
+		err = matrix.WriteParquet(&buf)
+	} else {
+		err = matrix.WriteCSV(&buf)
+	}
+	if err != nil {
+		return featureExport{}, err
+	}
+
+	key := fmt.Sprintf("%s%s-%s-%s-%dd-features.%s", objectExports, time.Now().UTC().Format("20060102T150405.000000000"),
+		unsafeArtifactChars.ReplaceAllString(params.Ticker, "_"), params.Interval, params.Days, format)
+	if err := g.putObject(ctx, key, buf.Bytes(), contentType); err != nil {
+		utils.Error("Failed to store export %s: %v", key, err)
+		return featureExport{}, fmt.Errorf("failed to store export")
+	}
+	link, err := g.downloadLink(key)
+	if err != nil {
+		return featureExport{}, err
+	}
+	utils.Info("Exported %d feature rows for %s %s to %s", len(matrix.Rows), params.Ticker, params.Interval, key)
+
+	return featureExport{
+		Download: link,
+		Format:   format,
+		Rows:     len(matrix.Rows),
+		Columns:  append([]string{"time"}, matrix.Columns...),
+		Size:     buf.Len(),
+		Spec:     spec,
+		Strategy: strategy,
+		Signals:  len(signals),
+	}, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/fundamentals"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/secrets"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// fundamentalsTTL is how long a fundamentals snapshot is served before refetching
+const fundamentalsTTL = 24 * time.Hour
+
+// fundamentalsTimeout bounds a single fundamentals fetch
+const fundamentalsTimeout = 15 * time.Second
+
+// alphaVantageKey is the secret name of the Alpha Vantage API key
+const alphaVantageKey = "ALPHA_VANTAGE_API_KEY"
+
+// newFundamentalsStore creates the fundamentals store in FUNDAMENTALS_DIR.
+// Snapshots are fetched from Alpha Vantage when ALPHA_VANTAGE_API_KEY is
+// available from the secrets backend; otherwise only previously stored
+// snapshots are served.
+func newFundamentalsStore() *fundamentals.Store {
+	dir := os.Getenv("FUNDAMENTALS_DIR")
+	if dir == "" {
+		dir = "fundamentals"
+	}
+
+	var fetch fundamentals.Fetcher
+	if provider := newAlphaVantageProvider(); provider != nil {
+		fetch = provider.GetFundamentals
+	} else {
+		utils.Warn("%s not set, serving stored fundamentals only", alphaVantageKey)
+	}
+
+	return fundamentals.NewStore(dir, fundamentalsTTL, fetch)
+}
+
+// newAlphaVantageProvider creates an Alpha Vantage provider keyed from the
+// secrets backend, reloading the key when it is rotated. It returns nil
+// when no key is available.
+func newAlphaVantageProvider() *market.AlphaVantageProvider {
+	store, err := secrets.FromEnv()
+	if err != nil {
+		utils.Error("Invalid secrets configuration: %v", err)
+		return nil
+	}
+
+	ctx := context.Background()
+	key, err := store.Get(ctx, alphaVantageKey)
+	if err != nil {
+		if !errors.Is(err, secrets.ErrNotFound) {
+			utils.Error("Failed to load %s from %s secrets: %v", alphaVantageKey, store.Name(), err)
+		}
+		return nil
+	}
+	provider, err := market.NewAlphaVantageProvider(key)
+	if err != nil {
+		return nil
+	}
+
+	store.Watch(ctx, []string{alphaVantageKey}, func(values map[string]string) {
+		if err := provider.SetAPIKey(values[alphaVantageKey]); err != nil {
+			utils.Error("Ignoring rotated Alpha Vantage key: %v", err)
+		} else {
+			utils.Info("Reloaded Alpha Vantage key")
+		}
+	})
+	return provider
+}
+
+// fundamentalsHandler returns the latest fundamentals for a ticker, or every
+// stored snapshot with history=true
+func (g *APIGateway) fundamentalsHandler(w http.ResponseWriter, r *http.Request) {
+	ticker, err := market.ValidateTicker(r.URL.Query().Get("ticker"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if market.IsForexPair(ticker) {
+		http.Error(w, "fundamentals are not available for forex pairs", http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("history") == "true" {
+		history, err := g.fundamentals.History(ticker)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if history == nil {
+			history = []market.Fundamentals{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(history)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), fundamentalsTimeout)
+	defer cancel()
+
+	snapshot, err := g.fundamentals.Get(ctx, ticker)
+	if err != nil {
+		if errors.Is(err, fundamentals.ErrUnavailable) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
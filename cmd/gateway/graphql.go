@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/auth"
+	"github.com/myapp/tradinglab/pkg/graphql"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/recommendation"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// graphqlTimeout bounds one GraphQL request, batches included
+const graphqlTimeout = 30 * time.Second
+
+// graphqlMaxBatch caps the number of operations in one batched request
+const graphqlMaxBatch = 20
+
+// graphqlMaxBody caps the size of a GraphQL request body
+const graphqlMaxBody = 1 << 20
+
+// graphqlMaxComplexity caps the estimated cost of one request, batches
+// included, so aliases cannot fan one query out into many backtests
+const graphqlMaxComplexity = 200
+
+// graphqlMaxParallel caps the root fields resolving at once across requests
+const graphqlMaxParallel = 8
+
+// Complexity of the fields that call the trading service
+const (
+	candlesCost  = 2
+	signalsCost  = 5
+	backtestCost = 10
+)
+
+// graphqlSchema exposes tickers, candles, signals, backtests and the
+// portfolio as a graph. Fields take the same arguments and defaults as the
+// equivalent REST endpoints; results use the same snake_case field names.
+func (g *APIGateway) graphqlSchema() *graphql.Schema {
+	candles := &graphql.Field{Resolve: g.resolveCandles, Cost: candlesCost}
+	signals := &graphql.Field{Resolve: g.resolveSignals, Cost: signalsCost}
+	backtest := &graphql.Field{Resolve: g.resolveBacktest, Cost: backtestCost}
+
+	ticker := &graphql.Object{
+		Name: "Ticker",
+		Fields: map[string]*graphql.Field{
+			"symbol": {},
+			"reference": {Resolve: func(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error) {
+				if c, ok := g.reference.Get(parentTicker(parent)); ok {
+					return c, nil
+				}
+				return nil, nil
+			}},
+			"candles":  candles,
+			"signals":  signals,
+			"backtest": backtest,
+		},
+	}
+
+	portfolio := &graphql.Object{
+		Name: "Portfolio",
+		Fields: map[string]*graphql.Field{
+			"status": {Resolve: func(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error) {
+				return g.risk.Status(), nil
+			}},
+			"positions": {Resolve: func(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error) {
+				return g.risk.Positions(), nil
+			}},
+			"closed_positions": {Resolve: func(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error) {
+				return g.risk.ClosedPositions(), nil
+			}},
+		},
+	}
+
+	return &graphql.Schema{MaxParallel: graphqlMaxParallel, Query: &graphql.Object{
+		Name: "Query",
+		Fields: map[string]*graphql.Field{
+			"tickers": {Type: ticker, Size: len(defaultWatchlist()), Resolve: func(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error) {
+				watchlist := defaultWatchlist()
+				tickers := make([]map[string]interface{}, 0, len(watchlist))
+				for _, symbol := range watchlist {
+					tickers = append(tickers, map[string]interface{}{"symbol": symbol})
+				}
+				return tickers, nil
+			}},
+			"ticker": {Type: ticker, Resolve: func(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error) {
+				symbol, err := market.ValidateTicker(graphql.String(args, "symbol", ""))
+				if err != nil {
+					return nil, fmt.Errorf("symbol argument: %w", err)
+				}
+				return map[string]interface{}{"symbol": symbol}, nil
+			}},
+			"candles":  candles,
+			"signals":  signals,
+			"backtest": backtest,
+			"portfolio": {Type: portfolio, Resolve: func(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error) {
+				return struct{}{}, nil
+			}},
+			"recommendations": {Resolve: func(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error) {
+				return g.recommendations.Find(recommendation.Query{
+					Ticker:   graphql.String(args, "ticker", ""),
+					Strategy: graphql.String(args, "strategy", ""),
+					Status:   graphql.String(args, "status", ""),
+				}), nil
+			}},
+		},
+	}}
+}
+
+// parentTicker returns the symbol of a Ticker parent, if any
+func parentTicker(parent interface{}) string {
+	if t, ok := parent.(map[string]interface{}); ok {
+		symbol, _ := t["symbol"].(string)
+		return symbol
+	}
+	return ""
+}
+
+// historicalArgs reads the ticker, interval and days arguments shared by
+// candles, signals and backtests, taking the ticker from a Ticker parent
+func historicalArgs(parent interface{}, args map[string]interface{}) (market.HistoricalParams, error) {
+	ticker := graphql.String(args, "ticker", parentTicker(parent))
+	if ticker == "" {
+		return market.HistoricalParams{}, fmt.Errorf("ticker argument is required")
+	}
+	return market.NormalizeHistoricalParams(ticker, graphql.String(args, "interval", "15min"), graphql.Int(args, "days", 30))
+}
+
+// resolveCandles returns historical candles, oldest first
+func (g *APIGateway) resolveCandles(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error) {
+	params, err := historicalArgs(parent, args)
+	if err != nil {
+		return nil, err
+	}
+	if g.usage.Exceeded(usageUser(ctx), usageCandles) {
+		return nil, errUsageLimit(usageCandles, g.usage.limits.Candles)
+	}
+	return g.fetchCandles(ctx, params.Ticker, params.Interval, params.Days)
+}
+
+// resolveSignals generates strategy signals, checked against portfolio risk limits
+func (g *APIGateway) resolveSignals(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error) {
+	params, err := historicalArgs(parent, args)
+	if err != nil {
+		return nil, err
+	}
+	strategy := graphql.String(args, "strategy", "RedCandle")
+	values, _ := args["params"].(map[string]interface{})
+	strategyParams, err := g.strategies.Resolve(strategy, values)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := g.strategySignals(ctx, strategy, params, strategyParams)
+	if err != nil {
+		return nil, fmt.Errorf("error generating signals: %w", err)
+	}
+
+	signals := make([]map[string]interface{}, 0, len(resp.Signals))
+	for _, signal := range resp.Signals {
+		signals = append(signals, map[string]interface{}{
+			"date":        signal.Date,
+			"signal_type": signal.SignalType,
+			"entry_price": signal.EntryPrice,
+			"stoploss":    signal.Stoploss,
+		})
+	}
+	return g.applyRiskChecks(params.Ticker, signals), nil
+}
+
+// resolveBacktest runs a backtest and lists results by profit target,
+// sorted by name
+func (g *APIGateway) resolveBacktest(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error) {
+	if !auth.FromContext(ctx).Can(auth.PermBacktest) {
+		return nil, fmt.Errorf("backtests need the %s permission", auth.PermBacktest)
+	}
+	if g.usage.Exceeded(usageUser(ctx), usageBacktests) {
+		return nil, errUsageLimit(usageBacktests, g.usage.limits.Backtests)
+	}
+	params, err := historicalArgs(parent, args)
+	if err != nil {
+		return nil, err
+	}
+	strategy := graphql.String(args, "strategy", "RedCandle")
+	if _, isPlugin := g.plugins.Get(strategy); isPlugin {
+		return nil, fmt.Errorf("backtests are not supported for plugin strategies")
+	}
+	values, _ := args["params"].(map[string]interface{})
+	strategyParams, err := g.strategies.Resolve(strategy, values)
+	if err != nil {
+		return nil, err
+	}
+	benchmark, err := market.ValidateTicker(graphql.String(args, "benchmark", "SPY"))
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string][]float64, 3)
+	for _, name := range []string{"profit_targets", "risk_reward_ratios", "profit_targets_dollar"} {
+		if targets[name] = graphql.Floats(args, name); len(targets[name]) > maxBacktestTargets {
+			return nil, fmt.Errorf("%s accepts at most %d values", name, maxBacktestTargets)
+		}
+	}
+
+	req := &pb.BacktestRequest{
+		Ticker:              params.Ticker,
+		Days:                int32(params.Days),
+		Strategy:            g.strategies.EngineName(strategy),
+		Interval:            params.Interval,
+		ProfitTargets:       targets["profit_targets"],
+		RiskRewardRatios:    targets["risk_reward_ratios"],
+		ProfitTargetsDollar: targets["profit_targets_dollar"],
+		Parameters:          strategyParams,
+		Benchmark:           benchmark,
+		Costs:               costModelProto(g.risk.Costs()),
+	}
+	if session := g.sessionFilter(strategy, params.Interval); session != nil {
+		req.SessionFiltered = true
+		req.SessionWindows = sessionWindows(session, params.Days, time.Now())
+	}
+	value, err := g.runQueued(ctx, "backtest", func(ctx context.Context) (interface{}, error) {
+		return g.tradingClient.RunBacktest(ctx, req)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error running backtest: %w", err)
+	}
+	resp := value.(*pb.BacktestResponse)
+
+	results := make([]map[string]interface{}, 0, len(resp.Results))
+	for name, result := range resp.Results {
+		entry := backtestResult(result)
+		entry["name"] = name
+		results = append(results, entry)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i]["name"].(string) < results[j]["name"].(string)
+	})
+	return results, nil
+}
+
+// graphqlHandler executes GraphQL queries. POST takes a request object, or
+// an array of them to batch several operations into one round trip; GET
+// takes query, variables and operationName parameters.
+func (g *APIGateway) graphqlHandler(w http.ResponseWriter, r *http.Request) {
+	var requests []graphql.Request
+	batched := false
+
+	if r.Method == http.MethodGet {
+		req := graphql.Request{
+			Query:         r.URL.Query().Get("query"),
+			OperationName: r.URL.Query().Get("operationName"),
+		}
+		if raw := r.URL.Query().Get("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+				http.Error(w, "variables must be a JSON object", http.StatusBadRequest)
+				return
+			}
+		}
+		requests = append(requests, req)
+	} else {
+		body, err := io.ReadAll(io.LimitReader(r.Body, graphqlMaxBody))
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		body = bytes.TrimSpace(body)
+		batched = len(body) > 0 && body[0] == '['
+		if batched {
+			err = json.Unmarshal(body, &requests)
+		} else {
+			var req graphql.Request
+			err = json.Unmarshal(body, &req)
+			requests = append(requests, req)
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(requests) == 0 || len(requests) > graphqlMaxBatch {
+		http.Error(w, fmt.Sprintf("expected between 1 and %d operations", graphqlMaxBatch), http.StatusBadRequest)
+		return
+	}
+	complexity := 0
+	for _, req := range requests {
+		if req.Query == "" {
+			http.Error(w, "query is required", http.StatusBadRequest)
+			return
+		}
+		// Documents that do not parse are reported by Execute as usual
+		cost, err := g.graphql.Complexity(req)
+		if errors.Is(err, graphql.ErrTooDeep) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		complexity += cost
+	}
+	if complexity > graphqlMaxComplexity {
+		http.Error(w, fmt.Sprintf("query complexity %d exceeds the limit of %d", complexity, graphqlMaxComplexity), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), graphqlTimeout)
+	defer cancel()
+
+	responses := make([]graphql.Response, len(requests))
+	done := make(chan struct{})
+	for i, req := range requests {
+		go func(i int, req graphql.Request) {
+			responses[i] = g.graphql.Execute(ctx, req)
+			done <- struct{}{}
+		}(i, req)
+	}
+	for range requests {
+		<-done
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if batched {
+		json.NewEncoder(w).Encode(responses)
+		return
+	}
+	json.NewEncoder(w).Encode(responses[0])
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// grpcStreamBuffer is how many updates are held for a slow gRPC subscriber
+// before NATS starts dropping them
+const grpcStreamBuffer = 256
+
+// grpcStreamTypes are the subscription types available over gRPC streaming
+var grpcStreamTypes = map[string]bool{"market": true, "bars": true, "book": true, "trades": true, "analytics": true}
+
+// marketDataServer streams live market data to backend services over gRPC
+type marketDataServer struct {
+	pb.UnimplementedMarketDataServiceServer
+	g *APIGateway
+}
+
+// newGRPCServer creates the gateway's gRPC server: live market data streams
+// and the trading service, reachable natively and over gRPC-Web
+func (g *APIGateway) newGRPCServer() *grpc.Server {
+	server := grpc.NewServer()
+	pb.RegisterMarketDataServiceServer(server, &marketDataServer{g: g})
+	pb.RegisterTradingServiceServer(server, &tradingProxy{g: g})
+	return server
+}
+
+// serveGRPC serves native gRPC on GRPC_LISTEN_ADDR (default :5001; "off"
+// disables it, leaving gRPC-Web only)
+func (g *APIGateway) serveGRPC() {
+	addr := os.Getenv("GRPC_LISTEN_ADDR")
+	if addr == "" {
+		addr = ":5001"
+	}
+	if addr == "off" {
+		return
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		utils.Error("Failed to listen for gRPC on %s: %v", addr, err)
+		return
+	}
+
+	go func() {
+		utils.Info("gRPC listening on %s", addr)
+		if err := g.grpcServer.Serve(lis); err != nil {
+			utils.Error("gRPC server error: %v", err)
+		}
+	}()
+}
+
+// StreamMarketData forwards live events for the subscribed tickers and types
+// until the caller cancels
+func (s *marketDataServer) StreamMarketData(req *pb.MarketDataSubscription, stream pb.MarketDataService_StreamMarketDataServer) error {
+	types := req.Types
+	if len(types) == 0 {
+		types = []string{"market"}
+	}
+	tickers, err := market.ValidateTickers(req.Tickers)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(tickers) == 0 {
+		tickers = []string{"*"}
+	}
+
+	msgs := make(chan *nats.Msg, grpcStreamBuffer)
+	var subs []*nats.Subscription
+	defer func() {
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
+	}()
+
+	for _, streamType := range types {
+		if !grpcStreamTypes[streamType] {
+			return status.Errorf(codes.InvalidArgument, "unsupported type %q", streamType)
+		}
+		for _, ticker := range tickers {
+			subject, err := wsSubject(streamType, ticker, req.Interval, "")
+			if err != nil {
+				return status.Error(codes.InvalidArgument, err.Error())
+			}
+			sub, err := s.g.natsClient.GetNATS().ChanSubscribe(subject, msgs)
+			if err != nil {
+				return status.Errorf(codes.Unavailable, "subscribing to %s: %v", subject, err)
+			}
+			subs = append(subs, sub)
+		}
+	}
+	utils.Info("gRPC subscriber streaming %v for %v", types, tickers)
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-msgs:
+			update, err := marketDataUpdate(msg)
+			if err != nil {
+				utils.Debug("Dropping undecodable message on %s: %v", msg.Subject, err)
+				continue
+			}
+			if err := stream.Send(update); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// marketDataUpdate maps a NATS market event to a streamed update
+func marketDataUpdate(msg *nats.Msg) (*pb.MarketDataUpdate, error) {
+	data, err := events.Decode(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// market.<type>.<ticker>[.<interval>]
+	tokens := strings.Split(msg.Subject, ".")
+	if len(tokens) < 3 {
+		return nil, fmt.Errorf("unexpected subject %s", msg.Subject)
+	}
+	update := &pb.MarketDataUpdate{
+		Subject: msg.Subject,
+		Type:    tokens[1],
+		Ticker:  tokens[2],
+		Payload: data,
+	}
+	if update.Type == "live" {
+		update.Type = "market"
+	}
+	if update.Type != "market" && update.Type != "bars" {
+		return update, nil
+	}
+
+	// Type mismatches leave the affected fields unset
+	var bar struct {
+		Timestamp string  `json:"timestamp"`
+		Price     float64 `json:"price"`
+		Open      float64 `json:"open"`
+		High      float64 `json:"high"`
+		Low       float64 `json:"low"`
+		Close     float64 `json:"close"`
+		Volume    int64   `json:"volume"`
+		Interval  string  `json:"interval"`
+		Source    string  `json:"source"`
+	}
+	json.Unmarshal(data, &bar)
+	update.Timestamp = bar.Timestamp
+	update.Price, update.Open, update.High, update.Low, update.Close = bar.Price, bar.Open, bar.High, bar.Low, bar.Close
+	update.Volume = bar.Volume
+	update.Interval = bar.Interval
+	update.Source = bar.Source
+	return update, nil
+}
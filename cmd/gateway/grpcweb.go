@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/myapp/tradinglab/pkg/auth"
+	"github.com/myapp/tradinglab/pkg/jobs"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// gRPC-Web content types. The text variant base64-encodes the body for
+// clients that cannot read binary streams.
+const (
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+)
+
+// grpcWebTrailerFlag marks the frame carrying trailers at the end of a gRPC-Web response
+const grpcWebTrailerFlag = 0x80
+
+// grpcWebHeaders are the request headers browser clients send with gRPC-Web calls
+const grpcWebHeaders = "Content-Type, X-Grpc-Web, X-User-Agent, Grpc-Timeout, Authorization"
+
+// tradingProxy serves the trading service on the gateway's gRPC server by
+// forwarding each call to the trading service
+type tradingProxy struct {
+	pb.UnimplementedTradingServiceServer
+	g *APIGateway
+}
+
+func (p *tradingProxy) GetHistoricalData(ctx context.Context, req *pb.HistoricalDataRequest) (*pb.HistoricalDataResponse, error) {
+	return p.g.tradingClient.GetHistoricalData(ctx, req)
+}
+
+func (p *tradingProxy) GenerateSignals(ctx context.Context, req *pb.SignalRequest) (*pb.SignalResponse, error) {
+	return p.g.tradingClient.GenerateSignals(ctx, req)
+}
+
+func (p *tradingProxy) RunBacktest(ctx context.Context, req *pb.BacktestRequest) (*pb.BacktestResponse, error) {
+	value, err := p.g.runQueued(ctx, "backtest", func(ctx context.Context) (interface{}, error) {
+		return p.g.tradingClient.RunBacktest(ctx, req)
+	})
+	if err != nil {
+		return nil, queueStatus(err)
+	}
+	return value.(*pb.BacktestResponse), nil
+}
+
+func (p *tradingProxy) GetOptionsRecommendations(ctx context.Context, req *pb.RecommendationRequest) (*pb.RecommendationResponse, error) {
+	return p.g.tradingClient.GetOptionsRecommendations(ctx, req)
+}
+
+func (p *tradingProxy) RunOptionsBacktest(ctx context.Context, req *pb.OptionsBacktestRequest) (*pb.OptionsBacktestResponse, error) {
+	value, err := p.g.runQueued(ctx, "options", func(ctx context.Context) (interface{}, error) {
+		return p.g.tradingClient.RunOptionsBacktest(ctx, req)
+	})
+	if err != nil {
+		return nil, queueStatus(err)
+	}
+	return value.(*pb.OptionsBacktestResponse), nil
+}
+
+// queueStatus gives a refusal from the backtest pool a gRPC status, passing
+// other errors through
+func queueStatus(err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrOwnerLimit), errors.Is(err, jobs.ErrQueueFull):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, jobs.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return err
+}
+
+// grpcWebPermission returns the permission a gRPC-Web method needs
+func grpcWebPermission(method string) string {
+	switch method {
+	case "/trading.TradingService/RunBacktest", "/trading.TradingService/RunOptionsBacktest":
+		return auth.PermBacktest
+	}
+	return auth.PermRead
+}
+
+// grpcWebHandler serves gRPC-Web calls from browsers, including server
+// streaming, by translating them for the gateway's gRPC server
+func (g *APIGateway) grpcWebHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "Grpc-Status, Grpc-Message, Grpc-Status-Details-Bin")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", grpcWebHeaders)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if r.Method != http.MethodPost || !strings.HasPrefix(contentType, grpcWebContentType) {
+		http.Error(w, "expected a gRPC-Web request", http.StatusUnsupportedMediaType)
+		return
+	}
+	text := strings.HasPrefix(contentType, grpcWebTextContentType)
+
+	// Browser calls get the same roles as the REST API
+	r = g.authenticate(r)
+	if !requirePermission(w, r, grpcWebPermission(r.URL.Path)) {
+		return
+	}
+
+	// Streams outlive the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	// Present the call to the gRPC server as native gRPC over HTTP/2
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+	req.Header.Set("Content-Type", "application/grpc"+strings.TrimPrefix(strings.TrimPrefix(contentType, grpcWebTextContentType), grpcWebContentType))
+	if text {
+		req.Body = io.NopCloser(base64.NewDecoder(base64.StdEncoding, r.Body))
+	}
+
+	resp := &grpcWebResponse{w: w, header: make(http.Header), text: text, contentType: contentType}
+	g.grpcServer.ServeHTTP(resp, req)
+	resp.finish()
+}
+
+// grpcWebResponse adapts the gRPC server's HTTP/2 response to gRPC-Web,
+// moving trailers into a final frame of the body
+type grpcWebResponse struct {
+	w           http.ResponseWriter
+	header      http.Header // What the gRPC server sets, trailers included
+	text        bool
+	contentType string
+	wroteHeader bool
+}
+
+func (r *grpcWebResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *grpcWebResponse) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+
+	dst := r.w.Header()
+	for name, values := range r.header {
+		if name == "Trailer" || strings.HasPrefix(name, http.TrailerPrefix) {
+			continue
+		}
+		dst[name] = values
+	}
+	dst.Set("Content-Type", r.contentType)
+	r.w.WriteHeader(code)
+}
+
+func (r *grpcWebResponse) Write(p []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.text {
+		return r.w.Write(p)
+	}
+	if _, err := r.w.Write([]byte(base64.StdEncoding.EncodeToString(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (r *grpcWebResponse) Flush() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(r.w).Flush()
+}
+
+// finish writes the trailers the gRPC server set as the closing frame
+func (r *grpcWebResponse) finish() {
+	var trailers bytes.Buffer
+	for _, declared := range r.header.Values("Trailer") {
+		for _, name := range strings.Split(declared, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			for _, value := range r.header.Values(name) {
+				trailers.WriteString(strings.ToLower(name) + ": " + value + "\r\n")
+			}
+		}
+	}
+	for name, values := range r.header {
+		if !strings.HasPrefix(name, http.TrailerPrefix) {
+			continue
+		}
+		for _, value := range values {
+			trailers.WriteString(strings.ToLower(strings.TrimPrefix(name, http.TrailerPrefix)) + ": " + value + "\r\n")
+		}
+	}
+
+	frame := make([]byte, 5, 5+trailers.Len())
+	frame[0] = grpcWebTrailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(trailers.Len()))
+	r.Write(append(frame, trailers.Bytes()...))
+	r.Flush()
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/reference"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// heatmapBaselineDays is the daily history behind previous closes and
+// average volumes
+const heatmapBaselineDays = 30
+
+// heatmapVolumeSessions is how many sessions the average daily volume covers
+const heatmapVolumeSessions = 20
+
+// Bases a heatmap tile's change is measured from
+const (
+	heatmapBasisPreviousClose = "previous_close"
+	heatmapBasisOpen          = "open" // Until the previous close is known
+)
+
+// heatmapTile is one ticker's move in the current session
+type heatmapTile struct {
+	Ticker        string    `json:"ticker"`
+	Sector        string    `json:"sector"`
+	Last          float64   `json:"last"`
+	ChangePercent float64   `json:"change_percent"`
+	Basis         string    `json:"basis"`
+	Volume        int64     `json:"volume"`                 // Volume so far this session
+	VolumeRatio   float64   `json:"volume_ratio,omitempty"` // Session volume over the average daily volume
+	Session       string    `json:"session"`
+	Updated       time.Time `json:"updated"`
+}
+
+// heatmapSector groups the tiles of one sector
+type heatmapSector struct {
+	Sector        string        `json:"sector"`
+	ChangePercent float64       `json:"change_percent"`         // Mean of its tiles
+	VolumeRatio   float64       `json:"volume_ratio,omitempty"` // Mean of its tiles with a ratio
+	Tiles         []heatmapTile `json:"tiles"`
+}
+
+// heatmap is the market overview of the watched tickers
+type heatmap struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Tiles       []heatmapTile   `json:"tiles,omitempty"`   // By ticker, unless grouped
+	Sectors     []heatmapSector `json:"sectors,omitempty"` // By sector, when grouped
+	Missing     []string        `json:"missing"`           // Tickers without a live quote
+}
+
+// heatmapBaseline is what a ticker's session is compared against
+type heatmapBaseline struct {
+	session   string // Market date the baseline precedes
+	prevClose float64
+	avgVolume float64
+}
+
+// heatmapBaselines caches each ticker's baseline for a session, computed
+// from stored daily data once per session
+type heatmapBaselines struct {
+	mu        sync.Mutex
+	baselines map[string]heatmapBaseline
+}
+
+// newHeatmapBaselines creates an empty baseline cache
+func newHeatmapBaselines() *heatmapBaselines {
+	return &heatmapBaselines{baselines: make(map[string]heatmapBaseline)}
+}
+
+// heatmapBaseline returns the previous close and average daily volume of
+// ticker before a session, fetching daily candles the first time
+func (g *APIGateway) heatmapBaseline(ctx context.Context, ticker, session string) (heatmapBaseline, bool) {
+	g.heatmap.mu.Lock()
+	b, ok := g.heatmap.baselines[ticker]
+	g.heatmap.mu.Unlock()
+	if ok && b.session == session {
+		return b, true
+	}
+
+	candles, err := g.fetchCandles(ctx, ticker, market.Interval1Day, heatmapBaselineDays)
+	if err != nil {
+		utils.Debug("No heatmap baseline for %s: %v", ticker, err)
+		return heatmapBaseline{}, false
+	}
+
+	// Daily candles are dated by session; today's may already be stored
+	b = heatmapBaseline{session: session}
+	var volume float64
+	sessions := 0
+	for i := len(candles) - 1; i >= 0 && sessions < heatmapVolumeSessions; i-- {
+		if candles[i].Time.Format(market.SessionDateLayout) >= session {
+			continue
+		}
+		if b.prevClose == 0 {
+			b.prevClose = candles[i].Close
+		}
+		volume += candles[i].Volume
+		sessions++
+	}
+	if sessions > 0 {
+		b.avgVolume = volume / float64(sessions)
+	}
+
+	g.heatmap.mu.Lock()
+	g.heatmap.baselines[ticker] = b
+	g.heatmap.mu.Unlock()
+	return b, true
+}
+
+// heatmapTileFor computes a ticker's tile from its cached quote
+func (g *APIGateway) heatmapTileFor(ctx context.Context, q consolidatedQuote) heatmapTile {
+	tile := heatmapTile{
+		Ticker:  q.Ticker,
+		Sector:  reference.Unclassified,
+		Last:    q.Last,
+		Basis:   heatmapBasisOpen,
+		Volume:  q.SessionVolume,
+		Session: q.Session,
+		Updated: q.Updated,
+	}
+	if c, ok := g.reference.Get(q.Ticker); ok && c.Sector != "" {
+		tile.Sector = c.Sector
+	}
+
+	base := q.Open
+	if b, ok := g.heatmapBaseline(ctx, q.Ticker, q.Session); ok {
+		if b.prevClose > 0 {
+			base, tile.Basis = b.prevClose, heatmapBasisPreviousClose
+		}
+		if b.avgVolume > 0 {
+			tile.VolumeRatio = float64(q.SessionVolume) / b.avgVolume
+		}
+	}
+	if base > 0 {
+		tile.ChangePercent = (q.Last - base) / base * 100
+	}
+	return tile
+}
+
+// groupHeatmapBySector groups tiles by sector, sectors by name
+func groupHeatmapBySector(tiles []heatmapTile) []heatmapSector {
+	bySector := make(map[string]*heatmapSector)
+	var names []string
+	for _, tile := range tiles {
+		s, ok := bySector[tile.Sector]
+		if !ok {
+			s = &heatmapSector{Sector: tile.Sector}
+			bySector[tile.Sector] = s
+			names = append(names, tile.Sector)
+		}
+		s.Tiles = append(s.Tiles, tile)
+	}
+	sort.Strings(names)
+
+	sectors := make([]heatmapSector, 0, len(names))
+	for _, name := range names {
+		s := bySector[name]
+		ratios := 0
+		for _, tile := range s.Tiles {
+			s.ChangePercent += tile.ChangePercent
+			if tile.VolumeRatio > 0 {
+				s.VolumeRatio += tile.VolumeRatio
+				ratios++
+			}
+		}
+		s.ChangePercent /= float64(len(s.Tiles))
+		if ratios > 0 {
+			s.VolumeRatio /= float64(ratios)
+		}
+		sectors = append(sectors, *s)
+	}
+	return sectors
+}
+
+// heatmapHandler serves percent change and volume ratio for the watched
+// tickers, or ?tickers=, from the live quote cache. Changes are measured
+// from the previous close. group=sector groups the tiles by sector.
+func (g *APIGateway) heatmapHandler(w http.ResponseWriter, r *http.Request) {
+	tickers := defaultWatchlist()
+	if list := r.URL.Query().Get("tickers"); list != "" {
+		var err error
+		if tickers, err = market.ValidateTickers(strings.Split(list, ",")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	group := r.URL.Query().Get("group")
+	if group != "" && group != "sector" {
+		http.Error(w, "group must be sector", http.StatusBadRequest)
+		return
+	}
+
+	// Baselines are shared by every caller, so fetching them is not charged
+	// to this one
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	result := heatmap{GeneratedAt: time.Now().UTC(), Missing: []string{}}
+	tiles := make([]heatmapTile, 0, len(tickers))
+	for _, ticker := range tickers {
+		q, ok := g.quotes.Get(ticker)
+		if !ok || q.Last <= 0 {
+			result.Missing = append(result.Missing, ticker)
+			continue
+		}
+		tiles = append(tiles, g.heatmapTileFor(ctx, q))
+	}
+	sort.Slice(tiles, func(i, j int) bool { return tiles[i].Ticker < tiles[j].Ticker })
+	sort.Strings(result.Missing)
+
+	if group == "sector" {
+		result.Sectors = groupHeatmapBySector(tiles)
+	} else {
+		result.Tiles = tiles
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/auth"
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/jobs"
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// backtestJobsGauge counts queued and running backtest jobs
+var backtestJobsGauge = metrics.Default.NewGaugeVec("gateway_backtest_jobs",
+	"Backtest jobs waiting for or holding a worker", "state")
+
+// newBacktestQueue creates the worker pool backtests run on, whether
+// queued as jobs or waited for.
+// BACKTEST_WORKERS bounds backtests running at once (default 2),
+// BACKTEST_JOBS_RUNNING_PER_USER how many of those one user may hold
+// (default 1), BACKTEST_JOBS_PER_USER a user's unfinished jobs (default 20)
+// and BACKTEST_QUEUE_SIZE the jobs waiting across all users (default 200).
+// Finished jobs are kept for BACKTEST_JOBS_RETAIN (default 1h).
+func newBacktestQueue() *jobs.Queue {
+	limits := jobs.Limits{
+		Workers:         2,
+		RunningPerOwner: 1,
+		PendingPerOwner: 20,
+		MaxQueued:       200,
+		Retain:          time.Hour,
+	}
+	for env, limit := range map[string]*int{
+		"BACKTEST_WORKERS":               &limits.Workers,
+		"BACKTEST_JOBS_RUNNING_PER_USER": &limits.RunningPerOwner,
+		"BACKTEST_JOBS_PER_USER":         &limits.PendingPerOwner,
+		"BACKTEST_QUEUE_SIZE":            &limits.MaxQueued,
+	} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			utils.Warn("Invalid %s %q, using default %d", env, value, *limit)
+			continue
+		}
+		*limit = n
+	}
+	if value := os.Getenv("BACKTEST_JOBS_RETAIN"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			limits.Retain = d
+		} else {
+			utils.Warn("Invalid BACKTEST_JOBS_RETAIN %q, using default %s", value, limits.Retain)
+		}
+	}
+
+	queue := jobs.NewQueue(limits)
+	limits = queue.Limits()
+	utils.Info("Running backtest jobs on %d workers, %d at a time per user", limits.Workers, limits.RunningPerOwner)
+
+	metrics.Default.OnScrape(func() {
+		stats := queue.Stats()
+		backtestJobsGauge.With("queued").Set(float64(stats.Queued))
+		backtestJobsGauge.With("running").Set(float64(stats.Running))
+	})
+	return queue
+}
+
+// runQueued runs call on the backtest worker pool as a job of kind for the
+// caller and waits for it, so backtests answered synchronously share the
+// pool's bounds with queued ones. Calls made from within a job run directly.
+// If ctx ends first, the job is cancelled.
+func (g *APIGateway) runQueued(ctx context.Context, kind string, call jobs.Func) (interface{}, error) {
+	if jobs.IDFrom(ctx) != "" {
+		return call(ctx)
+	}
+
+	var result interface{}
+	var callErr error
+	job, err := g.backtestJobs.Submit(ctx, usageUser(ctx), kind, func(jobCtx context.Context) (interface{}, error) {
+		// The caller's deadline covers both the wait and the call
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			jobCtx, cancel = context.WithDeadline(jobCtx, deadline)
+			defer cancel()
+		}
+		// The caller takes the result, so the job does not keep it
+		result, callErr = call(jobCtx)
+		return nil, callErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	job, err = g.backtestJobs.Wait(ctx, job.ID)
+	switch {
+	case err != nil:
+		g.backtestJobs.Cancel(job.ID)
+		return nil, err
+	case job.State == jobs.StateCancelled:
+		return nil, fmt.Errorf("%s %s", kind, job.Error)
+	}
+	return result, callErr
+}
+
+// writeQueueError answers a request the backtest pool turned away,
+// reporting whether err was such a refusal
+func writeQueueError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, jobs.ErrOwnerLimit):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, jobs.ErrQueueFull), errors.Is(err, jobs.ErrClosed):
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		return false
+	}
+	return true
+}
+
+// backtestJobSubmitHandler queues a backtest to run in the background,
+// taking the same parameters as /backtest, or /backtest/portfolio when
+// tickers is given. It answers 202 with the job; poll the job for its queue
+// position and, once done, its results, or follow its progress over the
+// WebSocket with a "backtest" subscription.
+func (g *APIGateway) backtestJobSubmitHandler(w http.ResponseWriter, r *http.Request) {
+	kind, timeout := "backtest", backtestTimeout
+	req, strategy, err := g.backtestRequest(r, r.URL.Query().Get("ticker"))
+	if r.URL.Query().Get("tickers") != "" {
+		kind, timeout = "portfolio", portfolioBacktestTimeout
+		req, strategy, err = g.portfolioBacktestRequest(r)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	refresh, err := refreshParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !g.allowUsage(w, r, usageBacktests) {
+		return
+	}
+
+	// The job keeps the caller's identity, so it is charged to them.
+	// Clients following the job over WebSocket are told when it starts
+	// and ends; the trading service reports progress in between.
+	job, err := g.backtestJobs.Submit(r.Context(), usageUser(r.Context()), kind, func(jobCtx context.Context) (interface{}, error) {
+		id := jobs.IDFrom(jobCtx)
+		g.publishBacktestProgress(events.BacktestProgress{JobID: id, Stage: events.BacktestRunning})
+
+		ctx, cancel := context.WithTimeout(jobCtx, timeout)
+		defer cancel()
+
+		run, err := g.runBacktest(ctx, req, strategy, refresh)
+		switch {
+		case jobCtx.Err() != nil:
+			// Cancellation was announced by whoever cancelled the job
+			return nil, jobCtx.Err()
+		case err != nil:
+			err = fmt.Errorf("error running backtest: %w", err)
+			g.publishBacktestProgress(events.BacktestProgress{JobID: id, Stage: events.BacktestFailed, Error: err.Error()})
+			return nil, err
+		}
+		g.publishBacktestProgress(events.BacktestProgress{JobID: id, Stage: events.BacktestDone, Percent: 100})
+		return run, nil
+	})
+	switch {
+	case errors.Is(err, jobs.ErrOwnerLimit):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	case err != nil:
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/backtest/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// backtestJobsHandler lists the caller's backtest jobs, newest first, with
+// the queue's load. Operators see every user's jobs.
+func (g *APIGateway) backtestJobsHandler(w http.ResponseWriter, r *http.Request) {
+	owner := usageUser(r.Context())
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok && principal.Can(auth.PermConfig) {
+		owner = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jobs":  g.backtestJobs.List(owner),
+		"queue": g.backtestJobs.Stats(),
+	})
+}
+
+// backtestJobHandler reports a backtest job's state, its queue position
+// while queued and its results once done
+func (g *APIGateway) backtestJobHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := g.callerBacktestJob(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// backtestJobCancelHandler cancels a queued or running backtest job
+func (g *APIGateway) backtestJobCancelHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := g.callerBacktestJob(w, r); !ok {
+		return
+	}
+
+	job, err := g.cancelBacktestJob(mux.Vars(r)["id"])
+	switch {
+	case errors.Is(err, jobs.ErrFinished):
+		http.Error(w, fmt.Sprintf("job is already %s", job.State), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// callerBacktestJob looks up the job named in the path, writing a 404 when
+// it does not exist or belongs to another user and the caller is not an
+// operator
+func (g *APIGateway) callerBacktestJob(w http.ResponseWriter, r *http.Request) (jobs.Job, bool) {
+	job, err := g.backtestJobs.Get(mux.Vars(r)["id"])
+	if err == nil && job.Owner != usageUser(r.Context()) {
+		if principal, ok := auth.PrincipalFrom(r.Context()); !ok || !principal.Can(auth.PermConfig) {
+			err = jobs.ErrNotFound
+		}
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return jobs.Job{}, false
+	}
+	return job, true
+}
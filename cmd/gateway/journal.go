@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/journal"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/risk"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// newJournalStore creates the trade journal, persisted to JOURNAL_PATH when set
+func newJournalStore() *journal.Store {
+	store, err := journal.NewStore(os.Getenv("JOURNAL_PATH"))
+	if err != nil {
+		utils.Error("Failed to load trade journal, starting empty: %v", err)
+		store, _ = journal.NewStore("")
+	}
+	return store
+}
+
+// parseDateParam parses an optional date query parameter (YYYY-MM-DD or RFC3339).
+// Dates without a time are treated as the end of day when endOfDay is set.
+func parseDateParam(r *http.Request, name string, endOfDay bool) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// journalListHandler queries journal entries by ticker, strategy, tag and date range
+func (g *APIGateway) journalListHandler(w http.ResponseWriter, r *http.Request) {
+	from, err := parseDateParam(r, "from", false)
+	if err != nil {
+		http.Error(w, "invalid from parameter", http.StatusBadRequest)
+		return
+	}
+	to, err := parseDateParam(r, "to", true)
+	if err != nil {
+		http.Error(w, "invalid to parameter", http.StatusBadRequest)
+		return
+	}
+
+	entries := g.journal.Find(journal.Query{
+		Ticker:   r.URL.Query().Get("ticker"),
+		Strategy: r.URL.Query().Get("strategy"),
+		Tag:      r.URL.Query().Get("tag"),
+		From:     from,
+		To:       to,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// journalCreateHandler adds a new journal entry
+func (g *APIGateway) journalCreateHandler(w http.ResponseWriter, r *http.Request) {
+	var entry journal.Entry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		http.Error(w, "invalid journal entry payload", http.StatusBadRequest)
+		return
+	}
+	ticker, err := market.ValidateTicker(entry.Ticker)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entry.Ticker = ticker
+
+	created, err := g.journal.Add(entry)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// journalGetHandler returns a single journal entry
+func (g *APIGateway) journalGetHandler(w http.ResponseWriter, r *http.Request) {
+	entry, exists := g.journal.Get(mux.Vars(r)["id"])
+	if !exists {
+		http.Error(w, "journal entry not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// journalAnnotateHandler updates tags, screenshots, notes or strategy of an entry
+func (g *APIGateway) journalAnnotateHandler(w http.ResponseWriter, r *http.Request) {
+	var annotation journal.Annotation
+	if err := json.NewDecoder(r.Body).Decode(&annotation); err != nil {
+		http.Error(w, "invalid annotation payload", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := g.journal.Annotate(mux.Vars(r)["id"], annotation)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// journalDeleteHandler removes a journal entry
+func (g *APIGateway) journalDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if err := g.journal.Delete(mux.Vars(r)["id"]); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// journalClosedPosition records a closed position in the journal for later review
+func (g *APIGateway) journalClosedPosition(closed risk.ClosedPosition) {
+	_, err := g.journal.Add(journal.Entry{
+		Ticker:     closed.Ticker,
+		Strategy:   closed.Strategy,
+		Direction:  closed.Direction,
+		Quantity:   closed.Quantity,
+		EntryPrice: closed.EntryPrice,
+		ExitPrice:  closed.ExitPrice,
+		PnL:        closed.RealizedPnL,
+		Simulated:  closed.Simulated,
+		PositionID: closed.ID,
+		TradeTime:  closed.ClosedAt,
+	})
+	if err != nil {
+		utils.Error("Failed to journal closed position %s: %v", closed.ID, err)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/metrics"
+)
+
+// Stages of an event's trip to a WebSocket client
+const (
+	stageFetchToPublish   = "fetch_to_publish"   // Provider fetch to publish by the data service
+	stagePublishToProcess = "publish_to_process" // Publish to the hub publishing its derived event
+	stageSentToDelivery   = "sent_to_delivery"   // Last publish to the write to the client
+	stageEndToEnd         = "end_to_end"         // Earliest stamp to the write to the client
+)
+
+// latencyWindow is how many recent samples percentiles are taken over, per
+// event kind and stage
+const latencyWindow = 1024
+
+// eventLatency is the stage latency of events delivered to WebSocket clients
+var eventLatency = metrics.Default.NewHistogramVec("gateway_event_latency_seconds",
+	"Latency of events delivered to WebSocket clients by event kind and pipeline stage",
+	[]float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}, "kind", "stage")
+
+// latencySamples is a ring of recent samples
+type latencySamples struct {
+	values []time.Duration
+	next   int
+	count  int64
+}
+
+// add records a sample, overwriting the oldest once the window is full
+func (s *latencySamples) add(d time.Duration) {
+	if len(s.values) < latencyWindow {
+		s.values = append(s.values, d)
+	} else {
+		s.values[s.next] = d
+		s.next = (s.next + 1) % latencyWindow
+	}
+	s.count++
+}
+
+// latencySummary reports percentiles of a stage's recent samples
+type latencySummary struct {
+	Count   int64   `json:"count"`   // Samples since startup
+	Samples int     `json:"samples"` // Samples the percentiles are taken over
+	P50     float64 `json:"p50_ms"`
+	P90     float64 `json:"p90_ms"`
+	P99     float64 `json:"p99_ms"`
+	Max     float64 `json:"max_ms"`
+}
+
+// summary returns percentiles of the samples in the window
+func (s *latencySamples) summary() latencySummary {
+	sorted := append([]time.Duration{}, s.values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return latencySummary{
+		Count:   s.count,
+		Samples: len(sorted),
+		P50:     ms(percentile(sorted, 50)),
+		P90:     ms(percentile(sorted, 90)),
+		P99:     ms(percentile(sorted, 99)),
+		Max:     ms(percentile(sorted, 100)),
+	}
+}
+
+// percentile returns the p-th percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p / 100 * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+// latencyTracker keeps recent stage latencies of delivered events
+type latencyTracker struct {
+	mu      sync.Mutex
+	samples map[string]map[string]*latencySamples // Event kind, then stage
+}
+
+// newLatencyTracker creates an empty tracker
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{samples: make(map[string]map[string]*latencySamples)}
+}
+
+// Observe records the latency of an event on subject written to a client at
+// delivered. Stages whose stamps are missing are skipped; unstamped events
+// record nothing.
+func (t *latencyTracker) Observe(subject string, stamps events.Stamps, delivered time.Time) {
+	origin := stamps.Origin()
+	if origin.IsZero() {
+		return
+	}
+
+	stages := map[string]time.Duration{stageEndToEnd: delivered.Sub(origin)}
+	if sent := stamps.Sent(); !sent.IsZero() {
+		stages[stageSentToDelivery] = delivered.Sub(sent)
+	}
+	if !stamps.FetchedAt.IsZero() && !stamps.PublishedAt.IsZero() {
+		stages[stageFetchToPublish] = stamps.PublishedAt.Sub(stamps.FetchedAt)
+	}
+	if !stamps.PublishedAt.IsZero() && !stamps.ProcessedAt.IsZero() {
+		stages[stagePublishToProcess] = stamps.ProcessedAt.Sub(stamps.PublishedAt)
+	}
+
+	kind := latencyKind(subject)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	byStage, ok := t.samples[kind]
+	if !ok {
+		byStage = make(map[string]*latencySamples)
+		t.samples[kind] = byStage
+	}
+	for stage, d := range stages {
+		// Clocks differ between hosts; a negative stage is skew, not latency
+		if d < 0 {
+			d = 0
+		}
+		samples, ok := byStage[stage]
+		if !ok {
+			samples = &latencySamples{}
+			byStage[stage] = samples
+		}
+		samples.add(d)
+		eventLatency.With(kind, stage).Observe(d.Seconds())
+	}
+}
+
+// Summary returns percentiles by event kind and stage
+func (t *latencyTracker) Summary() map[string]map[string]latencySummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	summary := make(map[string]map[string]latencySummary, len(t.samples))
+	for kind, byStage := range t.samples {
+		summary[kind] = make(map[string]latencySummary, len(byStage))
+		for stage, samples := range byStage {
+			summary[kind][stage] = samples.summary()
+		}
+	}
+	return summary
+}
+
+// latencyKind names the kind of event on a subject by dropping the ticker
+// and any later tokens, e.g. market.bars.AAPL.5min is market.bars
+func latencyKind(subject string) string {
+	tokens := strings.Split(subject, ".")
+	if tokens[0] == "market" && len(tokens) > 1 {
+		return tokens[0] + "." + tokens[1]
+	}
+	return tokens[0]
+}
+
+// latencyHandler reports percentiles of the latency of events delivered to
+// WebSocket clients, by event kind and pipeline stage
+func (g *APIGateway) latencyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"window": latencyWindow,
+		"kinds":  g.latency.Summary(),
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// levelsLookbackDays is the amount of history used to detect support/resistance
+const levelsLookbackDays = 30
+
+// LevelsCache stores computed support/resistance levels per ticker and interval.
+// Entries are recomputed once per trading day.
+type LevelsCache struct {
+	mutex  sync.RWMutex
+	levels map[string]analytics.Levels
+}
+
+// NewLevelsCache creates a new levels cache
+func NewLevelsCache() *LevelsCache {
+	return &LevelsCache{
+		levels: make(map[string]analytics.Levels),
+	}
+}
+
+// Get returns cached levels if they were computed today (Eastern Time)
+func (c *LevelsCache) Get(key string) (analytics.Levels, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	levels, exists := c.levels[key]
+	if !exists || !sameTradingDay(levels.ComputedAt, time.Now()) {
+		return analytics.Levels{}, false
+	}
+	return levels, true
+}
+
+// Set stores computed levels
+func (c *LevelsCache) Set(key string, levels analytics.Levels) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.levels[key] = levels
+}
+
+// sameTradingDay reports whether two times fall on the same date in exchange time
+func sameTradingDay(a, b time.Time) bool {
+	loc := market.ExchangeLocation()
+	return a.In(loc).Format("2006-01-02") == b.In(loc).Format("2006-01-02")
+}
+
+// getLevels returns support/resistance levels for a ticker, computing them if needed
+func (g *APIGateway) getLevels(ctx context.Context, ticker, interval string) (analytics.Levels, error) {
+	key := ticker + ":" + interval
+	if levels, ok := g.levels.Get(key); ok {
+		return levels, nil
+	}
+
+	candles, err := g.fetchCandles(ctx, ticker, interval, levelsLookbackDays)
+	if err != nil {
+		return analytics.Levels{}, err
+	}
+
+	levels := analytics.DetectLevels(ticker, interval, candles, analytics.DefaultLevelOptions())
+	g.levels.Set(key, levels)
+	utils.Info("Computed %d support/resistance zones for %s (%s)", len(levels.Zones), ticker, interval)
+
+	return levels, nil
+}
+
+// levelsHandler serves support/resistance zones and pivot points for a ticker
+func (g *APIGateway) levelsHandler(w http.ResponseWriter, r *http.Request) {
+	ticker := r.URL.Query().Get("ticker")
+	if ticker == "" {
+		http.Error(w, "ticker parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = "1day"
+	}
+
+	params, err := market.NormalizeHistoricalParams(ticker, interval, levelsLookbackDays)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+	defer cancel()
+
+	levels, err := g.getLevels(ctx, params.Ticker, params.Interval)
+	if err != nil {
+		http.Error(w, "Error computing levels: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(levels)
+}
+
+// levelsContext summarizes the levels around a price for inclusion in signals
+func levelsContext(levels analytics.Levels, price float64) map[string]interface{} {
+	summary := map[string]interface{}{
+		"pivot": levels.Pivots.Pivot,
+		"r1":    levels.Pivots.R1,
+		"s1":    levels.Pivots.S1,
+	}
+	if support, ok := levels.NearestSupport(price); ok {
+		summary["nearest_support"] = support.Price
+	}
+	if resistance, ok := levels.NearestResistance(price); ok {
+		summary["nearest_resistance"] = resistance.Price
+	}
+	return summary
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// Request bounds, so one request cannot trigger a massive provider pull,
+// simulation or allocation. Lookbacks are bounded per interval by
+// market.MaxDays and ticker lists by market.MaxTickerList.
+const (
+	maxBacktestTargets  = 10 // Values per profit target or risk/reward list; each is a simulation
+	maxOptionsDTE       = 365
+	maxOptionsContracts = 1000
+
+	wsMaxMessageBytes  = 64 << 10 // Largest message a WebSocket client may send
+	wsMaxSubscriptions = 200      // Subjects one WebSocket client may follow
+
+	defaultMaxRequestBytes = 1 << 20
+)
+
+// maxRequestBytesFromEnv reads the request body limit from MAX_REQUEST_BYTES
+func maxRequestBytesFromEnv() int64 {
+	if value := os.Getenv("MAX_REQUEST_BYTES"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err == nil && n > 0 {
+			return n
+		}
+		utils.Warn("Invalid MAX_REQUEST_BYTES %q, using %d", value, defaultMaxRequestBytes)
+	}
+	return defaultMaxRequestBytes
+}
+
+// limitRequestBody rejects request bodies over limit bytes. Declared
+// lengths are rejected up front; chunked bodies fail to decode once they
+// pass the limit.
+func limitRequestBody(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(w, fmt.Sprintf("request body exceeds %d bytes", limit), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// floatListParam parses a comma-separated list of numbers from a query
+// parameter, skipping entries that are not numbers, and rejects lists of
+// more than maxBacktestTargets values
+func floatListParam(r *http.Request, name string) ([]float64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	entries := strings.Split(value, ",")
+	if len(entries) > maxBacktestTargets {
+		return nil, fmt.Errorf("%s accepts at most %d values", name, maxBacktestTargets)
+	}
+
+	var values []float64
+	for _, entry := range entries {
+		f, err := strconv.ParseFloat(entry, 64)
+		if err != nil {
+			continue
+		}
+		values = append(values, f)
+	}
+	return values, nil
+}
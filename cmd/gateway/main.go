@@ -21,7 +21,32 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/myapp/tradinglab/pkg/alerts"
+	"github.com/myapp/tradinglab/pkg/artifacts"
+	"github.com/myapp/tradinglab/pkg/audit"
+	"github.com/myapp/tradinglab/pkg/auth"
+	"github.com/myapp/tradinglab/pkg/buildinfo"
+	"github.com/myapp/tradinglab/pkg/chaos"
+	"github.com/myapp/tradinglab/pkg/clock"
+	"github.com/myapp/tradinglab/pkg/config"
 	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/fundamentals"
+	"github.com/myapp/tradinglab/pkg/graphql"
+	"github.com/myapp/tradinglab/pkg/jobs"
+	"github.com/myapp/tradinglab/pkg/journal"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/notify"
+	"github.com/myapp/tradinglab/pkg/profiling"
+	"github.com/myapp/tradinglab/pkg/recommendation"
+	"github.com/myapp/tradinglab/pkg/reference"
+	"github.com/myapp/tradinglab/pkg/report"
+	"github.com/myapp/tradinglab/pkg/risk"
+	"github.com/myapp/tradinglab/pkg/scheduler"
+	"github.com/myapp/tradinglab/pkg/slack"
+	"github.com/myapp/tradinglab/pkg/snapshot"
+	"github.com/myapp/tradinglab/pkg/storage"
+	"github.com/myapp/tradinglab/pkg/strategy"
 	"github.com/myapp/tradinglab/pkg/utils"
 	pb "github.com/myapp/tradinglab/proto"
 )
@@ -31,14 +56,55 @@ import (
 // and provides WebSocket connections for real-time updates via NATS
 
 type APIGateway struct {
-	natsClient     *events.EventClient
-	tradingClient  pb.TradingServiceClient
-	tradingConn    *grpc.ClientConn
-	router         *mux.Router
-	wsClients      map[*websocket.Conn]bool
-	wsClientsMutex sync.Mutex
-	upgrader       websocket.Upgrader
-	cache          *DataCache
+	natsClient      *events.EventClient
+	tradingClient   pb.TradingServiceClient
+	tradingConn     *grpc.ClientConn
+	grpcServer      *grpc.Server // Market data streams and the trading service for gRPC and gRPC-Web clients
+	graphql         *graphql.Schema
+	router          *mux.Router
+	wsConns         *wsRegistry   // WebSocket connections with their goroutines and subscriptions
+	wsQueue         wsQueueConfig // Per-client send queue size and slow-consumer policy
+	upgrader        websocket.Upgrader
+	cache           *DataCache
+	levels          *LevelsCache
+	quotes          *quoteCache       // Consolidated top of book per ticker from the live streams
+	heatmap         *heatmapBaselines // Previous closes and average volumes behind /heatmap
+	correlations    *correlationCache // Correlation matrices until the nightly refresh
+	sizingDefaults  SizingDefaults
+	risk            *risk.Engine
+	riskEnforcement string             // "annotate" or "block"
+	signalModel     signalModel        // Scores published signals; nil leaves them unscored
+	modelTimeout    time.Duration      // Bounds each call to the signal model
+	quarantine      *anomalyQuarantine // Tickers whose signals are not alerted on after a live data anomaly
+	journal         *journal.Store
+	recommendations *recommendation.Store
+	pnlInterval     time.Duration
+	subscribers     *alerts.Store
+	alertSignals    *alerts.Collector
+	alertTemplates  *alerts.Templates
+	alertRules      *alerts.RuleStore
+	pushers         map[string]notify.Pusher // Configured push platforms by name
+	slackChannels   *slack.ChannelStore
+	slackClient     *slack.Client // Nil when no bot token is configured
+	audit           *audit.Log
+	auth            *auth.Authenticator
+	access          *auth.AccessPolicy
+	fallbackPolicy  market.FallbackPolicy // What to serve when the trading service is unavailable
+	strategies      *strategy.Registry
+	plugins         *strategy.PluginSet
+	scans           *ScanRunner
+	scheduler       *scheduler.Scheduler
+	reports         *report.Store
+	artifacts       *artifacts.Manager // Retention, archival and trash for reports, backtests and exports
+	objects         storage.Store      // Exports, published reports and snapshots
+	objectConfig    config.ObjectStorage
+	snapshots       *snapshot.Snapshotter // Stream and persistence store snapshots in the object store
+	latency         *latencyTracker       // Pipeline latency of events delivered to WebSocket clients
+	usage           *usageTracker         // Candles fetched and backtests run per caller
+	backtestJobs    *jobs.Queue           // Every backtest runs on this bounded worker pool
+	backtestTTL     time.Duration         // How long identical backtests share cached results; zero disables
+	reference       *reference.Store
+	fundamentals    *fundamentals.Store
 }
 
 func NewAPIGateway(natsURL, tradingServiceURL string) (*APIGateway, error) {
@@ -58,6 +124,9 @@ func NewAPIGateway(natsURL, tradingServiceURL string) (*APIGateway, error) {
 		grpc.WithBlock(),
 		grpc.WithTimeout(10 * time.Second),
 	}
+	if interceptor := chaos.Default().UnaryClientInterceptor(); interceptor != nil {
+		opts = append(opts, grpc.WithUnaryInterceptor(interceptor))
+	}
 
 	// Retry logic for establishing gRPC connection
 	maxRetries := 3
@@ -100,45 +169,314 @@ func NewAPIGateway(natsURL, tradingServiceURL string) (*APIGateway, error) {
 		},
 	}
 
-	return &APIGateway{
-		natsClient:    natsClient,
-		tradingClient: tradingClient,
-		tradingConn:   tradingConn,
-		router:        router,
-		wsClients:     make(map[*websocket.Conn]bool),
-		upgrader:      upgrader,
-		cache:         NewDataCache(),
-	}, nil
+	// Determine fallback behaviour when the trading service cannot answer
+	fallbackPolicy := market.FallbackPolicyFromEnv()
+	utils.Info("Using fallback data policy: %s", fallbackPolicy)
+
+	// Roles come from the admin token, API tokens and proxy users
+	authenticator, err := newAuthenticator()
+	if err != nil {
+		return nil, fmt.Errorf("invalid access control configuration: %w", err)
+	}
+	accessPolicy, err := newAccessPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("invalid access policy: %w", err)
+	}
+
+	// Exports and reports go to the configured object store
+	objects, objectConfig, err := newObjectStore()
+	if err != nil {
+		return nil, fmt.Errorf("invalid object storage configuration: %w", err)
+	}
+
+	// Sector classifications also seed the risk engine's sector limits
+	referenceStore := newReferenceStore()
+
+	// Trading service calls are charged to the caller they serve
+	usage := newUsageTracker()
+
+	gateway := &APIGateway{
+		natsClient:      natsClient,
+		tradingClient:   meteredTradingClient{TradingServiceClient: tradingClient, usage: usage},
+		tradingConn:     tradingConn,
+		router:          router,
+		wsConns:         newWSRegistry(),
+		wsQueue:         wsQueueConfigFromEnv(),
+		upgrader:        upgrader,
+		cache:           NewDataCache(),
+		levels:          NewLevelsCache(),
+		quotes:          newQuoteCache(),
+		heatmap:         newHeatmapBaselines(),
+		correlations:    newCorrelationCache(),
+		latency:         newLatencyTracker(),
+		usage:           usage,
+		backtestJobs:    newBacktestQueue(),
+		backtestTTL:     backtestCacheTTLFromEnv(),
+		sizingDefaults:  loadSizingDefaults(),
+		risk:            newRiskEngine(referenceStore),
+		riskEnforcement: riskEnforcementFromEnv(),
+		signalModel:     newSignalModel(),
+		modelTimeout:    signalModelTimeoutFromEnv(),
+		quarantine:      newAnomalyQuarantine(anomalyQuarantineFromEnv()),
+		journal:         newJournalStore(),
+		recommendations: newRecommendationStore(),
+		pnlInterval:     pnlIntervalFromEnv(),
+		subscribers:     newSubscriberStore(),
+		alertSignals:    alerts.NewCollector(),
+		alertTemplates:  newAlertTemplates(),
+		alertRules:      newAlertRuleStore(),
+		pushers:         newPushers(),
+		slackChannels:   newSlackChannelStore(),
+		slackClient:     slack.ClientFromEnv(),
+		audit:           newAuditLog(),
+		auth:            authenticator,
+		access:          accessPolicy,
+		fallbackPolicy:  fallbackPolicy,
+		strategies:      newStrategyRegistry(),
+		reference:       referenceStore,
+		fundamentals:    newFundamentalsStore(),
+		objects:         objects,
+		objectConfig:    objectConfig,
+	}
+
+	gateway.grpcServer = gateway.newGRPCServer()
+	gateway.graphql = gateway.graphqlSchema()
+
+	// Publish risk events when portfolio thresholds are hit
+	gateway.risk.OnEvent(gateway.publishRiskEvent)
+
+	// Load compiled strategy plugins alongside built-in strategies
+	gateway.plugins = loadStrategyPlugins(gateway.strategies)
+	loadStrategySessions(gateway.strategies)
+
+	// Scheduled jobs: strategy scans need strategies and plugins in place
+	gateway.scheduler = scheduler.New()
+	gateway.scans = newScanRunner(gateway)
+	gateway.reports = newReportStore()
+	gateway.artifacts = newArtifactManager()
+	gateway.snapshots = gateway.newSnapshotter()
+	gateway.scheduleDailyReport()
+	gateway.scheduleConsumerJanitor()
+	gateway.scheduleRecommendationExpiry()
+	gateway.scheduleAlertDigest()
+	gateway.scheduleArtifactRetention()
+	gateway.scheduleDataRetention()
+	gateway.scheduleCorrelationRefresh()
+
+	// Collect signals for digests and send immediate and rule-based alerts
+	gateway.subscribeAlertSignals()
+	gateway.subscribeAlertPrices()
+	gateway.subscribeAnomalies()
+
+	// Keep WebSocket watchlist streams in step with subscriber watchlists
+	gateway.subscribers.OnChange(gateway.refreshWatchlists)
+
+	// Serve quotes from the live streams instead of the provider
+	gateway.subscribeQuotes()
+
+	// Pick up edits to the user strategy file without a restart
+	go gateway.watchStrategies()
+
+	return gateway, nil
 }
 
 func (g *APIGateway) setupRoutes() {
+	// Prometheus metrics, outside the API so scrapers need no credentials;
+	// the access policy keeps them inside the cluster
+	g.router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
 	// API routes
 	api := g.router.PathPrefix("/api").Subrouter()
 
+	// Bound request bodies before anything reads them
+	api.Use(limitRequestBody(maxRequestBytesFromEnv()))
+
+	// Identify the caller, record audited calls, then enforce roles, so
+	// denied calls are audited too
+	api.Use(g.authenticateMiddleware)
+	api.Use(g.auditMiddleware)
+	api.Use(g.authorizeMiddleware)
+
 	// Health check
 	api.HandleFunc("/health", g.healthHandler).Methods("GET")
 
+	// Caller identity and allowed actions
+	api.HandleFunc("/me", g.meHandler).Methods("GET")
+
 	// System status
 	api.HandleFunc("/status", g.statusHandler).Methods("GET")
 
+	// Build of the running gateway
+	api.HandleFunc("/version", g.versionHandler).Methods("GET")
+
+	// Service liveness from heartbeats
+	api.HandleFunc("/services", g.servicesHandler).Methods("GET")
+
 	// Available tickers
 	api.HandleFunc("/tickers", g.tickersHandler).Methods("GET")
 
+	// Symbol search for ticker autocomplete
+	api.HandleFunc("/symbols/search", g.symbolSearchHandler).Methods("GET")
+
+	// Sector, industry and index reference data
+	api.HandleFunc("/reference", g.referenceListHandler).Methods("GET")
+	api.HandleFunc("/reference/sectors", g.referenceSectorsHandler).Methods("GET")
+	api.HandleFunc("/reference/exposure", g.referenceExposureHandler).Methods("GET")
+	api.HandleFunc("/reference/{ticker}", g.referenceTickerHandler).Methods("GET")
+
+	// Company fundamentals
+	api.HandleFunc("/fundamentals", g.fundamentalsHandler).Methods("GET")
+
 	// Historical data
 	api.HandleFunc("/historical-data", g.historicalDataHandler).Methods("GET")
 
 	// Trading signals
-	api.HandleFunc("/signals", g.signalsHandler).Methods("GET")
+	api.HandleFunc("/signals", g.signalsHandler).Methods("GET", "POST")
+	api.HandleFunc("/signals/history", g.signalHistoryHandler).Methods("GET")
 
 	// Backtest
-	api.HandleFunc("/backtest", g.backtestHandler).Methods("GET")
+	api.HandleFunc("/backtest", g.backtestHandler).Methods("GET", "POST")
+	api.HandleFunc("/backtest/options", g.optionsBacktestHandler).Methods("GET", "POST")
+	api.HandleFunc("/backtest/portfolio", g.portfolioBacktestHandler).Methods("GET", "POST")
+	api.HandleFunc("/backtest/jobs", g.backtestJobSubmitHandler).Methods("POST")
+	api.HandleFunc("/backtest/jobs", g.backtestJobsHandler).Methods("GET")
+	api.HandleFunc("/backtest/jobs/{id}", g.backtestJobHandler).Methods("GET")
+	api.HandleFunc("/backtest/jobs/{id}", g.backtestJobCancelHandler).Methods("DELETE")
+	api.HandleFunc("/backtest/optimize", g.backtestOptimizeHandler).Methods("POST")
+	api.HandleFunc("/backtest/{id}/equity-curve", g.backtestEquityHandler).Methods("GET")
+	api.HandleFunc("/backtest/{id}/drawdown", g.backtestDrawdownHandler).Methods("GET")
 
 	// Recommendations
 	api.HandleFunc("/recommendations", g.recommendationsHandler).Methods("GET")
+	api.HandleFunc("/recommendations/tracked", g.recommendationListHandler).Methods("GET")
+	api.HandleFunc("/recommendations/stats", g.recommendationStatsHandler).Methods("GET")
+	api.HandleFunc("/recommendations/{id}", g.recommendationGetHandler).Methods("GET")
+	api.HandleFunc("/recommendations/{id}/fill", g.recommendationFillHandler).Methods("POST")
+	api.HandleFunc("/recommendations/{id}/close", g.recommendationCloseHandler).Methods("POST")
+
+	// Support/resistance levels
+	api.HandleFunc("/levels", g.levelsHandler).Methods("GET")
+
+	// Order book depth snapshots
+	api.HandleFunc("/book", g.orderBookHandler).Methods("GET")
+
+	// Consolidated top-of-book quotes
+	api.HandleFunc("/quote", g.quoteHandler).Methods("GET")
+
+	// Market overview heatmap of the watched tickers
+	api.HandleFunc("/heatmap", g.heatmapHandler).Methods("GET")
+
+	// Position sizing
+	api.HandleFunc("/position-size", g.positionSizeHandler).Methods("GET")
+
+	// Portfolio risk
+	api.HandleFunc("/risk/status", g.riskStatusHandler).Methods("GET")
+	api.HandleFunc("/risk/positions", g.riskPositionsHandler).Methods("GET")
+	api.HandleFunc("/risk/positions", g.openPositionHandler).Methods("POST")
+	api.HandleFunc("/risk/positions/{id}", g.closePositionHandler).Methods("DELETE")
+
+	// Trade journal
+	api.HandleFunc("/journal", g.journalListHandler).Methods("GET")
+	api.HandleFunc("/journal", g.journalCreateHandler).Methods("POST")
+	api.HandleFunc("/journal/{id}", g.journalGetHandler).Methods("GET")
+	api.HandleFunc("/journal/{id}", g.journalAnnotateHandler).Methods("PATCH", "PUT")
+	api.HandleFunc("/journal/{id}", g.journalDeleteHandler).Methods("DELETE")
+
+	// Performance analytics
+	api.HandleFunc("/analytics/performance", g.performanceHandler).Methods("GET")
+	api.HandleFunc("/analytics/correlation", g.correlationHandler).Methods("GET")
+
+	// Strategy registry
+	api.HandleFunc("/strategies", g.strategiesHandler).Methods("GET")
+	api.HandleFunc("/strategies", g.strategyDefineHandler).Methods("POST")
+	api.HandleFunc("/strategies/{name}", g.strategyGetHandler).Methods("GET")
+	api.HandleFunc("/strategies/{name}", g.strategyDefineHandler).Methods("PUT")
+	api.HandleFunc("/strategies/{name}", g.strategyDeleteHandler).Methods("DELETE")
+
+	// Scheduled strategy scans
+	api.HandleFunc("/scans", g.scansHandler).Methods("GET")
+	api.HandleFunc("/scans/{name}/run", g.scanRunHandler).Methods("POST")
+
+	// End-of-day reports
+	api.HandleFunc("/reports/daily", g.dailyReportsHandler).Methods("GET")
+	api.HandleFunc("/reports/daily/{date}", g.dailyReportHandler).Methods("GET")
+	api.HandleFunc("/reports/daily/{date}", g.generateDailyReportHandler).Methods("POST")
+
+	// Cross-ticker daily summary compiled by the hub at the close
+	api.HandleFunc("/summary/daily", g.dailySummaryHandler).Methods("GET")
+
+	// JetStream storage operations
+	api.HandleFunc("/ops/streams", g.streamUsageHandler).Methods("GET")
+	api.HandleFunc("/ops/streams/{name}/purge", g.streamPurgeHandler).Methods("POST")
+	api.HandleFunc("/ops/consumers/prune", g.consumerPruneHandler).Methods("POST")
+	api.HandleFunc("/ops/retention", g.retentionPolicyHandler).Methods("GET")
+	api.HandleFunc("/ops/retention", g.retentionRunHandler).Methods("POST")
+
+	// Latency of events delivered to WebSocket clients
+	api.HandleFunc("/ops/latency", g.latencyHandler).Methods("GET")
+
+	// Candles fetched and backtests run per caller
+	api.HandleFunc("/usage", g.usageHandler).Methods("GET")
+	api.HandleFunc("/ops/usage", g.usageAllHandler).Methods("GET")
+
+	// Cancellation of in-flight historical requests
+	api.HandleFunc("/requests/{id}", g.cancelRequestHandler).Methods("DELETE")
+
+	// Exports and downloads from object storage
+	api.HandleFunc("/exports/historical", g.exportHistoricalHandler).Methods("POST")
+	api.HandleFunc("/exports/features", g.exportFeaturesHandler).Methods("POST")
+	api.HandleFunc("/downloads/{key:.+}", g.downloadHandler).Methods("GET")
+
+	// Snapshots of streams and the persistence store
+	api.HandleFunc("/ops/snapshots", g.snapshotsHandler).Methods("GET")
+	api.HandleFunc("/ops/snapshots", g.snapshotCreateHandler).Methods("POST")
+	api.HandleFunc("/ops/snapshots/{id}", g.snapshotHandler).Methods("GET")
+	api.HandleFunc("/ops/snapshots/{id}/restore", g.snapshotRestoreHandler).Methods("POST")
+
+	// Stored artifact lifecycle: listing, trash and retention
+	api.HandleFunc("/ops/artifacts", g.artifactsHandler).Methods("GET")
+	api.HandleFunc("/ops/artifacts/purge", g.artifactPurgeHandler).Methods("POST")
+	api.HandleFunc("/ops/artifacts/retention", g.artifactRetentionHandler).Methods("POST")
+	api.HandleFunc("/ops/artifacts/{kind}/{name}", g.artifactDeleteHandler).Methods("DELETE")
+	api.HandleFunc("/ops/artifacts/{kind}/{name}/restore", g.artifactRestoreHandler).Methods("POST")
+
+	// Active connectivity probes for support triage
+	api.HandleFunc("/diagnostics", g.diagnosticsHandler).Methods("GET")
+
+	// Alert subscriptions, push devices, alert rules and digests
+	api.HandleFunc("/alerts/subscribers", g.alertSubscribersHandler).Methods("GET")
+	api.HandleFunc("/alerts/subscribers", g.alertSubscribeHandler).Methods("POST")
+	api.HandleFunc("/alerts/subscribers/{id}", g.alertSubscriberHandler).Methods("GET")
+	api.HandleFunc("/alerts/subscribers/{id}", g.alertUnsubscribeHandler).Methods("DELETE")
+	api.HandleFunc("/alerts/subscribers/{id}/digest", g.alertDigestPreviewHandler).Methods("GET")
+	api.HandleFunc("/alerts/digest", g.alertDigestSendHandler).Methods("POST")
+	api.HandleFunc("/alerts/subscribers/{id}/devices", g.alertDeviceRegisterHandler).Methods("POST")
+	api.HandleFunc("/alerts/devices/{token}", g.alertDeviceUnregisterHandler).Methods("DELETE")
+	api.HandleFunc("/alerts/rules", g.alertRulesHandler).Methods("GET")
+	api.HandleFunc("/alerts/rules", g.alertRuleCreateHandler).Methods("POST")
+	api.HandleFunc("/alerts/rules/{id}", g.alertRuleHandler).Methods("GET")
+	api.HandleFunc("/alerts/rules/{id}", g.alertRuleDeleteHandler).Methods("DELETE")
+	api.HandleFunc("/alerts/rules/{id}/rearm", g.alertRuleRearmHandler).Methods("POST")
+
+	// Inbound signals from external alerting
+	api.HandleFunc("/webhooks/tradingview", g.tradingViewWebhookHandler).Methods("POST")
+
+	// Audit log of admin and trading-affecting calls
+	api.HandleFunc("/audit", g.auditHandler).Methods("GET")
+
+	// Slack slash commands
+	api.HandleFunc("/slack/commands", g.slackCommandHandler).Methods("POST")
+
+	// GraphQL queries across tickers, candles, signals, backtests and the portfolio
+	api.HandleFunc("/graphql", g.graphqlHandler).Methods("GET", "POST")
 
 	// WebSocket endpoint for real-time updates
 	api.HandleFunc("/ws", g.websocketHandler)
 
+	// gRPC-Web calls to the trading and market data services, e.g.
+	// POST /trading.TradingService/GetHistoricalData
+	g.router.PathPrefix("/trading.").HandlerFunc(g.grpcWebHandler).Methods("POST", "OPTIONS")
+
 	// Serve static files for the UI
 	g.router.PathPrefix("/").Handler(http.FileServer(http.Dir("./ui/build")))
 }
@@ -180,6 +518,10 @@ func (g *APIGateway) statusHandler(w http.ResponseWriter, r *http.Request) {
 	g.cache.mutex.RUnlock()
 
 	status["cache_stats"] = cacheStats
+	status["websocket_clients"] = g.wsClientStats()
+	if faults := chaos.Default(); faults != nil {
+		status["fault_injection"] = faults.Stats()
+	}
 	status["timestamp"] = time.Now().Format(time.RFC3339)
 
 	w.Header().Set("Content-Type", "application/json")
@@ -192,6 +534,8 @@ func (g *APIGateway) healthHandler(w http.ResponseWriter, r *http.Request) {
 		"status":       "healthy",
 		"timestamp":    time.Now().Format(time.RFC3339),
 		"service_name": "tradinglab-api-gateway",
+		"version":      buildinfo.Get().Version,
+		"commit":       buildinfo.Get().Commit,
 	}
 
 	// Only perform deep health check for non-probe requests
@@ -227,8 +571,8 @@ func (g *APIGateway) healthHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (g *APIGateway) tickersHandler(w http.ResponseWriter, r *http.Request) {
-	// Default tickers
-	tickers := []string{"SPY", "AAPL", "MSFT", "GOOGL", "AMZN"}
+	// Default tickers, overridable with WATCH_TICKERS
+	tickers := defaultWatchlist()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(tickers)
@@ -258,17 +602,27 @@ func (g *APIGateway) historicalDataHandler(w http.ResponseWriter, r *http.Reques
 		interval = "15min"
 	}
 
-	// Create cache key
-	cacheKey := fmt.Sprintf("%s:%d:%s", ticker, days, interval)
+	// Normalize parameters so equivalent spellings share cache entries
+	params, err := market.NormalizeHistoricalParams(ticker, interval, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ticker, interval, days = params.Ticker, params.Interval, params.Days
+	seed, err := simulationSeed(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !g.allowUsage(w, r, usageCandles) {
+		return
+	}
 
-	// Track failures for system status
-	var systemFailures int
-	defer func() {
-		g.cache.updateServiceStatus("historical-data", systemFailures)
-	}()
+	// Create cache key
+	cacheKey := params.CacheKey()
 
-	// Create gRPC request with longer timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	// Create gRPC request with longer timeout, charged to the caller
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
 	defer cancel()
 
 	req := &pb.HistoricalDataRequest{
@@ -279,7 +633,6 @@ func (g *APIGateway) historicalDataHandler(w http.ResponseWriter, r *http.Reques
 
 	// Call gRPC service with retry logic
 	var resp *pb.HistoricalDataResponse
-	var err error
 	maxRetries := 3
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
@@ -289,12 +642,12 @@ func (g *APIGateway) historicalDataHandler(w http.ResponseWriter, r *http.Reques
 		}
 
 		resp, err = g.tradingClient.GetHistoricalData(ctx, req)
+		g.cache.RecordDependency(depGRPC, err)
 		if err == nil {
 			break // Success, exit retry loop
 		}
 
 		utils.Info("Historical data request failed (attempt %d/%d): %v", attempt, maxRetries, err)
-		systemFailures++
 
 		if attempt == maxRetries || ctx.Err() != nil {
 			// All retries failed or context timeout
@@ -328,9 +681,9 @@ func (g *APIGateway) historicalDataHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// All retries failed, try to use cached data
+	// All retries failed, try to use cached data if the fallback policy allows it
 	cachedData, exists := g.cache.GetCachedHistoricalData(cacheKey)
-	if exists {
+	if exists && g.fallbackPolicy.AllowsCached() {
 		utils.Info("Using cached historical data for %s (%.1f minutes old)",
 			ticker, time.Since(cachedData.Timestamp).Minutes())
 
@@ -345,6 +698,35 @@ func (g *APIGateway) historicalDataHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Then try the chunks the market data service persisted to JetStream
+	if g.fallbackPolicy.AllowsCached() {
+		if candles, source, ok := g.storedCandles(params); ok {
+			utils.Info("Using %d candles for %s stored in JetStream (%s)", len(candles), ticker, source)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Data-Source", source)
+			w.Header().Set("X-System-Mode", g.cache.GetServiceStatus()["mode"].(string))
+
+			json.NewEncoder(w).Encode(candles)
+			return
+		}
+	}
+
+	// As a last resort, serve clearly labeled simulated candles if explicitly enabled
+	if g.fallbackPolicy.AllowsSample() {
+		if sample := generateFallbackCandles(ticker, days, interval, seed); sample != nil {
+			utils.Warn("Serving simulated historical data for %s", ticker)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Data-Source", market.SourceSimulated)
+			w.Header().Set("X-Simulation-Seed", strconv.FormatInt(seed, 10))
+			w.Header().Set("X-System-Mode", g.cache.GetServiceStatus()["mode"].(string))
+
+			json.NewEncoder(w).Encode(sample)
+			return
+		}
+	}
+
 	// No cached data available
 	if g.cache.GetServiceStatus()["mode"] == "readonly" {
 		// In read-only mode, return a specific error
@@ -365,6 +747,12 @@ type DataCache struct {
 	serviceMode       string // "normal", "degraded", "readonly"
 	lastStatusChange  time.Time
 	statusDescription string
+	recovery          recoveryPolicy               // Successes and time in mode needed to return to normal
+	failures          failurePolicy                // When a dependency counts as failing
+	dependencies      map[string]*dependencyWindow // Recent call outcomes by dependency
+	healthyStreak     int                          // Successful calls and probes since the last failure
+	lastProbe         time.Time
+	lastProbeError    error
 }
 
 // CachedData stores response data with metadata
@@ -384,31 +772,22 @@ func NewDataCache() *DataCache {
 		serviceMode:       "normal",
 		lastStatusChange:  time.Now(),
 		statusDescription: "System operating normally",
+		recovery:          recoveryPolicyFromEnv(),
+		failures:          failurePolicyFromEnv(),
+		dependencies:      make(map[string]*dependencyWindow),
 	}
 }
 
-// cacheSystems keeps track of which systems are having issues
-func (c *DataCache) updateServiceStatus(failedSystem string, failureCount int) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	// Determine system state based on failures
+// setMode switches the service mode. The caller holds c.mutex.
+func (c *DataCache) setMode(mode, description string) {
 	oldMode := c.serviceMode
-
-	if failureCount > 5 {
-		c.serviceMode = "readonly"
-		c.statusDescription = fmt.Sprintf("System in read-only mode: %s unavailable", failedSystem)
-	} else if failureCount > 2 {
-		c.serviceMode = "degraded"
-		c.statusDescription = fmt.Sprintf("System in degraded mode: %s experiencing issues", failedSystem)
-	} else if failureCount == 0 {
-		c.serviceMode = "normal"
-		c.statusDescription = "System operating normally"
-	}
+	c.serviceMode = mode
+	c.statusDescription = description
 
 	// If status changed, update timestamp
 	if oldMode != c.serviceMode {
 		c.lastStatusChange = time.Now()
+		serviceModeChanges.With(mode).Inc()
 		utils.Info("Service status changed to %s: %s", c.serviceMode, c.statusDescription)
 	}
 }
@@ -423,6 +802,9 @@ func (c *DataCache) GetServiceStatus() map[string]interface{} {
 		"description":        c.statusDescription,
 		"last_status_change": c.lastStatusChange.Format(time.RFC3339),
 		"readonly":           c.serviceMode == "readonly",
+		"recovery":           c.recoveryStatus(),
+		"dependencies":       c.dependencyStatus(),
+		"failure_window":     c.failures.Window.String(),
 	}
 }
 
@@ -477,15 +859,35 @@ func hash(s string) uint32 {
 	return h
 }
 
-// generateFallbackCandles creates sample market data when real data is unavailable
-func generateFallbackCandles(ticker string, days int, interval string) []map[string]interface{} {
+// simulationSeed reads the seed for simulated data from the seed parameter,
+// or SIMULATION_SEED when the request has none. The default of zero keeps
+// each ticker's usual series.
+func simulationSeed(r *http.Request) (int64, error) {
+	value := r.URL.Query().Get("seed")
+	if value == "" {
+		value = os.Getenv("SIMULATION_SEED")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	seed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid seed %q, expected an integer", value)
+	}
+	return seed, nil
+}
+
+// generateFallbackCandles creates sample market data when real data is
+// unavailable. Prices depend only on the ticker and seed, so a run can be
+// reproduced by passing the seed it reported.
+func generateFallbackCandles(ticker string, days int, interval string, seed int64) []map[string]interface{} {
 	// Only generate fallback data for 30 days or less
 	if days > 30 {
 		return nil
 	}
 
 	// Seed random number generator with ticker name for consistent results
-	source := rand.NewSource(int64(hash(ticker)))
+	source := rand.NewSource(seed + int64(hash(ticker)))
 	rng := rand.New(source)
 
 	// Set base price based on ticker
@@ -505,14 +907,14 @@ func generateFallbackCandles(ticker string, days int, interval string) []map[str
 		basePrice = 100.0
 	}
 
-	// Determine number of candles based on interval
+	// Determine number of candles based on interval (~6.5 trading hours per day)
+	barLength, err := market.IntervalDuration(interval)
+	if err != nil {
+		return nil
+	}
 	candlesPerDay := 1
-	if interval == "15min" {
-		candlesPerDay = 26 // ~6.5 trading hours / 15min
-	} else if interval == "5min" {
-		candlesPerDay = 78 // ~6.5 trading hours / 5min
-	} else if interval == "60min" {
-		candlesPerDay = 7 // ~6.5 trading hours / 60min
+	if barLength < 24*time.Hour {
+		candlesPerDay = int(math.Ceil(float64(390*time.Minute) / float64(barLength)))
 	}
 
 	totalCandles := days * candlesPerDay
@@ -527,16 +929,10 @@ func generateFallbackCandles(ticker string, days int, interval string) []map[str
 	for i := 0; i < totalCandles; i++ {
 		// Calculate time, moving backward from now
 		var candleTime time.Time
-		if interval == "day" {
+		if barLength >= 24*time.Hour {
 			candleTime = now.AddDate(0, 0, -i)
 		} else {
-			minutesInterval := 15
-			if interval == "5min" {
-				minutesInterval = 5
-			} else if interval == "60min" {
-				minutesInterval = 60
-			}
-			candleTime = now.Add(-time.Duration(i*minutesInterval) * time.Minute)
+			candleTime = now.Add(-time.Duration(i) * barLength)
 		}
 
 		// Generate price movements (basic random walk with trend)
@@ -561,6 +957,7 @@ func generateFallbackCandles(ticker string, days int, interval string) []map[str
 			"low":    low,
 			"close":  close,
 			"volume": volume,
+			"source": market.SourceSimulated,
 		}
 
 		// Update base price for next candle
@@ -599,48 +996,82 @@ func (g *APIGateway) signalsHandler(w http.ResponseWriter, r *http.Request) {
 		interval = "15min"
 	}
 
-	// Create cache key
-	cacheKey := fmt.Sprintf("%s:%d:%s:%s", ticker, days, strategy, interval)
+	// Normalize parameters so equivalent spellings share cache entries
+	params, err := market.NormalizeHistoricalParams(ticker, interval, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ticker, interval, days = params.Ticker, params.Interval, params.Days
 
-	// Track failures for system status
-	var systemFailures int
-	defer func() {
-		g.cache.updateServiceStatus("signals", systemFailures)
-	}()
+	// Validate strategy parameters and fill in defaults
+	strategyParams, err := g.strategyParams(r, strategy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Optional multi-timeframe confirmation
+	confirmSpec, err := confirmSpecFromRequest(r)
+	if err == nil && confirmSpec != nil {
+		err = validateConfirmSpec(confirmSpec, interval)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Create cache key
+	cacheKey := fmt.Sprintf("%s:%s:%s", params.CacheKey(), strategy, paramsCacheKey(strategyParams))
+	if confirmSpec != nil {
+		cacheKey = fmt.Sprintf("%s:confirm=%s/%d/%t", cacheKey, confirmSpec.Interval, confirmSpec.Period, confirmSpec.AnnotateOnly)
+	}
+	if session := g.sessionFilter(strategy, interval); session != nil {
+		cacheKey = fmt.Sprintf("%s:session=%+v", cacheKey, *session)
+	}
 
 	// Create gRPC request with longer timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
 
 	req := &pb.SignalRequest{
-		Ticker:   ticker,
-		Days:     int32(days),
-		Strategy: strategy,
-		Interval: interval,
+		Ticker:     ticker,
+		Days:       int32(days),
+		Strategy:   g.strategies.EngineName(strategy),
+		Interval:   interval,
+		Parameters: strategyParams,
 	}
 
 	// Call gRPC service with retry logic
 	var resp *pb.SignalResponse
-	var err error
 	maxRetries := 3
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if attempt > 1 {
-			utils.Info("Retrying signal generation for %s (attempt %d/%d)", ticker, attempt, maxRetries)
-			time.Sleep(time.Duration(attempt) * time.Second) // Exponential backoff
+	if plugin, isPlugin := g.plugins.Get(strategy); isPlugin {
+		// Plugin strategies run in the gateway against historical candles
+		resp, err = g.pluginSignals(ctx, plugin, params, strategyParams)
+		if err != nil {
+			utils.Error("Plugin strategy %s failed for %s: %v", strategy, ticker, err)
+			maxRetries = 1
 		}
+	} else {
+		for attempt := 1; attempt <= maxRetries; attempt++ {
+			if attempt > 1 {
+				utils.Info("Retrying signal generation for %s (attempt %d/%d)", ticker, attempt, maxRetries)
+				time.Sleep(time.Duration(attempt) * time.Second) // Exponential backoff
+			}
 
-		resp, err = g.tradingClient.GenerateSignals(ctx, req)
-		if err == nil {
-			break // Success, exit retry loop
-		}
+			resp, err = g.tradingClient.GenerateSignals(ctx, req)
+			g.cache.RecordDependency(depGRPC, err)
+			if err == nil {
+				break // Success, exit retry loop
+			}
 
-		utils.Info("Signal generation failed (attempt %d/%d): %v", attempt, maxRetries, err)
-		systemFailures++
+			utils.Info("Signal generation failed (attempt %d/%d): %v", attempt, maxRetries, err)
 
-		if attempt == maxRetries || ctx.Err() != nil {
-			// All retries failed or context timeout
-			break
+			if attempt == maxRetries || ctx.Err() != nil {
+				// All retries failed or context timeout
+				break
+			}
 		}
 	}
 
@@ -648,6 +1079,9 @@ func (g *APIGateway) signalsHandler(w http.ResponseWriter, r *http.Request) {
 	var signals []map[string]interface{}
 
 	if err == nil {
+		// Keep entries within the strategy's trading hours
+		resp.Signals = g.filterSessionSignals(strategy, interval, resp.Signals)
+
 		// Process successful response
 		signals = make([]map[string]interface{}, 0, len(resp.Signals))
 		for _, signal := range resp.Signals {
@@ -659,18 +1093,36 @@ func (g *APIGateway) signalsHandler(w http.ResponseWriter, r *http.Request) {
 			})
 		}
 
-		// Cache the successful response
+		// Attach support/resistance context when levels are available
+		if levels, err := g.getLevels(ctx, ticker, interval); err == nil {
+			for i, signal := range resp.Signals {
+				signals[i]["levels"] = levelsContext(levels, signal.EntryPrice)
+			}
+		} else {
+			utils.Debug("Levels unavailable for %s signals: %v", ticker, err)
+		}
+
+		// Require agreement with the higher timeframe trend
+		if confirmSpec != nil {
+			signals = g.confirmSignals(ctx, params, *confirmSpec, signals)
+		}
+
+		// Cache the successful response before the risk checks, which
+		// depend on the exposure when the signals are served
 		g.cache.CacheSignalData(cacheKey, signals)
 
+		// Check signals against portfolio risk limits
+		signals = g.applyRiskChecks(ticker, signals)
+
 		// Return the data
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(signals)
 		return
 	}
 
-	// All retries failed, try to use cached data
+	// All retries failed, try to use cached data if the fallback policy allows it
 	cachedData, exists := g.cache.GetCachedSignalData(cacheKey)
-	if exists {
+	if exists && g.fallbackPolicy.AllowsCached() {
 		utils.Info("Using cached signal data for %s (%.1f minutes old)",
 			ticker, time.Since(cachedData.Timestamp).Minutes())
 
@@ -680,8 +1132,9 @@ func (g *APIGateway) signalsHandler(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("X-Data-Age", fmt.Sprintf("%.1f minutes", time.Since(cachedData.Timestamp).Minutes()))
 		w.Header().Set("X-System-Mode", g.cache.GetServiceStatus()["mode"].(string))
 
-		// Return cached data
-		json.NewEncoder(w).Encode(cachedData.Data)
+		// Return cached data, checked against the current exposure
+		cached, _ := cachedData.Data.([]map[string]interface{})
+		json.NewEncoder(w).Encode(g.applyRiskChecks(ticker, cached))
 		return
 	}
 
@@ -696,22 +1149,149 @@ func (g *APIGateway) signalsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// backtestTimeout bounds a single-ticker backtest
+const backtestTimeout = 30 * time.Second
+
 func (g *APIGateway) backtestHandler(w http.ResponseWriter, r *http.Request) {
-	// Extract query parameters
-	ticker := r.URL.Query().Get("ticker")
-	if ticker == "" {
-		http.Error(w, "ticker parameter is required", http.StatusBadRequest)
+	req, strategy, err := g.backtestRequest(r, r.URL.Query().Get("ticker"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	refresh, err := refreshParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !g.allowUsage(w, r, usageBacktests) {
 		return
 	}
 
+	// Create gRPC request, charged to the caller
+	ctx, cancel := context.WithTimeout(r.Context(), backtestTimeout)
+	defer cancel()
+
+	run, err := g.runBacktest(ctx, req, strategy, refresh)
+	if writeQueueError(w, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error running backtest: %v", err), http.StatusInternalServerError)
+		return
+	}
+	setBacktestHeaders(w, run)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run.Results)
+}
+
+// runBacktest serves a backtest from the cache when an identical one ran on
+// the same data within the cache TTL, unless refresh is set. Otherwise it
+// calls the trading service and keeps the run as an artifact so it can be
+// revisited until retention removes it.
+func (g *APIGateway) runBacktest(ctx context.Context, req *pb.BacktestRequest, strategy string, refresh bool) (backtestRun, error) {
+	var cacheKey string
+	if g.backtestTTL > 0 {
+		key, err := backtestCacheKey(req, usageUser(ctx), time.Now())
+		if err != nil {
+			utils.Warn("Failed to hash backtest request: %v", err)
+		}
+		cacheKey = key
+	}
+	if cacheKey != "" && !refresh {
+		if run, exists := g.cache.GetCachedBacktestResults(cacheKey, g.backtestTTL); exists {
+			return run, nil
+		}
+	}
+
+	// Backtests wait their turn on the worker pool, and have the trading
+	// service publish their progress under their job
+	kind := "backtest"
+	if len(req.Tickers) > 0 {
+		kind = "portfolio"
+	}
+	value, err := g.runQueued(ctx, kind, func(ctx context.Context) (interface{}, error) {
+		req.JobId = jobs.IDFrom(ctx)
+		return g.tradingClient.RunBacktest(ctx, req)
+	})
+	if err != nil {
+		return backtestRun{}, err
+	}
+	resp := value.(*pb.BacktestResponse)
+
+	label := req.Ticker
+	run := backtestRun{Results: backtestResults(resp.Results)}
+	if len(req.Tickers) > 0 {
+		label = strings.Join(req.Tickers, "_")
+		run.Results = portfolioResults(req, resp)
+	}
+
+	if run.Artifact, err = g.saveBacktestArtifact(req, run.Results, backtestCurves(resp), label, strategy); err != nil {
+		utils.Warn("Failed to store backtest for %s: %v", label, err)
+	}
+	if cacheKey != "" {
+		g.cache.CacheBacktestResults(cacheKey, run, g.backtestTTL)
+	}
+	return run, nil
+}
+
+// setBacktestHeaders points the response at a run's artifact and marks
+// results served from the cache with their age
+func setBacktestHeaders(w http.ResponseWriter, run backtestRun) {
+	if run.Artifact != "" {
+		w.Header().Set("X-Backtest-Artifact", run.Artifact)
+	}
+	if run.CachedAt != nil {
+		w.Header().Set("X-Data-Source", "cache")
+		w.Header().Set("X-Data-Age", fmt.Sprintf("%.1f minutes", time.Since(*run.CachedAt).Minutes()))
+	}
+}
+
+// refreshParam reads the refresh flag that bypasses cached results
+func refreshParam(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("refresh")
+	if value == "" {
+		return false, nil
+	}
+	refresh, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid refresh parameter")
+	}
+	return refresh, nil
+}
+
+// asOfParam reads the as_of time a backtest's data is pinned to. Times
+// without a zone are exchange time, and it is zero when not given.
+func asOfParam(r *http.Request, now time.Time) (time.Time, error) {
+	value := r.URL.Query().Get("as_of")
+	if value == "" {
+		return time.Time{}, nil
+	}
+	asOf, err := market.ParseTimestamp(value, market.ExchangeLocation())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid as_of parameter")
+	}
+	if asOf.After(now) {
+		return time.Time{}, fmt.Errorf("as_of cannot be in the future")
+	}
+	return asOf, nil
+}
+
+// backtestRequest builds a backtest of ticker from the request's query
+// parameters. It also returns the strategy name as the caller gave it.
+func (g *APIGateway) backtestRequest(r *http.Request, ticker string) (*pb.BacktestRequest, string, error) {
+	if ticker == "" {
+		return nil, "", fmt.Errorf("ticker parameter is required")
+	}
+
 	daysStr := r.URL.Query().Get("days")
 	days := 30 // Default
 	if daysStr != "" {
 		var err error
 		days, err = strconv.Atoi(daysStr)
 		if err != nil {
-			http.Error(w, "invalid days parameter", http.StatusBadRequest)
-			return
+			return nil, "", fmt.Errorf("invalid days parameter")
 		}
 	}
 
@@ -725,81 +1305,124 @@ func (g *APIGateway) backtestHandler(w http.ResponseWriter, r *http.Request) {
 		interval = "15min"
 	}
 
-	// Parse profit targets
-	var profitTargets []float64
-	if ptStr := r.URL.Query().Get("profit_targets"); ptStr != "" {
-		for _, pt := range strings.Split(ptStr, ",") {
-			ptFloat, err := strconv.ParseFloat(pt, 64)
-			if err != nil {
-				continue
-			}
-			profitTargets = append(profitTargets, ptFloat)
-		}
+	// Normalize parameters and bound the lookback for the interval
+	params, err := market.NormalizeHistoricalParams(ticker, interval, days)
+	if err != nil {
+		return nil, "", err
 	}
+	ticker, interval, days = params.Ticker, params.Interval, params.Days
 
-	// Parse risk reward ratios
-	var riskRewardRatios []float64
-	if rrStr := r.URL.Query().Get("risk_reward_ratios"); rrStr != "" {
-		for _, rr := range strings.Split(rrStr, ",") {
-			rrFloat, err := strconv.ParseFloat(rr, 64)
-			if err != nil {
-				continue
-			}
-			riskRewardRatios = append(riskRewardRatios, rrFloat)
-		}
+	// Validate strategy parameters and fill in defaults
+	strategyParams, err := g.strategyParams(r, strategy)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, isPlugin := g.plugins.Get(strategy); isPlugin {
+		return nil, "", fmt.Errorf("backtests are not supported for plugin strategies")
 	}
 
-	// Parse profit targets dollar
-	var profitTargetsDollar []float64
-	if ptdStr := r.URL.Query().Get("profit_targets_dollar"); ptdStr != "" {
-		for _, ptd := range strings.Split(ptdStr, ",") {
-			ptdFloat, err := strconv.ParseFloat(ptd, 64)
-			if err != nil {
-				continue
-			}
-			profitTargetsDollar = append(profitTargetsDollar, ptdFloat)
+	// Each target is a separate simulation, so the lists are bounded
+	profitTargets, err := floatListParam(r, "profit_targets")
+	if err != nil {
+		return nil, "", err
+	}
+	riskRewardRatios, err := floatListParam(r, "risk_reward_ratios")
+	if err != nil {
+		return nil, "", err
+	}
+	profitTargetsDollar, err := floatListParam(r, "profit_targets_dollar")
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Relative metrics are measured against SPY unless another benchmark is named
+	benchmark := "SPY"
+	if value := r.URL.Query().Get("benchmark"); value != "" {
+		if benchmark, err = market.ValidateTicker(value); err != nil {
+			return nil, "", err
 		}
 	}
 
-	// Create gRPC request
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	// Fills are modeled like paper trading's unless the request overrides them
+	costs, err := costModelParams(r, g.risk.Costs())
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Runs as of a past time see the bars known then, however they were
+	// corrected since
+	asOf, err := asOfParam(r, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
 
 	req := &pb.BacktestRequest{
 		Ticker:              ticker,
 		Days:                int32(days),
-		Strategy:            strategy,
+		Strategy:            g.strategies.EngineName(strategy),
 		Interval:            interval,
 		ProfitTargets:       profitTargets,
 		RiskRewardRatios:    riskRewardRatios,
 		ProfitTargetsDollar: profitTargetsDollar,
+		Parameters:          strategyParams,
+		Benchmark:           benchmark,
+		Costs:               costModelProto(costs),
 	}
-
-	// Call gRPC service
-	resp, err := g.tradingClient.RunBacktest(ctx, req)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("error running backtest: %v", err), http.StatusInternalServerError)
-		return
+	end := time.Now()
+	if !asOf.IsZero() {
+		req.AsOf = market.FormatTimestamp(asOf)
+		end = asOf
 	}
+	if session := g.sessionFilter(strategy, interval); session != nil {
+		req.SessionFiltered = true
+		req.SessionWindows = sessionWindows(session, days, end)
+	}
+	return req, strategy, nil
+}
 
-	// Convert results map to JSON-friendly format
-	results := make(map[string]interface{})
-	for name, result := range resp.Results {
-		results[name] = map[string]interface{}{
-			"win_rate":         result.WinRate,
-			"profit_factor":    result.ProfitFactor,
-			"total_return":     result.TotalReturn,
-			"total_return_pct": result.TotalReturnPct,
-			"total_trades":     result.TotalTrades,
-			"winning_trades":   result.WinningTrades,
-			"losing_trades":    result.LosingTrades,
-			"max_drawdown":     result.MaxDrawdown,
-			"max_drawdown_pct": result.MaxDrawdownPct,
-		}
+// backtestResults converts backtest results to a JSON-friendly format
+func backtestResults(results map[string]*pb.BacktestResult) map[string]interface{} {
+	converted := make(map[string]interface{})
+	for name, result := range results {
+		converted[name] = backtestResult(result)
 	}
+	return converted
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(results)
+// backtestResult converts one backtest result to a JSON-friendly format
+func backtestResult(result *pb.BacktestResult) map[string]interface{} {
+	entry := map[string]interface{}{
+		"win_rate":         result.WinRate,
+		"profit_factor":    result.ProfitFactor,
+		"total_return":     result.TotalReturn,
+		"total_return_pct": result.TotalReturnPct,
+		"total_trades":     result.TotalTrades,
+		"winning_trades":   result.WinningTrades,
+		"losing_trades":    result.LosingTrades,
+		"max_drawdown":     result.MaxDrawdown,
+		"max_drawdown_pct": result.MaxDrawdownPct,
+		"commission":       result.Commission,
+		"slippage":         result.Slippage,
+		"partial_fills":    result.PartialFills,
+		"sharpe_ratio":     result.SharpeRatio,
+	}
+	if result.Benchmark != nil {
+		entry["benchmark"] = benchmarkMetrics(result.Benchmark)
+	}
+	return entry
+}
+
+// benchmarkMetrics converts a backtest's benchmark-relative metrics to a
+// JSON-friendly format
+func benchmarkMetrics(m *pb.BenchmarkMetrics) map[string]interface{} {
+	return map[string]interface{}{
+		"ticker":                    m.Ticker,
+		"alpha":                     m.Alpha,
+		"beta":                      m.Beta,
+		"benchmark_return_pct":      m.BenchmarkReturnPct,
+		"excess_return_pct":         m.ExcessReturnPct,
+		"relative_max_drawdown_pct": m.RelativeMaxDrawdownPct,
+	}
 }
 
 func (g *APIGateway) recommendationsHandler(w http.ResponseWriter, r *http.Request) {
@@ -831,6 +1454,14 @@ func (g *APIGateway) recommendationsHandler(w http.ResponseWriter, r *http.Reque
 		interval = "15min"
 	}
 
+	// Normalize parameters and bound the lookback for the interval
+	params, err := market.NormalizeHistoricalParams(ticker, interval, days)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ticker, interval, days = params.Ticker, params.Interval, params.Days
+
 	// Create gRPC request
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -852,7 +1483,8 @@ func (g *APIGateway) recommendationsHandler(w http.ResponseWriter, r *http.Reque
 	// Convert gRPC response to JSON-friendly format
 	recommendations := make([]map[string]interface{}, 0, len(resp.Recommendations))
 	for _, rec := range resp.Recommendations {
-		recommendations = append(recommendations, map[string]interface{}{
+		recommendation := map[string]interface{}{
+			"ticker":      ticker,
 			"date":        rec.Date,
 			"signal_type": rec.SignalType,
 			"stock_price": rec.StockPrice,
@@ -863,7 +1495,33 @@ func (g *APIGateway) recommendationsHandler(w http.ResponseWriter, r *http.Reque
 			"delta":       rec.Delta,
 			"iv":          rec.Iv,
 			"price":       rec.Price,
-		})
+		}
+
+		// Track the recommendation so its fill, expiry and outcome can be followed by ID
+		tracked, created, err := g.trackRecommendation(ticker, strategy, rec)
+		if err != nil {
+			utils.Warn("Failed to track recommendation for %s: %v", ticker, err)
+		}
+		if tracked.ID != "" {
+			recommendation["id"] = tracked.ID
+			recommendation["status"] = tracked.Status
+		}
+
+		// Attach a suggested position size based on the default account settings
+		if sizing := g.recommendationSizing(rec.SignalType, rec.StockPrice, rec.Stoploss, rec.Price); sizing != nil {
+			recommendation["position_size"] = sizing
+		}
+
+		recommendations = append(recommendations, recommendation)
+
+		// Publish new recommendations, enriched so stream consumers see the
+		// sizing too; ones already tracked were published when first seen
+		if !created {
+			continue
+		}
+		if err := g.natsClient.PublishRecommendation(ctx, ticker, recommendation); err != nil {
+			utils.Warn("Failed to publish recommendation for %s: %v", ticker, err)
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -897,8 +1555,9 @@ func (g *APIGateway) websocketHandler(w http.ResponseWriter, r *http.Request) {
 		CheckOrigin: func(r *http.Request) bool {
 			return true // Allow any origin in dev; restrict in production
 		},
-		// This is important - be more lenient with header checking
-		Subprotocols: []string{"websocket"},
+		// This is important - be more lenient with header checking.
+		// "msgpack" selects MessagePack binary frames.
+		Subprotocols: wsSubprotocols,
 	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
@@ -908,26 +1567,27 @@ func (g *APIGateway) websocketHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
+	// Oversized client messages close the connection with 1009 (message too big)
+	conn.SetReadLimit(wsMaxMessageBytes)
+
 	utils.Info("WebSocket connection established successfully")
 
-	// Register client
-	g.wsClientsMutex.Lock()
-	g.wsClients[conn] = true
-	g.wsClientsMutex.Unlock()
+	// Register client with its send queue
+	queue := newWSClientQueue(g.wsQueue, conn.RemoteAddr().String())
+	client := g.wsConns.Register(conn, queue, wsEncoding(r, conn), wsDeltaSnapshotEvery(r), usageUser(r.Context()))
 
 	// Clean up on disconnect
 	defer func() {
-		g.wsClientsMutex.Lock()
-		delete(g.wsClients, conn)
-		g.wsClientsMutex.Unlock()
+		queue.Close()
+		g.wsConns.Release(client)
 		utils.Info("WebSocket connection closed")
 	}()
 
 	// Handle WebSocket messages (for subscription requests)
-	messageHandler := make(chan error)
-	go func() {
-		messageHandler <- g.handleWebSocketMessages(conn)
-	}()
+	messageHandler := make(chan error, 1)
+	client.Go(func() {
+		messageHandler <- g.handleWebSocketMessages(client)
+	})
 
 	// Keep connection alive with ping/pong
 	pingTicker := time.NewTicker(30 * time.Second)
@@ -951,6 +1611,12 @@ func (g *APIGateway) websocketHandler(w http.ResponseWriter, r *http.Request) {
 		case err := <-messageHandler:
 			utils.Info("WebSocket message handler returned: %v", err)
 			return
+		case <-queue.Evicted():
+			// Slow consumer under the disconnect policy
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseProtocolError, "slow consumer: send queue full"),
+				time.Now().Add(5*time.Second))
+			return
 		case <-pingTicker.C:
 			// Send ping to client
 			pingData := []byte(fmt.Sprintf("ping-%d", time.Now().Unix()))
@@ -963,50 +1629,149 @@ func (g *APIGateway) websocketHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (g *APIGateway) handleWebSocketMessages(conn *websocket.Conn) error {
-	// Set up subscriptions based on client messages
-	subscriptions := make(map[string]*nats.Subscription)
-	defer func() {
-		// Clean up subscriptions when connection closes
-		for subject, sub := range subscriptions {
-			utils.Info("Cleaning up subscription to %s", subject)
-			if err := sub.Unsubscribe(); err != nil {
-				utils.Info("Error unsubscribing from %s: %v", subject, err)
+// wsSubject maps a WebSocket subscription request to its NATS subject. The
+// ticker is validated so it cannot change the subject's meaning; "*" matches
+// every ticker.
+func wsSubject(streamType, ticker, interval, subject string) (string, error) {
+	if subject != "" {
+		return subject, nil
+	}
+	if ticker != "*" && !(streamType == "pnl" && ticker == "") {
+		normalized, err := market.ValidateTicker(ticker)
+		if err != nil {
+			return "", err
+		}
+		ticker = normalized
+	}
+	switch streamType {
+	case "market":
+		return fmt.Sprintf("market.live.%s", ticker), nil
+	case "signals":
+		return fmt.Sprintf("signals.%s", ticker), nil
+	case "recommendations":
+		return fmt.Sprintf("recommendations.%s", ticker), nil
+	case "analytics":
+		return fmt.Sprintf("market.analytics.%s", ticker), nil
+	case "book":
+		return fmt.Sprintf("market.book.%s", ticker), nil
+	case "trades":
+		return fmt.Sprintf("market.trades.%s", ticker), nil
+	case "bars":
+		normalized, err := market.NormalizeInterval(interval)
+		if err != nil {
+			return "", fmt.Errorf("invalid interval %q", interval)
+		}
+		return fmt.Sprintf("market.bars.%s.%s", ticker, normalized), nil
+	case "pnl":
+		if ticker != "" {
+			return pnlSubject + "." + ticker, nil
+		}
+		return pnlSubject, nil
+	}
+	return "", fmt.Errorf("unsupported type %q", streamType)
+}
+
+// wsSubscribe forwards subject to a WebSocket client and reports whether
+// the client is subscribed. Durable subjects carry their stream sequence and
+// replay anything after resumeFrom first. The caller holds client.subscribing.
+func (g *APIGateway) wsSubscribe(client *wsConnection, subject string, resumeFrom uint64) bool {
+	queue := client.queue
+
+	// Check if already subscribed
+	if _, exists := client.Subscription(subject); exists {
+		return true
+	}
+	if client.Subscriptions() >= wsMaxSubscriptions {
+		errorJSON, _ := json.Marshal(map[string]string{
+			"error": fmt.Sprintf("subscription limit of %d subjects reached", wsMaxSubscriptions),
+		})
+		queue.Push("", errorJSON)
+		return false
+	}
+
+	var sub *nats.Subscription
+	var err error
+	if isResumable(subject) {
+		sub, err = g.subscribeResumable(subject, resumeFrom, queue)
+	} else if isPnLSubject(subject) {
+		sub, err = g.subscribePnL(subject, queue)
+	} else {
+		// Subscribe to NATS subject with circuit breaker pattern for slow consumers
+		sub, err = g.natsClient.GetNATS().Subscribe(subject, func(msg *nats.Msg) {
+			data, err := events.Decode(msg)
+			if err != nil {
+				utils.Info("Dropping undecodable message on %s: %v", subject, err)
+				return
 			}
+
+			queue.PushStamped(subject, data, events.ReadStamps(msg.Header))
+		})
+		if err == nil {
+			// Confirm subscription through the queue so writes stay on the sender
+			queue.Push("", subscribedEvent(subject, 0, false))
 		}
-	}()
+	}
+
+	if err != nil {
+		utils.Info("Error subscribing to NATS subject %s: %v", subject, err)
+		return false
+	}
+
+	// Set pending limits to avoid overwhelming NATS with slow consumers
+	// This sets how many messages/bytes can be pending before NATS drops them
+	if err := sub.SetPendingLimits(256, 1024*1024); err != nil {
+		utils.Info("Error setting pending limits: %v", err)
+	}
+
+	// Store subscription
+	client.AddSubscription(subject, sub)
+	return true
+}
 
-	// Message queue with a buffer to handle slow consumers
-	const maxPendingMessages = 250 // Increased buffer size
-	messageQueue := make(chan []byte, maxPendingMessages)
+func (g *APIGateway) handleWebSocketMessages(client *wsConnection) error {
+	conn, queue := client.conn, client.queue
 
-	// Start message sender goroutine - handles backpressure
-	done := make(chan struct{})
+	// Subscriptions are held by the registry, which forces them down if this
+	// cleanup never runs
+	defer func() {
+		if n := client.UnsubscribeAll(); n > 0 {
+			utils.Info("Cleaned up %d subscriptions", n)
+		}
+	}()
+
+	// Start message sender goroutine; the client's queue applies the
+	// slow-consumer policy when it falls behind
 	senderErrors := make(chan error, 1)
+	defer queue.Close()
 
-	go func() {
-		defer close(done)
+	client.Go(func() {
+		delta := newWSDeltaEncoder(client.deltaEvery)
 		for {
-			select {
-			case <-done:
+			subject, msg, stamps, ok := queue.Pop()
+			if !ok {
+				return
+			}
+			msg = delta.Encode(subject, msg)
+
+			// Try to write with timeout
+			writeTimeout := time.Second * 5 // Increased timeout
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			messageType, frame := wsFrame(client.encoding, msg)
+			if err := conn.WriteMessage(messageType, frame); err != nil {
+				utils.Info("Error forwarding message to WebSocket, closing: %v", err)
+				senderErrors <- err
 				return
-			case msg, ok := <-messageQueue:
-				if !ok {
-					return
-				}
-
-				// Try to write with timeout
-				writeTimeout := time.Second * 5 // Increased timeout
-				conn.SetWriteDeadline(time.Now().Add(writeTimeout))
-				if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
-					utils.Info("Error forwarding message to WebSocket, closing: %v", err)
-					senderErrors <- err
-					return
-				}
-				conn.SetWriteDeadline(time.Time{}) // Reset deadline
 			}
+			conn.SetWriteDeadline(time.Time{}) // Reset deadline
+			g.latency.Observe(subject, stamps, time.Now())
 		}
-	}()
+	})
+
+	subscribe := func(subject string, resumeFrom uint64) {
+		client.subscribing.Lock()
+		defer client.subscribing.Unlock()
+		g.wsSubscribe(client, subject, resumeFrom)
+	}
 
 	// Set initial read deadline
 	conn.SetReadDeadline(time.Now().Add(10 * time.Minute))
@@ -1031,16 +1796,16 @@ func (g *APIGateway) handleWebSocketMessages(conn *websocket.Conn) error {
 			} else {
 				utils.Info("WebSocket closed: %v", err)
 			}
-			close(messageQueue) // Signal sender to stop
 			return err
 		}
 
 		// Extend read deadline after each successful message
 		conn.SetReadDeadline(time.Now().Add(10 * time.Minute))
 
-		// Only process text messages
-		if messageType != websocket.TextMessage {
-			utils.Info("Ignoring non-text message type: %d", messageType)
+		// Only process text messages, or MessagePack on MessagePack connections
+		p, ok := wsRequestJSON(client.encoding, messageType, p)
+		if !ok {
+			utils.Info("Ignoring message type %d on %s connection", messageType, client.encoding)
 			continue
 		}
 
@@ -1048,10 +1813,18 @@ func (g *APIGateway) handleWebSocketMessages(conn *websocket.Conn) error {
 
 		// Parse subscription request
 		var request struct {
-			Action  string `json:"action"`  // "subscribe" or "unsubscribe"
-			Type    string `json:"type"`    // "market", "signals", "recommendations"
-			Ticker  string `json:"ticker"`  // Stock ticker
-			Subject string `json:"subject"` // Optional specific NATS subject
+			Action     string            `json:"action"`      // "subscribe", "unsubscribe", "resume", "historical" or "cancel"
+			Type       string            `json:"type"`        // "market", "signals", "recommendations", "analytics", "book", "trades", "bars", "pnl", "watchlist", "backtest"
+			Ticker     string            `json:"ticker"`      // Stock ticker
+			Interval   string            `json:"interval"`    // Bar interval for "bars" and "historical", e.g. "5min"
+			Days       int               `json:"days"`        // Days of history for "historical"
+			Priority   string            `json:"priority"`    // Priority of a "historical" request; default "interactive"
+			RequestID  string            `json:"request_id"`  // Historical request to "cancel"
+			JobID      string            `json:"job_id"`      // Backtest job to follow with "backtest" or to "cancel"
+			Subscriber string            `json:"subscriber"`  // Alert subscriber ID or email whose "watchlist" to follow
+			Subject    string            `json:"subject"`     // Optional specific NATS subject
+			ResumeFrom uint64            `json:"resume_from"` // Last stream sequence received on the subject
+			Positions  map[string]uint64 `json:"positions"`   // Resume: last stream sequence received per subject
 		}
 
 		if err := json.Unmarshal(p, &request); err != nil {
@@ -1061,100 +1834,110 @@ func (g *APIGateway) handleWebSocketMessages(conn *websocket.Conn) error {
 				"error": fmt.Sprintf("Invalid message format: %v", err),
 			}
 			errorJSON, _ := json.Marshal(errorMsg)
-			messageQueue <- errorJSON
+			queue.Push("", errorJSON)
 			continue
 		}
 
 		// Handle subscription request
 		switch request.Action {
 		case "subscribe":
-			// Determine NATS subject based on request
-			var subject string
-			if request.Subject != "" {
-				subject = request.Subject
-			} else {
-				switch request.Type {
-				case "market":
-					subject = fmt.Sprintf("market.live.%s", request.Ticker)
-				case "signals":
-					subject = fmt.Sprintf("signals.%s", request.Ticker)
-				case "recommendations":
-					subject = fmt.Sprintf("recommendations.%s", request.Ticker)
-				default:
-					continue // Unknown type
+			if request.Type == "watchlist" {
+				// Follow every ticker on the watchlist; the subjects track
+				// the watchlist as it changes
+				if err := g.followWatchlist(client, request.Subscriber); err != nil {
+					errorJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+					queue.Push("", errorJSON)
 				}
-			}
-
-			// Check if already subscribed
-			if _, exists := subscriptions[subject]; exists {
 				continue
 			}
-
-			// Subscribe to NATS subject with circuit breaker pattern for slow consumers
-			sub, err := g.natsClient.GetNATS().Subscribe(subject, func(msg *nats.Msg) {
-				// Use non-blocking send to message queue
-				select {
-				case messageQueue <- msg.Data:
-					// Message sent to queue
-				default:
-					// Queue full, discard message but keep connection alive
-					utils.Info("WebSocket message queue full for %s, discarding message", subject)
+			if request.Type == "backtest" {
+				// Relay a backtest job's progress until it finishes
+				if err := g.followBacktest(client, request.JobID); err != nil {
+					errorJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+					queue.Push("", errorJSON)
 				}
-			})
+				continue
+			}
 
+			// Determine NATS subject based on request
+			subject, err := wsSubject(request.Type, request.Ticker, request.Interval, request.Subject)
 			if err != nil {
-				utils.Info("Error subscribing to NATS subject %s: %v", subject, err)
+				errorJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+				queue.Push("", errorJSON)
 				continue
 			}
-
-			// Set pending limits to avoid overwhelming NATS with slow consumers
-			// This sets how many messages/bytes can be pending before NATS drops them
-			if err := sub.SetPendingLimits(256, 1024*1024); err != nil {
-				utils.Info("Error setting pending limits: %v", err)
+			subscribe(subject, request.ResumeFrom)
+
+		case "resume":
+			// A reconnecting client presents the last stream sequence it
+			// received per subject; missed durable events are replayed
+			// before live delivery continues
+			if len(request.Positions) > wsMaxSubscriptions {
+				errorJSON, _ := json.Marshal(map[string]string{
+					"error": fmt.Sprintf("resume accepts at most %d subjects", wsMaxSubscriptions),
+				})
+				queue.Push("", errorJSON)
+				continue
+			}
+			for subject, seq := range request.Positions {
+				subscribe(subject, seq)
 			}
 
-			// Store subscription
-			subscriptions[subject] = sub
+		case "historical":
+			// Request history; the chunks arrive on the data subject and
+			// the request's progress is relayed until it is done
+			params, err := market.NormalizeHistoricalParams(request.Ticker, request.Interval, request.Days)
+			if err != nil {
+				errorJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+				queue.Push("", errorJSON)
+				continue
+			}
+			dataSubject := fmt.Sprintf(events.SubjectMarketHistoricalData, params.Ticker, params.Interval, params.Days)
+			subscribe(dataSubject, 0)
+			if _, err := g.requestHistorical(client, params, dataSubject, request.Priority); err != nil {
+				utils.Info("Error requesting historical data for %s: %v", params.Ticker, err)
+				errorJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+				queue.Push("", errorJSON)
+			}
 
-			// Confirm subscription
-			conn.WriteJSON(map[string]string{
-				"event":   "subscribed",
-				"subject": subject,
-			})
+		case "cancel":
+			// Stop a backtest job or historical request the client no
+			// longer needs; its progress reports "cancelled"
+			if request.JobID != "" {
+				if err := g.cancelClientBacktest(client, request.JobID); err != nil {
+					errorJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+					queue.Push("", errorJSON)
+				}
+				continue
+			}
+			if err := g.cancelHistorical(request.RequestID); err != nil {
+				errorJSON, _ := json.Marshal(map[string]string{"error": err.Error()})
+				queue.Push("", errorJSON)
+			}
 
 		case "unsubscribe":
-			// Determine NATS subject
-			var subject string
-			if request.Subject != "" {
-				subject = request.Subject
-			} else {
-				switch request.Type {
-				case "market":
-					subject = fmt.Sprintf("market.live.%s", request.Ticker)
-				case "signals":
-					subject = fmt.Sprintf("signals.%s", request.Ticker)
-				case "recommendations":
-					subject = fmt.Sprintf("recommendations.%s", request.Ticker)
-				default:
-					continue // Unknown type
-				}
+			if request.Type == "watchlist" {
+				g.unfollowWatchlist(client)
+				continue
 			}
 
-			// Check if subscribed
-			sub, exists := subscriptions[subject]
-			if !exists {
+			// Determine NATS subject
+			subject, err := wsSubject(request.Type, request.Ticker, request.Interval, request.Subject)
+			if err != nil {
 				continue
 			}
 
-			// Unsubscribe
-			sub.Unsubscribe()
-			delete(subscriptions, subject)
+			// Unsubscribe, if subscribed
+			if !client.Unsubscribe(subject) {
+				continue
+			}
 
 			// Confirm unsubscription
-			conn.WriteJSON(map[string]string{
+			confirmation, _ := json.Marshal(map[string]string{
 				"event":   "unsubscribed",
 				"subject": subject,
 			})
+			queue.Push("", confirmation)
 		}
 	}
 }
@@ -1163,12 +1946,29 @@ func (g *APIGateway) Serve(addr string) error {
 	// Configure server
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      g.router,
+		Handler:      g.access.Middleware(profiling.Guard(g.router, profiling.EnabledFromEnv(), g.authorizeDebug)),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  120 * time.Second,
 	}
 
+	// Start scheduled scans and reports
+	g.scheduler.Start()
+
+	// Announce the gateway until shutdown
+	heartbeatCtx, stopHeartbeat := context.WithCancel(context.Background())
+	defer stopHeartbeat()
+	g.startHeartbeat(heartbeatCtx)
+
+	// Force down WebSocket goroutines and subscriptions left by abnormal closes
+	g.wsConns.Start(heartbeatCtx)
+
+	// Probe the trading service while degraded so the mode recovers on its own
+	go g.probeTradingService(heartbeatCtx)
+
+	// Serve gRPC clients alongside REST
+	g.serveGRPC()
+
 	// Start server in a goroutine
 	go func() {
 		utils.Info("API Gateway listening on %s", addr)
@@ -1187,13 +1987,13 @@ func (g *APIGateway) Serve(addr string) error {
 	utils.Info("Shutting down server...")
 
 	// Close all WebSocket connections first to avoid hanging
-	g.wsClientsMutex.Lock()
-	for conn := range g.wsClients {
-		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "Server shutting down"))
-		conn.Close()
-		delete(g.wsClients, conn)
-	}
-	g.wsClientsMutex.Unlock()
+	g.wsConns.CloseAll(websocket.FormatCloseMessage(websocket.CloseNormalClosure, "Server shutting down"))
+
+	// Stop scheduled jobs before their dependencies go away
+	g.scheduler.Stop()
+
+	// Stop gRPC streams; subscribers reconnect to another gateway
+	g.grpcServer.Stop()
 
 	// Close NATS client before closing HTTP server to avoid hanging NATS subscriptions
 	if g.natsClient != nil {
@@ -1237,6 +2037,11 @@ func main() {
 		addr = ":5000"
 	}
 
+	// Load the exchange zone before anything schedules or rolls days by it
+	if _, err := clock.FromEnv(""); err != nil {
+		utils.Fatal("Invalid time zone configuration: %v", err)
+	}
+
 	// Create API Gateway
 	gateway, err := NewAPIGateway(natsURL, tradingServiceURL)
 	if err != nil {
@@ -1250,4 +2055,4 @@ func main() {
 	if err := gateway.Serve(addr); err != nil {
 		utils.Fatal("Server error: %v", err)
 	}
-}
\ No newline at end of file
+}
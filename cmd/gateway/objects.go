@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/config"
+	"github.com/myapp/tradinglab/pkg/parquet"
+	"github.com/myapp/tradinglab/pkg/report"
+	"github.com/myapp/tradinglab/pkg/storage"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// Key prefixes in the object store. Only reports and exports can be
+// downloaded through the gateway.
+const (
+	objectReports   = "reports/"
+	objectExports   = "exports/"
+	objectSnapshots = "snapshots/"
+)
+
+// downloadTypes are content types for downloads, which mime.TypeByExtension
+// only knows on some systems
+var downloadTypes = map[string]string{
+	".csv":     "text/csv",
+	".json":    "application/json",
+	".html":    "text/html; charset=utf-8",
+	".parquet": parquet.ContentType,
+}
+
+// downloadLink is where a stored object can be fetched. ExpiresAt is set for
+// pre-signed URLs.
+type downloadLink struct {
+	Key       string     `json:"key"`
+	URL       string     `json:"url"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// newObjectStore opens the object store configured in pkg/config. The file
+// backend defaults to ARTIFACTS_DIR, so exports land in the directory artifact
+// retention manages. An invalid config or a store that cannot be opened is an
+// error, so a misconfigured gateway does not quietly write to local disk.
+func newObjectStore() (storage.Store, config.ObjectStorage, error) {
+	cfg, err := config.LoadObjectStorage()
+	if err != nil {
+		return nil, config.ObjectStorage{}, err
+	}
+	if cfg.Backend == config.ObjectStoreFile && cfg.Dir == "" {
+		cfg.Dir = artifactsDir()
+	}
+
+	store, err := storage.Open(cfg)
+	if err != nil {
+		return nil, config.ObjectStorage{}, fmt.Errorf("failed to open object storage: %w", err)
+	}
+	utils.Info("Storing objects in %s", store.Name())
+	return store, cfg, nil
+}
+
+// downloadable reports whether a key may be fetched through the gateway
+func downloadable(key string) bool {
+	return storage.ValidKey(key) && (strings.HasPrefix(key, objectReports) || strings.HasPrefix(key, objectExports))
+}
+
+// downloadLink returns a pre-signed URL for a key when the store supports
+// them, and the gateway's download route otherwise
+func (g *APIGateway) downloadLink(key string) (downloadLink, error) {
+	presigner, ok := g.objects.(storage.Presigner)
+	if !ok {
+		return downloadLink{Key: key, URL: "/api/downloads/" + key}, nil
+	}
+
+	expiry := time.Duration(g.objectConfig.URLExpiry)
+	url, err := presigner.PresignGet(key, expiry)
+	if err != nil {
+		return downloadLink{}, err
+	}
+	expires := time.Now().Add(expiry).UTC()
+	return downloadLink{Key: key, URL: url, ExpiresAt: &expires}, nil
+}
+
+// putObject writes data to the object store
+func (g *APIGateway) putObject(ctx context.Context, key string, data []byte, contentType string) error {
+	return g.objects.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// publishReport copies a daily report, as JSON and HTML, to an S3 object
+// store so it can be shared with pre-signed links. The local report store
+// stays the source of truth, so nothing is published with the file backend.
+func (g *APIGateway) publishReport(ctx context.Context, daily *report.Daily) error {
+	if g.objectConfig.Backend != config.ObjectStoreS3 {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(daily, "", "  ")
+	if err != nil {
+		return err
+	}
+	html, err := report.RenderHTML(daily)
+	if err != nil {
+		return err
+	}
+
+	name := objectReports + "daily-" + daily.Date
+	if err := g.putObject(ctx, name+".json", data, "application/json"); err != nil {
+		return err
+	}
+	return g.putObject(ctx, name+".html", html, "text/html; charset=utf-8")
+}
+
+// downloadHandler serves a report or export from the object store. Stores
+// that pre-sign URLs redirect there; pass redirect=false to get the link as
+// JSON instead. The file backend streams the object.
+func (g *APIGateway) downloadHandler(w http.ResponseWriter, r *http.Request) {
+	key := mux.Vars(r)["key"]
+	if !downloadable(key) {
+		http.Error(w, "download not found", http.StatusNotFound)
+		return
+	}
+
+	if _, ok := g.objects.(storage.Presigner); ok {
+		link, err := g.downloadLink(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Query().Get("redirect") == "false" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(link)
+			return
+		}
+		http.Redirect(w, r, link.URL, http.StatusTemporaryRedirect)
+		return
+	}
+
+	body, err := g.objects.Get(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "download not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	contentType := downloadTypes[path.Ext(key)]
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(key)}))
+	io.Copy(w, body)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/auth"
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// consumerJanitorJob is the scheduler job name for pruning stale consumers
+const consumerJanitorJob = "consumer-janitor"
+
+// defaultConsumerJanitorSchedule prunes stale consumers hourly
+const defaultConsumerJanitorSchedule = "30 * * * *"
+
+// requireAdmin checks the request's principal may change runtime
+// configuration and writes an error response if not. Admins are identified
+// by ADMIN_TOKEN, an admin API token or a trusted proxy user.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	return requirePermission(w, r, auth.PermConfig)
+}
+
+// authorizeDebug admits callers with the config permission to the pprof
+// and expvar endpoints, which sit outside the API routes and middleware
+func (g *APIGateway) authorizeDebug(w http.ResponseWriter, r *http.Request) bool {
+	return requireAdmin(w, g.authenticate(r))
+}
+
+// streamUsageHandler reports message counts, bytes and oldest message age per stream
+func (g *APIGateway) streamUsageHandler(w http.ResponseWriter, r *http.Request) {
+	usage, err := g.natsClient.StreamUsage()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	var totalBytes, totalMessages uint64
+	for _, u := range usage {
+		totalBytes += u.Bytes
+		totalMessages += u.Messages
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"streams":        usage,
+		"total_bytes":    totalBytes,
+		"total_messages": totalMessages,
+		"timestamp":      time.Now().Format(time.RFC3339),
+	})
+}
+
+// streamPurgeHandler purges a stream, optionally limited to a subject and to
+// messages older than older_than (e.g. 72h). Requires the admin token.
+func (g *APIGateway) streamPurgeHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	stream := mux.Vars(r)["name"]
+	subject := r.URL.Query().Get("subject")
+
+	var olderThan time.Duration
+	if value := r.URL.Query().Get("older_than"); value != "" {
+		var err error
+		if olderThan, err = time.ParseDuration(value); err != nil || olderThan <= 0 {
+			http.Error(w, "older_than must be a positive duration such as 72h", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := g.natsClient.PurgeStream(stream, subject, olderThan)
+	if err != nil {
+		if errors.Is(err, events.ErrUnknownStream) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, fmt.Sprintf("error purging stream: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+	utils.Info("Purged %d messages from stream %s (subject %q, older than %s)", result.Purged, stream, subject, olderThan)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// consumerMaxIdle returns how long an unbound consumer may sit idle before
+// it is pruned, from CONSUMER_MAX_IDLE
+func consumerMaxIdle() time.Duration {
+	if value := os.Getenv("CONSUMER_MAX_IDLE"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err == nil && d > 0 {
+			return d
+		}
+		utils.Warn("Invalid CONSUMER_MAX_IDLE %q, using %v", value, events.DefaultConsumerMaxIdle)
+	}
+	return events.DefaultConsumerMaxIdle
+}
+
+// scheduleConsumerJanitor registers the job that deletes durable consumers
+// left behind by processes that did not shut down cleanly. Set
+// CONSUMER_JANITOR_SCHEDULE to a cron expression to change when it runs, or
+// to "off" to disable it.
+func (g *APIGateway) scheduleConsumerJanitor() {
+	schedule := os.Getenv("CONSUMER_JANITOR_SCHEDULE")
+	if schedule == "" {
+		schedule = defaultConsumerJanitorSchedule
+	}
+	if schedule == "off" || g.natsClient == nil {
+		return
+	}
+
+	maxIdle := consumerMaxIdle()
+	err := g.scheduler.Add(consumerJanitorJob, schedule, time.UTC, time.Minute, func(ctx context.Context) error {
+		pruned, err := g.natsClient.PruneConsumers(maxIdle)
+		for _, p := range pruned {
+			utils.Info("Pruned consumer %s on %s, idle %s", p.Name, p.Stream, p.Idle)
+		}
+		return err
+	})
+	if err != nil {
+		utils.Error("Failed to schedule consumer janitor: %v", err)
+		return
+	}
+	utils.Info("Scheduled consumer janitor (%s, max idle %v)", schedule, maxIdle)
+}
+
+// consumerPruneHandler deletes stale managed consumers now, optionally with a
+// max_idle (e.g. 1h) other than the configured one. Requires the admin token.
+func (g *APIGateway) consumerPruneHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	if g.natsClient == nil {
+		http.Error(w, "NATS is not connected", http.StatusServiceUnavailable)
+		return
+	}
+
+	maxIdle := consumerMaxIdle()
+	if value := r.URL.Query().Get("max_idle"); value != "" {
+		var err error
+		if maxIdle, err = time.ParseDuration(value); err != nil || maxIdle <= 0 {
+			http.Error(w, "max_idle must be a positive duration such as 1h", http.StatusBadRequest)
+			return
+		}
+	}
+
+	pruned, err := g.natsClient.PruneConsumers(maxIdle)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error pruning consumers: %v", err), http.StatusInternalServerError)
+		return
+	}
+	utils.Info("Pruned %d consumers idle for more than %v", len(pruned), maxIdle)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pruned":   pruned,
+		"max_idle": maxIdle.String(),
+	})
+}
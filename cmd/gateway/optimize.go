@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/jobs"
+	"github.com/myapp/tradinglab/pkg/optimize"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// optimizeTimeout bounds a whole parameter search; each backtest in it is
+// bounded by backtestTimeout
+const optimizeTimeout = 30 * time.Minute
+
+// maxOptimizeBody bounds an optimization request's body
+const maxOptimizeBody = 1 << 20
+
+// optimizeRequest is the body of an optimization. Parameters not in space
+// are fixed at the values given under params, as for /backtest, or their
+// defaults.
+type optimizeRequest struct {
+	Space           map[string]optimize.Range `json:"space"`
+	Fitness         string                    `json:"fitness"`
+	DrawdownPenalty *float64                  `json:"drawdown_penalty"`
+	MinTrades       *int                      `json:"min_trades"`
+	optimize.Config
+}
+
+// optimizeRun is the outcome of an optimization job: the search, and the
+// best candidate's backtest, stored as an artifact like any other
+type optimizeRun struct {
+	optimize.Result
+	Space    optimize.Space         `json:"space"`
+	Fitness  optimize.Fitness       `json:"fitness"`
+	Test     string                 `json:"test"` // Profit target or ratio the best fitness was scored on
+	Results  map[string]interface{} `json:"results"`
+	Artifact string                 `json:"artifact,omitempty"`
+}
+
+// backtestOptimizeHandler queues a genetic search of a strategy's parameter
+// space as a backtest job, taking the backtest parameters of /backtest and
+// a JSON body naming the parameters to vary, e.g. {"space": {"rsi_period":
+// {"min": 5, "max": 30}}, "fitness": "profit_factor"}. Each candidate is
+// backtested on the same data and scored on its best profit target or
+// ratio. Follow the job for a progress event per generation.
+func (g *APIGateway) backtestOptimizeHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxOptimizeBody))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	var spec optimizeRequest
+	if err := json.Unmarshal(body, &spec); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("tickers") != "" {
+		http.Error(w, "portfolio backtests cannot be optimized", http.StatusBadRequest)
+		return
+	}
+
+	// The body also carries the fixed strategy parameters
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	req, strategy, err := g.backtestRequest(r, r.URL.Query().Get("ticker"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fitness := optimize.DefaultFitness
+	if spec.Fitness != "" {
+		fitness.Name = spec.Fitness
+	}
+	if spec.DrawdownPenalty != nil {
+		fitness.DrawdownPenalty = *spec.DrawdownPenalty
+	}
+	if spec.MinTrades != nil {
+		fitness.MinTrades = *spec.MinTrades
+	}
+	def, _ := g.strategies.Get(strategy)
+	space, err := optimize.SpaceFor(def, spec.Space)
+	if err == nil {
+		err = fitness.Validate()
+	}
+	if err == nil {
+		err = g.limitParallelism(&spec.Config)
+	}
+	if err == nil {
+		err = spec.Config.Validate()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !g.allowUsage(w, r, usageBacktests) {
+		return
+	}
+
+	// Every backtest of the search is charged to the caller
+	job, err := g.backtestJobs.Submit(r.Context(), usageUser(r.Context()), "optimize", func(jobCtx context.Context) (interface{}, error) {
+		id := jobs.IDFrom(jobCtx)
+		g.publishBacktestProgress(events.BacktestProgress{JobID: id, Stage: events.BacktestRunning})
+
+		ctx, cancel := context.WithTimeout(jobCtx, optimizeTimeout)
+		defer cancel()
+
+		run, err := g.runOptimization(ctx, req, strategy, space, fitness, spec.Config)
+		switch {
+		case jobCtx.Err() != nil:
+			// Cancellation was announced by whoever cancelled the job
+			return nil, jobCtx.Err()
+		case err != nil:
+			err = fmt.Errorf("error optimizing %s: %w", strategy, err)
+			g.publishBacktestProgress(events.BacktestProgress{JobID: id, Stage: events.BacktestFailed, Error: err.Error()})
+			return nil, err
+		}
+		g.publishBacktestProgress(events.BacktestProgress{JobID: id, Stage: events.BacktestDone, Percent: 100,
+			Generation: len(run.History), BestFitness: run.Best.Fitness})
+		return run, nil
+	})
+	switch {
+	case errors.Is(err, jobs.ErrOwnerLimit):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	case err != nil:
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/backtest/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// limitParallelism bounds a search's backtests running at once by the
+// backtests its owner may run at once on the worker pool, since the search
+// holds only one worker. It defaults to that bound and refuses more.
+func (g *APIGateway) limitParallelism(cfg *optimize.Config) error {
+	limits := g.backtestJobs.Limits()
+	limit := min(limits.RunningPerOwner, limits.Workers, optimize.MaxParallelism)
+	if cfg.Parallelism == 0 {
+		cfg.Parallelism = limit
+	}
+	if cfg.Parallelism > limit {
+		return fmt.Errorf("parallelism must be at most %d, the backtests one user may run at once", limit)
+	}
+	return nil
+}
+
+// runOptimization searches the space for the parameters of req's strategy
+// scoring highest, then runs the best of them as a regular backtest so its
+// results are kept
+func (g *APIGateway) runOptimization(ctx context.Context, req *pb.BacktestRequest, strategy string,
+	space optimize.Space, fitness optimize.Fitness, cfg optimize.Config) (optimizeRun, error) {
+	jobID := jobs.IDFrom(ctx)
+
+	// The test each candidate scored best on, by its parameters
+	var mu sync.Mutex
+	tests := make(map[string]string)
+
+	// resolve fills in the fixed parameters around a candidate's values
+	resolve := func(values map[string]interface{}) (map[string]string, error) {
+		merged := make(map[string]interface{}, len(req.Parameters))
+		for name, value := range req.Parameters {
+			merged[name] = value
+		}
+		for name, value := range values {
+			merged[name] = value
+		}
+		return g.strategies.Resolve(strategy, merged)
+	}
+
+	cfg = cfg.WithDefaults()
+	cfg.OnGeneration = func(gen optimize.Generation) {
+		g.publishBacktestProgress(events.BacktestProgress{
+			JobID:       jobID,
+			Stage:       events.BacktestRunning,
+			Generation:  gen.Number,
+			BestFitness: gen.Best,
+			Percent:     float64(gen.Number) / float64(cfg.Generations) * 100,
+		})
+	}
+
+	result, err := optimize.Run(ctx, space, cfg, func(ctx context.Context, values map[string]interface{}) (float64, bool, error) {
+		if g.usage.Exceeded(usageUser(ctx), usageBacktests) {
+			return 0, false, errUsageLimit(usageBacktests, g.usage.limits.Backtests)
+		}
+		params, err := resolve(values)
+		if err != nil {
+			return 0, false, err
+		}
+		candidate := proto.Clone(req).(*pb.BacktestRequest)
+		candidate.Parameters = params
+
+		callCtx, cancel := context.WithTimeout(ctx, backtestTimeout)
+		defer cancel()
+		resp, err := g.tradingClient.RunBacktest(callCtx, candidate)
+		if err != nil {
+			return 0, false, err
+		}
+
+		score, eligible, test := math.Inf(-1), false, ""
+		for name, result := range resp.Results {
+			s, ok := fitness.Score(optimize.Metrics{
+				SharpeRatio:    result.SharpeRatio,
+				ProfitFactor:   result.ProfitFactor,
+				MaxDrawdownPct: result.MaxDrawdownPct,
+				TotalTrades:    int(result.TotalTrades),
+			})
+			if ok && (s > score || (s == score && name < test)) {
+				score, eligible, test = s, true, name
+			}
+		}
+		mu.Lock()
+		tests[paramsCacheKey(params)] = test
+		mu.Unlock()
+		return score, eligible, nil
+	})
+	if err != nil {
+		return optimizeRun{}, err
+	}
+
+	params, err := resolve(result.Best.Params)
+	if err != nil {
+		return optimizeRun{}, err
+	}
+	best := proto.Clone(req).(*pb.BacktestRequest)
+	best.Parameters = params
+	backtestCtx, cancel := context.WithTimeout(ctx, backtestTimeout)
+	defer cancel()
+	run, err := g.runBacktest(backtestCtx, best, strategy, false)
+	if err != nil {
+		return optimizeRun{}, fmt.Errorf("error running the best candidate: %w", err)
+	}
+
+	return optimizeRun{
+		Result:   result,
+		Space:    space,
+		Fitness:  fitness,
+		Test:     tests[paramsCacheKey(params)],
+		Results:  run.Results,
+		Artifact: run.Artifact,
+	}, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// optionsBacktestTimeout allows for fetching a chain snapshot per trading day
+const optionsBacktestTimeout = 2 * time.Minute
+
+// optionsBacktestHandler simulates options trades on a strategy's signals.
+// Premiums are modeled with Black-Scholes by default, or taken from historical
+// chain snapshots with pricing=chain.
+func (g *APIGateway) optionsBacktestHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	ticker, err := market.ValidateTicker(query.Get("ticker"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	strategy := query.Get("strategy")
+	if strategy == "" {
+		strategy = "RedCandle"
+	}
+
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = "15min"
+	}
+
+	pricing := query.Get("pricing")
+	if pricing == "" {
+		pricing = "model"
+	}
+	if pricing != "model" && pricing != "chain" {
+		http.Error(w, "pricing must be model or chain", http.StatusBadRequest)
+		return
+	}
+
+	ints := map[string]int{"days": 30, "dte": 30, "contracts": 1}
+	for name := range ints {
+		if value := query.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				http.Error(w, fmt.Sprintf("invalid %s parameter", name), http.StatusBadRequest)
+				return
+			}
+			ints[name] = n
+		}
+	}
+	if ints["dte"] > maxOptionsDTE {
+		http.Error(w, fmt.Sprintf("dte must be at most %d", maxOptionsDTE), http.StatusBadRequest)
+		return
+	}
+	if ints["contracts"] > maxOptionsContracts {
+		http.Error(w, fmt.Sprintf("contracts must be at most %d", maxOptionsContracts), http.StatusBadRequest)
+		return
+	}
+
+	// Normalize parameters and bound the lookback for the interval
+	params, err := market.NormalizeHistoricalParams(ticker, interval, ints["days"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	interval = params.Interval
+
+	floats := map[string]float64{"delta": 0.45, "risk_reward": 2, "premium_target": 0, "premium_stop": 0, "commission": 0}
+	for name, def := range floats {
+		value, err := queryFloat(r, name, def)
+		if err != nil || value < 0 {
+			http.Error(w, fmt.Sprintf("invalid %s parameter", name), http.StatusBadRequest)
+			return
+		}
+		floats[name] = value
+	}
+	if floats["delta"] <= 0 || floats["delta"] >= 1 {
+		http.Error(w, "delta must be between 0 and 1", http.StatusBadRequest)
+		return
+	}
+
+	// Validate strategy parameters and fill in defaults
+	strategyParams, err := g.strategyParams(r, strategy)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, isPlugin := g.plugins.Get(strategy); isPlugin {
+		http.Error(w, "backtests are not supported for plugin strategies", http.StatusBadRequest)
+		return
+	}
+
+	if !g.allowUsage(w, r, usageBacktests) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), optionsBacktestTimeout)
+	defer cancel()
+
+	req := &pb.OptionsBacktestRequest{
+		Ticker:           ticker,
+		Days:             int32(ints["days"]),
+		Strategy:         g.strategies.EngineName(strategy),
+		Interval:         interval,
+		Parameters:       strategyParams,
+		Pricing:          pricing,
+		DaysToExpiration: int32(ints["dte"]),
+		TargetDelta:      floats["delta"],
+		RiskRewardRatio:  floats["risk_reward"],
+		PremiumTargetPct: floats["premium_target"],
+		PremiumStopPct:   floats["premium_stop"],
+		Contracts:        int32(ints["contracts"]),
+		Commission:       floats["commission"],
+	}
+	value, err := g.runQueued(ctx, "options", func(ctx context.Context) (interface{}, error) {
+		return g.tradingClient.RunOptionsBacktest(ctx, req)
+	})
+	if writeQueueError(w, err) {
+		return
+	}
+	if err != nil {
+		code := http.StatusInternalServerError
+		switch status.Code(err) {
+		case codes.InvalidArgument:
+			code = http.StatusBadRequest
+		case codes.FailedPrecondition:
+			code = http.StatusServiceUnavailable
+		case codes.NotFound:
+			code = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("error running options backtest: %v", status.Convert(err).Message()), code)
+		return
+	}
+
+	resp := value.(*pb.OptionsBacktestResponse)
+
+	trades := make([]map[string]interface{}, 0, len(resp.Trades))
+	for _, t := range resp.Trades {
+		trades = append(trades, map[string]interface{}{
+			"entry_date":       t.EntryDate,
+			"exit_date":        t.ExitDate,
+			"signal_type":      t.SignalType,
+			"option_type":      t.OptionType,
+			"strike":           t.Strike,
+			"expiration":       t.Expiration,
+			"entry_premium":    t.EntryPremium,
+			"exit_premium":     t.ExitPremium,
+			"underlying_entry": t.UnderlyingEntry,
+			"underlying_exit":  t.UnderlyingExit,
+			"exit_reason":      t.ExitReason,
+			"pnl":              t.Pnl,
+			"return_pct":       t.ReturnPct,
+			"hold_days":        t.HoldDays,
+			"theta_cost":       t.ThetaCost,
+			"iv":               t.Iv,
+		})
+	}
+
+	summary := resp.Summary
+	if summary == nil {
+		summary = &pb.OptionsBacktestSummary{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ticker":   ticker,
+		"strategy": strategy,
+		"pricing":  resp.Pricing,
+		"summary": map[string]interface{}{
+			"total_trades":      summary.TotalTrades,
+			"winning_trades":    summary.WinningTrades,
+			"losing_trades":     summary.LosingTrades,
+			"win_rate":          summary.WinRate,
+			"total_pnl":         summary.TotalPnl,
+			"avg_pnl":           summary.AvgPnl,
+			"avg_premium":       summary.AvgPremium,
+			"avg_return_pct":    summary.AvgReturnPct,
+			"profit_factor":     summary.ProfitFactor,
+			"max_drawdown":      summary.MaxDrawdown,
+			"expired_worthless": summary.ExpiredWorthless,
+			"avg_hold_days":     summary.AvgHoldDays,
+			"total_theta_cost":  summary.TotalThetaCost,
+		},
+		"trades": trades,
+	})
+}
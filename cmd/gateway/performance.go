@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+	"github.com/myapp/tradinglab/pkg/journal"
+)
+
+// Trade history sources for performance analytics
+const (
+	performanceSourcePaper   = "paper"   // Positions closed through the risk engine
+	performanceSourceJournal = "journal" // All journaled trades, including imported backtest trades
+)
+
+// defaultRollingWindow is the number of trades in each rolling statistics window
+const defaultRollingWindow = 20
+
+// performanceHandler computes performance statistics from trade history
+func (g *APIGateway) performanceHandler(w http.ResponseWriter, r *http.Request) {
+	ticker := r.URL.Query().Get("ticker")
+	strategy := r.URL.Query().Get("strategy")
+
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		source = performanceSourcePaper
+	}
+
+	window := defaultRollingWindow
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		var err error
+		window, err = strconv.Atoi(windowStr)
+		if err != nil || window <= 0 {
+			http.Error(w, "invalid window parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var trades []analytics.TradeResult
+	switch source {
+	case performanceSourcePaper:
+		for _, closed := range g.risk.ClosedPositions() {
+			if ticker != "" && !strings.EqualFold(closed.Ticker, ticker) {
+				continue
+			}
+			if strategy != "" && !strings.EqualFold(closed.Strategy, strategy) {
+				continue
+			}
+			trades = append(trades, analytics.TradeResult{
+				Time:    closed.ClosedAt,
+				PnL:     closed.RealizedPnL,
+				Return:  closed.RealizedPnL / closed.Exposure(),
+				RiskAmt: closed.InitialRisk(),
+			})
+		}
+	case performanceSourceJournal:
+		for _, entry := range g.journal.Find(journal.Query{Ticker: ticker, Strategy: strategy}) {
+			committed := entry.EntryPrice * float64(entry.Quantity)
+			trade := analytics.TradeResult{Time: entry.TradeTime, PnL: entry.PnL}
+			if committed > 0 {
+				trade.Return = entry.PnL / committed
+			}
+			trades = append(trades, trade)
+		}
+	default:
+		http.Error(w, "source must be one of: paper, journal", http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"source":  source,
+		"window":  window,
+		"stats":   analytics.ComputePerformance(trades),
+		"rolling": analytics.RollingPerformance(trades, window),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/risk"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// pnlSubject is the WebSocket subscription key for P&L updates; a ticker
+// suffix ("pnl.AAPL") limits updates to that ticker's positions
+const pnlSubject = "pnl"
+
+// defaultPnLInterval is the minimum time between P&L updates to a client
+const defaultPnLInterval = time.Second
+
+// pnlIntervalFromEnv reads PNL_UPDATE_INTERVAL
+func pnlIntervalFromEnv() time.Duration {
+	value := os.Getenv("PNL_UPDATE_INTERVAL")
+	if value == "" {
+		return defaultPnLInterval
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		utils.Warn("Invalid PNL_UPDATE_INTERVAL %q, using %v", value, defaultPnLInterval)
+		return defaultPnLInterval
+	}
+	return d
+}
+
+// isPnLSubject reports whether a subscription key is a P&L stream
+func isPnLSubject(subject string) bool {
+	return subject == pnlSubject || strings.HasPrefix(subject, pnlSubject+".")
+}
+
+// positionPnL is the mark-to-market state of one open position
+type positionPnL struct {
+	risk.Position
+	MarkPrice     float64 `json:"mark_price"`
+	Marked        bool    `json:"marked"` // False until a live tick arrives; marked at entry until then
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+	UnrealizedPct float64 `json:"unrealized_pnl_pct"`
+}
+
+// pnlUpdate is the message streamed to "pnl" subscribers
+type pnlUpdate struct {
+	Event         string        `json:"event"`
+	Timestamp     time.Time     `json:"timestamp"`
+	Positions     []positionPnL `json:"positions"`
+	UnrealizedPnL float64       `json:"unrealized_pnl"`
+	DailyRealized float64       `json:"daily_realized_pnl"`
+}
+
+// pnlStream marks a client's open simulated positions to live ticks and
+// pushes throttled P&L updates
+type pnlStream struct {
+	g        *APIGateway
+	subject  string
+	ticker   string // Empty for all positions
+	queue    *wsClientQueue
+	interval time.Duration
+
+	mu       sync.Mutex
+	sub      *nats.Subscription
+	marks    map[string]float64
+	pending  bool
+	lastSent time.Time
+}
+
+// subscribePnL starts streaming P&L updates for open simulated positions.
+// An update is sent right away, then at most once per interval while ticks
+// move the marks.
+func (g *APIGateway) subscribePnL(subject string, queue *wsClientQueue) (*nats.Subscription, error) {
+	s := &pnlStream{
+		g:        g,
+		subject:  subject,
+		ticker:   market.NormalizeTicker(strings.TrimPrefix(strings.TrimPrefix(subject, pnlSubject), ".")),
+		queue:    queue,
+		interval: g.pnlInterval,
+		marks:    make(map[string]float64),
+	}
+
+	sub, err := g.natsClient.GetNATS().Subscribe(events.SubjectMarketLiveAll, s.onTick)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.sub = sub
+	s.mu.Unlock()
+
+	queue.Push("", subscribedEvent(subject, 0, false))
+	s.send()
+	return sub, nil
+}
+
+// onTick records the latest price of a held ticker and schedules an update
+func (s *pnlStream) onTick(msg *nats.Msg) {
+	data, err := events.Decode(msg)
+	if err != nil {
+		return
+	}
+	var tick struct {
+		Ticker string  `json:"ticker"`
+		Price  float64 `json:"price"`
+		Close  float64 `json:"close"`
+	}
+	if err := json.Unmarshal(data, &tick); err != nil {
+		return
+	}
+	if tick.Ticker == "" {
+		tick.Ticker = strings.TrimPrefix(msg.Subject, "market.live.")
+	}
+	ticker := market.NormalizeTicker(tick.Ticker)
+	price := tick.Price
+	if price <= 0 {
+		price = tick.Close
+	}
+	if price <= 0 || !s.holds(ticker) {
+		return
+	}
+
+	s.mu.Lock()
+	s.marks[ticker] = price
+	if s.pending {
+		s.mu.Unlock()
+		return
+	}
+	s.pending = true
+	wait := s.interval - time.Since(s.lastSent)
+	s.mu.Unlock()
+
+	if wait <= 0 {
+		s.send()
+		return
+	}
+	time.AfterFunc(wait, s.send)
+}
+
+// holds reports whether a tick for ticker can change the client's P&L
+func (s *pnlStream) holds(ticker string) bool {
+	if s.ticker != "" && ticker != s.ticker {
+		return false
+	}
+	for _, pos := range s.g.risk.Positions() {
+		if pos.Simulated && pos.Ticker == ticker {
+			return true
+		}
+	}
+	return false
+}
+
+// send pushes the current P&L unless the client has unsubscribed
+func (s *pnlStream) send() {
+	s.mu.Lock()
+	s.pending = false
+	s.lastSent = time.Now()
+	sub := s.sub
+	marks := make(map[string]float64, len(s.marks))
+	for ticker, price := range s.marks {
+		marks[ticker] = price
+	}
+	s.mu.Unlock()
+
+	if sub != nil && !sub.IsValid() {
+		return
+	}
+
+	update := pnlUpdate{
+		Event:         "pnl",
+		Timestamp:     time.Now(),
+		Positions:     []positionPnL{},
+		DailyRealized: s.g.risk.Status().DailyRealized,
+	}
+	for _, pos := range s.g.risk.Positions() {
+		if !pos.Simulated || (s.ticker != "" && pos.Ticker != s.ticker) {
+			continue
+		}
+		entry := positionPnL{Position: pos, MarkPrice: pos.EntryPrice}
+		if price, ok := marks[pos.Ticker]; ok {
+			entry.MarkPrice = price
+			entry.Marked = true
+		}
+		entry.UnrealizedPnL = pos.PnL(entry.MarkPrice)
+		if exposure := pos.Exposure(); exposure != 0 {
+			entry.UnrealizedPct = entry.UnrealizedPnL / exposure * 100
+		}
+		update.Positions = append(update.Positions, entry)
+		update.UnrealizedPnL += entry.UnrealizedPnL
+	}
+
+	data, err := json.Marshal(update)
+	if err != nil {
+		utils.Error("Failed to encode P&L update: %v", err)
+		return
+	}
+	s.queue.Push(s.subject, data)
+}
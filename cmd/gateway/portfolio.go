@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+const (
+	// maxPortfolioTickers bounds a portfolio backtest, which fetches and
+	// simulates each ticker in turn
+	maxPortfolioTickers = 20
+
+	// portfolioBacktestTimeout allows for fetching history for every ticker
+	portfolioBacktestTimeout = 2 * time.Minute
+
+	defaultPortfolioCapital = 100000.0
+)
+
+// portfolioAllocations are the ways capital can be divided between tickers
+var portfolioAllocations = map[string]bool{"equal": true, "weights": true, "inverse_volatility": true}
+
+// portfolioBacktestHandler backtests a strategy across several tickers
+// sharing one pool of capital, e.g. tickers=AAPL,MSFT&allocation=weights&
+// weights=AAPL:0.6,MSFT:0.4. Results are portfolio-level, with the equity
+// curve and each ticker's share and own statistics under portfolio.
+func (g *APIGateway) portfolioBacktestHandler(w http.ResponseWriter, r *http.Request) {
+	req, strategy, err := g.portfolioBacktestRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	refresh, err := refreshParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !g.allowUsage(w, r, usageBacktests) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), portfolioBacktestTimeout)
+	defer cancel()
+
+	run, err := g.runBacktest(ctx, req, strategy, refresh)
+	if writeQueueError(w, err) {
+		return
+	}
+	if err != nil {
+		code := http.StatusInternalServerError
+		if status.Code(err) == codes.InvalidArgument {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, fmt.Sprintf("error running portfolio backtest: %v", status.Convert(err).Message()), code)
+		return
+	}
+	setBacktestHeaders(w, run)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run.Results)
+}
+
+// portfolioBacktestRequest builds a portfolio backtest from the request's
+// query parameters. It also returns the strategy name as the caller gave it.
+func (g *APIGateway) portfolioBacktestRequest(r *http.Request) (*pb.BacktestRequest, string, error) {
+	tickers, err := portfolioTickers(r.URL.Query().Get("tickers"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Everything but the tickers is parsed like a single backtest
+	req, strategy, err := g.backtestRequest(r, tickers[0])
+	if err != nil {
+		return nil, "", err
+	}
+
+	allocation, err := portfolioAllocation(r, tickers)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Benchmark metrics are per ticker, so portfolios go without them
+	req.Ticker, req.Benchmark = "", ""
+	req.Tickers = tickers
+	req.Allocation = allocation
+	return req, strategy, nil
+}
+
+// portfolioResults converts a portfolio backtest's response to a
+// JSON-friendly format
+func portfolioResults(req *pb.BacktestRequest, resp *pb.BacktestResponse) map[string]interface{} {
+	portfolio := make(map[string]interface{}, len(resp.Portfolio))
+	for name, result := range resp.Portfolio {
+		portfolio[name] = portfolioResult(result)
+	}
+	missing := resp.Missing
+	if missing == nil {
+		missing = []string{}
+	}
+	return map[string]interface{}{
+		"tickers":    req.Tickers,
+		"allocation": req.Allocation.Method,
+		"results":    backtestResults(resp.Results),
+		"portfolio":  portfolio,
+		"missing":    missing,
+	}
+}
+
+// portfolioTickers parses a comma-separated list of two or more distinct
+// tickers
+func portfolioTickers(value string) ([]string, error) {
+	if value == "" {
+		return nil, fmt.Errorf("tickers parameter is required")
+	}
+
+	seen := make(map[string]bool)
+	var tickers []string
+	for _, item := range strings.Split(value, ",") {
+		ticker, err := market.ValidateTicker(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		if seen[ticker] {
+			continue
+		}
+		seen[ticker] = true
+		tickers = append(tickers, ticker)
+	}
+
+	if len(tickers) < 2 {
+		return nil, fmt.Errorf("a portfolio needs at least 2 tickers")
+	}
+	if len(tickers) > maxPortfolioTickers {
+		return nil, fmt.Errorf("a portfolio can have at most %d tickers", maxPortfolioTickers)
+	}
+	return tickers, nil
+}
+
+// portfolioAllocation reads how capital is divided between tickers from the
+// allocation, weights and capital parameters. Weights are given as
+// TICKER:weight pairs and need not sum to one.
+func portfolioAllocation(r *http.Request, tickers []string) (*pb.PortfolioAllocation, error) {
+	query := r.URL.Query()
+
+	method := query.Get("allocation")
+	if method == "" {
+		method = "equal"
+	}
+	if !portfolioAllocations[method] {
+		return nil, fmt.Errorf("allocation must be equal, weights or inverse_volatility")
+	}
+
+	capital, err := queryFloat(r, "capital", defaultPortfolioCapital)
+	if err != nil || capital <= 0 {
+		return nil, fmt.Errorf("invalid capital parameter")
+	}
+
+	allocation := &pb.PortfolioAllocation{Method: method, InitialCapital: capital}
+	if method != "weights" {
+		if query.Get("weights") != "" {
+			return nil, fmt.Errorf("weights require allocation=weights")
+		}
+		return allocation, nil
+	}
+
+	inPortfolio := make(map[string]bool, len(tickers))
+	for _, ticker := range tickers {
+		inPortfolio[ticker] = true
+	}
+
+	allocation.Weights = make(map[string]float64)
+	total := 0.0
+	for _, pair := range strings.Split(query.Get("weights"), ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found {
+			return nil, fmt.Errorf("weights must be TICKER:weight pairs")
+		}
+		ticker, err := market.ValidateTicker(name)
+		if err != nil {
+			return nil, err
+		}
+		if !inPortfolio[ticker] {
+			return nil, fmt.Errorf("weight given for %s, which is not in tickers", ticker)
+		}
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight for %s", ticker)
+		}
+		allocation.Weights[ticker] = weight
+		total += weight
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("weights must allocate some capital")
+	}
+	return allocation, nil
+}
+
+// portfolioResult converts a portfolio's equity curve and per-ticker
+// breakdown to a JSON-friendly format
+func portfolioResult(result *pb.PortfolioResult) map[string]interface{} {
+	curve := make([]map[string]interface{}, 0, len(result.EquityCurve))
+	for _, point := range result.EquityCurve {
+		curve = append(curve, map[string]interface{}{"date": point.Date, "equity": point.Equity})
+	}
+	sort.Slice(curve, func(i, j int) bool {
+		return curve[i]["date"].(string) < curve[j]["date"].(string)
+	})
+
+	symbols := make(map[string]interface{}, len(result.Symbols))
+	for ticker, symbol := range result.Symbols {
+		entry := map[string]interface{}{
+			"weight":  symbol.Weight,
+			"capital": symbol.Capital,
+			"pnl":     symbol.Pnl,
+		}
+		if symbol.Result != nil {
+			entry["result"] = backtestResult(symbol.Result)
+		}
+		symbols[ticker] = entry
+	}
+
+	return map[string]interface{}{
+		"initial_capital": result.InitialCapital,
+		"final_equity":    result.FinalEquity,
+		"equity_curve":    curve,
+		"symbols":         symbols,
+	}
+}
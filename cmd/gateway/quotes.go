@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// consolidatedQuote is the latest top of book and last price for a ticker,
+// combined from live ticks, order book snapshots and trade prints
+type consolidatedQuote struct {
+	Ticker    string     `json:"ticker"`
+	Bid       float64    `json:"bid,omitempty"`
+	BidSize   float64    `json:"bid_size,omitempty"`
+	Ask       float64    `json:"ask,omitempty"`
+	AskSize   float64    `json:"ask_size,omitempty"`
+	Mid       float64    `json:"mid,omitempty"`
+	Spread    float64    `json:"spread,omitempty"`
+	Last      float64    `json:"last,omitempty"`
+	LastSize  int64      `json:"last_size,omitempty"` // Size of the last trade print
+	Volume    int64      `json:"volume,omitempty"`    // Volume of the last live tick
+	QuoteTime *time.Time `json:"quote_time,omitempty"`
+	LastTime  *time.Time `json:"last_time,omitempty"`
+	Updated   time.Time  `json:"updated"`
+
+	Session       string  `json:"session,omitempty"`        // Market date of the session below
+	Open          float64 `json:"open,omitempty"`           // First price of the session
+	SessionVolume int64   `json:"session_volume,omitempty"` // Live tick volume summed over the session
+}
+
+// quoteCache keeps a consolidated quote per ticker from the live streams, so
+// quote lookups never reach the provider. Updates older than what a quote
+// already holds are ignored, since the streams are not ordered with each
+// other.
+type quoteCache struct {
+	mu     sync.RWMutex
+	quotes map[string]*consolidatedQuote
+}
+
+// newQuoteCache creates an empty cache
+func newQuoteCache() *quoteCache {
+	return &quoteCache{quotes: make(map[string]*consolidatedQuote)}
+}
+
+// Get returns a copy of the quote for ticker
+func (c *quoteCache) Get(ticker string) (consolidatedQuote, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	q, ok := c.quotes[ticker]
+	if !ok {
+		return consolidatedQuote{}, false
+	}
+	return *q, true
+}
+
+// quote returns the entry for ticker, creating it; callers hold the lock
+func (c *quoteCache) quote(ticker string) *consolidatedQuote {
+	q, ok := c.quotes[ticker]
+	if !ok {
+		q = &consolidatedQuote{Ticker: ticker}
+		c.quotes[ticker] = q
+	}
+	return q
+}
+
+// UpdateBook sets the bid and ask from an order book snapshot
+func (c *quoteCache) UpdateBook(book market.OrderBook) {
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	q := c.quote(market.NormalizeTicker(book.Ticker))
+	if q.QuoteTime != nil && book.Timestamp.Before(*q.QuoteTime) {
+		return
+	}
+	at := book.Timestamp
+	q.Bid, q.BidSize = book.Bids[0].Price, book.Bids[0].Size
+	q.Ask, q.AskSize = book.Asks[0].Price, book.Asks[0].Size
+	q.Mid = (q.Bid + q.Ask) / 2
+	q.Spread = q.Ask - q.Bid
+	q.QuoteTime = &at
+	q.Updated = time.Now()
+}
+
+// UpdateLast sets the last price, with volume from live ticks or size from
+// trade prints
+func (c *quoteCache) UpdateLast(ticker string, price float64, volume, size int64, at time.Time) {
+	if price <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	q := c.quote(market.NormalizeTicker(ticker))
+	if q.LastTime != nil && at.Before(*q.LastTime) {
+		return
+	}
+	if session := market.SessionDate(at); session != q.Session {
+		q.Session, q.Open, q.SessionVolume = session, price, 0
+	}
+	q.Last = price
+	if volume > 0 {
+		q.Volume = volume
+		q.SessionVolume += volume
+	}
+	if size > 0 {
+		q.LastSize = size
+	}
+	q.LastTime = &at
+	q.Updated = time.Now()
+}
+
+// subscribeQuotes keeps the quote cache current from live ticks, order books
+// and trade prints
+func (g *APIGateway) subscribeQuotes() {
+	nc := g.natsClient.GetNATS()
+	handlers := map[string]nats.MsgHandler{
+		events.SubjectMarketLiveAll: g.onQuoteTick,
+		events.SubjectMarketBookAll: g.onQuoteBook,
+		events.SubjectTradesAll:     g.onQuoteTrade,
+	}
+	for subject, handler := range handlers {
+		if _, err := nc.Subscribe(subject, handler); err != nil {
+			utils.Error("Failed to subscribe to %s for quotes: %v", subject, err)
+		}
+	}
+}
+
+// onQuoteTick records the last price and volume of a live tick
+func (g *APIGateway) onQuoteTick(msg *nats.Msg) {
+	data, err := events.Decode(msg)
+	if err != nil {
+		return
+	}
+	var tick struct {
+		Ticker    string    `json:"ticker"`
+		Timestamp time.Time `json:"timestamp"`
+		Price     float64   `json:"price"`
+		Close     float64   `json:"close"`
+		Volume    int64     `json:"volume"`
+	}
+	if err := json.Unmarshal(data, &tick); err != nil {
+		return
+	}
+	if tick.Ticker == "" {
+		tick.Ticker = strings.TrimPrefix(msg.Subject, "market.live.")
+	}
+	if tick.Timestamp.IsZero() {
+		tick.Timestamp = time.Now()
+	}
+	price := tick.Price
+	if price <= 0 {
+		price = tick.Close
+	}
+	g.quotes.UpdateLast(tick.Ticker, price, tick.Volume, 0, tick.Timestamp)
+}
+
+// onQuoteBook records the top of an order book snapshot
+func (g *APIGateway) onQuoteBook(msg *nats.Msg) {
+	data, err := events.Decode(msg)
+	if err != nil {
+		return
+	}
+	var book market.OrderBook
+	if err := json.Unmarshal(data, &book); err != nil {
+		return
+	}
+	if book.Ticker == "" {
+		book.Ticker = strings.TrimPrefix(msg.Subject, "market.book.")
+	}
+	g.quotes.UpdateBook(book)
+}
+
+// onQuoteTrade records the price and size of a trade print
+func (g *APIGateway) onQuoteTrade(msg *nats.Msg) {
+	data, err := events.Decode(msg)
+	if err != nil {
+		return
+	}
+	var trade market.Trade
+	if err := json.Unmarshal(data, &trade); err != nil {
+		return
+	}
+	if trade.Ticker == "" {
+		trade.Ticker = strings.TrimPrefix(msg.Subject, "market.trades.")
+	}
+	g.quotes.UpdateLast(trade.Ticker, trade.Price, 0, trade.Size, trade.Timestamp)
+}
+
+// quoteHandler serves the consolidated quote for ?ticker= from the cache. A
+// ticker with nothing cached since startup is seeded from the latest stored
+// order book rather than the provider.
+func (g *APIGateway) quoteHandler(w http.ResponseWriter, r *http.Request) {
+	ticker, err := market.ValidateTicker(r.URL.Query().Get("ticker"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	q, ok := g.quotes.Get(ticker)
+	if !ok {
+		data, err := g.natsClient.LatestOrderBook(ticker)
+		if err != nil && !errors.Is(err, nats.ErrMsgNotFound) {
+			http.Error(w, fmt.Sprintf("error reading order book: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+		var book market.OrderBook
+		if err == nil && json.Unmarshal(data, &book) == nil {
+			if book.Ticker == "" {
+				book.Ticker = ticker
+			}
+			g.quotes.UpdateBook(book)
+		}
+		if q, ok = g.quotes.Get(ticker); !ok {
+			http.Error(w, fmt.Sprintf("no quote available for %s", ticker), http.StatusNotFound)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Data-Age", fmt.Sprintf("%.1f seconds", time.Since(q.Updated).Seconds()))
+	json.NewEncoder(w).Encode(q)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/auth"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// publicRoutes need no role: health checks, the caller's own identity, and
+// webhooks that carry their own signatures
+var publicRoutes = map[string]bool{
+	"GET /api/health":                true,
+	"GET /api/me":                    true,
+	"POST /api/webhooks/tradingview": true,
+	"POST /api/slack/commands":       true,
+}
+
+// routePermissions names the permission each method and route needs. GET
+// routes not listed need read; any other unlisted route needs config, so a
+// new mutating route is admin-only until it is classified here.
+var routePermissions = map[string]string{
+	// Reads that use POST for their request body
+	"POST /api/signals": auth.PermRead,
+	"POST /api/graphql": auth.PermRead,
+
+	"POST /api/exports/historical": auth.PermRead,
+	"POST /api/exports/features":   auth.PermRead,
+
+	// Cancelling a historical request only stops a read
+	"DELETE /api/requests/{id}": auth.PermRead,
+
+	"GET /api/backtest":                   auth.PermBacktest,
+	"POST /api/backtest":                  auth.PermBacktest,
+	"GET /api/backtest/options":           auth.PermBacktest,
+	"POST /api/backtest/options":          auth.PermBacktest,
+	"GET /api/backtest/portfolio":         auth.PermBacktest,
+	"POST /api/backtest/portfolio":        auth.PermBacktest,
+	"GET /api/backtest/jobs":              auth.PermBacktest,
+	"POST /api/backtest/jobs":             auth.PermBacktest,
+	"GET /api/backtest/jobs/{id}":         auth.PermBacktest,
+	"DELETE /api/backtest/jobs/{id}":      auth.PermBacktest,
+	"POST /api/backtest/optimize":         auth.PermBacktest,
+	"GET /api/backtest/{id}/equity-curve": auth.PermBacktest,
+	"GET /api/backtest/{id}/drawdown":     auth.PermBacktest,
+
+	"POST /api/alerts/subscribers":              auth.PermAlerts,
+	"DELETE /api/alerts/subscribers/{id}":       auth.PermAlerts,
+	"POST /api/alerts/subscribers/{id}/devices": auth.PermAlerts,
+	"DELETE /api/alerts/devices/{token}":        auth.PermAlerts,
+	"POST /api/alerts/rules":                    auth.PermAlerts,
+	"DELETE /api/alerts/rules/{id}":             auth.PermAlerts,
+	"POST /api/alerts/rules/{id}/rearm":         auth.PermAlerts,
+
+	"POST /api/risk/positions":             auth.PermTrade,
+	"DELETE /api/risk/positions/{id}":      auth.PermTrade,
+	"POST /api/recommendations/{id}/fill":  auth.PermTrade,
+	"POST /api/recommendations/{id}/close": auth.PermTrade,
+	"POST /api/journal":                    auth.PermTrade,
+	"PATCH /api/journal/{id}":              auth.PermTrade,
+	"PUT /api/journal/{id}":                auth.PermTrade,
+	"DELETE /api/journal/{id}":             auth.PermTrade,
+
+	// Runtime configuration and operations
+	"GET /api/ops/streams":        auth.PermConfig,
+	"GET /api/ops/latency":        auth.PermConfig,
+	"GET /api/ops/usage":          auth.PermConfig,
+	"GET /api/audit":              auth.PermConfig,
+	"GET /api/diagnostics":        auth.PermConfig,
+	"GET /api/ops/artifacts":      auth.PermConfig,
+	"GET /api/ops/snapshots":      auth.PermConfig,
+	"GET /api/ops/snapshots/{id}": auth.PermConfig,
+}
+
+// routePermission returns the permission a request needs, or "" for a public route
+func routePermission(method, template string) string {
+	key := method + " " + template
+	if publicRoutes[key] {
+		return ""
+	}
+	if perm, ok := routePermissions[key]; ok {
+		return perm
+	}
+	if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+		return auth.PermRead
+	}
+	return auth.PermConfig
+}
+
+// newAuthenticator configures authentication from the environment
+func newAuthenticator() (*auth.Authenticator, error) {
+	authenticator, err := auth.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	utils.Info("Anonymous requests act as %s (%d API tokens, proxy headers trusted: %v)",
+		authenticator.AnonymousRole, len(authenticator.Tokens), authenticator.TrustProxy)
+	return authenticator, nil
+}
+
+// authenticateMiddleware attaches the request's principal to its context.
+// It rejects nothing itself, so rejected calls still reach the audit log.
+func (g *APIGateway) authenticateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, g.authenticate(r))
+	})
+}
+
+// authenticate returns the request with its principal in the context. A
+// request with an unrecognized token gets no role at all.
+func (g *APIGateway) authenticate(r *http.Request) *http.Request {
+	principal, err := g.auth.Authenticate(r)
+	if err != nil {
+		principal.Role = auth.RoleNone
+	}
+	return r.WithContext(auth.NewContext(r.Context(), principal))
+}
+
+// authorizeMiddleware rejects requests whose role lacks the route's permission
+func (g *APIGateway) authorizeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		template, _ := route.GetPathTemplate()
+		if perm := routePermission(r.Method, template); perm != "" && !requirePermission(w, r, perm) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requirePermission checks the request's principal has a permission and
+// writes an error response if not: 401 when signing in might help, 403 when
+// the signed-in user's role does not allow it
+func requirePermission(w http.ResponseWriter, r *http.Request, perm string) bool {
+	principal := auth.FromContext(r.Context())
+	if principal.Can(perm) {
+		return true
+	}
+	if !principal.Authenticated {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	http.Error(w, fmt.Sprintf("role %s lacks the %s permission", principal.Role, perm), http.StatusForbidden)
+	return false
+}
+
+// meResponse describes the caller and what they may do
+type meResponse struct {
+	auth.Principal
+	Permissions []string               `json:"permissions"`
+	Roles       map[auth.Role][]string `json:"roles"`
+}
+
+// meHandler returns the caller's identity, role and allowed actions, so
+// clients can hide what the user cannot do
+func (g *APIGateway) meHandler(w http.ResponseWriter, r *http.Request) {
+	principal := auth.FromContext(r.Context())
+	permissions := principal.Role.Permissions()
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(meResponse{
+		Principal:   principal,
+		Permissions: permissions,
+		Roles:       auth.Roles(),
+	})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/recommendation"
+	"github.com/myapp/tradinglab/pkg/utils"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// recommendationExpiryJob is the scheduler job name for settling expired recommendations
+const recommendationExpiryJob = "recommendation-expiry"
+
+// defaultRecommendationExpirySchedule runs after the close on weekdays, once
+// the expiration day's bar is available
+const defaultRecommendationExpirySchedule = "30 16 * * 1-5"
+
+// recommendationExpiryTimeout bounds one expiry run
+const recommendationExpiryTimeout = 5 * time.Minute
+
+// newRecommendationStore creates the recommendation tracker, persisted to
+// RECOMMENDATIONS_PATH when set
+func newRecommendationStore() *recommendation.Store {
+	store, err := recommendation.NewStore(os.Getenv("RECOMMENDATIONS_PATH"))
+	if err != nil {
+		utils.Error("Failed to load recommendations, starting empty: %v", err)
+		store, _ = recommendation.NewStore("")
+	}
+	return store
+}
+
+// trackRecommendation starts tracking a recommendation returned by the
+// trading service and returns the tracked record and whether it is new
+func (g *APIGateway) trackRecommendation(ticker, strategy string, rec *pb.OptionsRecommendation) (recommendation.Recommendation, bool, error) {
+	return g.recommendations.Track(recommendation.Recommendation{
+		Ticker:         ticker,
+		Strategy:       strategy,
+		Date:           rec.Date,
+		SignalType:     rec.SignalType,
+		StockPrice:     rec.StockPrice,
+		Stoploss:       rec.Stoploss,
+		OptionType:     rec.OptionType,
+		Strike:         rec.Strike,
+		Expiration:     rec.Expiration,
+		SuggestedPrice: rec.Price,
+	})
+}
+
+// scheduleRecommendationExpiry registers the job settling expired
+// recommendations. Set RECOMMENDATION_EXPIRY_SCHEDULE to a cron expression to
+// change when it runs, or to "off" to disable it.
+func (g *APIGateway) scheduleRecommendationExpiry() {
+	schedule := os.Getenv("RECOMMENDATION_EXPIRY_SCHEDULE")
+	if schedule == "" {
+		schedule = defaultRecommendationExpirySchedule
+	}
+	if schedule == "off" {
+		return
+	}
+
+	err := g.scheduler.Add(recommendationExpiryJob, schedule, market.ExchangeLocation(), recommendationExpiryTimeout, func(ctx context.Context) error {
+		_, err := g.expireRecommendations(ctx)
+		return err
+	})
+	if err != nil {
+		utils.Error("Failed to schedule recommendation expiry: %v", err)
+		return
+	}
+	utils.Info("Scheduled recommendation expiry (%s)", schedule)
+}
+
+// expireRecommendations settles recommendations whose options have expired,
+// valuing filled ones against the underlying's close on the expiration date
+func (g *APIGateway) expireRecommendations(ctx context.Context) ([]recommendation.Recommendation, error) {
+	expired, err := g.recommendations.Expire(time.Now(), func(ticker string, expiration time.Time) (float64, bool) {
+		summary := g.tickerSummary(ctx, ticker, expiration)
+		if summary.Error != "" {
+			utils.Warn("No settlement price for %s on %s: %s", ticker, expiration.Format(market.SessionDateLayout), summary.Error)
+			return 0, false
+		}
+		return summary.Close, true
+	})
+	if err != nil {
+		return expired, err
+	}
+
+	for _, rec := range expired {
+		g.publishRecommendationUpdate(ctx, rec)
+	}
+	if len(expired) > 0 {
+		utils.Info("Expired %d recommendations", len(expired))
+	}
+	return expired, nil
+}
+
+// publishRecommendationUpdate publishes a status change so stream consumers
+// can follow a recommendation's lifecycle
+func (g *APIGateway) publishRecommendationUpdate(ctx context.Context, rec recommendation.Recommendation) {
+	if err := g.natsClient.PublishRecommendation(ctx, rec.Ticker, rec); err != nil {
+		utils.Warn("Failed to publish recommendation update for %s: %v", rec.ID, err)
+	}
+}
+
+// recommendationListHandler lists tracked recommendations by ticker, strategy and status
+func (g *APIGateway) recommendationListHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	records := g.recommendations.Find(recommendation.Query{
+		Ticker:   query.Get("ticker"),
+		Strategy: query.Get("strategy"),
+		Status:   query.Get("status"),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// recommendationStatsHandler summarizes how tracked recommendations turned out
+func (g *APIGateway) recommendationStatsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	records := g.recommendations.Find(recommendation.Query{
+		Ticker:   query.Get("ticker"),
+		Strategy: query.Get("strategy"),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recommendation.Summarize(records))
+}
+
+// recommendationGetHandler returns a single recommendation with its status and outcome
+func (g *APIGateway) recommendationGetHandler(w http.ResponseWriter, r *http.Request) {
+	rec, exists := g.recommendations.Get(mux.Vars(r)["id"])
+	if !exists {
+		http.Error(w, "recommendation not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
+
+// recommendationFillRequest is the payload for filling or closing a recommendation
+type recommendationFillRequest struct {
+	Price float64   `json:"price"`
+	At    time.Time `json:"at"` // Defaults to now
+}
+
+// recommendationFillHandler records that a recommendation was acted on
+func (g *APIGateway) recommendationFillHandler(w http.ResponseWriter, r *http.Request) {
+	g.updateRecommendation(w, r, g.recommendations.Fill)
+}
+
+// recommendationCloseHandler records the exit of a filled recommendation
+func (g *APIGateway) recommendationCloseHandler(w http.ResponseWriter, r *http.Request) {
+	g.updateRecommendation(w, r, g.recommendations.Close)
+}
+
+// updateRecommendation applies a fill or close from the request payload
+func (g *APIGateway) updateRecommendation(w http.ResponseWriter, r *http.Request, update func(id string, price float64, at time.Time) (recommendation.Recommendation, error)) {
+	var req recommendationFillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid recommendation update payload", http.StatusBadRequest)
+		return
+	}
+	if req.Price < 0 {
+		http.Error(w, "price must not be negative", http.StatusBadRequest)
+		return
+	}
+	if req.At.IsZero() {
+		req.At = time.Now()
+	}
+
+	id := mux.Vars(r)["id"]
+	if _, exists := g.recommendations.Get(id); !exists {
+		http.Error(w, "recommendation not found", http.StatusNotFound)
+		return
+	}
+
+	rec, err := update(id, req.Price, req.At)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	g.publishRecommendationUpdate(r.Context(), rec)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rec)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// Defaults for leaving degraded and read-only mode, overridden by
+// SERVICE_RECOVERY_SUCCESSES, SERVICE_MIN_MODE_DURATION and
+// SERVICE_PROBE_INTERVAL
+const (
+	defaultRecoverySuccesses    = 3
+	defaultMinModeDuration      = 30 * time.Second
+	defaultServiceProbeInterval = 10 * time.Second
+)
+
+var (
+	serviceModeChanges = metrics.Default.NewCounterVec("gateway_service_mode_changes_total",
+		"Service mode changes by the mode entered", "mode")
+	serviceProbes = metrics.Default.NewCounterVec("gateway_service_probes_total",
+		"Background probes of the trading service while degraded by result", "result")
+)
+
+// recoveryPolicy is the hysteresis on the way back to normal mode: failures
+// degrade the mode at once, but it only recovers after Successes calls or
+// probes in a row succeed and it has held for at least MinDuration
+type recoveryPolicy struct {
+	Successes     int
+	MinDuration   time.Duration
+	ProbeInterval time.Duration // How often the trading service is probed while degraded; zero disables
+}
+
+// recoveryPolicyFromEnv reads the recovery policy
+func recoveryPolicyFromEnv() recoveryPolicy {
+	policy := recoveryPolicy{
+		Successes:     defaultRecoverySuccesses,
+		MinDuration:   defaultMinModeDuration,
+		ProbeInterval: defaultServiceProbeInterval,
+	}
+	if value := os.Getenv("SERVICE_RECOVERY_SUCCESSES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			policy.Successes = n
+		} else {
+			utils.Warn("Invalid SERVICE_RECOVERY_SUCCESSES %q, using default %d", value, defaultRecoverySuccesses)
+		}
+	}
+	if value := os.Getenv("SERVICE_MIN_MODE_DURATION"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			policy.MinDuration = d
+		} else {
+			utils.Warn("Invalid SERVICE_MIN_MODE_DURATION %q, using default %s", value, defaultMinModeDuration)
+		}
+	}
+	if value := os.Getenv("SERVICE_PROBE_INTERVAL"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			policy.ProbeInterval = d
+		} else {
+			utils.Warn("Invalid SERVICE_PROBE_INTERVAL %q, using default %s", value, defaultServiceProbeInterval)
+		}
+	}
+	return policy
+}
+
+// RecordProbe records the outcome of a background probe of the trading
+// service, which counts as a call to it
+func (c *DataCache) RecordProbe(err error) {
+	c.mutex.Lock()
+	c.lastProbe = time.Now()
+	c.lastProbeError = err
+	c.mutex.Unlock()
+
+	c.RecordDependency(depGRPC, err)
+}
+
+// Degraded reports whether the service is out of normal mode
+func (c *DataCache) Degraded() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.serviceMode != "normal"
+}
+
+// recoveryStatus reports progress towards normal mode. The caller holds
+// c.mutex.
+func (c *DataCache) recoveryStatus() map[string]interface{} {
+	status := map[string]interface{}{
+		"successes":         c.healthyStreak,
+		"successes_needed":  c.recovery.Successes,
+		"min_mode_duration": c.recovery.MinDuration.String(),
+		"probe_interval":    c.recovery.ProbeInterval.String(),
+	}
+	if !c.lastProbe.IsZero() {
+		status["last_probe"] = c.lastProbe.Format(time.RFC3339)
+		if c.lastProbeError != nil {
+			status["last_probe_error"] = c.lastProbeError.Error()
+		}
+	}
+	return status
+}
+
+// probeTradingService checks the trading service's health every probe
+// interval while the gateway is degraded, so the mode recovers without
+// waiting for user requests to succeed
+func (g *APIGateway) probeTradingService(ctx context.Context) {
+	interval := g.cache.recovery.ProbeInterval
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !g.cache.Degraded() {
+			continue
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, diagnosticTimeout)
+		_, err := g.probeGRPC(probeCtx)
+		cancel()
+		if err != nil {
+			serviceProbes.With("error").Inc()
+			utils.Warn("Trading service probe failed, staying degraded: %v", err)
+		} else {
+			serviceProbes.With("ok").Inc()
+		}
+		g.cache.RecordProbe(err)
+	}
+}
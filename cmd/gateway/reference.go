@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/reference"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// newReferenceStore creates the sector and index reference data, merging the
+// file at REFERENCE_DATA_PATH over the built-in classifications
+func newReferenceStore() *reference.Store {
+	store := reference.NewStore()
+
+	if path := os.Getenv("REFERENCE_DATA_PATH"); path != "" {
+		loaded, err := store.LoadFile(path)
+		if err != nil {
+			utils.Error("Failed to load reference data: %v", err)
+		} else {
+			utils.Info("Loaded %d reference classifications from %s", loaded, path)
+		}
+	}
+
+	return store
+}
+
+// referenceFilter reads the sector, industry and index query parameters
+func referenceFilter(r *http.Request) reference.Filter {
+	query := r.URL.Query()
+	return reference.Filter{
+		Sector:   query.Get("sector"),
+		Industry: query.Get("industry"),
+		Index:    query.Get("index"),
+	}
+}
+
+// referenceListHandler lists classifications filtered by sector, industry or index
+func (g *APIGateway) referenceListHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.reference.List(referenceFilter(r)))
+}
+
+// referenceSectorsHandler lists sectors with their industries and ticker counts
+func (g *APIGateway) referenceSectorsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.reference.Sectors())
+}
+
+// referenceTickerHandler returns the classification for one ticker
+func (g *APIGateway) referenceTickerHandler(w http.ResponseWriter, r *http.Request) {
+	ticker, err := market.ValidateTicker(mux.Vars(r)["ticker"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c, ok := g.reference.Get(ticker)
+	if !ok {
+		http.Error(w, "no reference data for ticker", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// referenceExposureHandler breaks down open position exposure by sector,
+// industry and index
+func (g *APIGateway) referenceExposureHandler(w http.ResponseWriter, r *http.Request) {
+	exposures := make(map[string]float64)
+	for _, pos := range g.risk.Positions() {
+		exposures[pos.Ticker] += pos.Exposure()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.reference.Breakdown(exposures))
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/notify"
+	"github.com/myapp/tradinglab/pkg/report"
+	"github.com/myapp/tradinglab/pkg/risk"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// dailyReportJob is the scheduler job name for end-of-day reports
+const dailyReportJob = "daily-report"
+
+// defaultReportSchedule runs the report shortly after the close on weekdays
+const defaultReportSchedule = "15 16 * * 1-5"
+
+// reportTimeout bounds building and distributing one report
+const reportTimeout = 5 * time.Minute
+
+// reportsDir returns REPORTS_DIR, where daily reports are kept
+func reportsDir() string {
+	if dir := os.Getenv("REPORTS_DIR"); dir != "" {
+		return dir
+	}
+	return "reports"
+}
+
+// newReportStore creates the report store in REPORTS_DIR
+func newReportStore() *report.Store {
+	return report.NewStore(reportsDir())
+}
+
+// scheduleDailyReport registers the end-of-day report job. Set REPORT_SCHEDULE
+// to a cron expression to change when it runs, or to "off" to disable it.
+func (g *APIGateway) scheduleDailyReport() {
+	schedule := os.Getenv("REPORT_SCHEDULE")
+	if schedule == "" {
+		schedule = defaultReportSchedule
+	}
+	if schedule == "off" {
+		return
+	}
+
+	err := g.scheduler.Add(dailyReportJob, schedule, market.ExchangeLocation(), reportTimeout, func(ctx context.Context) error {
+		today := time.Now().In(market.ExchangeLocation()).Format(report.DateLayout)
+		_, err := g.generateDailyReport(ctx, today, true)
+		return err
+	})
+	if err != nil {
+		utils.Error("Failed to schedule daily report: %v", err)
+		return
+	}
+	utils.Info("Scheduled daily report (%s)", schedule)
+}
+
+// generateDailyReport builds, stores and optionally distributes the report for a date
+func (g *APIGateway) generateDailyReport(ctx context.Context, date string, distribute bool) (*report.Daily, error) {
+	day, err := time.ParseInLocation(report.DateLayout, date, market.ExchangeLocation())
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", date)
+	}
+
+	daily := &report.Daily{
+		Date:        date,
+		GeneratedAt: time.Now(),
+		Signals:     []report.SignalEntry{},
+	}
+
+	closes := make(map[string]float64)
+	for _, ticker := range defaultWatchlist() {
+		summary := g.tickerSummary(ctx, ticker, day)
+		if summary.Error == "" {
+			closes[ticker] = summary.Close
+		}
+		daily.Tickers = append(daily.Tickers, summary)
+		daily.Signals = append(daily.Signals, g.reportSignals(ctx, ticker, day)...)
+	}
+	daily.Paper = g.paperSummary(day, closes)
+
+	if err := g.reports.Save(daily); err != nil {
+		return nil, err
+	}
+	utils.Info("Generated daily report for %s", date)
+	if err := g.publishReport(ctx, daily); err != nil {
+		utils.Error("Failed to publish daily report for %s: %v", date, err)
+	}
+
+	if distribute {
+		if err := g.distributeReport(ctx, daily); err != nil {
+			utils.Error("Failed to distribute daily report for %s: %v", date, err)
+		}
+	}
+	return daily, nil
+}
+
+// tickerSummary returns the daily bar and change for a ticker on a date
+func (g *APIGateway) tickerSummary(ctx context.Context, ticker string, day time.Time) report.TickerSummary {
+	summary := report.TickerSummary{Ticker: ticker}
+
+	days := int(time.Since(day).Hours()/24) + 5
+	if days > market.MaxHistoricalDays {
+		summary.Error = "date is outside the historical data range"
+		return summary
+	}
+
+	candles, err := g.fetchCandles(ctx, ticker, market.Interval1Day, days)
+	if err != nil {
+		summary.Error = err.Error()
+		return summary
+	}
+
+	date := day.Format(report.DateLayout)
+	for i, c := range candles {
+		if c.Time.Format(report.DateLayout) != date {
+			continue
+		}
+		summary.Open, summary.High, summary.Low, summary.Close, summary.Volume = c.Open, c.High, c.Low, c.Close, c.Volume
+		if i > 0 {
+			summary.PrevClose = candles[i-1].Close
+			summary.Change = c.Close - summary.PrevClose
+			if summary.PrevClose != 0 {
+				summary.ChangePct = summary.Change / summary.PrevClose * 100
+			}
+		}
+		return summary
+	}
+
+	summary.Error = "no bar for " + date
+	return summary
+}
+
+// reportSignals returns the signals the report strategy triggered for a ticker on a date
+func (g *APIGateway) reportSignals(ctx context.Context, ticker string, day time.Time) []report.SignalEntry {
+	strategyName := os.Getenv("REPORT_STRATEGY")
+	if strategyName == "" {
+		strategyName = "RedCandle"
+	}
+
+	strategyParams, err := g.strategies.Resolve(strategyName, nil)
+	if err != nil {
+		utils.Error("Report strategy %s is not usable: %v", strategyName, err)
+		return nil
+	}
+
+	days := int(time.Since(day).Hours()/24) + 1
+	params, err := market.NormalizeHistoricalParams(ticker, market.Interval15Min, days)
+	if err != nil {
+		return nil
+	}
+
+	resp, err := g.strategySignals(ctx, strategyName, params, strategyParams)
+	if err != nil {
+		utils.Error("Failed to get report signals for %s: %v", ticker, err)
+		return nil
+	}
+
+	date := day.Format(report.DateLayout)
+	var entries []report.SignalEntry
+	for _, signal := range resp.Signals {
+		if !strings.HasPrefix(signal.Date, date) {
+			continue
+		}
+		entries = append(entries, report.SignalEntry{
+			Ticker:     ticker,
+			Strategy:   strategyName,
+			Date:       signal.Date,
+			SignalType: signal.SignalType,
+			EntryPrice: signal.EntryPrice,
+			Stoploss:   signal.Stoploss,
+		})
+	}
+	return entries
+}
+
+// paperSummary summarizes paper positions closed on a date and marks open
+// positions to the given closing prices
+func (g *APIGateway) paperSummary(day time.Time, closes map[string]float64) report.PaperSummary {
+	summary := report.PaperSummary{ClosedTrades: []report.PaperTrade{}}
+	date := day.Format(report.DateLayout)
+
+	for _, closed := range g.risk.ClosedPositions() {
+		if closed.ClosedAt.In(market.ExchangeLocation()).Format(report.DateLayout) != date {
+			continue
+		}
+		summary.RealizedPnL += closed.RealizedPnL
+		summary.ClosedTrades = append(summary.ClosedTrades, report.PaperTrade{
+			Ticker:     closed.Ticker,
+			Direction:  closed.Direction,
+			Quantity:   closed.Quantity,
+			EntryPrice: closed.EntryPrice,
+			ExitPrice:  closed.ExitPrice,
+			PnL:        closed.RealizedPnL,
+		})
+	}
+
+	for _, pos := range g.risk.Positions() {
+		summary.OpenPositions++
+		price, ok := closes[pos.Ticker]
+		if !ok {
+			continue
+		}
+		pnl := (price - pos.EntryPrice) * float64(pos.Quantity)
+		if pos.Direction == risk.DirectionShort {
+			pnl = -pnl
+		}
+		summary.UnrealizedPnL += pnl
+	}
+
+	return summary
+}
+
+// distributeReport sends a report to the configured webhook and email recipients
+func (g *APIGateway) distributeReport(ctx context.Context, daily *report.Daily) error {
+	var notifiers []notify.Notifier
+	if url := os.Getenv("REPORT_WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, notify.NewWebhook(url))
+	}
+	if email := notify.EmailFromEnv(os.Getenv("REPORT_EMAIL_TO")); email != nil {
+		notifiers = append(notifiers, email)
+	}
+	if len(notifiers) == 0 {
+		return nil
+	}
+
+	html, err := report.RenderHTML(daily)
+	if err != nil {
+		return err
+	}
+
+	return notify.Send(ctx, notifiers, notify.Message{
+		Subject: "TradingLab daily report " + daily.Date,
+		Text:    report.Text(daily),
+		HTML:    string(html),
+		JSON:    daily,
+	})
+}
+
+// dailyReportsHandler lists the dates with stored reports
+func (g *APIGateway) dailyReportsHandler(w http.ResponseWriter, r *http.Request) {
+	dates, err := g.reports.Dates()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error listing reports: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dates)
+}
+
+// dailyReportHandler serves a stored report as JSON, or as HTML with format=html
+func (g *APIGateway) dailyReportHandler(w http.ResponseWriter, r *http.Request) {
+	daily, err := g.reports.Load(mux.Vars(r)["date"])
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "report not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	if r.URL.Query().Get("format") == "html" {
+		html, err := report.RenderHTML(daily)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(html)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(daily)
+}
+
+// generateDailyReportHandler builds the report for a date on demand. Pass
+// distribute=true to also send it to the configured channels.
+func (g *APIGateway) generateDailyReportHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), reportTimeout)
+	defer cancel()
+
+	daily, err := g.generateDailyReport(ctx, mux.Vars(r)["date"], r.URL.Query().Get("distribute") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(daily)
+}
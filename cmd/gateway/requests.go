@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// cancelRequestHandler cancels an in-flight historical request by ID, such
+// as a chart load the UI abandoned, so the market data service stops
+// fetching and publishing it. Cancellation is asynchronous: the request's
+// progress subject reports "cancelled" once it has stopped. Cancelling a
+// request that has already finished does nothing.
+func (g *APIGateway) cancelRequestHandler(w http.ResponseWriter, r *http.Request) {
+	requestID := mux.Vars(r)["id"]
+	progressSubject, err := events.ProgressSubject(requestID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if err := g.natsClient.CancelRequest(ctx, requestID, r.URL.Query().Get("reason")); err != nil {
+		http.Error(w, "Failed to cancel request: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"request_id": requestID,
+		"status":     "cancelling",
+		"progress":   progressSubject,
+	})
+}
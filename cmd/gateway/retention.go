@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/config"
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// dataRetentionJob is the scheduler job name for market data retention
+const dataRetentionJob = "data-retention"
+
+// defaultDataRetentionSchedule enforces retention nightly
+const defaultDataRetentionSchedule = "30 3 * * *"
+
+// dataRetentionTimeout bounds one retention run
+const dataRetentionTimeout = 30 * time.Minute
+
+// scheduleDataRetention registers the job that purges historical market data
+// past its asset class and interval's max age. The policy in
+// RETENTION_CONFIG_PATH is reloaded on every run. Set DATA_RETENTION_SCHEDULE
+// to a cron expression to change when it runs, or to "off" to disable it.
+func (g *APIGateway) scheduleDataRetention() {
+	schedule := os.Getenv("DATA_RETENTION_SCHEDULE")
+	if schedule == "" {
+		schedule = defaultDataRetentionSchedule
+	}
+	if schedule == "off" || g.natsClient == nil {
+		return
+	}
+
+	err := g.scheduler.Add(dataRetentionJob, schedule, time.UTC, dataRetentionTimeout, func(ctx context.Context) error {
+		_, err := g.enforceDataRetention(ctx)
+		return err
+	})
+	if err != nil {
+		utils.Error("Failed to schedule data retention: %v", err)
+		return
+	}
+	utils.Info("Scheduled data retention (%s)", schedule)
+}
+
+// enforceDataRetention reloads the retention policy and applies it
+func (g *APIGateway) enforceDataRetention(ctx context.Context) (*events.RetentionSummary, error) {
+	policy, err := config.LoadRetentionPolicy()
+	if err != nil {
+		return nil, err
+	}
+	summary, err := g.natsClient.EnforceRetention(ctx, policy)
+	if err != nil {
+		return summary, err
+	}
+
+	var purged uint64
+	for _, p := range summary.Purged {
+		purged += p.Purged
+	}
+	utils.Info("Data retention purged %d messages from %d subjects", purged, len(summary.Purged))
+	for _, failure := range summary.Errors {
+		utils.Warn("Data retention: %s", failure)
+	}
+	if len(summary.Errors) > 0 {
+		return summary, fmt.Errorf("%d subjects failed retention", len(summary.Errors))
+	}
+	return summary, nil
+}
+
+// retentionPolicyHandler returns the retention policy the next run will apply
+func (g *APIGateway) retentionPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	policy, err := config.LoadRetentionPolicy()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy":  policy,
+		"streams": events.RetentionStreams,
+	})
+}
+
+// retentionRunHandler enforces the retention policy now and reports what it purged
+func (g *APIGateway) retentionRunHandler(w http.ResponseWriter, r *http.Request) {
+	if g.natsClient == nil {
+		http.Error(w, "NATS is not connected", http.StatusServiceUnavailable)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), dataRetentionTimeout)
+	defer cancel()
+
+	summary, err := g.enforceDataRetention(ctx)
+	if summary == nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
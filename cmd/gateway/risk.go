@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/reference"
+	"github.com/myapp/tradinglab/pkg/risk"
+	"github.com/myapp/tradinglab/pkg/utils"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// Risk enforcement modes for generated signals
+const (
+	riskEnforcementAnnotate = "annotate" // Attach risk check results to signals
+	riskEnforcementBlock    = "block"    // Drop signals that would breach limits
+)
+
+// newRiskEngine creates the portfolio risk engine from environment configuration.
+// Sectors come from the reference data, overridden by RISK_SECTORS.
+func newRiskEngine(ref *reference.Store) *risk.Engine {
+	engine := risk.NewEngine(risk.Limits{
+		MaxTickerExposure: envFloat("RISK_MAX_TICKER_EXPOSURE", 0),
+		MaxSectorExposure: envFloat("RISK_MAX_SECTOR_EXPOSURE", 0),
+		MaxDailyLoss:      envFloat("RISK_MAX_DAILY_LOSS", 0),
+	})
+	engine.SetCosts(costModelFromEnv())
+
+	for _, c := range ref.List(reference.Filter{}) {
+		if c.Sector != "" {
+			engine.SetSector(c.Ticker, c.Sector)
+		}
+	}
+
+	// Sector assignments in the form "AAPL:Technology,XOM:Energy"
+	if sectors := os.Getenv("RISK_SECTORS"); sectors != "" {
+		for _, entry := range strings.Split(sectors, ",") {
+			parts := strings.SplitN(entry, ":", 2)
+			if len(parts) == 2 {
+				engine.SetSector(market.NormalizeTicker(parts[0]), strings.TrimSpace(parts[1]))
+			}
+		}
+	}
+
+	return engine
+}
+
+// costModelFromEnv reads the execution costs paper positions are filled
+// under, which are also the defaults for backtests. Invalid settings fall
+// back to ideal fills.
+func costModelFromEnv() risk.CostModel {
+	costs := risk.CostModel{
+		CommissionFixed:   envFloat("EXECUTION_COMMISSION_FIXED", 0),
+		CommissionPercent: envFloat("EXECUTION_COMMISSION_PERCENT", 0),
+		SpreadPercent:     envFloat("EXECUTION_SPREAD_PERCENT", 0),
+		MaxVolumePercent:  envFloat("EXECUTION_MAX_VOLUME_PERCENT", 0),
+	}
+	if err := costs.Validate(); err != nil {
+		utils.Warn("Invalid execution costs, filling at quoted prices: %v", err)
+		return risk.CostModel{}
+	}
+	return costs
+}
+
+// costModelParams overrides the default execution costs with the
+// commission_fixed, commission_percent, spread_percent and
+// max_volume_percent query parameters
+func costModelParams(r *http.Request, costs risk.CostModel) (risk.CostModel, error) {
+	for name, field := range map[string]*float64{
+		"commission_fixed":   &costs.CommissionFixed,
+		"commission_percent": &costs.CommissionPercent,
+		"spread_percent":     &costs.SpreadPercent,
+		"max_volume_percent": &costs.MaxVolumePercent,
+	} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return costs, fmt.Errorf("invalid %s parameter", name)
+		}
+		*field = f
+	}
+	return costs, costs.Validate()
+}
+
+// costModelProto converts execution costs for a backtest request
+func costModelProto(costs risk.CostModel) *pb.CostModel {
+	return &pb.CostModel{
+		CommissionFixed:   costs.CommissionFixed,
+		CommissionPercent: costs.CommissionPercent,
+		SpreadPercent:     costs.SpreadPercent,
+		MaxVolumePercent:  costs.MaxVolumePercent,
+	}
+}
+
+// riskEnforcementFromEnv reads the RISK_ENFORCEMENT mode
+func riskEnforcementFromEnv() string {
+	if strings.ToLower(os.Getenv("RISK_ENFORCEMENT")) == riskEnforcementBlock {
+		return riskEnforcementBlock
+	}
+	return riskEnforcementAnnotate
+}
+
+// publishRiskEvent forwards risk engine events to the event stream
+func (g *APIGateway) publishRiskEvent(event risk.Event) {
+	utils.Warn("Risk limit hit: %s", event.Violation.Message)
+	if err := g.natsClient.PublishRiskEvent(context.Background(), event.Type, event); err != nil {
+		utils.Error("Failed to publish risk event: %v", err)
+	}
+}
+
+// applyRiskChecks annotates copies of signals with risk check results,
+// dropping violating signals when enforcement is set to block. The signals
+// themselves are left as they are, so cached ones can be checked again.
+func (g *APIGateway) applyRiskChecks(ticker string, signals []map[string]interface{}) []map[string]interface{} {
+	filtered := make([]map[string]interface{}, 0, len(signals))
+	for _, signal := range signals {
+		entry, _ := signal["entry_price"].(float64)
+		stoploss, _ := signal["stoploss"].(float64)
+		direction, _ := signal["signal_type"].(string)
+
+		// Size the proposed trade with the default account settings
+		exposure := 0.0
+		if sizing := g.recommendationSizing(direction, entry, stoploss, 0); sizing != nil {
+			exposure = sizing.PositionValue
+		}
+
+		result := g.risk.Check(ticker, exposure)
+		if !result.Allowed && g.riskEnforcement == riskEnforcementBlock {
+			utils.Info("Blocked %s signal for %s: %d risk violations", direction, ticker, len(result.Violations))
+			continue
+		}
+
+		annotated := make(map[string]interface{}, len(signal)+1)
+		for key, value := range signal {
+			annotated[key] = value
+		}
+		annotated["risk"] = result
+		filtered = append(filtered, annotated)
+	}
+	return filtered
+}
+
+// riskStatusHandler returns current exposures and limits
+func (g *APIGateway) riskStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.risk.Status())
+}
+
+// riskPositionsHandler lists open positions
+func (g *APIGateway) riskPositionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.risk.Positions())
+}
+
+// openPositionHandler records a new position after a risk check
+func (g *APIGateway) openPositionHandler(w http.ResponseWriter, r *http.Request) {
+	var pos risk.Position
+	if err := json.NewDecoder(r.Body).Decode(&pos); err != nil {
+		http.Error(w, "invalid position payload", http.StatusBadRequest)
+		return
+	}
+	ticker, err := market.ValidateTicker(pos.Ticker)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pos.Ticker = ticker
+
+	opened, result, err := g.risk.Open(pos)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if result.Violations != nil {
+			w.WriteHeader(http.StatusConflict)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":      err.Error(),
+			"violations": result.Violations,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(opened)
+}
+
+// closePositionHandler closes an open position at the given exit price
+func (g *APIGateway) closePositionHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	exitPrice, err := strconv.ParseFloat(r.URL.Query().Get("exit_price"), 64)
+	if err != nil || exitPrice <= 0 {
+		http.Error(w, "exit_price parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	closed, err := g.risk.Close(id, exitPrice)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// Every closed trade gets a journal entry for review
+	g.journalClosedPosition(closed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(closed)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/fundamentals"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/reference"
+	"github.com/myapp/tradinglab/pkg/strategy"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// scanTimeout bounds a single run of a scan job across all of its tickers
+const scanTimeout = 2 * time.Minute
+
+// scanJobPrefix namespaces scan jobs in the gateway scheduler
+const scanJobPrefix = "scan:"
+
+// ScanJob configures a scheduled strategy scan, e.g. RedCandle on the watchlist
+// every 15 minutes during market hours:
+//
+//	{"name": "redcandle-watchlist", "schedule": "*/15 9-15 * * 1-5", "strategy": "RedCandle", "market_hours": true}
+type ScanJob struct {
+	Name         string                 `json:"name"`
+	Schedule     string                 `json:"schedule"`           // Five-field cron expression
+	Timezone     string                 `json:"timezone,omitempty"` // Defaults to the exchange zone
+	Strategy     string                 `json:"strategy"`
+	Params       map[string]interface{} `json:"params,omitempty"`
+	Tickers      []string               `json:"tickers,omitempty"` // Defaults to the sector/index universe or the watchlist
+	Sector       string                 `json:"sector,omitempty"`  // Scan every ticker in a sector
+	Index        string                 `json:"index,omitempty"`   // Scan the constituents of an index, e.g. SP500
+	Interval     string                 `json:"interval,omitempty"`
+	Days         int                    `json:"days,omitempty"`
+	MarketHours  bool                   `json:"market_hours"`           // Skip tickers whose market is closed (24x5 for forex)
+	Confirm      *strategy.ConfirmSpec  `json:"confirm,omitempty"`      // Higher timeframe confirmation
+	Fundamentals []fundamentals.Filter  `json:"fundamentals,omitempty"` // Skip tickers outside these bounds, e.g. pe_ratio max 25
+	Priority     string                 `json:"priority,omitempty"`     // "high" emails signals to subscribers immediately
+}
+
+// ScanRunner evaluates scan jobs on their schedules and publishes new signals
+type ScanRunner struct {
+	gateway   *APIGateway
+	mu        sync.Mutex
+	jobs      map[string]ScanJob
+	published map[string]string // Latest published signal date by job and ticker
+}
+
+// defaultWatchlist returns the tickers from WATCH_TICKERS or the built-in list
+func defaultWatchlist() []string {
+	if custom := os.Getenv("WATCH_TICKERS"); custom != "" {
+		var tickers []string
+		for _, ticker := range strings.Split(custom, ",") {
+			if strings.TrimSpace(ticker) == "" {
+				continue
+			}
+			valid, err := market.ValidateTicker(ticker)
+			if err != nil {
+				utils.Warn("Ignoring WATCH_TICKERS entry: %v", err)
+				continue
+			}
+			tickers = append(tickers, valid)
+		}
+		return tickers
+	}
+	return []string{"SPY", "AAPL", "MSFT", "GOOGL", "AMZN"}
+}
+
+// newScanRunner loads scan jobs from SCAN_JOBS_PATH and schedules them on the
+// gateway scheduler
+func newScanRunner(g *APIGateway) *ScanRunner {
+	runner := &ScanRunner{
+		gateway:   g,
+		jobs:      make(map[string]ScanJob),
+		published: make(map[string]string),
+	}
+
+	path := os.Getenv("SCAN_JOBS_PATH")
+	if path == "" {
+		return runner
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		utils.Error("Failed to read scan jobs: %v", err)
+		return runner
+	}
+
+	var jobs []ScanJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		utils.Error("Failed to parse scan jobs: %v", err)
+		return runner
+	}
+
+	for _, job := range jobs {
+		if err := runner.Add(job); err != nil {
+			utils.Error("Skipping scan job %s: %v", job.Name, err)
+			continue
+		}
+		utils.Info("Scheduled scan job %s (%s) for %s", job.Name, job.Schedule, job.Strategy)
+	}
+
+	return runner
+}
+
+// Add validates and schedules a scan job
+func (s *ScanRunner) Add(job ScanJob) error {
+	if job.Name == "" || job.Schedule == "" {
+		return fmt.Errorf("name and schedule are required")
+	}
+	if job.Strategy == "" {
+		job.Strategy = "RedCandle"
+	}
+	if job.Interval == "" {
+		job.Interval = "15min"
+	}
+	if job.Days <= 0 {
+		job.Days = 5
+	}
+	if job.Confirm != nil {
+		interval, err := market.NormalizeInterval(job.Interval)
+		if err != nil {
+			return err
+		}
+		if err := validateConfirmSpec(job.Confirm, interval); err != nil {
+			return err
+		}
+	}
+	for _, filter := range job.Fundamentals {
+		if err := filter.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate parameters once up front rather than on every run
+	if _, err := s.gateway.strategies.Resolve(job.Strategy, job.Params); err != nil {
+		return err
+	}
+
+	loc := market.ExchangeLocation()
+	if job.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(job.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+
+	if err := s.gateway.scheduler.Add(scanJobPrefix+job.Name, job.Schedule, loc, scanTimeout, func(ctx context.Context) error {
+		return s.run(ctx, job)
+	}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.jobs[job.Name] = job
+	s.mu.Unlock()
+	return nil
+}
+
+// run evaluates a scan job across its tickers
+func (s *ScanRunner) run(ctx context.Context, job ScanJob) error {
+	strategyParams, err := s.gateway.strategies.Resolve(job.Strategy, job.Params)
+	if err != nil {
+		return err
+	}
+
+	tickers := job.Tickers
+	if len(tickers) == 0 && (job.Sector != "" || job.Index != "") {
+		tickers = s.gateway.reference.Tickers(reference.Filter{Sector: job.Sector, Index: job.Index})
+	} else if len(tickers) == 0 {
+		tickers = defaultWatchlist()
+	}
+
+	var failed []string
+	published := 0
+	for _, ticker := range tickers {
+		if job.MarketHours && !market.InSession(ticker, time.Now()) {
+			continue
+		}
+		if len(job.Fundamentals) > 0 && !s.passesFundamentals(ctx, job, ticker) {
+			continue
+		}
+		count, err := s.scanTicker(ctx, job, ticker, strategyParams)
+		if err != nil {
+			utils.Error("Scan %s failed for %s: %v", job.Name, ticker, err)
+			failed = append(failed, ticker)
+			continue
+		}
+		published += count
+	}
+
+	utils.Info("Scan %s published %d new signals across %d tickers", job.Name, published, len(tickers))
+	if len(failed) > 0 {
+		return fmt.Errorf("scan failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// passesFundamentals reports whether a ticker meets a job's fundamental
+// filters. Tickers without fundamentals are skipped.
+func (s *ScanRunner) passesFundamentals(ctx context.Context, job ScanJob, ticker string) bool {
+	snapshot, err := s.gateway.fundamentals.Get(ctx, ticker)
+	if err != nil {
+		utils.Warn("Scan %s skipping %s: %v", job.Name, ticker, err)
+		return false
+	}
+	if ok, reason := fundamentals.Match(snapshot, job.Fundamentals); !ok {
+		utils.Debug("Scan %s skipping %s: %s", job.Name, ticker, reason)
+		return false
+	}
+	return true
+}
+
+// scanTicker generates signals for one ticker and publishes those not seen on
+// earlier runs. Only signals from the current trading day are published.
+func (s *ScanRunner) scanTicker(ctx context.Context, job ScanJob, ticker string, strategyParams map[string]string) (int, error) {
+	g := s.gateway
+
+	params, err := market.NormalizeHistoricalParams(ticker, job.Interval, job.Days)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := g.strategySignals(ctx, job.Strategy, params, strategyParams)
+	if err != nil {
+		return 0, err
+	}
+
+	key := job.Name + ":" + params.Ticker
+	today := time.Now().In(market.ExchangeLocation()).Format("2006-01-02")
+
+	s.mu.Lock()
+	last := s.published[key]
+	s.mu.Unlock()
+
+	var fresh []map[string]interface{}
+	latest := last
+	for _, signal := range resp.Signals {
+		// Signal dates sort lexically ("2006-01-02 15:04:05")
+		if !strings.HasPrefix(signal.Date, today) || signal.Date <= last {
+			continue
+		}
+		fresh = append(fresh, map[string]interface{}{
+			"date":        signal.Date,
+			"signal_type": signal.SignalType,
+			"entry_price": signal.EntryPrice,
+			"stoploss":    signal.Stoploss,
+		})
+		if signal.Date > latest {
+			latest = signal.Date
+		}
+	}
+
+	if job.Confirm != nil {
+		fresh = g.confirmSignals(ctx, params, *job.Confirm, fresh)
+	}
+	fresh = g.applyRiskChecks(params.Ticker, fresh)
+	for _, signal := range fresh {
+		signal["ticker"] = params.Ticker
+		signal["strategy"] = job.Strategy
+		signal["interval"] = params.Interval
+		signal["scan"] = job.Name
+		if job.Priority != "" {
+			signal["priority"] = job.Priority
+		}
+	}
+	g.scoreSignals(ctx, fresh)
+	for _, signal := range fresh {
+		if err := g.natsClient.PublishSignal(ctx, params.Ticker, signal); err != nil {
+			return 0, fmt.Errorf("failed to publish signal: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.published[key] = latest
+	s.mu.Unlock()
+
+	return len(fresh), nil
+}
+
+// scansHandler lists scan jobs with their schedule status
+func (g *APIGateway) scansHandler(w http.ResponseWriter, r *http.Request) {
+	g.scans.mu.Lock()
+	jobs := make(map[string]ScanJob, len(g.scans.jobs))
+	for name, job := range g.scans.jobs {
+		jobs[name] = job
+	}
+	g.scans.mu.Unlock()
+
+	response := make([]map[string]interface{}, 0, len(jobs))
+	for _, status := range g.scheduler.Jobs() {
+		job, isScan := jobs[strings.TrimPrefix(status.Name, scanJobPrefix)]
+		if !isScan || !strings.HasPrefix(status.Name, scanJobPrefix) {
+			continue
+		}
+		response = append(response, map[string]interface{}{
+			"job":    job,
+			"status": status,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// scanRunHandler runs a scan job immediately
+func (g *APIGateway) scanRunHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	g.scans.mu.Lock()
+	_, exists := g.scans.jobs[name]
+	g.scans.mu.Unlock()
+	if !exists {
+		http.Error(w, "scan job not found", http.StatusNotFound)
+		return
+	}
+
+	if err := g.scheduler.RunNow(scanJobPrefix + name); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	for _, status := range g.scheduler.Jobs() {
+		if status.Name == scanJobPrefix+name {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(status)
+			return
+		}
+	}
+}
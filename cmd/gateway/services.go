@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/buildinfo"
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/hub"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// servicesTimeout bounds the query for service heartbeats held by the hub
+const servicesTimeout = 2 * time.Second
+
+// startHeartbeat announces the gateway on the heartbeat subject with its
+// service mode and WebSocket client count
+func (g *APIGateway) startHeartbeat(ctx context.Context) {
+	if g.natsClient == nil {
+		return
+	}
+	g.natsClient.StartHeartbeat(ctx, "gateway", events.HeartbeatInterval(), func() map[string]interface{} {
+		clients := len(g.wsConns.Open())
+
+		return map[string]interface{}{
+			"mode":       g.cache.GetServiceStatus()["mode"],
+			"ws_clients": clients,
+		}
+	})
+}
+
+// servicesHandler lists the service instances the hub has heard from, with
+// their versions and whether they are still alive
+func (g *APIGateway) servicesHandler(w http.ResponseWriter, r *http.Request) {
+	if g.natsClient == nil {
+		http.Error(w, "NATS is not connected", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), servicesTimeout)
+	defer cancel()
+
+	var services []hub.ServiceStatus
+	err := g.natsClient.Request(ctx, events.SubjectServices, struct{}{}, &services)
+	g.recordRequest(err, false)
+	if err != nil {
+		utils.Warn("Service query failed: %v", err)
+		http.Error(w, "event hub is not answering service queries", http.StatusServiceUnavailable)
+		return
+	}
+
+	alive, skewed := 0, 0
+	for _, s := range services {
+		if s.Alive {
+			alive++
+		}
+		if s.ClockSkewed {
+			skewed++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"services":  services,
+		"alive":     alive,
+		"down":      len(services) - alive,
+		"skewed":    skewed,
+		"timestamp": time.Now().Format(time.RFC3339),
+	})
+}
+
+// versionHandler reports the gateway's version, commit and build date
+func (g *APIGateway) versionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildinfo.Get())
+}
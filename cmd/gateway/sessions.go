@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/strategy"
+	"github.com/myapp/tradinglab/pkg/utils"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// loadStrategySessions applies session filters to built-in and plugin
+// strategies from the JSON object at STRATEGY_SESSIONS_PATH, keyed by
+// strategy name, e.g. {"RedCandle": {"start": "09:45", "end": "15:30"}}
+func loadStrategySessions(registry *strategy.Registry) {
+	path := os.Getenv("STRATEGY_SESSIONS_PATH")
+	if path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		utils.Error("Failed to read strategy sessions: %v", err)
+		return
+	}
+
+	var sessions map[string]strategy.SessionFilter
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		utils.Error("Failed to parse strategy sessions: %v", err)
+		return
+	}
+
+	for name, session := range sessions {
+		if err := registry.SetSession(name, &session); err != nil {
+			utils.Error("Skipping session filter: %v", err)
+			continue
+		}
+		utils.Info("Strategy %s trades %+v", name, session)
+	}
+}
+
+// sessionFilter returns the session filter of a strategy at an interval.
+// Daily and longer bars have no time of day, so they are never filtered.
+func (g *APIGateway) sessionFilter(name, interval string) *strategy.SessionFilter {
+	if length, err := market.IntervalDuration(interval); err != nil || length >= 24*time.Hour {
+		return nil
+	}
+	def, exists := g.strategies.Get(name)
+	if !exists {
+		return nil
+	}
+	return def.Session
+}
+
+// filterSessionSignals drops signals outside the strategy's session filter.
+// Signal dates are in exchange time.
+func (g *APIGateway) filterSessionSignals(name, interval string, signals []*pb.Signal) []*pb.Signal {
+	filter := g.sessionFilter(name, interval)
+	if filter == nil {
+		return signals
+	}
+
+	kept := signals[:0]
+	for _, signal := range signals {
+		t, err := market.ParseTimestamp(signal.Date, market.ExchangeLocation())
+		if err != nil || !filter.Allows(t) {
+			continue
+		}
+		kept = append(kept, signal)
+	}
+	return kept
+}
+
+// sessionWindows lists the entry window of each trading day in the last
+// days calendar days, for the trading service to apply in backtests
+func sessionWindows(filter *strategy.SessionFilter, days int, now time.Time) []*pb.SessionWindow {
+	if filter == nil {
+		return nil
+	}
+
+	today := now.In(market.ExchangeLocation())
+	windows := make([]*pb.SessionWindow, 0, days)
+	for day := today.AddDate(0, 0, -days); !day.After(today); day = day.AddDate(0, 0, 1) {
+		from, to, ok := filter.Window(day)
+		if !ok {
+			continue
+		}
+		windows = append(windows, &pb.SessionWindow{
+			Date:        day.Format(market.SessionDateLayout),
+			StartMinute: int32(from),
+			EndMinute:   int32(to),
+		})
+	}
+	return windows
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/utils"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// defaultSignalModelTimeout bounds one scoring call unless
+// SIGNAL_MODEL_TIMEOUT is set. Signals are published unscored once it passes.
+const defaultSignalModelTimeout = 2 * time.Second
+
+// signalModelCalls counts calls to the signal model by result
+var signalModelCalls = metrics.Default.NewCounterVec("gateway_signal_model_calls_total",
+	"Calls to the external signal model by result", "result")
+
+// signalModel scores signals with an external model, returning one
+// confidence from 0 to 1 per signal, in order
+type signalModel interface {
+	ScoreSignals(ctx context.Context, signals []*pb.ModelSignal) ([]float64, error)
+}
+
+// httpSignalModel posts signals as JSON, {"signals": [...]}, and reads back
+// {"confidence": [...]}. Field names follow ModelSignal in trading.proto.
+type httpSignalModel struct {
+	url    string
+	client *http.Client
+}
+
+// modelSignalJSON is a ModelSignal as posted to HTTP models
+type modelSignalJSON struct {
+	Ticker     string  `json:"ticker"`
+	Strategy   string  `json:"strategy"`
+	Interval   string  `json:"interval,omitempty"`
+	Date       string  `json:"date"`
+	SignalType string  `json:"signal_type"`
+	EntryPrice float64 `json:"entry_price"`
+	Stoploss   float64 `json:"stoploss,omitempty"`
+}
+
+func (m *httpSignalModel) ScoreSignals(ctx context.Context, signals []*pb.ModelSignal) ([]float64, error) {
+	payload := struct {
+		Signals []modelSignalJSON `json:"signals"`
+	}{Signals: make([]modelSignalJSON, len(signals))}
+	for i, s := range signals {
+		payload.Signals[i] = modelSignalJSON{
+			Ticker:     s.Ticker,
+			Strategy:   s.Strategy,
+			Interval:   s.Interval,
+			Date:       s.Date,
+			SignalType: s.SignalType,
+			EntryPrice: s.EntryPrice,
+			Stoploss:   s.Stoploss,
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("model request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("model returned status %d", resp.StatusCode)
+	}
+
+	var scored struct {
+		Confidence []float64 `json:"confidence"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&scored); err != nil {
+		return nil, fmt.Errorf("invalid model response: %w", err)
+	}
+	return scored.Confidence, nil
+}
+
+// grpcSignalModel calls the SignalModel service
+type grpcSignalModel struct {
+	client pb.SignalModelClient
+}
+
+func (m *grpcSignalModel) ScoreSignals(ctx context.Context, signals []*pb.ModelSignal) ([]float64, error) {
+	resp, err := m.client.ScoreSignals(ctx, &pb.ScoreSignalsRequest{Signals: signals})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Confidence, nil
+}
+
+// newSignalModel connects to the model at SIGNAL_MODEL_URL: an http:// or
+// https:// URL to post signals to, or grpc://host:port for the SignalModel
+// service. It returns nil, leaving signals unscored, when none is set.
+func newSignalModel() signalModel {
+	endpoint := os.Getenv("SIGNAL_MODEL_URL")
+	switch {
+	case endpoint == "":
+		return nil
+	case strings.HasPrefix(endpoint, "http://"), strings.HasPrefix(endpoint, "https://"):
+		utils.Info("Scoring signals with the model at %s", endpoint)
+		return &httpSignalModel{url: endpoint, client: &http.Client{}}
+	case strings.HasPrefix(endpoint, "grpc://"):
+		// The connection is made lazily, so an unavailable model does not
+		// hold up startup
+		conn, err := grpc.Dial(strings.TrimPrefix(endpoint, "grpc://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			utils.Error("Failed to set up the signal model at %s, signals are unscored: %v", endpoint, err)
+			return nil
+		}
+		utils.Info("Scoring signals with the model at %s", endpoint)
+		return &grpcSignalModel{client: pb.NewSignalModelClient(conn)}
+	}
+	utils.Warn("Invalid SIGNAL_MODEL_URL %q, expected http://, https:// or grpc://; signals are unscored", endpoint)
+	return nil
+}
+
+// signalModelTimeoutFromEnv reads SIGNAL_MODEL_TIMEOUT
+func signalModelTimeoutFromEnv() time.Duration {
+	if value := os.Getenv("SIGNAL_MODEL_TIMEOUT"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+		utils.Warn("Invalid SIGNAL_MODEL_TIMEOUT %q, using default %s", value, defaultSignalModelTimeout)
+	}
+	return defaultSignalModelTimeout
+}
+
+// scoreSignals attaches the model's confidence to each signal, which alert
+// rules can filter on. Signals are kept unscored when no model is configured
+// or it fails, so an unavailable model does not hold back signals.
+func (g *APIGateway) scoreSignals(ctx context.Context, signals []map[string]interface{}) {
+	if g.signalModel == nil || len(signals) == 0 {
+		return
+	}
+
+	batch := make([]*pb.ModelSignal, len(signals))
+	for i, signal := range signals {
+		s := &pb.ModelSignal{}
+		s.Ticker, _ = signal["ticker"].(string)
+		s.Strategy, _ = signal["strategy"].(string)
+		s.Interval, _ = signal["interval"].(string)
+		s.Date, _ = signal["date"].(string)
+		s.SignalType, _ = signal["signal_type"].(string)
+		s.EntryPrice, _ = signal["entry_price"].(float64)
+		s.Stoploss, _ = signal["stoploss"].(float64)
+		batch[i] = s
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, g.modelTimeout)
+	defer cancel()
+	confidence, err := g.signalModel.ScoreSignals(ctx, batch)
+	if err == nil && len(confidence) != len(signals) {
+		err = fmt.Errorf("model returned %d scores for %d signals", len(confidence), len(signals))
+	}
+	for i := 0; err == nil && i < len(confidence); i++ {
+		if c := confidence[i]; c < 0 || c > 1 || math.IsNaN(c) {
+			err = fmt.Errorf("model returned confidence %v outside 0 to 1", c)
+		}
+	}
+	if err != nil {
+		signalModelCalls.With("error").Inc()
+		utils.Warn("Failed to score %d signals, publishing them unscored: %v", len(signals), err)
+		return
+	}
+
+	signalModelCalls.With("ok").Inc()
+	for i, signal := range signals {
+		signal["confidence"] = confidence[i]
+	}
+}
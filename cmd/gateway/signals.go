@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// signalHistoryHandler pages through signals stored in the SIGNALS stream,
+// oldest first. from and to (YYYY-MM-DD or RFC3339) bound the publication
+// time; pass the returned next_cursor as cursor to read the next page.
+func (g *APIGateway) signalHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if g.natsClient == nil {
+		http.Error(w, "NATS is not connected", http.StatusServiceUnavailable)
+		return
+	}
+
+	from, err := parseDateParam(r, "from", false)
+	if err != nil {
+		http.Error(w, "invalid from parameter", http.StatusBadRequest)
+		return
+	}
+	to, err := parseDateParam(r, "to", true)
+	if err != nil {
+		http.Error(w, "invalid to parameter", http.StatusBadRequest)
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+
+	query := events.HistoryQuery{From: from, To: to}
+	if value := r.URL.Query().Get("cursor"); value != "" {
+		if query.After, err = strconv.ParseUint(value, 10, 64); err != nil {
+			http.Error(w, "invalid cursor parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit <= 0 {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", events.MaxHistoryLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ticker := r.URL.Query().Get("ticker")
+	if ticker != "" {
+		if ticker, err = market.ValidateTicker(ticker); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := g.natsClient.SignalHistory(ticker, query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading signal history: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
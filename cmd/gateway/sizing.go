@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/risk"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// atrPeriod is the lookback used for ATR-based stops
+const atrPeriod = 14
+
+// SizingDefaults holds the account settings used when a request does not specify them
+type SizingDefaults struct {
+	AccountSize float64
+	RiskPercent float64
+}
+
+// loadSizingDefaults reads default account settings from the environment
+func loadSizingDefaults() SizingDefaults {
+	return SizingDefaults{
+		AccountSize: envFloat("ACCOUNT_SIZE", 10000),
+		RiskPercent: envFloat("RISK_PER_TRADE_PCT", 1),
+	}
+}
+
+// envFloat reads a float environment variable with a default
+func envFloat(name string, def float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		utils.Warn("Invalid %s value '%s', using default %.2f", name, value, def)
+		return def
+	}
+	return f
+}
+
+// queryFloat parses an optional float query parameter with a default
+func queryFloat(r *http.Request, name string, def float64) (float64, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// getATR computes the ATR for a ticker from recent daily candles
+func (g *APIGateway) getATR(ctx context.Context, ticker, interval string) (float64, error) {
+	candles, err := g.fetchCandles(ctx, ticker, interval, 30)
+	if err != nil {
+		return 0, err
+	}
+	return analytics.ATR(candles, atrPeriod), nil
+}
+
+// positionSizeHandler suggests a position size for a trade
+func (g *APIGateway) positionSizeHandler(w http.ResponseWriter, r *http.Request) {
+	req := risk.SizingRequest{
+		Direction: r.URL.Query().Get("direction"),
+	}
+
+	var err error
+	for _, p := range []struct {
+		name   string
+		target *float64
+		def    float64
+	}{
+		{"account_size", &req.AccountSize, g.sizingDefaults.AccountSize},
+		{"risk_pct", &req.RiskPercent, g.sizingDefaults.RiskPercent},
+		{"entry", &req.Entry, 0},
+		{"stoploss", &req.Stoploss, 0},
+		{"atr_multiplier", &req.ATRMultiplier, risk.DefaultATRMultiplier},
+		{"option_price", &req.OptionPrice, 0},
+	} {
+		if *p.target, err = queryFloat(r, p.name, p.def); err != nil {
+			http.Error(w, "invalid "+p.name+" parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Use ATR for the stop when none was given and a ticker is known
+	if ticker := r.URL.Query().Get("ticker"); ticker != "" && req.Stoploss <= 0 {
+		ticker, err := market.ValidateTicker(ticker)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		interval := r.URL.Query().Get("interval")
+		if interval == "" {
+			interval = market.Interval1Day
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 20*time.Second)
+		defer cancel()
+
+		req.ATR, err = g.getATR(ctx, ticker, interval)
+		if err != nil {
+			http.Error(w, "Error computing ATR: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	result, err := risk.CalculatePositionSize(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// recommendationSizing computes the default position size for a recommendation
+func (g *APIGateway) recommendationSizing(direction string, entry, stoploss, optionPrice float64) *risk.SizingResult {
+	result, err := risk.CalculatePositionSize(risk.SizingRequest{
+		AccountSize: g.sizingDefaults.AccountSize,
+		RiskPercent: g.sizingDefaults.RiskPercent,
+		Entry:       entry,
+		Stoploss:    stoploss,
+		Direction:   direction,
+		OptionPrice: optionPrice,
+	})
+	if err != nil {
+		return nil
+	}
+	return &result
+}
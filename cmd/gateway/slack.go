@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/alerts"
+	"github.com/myapp/tradinglab/pkg/audit"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/slack"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// slackAckTimeout is how long a slash command may run before the handler
+// acknowledges it and replies later; Slack gives up after 3 seconds
+const slackAckTimeout = 2500 * time.Millisecond
+
+// slackCommandTimeout bounds a slash command that replies later
+const slackCommandTimeout = 30 * time.Second
+
+// slackSignalLimit caps the signals listed by /tl signals
+const slackSignalLimit = 10
+
+// slackHelp lists the slash commands
+const slackHelp = "*TradingLab commands*\n" +
+	"• `quote SPY` – latest daily bar and change\n" +
+	"• `signals AAPL [15min] [strategy]` – recent strategy signals\n" +
+	"• `subscribe AAPL SPY` – post live signals for tickers to this channel\n" +
+	"• `unsubscribe [AAPL]` – stop posting signals for tickers, or all of them\n" +
+	"• `subscriptions` – list this channel's tickers"
+
+// newSlackChannelStore creates the Slack channel subscription store,
+// persisted to SLACK_CHANNELS_PATH when set
+func newSlackChannelStore() *slack.ChannelStore {
+	store, err := slack.NewChannelStore(os.Getenv("SLACK_CHANNELS_PATH"))
+	if err != nil {
+		utils.Error("Failed to load Slack channel subscriptions, starting empty: %v", err)
+		store, _ = slack.NewChannelStore("")
+	}
+	return store
+}
+
+// slackCommandHandler answers slash commands from the Slack app. Requests
+// are verified with SLACK_SIGNING_SECRET; commands that outlast Slack's
+// deadline are acknowledged and answered through the response URL.
+func (g *APIGateway) slackCommandHandler(w http.ResponseWriter, r *http.Request) {
+	secret := os.Getenv("SLACK_SIGNING_SECRET")
+	if secret == "" {
+		http.Error(w, "Slack integration is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhookMaxBody))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if err := slack.VerifyRequest(secret, r.Header, body, time.Now()); err != nil {
+		utils.Info("Rejected Slack command from %s: %v", r.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid command payload", http.StatusBadRequest)
+		return
+	}
+	cmd := slack.ParseCommand(form)
+
+	done := make(chan slack.Response, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), slackCommandTimeout)
+		defer cancel()
+		done <- g.runSlackCommand(ctx, cmd)
+	}()
+
+	var resp slack.Response
+	select {
+	case resp = <-done:
+	case <-time.After(slackAckTimeout):
+		go g.respondSlackLater(cmd, done)
+		resp = slack.Reply("Working on `%s`…", cmd.Text)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// respondSlackLater sends a slow command's reply to its response URL
+func (g *APIGateway) respondSlackLater(cmd slack.Command, done <-chan slack.Response) {
+	resp := <-done
+	if cmd.ResponseURL == "" {
+		return
+	}
+	resp.ReplaceOriginal = true
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := slack.Respond(ctx, cmd.ResponseURL, resp); err != nil {
+		utils.Warn("Failed to send delayed Slack reply to %s: %v", cmd.ChannelName, err)
+	}
+}
+
+// runSlackCommand dispatches a slash command to its subcommand
+func (g *APIGateway) runSlackCommand(ctx context.Context, cmd slack.Command) slack.Response {
+	name, args := cmd.Args()
+	switch name {
+	case "quote":
+		if len(args) != 1 {
+			return slack.Reply("Usage: `%s quote SPY`", cmd.Command)
+		}
+		return g.slackQuote(ctx, args[0])
+	case "signals":
+		if len(args) < 1 || len(args) > 3 {
+			return slack.Reply("Usage: `%s signals AAPL [15min] [strategy]`", cmd.Command)
+		}
+		interval, strategy := "15min", "RedCandle"
+		if len(args) > 1 {
+			interval = args[1]
+		}
+		if len(args) > 2 {
+			strategy = args[2]
+		}
+		return g.slackSignals(ctx, args[0], interval, strategy)
+	case "subscribe":
+		if len(args) == 0 {
+			return slack.Reply("Usage: `%s subscribe AAPL SPY`", cmd.Command)
+		}
+		tickers, err := market.ValidateTickers(args)
+		if err != nil {
+			return slack.Reply("%v", err)
+		}
+		sub, err := g.slackChannels.Subscribe(cmd, tickers)
+		if err != nil {
+			utils.Error("Failed to save Slack subscription for %s: %v", cmd.ChannelID, err)
+			return slack.Reply("Failed to save the subscription, please try again")
+		}
+		g.auditSlackCommand(cmd, "watchlist.slack.subscribe", tickers)
+		resp := slack.Response{
+			ResponseType: slack.InChannel,
+			Text:         fmt.Sprintf("This channel now gets live signals for %s", strings.Join(sub.Tickers, ", ")),
+		}
+		if g.slackClient == nil {
+			resp.Text += "\n_Posting is not configured yet: the gateway needs a SLACK_BOT_TOKEN_"
+		}
+		return resp
+	case "unsubscribe":
+		sub, err := g.slackChannels.Unsubscribe(cmd.ChannelID, args)
+		if err != nil {
+			utils.Error("Failed to save Slack subscription for %s: %v", cmd.ChannelID, err)
+			return slack.Reply("Failed to save the subscription, please try again")
+		}
+		g.auditSlackCommand(cmd, "watchlist.slack.unsubscribe", args)
+		if len(sub.Tickers) == 0 {
+			return slack.Response{ResponseType: slack.InChannel, Text: "This channel no longer gets live signals"}
+		}
+		return slack.Response{
+			ResponseType: slack.InChannel,
+			Text:         fmt.Sprintf("This channel now gets live signals for %s", strings.Join(sub.Tickers, ", ")),
+		}
+	case "subscriptions":
+		sub, ok := g.slackChannels.Get(cmd.ChannelID)
+		if !ok {
+			return slack.Reply("This channel has no signal subscriptions")
+		}
+		return slack.Reply("This channel gets live signals for %s", strings.Join(sub.Tickers, ", "))
+	case "", "help":
+		return slack.Reply(slackHelp)
+	}
+	return slack.Reply("Unknown command `%s`\n%s", name, slackHelp)
+}
+
+// auditSlackCommand records a channel subscription change made from Slack
+func (g *APIGateway) auditSlackCommand(cmd slack.Command, action string, tickers []string) {
+	g.recordAudit(audit.Entry{
+		Actor:    "slack:" + cmd.UserName,
+		Action:   action,
+		Resource: map[string]string{"channel_id": cmd.ChannelID, "channel_name": cmd.ChannelName},
+		Details:  map[string]interface{}{"tickers": tickers, "slack_user_id": cmd.UserID, "team_id": cmd.TeamID},
+	})
+}
+
+// slackQuote replies with a ticker's latest daily bar and its change on
+// the previous close
+func (g *APIGateway) slackQuote(ctx context.Context, ticker string) slack.Response {
+	params, err := market.NormalizeHistoricalParams(ticker, market.Interval1Day, 7)
+	if err != nil {
+		return slack.Reply("%v", err)
+	}
+	candles, err := g.fetchCandles(ctx, params.Ticker, params.Interval, params.Days)
+	if err != nil {
+		utils.Warn("Slack quote for %s failed: %v", params.Ticker, err)
+		return slack.Reply("No quote available for %s", params.Ticker)
+	}
+
+	last := candles[len(candles)-1]
+	text := fmt.Sprintf("*%s* %.2f", params.Ticker, last.Close)
+	if len(candles) > 1 {
+		prev := candles[len(candles)-2].Close
+		change := last.Close - prev
+		arrow := "▲"
+		if change < 0 {
+			arrow = "▼"
+		}
+		text += fmt.Sprintf("  %s %+.2f (%+.2f%%)", arrow, change, change/prev*100)
+	}
+	text += fmt.Sprintf("\nO %.2f · H %.2f · L %.2f · V %s · %s",
+		last.Open, last.High, last.Low, formatVolume(last.Volume), last.Time.Format("2006-01-02"))
+	return slack.Response{ResponseType: slack.InChannel, Text: text}
+}
+
+// slackSignals replies with a strategy's most recent signals for a ticker
+func (g *APIGateway) slackSignals(ctx context.Context, ticker, interval, strategy string) slack.Response {
+	params, err := market.NormalizeHistoricalParams(ticker, interval, 5)
+	if err != nil {
+		return slack.Reply("%v", err)
+	}
+	strategyParams, err := g.strategies.Resolve(strategy, nil)
+	if err != nil {
+		return slack.Reply("%v", err)
+	}
+	resp, err := g.strategySignals(ctx, strategy, params, strategyParams)
+	if err != nil {
+		utils.Warn("Slack signals for %s failed: %v", params.Ticker, err)
+		return slack.Reply("Failed to generate %s signals for %s", strategy, params.Ticker)
+	}
+
+	header := fmt.Sprintf("*%s %s signals* (%s, last %d days)", params.Ticker, strategy, params.Interval, params.Days)
+	if len(resp.Signals) == 0 {
+		return slack.Response{ResponseType: slack.InChannel, Text: header + "\nNo signals"}
+	}
+	lines := []string{header}
+	signals := resp.Signals
+	if len(signals) > slackSignalLimit {
+		signals = signals[len(signals)-slackSignalLimit:]
+	}
+	for _, s := range signals {
+		lines = append(lines, fmt.Sprintf("• %s %s @ %.2f, stop %.2f", s.Date, s.SignalType, s.EntryPrice, s.Stoploss))
+	}
+	return slack.Response{ResponseType: slack.InChannel, Text: strings.Join(lines, "\n")}
+}
+
+// postSlackSignal posts a live signal to the channels subscribed to its ticker
+func (g *APIGateway) postSlackSignal(signal alerts.Signal) {
+	if g.slackClient == nil {
+		return
+	}
+	channels := g.slackChannels.Channels(signal.Ticker)
+	if len(channels) == 0 {
+		return
+	}
+
+	text := formatSlackSignal(signal)
+	ctx, cancel := context.WithTimeout(context.Background(), alertSendTimeout)
+	defer cancel()
+	for _, channel := range channels {
+		if err := g.slackClient.PostMessage(ctx, channel, text); err != nil {
+			utils.Warn("Failed to post %s signal to Slack channel %s: %v", signal.Ticker, channel, err)
+		}
+	}
+}
+
+// formatSlackSignal renders a live signal as a Slack message
+func formatSlackSignal(s alerts.Signal) string {
+	icon := ":chart_with_upwards_trend:"
+	if strings.EqualFold(s.SignalType, "SHORT") {
+		icon = ":chart_with_downwards_trend:"
+	}
+	text := fmt.Sprintf("%s *%s %s* @ %.2f", icon, s.SignalType, s.Ticker, s.EntryPrice)
+	if s.Stoploss > 0 {
+		text += fmt.Sprintf(", stop %.2f", s.Stoploss)
+	}
+
+	details := []string{s.Strategy}
+	if s.Interval != "" {
+		details = append(details, s.Interval)
+	}
+	if s.Date != "" {
+		details = append(details, s.Date)
+	}
+	if s.HighPriority() {
+		details = append(details, "high priority")
+	}
+	return text + "\n" + strings.Join(details, " · ")
+}
+
+// formatVolume abbreviates a share volume
+func formatVolume(v float64) string {
+	switch {
+	case v >= 1e9:
+		return fmt.Sprintf("%.1fB", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case v >= 1e3:
+		return fmt.Sprintf("%.1fK", v/1e3)
+	}
+	return fmt.Sprintf("%.0f", v)
+}
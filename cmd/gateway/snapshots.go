@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/snapshot"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// snapshotTimeout bounds taking or restoring one snapshot
+const snapshotTimeout = 30 * time.Minute
+
+// newSnapshotter snapshots the managed streams and the persistence store to
+// the object store
+func (g *APIGateway) newSnapshotter() *snapshot.Snapshotter {
+	return snapshot.New(g.objects, g.natsClient, snapshot.LocationsFromEnv()...)
+}
+
+// snapshotError writes the response for a snapshot operation error
+func snapshotError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, snapshot.ErrNotFound), errors.Is(err, events.ErrUnknownStream):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// snapshotsHandler lists completed snapshots
+func (g *APIGateway) snapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	manifests, err := g.snapshots.List(r.Context())
+	if err != nil {
+		snapshotError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"snapshots": manifests})
+}
+
+// snapshotHandler returns one snapshot's manifest
+func (g *APIGateway) snapshotHandler(w http.ResponseWriter, r *http.Request) {
+	manifest, err := g.snapshots.Get(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		snapshotError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(manifest)
+}
+
+// snapshotCreateHandler snapshots the streams listed in streams (all by
+// default, "none" for none) and the persistence store unless store=false
+func (g *APIGateway) snapshotCreateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), snapshotTimeout)
+	defer cancel()
+
+	manifest, err := g.snapshots.Create(ctx, snapshot.Options{
+		Streams: snapshot.ParseStreams(r.URL.Query().Get("streams")),
+		Store:   r.URL.Query().Get("store") != "false",
+	})
+	if err != nil {
+		utils.Error("Snapshot failed: %v", err)
+		snapshotError(w, err)
+		return
+	}
+	utils.Info("Created snapshot %s of %d streams", manifest.ID, len(manifest.Streams))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(manifest)
+}
+
+// snapshotRestoreHandler restores a snapshot's streams, optionally only those
+// listed in streams. Non-empty streams are only overwritten with
+// replace=true. The persistence store is restored with the snapshot command
+// while the gateway is stopped, since the gateway holds it in memory.
+func (g *APIGateway) snapshotRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("store") == "true" {
+		http.Error(w, "restore the persistence store with the snapshot command while the gateway is stopped", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), snapshotTimeout)
+	defer cancel()
+
+	id := mux.Vars(r)["id"]
+	result, err := g.snapshots.Restore(ctx, id, snapshot.Options{
+		Streams: snapshot.ParseStreams(r.URL.Query().Get("streams")),
+		Replace: r.URL.Query().Get("replace") == "true",
+	})
+	if err != nil {
+		utils.Error("Restoring snapshot %s failed: %v", id, err)
+		if result == nil {
+			snapshotError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "restored": result})
+		return
+	}
+	utils.Info("Restored snapshot %s", id)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
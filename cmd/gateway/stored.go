@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+var storedFallbacks = metrics.Default.NewCounterVec("gateway_stored_fallbacks_total",
+	"Historical requests served from bars stored in JetStream while the trading service was down, by source", "source")
+
+// storedCandles reads the historical candles the market data service
+// persisted in JetStream, for when neither the trading service nor the
+// in-process cache can answer. It reports false if none are stored.
+func (g *APIGateway) storedCandles(params market.HistoricalParams) ([]map[string]interface{}, string, bool) {
+	if g.natsClient == nil {
+		return nil, "", false
+	}
+
+	bars, source, err := g.natsClient.StoredHistoricalData(params.Ticker, params.Interval, params.Days)
+	g.recordRequest(err, false)
+	if err != nil {
+		if !errors.Is(err, nats.ErrMsgNotFound) {
+			utils.Warn("Failed to read stored historical data for %s: %v", params.Ticker, err)
+		}
+		return nil, "", false
+	}
+
+	candles := make([]map[string]interface{}, 0, len(bars))
+	for _, bar := range bars {
+		candles = append(candles, map[string]interface{}{
+			"date":   market.FormatTimestamp(bar.Timestamp),
+			"open":   bar.Open,
+			"high":   bar.High,
+			"low":    bar.Low,
+			"close":  bar.Close,
+			"volume": bar.Volume,
+		})
+	}
+	storedFallbacks.With(source).Inc()
+	return candles, source, true
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/strategy"
+	"github.com/myapp/tradinglab/pkg/utils"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// strategyParamPrefix marks individual strategy parameters in query strings, e.g. param.rsi_period=10
+const strategyParamPrefix = "param."
+
+// strategyReloadInterval is how often the user strategy file is checked for changes
+const strategyReloadInterval = 10 * time.Second
+
+// newStrategyRegistry creates the strategy registry with built-in strategies and
+// user strategies loaded from STRATEGIES_PATH
+func newStrategyRegistry() *strategy.Registry {
+	registry := strategy.DefaultRegistry()
+	if err := registry.LoadFile(os.Getenv("STRATEGIES_PATH")); err != nil {
+		utils.Error("Failed to load user strategies: %v", err)
+	}
+	return registry
+}
+
+// loadStrategyPlugins loads strategy plugins from STRATEGY_PLUGINS_DIR and adds
+// them to the registry
+func loadStrategyPlugins(registry *strategy.Registry) *strategy.PluginSet {
+	set := strategy.NewPluginSet()
+
+	plugins, failures := strategy.LoadPlugins(os.Getenv("STRATEGY_PLUGINS_DIR"))
+	for dir, err := range failures {
+		utils.Error("Skipping strategy plugin %s: %v", dir, err)
+	}
+	for _, plugin := range plugins {
+		if err := set.Add(plugin, registry); err != nil {
+			utils.Error("Skipping strategy plugin: %v", err)
+			continue
+		}
+		limits := plugin.Limits()
+		utils.Info("Loaded strategy plugin %s (timeout %ds, memory %dMB)",
+			plugin.Definition().Name, limits.TimeoutSeconds, limits.MemoryMB)
+	}
+
+	return set
+}
+
+// pluginSignals runs a plugin strategy against historical candles
+func (g *APIGateway) pluginSignals(ctx context.Context, plugin *strategy.Plugin, params market.HistoricalParams, strategyParams map[string]string) (*pb.SignalResponse, error) {
+	candles, err := g.fetchCandles(ctx, params.Ticker, params.Interval, params.Days)
+	if err != nil {
+		return nil, err
+	}
+
+	signals, err := plugin.GenerateSignals(ctx, strategy.SignalInput{
+		Ticker:   params.Ticker,
+		Interval: params.Interval,
+		Candles:  candles,
+		Params:   strategyParams,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.SignalResponse{}
+	for _, signal := range signals {
+		resp.Signals = append(resp.Signals, &pb.Signal{
+			Date:       signal.Date,
+			SignalType: signal.SignalType,
+			EntryPrice: signal.EntryPrice,
+			Stoploss:   signal.Stoploss,
+		})
+	}
+	return resp, nil
+}
+
+// strategySignals generates signals with a plugin, or with the trading service
+// for built-in and rule-based strategies, keeping those within the strategy's
+// session filter
+func (g *APIGateway) strategySignals(ctx context.Context, name string, params market.HistoricalParams, strategyParams map[string]string) (*pb.SignalResponse, error) {
+	var resp *pb.SignalResponse
+	var err error
+	if plugin, isPlugin := g.plugins.Get(name); isPlugin {
+		resp, err = g.pluginSignals(ctx, plugin, params, strategyParams)
+	} else {
+		resp, err = g.tradingClient.GenerateSignals(ctx, &pb.SignalRequest{
+			Ticker:     params.Ticker,
+			Days:       int32(params.Days),
+			Strategy:   g.strategies.EngineName(name),
+			Interval:   params.Interval,
+			Parameters: strategyParams,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Signals = g.filterSessionSignals(name, params.Interval, resp.Signals)
+	return resp, nil
+}
+
+// watchStrategies reloads user strategies when the strategy file is edited
+func (g *APIGateway) watchStrategies() {
+	ticker := time.NewTicker(strategyReloadInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		reloaded, err := g.strategies.Reload()
+		if err != nil {
+			utils.Error("Failed to reload user strategies, keeping previous set: %v", err)
+			continue
+		}
+		if reloaded {
+			utils.Info("Reloaded user strategies from file")
+		}
+	}
+}
+
+// strategiesHandler lists registered strategies and their configurable parameters
+func (g *APIGateway) strategiesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.strategies.List())
+}
+
+// strategyGetHandler returns a single strategy definition
+func (g *APIGateway) strategyGetHandler(w http.ResponseWriter, r *http.Request) {
+	def, exists := g.strategies.Get(mux.Vars(r)["name"])
+	if !exists {
+		http.Error(w, "strategy not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(def)
+}
+
+// strategyDefineHandler creates or replaces a user strategy from rule expressions
+func (g *APIGateway) strategyDefineHandler(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Name        string                  `json:"name"`
+		Description string                  `json:"description"`
+		Rules       strategy.Rules          `json:"rules"`
+		Session     *strategy.SessionFilter `json:"session"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid strategy payload", http.StatusBadRequest)
+		return
+	}
+	if name := mux.Vars(r)["name"]; name != "" {
+		payload.Name = name
+	}
+
+	def, err := g.strategies.Define(payload.Name, payload.Description, payload.Rules, payload.Session)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	utils.Info("Defined user strategy %s", def.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodPost {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(def)
+}
+
+// strategyDeleteHandler removes a user strategy
+func (g *APIGateway) strategyDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if err := g.strategies.Remove(mux.Vars(r)["name"]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// strategyParams reads strategy parameters from a request and validates them
+// against the registry. Parameters may be sent as a JSON object in the params
+// query value, as individual param.<name> query values, or as a JSON body of
+// the form {"params": {...}}.
+func (g *APIGateway) strategyParams(r *http.Request, strategy string) (map[string]string, error) {
+	values := make(map[string]interface{})
+
+	if raw := r.URL.Query().Get("params"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &values); err != nil {
+			return nil, fmt.Errorf("params must be a JSON object: %w", err)
+		}
+	}
+
+	for key, vals := range r.URL.Query() {
+		if strings.HasPrefix(key, strategyParamPrefix) && len(vals) > 0 {
+			values[strings.TrimPrefix(key, strategyParamPrefix)] = vals[0]
+		}
+	}
+
+	if r.Method == http.MethodPost && r.Body != nil {
+		var body struct {
+			Params map[string]interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+		for key, value := range body.Params {
+			values[key] = value
+		}
+	}
+
+	return g.strategies.Resolve(strategy, values)
+}
+
+// paramsCacheKey encodes resolved parameters in a stable order for cache keys
+func paramsCacheKey(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+params[key])
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// summaryLookbackDays is how far back the latest daily summary is looked for,
+// enough to span a long weekend
+const summaryLookbackDays = 7
+
+// dailySummaryHandler returns the hub's cross-ticker summary for a market
+// date, or the most recent one when no date is given
+func (g *APIGateway) dailySummaryHandler(w http.ResponseWriter, r *http.Request) {
+	var summary *events.DailySummary
+	var err error
+	if date := r.URL.Query().Get("date"); date != "" {
+		if _, parseErr := time.Parse(events.SummaryDateLayout, date); parseErr != nil {
+			http.Error(w, "invalid date parameter, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		summary, err = g.natsClient.DailySummary(date)
+	} else {
+		summary, err = g.natsClient.LatestDailySummary(time.Now().In(market.ExchangeLocation()), summaryLookbackDays)
+	}
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		http.Error(w, "no daily summary available", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load daily summary: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// symbolSearchHandler serves ticker autocomplete from the market data service's
+// symbol reference data
+func (g *APIGateway) symbolSearchHandler(w http.ResponseWriter, r *http.Request) {
+	search := market.SymbolSearch{
+		Query: r.URL.Query().Get("q"),
+		Limit: market.DefaultSymbolResults,
+	}
+	if search.Query == "" {
+		http.Error(w, "q parameter is required", http.StatusBadRequest)
+		return
+	}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > market.MaxSymbolResults {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", market.MaxSymbolResults), http.StatusBadRequest)
+			return
+		}
+		search.Limit = limit
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var symbols []market.Symbol
+	err := g.natsClient.Request(ctx, events.SubjectReferenceSymbolSearch, search, &symbols)
+	g.recordRequest(err, true)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("symbol search unavailable: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(symbols)
+}
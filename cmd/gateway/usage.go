@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/myapp/tradinglab/pkg/auth"
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/utils"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// Kinds of metered usage
+const (
+	usageCandles   = "candles"   // Candles fetched from the trading service
+	usageBacktests = "backtests" // Backtests and options backtests run
+)
+
+// usageSystemUser is charged for work the gateway starts itself, such as
+// scheduled reports and scans
+const usageSystemUser = "system"
+
+// usageTotal counts metered usage across all users
+var usageTotal = metrics.Default.NewCounterVec("gateway_usage_total",
+	"Candles fetched and backtests run on behalf of callers", "kind")
+
+// UsageCounts is usage by kind
+type UsageCounts struct {
+	Candles   int64 `json:"candles"`
+	Backtests int64 `json:"backtests"`
+}
+
+// add adds n to a kind
+func (c *UsageCounts) add(kind string, n int64) {
+	switch kind {
+	case usageCandles:
+		c.Candles += n
+	case usageBacktests:
+		c.Backtests += n
+	}
+}
+
+// get returns the count of a kind
+func (c UsageCounts) get(kind string) int64 {
+	switch kind {
+	case usageCandles:
+		return c.Candles
+	case usageBacktests:
+		return c.Backtests
+	}
+	return 0
+}
+
+// UsageReport is one user's usage today (UTC) and since the gateway started
+type UsageReport struct {
+	User   string      `json:"user"`
+	Day    string      `json:"day"`
+	Today  UsageCounts `json:"today"`
+	Total  UsageCounts `json:"total"`
+	Limits UsageCounts `json:"daily_limits"` // Zero means unlimited
+}
+
+// usageTracker meters provider-backed work per user and enforces optional
+// daily limits. Counts are kept in memory, per gateway instance.
+type usageTracker struct {
+	mu     sync.Mutex
+	day    string
+	today  map[string]*UsageCounts
+	total  map[string]*UsageCounts
+	limits UsageCounts
+}
+
+// newUsageTracker creates a tracker with daily limits per user from
+// USAGE_DAILY_CANDLES and USAGE_DAILY_BACKTESTS; unset or 0 is unlimited
+func newUsageTracker() *usageTracker {
+	t := &usageTracker{
+		today: make(map[string]*UsageCounts),
+		total: make(map[string]*UsageCounts),
+	}
+	for env, limit := range map[string]*int64{"USAGE_DAILY_CANDLES": &t.limits.Candles, "USAGE_DAILY_BACKTESTS": &t.limits.Backtests} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			utils.Warn("Invalid %s %q, not limiting", env, value)
+			continue
+		}
+		*limit = n
+	}
+	if t.limits != (UsageCounts{}) {
+		utils.Info("Limiting each user to %d candles and %d backtests a day (0 is unlimited)",
+			t.limits.Candles, t.limits.Backtests)
+	}
+	return t
+}
+
+// rollover starts a new day's counts at UTC midnight; the caller holds mu
+func (t *usageTracker) rollover() string {
+	day := time.Now().UTC().Format("2006-01-02")
+	if day != t.day {
+		t.day = day
+		t.today = make(map[string]*UsageCounts)
+	}
+	return day
+}
+
+// Add charges a user for n units of a kind
+func (t *usageTracker) Add(user, kind string, n int64) {
+	if n <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+	for _, counts := range []map[string]*UsageCounts{t.today, t.total} {
+		if counts[user] == nil {
+			counts[user] = &UsageCounts{}
+		}
+		counts[user].add(kind, n)
+	}
+	usageTotal.With(kind).Add(float64(n))
+}
+
+// Exceeded reports whether a user has used up today's limit of a kind
+func (t *usageTracker) Exceeded(user, kind string) bool {
+	limit := t.limits.get(kind)
+	if limit == 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollover()
+	today := t.today[user]
+	return today != nil && today.get(kind) >= limit
+}
+
+// Report returns a user's usage
+func (t *usageTracker) Report(user string) UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.report(user)
+}
+
+// report returns a user's usage; the caller holds mu
+func (t *usageTracker) report(user string) UsageReport {
+	report := UsageReport{User: user, Day: t.rollover(), Limits: t.limits}
+	if today := t.today[user]; today != nil {
+		report.Today = *today
+	}
+	if total := t.total[user]; total != nil {
+		report.Total = *total
+	}
+	return report
+}
+
+// Reports returns the usage of every user seen, by name
+func (t *usageTracker) Reports() []UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	reports := make([]UsageReport, 0, len(t.total))
+	for user := range t.total {
+		reports = append(reports, t.report(user))
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].User < reports[j].User })
+	return reports
+}
+
+// resetIn returns how long until today's counts reset
+func (t *usageTracker) resetIn() time.Duration {
+	now := time.Now().UTC()
+	return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
+
+// usageUser names who a call is charged to: the caller of the request it
+// serves, or the system for work the gateway starts itself
+func usageUser(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFrom(ctx); ok {
+		return principal.User
+	}
+	return usageSystemUser
+}
+
+// meteredTradingClient charges the caller for candles fetched and backtests
+// run through the trading service, whichever route they came in on
+type meteredTradingClient struct {
+	pb.TradingServiceClient
+	usage *usageTracker
+}
+
+func (c meteredTradingClient) GetHistoricalData(ctx context.Context, req *pb.HistoricalDataRequest, opts ...grpc.CallOption) (*pb.HistoricalDataResponse, error) {
+	resp, err := c.TradingServiceClient.GetHistoricalData(ctx, req, opts...)
+	if err == nil {
+		c.usage.Add(usageUser(ctx), usageCandles, int64(len(resp.Candles)))
+	}
+	return resp, err
+}
+
+func (c meteredTradingClient) RunBacktest(ctx context.Context, req *pb.BacktestRequest, opts ...grpc.CallOption) (*pb.BacktestResponse, error) {
+	resp, err := c.TradingServiceClient.RunBacktest(ctx, req, opts...)
+	if err == nil {
+		c.usage.Add(usageUser(ctx), usageBacktests, 1)
+	}
+	return resp, err
+}
+
+func (c meteredTradingClient) RunOptionsBacktest(ctx context.Context, req *pb.OptionsBacktestRequest, opts ...grpc.CallOption) (*pb.OptionsBacktestResponse, error) {
+	resp, err := c.TradingServiceClient.RunOptionsBacktest(ctx, req, opts...)
+	if err == nil {
+		c.usage.Add(usageUser(ctx), usageBacktests, 1)
+	}
+	return resp, err
+}
+
+// errUsageLimit describes a used-up daily limit
+func errUsageLimit(kind string, limit int64) error {
+	return fmt.Errorf("daily limit of %d %s reached", limit, kind)
+}
+
+// allowUsage rejects a request with 429 when the caller has used up today's
+// limit of a kind
+func (g *APIGateway) allowUsage(w http.ResponseWriter, r *http.Request, kind string) bool {
+	if !g.usage.Exceeded(usageUser(r.Context()), kind) {
+		return true
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(g.usage.resetIn().Seconds())+1))
+	http.Error(w, errUsageLimit(kind, g.usage.limits.get(kind)).Error(), http.StatusTooManyRequests)
+	return false
+}
+
+// usageHandler reports the caller's own usage and limits
+func (g *APIGateway) usageHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.usage.Report(usageUser(r.Context())))
+}
+
+// usageAllHandler reports every user's usage, for operators
+func (g *APIGateway) usageAllHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"users":  g.usage.Reports(),
+		"limits": g.usage.limits,
+	})
+}
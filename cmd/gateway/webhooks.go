@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// tradingViewSource tags signals ingested from TradingView alerts
+const tradingViewSource = "tradingview"
+
+// webhookMaxBody caps the size of an inbound webhook payload
+const webhookMaxBody = 64 << 10
+
+// tradingViewAlert is a TradingView alert message. TradingView posts the
+// alert text as written, so users template it as JSON with placeholders:
+//
+//	{"secret": "...", "ticker": "{{ticker}}", "action": "{{strategy.order.action}}",
+//	 "price": {{close}}, "interval": "{{interval}}", "time": "{{timenow}}"}
+//
+// Numbers are accepted as JSON numbers or strings, since placeholders are
+// often quoted.
+type tradingViewAlert struct {
+	Secret     string      `json:"secret"`
+	Ticker     string      `json:"ticker"`
+	Exchange   string      `json:"exchange"`
+	Action     string      `json:"action"`
+	SignalType string      `json:"signal_type"`
+	Price      json.Number `json:"price"`
+	Stoploss   json.Number `json:"stoploss"`
+	Interval   string      `json:"interval"`
+	Time       string      `json:"time"`
+	Strategy   string      `json:"strategy"`
+	Message    string      `json:"message"`
+	Priority   string      `json:"priority"`
+}
+
+// tradingViewSecret returns the shared secret alerts must carry, from
+// TRADINGVIEW_WEBHOOK_SECRET. The webhook is disabled without one.
+func tradingViewSecret() string {
+	return os.Getenv("TRADINGVIEW_WEBHOOK_SECRET")
+}
+
+// tradingViewSignalType maps an alert action to a signal type
+func tradingViewSignalType(action string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(action)) {
+	case "buy", "long":
+		return "LONG", nil
+	case "sell", "short":
+		return "SHORT", nil
+	}
+	return "", fmt.Errorf("unsupported action %q, expected buy, sell, long or short", action)
+}
+
+// tradingViewInterval converts a TradingView interval ("15", "60", "1D")
+// to its canonical name. Bare numbers are minutes.
+func tradingViewInterval(interval string) (string, error) {
+	interval = strings.TrimSpace(interval)
+	if _, err := strconv.Atoi(interval); err == nil {
+		interval += "m"
+	} else if strings.EqualFold(interval, "D") {
+		interval = "1d"
+	}
+	return market.NormalizeInterval(interval)
+}
+
+// normalize converts an alert into the signal event format published by
+// the strategy engine and scan jobs
+func (a tradingViewAlert) normalize(received time.Time) (map[string]interface{}, error) {
+	// Tickers arrive with an exchange prefix when the alert uses {{exchange}}:{{ticker}}
+	ticker := a.Ticker
+	if i := strings.LastIndex(ticker, ":"); i >= 0 {
+		ticker = ticker[i+1:]
+	}
+	ticker, err := market.ValidateTicker(ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	action := a.SignalType
+	if action == "" {
+		action = a.Action
+	}
+	signalType, err := tradingViewSignalType(action)
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := a.Price.Float64()
+	if err != nil || price <= 0 {
+		return nil, fmt.Errorf("price must be a positive number")
+	}
+
+	at := received
+	if a.Time != "" {
+		if at, err = market.ParseTimestamp(a.Time, time.UTC); err != nil {
+			return nil, err
+		}
+	}
+
+	strategy := a.Strategy
+	if strategy == "" {
+		strategy = "TradingView"
+	}
+
+	signal := map[string]interface{}{
+		"ticker":      ticker,
+		"timestamp":   market.FormatTimestamp(at),
+		"date":        at.In(market.ExchangeLocation()).Format("2006-01-02 15:04:05"),
+		"strategy":    strategy,
+		"signal_type": signalType,
+		"entry_price": price,
+		"stoploss":    nil,
+		"source":      tradingViewSource,
+	}
+	if a.Stoploss != "" {
+		stoploss, err := a.Stoploss.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("stoploss must be a number")
+		}
+		signal["stoploss"] = stoploss
+	}
+	if a.Priority != "" {
+		signal["priority"] = strings.ToLower(a.Priority)
+	}
+	if a.Interval != "" {
+		interval, err := tradingViewInterval(a.Interval)
+		if err != nil {
+			return nil, err
+		}
+		signal["interval"] = interval
+	}
+
+	metadata := map[string]interface{}{}
+	if a.Exchange != "" {
+		metadata["exchange"] = a.Exchange
+	}
+	if a.Message != "" {
+		metadata["message"] = a.Message
+	}
+	if len(metadata) > 0 {
+		signal["metadata"] = metadata
+	}
+	return signal, nil
+}
+
+// tradingViewWebhookHandler ingests a TradingView alert, verifies its shared
+// secret and publishes it as a signal on signals.{ticker}. The secret is
+// read from the payload, since TradingView cannot set headers, or from an
+// X-Webhook-Secret header for other senders.
+func (g *APIGateway) tradingViewWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	secret := tradingViewSecret()
+	if secret == "" {
+		http.Error(w, "TradingView webhook is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhookMaxBody))
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	var alert tradingViewAlert
+	if err := json.Unmarshal(body, &alert); err != nil {
+		http.Error(w, "alert message must be a JSON object", http.StatusBadRequest)
+		return
+	}
+
+	provided := alert.Secret
+	if provided == "" {
+		provided = r.Header.Get("X-Webhook-Secret")
+	}
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(secret)) != 1 {
+		utils.Info("Rejected TradingView alert with an invalid secret from %s", r.RemoteAddr)
+		http.Error(w, "invalid webhook secret", http.StatusUnauthorized)
+		return
+	}
+
+	signal, err := alert.normalize(time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ticker := signal["ticker"].(string)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	g.scoreSignals(ctx, []map[string]interface{}{signal})
+	if err := g.natsClient.PublishSignal(ctx, ticker, signal); err != nil {
+		utils.Error("Failed to publish TradingView signal for %s: %v", ticker, err)
+		http.Error(w, "failed to publish signal", http.StatusInternalServerError)
+		return
+	}
+	utils.Info("Published %s TradingView signal for %s", signal["signal_type"], ticker)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(signal)
+}
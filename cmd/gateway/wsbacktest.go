@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/jobs"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// wsBacktestProgressEvent relays a backtest job's progress to a client
+// following the job. Position is set while the job is queued.
+type wsBacktestProgressEvent struct {
+	Event    string `json:"event"`
+	Position int    `json:"position,omitempty"`
+	events.BacktestProgress
+}
+
+// followBacktest relays a backtest job's progress to a WebSocket client
+// until the job finishes, starting with its current state. Clients may only
+// follow their own jobs.
+func (g *APIGateway) followBacktest(client *wsConnection, jobID string) error {
+	job, err := g.backtestJobs.Get(jobID)
+	if err != nil || job.Owner != client.user {
+		return jobs.ErrNotFound
+	}
+	if g.natsClient == nil {
+		return fmt.Errorf("event system is unavailable")
+	}
+	subject, err := events.BacktestProgressSubject(jobID)
+	if err != nil {
+		return err
+	}
+	if _, exists := client.Subscription(subject); exists {
+		return nil
+	}
+	if client.Subscriptions() >= wsMaxSubscriptions {
+		return fmt.Errorf("subscription limit of %d subjects reached", wsMaxSubscriptions)
+	}
+
+	// Subscribe before reading the job's state so no change is missed, and
+	// hold progress until the state is queued
+	confirmed := make(chan struct{})
+	sub, err := g.natsClient.SubscribeBacktestProgress(jobID, func(progress events.BacktestProgress) {
+		<-confirmed
+		data, _ := json.Marshal(wsBacktestProgressEvent{Event: "backtest_progress", BacktestProgress: progress})
+		client.queue.Push("", data)
+		if progress.Finished() {
+			client.Unsubscribe(subject)
+		}
+	})
+	if err != nil {
+		close(confirmed)
+		return err
+	}
+	client.AddSubscription(subject, sub)
+
+	if job, err = g.backtestJobs.Get(jobID); err != nil {
+		client.Unsubscribe(subject)
+		close(confirmed)
+		return err
+	}
+	progress := backtestJobProgress(job)
+	data, _ := json.Marshal(wsBacktestProgressEvent{Event: "backtest_progress", Position: job.Position, BacktestProgress: progress})
+	client.queue.Push("", data)
+	if progress.Finished() {
+		client.Unsubscribe(subject)
+	}
+	close(confirmed)
+	return nil
+}
+
+// cancelClientBacktest cancels a WebSocket client's own backtest job
+func (g *APIGateway) cancelClientBacktest(client *wsConnection, jobID string) error {
+	job, err := g.backtestJobs.Get(jobID)
+	if err != nil || job.Owner != client.user {
+		return jobs.ErrNotFound
+	}
+	if _, err := g.cancelBacktestJob(jobID); err != nil {
+		return fmt.Errorf("cannot cancel job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// cancelBacktestJob cancels a queued or running backtest job and tells the
+// clients following it
+func (g *APIGateway) cancelBacktestJob(jobID string) (jobs.Job, error) {
+	job, err := g.backtestJobs.Cancel(jobID)
+	if err == nil {
+		g.publishBacktestProgress(events.BacktestProgress{JobID: jobID, Stage: events.BacktestCancelled, Error: job.Error})
+	}
+	return job, err
+}
+
+// publishBacktestProgress announces a backtest job's progress to the
+// clients following it
+func (g *APIGateway) publishBacktestProgress(progress events.BacktestProgress) {
+	if g.natsClient == nil {
+		return
+	}
+	if err := g.natsClient.PublishBacktestProgress(progress); err != nil {
+		utils.Debug("Failed to publish progress of backtest job %s: %v", progress.JobID, err)
+	}
+}
+
+// backtestJobProgress describes a job's state as a progress event
+func backtestJobProgress(job jobs.Job) events.BacktestProgress {
+	progress := events.BacktestProgress{JobID: job.ID, Stage: job.State, Error: job.Error, Timestamp: time.Now().UTC()}
+	if job.State == jobs.StateDone {
+		progress.Percent = 100
+	}
+	if job.Finished != nil {
+		progress.Timestamp = job.Finished.UTC()
+	}
+	return progress
+}
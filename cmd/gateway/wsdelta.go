@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/myapp/tradinglab/pkg/client"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// defaultWSDeltaSnapshotEvery is how many messages per subject a delta
+// connection receives between full snapshots
+const defaultWSDeltaSnapshotEvery = 20
+
+// wsDeltaPrefixes are the subjects delta encoding applies to: live state
+// where most fields repeat between ticks. Events such as signals are always
+// sent whole.
+var wsDeltaPrefixes = []string{"market.live.", "market.analytics.", "market.book."}
+
+// wsDeltaSnapshotEvery returns the snapshot interval for a connection that
+// asked for delta frames with ?delta=true, read from WS_DELTA_SNAPSHOT_EVERY,
+// or zero if it did not
+func wsDeltaSnapshotEvery(r *http.Request) int {
+	if enabled, _ := strconv.ParseBool(r.URL.Query().Get("delta")); !enabled {
+		return 0
+	}
+	every := defaultWSDeltaSnapshotEvery
+	if value := os.Getenv("WS_DELTA_SNAPSHOT_EVERY"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			utils.Warn("Invalid WS_DELTA_SNAPSHOT_EVERY %q, using %d", value, every)
+		} else {
+			every = n
+		}
+	}
+	return every
+}
+
+// wsDeltaState is what a client last received on a subject
+type wsDeltaState struct {
+	seq           uint64
+	sinceSnapshot int
+	fields        map[string]json.RawMessage
+}
+
+// wsDeltaEncoder turns a connection's messages into snapshots and diffs of
+// their top-level fields. It runs on the connection's sender, after the
+// slow-consumer policy, so a dropped or coalesced message never leaves the
+// client applying a diff to the wrong base. Not safe for concurrent use.
+type wsDeltaEncoder struct {
+	every    int
+	subjects map[string]*wsDeltaState
+}
+
+// newWSDeltaEncoder creates an encoder sending a snapshot every n messages
+// per subject, or nil if n is zero; a nil encoder sends messages unchanged
+func newWSDeltaEncoder(every int) *wsDeltaEncoder {
+	if every <= 0 {
+		return nil
+	}
+	return &wsDeltaEncoder{every: every, subjects: make(map[string]*wsDeltaState)}
+}
+
+// Encode returns the frame to send for a message received on subject.
+// Subscription confirmations reset the subject, so the next message after a
+// resubscribe is a snapshot.
+func (e *wsDeltaEncoder) Encode(subject string, data []byte) []byte {
+	if e == nil {
+		return data
+	}
+	if subject == "" {
+		e.observeControl(data)
+		return data
+	}
+	if !isDeltaSubject(subject) {
+		return data
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		// Only objects are diffed
+		return data
+	}
+
+	state, ok := e.subjects[subject]
+	if !ok || state.sinceSnapshot+1 >= e.every {
+		seq := uint64(1)
+		if ok {
+			seq = state.seq + 1
+		}
+		e.subjects[subject] = &wsDeltaState{seq: seq, fields: fields}
+		return e.marshal(client.DeltaFrame{Delta: client.DeltaSnapshot, Subject: subject, Seq: seq, Data: fields}, data)
+	}
+
+	frame := client.DeltaFrame{
+		Delta:   client.DeltaDiff,
+		Subject: subject,
+		Seq:     state.seq + 1,
+		Base:    state.seq,
+		Data:    make(map[string]json.RawMessage),
+	}
+	for key, value := range fields {
+		if previous, ok := state.fields[key]; !ok || !bytes.Equal(previous, value) {
+			frame.Data[key] = value
+		}
+	}
+	for key := range state.fields {
+		if _, ok := fields[key]; !ok {
+			frame.Removed = append(frame.Removed, key)
+		}
+	}
+	sort.Strings(frame.Removed)
+
+	state.seq = frame.Seq
+	state.sinceSnapshot++
+	state.fields = fields
+	return e.marshal(frame, data)
+}
+
+// observeControl clears a subject's state when the client is told it has
+// subscribed or unsubscribed
+func (e *wsDeltaEncoder) observeControl(data []byte) {
+	var event struct {
+		Event   string `json:"event"`
+		Subject string `json:"subject"`
+	}
+	if json.Unmarshal(data, &event) != nil {
+		return
+	}
+	if event.Event == "subscribed" || event.Event == "unsubscribed" {
+		delete(e.subjects, event.Subject)
+	}
+}
+
+// marshal encodes a frame, falling back to the whole message and dropping the
+// subject's state so the next message starts over from a snapshot
+func (e *wsDeltaEncoder) marshal(frame client.DeltaFrame, data []byte) []byte {
+	encoded, err := json.Marshal(frame)
+	if err != nil {
+		utils.Debug("Sending message on %s whole, cannot encode delta: %v", frame.Subject, err)
+		delete(e.subjects, frame.Subject)
+		return data
+	}
+	return encoded
+}
+
+// isDeltaSubject reports whether delta encoding applies to subject
+func isDeltaSubject(subject string) bool {
+	for _, prefix := range wsDeltaPrefixes {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
+	}
+	return false
+}
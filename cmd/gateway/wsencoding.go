@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/myapp/tradinglab/pkg/msgpack"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// WebSocket frame encodings. JSON text frames are the default; heavy
+// dashboards can ask for MessagePack binary frames, which are smaller and
+// cheaper to parse for high-frequency ticks.
+const (
+	wsEncodingJSON    = "json"
+	wsEncodingMsgPack = "msgpack"
+)
+
+// wsSubprotocols are the subprotocols the upgrader accepts. Requesting
+// "msgpack" selects MessagePack frames.
+var wsSubprotocols = []string{"websocket", wsEncodingMsgPack}
+
+// wsEncoding picks a connection's frame encoding from its negotiated
+// subprotocol, or an encoding query parameter for clients that cannot set
+// subprotocols
+func wsEncoding(r *http.Request, conn *websocket.Conn) string {
+	if conn.Subprotocol() == wsEncodingMsgPack ||
+		strings.EqualFold(r.URL.Query().Get("encoding"), wsEncodingMsgPack) {
+		return wsEncodingMsgPack
+	}
+	return wsEncodingJSON
+}
+
+// wsFrame encodes a queued JSON message for the connection's encoding,
+// returning the WebSocket message type to send it as. Messages that cannot
+// be converted are sent as JSON text.
+func wsFrame(encoding string, msg []byte) (int, []byte) {
+	if encoding != wsEncodingMsgPack {
+		return websocket.TextMessage, msg
+	}
+	frame, err := msgpack.FromJSON(msg)
+	if err != nil {
+		utils.Debug("Sending message as JSON, cannot encode as MessagePack: %v", err)
+		return websocket.TextMessage, msg
+	}
+	return websocket.BinaryMessage, frame
+}
+
+// wsRequestJSON returns a client message as JSON. MessagePack connections
+// may send requests as binary frames; other binary frames are rejected.
+func wsRequestJSON(encoding string, messageType int, p []byte) ([]byte, bool) {
+	switch {
+	case messageType == websocket.TextMessage:
+		return p, true
+	case messageType == websocket.BinaryMessage && encoding == wsEncodingMsgPack:
+		data, err := msgpack.ToJSON(p)
+		if err != nil {
+			utils.Info("Ignoring undecodable MessagePack request: %v", err)
+			return nil, false
+		}
+		return data, true
+	}
+	return nil, false
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// wsHistoricalTimeout bounds publishing a historical request for a client
+const wsHistoricalTimeout = 5 * time.Second
+
+// wsProgressEvent relays a historical request's progress to the client that
+// made the request
+type wsProgressEvent struct {
+	Event string `json:"event"`
+	events.RequestProgress
+}
+
+// historicalRequestedEvent confirms a client's historical request, naming
+// the subjects its chunks and progress arrive on
+func historicalRequestedEvent(requestID, dataSubject, progressSubject string, params market.HistoricalParams) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"event":      "historical_requested",
+		"request_id": requestID,
+		"ticker":     params.Ticker,
+		"timeframe":  params.Interval,
+		"days":       params.Days,
+		"subject":    dataSubject,
+		"progress":   progressSubject,
+	})
+	return data
+}
+
+// requestHistorical asks the market data service for history on behalf of a
+// WebSocket client and relays the request's progress to the client until it
+// is done or fails. The chunks arrive on the client's subscription to
+// dataSubject. Requests are interactive unless priority says otherwise.
+func (g *APIGateway) requestHistorical(client *wsConnection, params market.HistoricalParams, dataSubject, priority string) (string, error) {
+	if client.Subscriptions() >= wsMaxSubscriptions {
+		return "", fmt.Errorf("subscription limit of %d subjects reached", wsMaxSubscriptions)
+	}
+	if g.usage.Exceeded(client.user, usageCandles) {
+		return "", errUsageLimit(usageCandles, g.usage.limits.Candles)
+	}
+	if priority == "" {
+		priority = events.PriorityInteractive.String()
+	}
+
+	requestID := events.NewRequestID("ws")
+	progressSubject, err := events.ProgressSubject(requestID)
+	if err != nil {
+		return "", err
+	}
+
+	// Subscribe before requesting so no progress is missed, and hold
+	// progress until the confirmation is queued
+	confirmed := make(chan struct{})
+	sub, err := g.natsClient.SubscribeRequestProgress(requestID, func(progress events.RequestProgress) {
+		<-confirmed
+		// The candles are fetched once publishing starts
+		if progress.Stage == events.ProgressPublishing && progress.ChunksPublished == 0 {
+			g.usage.Add(client.user, usageCandles, int64(progress.Records))
+		}
+		data, _ := json.Marshal(wsProgressEvent{Event: "historical_progress", RequestProgress: progress})
+		client.queue.Push("", data)
+		if progress.Finished() {
+			client.Unsubscribe(progressSubject)
+		}
+	})
+	if err != nil {
+		close(confirmed)
+		return "", err
+	}
+	client.AddSubscription(progressSubject, sub)
+
+	ctx, cancel := context.WithTimeout(context.Background(), wsHistoricalTimeout)
+	defer cancel()
+	err = g.natsClient.RequestHistoricalData(ctx, params.Ticker, params.Interval, params.Days, map[string]interface{}{
+		"request_id": requestID,
+		"source":     "ws",
+		"priority":   events.ParsePriority(priority).String(),
+		"timestamp":  time.Now().UTC().Format(time.RFC3339),
+	})
+	g.recordRequest(err, false)
+	if err != nil {
+		client.Unsubscribe(progressSubject)
+		close(confirmed)
+		return "", err
+	}
+
+	client.queue.Push("", historicalRequestedEvent(requestID, dataSubject, progressSubject, params))
+	close(confirmed)
+	return requestID, nil
+}
+
+// cancelHistorical asks for a client's historical request to be cancelled
+func (g *APIGateway) cancelHistorical(requestID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), wsHistoricalTimeout)
+	defer cancel()
+	return g.natsClient.CancelRequest(ctx, requestID, "cancelled by WebSocket client")
+}
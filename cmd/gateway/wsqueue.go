@@ -0,0 +1,237 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// Policies for a WebSocket client whose send queue is full
+const (
+	wsPolicyDropOldest = "drop-oldest" // Drop the oldest queued message
+	wsPolicyCoalesce   = "coalesce"    // Replace the queued message on the same subject
+	wsPolicyDisconnect = "disconnect"  // Close the connection with a protocol error
+)
+
+// defaultWSQueueSize is the number of messages queued per client
+const defaultWSQueueSize = 250
+
+// wsQueueConfig controls per-client send queues
+type wsQueueConfig struct {
+	Policy string
+	Size   int
+}
+
+// wsQueueConfigFromEnv reads WS_SLOW_CONSUMER_POLICY and WS_QUEUE_SIZE
+func wsQueueConfigFromEnv() wsQueueConfig {
+	cfg := wsQueueConfig{Policy: wsPolicyDropOldest, Size: defaultWSQueueSize}
+
+	switch policy := strings.ToLower(os.Getenv("WS_SLOW_CONSUMER_POLICY")); policy {
+	case "":
+	case wsPolicyDropOldest, wsPolicyCoalesce, wsPolicyDisconnect:
+		cfg.Policy = policy
+	default:
+		utils.Warn("Unknown WS_SLOW_CONSUMER_POLICY %q, using %s", policy, cfg.Policy)
+	}
+
+	if size, err := strconv.Atoi(os.Getenv("WS_QUEUE_SIZE")); err == nil && size > 0 {
+		cfg.Size = size
+	}
+	return cfg
+}
+
+// wsMessage is a queued message; control messages have no subject
+type wsMessage struct {
+	subject string
+	data    []byte
+	stamps  events.Stamps // Pipeline times of the event, for delivery latency
+}
+
+// wsClientStats reports a client's queue and drop counts
+type wsClientStats struct {
+	RemoteAddr       string           `json:"remote_addr"`
+	ConnectedAt      time.Time        `json:"connected_at"`
+	Policy           string           `json:"policy"`
+	Queued           int              `json:"queued"`
+	Sent             int64            `json:"sent"`
+	Dropped          int64            `json:"dropped"`
+	Coalesced        int64            `json:"coalesced"`
+	DroppedBySubject map[string]int64 `json:"dropped_by_subject,omitempty"`
+	Evicted          bool             `json:"evicted"`
+}
+
+// wsClientQueue buffers messages for one WebSocket client and applies the
+// slow-consumer policy when the buffer is full
+type wsClientQueue struct {
+	mu      sync.Mutex
+	policy  string
+	size    int
+	items   []*wsMessage
+	latest  map[string]*wsMessage // Queued message per subject, for coalescing
+	ready   chan struct{}         // Signalled when a message is queued or the queue closes
+	evicted chan struct{}         // Closed when the disconnect policy trips
+	closed  bool
+	stats   wsClientStats
+}
+
+// newWSClientQueue creates a queue for a client
+func newWSClientQueue(cfg wsQueueConfig, remoteAddr string) *wsClientQueue {
+	return &wsClientQueue{
+		policy:  cfg.Policy,
+		size:    cfg.Size,
+		latest:  make(map[string]*wsMessage),
+		ready:   make(chan struct{}, 1),
+		evicted: make(chan struct{}),
+		stats: wsClientStats{
+			RemoteAddr:       remoteAddr,
+			ConnectedAt:      time.Now(),
+			Policy:           cfg.Policy,
+			DroppedBySubject: make(map[string]int64),
+		},
+	}
+}
+
+// Push queues a message received on subject. Control messages (empty subject)
+// are always queued; they are few and the client needs them.
+func (q *wsClientQueue) Push(subject string, data []byte) {
+	q.PushStamped(subject, data, events.Stamps{})
+}
+
+// PushStamped queues an event received on subject with its pipeline stamps
+func (q *wsClientQueue) PushStamped(subject string, data []byte, stamps events.Stamps) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed || q.stats.Evicted {
+		return
+	}
+
+	if subject != "" && len(q.items) >= q.size {
+		switch q.policy {
+		case wsPolicyCoalesce:
+			if queued, ok := q.latest[subject]; ok {
+				// Keep the queue position but deliver only the latest tick
+				queued.data = data
+				queued.stamps = stamps
+				q.stats.Coalesced++
+				return
+			}
+			q.dropOldest()
+		case wsPolicyDisconnect:
+			q.recordDrop(subject)
+			q.stats.Evicted = true
+			close(q.evicted)
+			utils.Warn("WebSocket client %s is too slow, disconnecting", q.stats.RemoteAddr)
+			return
+		default:
+			q.dropOldest()
+		}
+	}
+
+	msg := &wsMessage{subject: subject, data: data, stamps: stamps}
+	q.items = append(q.items, msg)
+	if subject != "" {
+		q.latest[subject] = msg
+	}
+	q.signal()
+}
+
+// Pop waits for the next message, the subject it arrived on and its
+// stamps; ok is false once the queue is closed
+func (q *wsClientQueue) Pop() (subject string, data []byte, stamps events.Stamps, ok bool) {
+	for {
+		q.mu.Lock()
+		if q.closed || q.stats.Evicted {
+			q.mu.Unlock()
+			return "", nil, events.Stamps{}, false
+		}
+		if len(q.items) > 0 {
+			msg := q.items[0]
+			q.items[0] = nil
+			q.items = q.items[1:]
+			if q.latest[msg.subject] == msg {
+				delete(q.latest, msg.subject)
+			}
+			q.stats.Sent++
+			q.mu.Unlock()
+			return msg.subject, msg.data, msg.stamps, true
+		}
+		q.mu.Unlock()
+		<-q.ready
+	}
+}
+
+// Evicted is closed when the client is disconnected for being too slow
+func (q *wsClientQueue) Evicted() <-chan struct{} {
+	return q.evicted
+}
+
+// Close discards queued messages and wakes the sender
+func (q *wsClientQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.items = nil
+	q.signal()
+}
+
+// Stats returns a snapshot of the client's queue counters
+func (q *wsClientQueue) Stats() wsClientStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := q.stats
+	stats.Queued = len(q.items)
+	stats.DroppedBySubject = make(map[string]int64, len(q.stats.DroppedBySubject))
+	for subject, n := range q.stats.DroppedBySubject {
+		stats.DroppedBySubject[subject] = n
+	}
+	return stats
+}
+
+// dropOldest removes the oldest subject message. Caller holds the lock.
+func (q *wsClientQueue) dropOldest() {
+	for i, msg := range q.items {
+		if msg.subject == "" {
+			continue
+		}
+		q.items = append(q.items[:i], q.items[i+1:]...)
+		if q.latest[msg.subject] == msg {
+			delete(q.latest, msg.subject)
+		}
+		q.recordDrop(msg.subject)
+		return
+	}
+}
+
+// recordDrop counts a dropped message. Caller holds the lock.
+func (q *wsClientQueue) recordDrop(subject string) {
+	if q.stats.Dropped == 0 {
+		utils.Warn("WebSocket client %s queue full, dropping messages (policy %s)", q.stats.RemoteAddr, q.policy)
+	}
+	q.stats.Dropped++
+	q.stats.DroppedBySubject[subject]++
+}
+
+// signal wakes the sender without blocking. Caller holds the lock.
+func (q *wsClientQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// wsClientStats returns queue counters for every connected client
+func (g *APIGateway) wsClientStats() []wsClientStats {
+	open := g.wsConns.Open()
+	stats := make([]wsClientStats, 0, len(open))
+	for _, c := range open {
+		stats = append(stats, c.queue.Stats())
+	}
+	return stats
+}
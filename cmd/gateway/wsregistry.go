@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// defaultWSReconcileInterval is how often the registry looks for orphans
+const defaultWSReconcileInterval = time.Minute
+
+// wsOrphanGrace is how long a closed connection may keep goroutines or
+// subscriptions before they are forced down
+const wsOrphanGrace = 30 * time.Second
+
+// WebSocket layer metrics, served at /metrics
+var (
+	wsConnectionsGauge = metrics.Default.NewGaugeVec("gateway_ws_connections",
+		"WebSocket connections by state; closing connections still hold resources", "state")
+	wsGoroutinesGauge = metrics.Default.NewGaugeVec("gateway_ws_goroutines",
+		"Goroutines running for WebSocket connections")
+	wsSubscriptionsGauge = metrics.Default.NewGaugeVec("gateway_ws_subscriptions",
+		"NATS subscriptions held for WebSocket connections")
+	wsOrphansReaped = metrics.Default.NewCounterVec("gateway_ws_orphans_reaped_total",
+		"Orphaned WebSocket resources forced down by reconciliation", "kind")
+)
+
+// wsConnection is a WebSocket client with the goroutines and NATS
+// subscriptions it owns
+type wsConnection struct {
+	id         uint64
+	conn       *websocket.Conn
+	queue      *wsClientQueue
+	encoding   string // Frame encoding negotiated at connect
+	deltaEvery int    // Messages between delta snapshots; zero sends messages whole
+	user       string // Who the connection's requests are charged to
+	registry   *wsRegistry
+	goroutines atomic.Int32
+
+	subscribing sync.Mutex // Serializes subscribing by the reader and watchlist updates
+
+	mu        sync.Mutex
+	subs      map[string]*nats.Subscription
+	watchlist *wsWatchlist // Watchlist the connection follows, if any
+	closedAt  time.Time    // Zero until the connection's handler returns
+}
+
+// Go runs fn in a goroutine counted against the connection. The last one
+// to exit after the connection closes removes it from the registry.
+func (c *wsConnection) Go(fn func()) {
+	c.goroutines.Add(1)
+	go func() {
+		defer func() {
+			if c.goroutines.Add(-1) == 0 {
+				c.registry.forgetIfDone(c)
+			}
+		}()
+		fn()
+	}()
+}
+
+// Subscription returns the subscription to subject, if any
+func (c *wsConnection) Subscription(subject string) (*nats.Subscription, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sub, ok := c.subs[subject]
+	return sub, ok
+}
+
+// Subscriptions returns the number of subscriptions held
+func (c *wsConnection) Subscriptions() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.subs)
+}
+
+// AddSubscription records a subscription owned by the connection
+func (c *wsConnection) AddSubscription(subject string, sub *nats.Subscription) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subs[subject] = sub
+}
+
+// Unsubscribe drops the subscription to subject; false if there was none
+func (c *wsConnection) Unsubscribe(subject string) bool {
+	c.mu.Lock()
+	sub, ok := c.subs[subject]
+	delete(c.subs, subject)
+	c.mu.Unlock()
+	if ok {
+		sub.Unsubscribe()
+	}
+	return ok
+}
+
+// UnsubscribeAll drops every subscription and returns how many there were
+func (c *wsConnection) UnsubscribeAll() int {
+	c.mu.Lock()
+	subs := c.subs
+	c.subs = make(map[string]*nats.Subscription)
+	c.mu.Unlock()
+
+	for subject, sub := range subs {
+		if err := sub.Unsubscribe(); err != nil && err != nats.ErrBadSubscription {
+			utils.Info("Error unsubscribing from %s: %v", subject, err)
+		}
+	}
+	return len(subs)
+}
+
+// pruneInvalid forgets subscriptions NATS has already closed, such as after
+// a slow consumer error, and returns how many there were
+func (c *wsConnection) pruneInvalid() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pruned := 0
+	for subject, sub := range c.subs {
+		if !sub.IsValid() {
+			delete(c.subs, subject)
+			pruned++
+		}
+	}
+	return pruned
+}
+
+// wsRegistry tracks WebSocket connections and what they own, and
+// periodically forces down resources left behind by abnormal closes
+type wsRegistry struct {
+	mu     sync.Mutex
+	conns  map[uint64]*wsConnection
+	nextID uint64
+}
+
+// newWSRegistry creates a registry whose counts are reported on /metrics
+func newWSRegistry() *wsRegistry {
+	r := &wsRegistry{conns: make(map[uint64]*wsConnection)}
+	metrics.Default.OnScrape(r.updateMetrics)
+	return r
+}
+
+// Register starts tracking a connection
+func (r *wsRegistry) Register(conn *websocket.Conn, queue *wsClientQueue, encoding string, deltaEvery int, user string) *wsConnection {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	c := &wsConnection{
+		id:         r.nextID,
+		conn:       conn,
+		queue:      queue,
+		encoding:   encoding,
+		deltaEvery: deltaEvery,
+		user:       user,
+		registry:   r,
+		subs:       make(map[string]*nats.Subscription),
+	}
+	r.conns[c.id] = c
+	return c
+}
+
+// Release marks a connection's handler as finished. It is forgotten once
+// its goroutines have exited and subscriptions are gone; reconciliation
+// forces them down if they linger.
+func (r *wsRegistry) Release(c *wsConnection) {
+	c.mu.Lock()
+	c.closedAt = time.Now()
+	c.mu.Unlock()
+	r.forgetIfDone(c)
+}
+
+// forgetIfDone removes a closed connection that holds nothing
+func (r *wsRegistry) forgetIfDone(c *wsConnection) bool {
+	c.mu.Lock()
+	done := !c.closedAt.IsZero() && len(c.subs) == 0 && c.goroutines.Load() == 0
+	c.mu.Unlock()
+	if done {
+		r.mu.Lock()
+		delete(r.conns, c.id)
+		r.mu.Unlock()
+	}
+	return done
+}
+
+// Open returns the connections whose handlers are still running
+func (r *wsRegistry) Open() []*wsConnection {
+	var open []*wsConnection
+	for _, c := range r.snapshot() {
+		c.mu.Lock()
+		closed := !c.closedAt.IsZero()
+		c.mu.Unlock()
+		if !closed {
+			open = append(open, c)
+		}
+	}
+	return open
+}
+
+// snapshot returns every tracked connection
+func (r *wsRegistry) snapshot() []*wsConnection {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conns := make([]*wsConnection, 0, len(r.conns))
+	for _, c := range r.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// Reconcile prunes subscriptions NATS has closed and forces down connections
+// that closed over grace ago but still hold goroutines or subscriptions.
+// Closing the socket and queue unblocks the reader and sender goroutines.
+func (r *wsRegistry) Reconcile(grace time.Duration) {
+	for _, c := range r.snapshot() {
+		if n := c.pruneInvalid(); n > 0 {
+			wsOrphansReaped.With("subscription").Add(float64(n))
+		}
+		if r.forgetIfDone(c) {
+			continue
+		}
+
+		c.mu.Lock()
+		closedAt := c.closedAt
+		c.mu.Unlock()
+		if closedAt.IsZero() || time.Since(closedAt) < grace {
+			continue
+		}
+
+		subs := c.UnsubscribeAll()
+		goroutines := c.goroutines.Load()
+		utils.Warn("Forcing down orphaned WebSocket connection %d from %s: %d subscriptions, %d goroutines",
+			c.id, c.queue.Stats().RemoteAddr, subs, goroutines)
+		c.queue.Close()
+		c.conn.Close()
+		wsOrphansReaped.With("connection").Inc()
+		wsOrphansReaped.With("subscription").Add(float64(subs))
+
+		// Goroutines blocked in the socket exit once it is closed; forget the
+		// connection now so a goroutine stuck elsewhere is not reported forever
+		r.mu.Lock()
+		delete(r.conns, c.id)
+		r.mu.Unlock()
+	}
+}
+
+// Start reconciles every WS_RECONCILE_INTERVAL until ctx is cancelled
+func (r *wsRegistry) Start(ctx context.Context) {
+	interval := defaultWSReconcileInterval
+	if value := os.Getenv("WS_RECONCILE_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			utils.Warn("Invalid WS_RECONCILE_INTERVAL %q, using %v", value, interval)
+		} else {
+			interval = d
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Reconcile(wsOrphanGrace)
+			}
+		}
+	}()
+}
+
+// CloseAll closes every connection, for shutdown
+func (r *wsRegistry) CloseAll(message []byte) {
+	for _, c := range r.snapshot() {
+		c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+		c.conn.Close()
+	}
+}
+
+// updateMetrics refreshes the WebSocket gauges before a scrape
+func (r *wsRegistry) updateMetrics() {
+	var open, closing, goroutines, subs int
+	for _, c := range r.snapshot() {
+		c.mu.Lock()
+		if c.closedAt.IsZero() {
+			open++
+		} else {
+			closing++
+		}
+		subs += len(c.subs)
+		c.mu.Unlock()
+		goroutines += int(c.goroutines.Load())
+	}
+	wsConnectionsGauge.With("open").Set(float64(open))
+	wsConnectionsGauge.With("closing").Set(float64(closing))
+	wsGoroutinesGauge.With().Set(float64(goroutines))
+	wsSubscriptionsGauge.With().Set(float64(subs))
+}
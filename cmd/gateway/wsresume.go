@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// resumableSubjectPrefixes are the durable event subjects a reconnecting
+// WebSocket client can resume. Market ticks are not replayed; a fresh tick
+// supersedes the missed ones.
+var resumableSubjectPrefixes = []string{"signals.", "recommendations."}
+
+// isResumable reports whether messages on subject carry a stream sequence
+// and can be replayed after a reconnect
+func isResumable(subject string) bool {
+	for _, prefix := range resumableSubjectPrefixes {
+		if strings.HasPrefix(subject, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// withStreamSeq adds the stream sequence to a JSON object payload as
+// "stream_seq". Clients keep the last one per subject and present it when
+// resuming. Other payloads are returned unchanged.
+func withStreamSeq(data []byte, seq uint64) []byte {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return data
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return data
+	}
+	fields["stream_seq"], _ = json.Marshal(seq)
+	tagged, err := json.Marshal(fields)
+	if err != nil {
+		return data
+	}
+	return tagged
+}
+
+// subscribedEvent is the confirmation sent when a subscription starts.
+// Resumed subscriptions report where they resumed and whether events were
+// lost because they aged out of the stream.
+func subscribedEvent(subject string, resumedFrom uint64, gap bool) []byte {
+	event := map[string]interface{}{
+		"event":   "subscribed",
+		"subject": subject,
+	}
+	if resumedFrom > 0 {
+		event["resumed_from"] = resumedFrom
+		event["gap"] = gap
+	}
+	data, _ := json.Marshal(event)
+	return data
+}
+
+// subscribeResumable forwards a durable subject to a client, first replaying
+// anything stored after the sequence the client last received. The
+// confirmation is queued before any replayed event.
+func (g *APIGateway) subscribeResumable(subject string, after uint64, queue *wsClientQueue) (*nats.Subscription, error) {
+	var gap bool
+	if after > 0 {
+		first, err := g.natsClient.FirstSequence(subject)
+		if err != nil {
+			utils.Warn("Cannot check replay window for %s: %v", subject, err)
+		} else {
+			gap = first > after+1
+		}
+	}
+
+	// Hold deliveries until the confirmation is queued
+	confirmed := make(chan struct{})
+	defer close(confirmed)
+
+	// Replayed events were sent before the client subscribed; their age is
+	// not delivery latency
+	subscribed := time.Now()
+	sub, err := g.natsClient.SubscribeReplay(subject, after, func(data []byte, seq uint64, stamps events.Stamps) {
+		<-confirmed
+		if stamps.Sent().Before(subscribed) {
+			stamps = events.Stamps{}
+		}
+		queue.PushStamped(subject, withStreamSeq(data, seq), stamps)
+	})
+	if err != nil {
+		return nil, err
+	}
+	queue.Push("", subscribedEvent(subject, after, gap))
+	return sub, nil
+}
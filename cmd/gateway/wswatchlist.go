@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/myapp/tradinglab/pkg/alerts"
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// wsWatchlist is the alert subscriber whose watchlist a WebSocket
+// connection follows
+type wsWatchlist struct {
+	subscriberID string
+	subjects     map[string]bool // Subscribed for the watchlist rather than by the client
+}
+
+// wsWatchlistEvent tells a client which tickers its watchlist stream covers,
+// sent when it starts following one and whenever the watchlist changes
+type wsWatchlistEvent struct {
+	Event      string   `json:"event"` // "watchlist"
+	Subscriber string   `json:"subscriber"`
+	Tickers    []string `json:"tickers"` // Empty covers every ticker
+	Added      []string `json:"added"`   // Subjects subscribed by this change
+	Removed    []string `json:"removed"` // Subjects dropped by this change
+	Deleted    bool     `json:"deleted,omitempty"`
+}
+
+// watchlistSubjects returns the subjects a watchlist stream covers: live
+// data, signals and the subscriber's alerts for each watched ticker
+func watchlistSubjects(sub alerts.Subscriber) map[string]bool {
+	tickers := sub.Watchlist
+	if len(tickers) == 0 {
+		tickers = []string{"*"}
+	}
+	subjects := make(map[string]bool, 3*len(tickers))
+	for _, ticker := range tickers {
+		alertSubject, err := events.AlertSubject(sub.ID, ticker)
+		if err != nil {
+			utils.Debug("Skipping watchlist ticker %s of %s: %v", ticker, sub.ID, err)
+			continue
+		}
+		subjects[fmt.Sprintf(events.SubjectMarketLiveTicker, ticker)] = true
+		subjects[fmt.Sprintf(events.SubjectSignalsTicker, ticker)] = true
+		subjects[alertSubject] = true
+	}
+	return subjects
+}
+
+// followWatchlist subscribes a client to the watchlist of the alert
+// subscriber with the given ID or email address, defaulting to the
+// connection's user. A connection follows one watchlist at a time.
+func (g *APIGateway) followWatchlist(client *wsConnection, key string) error {
+	if key == "" {
+		key = client.user
+	}
+	sub, ok := g.subscribers.Lookup(key)
+	if !ok {
+		return fmt.Errorf("no watchlist for %q, subscribe to alerts first", key)
+	}
+
+	client.subscribing.Lock()
+	defer client.subscribing.Unlock()
+	client.mu.Lock()
+	previous := client.watchlist
+	client.mu.Unlock()
+	if previous != nil && previous.subscriberID != sub.ID {
+		g.syncWatchlist(client, alerts.Subscriber{ID: previous.subscriberID}, false)
+	}
+	g.syncWatchlist(client, sub, true)
+	return nil
+}
+
+// unfollowWatchlist drops a client's watchlist stream, keeping subjects
+// the client subscribed to itself
+func (g *APIGateway) unfollowWatchlist(client *wsConnection) {
+	client.subscribing.Lock()
+	defer client.subscribing.Unlock()
+	client.mu.Lock()
+	current := client.watchlist
+	client.mu.Unlock()
+	if current != nil {
+		g.syncWatchlist(client, alerts.Subscriber{ID: current.subscriberID}, false)
+	}
+}
+
+// refreshWatchlists brings the streams of clients following a subscriber's
+// watchlist up to date after it is saved or deleted
+func (g *APIGateway) refreshWatchlists(subscriberID string) {
+	sub, ok := g.subscribers.Get(subscriberID)
+	for _, client := range g.wsConns.Open() {
+		client.subscribing.Lock()
+		client.mu.Lock()
+		following := client.watchlist != nil && client.watchlist.subscriberID == subscriberID
+		client.mu.Unlock()
+		if following && ok {
+			g.syncWatchlist(client, sub, true)
+		} else if following {
+			g.syncWatchlist(client, alerts.Subscriber{ID: subscriberID}, false)
+		}
+		client.subscribing.Unlock()
+	}
+}
+
+// syncWatchlist subscribes a client to the subjects of a watchlist it
+// follows, or unsubscribes it from all of them when follow is false, and
+// tells the client what changed. The caller holds client.subscribing.
+func (g *APIGateway) syncWatchlist(client *wsConnection, sub alerts.Subscriber, follow bool) {
+	want := map[string]bool{}
+	if follow {
+		want = watchlistSubjects(sub)
+	}
+
+	client.mu.Lock()
+	owned := map[string]bool{}
+	if client.watchlist != nil && client.watchlist.subscriberID == sub.ID {
+		owned = client.watchlist.subjects
+	}
+	client.mu.Unlock()
+
+	_, stored := g.subscribers.Get(sub.ID)
+	event := wsWatchlistEvent{
+		Event:      "watchlist",
+		Subscriber: sub.ID,
+		Tickers:    sub.Watchlist,
+		Added:      []string{},
+		Removed:    []string{},
+		Deleted:    !stored,
+	}
+	if event.Tickers == nil {
+		event.Tickers = []string{}
+	}
+
+	next := make(map[string]bool, len(want))
+	for subject := range owned {
+		if want[subject] {
+			next[subject] = true
+		} else if client.Unsubscribe(subject) {
+			event.Removed = append(event.Removed, subject)
+		}
+	}
+	for subject := range want {
+		if owned[subject] {
+			continue
+		}
+		// Subjects the client already follows stay the client's
+		if _, exists := client.Subscription(subject); exists {
+			continue
+		}
+		if g.wsSubscribe(client, subject, 0) {
+			next[subject] = true
+			event.Added = append(event.Added, subject)
+		}
+	}
+	sort.Strings(event.Added)
+	sort.Strings(event.Removed)
+
+	client.mu.Lock()
+	if follow {
+		client.watchlist = &wsWatchlist{subscriberID: sub.ID, subjects: next}
+	} else {
+		client.watchlist = nil
+	}
+	client.mu.Unlock()
+
+	data, _ := json.Marshal(event)
+	client.queue.Push("", data)
+}
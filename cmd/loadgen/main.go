@@ -0,0 +1,334 @@
+// cmd/loadgen/main.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// tick is a synthetic live bar carrying its send time, so the receiver can
+// measure delivery latency
+type tick struct {
+	Ticker    string    `json:"ticker"`
+	Timestamp time.Time `json:"timestamp"`
+	Price     float64   `json:"price"`
+	Open      float64   `json:"open"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Close     float64   `json:"close"`
+	Volume    int64     `json:"volume"`
+	DataType  string    `json:"data_type"`
+	Source    string    `json:"source"`
+	Seq       int64     `json:"loadgen_seq"`
+	SentNanos int64     `json:"loadgen_sent_ns"`
+}
+
+// config holds the load test settings
+type config struct {
+	natsURL  string
+	wsURL    string
+	tickers  []string
+	rate     float64 // Ticks per second per ticker
+	duration time.Duration
+	drain    time.Duration // How long to wait for in-flight ticks after publishing stops
+	seed     int64         // Seeds each ticker's price path; LOADGEN_SEED repeats a run
+}
+
+// configFromEnv reads the load test settings from the environment
+func configFromEnv() (config, error) {
+	cfg := config{
+		natsURL:  os.Getenv("NATS_URL"),
+		wsURL:    os.Getenv("GATEWAY_WS_URL"),
+		rate:     10,
+		duration: 30 * time.Second,
+		drain:    5 * time.Second,
+		seed:     time.Now().UnixNano(),
+	}
+	if cfg.natsURL == "" {
+		cfg.natsURL = "nats://localhost:4222"
+	}
+
+	// LOADGEN_TICKERS is either a list of symbols or a count of generated ones
+	value := os.Getenv("LOADGEN_TICKERS")
+	if value == "" {
+		value = "10"
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		if n <= 0 {
+			return cfg, fmt.Errorf("LOADGEN_TICKERS must be positive")
+		}
+		for i := 1; i <= n; i++ {
+			cfg.tickers = append(cfg.tickers, fmt.Sprintf("LOAD%d", i))
+		}
+	} else {
+		for _, ticker := range strings.Split(value, ",") {
+			if ticker = strings.ToUpper(strings.TrimSpace(ticker)); ticker != "" {
+				cfg.tickers = append(cfg.tickers, ticker)
+			}
+		}
+	}
+
+	if value := os.Getenv("LOADGEN_RATE"); value != "" {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			return cfg, fmt.Errorf("invalid LOADGEN_RATE %q", value)
+		}
+		cfg.rate = rate
+	}
+	for name, target := range map[string]*time.Duration{"LOADGEN_DURATION": &cfg.duration, "LOADGEN_DRAIN": &cfg.drain} {
+		if value := os.Getenv(name); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return cfg, fmt.Errorf("invalid %s %q", name, value)
+			}
+			*target = d
+		}
+	}
+	if value := os.Getenv("LOADGEN_SEED"); value != "" {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid LOADGEN_SEED %q", value)
+		}
+		cfg.seed = seed
+	}
+	return cfg, nil
+}
+
+// recorder collects delivery latencies
+type recorder struct {
+	mu        sync.Mutex
+	latencies []time.Duration
+	seen      map[string]int64 // Highest sequence received per ticker
+	reordered int64
+}
+
+// record stores a received tick
+func (r *recorder) record(t tick, received time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latencies = append(r.latencies, received.Sub(time.Unix(0, t.SentNanos)))
+	if t.Seq < r.seen[t.Ticker] {
+		r.reordered++
+	} else {
+		r.seen[t.Ticker] = t.Seq
+	}
+}
+
+// percentile returns the p-th percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p / 100 * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+func main() {
+	cfg, err := configFromEnv()
+	if err != nil {
+		utils.Fatal("Invalid load test configuration: %v", err)
+	}
+
+	client, err := events.NewEventClient(cfg.natsURL)
+	if err != nil {
+		utils.Fatal("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		utils.Info("Received signal: %v", sig)
+		cancel()
+	}()
+
+	// Measure delivery through the gateway websocket when one is configured
+	rec := &recorder{seen: make(map[string]int64)}
+	var received atomic.Int64
+	if cfg.wsURL != "" {
+		conn, err := subscribeGateway(cfg.wsURL, cfg.tickers)
+		if err != nil {
+			utils.Fatal("Failed to subscribe through the gateway: %v", err)
+		}
+		defer conn.Close()
+		go readTicks(conn, rec, &received)
+	} else {
+		utils.Info("GATEWAY_WS_URL not set, measuring publish throughput only")
+	}
+
+	utils.Info("Publishing %.1f ticks/s for each of %d tickers for %v, seed %d", cfg.rate, len(cfg.tickers), cfg.duration, cfg.seed)
+
+	var sent, failed atomic.Int64
+	runCtx, stop := context.WithTimeout(ctx, cfg.duration)
+	defer stop()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i, ticker := range cfg.tickers {
+		wg.Add(1)
+		rng := rand.New(rand.NewSource(cfg.seed + int64(i)))
+		go func(ticker string) {
+			defer wg.Done()
+			publishTicks(runCtx, client, ticker, cfg.rate, rng, &sent, &failed)
+		}(ticker)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// Give in-flight ticks time to arrive
+	if cfg.wsURL != "" && ctx.Err() == nil {
+		deadline := time.After(cfg.drain)
+	wait:
+		for received.Load() < sent.Load() {
+			select {
+			case <-ctx.Done():
+				break wait
+			case <-deadline:
+				break wait
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}
+
+	report(cfg, rec, sent.Load(), failed.Load(), received.Load(), elapsed)
+}
+
+// publishTicks publishes synthetic ticks for a ticker at rate per second
+// until ctx ends, walking the price with rng
+func publishTicks(ctx context.Context, client *events.EventClient, ticker string, rate float64, rng *rand.Rand, sent, failed *atomic.Int64) {
+	interval := time.Duration(float64(time.Second) / rate)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	price := 100 + rng.Float64()*100
+	var seq int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			seq++
+			price += (rng.Float64() - 0.5) * 0.1
+			data := tick{
+				Ticker:    ticker,
+				Timestamp: now,
+				Price:     price,
+				Open:      price,
+				High:      price + 0.05,
+				Low:       price - 0.05,
+				Close:     price,
+				Volume:    100,
+				DataType:  "live",
+				Source:    "loadgen",
+				Seq:       seq,
+				SentNanos: time.Now().UnixNano(),
+			}
+			if err := client.PublishMarketLiveData(ctx, ticker, data); err != nil {
+				if ctx.Err() == nil {
+					failed.Add(1)
+					utils.Debug("Publish failed for %s: %v", ticker, err)
+				}
+				continue
+			}
+			sent.Add(1)
+		}
+	}
+}
+
+// subscribeGateway opens a websocket to the gateway and subscribes to each ticker
+func subscribeGateway(url string, tickers []string) (*websocket.Conn, error) {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ticker := range tickers {
+		request := map[string]string{"action": "subscribe", "type": "market", "ticker": ticker}
+		if err := conn.WriteJSON(request); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	// Wait for every confirmation so no tick is published before its subscription
+	confirmed := 0
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for confirmed < len(tickers) {
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("waiting for subscription confirmations: %w", err)
+		}
+		if msg["event"] == "subscribed" {
+			confirmed++
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+	return conn, nil
+}
+
+// readTicks records the latency of each tick delivered over the websocket
+func readTicks(conn *websocket.Conn, rec *recorder, received *atomic.Int64) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		now := time.Now()
+
+		var t tick
+		if err := json.Unmarshal(data, &t); err != nil || t.SentNanos == 0 {
+			continue
+		}
+		rec.record(t, now)
+		received.Add(1)
+	}
+}
+
+// report logs throughput and latency figures
+func report(cfg config, rec *recorder, sent, failed, received int64, elapsed time.Duration) {
+	utils.Info("Published %d ticks in %v (%.0f/s), %d failed", sent, elapsed.Round(time.Millisecond),
+		float64(sent)/elapsed.Seconds(), failed)
+	if cfg.wsURL == "" {
+		return
+	}
+
+	rec.mu.Lock()
+	latencies := append([]time.Duration(nil), rec.latencies...)
+	reordered := rec.reordered
+	rec.mu.Unlock()
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	lost := sent - received
+	if lost < 0 {
+		lost = 0
+	}
+	var lossPct float64
+	if sent > 0 {
+		lossPct = float64(lost) / float64(sent) * 100
+	}
+	utils.Info("Delivered %d ticks over the websocket, %d lost (%.2f%%), %d out of order", received, lost, lossPct, reordered)
+	if len(latencies) > 0 {
+		utils.Info("Latency p50 %v, p90 %v, p99 %v, max %v",
+			percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99), latencies[len(latencies)-1])
+	}
+}
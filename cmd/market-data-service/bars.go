@@ -0,0 +1,184 @@
+// cmd/market-data-service/bars.go
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/scheduler"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// liveBarIntervals returns the intervals of consolidated bars to publish for
+// a ticker, from LIVE_BAR_INTERVALS_<TICKER> or else LIVE_BAR_INTERVALS (e.g.
+// "1min,5min,15min"). Empty means only raw snapshots are published.
+func liveBarIntervals(ticker string) []string {
+	key := strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(ticker))
+
+	value, ok := os.LookupEnv("LIVE_BAR_INTERVALS_" + key)
+	if !ok {
+		value = os.Getenv("LIVE_BAR_INTERVALS")
+	}
+
+	var intervals []string
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		interval, err := market.NormalizeInterval(entry)
+		if err != nil || interval == market.Interval1Day {
+			utils.Warn("Ignoring live bar interval %q for %s", entry, ticker)
+			continue
+		}
+		if !seen[interval] {
+			seen[interval] = true
+			intervals = append(intervals, interval)
+		}
+	}
+	return intervals
+}
+
+// barBuilder accumulates polled snapshots into bars of one interval
+type barBuilder struct {
+	interval string
+	period   time.Duration
+	bar      *market.MarketData // In progress; nil until the first snapshot
+	end      time.Time          // When the bar in progress closes
+
+	// The provider reports the latest minute bar on every poll; its volume is
+	// counted once however many polls see it
+	minute market.MarketData
+}
+
+// add folds a snapshot into the bar in progress, returning the previous bar
+// if the snapshot starts a new one
+func (b *barBuilder) add(data *market.MarketData, at time.Time) *market.MarketData {
+	var completed *market.MarketData
+	if b.bar != nil && !at.Before(b.end) {
+		completed = b.flush()
+	}
+
+	price := data.Price
+	if price <= 0 {
+		price = data.Close
+	}
+	if price <= 0 {
+		return completed
+	}
+
+	newMinute := data.Open != b.minute.Open || data.High != b.minute.High || data.Low != b.minute.Low ||
+		data.Close != b.minute.Close || data.Volume != b.minute.Volume
+	if newMinute {
+		b.minute = *data
+	}
+
+	if b.bar == nil {
+		b.end = scheduler.NextBoundary(at, b.period, 0, clk.Market())
+		b.bar = &market.MarketData{
+			Ticker:    data.Ticker,
+			Timestamp: b.end.Add(-b.period),
+			Open:      price,
+			High:      price,
+			Low:       price,
+			Interval:  b.interval,
+			Source:    data.Source,
+			DataType:  "bar",
+		}
+	}
+
+	bar := b.bar
+	bar.Price, bar.Close = price, price
+	if price > bar.High {
+		bar.High = price
+	}
+	if price < bar.Low {
+		bar.Low = price
+	}
+	if newMinute {
+		bar.Volume += data.Volume
+	}
+	return completed
+}
+
+// flush returns the bar in progress, if any, and starts over
+func (b *barBuilder) flush() *market.MarketData {
+	bar := b.bar
+	b.bar = nil
+	return bar
+}
+
+// barConsolidator builds consolidated live bars for each ticker
+type barConsolidator struct {
+	mu       sync.Mutex
+	builders map[string][]*barBuilder // By ticker; empty when bars are off for it
+}
+
+// newBarConsolidator creates an empty consolidator
+func newBarConsolidator() *barConsolidator {
+	return &barConsolidator{builders: make(map[string][]*barBuilder)}
+}
+
+// tickerBuilders returns the builders for a ticker, creating them from the
+// configuration on first use. Caller holds the lock.
+func (c *barConsolidator) tickerBuilders(ticker string) []*barBuilder {
+	builders, ok := c.builders[ticker]
+	if ok {
+		return builders
+	}
+	for _, interval := range liveBarIntervals(ticker) {
+		period, err := market.IntervalDuration(interval)
+		if err != nil {
+			continue
+		}
+		builders = append(builders, &barBuilder{interval: interval, period: period})
+	}
+	if len(builders) > 0 {
+		utils.Info("Consolidating live bars for %s at %v", ticker, liveBarIntervals(ticker))
+	}
+	c.builders[ticker] = builders
+	return builders
+}
+
+// Add folds a live snapshot into every configured interval and returns the
+// bars it completed
+func (c *barConsolidator) Add(ticker string, data *market.MarketData) []*market.MarketData {
+	at := data.Timestamp
+	if at.IsZero() {
+		at = clk.Now()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var completed []*market.MarketData
+	for _, b := range c.tickerBuilders(ticker) {
+		if bar := b.add(data, at); bar != nil {
+			completed = append(completed, bar)
+		}
+	}
+	return completed
+}
+
+// Flush returns the bars in progress for a ticker, used when the session
+// ends and no later snapshot will close them
+func (c *barConsolidator) Flush(ticker string) []*market.MarketData {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var completed []*market.MarketData
+	for _, b := range c.builders[ticker] {
+		if bar := b.flush(); bar != nil {
+			completed = append(completed, bar)
+		}
+	}
+	return completed
+}
@@ -0,0 +1,120 @@
+// cmd/market-data-service/clockskew.go
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/clock"
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// marketClockPeer names Alpaca's market clock among measured clocks
+const marketClockPeer = "alpaca"
+
+// defaultClockCheckInterval is how often the local clock is measured
+// against the market clock
+const defaultClockCheckInterval = 5 * time.Minute
+
+// Clock skew metrics, served at /metrics
+var (
+	clockOffset = metrics.Default.NewGaugeVec("marketdata_clock_offset_seconds",
+		"Measured offset of a reference clock ahead of the local clock", "reference")
+	clockCorrection = metrics.Default.NewGaugeVec("marketdata_clock_correction_seconds",
+		"Correction applied to the local clock for market time and bar alignment")
+)
+
+// skew tracks the local clock against the market clock
+var skew = clock.NewSkewDetector(clock.SkewThreshold())
+
+// ClockSkewStatus reports the local clock against the market clock
+type ClockSkewStatus struct {
+	clock.PeerOffset
+	CorrectionMS float64 `json:"correction_ms"` // Applied to market time and bar alignment
+}
+
+// clockSkewStatus returns the latest market clock measurement, or nil if
+// none has been made
+func clockSkewStatus() *ClockSkewStatus {
+	offset, ok := skew.Peer(marketClockPeer)
+	if !ok {
+		return nil
+	}
+	return &ClockSkewStatus{
+		PeerOffset:   offset,
+		CorrectionMS: float64(clk.Offset()) / float64(time.Millisecond),
+	}
+}
+
+// monitorClockSkew measures the local clock against Alpaca's market clock
+// every CLOCK_CHECK_INTERVAL (default 5m; "off" disables it). Market hours and
+// bar boundaries are worked out from the local clock, so when it is skewed
+// beyond CLOCK_SKEW_THRESHOLD the service warns and corrects its clock by the
+// measured offset, unless CLOCK_SKEW_COMPENSATE=false. The correction is
+// removed once the clocks agree again.
+func monitorClockSkew(ctx context.Context) {
+	interval := defaultClockCheckInterval
+	switch value := os.Getenv("CLOCK_CHECK_INTERVAL"); value {
+	case "":
+	case "off":
+		return
+	default:
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			utils.Warn("Invalid CLOCK_CHECK_INTERVAL %q, checking every %v", value, interval)
+		} else {
+			interval = d
+		}
+	}
+	compensate := os.Getenv("CLOCK_SKEW_COMPENSATE") != "false"
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			checkMarketClock(ctx, compensate)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	utils.Info("Checking the local clock against the market clock every %v", interval)
+}
+
+// checkMarketClock measures the local clock against the market clock once
+// and applies or removes the correction
+func checkMarketClock(ctx context.Context, compensate bool) {
+	sent := time.Now()
+	marketClock, err := marketProvider.Clock(ctx)
+	received := time.Now()
+	observeProvider("alpaca", "market_clock", sent, err)
+	if err != nil {
+		utils.Debug("Cannot check clock skew: %v", err)
+		return
+	}
+	if marketClock.Timestamp.IsZero() {
+		return
+	}
+
+	offset, uncertainty := clock.EstimateOffset(sent, marketClock.Timestamp, received)
+	skewed := skew.Record(marketClockPeer, offset, uncertainty)
+	clockOffset.With(marketClockPeer).Set(offset.Seconds())
+
+	correction := time.Duration(0)
+	if skewed && compensate {
+		correction = offset
+	}
+	if (correction != 0) != (clk.Offset() != 0) {
+		if correction != 0 {
+			utils.Warn("Correcting the local clock by %v to follow the market clock", correction.Round(time.Millisecond))
+		} else {
+			utils.Info("Removing the local clock correction")
+		}
+	}
+	clk.SetOffset(correction)
+	clockCorrection.With().Set(correction.Seconds())
+}
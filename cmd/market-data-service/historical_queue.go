@@ -0,0 +1,163 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// defaultHistoricalWorkers is the number of historical requests served at once
+const defaultHistoricalWorkers = 2
+
+// errRequestCancelled is the cause of a served request's context being
+// cancelled at the requester's request, as opposed to at shutdown
+var errRequestCancelled = errors.New("request cancelled")
+
+// historicalJob is a queued historical data request
+type historicalJob struct {
+	ticker    string
+	timeframe string
+	days      int
+	priority  events.Priority
+	requestID string    // Progress is published under it; empty if the requester sent none
+	asOf      time.Time // Serve bars as known then from the bar history; zero fetches them now
+	queuedAt  time.Time
+	seq       uint64 // Arrival order, so equal priorities are served first come first served
+}
+
+// historicalJobHeap orders jobs by priority, then arrival
+type historicalJobHeap []*historicalJob
+
+func (h historicalJobHeap) Len() int { return len(h) }
+func (h historicalJobHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h historicalJobHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *historicalJobHeap) Push(x interface{}) { *h = append(*h, x.(*historicalJob)) }
+func (h *historicalJobHeap) Pop() interface{} {
+	old := *h
+	job := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return job
+}
+
+// historicalQueue is a worker pool that serves historical requests highest
+// priority first, so interactive chart loads are not stuck behind backfills
+type historicalQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	jobs    historicalJobHeap
+	seq     uint64
+	running map[string]context.CancelCauseFunc // By request ID, for cancellation
+	serve   func(context.Context, *historicalJob)
+}
+
+// newHistoricalQueue creates a queue whose workers call serve
+func newHistoricalQueue(serve func(context.Context, *historicalJob)) *historicalQueue {
+	q := &historicalQueue{serve: serve, running: make(map[string]context.CancelCauseFunc)}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Start runs workers until ctx is cancelled
+func (q *historicalQueue) Start(ctx context.Context, workers int) {
+	go func() {
+		<-ctx.Done()
+		q.mu.Lock()
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				job, ok := q.next(ctx)
+				if !ok {
+					return
+				}
+				q.run(ctx, job)
+			}
+		}()
+	}
+}
+
+// Add queues a job
+func (q *historicalQueue) Add(job *historicalJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.seq++
+	job.seq = q.seq
+	job.queuedAt = time.Now()
+	heap.Push(&q.jobs, job)
+	historicalQueueDepth.With().Set(float64(len(q.jobs)))
+	q.cond.Signal()
+}
+
+// next waits for the highest priority job; ok is false once ctx is done
+func (q *historicalQueue) next(ctx context.Context) (*historicalJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.jobs) == 0 && ctx.Err() == nil {
+		q.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	job := heap.Pop(&q.jobs).(*historicalJob)
+	historicalQueueDepth.With().Set(float64(len(q.jobs)))
+	return job, true
+}
+
+// run serves a job under a context that Cancel can cancel
+func (q *historicalQueue) run(ctx context.Context, job *historicalJob) {
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if job.requestID != "" {
+		q.mu.Lock()
+		q.running[job.requestID] = cancel
+		q.mu.Unlock()
+		defer func() {
+			q.mu.Lock()
+			delete(q.running, job.requestID)
+			q.mu.Unlock()
+		}()
+	}
+	q.serve(jobCtx, job)
+}
+
+// Cancel stops a request. A queued request is removed and returned so the
+// caller can report it; a request being served has its context cancelled
+// and stops at its next step. found is false if the queue does not hold the
+// request.
+func (q *historicalQueue) Cancel(requestID string) (queued *historicalJob, found bool) {
+	if requestID == "" {
+		return nil, false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if cancel, ok := q.running[requestID]; ok {
+		cancel(errRequestCancelled)
+		return nil, true
+	}
+	for i, job := range q.jobs {
+		if job.requestID == requestID {
+			heap.Remove(&q.jobs, i)
+			historicalQueueDepth.With().Set(float64(len(q.jobs)))
+			return job, true
+		}
+	}
+	return nil, false
+}
+
+// cancelled reports whether a served request's context was cancelled by Cancel
+func cancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errRequestCancelled)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/scheduler"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// defaultSyncDays is the lookback for a ticker's first incremental sync
+const defaultSyncDays = 30
+
+// startHistoricalSync runs incremental sync for HISTORICAL_SYNC_INTERVALS
+// (e.g. "15min,1day"), once at startup and then at each bar boundary of the
+// interval. Each sync publishes only the bars since the cursor stored in the
+// HISTORICAL_SYNC bucket, so consumers can keep history current without
+// requesting overlapping N-day windows. The first sync of a ticker reaches
+// back HISTORICAL_SYNC_DAYS.
+func startHistoricalSync(ctx context.Context, tickers []string) {
+	value := os.Getenv("HISTORICAL_SYNC_INTERVALS")
+	if value == "" {
+		return
+	}
+
+	days := defaultSyncDays
+	if n, err := strconv.Atoi(os.Getenv("HISTORICAL_SYNC_DAYS")); err == nil && n > 0 && n <= market.MaxHistoricalDays {
+		days = n
+	}
+
+	for _, name := range strings.Split(value, ",") {
+		interval, err := market.NormalizeInterval(name)
+		if err != nil {
+			utils.Warn("Ignoring historical sync interval %q: %v", name, err)
+			continue
+		}
+		barLength, _ := market.IntervalDuration(interval)
+
+		utils.Info("Starting incremental historical sync for %v at %s", tickers, interval)
+		go func() {
+			syncHistorical(ctx, tickers, interval, days)
+
+			// Sync shortly after each bar closes
+			t := scheduler.NewBoundaryTickerFunc(barLength, 5*time.Second, clk.Market(), clk.Now)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					syncHistorical(ctx, tickers, interval, days)
+				}
+			}
+		}()
+	}
+}
+
+// syncHistorical publishes each ticker's bars since its cursor and advances it
+func syncHistorical(ctx context.Context, tickers []string, interval string, days int) {
+	for _, ticker := range tickers {
+		if err := syncTicker(ctx, ticker, interval, days); err != nil {
+			utils.Error("Historical sync failed for %s (%s): %v", ticker, interval, err)
+		}
+	}
+}
+
+// syncTicker publishes one ticker's new bars. The last synced bar is fetched
+// again because it may have been incomplete, so consumers should replace bars
+// with the same timestamp.
+func syncTicker(ctx context.Context, ticker, interval string, days int) error {
+	cursor, err := eventClient.SyncCursor(ticker, interval)
+	if err != nil {
+		return err
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	if cursor != nil {
+		since = cursor.LastBar
+	}
+
+	start := time.Now()
+	bars, err := marketProvider.GetHistoricalDataSince(ctx, ticker, interval, since)
+	observeProvider("alpaca", "historical_since", start, err)
+	if err != nil {
+		return err
+	}
+	barHistory.Record(ticker, interval, bars, time.Now())
+	ctx = events.WithFetchTime(ctx, time.Now())
+	if len(bars) == 0 {
+		utils.Debug("No new %s bars for %s since %s", interval, ticker, since.Format(time.RFC3339))
+		return nil
+	}
+
+	chunks, err := events.ChunkHistoricalData(bars, eventClient.ChunkBudget())
+	if err != nil {
+		return err
+	}
+	for i, chunk := range chunks {
+		chunkData := market.ChunkData{
+			Data: chunk,
+			Metadata: market.ChunkMetadata{
+				Ticker:      ticker,
+				Timeframe:   interval,
+				Chunk:       i + 1,
+				TotalChunks: len(chunks),
+				DataType:    "sync",
+			},
+		}
+		if err := eventClient.PublishHistoricalSync(ctx, ticker, interval, chunkData); err != nil {
+			// Leave the cursor so the next sync retries from the same point
+			publishErrors.With("sync").Inc()
+			return err
+		}
+		historicalChunks.With("sync").Inc()
+	}
+
+	last := bars[len(bars)-1].Timestamp
+	utils.Info("Synced %d %s bars for %s through %s", len(bars), interval, ticker, last.Format(time.RFC3339))
+	return eventClient.SetSyncCursor(events.SyncCursor{Ticker: ticker, Interval: interval, LastBar: last})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/config"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/scheduler"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// defaultHistoryRetentionSchedule matches the gateway's data retention job
+const defaultHistoryRetentionSchedule = "30 3 * * *"
+
+// historyRetentionTimeout bounds one trim of the bar history
+const historyRetentionTimeout = 30 * time.Minute
+
+// scheduleHistoryRetention trims the bar history by the market data
+// retention policy in RETENTION_CONFIG_PATH, on DATA_RETENTION_SCHEDULE like
+// the gateway's retention job, so bars are not kept for as-of requests
+// longer than the streams keep them. "off" disables it.
+func scheduleHistoryRetention(ctx context.Context) {
+	schedule := os.Getenv("DATA_RETENTION_SCHEDULE")
+	if schedule == "" {
+		schedule = defaultHistoryRetentionSchedule
+	}
+	if schedule == "off" {
+		return
+	}
+
+	jobs := scheduler.New()
+	err := jobs.Add("bar-history-retention", schedule, time.UTC, historyRetentionTimeout, func(ctx context.Context) error {
+		policy, err := config.LoadRetentionPolicy()
+		if err != nil {
+			return err
+		}
+		dropped, err := barHistory.Trim(func(ticker, interval string) time.Duration {
+			if normalized, err := market.NormalizeInterval(interval); err == nil {
+				interval = normalized
+			}
+			return policy.MaxAge(market.AssetClassOf(ticker), interval)
+		}, time.Now())
+		if err != nil {
+			return err
+		}
+		utils.Info("Bar history retention dropped %d bars", dropped)
+		return nil
+	})
+	if err != nil {
+		utils.Error("Failed to schedule bar history retention: %v", err)
+		return
+	}
+	jobs.Start()
+	utils.Info("Scheduled bar history retention (%s)", schedule)
+
+	go func() {
+		<-ctx.Done()
+		jobs.Stop()
+	}()
+}
@@ -8,27 +8,43 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/myapp/tradinglab/pkg/auth"
+	"github.com/myapp/tradinglab/pkg/buildinfo"
+	"github.com/myapp/tradinglab/pkg/clock"
 	"github.com/myapp/tradinglab/pkg/events"
 	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/profiling"
+	"github.com/myapp/tradinglab/pkg/scheduler"
+	"github.com/myapp/tradinglab/pkg/secrets"
 	"github.com/myapp/tradinglab/pkg/utils"
 )
 
 // ServiceStatus contains information about the service status
 type ServiceStatus struct {
-	Status        string    `json:"status"`
-	Uptime        string    `json:"uptime"`
-	StartTime     time.Time `json:"start_time"`
-	Tickers       []string  `json:"tickers"`
-	MarketOpen    bool      `json:"market_open"`
-	LastPublished time.Time `json:"last_published"`
+	Status        string                  `json:"status"`
+	Version       string                  `json:"version"`
+	Commit        string                  `json:"commit,omitempty"`
+	Uptime        string                  `json:"uptime"`
+	StartTime     time.Time               `json:"start_time"`
+	Tickers       []string                `json:"tickers"`
+	MarketOpen    bool                    `json:"market_open"`
+	LastPublished time.Time               `json:"last_published"`
+	ProviderCache *market.CacheStats      `json:"provider_cache,omitempty"`
+	BarHistory    *market.BarHistoryStats `json:"bar_history,omitempty"`
+	ClockSkew     *ClockSkewStatus        `json:"clock_skew,omitempty"`
 	StreamStats   struct {
 		LiveEvents     int64 `json:"live_events"`
 		DailyEvents    int64 `json:"daily_events"`
 		HistoricalReqs int64 `json:"historical_requests"`
+		BookEvents     int64 `json:"book_events"`
+		TradeEvents    int64 `json:"trade_events"`
+		BarEvents      int64 `json:"bar_events"`
 	} `json:"stream_stats"`
 }
 
@@ -39,21 +55,21 @@ var (
 		StartTime: startTime,
 		Tickers:   []string{},
 	}
-	currentTickers []string
-	marketProvider *market.AlpacaProvider
-	eventClient    *events.EventClient
+	currentTickers   []string
+	marketProvider   *market.AlpacaProvider
+	eventClient      *events.EventClient
+	publishSimulated bool // Whether synthetic data may be published to the event streams
+	publishBooks     bool // Whether order book snapshots are published alongside live data
+	symbolDirectory  *market.SymbolDirectory
+	forexProvider    *market.AlphaVantageProvider // Serves currency pairs; nil when FX is not configured
+	providerCache    *market.ResponseCache        // Shared historical response cache; nil when disabled
+	barHistory       *market.BarHistory           // Every version of fetched bars, for as-of requests
+	clk              *clock.Clock                 // Market and display time zones
+	secretStore      *secrets.Manager             // Source of provider credentials
+	credWatcher      *market.CredentialWatcher    // Reloads rotated Alpaca credentials
+	liveBars         = newBarConsolidator()       // Consolidated bars per LIVE_BAR_INTERVALS
 )
 
-func init() {
-	// Set timezone to ET (Eastern Time) for market hours
-	loc, err := time.LoadLocation("America/New_York")
-	if err != nil {
-		utils.Error("Failed to load ET timezone: %v", err)
-	} else {
-		time.Local = loc
-	}
-}
-
 func main() {
 	// Get NATS URL from environment or use default
 	natsURL := os.Getenv("NATS_URL")
@@ -67,10 +83,16 @@ func main() {
 		httpPort = "8080"
 	}
 
-	utils.Info("Market Data Service starting, connecting to NATS server at %s", natsURL)
+	// Times are evaluated in the market zone and shown in ET unless configured
+	var err error
+	clk, err = clock.FromEnv("America/New_York")
+	if err != nil {
+		utils.Fatal("Invalid time zone configuration: %v", err)
+	}
+
+	utils.Info("Market Data Service %s starting, connecting to NATS server at %s", buildinfo.Get().Version, natsURL)
 
 	// Create event client
-	var err error
 	eventClient, err = events.NewEventClient(natsURL)
 	if err != nil {
 		utils.Fatal("Failed to create event client: %v", err)
@@ -91,14 +113,34 @@ func main() {
 		cancel()
 	}()
 
-	// Get Alpaca API credentials from environment
-	apiKey := os.Getenv("ALPACA_API_KEY")
-	apiSecret := os.Getenv("ALPACA_API_SECRET")
+	// Announce the service with a digest of what it has published
+	eventClient.StartHeartbeat(ctx, "market-data-service", events.HeartbeatInterval(), func() map[string]interface{} {
+		return map[string]interface{}{
+			"tickers":             len(currentTickers),
+			"market_open":         status.MarketOpen,
+			"live_events":         status.StreamStats.LiveEvents,
+			"historical_reqs":     status.StreamStats.HistoricalReqs,
+			"last_published":      status.LastPublished,
+			"clock_correction_ms": float64(clk.Offset()) / float64(time.Millisecond),
+		}
+	})
+
+	// Load provider credentials from the configured secrets backend
+	secretStore, err = secrets.FromEnv()
+	if err != nil {
+		utils.Fatal("Invalid secrets configuration: %v", err)
+	}
+	credentials, err := secretStore.Fetch(ctx, alpacaKeyID, alpacaSecretKey, alphaVantageKey)
+	if err != nil {
+		utils.Fatal("Failed to load provider credentials from %s: %v", secretStore.Name(), err)
+	}
+	apiKey, apiSecret := credentials[alpacaKeyID], credentials[alpacaSecretKey]
 
 	// Check if credentials are provided
 	if apiKey == "" || apiSecret == "" {
-		utils.Fatal("ALPACA_API_KEY and ALPACA_API_SECRET environment variables are required")
+		utils.Fatal("%s and %s are required from the %s secrets backend", alpacaKeyID, alpacaSecretKey, secretStore.Name())
 	}
+	utils.Info("Loaded provider credentials from %s secrets", secretStore.Name())
 
 	// Determine if we should use paper trading
 	usePaperTrading := true
@@ -119,6 +161,24 @@ func main() {
 		utils.Fatal("Failed to create market data provider: %v", err)
 	}
 
+	// Reuse identical historical queries to spare provider rate limits
+	providerCache = market.ResponseCacheFromEnv()
+	marketProvider.SetResponseCache(providerCache)
+
+	// Keep every version of fetched bars so corrections do not rewrite the
+	// inputs of past backtests
+	barHistory = market.BarHistoryFromEnv()
+
+	// Simulated data is never published unless explicitly enabled
+	publishSimulated = os.Getenv("PUBLISH_SIMULATED_DATA") == "true"
+	if publishSimulated {
+		utils.Warn("Publishing of simulated data is enabled; consumers may receive synthetic data labeled source=%s",
+			market.SourceSimulated)
+	}
+
+	// Order book snapshots are on by default; set PUBLISH_ORDER_BOOK=false to disable
+	publishBooks = os.Getenv("PUBLISH_ORDER_BOOK") != "false"
+
 	// Define tickers to watch
 	currentTickers = []string{"SPY", "AAPL", "MSFT", "GOOGL"}
 
@@ -128,17 +188,65 @@ func main() {
 		currentTickers = strings.Split(customTickers, ",")
 	}
 
+	// Currency pairs to watch, e.g. FOREX_PAIRS=EURUSD,GBP/USD
+	var forexPairs []string
+	if pairs := os.Getenv("FOREX_PAIRS"); pairs != "" {
+		forexProvider, err = market.NewAlphaVantageProvider(credentials[alphaVantageKey])
+		if err != nil {
+			utils.Error("Forex pairs configured but the forex provider is unavailable: %v", err)
+		} else {
+			forexProvider.SetResponseCache(providerCache)
+			for _, pair := range strings.Split(pairs, ",") {
+				if !market.IsForexPair(pair) {
+					utils.Warn("Ignoring invalid currency pair %q", pair)
+					continue
+				}
+				forexPairs = append(forexPairs, market.NormalizeTicker(pair))
+			}
+		}
+	}
+
+	// Swap rotated credentials into the providers without a restart
+	watchCredentials(ctx)
+
 	// Update global status
-	status.Tickers = currentTickers
+	status.Tickers = append(append([]string{}, currentTickers...), forexPairs...)
+	publishes.Watch(status.Tickers...)
 
 	// Subscribe to historical data requests
 	go subscribeToHistoricalRequests(ctx)
 
-	// Start streaming data for each ticker
-	for _, ticker := range currentTickers {
-		go streamMarketData(ctx, ticker)
+	// Answer symbol searches from the cached asset list, refreshed daily
+	symbolDirectory = market.NewSymbolDirectory(marketProvider.ListSymbols, 24*time.Hour)
+	serveSymbolSearch(ctx)
+
+	// Answer market clock queries, which diagnostics use to probe the provider
+	serveMarketClock(ctx)
+
+	// Watch the local clock against the market clock before relying on it
+	monitorClockSkew(ctx)
+
+	// Start streaming data for all tickers, fetched together each poll
+	go streamMarketData(ctx, currentTickers)
+
+	// Keep history current incrementally when HISTORICAL_SYNC_INTERVALS is set
+	startHistoricalSync(ctx, currentTickers)
+
+	for _, pair := range forexPairs {
+		startForexStream(ctx, pair)
 	}
 
+	// Restart streams the hub reports stale
+	serveStreamRestarts(ctx)
+
+	// Stream trade prints unless disabled with STREAM_TRADES=false
+	if os.Getenv("STREAM_TRADES") != "false" {
+		go streamTrades(ctx, currentTickers)
+	}
+
+	// Drop bar history past the retention policy
+	scheduleHistoryRetention(ctx)
+
 	// Start HTTP server for health checks and API endpoints
 	go startHTTPServer(httpPort)
 
@@ -148,41 +256,115 @@ func main() {
 	utils.Info("Shutting down Market Data Service")
 }
 
-// streamMarketData handles both live and daily market data streaming
-func streamMarketData(ctx context.Context, tickerSymbol string) {
-	// Default polling interval is 60 seconds
-	intervalStr := os.Getenv("POLLING_INTERVAL")
-	interval := 60 * time.Second
+// Secret names of the provider credentials
+const (
+	alpacaKeyID     = "ALPACA_API_KEY"
+	alpacaSecretKey = "ALPACA_API_SECRET"
+	alphaVantageKey = "ALPHA_VANTAGE_API_KEY"
+)
 
-	if intervalStr != "" {
-		// Try to parse custom interval
-		customInterval, err := time.ParseDuration(intervalStr)
-		if err == nil && customInterval > 0 {
-			interval = customInterval
+// credentialFilePollInterval is how often credential files are checked for changes
+const credentialFilePollInterval = 10 * time.Second
+
+// watchCredentials swaps rotated credentials into the providers: Alpaca
+// credentials are reloaded when their files change, when the secrets
+// backend reports new values, or on POST /admin/credentials/reload
+func watchCredentials(ctx context.Context) {
+	credWatcher = market.NewCredentialWatcher(marketProvider, func(ctx context.Context) (market.Credentials, error) {
+		values, err := secretStore.Fetch(ctx, alpacaKeyID, alpacaSecretKey)
+		if err != nil {
+			return market.Credentials{}, err
 		}
-	}
+		return market.Credentials{APIKey: values[alpacaKeyID], APISecret: values[alpacaSecretKey]}, nil
+	})
+	credWatcher.WatchFiles(ctx, credentialFiles(), credentialFilePollInterval)
 
-	utils.Info("Starting market data stream for %s with interval %v", tickerSymbol, interval)
+	secretStore.Watch(ctx, []string{alpacaKeyID, alpacaSecretKey, alphaVantageKey}, func(values map[string]string) {
+		if _, err := credWatcher.Reload(ctx); err != nil {
+			utils.Error("Keeping current Alpaca credentials: %v", err)
+		}
+		if forexProvider != nil {
+			if err := forexProvider.SetAPIKey(values[alphaVantageKey]); err != nil {
+				utils.Error("Ignoring rotated Alpha Vantage key: %v", err)
+			}
+		}
+	})
+}
 
-	// Verify data availability before starting stream
-	if !verifyDataAvailability(ctx, tickerSymbol) {
-		utils.Info("Data not available for %s. Stream will not start until data becomes available.", tickerSymbol)
+// credentialFiles lists the files Alpaca credentials are read from: the
+// secrets directory for the file backend, and any KEY_FILE paths
+func credentialFiles() []string {
+	var paths []string
+	if strings.EqualFold(os.Getenv("SECRETS_BACKEND"), "file") {
+		dir := os.Getenv("SECRETS_DIR")
+		if dir == "" {
+			dir = secrets.DefaultDir
+		}
+		paths = append(paths, dir)
 	}
+	for _, key := range []string{alpacaKeyID, alpacaSecretKey} {
+		if path := os.Getenv(key + "_FILE"); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
 
-	t := time.NewTicker(interval)
-	defer t.Stop()
+// pollingInterval parses a polling interval from an environment variable as a
+// duration ("60s") or a bar interval ("15min"), defaulting to 60 seconds
+func pollingInterval(name string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return 60 * time.Second
+	}
+	if interval, err := time.ParseDuration(value); err == nil && interval > 0 {
+		return interval
+	}
+	if interval, err := market.IntervalDuration(value); err == nil {
+		return interval
+	}
+	utils.Warn("Invalid %s %q, polling every 60s", name, value)
+	return 60 * time.Second
+}
 
-	// Create daily timer that fires at 4:30 PM ET (after market close)
-	// Set safe default timezone
-	loc := time.UTC
-	etLoc, err := time.LoadLocation("America/New_York")
-	if err == nil {
-		loc = etLoc
-	} else {
-		utils.Warn("Failed to load ET timezone, using UTC instead: %v", err)
+// newPollTicker returns a channel that fires every interval. Polls are aligned
+// to wall-clock bar boundaries in exchange time (e.g. :00 of each minute, or
+// the 15 minute marks for 15min), delayed by POLLING_OFFSET to give the
+// provider time to close the bar. POLLING_ALIGN=false polls at a fixed rate
+// from startup instead.
+func newPollTicker(interval time.Duration) (<-chan time.Time, func()) {
+	if os.Getenv("POLLING_ALIGN") == "false" {
+		t := time.NewTicker(interval)
+		return t.C, t.Stop
+	}
+
+	var offset time.Duration
+	if value := os.Getenv("POLLING_OFFSET"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 || parsed >= interval {
+			utils.Warn("Invalid POLLING_OFFSET %q, polling on the boundary", value)
+		} else {
+			offset = parsed
+		}
 	}
 
-	now := time.Now().In(loc)
+	t := scheduler.NewBoundaryTickerFunc(interval, offset, clk.Market(), clk.Now)
+	return t.C, t.Stop
+}
+
+// streamMarketData polls live data for all watched tickers, fetching every
+// ticker in one batch per poll, and publishes daily summaries after the close
+func streamMarketData(ctx context.Context, tickers []string) {
+	interval := pollingInterval("POLLING_INTERVAL")
+
+	utils.Info("Starting market data stream for %v with interval %v", tickers, interval)
+
+	ticks, stopTicks := newPollTicker(interval)
+	defer stopTicks()
+
+	// Create daily timer that fires at 4:30 PM market time (after market close)
+	loc := clk.Market()
+	now := clk.MarketNow()
 	marketCloseTime := time.Date(now.Year(), now.Month(), now.Day(), 16, 30, 0, 0, loc)
 
 	// If we're past 4:30 PM, schedule for tomorrow
@@ -197,77 +379,155 @@ func streamMarketData(ctx context.Context, tickerSymbol string) {
 	go func() {
 		for {
 			<-dailyTicker.C
-			// Publish daily summary
-			go publishDailyData(ctx, tickerSymbol)
+			// Publish daily summaries
+			for _, ticker := range tickers {
+				go publishDailyData(ctx, ticker)
+			}
 			// Reset timer for next day
 			dailyTicker.Reset(24 * time.Hour)
 		}
 	}()
 
-	dataAvailable := false
+	// Tickers are published once real (not sample) data is available for them
+	dataAvailable := make(map[string]bool, len(tickers))
 
-	for {
-		select {
-		case <-ctx.Done():
+	poll := func() {
+		// Check if market is open
+		start := time.Now()
+		isOpen, err := marketProvider.IsMarketOpen(ctx)
+		observeProvider("alpaca", "market_clock", start, err)
+		if err != nil {
+			utils.Error("Failed to check market status: %v", err)
+		}
+
+		status.MarketOpen = isOpen
+
+		start = time.Now()
+		snapshots, err := marketProvider.GetLatestBatch(ctx, tickers)
+		observeProvider("alpaca", "latest_batch", start, err)
+		if err != nil {
+			utils.Error("Failed to get market data: %v", err)
 			return
-		case <-t.C:
-			// If data wasn't available before, check again
-			if !dataAvailable {
-				dataAvailable = verifyDataAvailability(ctx, tickerSymbol)
-				if !dataAvailable {
-					utils.Info("Still waiting for data availability for %s", tickerSymbol)
-					continue
-				} else {
-					utils.Info("Data now available for %s, starting regular stream", tickerSymbol)
-				}
-			}
+		}
+		fetched := events.WithFetchTime(ctx, time.Now())
 
-			// Check if market is open
-			isOpen, err := marketProvider.IsMarketOpen(ctx)
-			if err != nil {
-				utils.Error("Failed to check market status: %v", err)
+		for _, ticker := range tickers {
+			snapshot, ok := snapshots[ticker]
+			if !ok {
+				continue
 			}
 
-			status.MarketOpen = isOpen
+			if !dataAvailable[ticker] {
+				if snapshot.Data.IsSimulated() {
+					utils.Info("Still waiting for data availability for %s", ticker)
+					continue
+				}
+				dataAvailable[ticker] = true
+				utils.Info("Data now available for %s, starting regular stream. Source: %s", ticker, snapshot.Data.Source)
+			}
 
-			// Fetch and publish appropriate data
+			// Publish appropriate data
 			if isOpen {
 				// Market is open, publish live data
-				publishLiveData(ctx, tickerSymbol)
+				publishLiveData(fetched, ticker, snapshot.Data)
+				if publishBooks && snapshot.Book != nil {
+					publishOrderBook(fetched, ticker, snapshot.Book)
+				}
 			} else {
+				// Close out bars left open by the end of the session
+				publishBars(fetched, ticker, liveBars.Flush(ticker))
+
 				// Market is closed, publish most recent data as daily data
 				// We'll also publish a proper daily summary at 4:30 PM
-				publishMostRecentData(ctx, tickerSymbol)
+				publishMostRecentData(fetched, ticker, snapshot.Data)
 			}
 		}
 	}
-}
 
-// verifyDataAvailability checks if actual data (not sample data) is available for the ticker
-func verifyDataAvailability(ctx context.Context, tickerSymbol string) bool {
-	// Try to get data
-	data, err := marketProvider.GetMostRecentData(ctx, tickerSymbol)
-	if err != nil {
-		utils.Error("Failed to verify data availability for %s: %v", tickerSymbol, err)
-		return false
+	// Poll once at startup so availability is known before the first boundary
+	poll()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			poll()
+		}
 	}
+}
+
+// streamForexData polls the exchange rate for a currency pair and publishes it
+// as live data while the 24x5 forex session is open
+func streamForexData(ctx context.Context, pair string) {
+	interval := pollingInterval("FOREX_POLLING_INTERVAL")
+
+	utils.Info("Starting forex stream for %s with interval %v", pair, interval)
+
+	ticks, stopTicks := newPollTicker(interval)
+	defer stopTicks()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			if !market.InForexSession(time.Now()) {
+				continue
+			}
+
+			start := time.Now()
+			data, err := forexProvider.GetForexRate(ctx, pair)
+			observeProvider("alphavantage", "forex_rate", start, err)
+			if err != nil {
+				utils.Error("Failed to get forex rate for %s: %v", pair, err)
+				continue
+			}
 
-	// Check if we got real data or sample data
-	if data.Source == "Sample" {
-		utils.Info("Only sample data available for %s, not starting stream yet", tickerSymbol)
-		return false
+			if err := eventClient.PublishMarketLiveData(events.WithFetchTime(ctx, time.Now()), pair, data); err != nil {
+				utils.Error("Failed to publish forex data for %s: %v", pair, err)
+				publishErrors.With("live").Inc()
+			} else {
+				utils.Info("Published forex rate for %s: %.5f", pair, data.Price)
+				status.LastPublished = time.Now()
+				status.StreamStats.LiveEvents++
+				eventsPublished.With("live", pair).Inc()
+				publishes.Published(pair)
+			}
+		}
 	}
+}
+
+// streamTrades publishes trade prints for the watched tickers, restarting the
+// feed if it terminates
+func streamTrades(ctx context.Context, tickers []string) {
+	for {
+		err := marketProvider.StreamTrades(ctx, tickers, func(trade market.Trade) {
+			if err := eventClient.PublishTrade(events.WithFetchTime(ctx, time.Now()), trade.Ticker, trade); err != nil {
+				utils.Error("Failed to publish trade for %s: %v", trade.Ticker, err)
+				publishErrors.With("trade").Inc()
+				return
+			}
+			status.StreamStats.TradeEvents++
+			eventsPublished.With("trade", trade.Ticker).Inc()
+		})
+		if ctx.Err() != nil {
+			return
+		}
 
-	utils.Info("Verified data availability for %s. Source: %s", tickerSymbol, data.Source)
-	return true
+		utils.Error("Trade stream stopped: %v; restarting in 30s", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(30 * time.Second):
+		}
+	}
 }
 
 // publishLiveData publishes real-time market data
-func publishLiveData(ctx context.Context, tickerSymbol string) {
-	// Fetch latest data from the provider
-	data, err := marketProvider.GetLatestData(ctx, tickerSymbol)
-	if err != nil {
-		utils.Error("Failed to get live data for %s: %v", tickerSymbol, err)
+func publishLiveData(ctx context.Context, tickerSymbol string, data *market.MarketData) {
+	if data.IsSimulated() && !publishSimulated {
+		utils.Debug("Skipping publish of simulated live data for %s", tickerSymbol)
 		return
 	}
 
@@ -277,20 +537,50 @@ func publishLiveData(ctx context.Context, tickerSymbol string) {
 	// Publish to event stream
 	if err := eventClient.PublishMarketLiveData(ctx, tickerSymbol, data); err != nil {
 		utils.Error("Failed to publish live market data for %s: %v", tickerSymbol, err)
+		publishErrors.With("live").Inc()
 	} else {
 		utils.Info("Published live market data for %s: price=$%.2f, volume=%d",
 			tickerSymbol, data.Price, data.Volume)
 		status.LastPublished = time.Now()
 		status.StreamStats.LiveEvents++
+		eventsPublished.With("live", tickerSymbol).Inc()
+		publishes.Published(tickerSymbol)
 	}
+
+	publishBars(ctx, tickerSymbol, liveBars.Add(tickerSymbol, data))
+}
+
+// publishBars publishes completed consolidated bars
+func publishBars(ctx context.Context, tickerSymbol string, bars []*market.MarketData) {
+	for _, bar := range bars {
+		if err := eventClient.PublishMarketBar(ctx, tickerSymbol, bar.Interval, bar); err != nil {
+			utils.Error("Failed to publish %s bar for %s: %v", bar.Interval, tickerSymbol, err)
+			publishErrors.With("bar").Inc()
+			continue
+		}
+		utils.Debug("Published %s bar for %s: o=%.2f h=%.2f l=%.2f c=%.2f v=%d",
+			bar.Interval, tickerSymbol, bar.Open, bar.High, bar.Low, bar.Close, bar.Volume)
+		status.StreamStats.BarEvents++
+		barsPublished.With(tickerSymbol, bar.Interval).Inc()
+	}
+}
+
+// publishOrderBook publishes a depth snapshot
+func publishOrderBook(ctx context.Context, tickerSymbol string, book *market.OrderBook) {
+	if err := eventClient.PublishOrderBook(ctx, tickerSymbol, book); err != nil {
+		utils.Error("Failed to publish order book for %s: %v", tickerSymbol, err)
+		publishErrors.With("book").Inc()
+		return
+	}
+	utils.Debug("Published order book for %s: spread=%.4f, levels=%d", tickerSymbol, book.Spread, book.Levels)
+	status.StreamStats.BookEvents++
+	eventsPublished.With("book", tickerSymbol).Inc()
 }
 
 // publishMostRecentData publishes most recent data when market is closed
-func publishMostRecentData(ctx context.Context, tickerSymbol string) {
-	// Fetch recent data from the provider
-	data, err := marketProvider.GetMostRecentData(ctx, tickerSymbol)
-	if err != nil {
-		utils.Error("Failed to get recent data for %s: %v", tickerSymbol, err)
+func publishMostRecentData(ctx context.Context, tickerSymbol string, data *market.MarketData) {
+	if data.IsSimulated() && !publishSimulated {
+		utils.Debug("Skipping publish of simulated recent data for %s", tickerSymbol)
 		return
 	}
 
@@ -300,21 +590,27 @@ func publishMostRecentData(ctx context.Context, tickerSymbol string) {
 	// Publish to event stream - we still use the live stream but with a "recent" flag
 	if err := eventClient.PublishMarketLiveData(ctx, tickerSymbol, data); err != nil {
 		utils.Error("Failed to publish recent market data for %s: %v", tickerSymbol, err)
+		publishErrors.With("recent").Inc()
 	} else {
 		utils.Info("Published recent market data for %s: price=$%.2f, volume=%d",
 			tickerSymbol, data.Price, data.Volume)
 		status.LastPublished = time.Now()
+		eventsPublished.With("recent", tickerSymbol).Inc()
+		publishes.Published(tickerSymbol)
 	}
 }
 
 // publishDailyData publishes end-of-day summary
 func publishDailyData(ctx context.Context, tickerSymbol string) {
 	// Fetch daily data from the provider
+	start := time.Now()
 	data, err := marketProvider.GetDailyData(ctx, tickerSymbol)
+	observeProvider("alpaca", "daily", start, err)
 	if err != nil {
 		utils.Error("Failed to get daily data for %s: %v", tickerSymbol, err)
 		return
 	}
+	ctx = events.WithFetchTime(ctx, time.Now())
 
 	// Add data type metadata
 	data.DataType = "daily"
@@ -322,134 +618,314 @@ func publishDailyData(ctx context.Context, tickerSymbol string) {
 	// Publish to daily event stream
 	if err := eventClient.PublishMarketDailyData(ctx, tickerSymbol, data); err != nil {
 		utils.Error("Failed to publish daily market data for %s: %v", tickerSymbol, err)
+		publishErrors.With("daily").Inc()
 	} else {
 		utils.Info("Published daily market data for %s: close=$%.2f, volume=%d",
 			tickerSymbol, data.Close, data.Volume)
 		status.StreamStats.DailyEvents++
+		eventsPublished.With("daily", tickerSymbol).Inc()
 	}
 }
 
 // subscribeToHistoricalRequests listens for requests to fetch historical data
+// and queues them for a worker pool that serves the highest priority first.
+// HISTORICAL_WORKERS sets the pool size.
 func subscribeToHistoricalRequests(ctx context.Context) {
 	utils.Info("Setting up subscription for historical data requests")
-	
+
+	workers := defaultHistoricalWorkers
+	if n, err := strconv.Atoi(os.Getenv("HISTORICAL_WORKERS")); err == nil && n > 0 {
+		workers = n
+	}
+	queue := newHistoricalQueue(serveHistoricalRequest)
+	queue.Start(ctx, workers)
+
 	// Subscribe to historical data requests
 	_, err := eventClient.SubscribeHistoricalRequests(func(ticker, timeframe string, days int, reqData []byte) {
-		utils.Debug("Received historical data request: %s, %s, %d days", ticker, timeframe, days)
+		priority := events.RequestPriority(reqData)
+		utils.Debug("Received %s historical data request: %s, %s, %d days", priority, ticker, timeframe, days)
 		status.StreamStats.HistoricalReqs++
 
-		// Parse request data for any additional parameters
-		var request map[string]interface{}
-		if err := json.Unmarshal(reqData, &request); err != nil {
-			utils.Warn("Failed to parse request data: %v", err)
+		job := &historicalJob{
+			ticker:    ticker,
+			timeframe: timeframe,
+			days:      days,
+			priority:  priority,
+			requestID: events.RequestID(reqData),
+			asOf:      events.RequestAsOf(reqData),
 		}
+		queue.Add(job)
+		reportProgress(ctx, job, events.RequestProgress{Stage: events.ProgressQueued})
+	})
 
-		// Fetch historical data
-		utils.Debug("Fetching historical data from provider for %s", ticker)
-		historicalData, err := marketProvider.GetHistoricalData(ctx, ticker, days, timeframe)
-		if err != nil {
-			utils.Error("Failed to get historical data: %v", err)
+	if err != nil {
+		utils.Error("Failed to subscribe to historical requests: %v", err)
+	} else {
+		utils.Info("Successfully subscribed to historical data requests (%d workers)", workers)
+	}
+
+	// Requesters cancel requests they no longer need, such as an abandoned
+	// chart load, so the provider budget goes to requests still wanted
+	_, err = eventClient.SubscribeRequestCancels(func(requestID string) {
+		job, found := queue.Cancel(requestID)
+		if !found {
 			return
 		}
+		if job != nil {
+			utils.Info("Cancelled queued historical request %s for %s", requestID, job.ticker)
+			historicalCancelled.With(events.ProgressQueued).Inc()
+			reportProgress(ctx, job, events.RequestProgress{Stage: events.ProgressCancelled})
+		} else {
+			utils.Info("Cancelling historical request %s", requestID)
+		}
+	})
+	if err != nil {
+		utils.Error("Failed to subscribe to request cancellations: %v", err)
+	}
+}
 
-		// Stream is limited so we'll publish in chunks if necessary
-		const chunkSize = 100
-		utils.Debug("Got %d data points for %s, will chunk if needed (chunk size: %d)", 
-			len(historicalData), ticker, chunkSize)
+// reportProgress publishes the progress of a historical request that has an
+// ID to publish it under
+func reportProgress(ctx context.Context, job *historicalJob, progress events.RequestProgress) {
+	if job.requestID == "" {
+		return
+	}
+	progress.RequestID = job.requestID
+	progress.Ticker, progress.Timeframe, progress.Days = job.ticker, job.timeframe, job.days
+	if err := eventClient.PublishRequestProgress(ctx, progress); err != nil {
+		utils.Debug("Failed to publish progress of request %s: %v", job.requestID, err)
+	}
+}
 
-		// If we have a large dataset, publish in chunks
-		if len(historicalData) > chunkSize {
-			chunks := len(historicalData) / chunkSize
-			if len(historicalData)%chunkSize > 0 {
-				chunks++
-			}
-			
-			utils.Debug("Data size exceeds chunk size. Will publish in %d chunks", chunks)
-
-			for i := 0; i < chunks; i++ {
-				start := i * chunkSize
-				end := start + chunkSize
-				if end > len(historicalData) {
-					end = len(historicalData)
-				}
+// serveHistoricalRequest fetches historical data for a queued request and
+// publishes it in chunks, reporting progress as it goes
+func serveHistoricalRequest(ctx context.Context, job *historicalJob) {
+	ticker, timeframe, days := job.ticker, job.timeframe, job.days
+	utils.Debug("Serving %s historical data request for %s after %v in queue",
+		job.priority, ticker, time.Since(job.queuedAt).Round(time.Millisecond))
+	reportProgress(ctx, job, events.RequestProgress{Stage: events.ProgressFetching})
+
+	// Fetch historical data
+	utils.Debug("Fetching historical data from provider for %s", ticker)
+	var historicalData []*market.MarketData
+	var err error
+	start := time.Now()
+	switch {
+	case !job.asOf.IsZero():
+		// As-of requests are answered from the bars recorded by then, so a
+		// backtest sees the same inputs however often it is rerun
+		utils.Debug("Serving %s as of %s from bar history", ticker, job.asOf.Format(time.RFC3339))
+		historicalData, err = barHistory.AsOf(ticker, timeframe, days, job.asOf)
+	case market.IsForexPair(ticker) && forexProvider != nil:
+		historicalData, err = forexProvider.GetForexHistorical(ctx, ticker, days, timeframe)
+		observeProvider("alphavantage", "forex_historical", start, err)
+	default:
+		historicalData, err = marketProvider.GetHistoricalData(ctx, ticker, days, timeframe)
+		observeProvider("alpaca", "historical", start, err)
+	}
+	if cancelled(ctx) {
+		historicalCancelled.With(events.ProgressFetching).Inc()
+		reportProgress(ctx, job, events.RequestProgress{Stage: events.ProgressCancelled})
+		return
+	}
+	if err != nil {
+		utils.Error("Failed to get historical data: %v", err)
+		reportProgress(ctx, job, events.RequestProgress{Stage: events.ProgressFailed, Error: err.Error()})
+		return
+	}
+	if job.asOf.IsZero() {
+		barHistory.Record(ticker, timeframe, historicalData, time.Now())
+	}
+	ctx = events.WithFetchTime(ctx, time.Now())
 
-				utils.Debug("Preparing chunk %d/%d for %s with %d data points", 
-					i+1, chunks, ticker, end-start)
-
-				// Prepare chunk data
-				metadata := market.ChunkMetadata{
-					Ticker:      ticker,
-					Timeframe:   timeframe,
-					Days:        days,
-					Chunk:       i + 1,
-					TotalChunks: chunks,
-					DataType:    "historical",
-				}
-				
-				chunkData := market.ChunkData{
-					Data:     historicalData[start:end],
-					Metadata: metadata,
-				}
+	// Size chunks by serialized bytes so each fits within the server's max payload
+	chunks, err := events.ChunkHistoricalData(historicalData, eventClient.ChunkBudget())
+	if err != nil {
+		utils.Error("Failed to chunk historical data: %v", err)
+		reportProgress(ctx, job, events.RequestProgress{Stage: events.ProgressFailed, FetchedPercent: 100, Error: err.Error()})
+		return
+	}
+	utils.Debug("Got %d data points for %s, publishing in %d chunks",
+		len(historicalData), ticker, len(chunks))
+	progress := events.RequestProgress{
+		Stage:          events.ProgressPublishing,
+		FetchedPercent: 100,
+		Records:        len(historicalData),
+		TotalChunks:    len(chunks),
+	}
+	reportProgress(ctx, job, progress)
+	failed := 0
 
-				// Publish chunk
-				utils.Debug("Publishing historical data chunk %d/%d to stream", i+1, chunks)
-				if err := eventClient.PublishHistoricalData(ctx, ticker, timeframe, days, chunkData); err != nil {
-					utils.Error("Failed to publish historical data chunk %d/%d: %v", i+1, chunks, err)
-				} else {
-					utils.Info("Published historical data chunk %d/%d for %s (%s, %d days)",
-						i+1, chunks, ticker, timeframe, days)
-				}
+	for i, chunk := range chunks {
+		if ctx.Err() != nil {
+			break
+		}
+		metadata := market.ChunkMetadata{
+			Ticker:      ticker,
+			Timeframe:   timeframe,
+			Days:        days,
+			Chunk:       i + 1,
+			TotalChunks: len(chunks),
+			DataType:    "historical",
+		}
 
-				// Small pause between chunks to avoid overwhelming the system
-				time.Sleep(500 * time.Millisecond)
-			}
+		chunkData := market.ChunkData{
+			Data:     chunk,
+			Metadata: metadata,
+		}
+		var payload interface{} = chunkData
+		if !job.asOf.IsZero() {
+			payload = events.AsOfChunk{ChunkData: chunkData, AsOf: market.FormatTimestamp(job.asOf)}
+		}
+
+		if err := eventClient.PublishHistoricalData(ctx, ticker, timeframe, days, payload); err != nil {
+			utils.Error("Failed to publish historical data chunk %d/%d: %v", i+1, len(chunks), err)
+			publishErrors.With("historical").Inc()
+			failed++
 		} else {
-			utils.Debug("Data fits in a single chunk, publishing directly")
-			
-			// Prepare data package using our centralized model
-			metadata := market.ChunkMetadata{
-				Ticker:      ticker,
-				Timeframe:   timeframe,
-				Days:        days,
-				Chunk:       1,
-				TotalChunks: 1,
-				DataType:    "historical",
-			}
-			
-			chunkData := market.ChunkData{
-				Data:     historicalData,
-				Metadata: metadata,
+			historicalChunks.With("request").Inc()
+			utils.Info("Published historical data chunk %d/%d for %s (%s, %d days, %d data points)",
+				i+1, len(chunks), ticker, timeframe, days, len(chunk))
+			progress.ChunksPublished++
+			if i < len(chunks)-1 {
+				reportProgress(ctx, job, progress)
 			}
+		}
 
-			// Publish all data at once for smaller datasets
-			utils.Debug("Publishing historical data to stream")
-			if err := eventClient.PublishHistoricalData(ctx, ticker, timeframe, days, chunkData); err != nil {
-				utils.Error("Failed to publish historical data: %v", err)
-			} else {
-				utils.Info("Published historical data for %s (%s, %d days)", ticker, timeframe, days)
+		// Small pause between chunks to avoid overwhelming the system
+		if i < len(chunks)-1 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(500 * time.Millisecond):
 			}
 		}
+	}
+
+	switch {
+	case cancelled(ctx) && progress.ChunksPublished+failed < len(chunks):
+		historicalCancelled.With(events.ProgressPublishing).Inc()
+		progress.Stage = events.ProgressCancelled
+	case ctx.Err() != nil && progress.ChunksPublished+failed < len(chunks):
+		progress.Stage = events.ProgressFailed
+		progress.Error = "service shutting down"
+	case failed > 0:
+		progress.Stage = events.ProgressFailed
+		progress.Error = fmt.Sprintf("%d of %d chunks failed to publish", failed, len(chunks))
+	default:
+		progress.Stage = events.ProgressDone
+	}
+	reportProgress(ctx, job, progress)
+}
+
+// serveSymbolSearch answers symbol search requests from the gateway
+func serveSymbolSearch(ctx context.Context) {
+	_, err := eventClient.ServeRequests(events.SubjectReferenceSymbolSearch, func(data []byte) (interface{}, error) {
+		var search market.SymbolSearch
+		if err := json.Unmarshal(data, &search); err != nil {
+			return nil, fmt.Errorf("invalid search request: %w", err)
+		}
+		if search.Limit <= 0 || search.Limit > market.MaxSymbolResults {
+			search.Limit = market.DefaultSymbolResults
+		}
+		return symbolDirectory.Search(ctx, search.Query, search.Limit)
 	})
 
 	if err != nil {
-		utils.Error("Failed to subscribe to historical requests: %v", err)
+		utils.Error("Failed to subscribe to symbol searches: %v", err)
 	} else {
-		utils.Info("Successfully subscribed to historical data requests")
+		utils.Info("Serving symbol searches on %s", events.SubjectReferenceSymbolSearch)
+	}
+}
+
+// serveMarketClock answers market clock requests from the provider's trading API
+func serveMarketClock(ctx context.Context) {
+	_, err := eventClient.ServeRequests(events.SubjectReferenceMarketClock, func(data []byte) (interface{}, error) {
+		start := time.Now()
+		clock, err := marketProvider.Clock(ctx)
+		observeProvider("alpaca", "market_clock", start, err)
+		return clock, err
+	})
+
+	if err != nil {
+		utils.Error("Failed to subscribe to market clock requests: %v", err)
+	} else {
+		utils.Info("Serving market clock requests on %s", events.SubjectReferenceMarketClock)
 	}
 }
 
 // startHTTPServer starts an HTTP server for health checks and API endpoints
 func startHTTPServer(port string) {
+	// Historical requests spend upstream API quota, so the API and admin
+	// endpoints only answer inside the cluster unless configured otherwise
+	policy, err := auth.AccessPolicyFromEnv(auth.AccessPolicy{
+		Allow:   auth.MustParsePrefixes(auth.InternalNetworks...),
+		Public:  []string{"/health"},
+		Private: []string{"/api/*", "/admin/*", "/metrics", "/debug/*"},
+	})
+	if err != nil {
+		utils.Fatal("Invalid access policy: %v", err)
+	}
+
+	// Admin and profiling endpoints need an admin credential, checked as
+	// the other services check it
+	authn, err := auth.FromEnv()
+	if err != nil {
+		utils.Fatal("Invalid access control configuration: %v", err)
+	}
+	authorizeAdmin := func(w http.ResponseWriter, r *http.Request) bool {
+		return authn.Require(w, r, auth.PermConfig)
+	}
+
 	// Define health check handler
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		// Update uptime
 		status.Uptime = time.Since(startTime).String()
+		status.Version = buildinfo.Get().Version
+		status.Commit = buildinfo.Get().Commit
+		if providerCache != nil {
+			stats := providerCache.Stats()
+			status.ProviderCache = &stats
+		}
+		if barHistory != nil {
+			stats := barHistory.Stats()
+			status.BarHistory = &stats
+		}
+		status.ClockSkew = clockSkewStatus()
 
 		// Return status as JSON
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(status)
 	})
 
+	// Publish pipeline metrics for Prometheus
+	http.Handle("/metrics", metrics.Handler())
+
+	// Credential reload history, and a trigger for reloading after a rotation
+	http.HandleFunc("/admin/credentials", func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeAdmin(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(credWatcher.Status())
+	})
+	http.HandleFunc("/admin/credentials/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !authorizeAdmin(w, r) {
+			return
+		}
+		changed, err := credWatcher.Reload(r.Context())
+		if err != nil {
+			utils.Error("Credential reload failed: %v", err)
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"reloaded": changed, "status": credWatcher.Status()})
+	})
+
 	// API endpoint to request historical data directly via HTTP
 	http.HandleFunc("/api/historical", func(w http.ResponseWriter, r *http.Request) {
 		// Only accept GET requests
@@ -477,12 +953,33 @@ func startHTTPServer(port string) {
 			return
 		}
 
+		// Tickers and timeframes become NATS subject tokens
+		params, err := market.NormalizeHistoricalParams(ticker, timeframe, days)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		ticker, timeframe = params.Ticker, params.Interval
+
 		// Create request data
-		requestID := fmt.Sprintf("%s-%d", r.RemoteAddr, time.Now().UnixNano())
+		requestID := events.NewRequestID("api")
 		requestData := map[string]interface{}{
 			"request_id": requestID,
 			"source":     "http_api",
-			"timestamp":  time.Now().Format(time.RFC3339),
+			"priority":   events.ParsePriority(r.URL.Query().Get("priority")).String(),
+			"timestamp":  market.FormatTimestamp(clk.Now()),
+		}
+
+		// as_of asks for bars as they were known then, from the bar history
+		if value := r.URL.Query().Get("as_of"); value != "" {
+			asOf, err := market.ParseTimestamp(value, market.ExchangeLocation())
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(fmt.Sprintf("Invalid as_of parameter: %v", err)))
+				return
+			}
+			requestData["as_of"] = market.FormatTimestamp(asOf)
 		}
 
 		// Publish request to NATS
@@ -499,6 +996,7 @@ func startHTTPServer(port string) {
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"status":     "accepted",
 			"request_id": requestID,
+			"progress":   fmt.Sprintf(events.SubjectRequestProgress, requestID),
 			"message": fmt.Sprintf("Historical data request for %s (%s, %d days) has been submitted",
 				ticker, timeframe, days),
 		})
@@ -507,7 +1005,9 @@ func startHTTPServer(port string) {
 	// Start HTTP server
 	serverAddr := ":" + port
 	utils.Info("Starting HTTP server on %s", serverAddr)
-	if err := http.ListenAndServe(serverAddr, nil); err != nil {
+	// Profiling endpoints need DEBUG_ENDPOINTS=true and an admin credential
+	handler := profiling.Guard(http.DefaultServeMux, profiling.EnabledFromEnv(), authorizeAdmin)
+	if err := http.ListenAndServe(serverAddr, policy.Middleware(handler)); err != nil {
 		utils.Fatal("HTTP server failed: %v", err)
 	}
 }
\ No newline at end of file
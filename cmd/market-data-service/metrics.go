@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/metrics"
+)
+
+// Publish pipeline metrics, served at /metrics. Last-publish age per ticker
+// is what alerting watches: it keeps growing while a stream is stalled even
+// though no other series changes.
+var (
+	eventsPublished = metrics.Default.NewCounterVec("marketdata_events_published_total",
+		"Events published per stream and ticker", "stream", "ticker")
+	barsPublished = metrics.Default.NewCounterVec("marketdata_bars_published_total",
+		"Consolidated bars published per ticker and interval", "ticker", "interval")
+	publishErrors = metrics.Default.NewCounterVec("marketdata_publish_errors_total",
+		"Failed publishes per stream", "stream")
+	providerLatency = metrics.Default.NewHistogramVec("marketdata_provider_request_seconds",
+		"Provider call latency in seconds", nil, "provider", "operation")
+	providerErrors = metrics.Default.NewCounterVec("marketdata_provider_errors_total",
+		"Failed provider calls", "provider", "operation")
+	historicalQueueDepth = metrics.Default.NewGaugeVec("marketdata_historical_queue_depth",
+		"Historical requests waiting for a worker")
+	historicalChunks = metrics.Default.NewCounterVec("marketdata_historical_chunks_published_total",
+		"Historical data chunks published, by request or sync", "source")
+	historicalCancelled = metrics.Default.NewCounterVec("marketdata_historical_cancelled_total",
+		"Historical requests cancelled, by the stage they reached", "stage")
+	lastPublishTime = metrics.Default.NewGaugeVec("marketdata_last_publish_timestamp_seconds",
+		"Unix time of the last live publish per ticker", "ticker")
+	lastPublishAge = metrics.Default.NewGaugeVec("marketdata_last_publish_age_seconds",
+		"Seconds since the last live publish per ticker, or since startup if none", "ticker")
+)
+
+// publishTracker records when each watched ticker last published
+type publishTracker struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// publishes tracks live publishes for the last-publish age gauges
+var publishes = newPublishTracker()
+
+func newPublishTracker() *publishTracker {
+	t := &publishTracker{last: make(map[string]time.Time)}
+	metrics.Default.OnScrape(t.updateAges)
+	return t
+}
+
+// Watch starts tracking tickers that have not published yet, aged from now
+// so a ticker that never publishes still trips alerting
+func (t *publishTracker) Watch(tickers ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for _, ticker := range tickers {
+		if _, ok := t.last[ticker]; !ok {
+			t.last[ticker] = now
+		}
+	}
+}
+
+// Published records a publish for ticker
+func (t *publishTracker) Published(ticker string) {
+	now := time.Now()
+	t.mu.Lock()
+	t.last[ticker] = now
+	t.mu.Unlock()
+	lastPublishTime.With(ticker).SetTime(now)
+}
+
+// updateAges refreshes the age gauges before a scrape
+func (t *publishTracker) updateAges() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for ticker, at := range t.last {
+		lastPublishAge.With(ticker).Set(now.Sub(at).Seconds())
+	}
+}
+
+// observeProvider records the latency and outcome of a provider call
+func observeProvider(provider, operation string, start time.Time, err error) {
+	providerLatency.With(provider, operation).ObserveSince(start)
+	if err != nil {
+		providerErrors.With(provider, operation).Inc()
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/metrics"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// streamRestarts counts stream restarts requested per ticker
+var streamRestarts = metrics.Default.NewCounterVec("marketdata_stream_restarts_total",
+	"Stream restarts requested for stale tickers", "ticker")
+
+// forexStreams holds the cancel function of each currency pair's polling, so
+// a stale pair's stream can be started afresh
+var (
+	forexStreamsMu sync.Mutex
+	forexStreams   = make(map[string]context.CancelFunc)
+)
+
+// startForexStream starts polling a currency pair, stopping any stream
+// already running for it
+func startForexStream(ctx context.Context, pair string) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	forexStreamsMu.Lock()
+	if stop, ok := forexStreams[pair]; ok {
+		stop()
+	}
+	forexStreams[pair] = cancel
+	forexStreamsMu.Unlock()
+
+	go streamForexData(streamCtx, pair)
+}
+
+// serveStreamRestarts restarts the streams of tickers the hub reports stale.
+// A currency pair's polling starts afresh; an equity, polled in one batch
+// with the others, is fetched on its own straight away.
+func serveStreamRestarts(ctx context.Context) {
+	_, err := eventClient.SubscribeStreamRestarts(func(restart events.StreamRestart) {
+		ticker := restart.Ticker
+		forexStreamsMu.Lock()
+		_, forex := forexStreams[ticker]
+		forexStreamsMu.Unlock()
+
+		switch {
+		case forex:
+			utils.Warn("Restarting forex stream for %s at the request of %s: %s", ticker, restart.RequestedBy, restart.Reason)
+			startForexStream(ctx, ticker)
+		case slices.Contains(currentTickers, ticker):
+			utils.Warn("Refetching %s at the request of %s: %s", ticker, restart.RequestedBy, restart.Reason)
+			go refetchTicker(ctx, ticker)
+		default:
+			utils.Debug("Ignoring stream restart for %s, which this service does not stream", ticker)
+			return
+		}
+		streamRestarts.With(ticker).Inc()
+	})
+	if err != nil {
+		utils.Error("Failed to subscribe to stream restarts: %v", err)
+	}
+}
+
+// refetchTicker fetches one equity's latest data outside the batch poll and
+// publishes it while the market is open
+func refetchTicker(ctx context.Context, ticker string) {
+	start := time.Now()
+	snapshots, err := marketProvider.GetLatestBatch(ctx, []string{ticker})
+	observeProvider("alpaca", "latest_batch", start, err)
+	if err != nil {
+		utils.Error("Failed to refetch market data for %s: %v", ticker, err)
+		return
+	}
+	snapshot, ok := snapshots[ticker]
+	if !ok {
+		utils.Warn("Provider returned no data for %s on refetch", ticker)
+		return
+	}
+	if !status.MarketOpen {
+		return
+	}
+	publishLiveData(events.WithFetchTime(ctx, time.Now()), ticker, snapshot.Data)
+}
@@ -0,0 +1,160 @@
+// cmd/snapshot/main.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/myapp/tradinglab/pkg/config"
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/snapshot"
+	"github.com/myapp/tradinglab/pkg/storage"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+const usage = `usage: snapshot create | list | show <id> | restore <id>
+
+Snapshots JetStream streams and the persistence store to object storage, and
+restores them into this environment. Object storage is configured as for the
+gateway; streams come from NATS_URL.
+
+  SNAPSHOT_STREAMS   Comma-separated streams (default all, "none" for none)
+  SNAPSHOT_STORE     Include the persistence store (default true)
+  SNAPSHOT_REPLACE   On restore, overwrite non-empty streams and existing files
+
+Restore the persistence store while the services using it are stopped.`
+
+// openStore opens the object store, defaulting the file backend to
+// ARTIFACTS_DIR as the gateway does
+func openStore() (storage.Store, error) {
+	cfg, err := config.LoadObjectStorage()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Backend == config.ObjectStoreFile && cfg.Dir == "" {
+		cfg.Dir = os.Getenv("ARTIFACTS_DIR")
+		if cfg.Dir == "" {
+			cfg.Dir = "artifacts"
+		}
+	}
+	return storage.Open(cfg)
+}
+
+// connect opens the event client for NATS_URL
+func connect() (*events.EventClient, error) {
+	natsURL := os.Getenv("NATS_URL")
+	if natsURL == "" {
+		natsURL = "nats://localhost:4222"
+	}
+	return events.NewEventClient(natsURL)
+}
+
+// boolEnv reads a boolean environment variable
+func boolEnv(name string, fallback bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		utils.Fatal("Invalid %s %q", name, value)
+	}
+	return b
+}
+
+// printJSON writes a result to stdout
+func printJSON(v interface{}) {
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	out.Encode(v)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+	if (command == "show" || command == "restore") && len(args) != 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	store, err := openStore()
+	if err != nil {
+		utils.Fatal("Invalid object storage configuration: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		utils.Info("Received signal: %v", sig)
+		cancel()
+	}()
+
+	opts := snapshot.Options{
+		Streams: snapshot.ParseStreams(os.Getenv("SNAPSHOT_STREAMS")),
+		Store:   boolEnv("SNAPSHOT_STORE", true),
+		Replace: boolEnv("SNAPSHOT_REPLACE", false),
+	}
+
+	switch command {
+	case "list", "show":
+		snapshots := snapshot.New(store, nil)
+		if command == "list" {
+			manifests, err := snapshots.List(ctx)
+			if err != nil {
+				utils.Fatal("Failed to list snapshots: %v", err)
+			}
+			printJSON(manifests)
+			return
+		}
+		manifest, err := snapshots.Get(ctx, args[0])
+		if err != nil {
+			utils.Fatal("Failed to read snapshot %s: %v", args[0], err)
+		}
+		printJSON(manifest)
+
+	case "create", "restore":
+		var streams snapshot.Streams
+		if opts.Streams == nil || len(opts.Streams) > 0 {
+			client, err := connect()
+			if err != nil {
+				utils.Fatal("Failed to create event client: %v", err)
+			}
+			defer client.Close()
+			streams = client
+		}
+		snapshots := snapshot.New(store, streams, snapshot.LocationsFromEnv()...)
+
+		if command == "create" {
+			utils.Info("Snapshotting to %s", store.Name())
+			manifest, err := snapshots.Create(ctx, opts)
+			if err != nil {
+				utils.Fatal("Snapshot failed: %v", err)
+			}
+			printJSON(manifest)
+			return
+		}
+		utils.Info("Restoring snapshot %s from %s", args[0], store.Name())
+		result, err := snapshots.Restore(ctx, args[0], opts)
+		if result != nil {
+			printJSON(result)
+		}
+		if err != nil {
+			utils.Fatal("Restore failed: %v", err)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -6,6 +6,26 @@ import os
 
 from .provider import DataProvider
 
+# ISO codes accepted in currency pairs such as EURUSD or EUR/USD
+FOREX_CURRENCIES = {
+    'USD', 'EUR', 'JPY', 'GBP', 'AUD', 'CAD', 'CHF', 'NZD', 'SEK', 'NOK', 'DKK', 'HKD',
+    'SGD', 'MXN', 'ZAR', 'CNH', 'CNY', 'TRY', 'PLN', 'CZK', 'HUF', 'ILS', 'INR', 'KRW',
+    'BRL',
+}
+
+
+def parse_forex_pair(ticker):
+    """Split a currency pair into (base, quote), or return None for other tickers"""
+    symbol = ticker.upper().strip()
+    for separator in ('/', '_', '-'):
+        symbol = symbol.replace(separator, '')
+    if len(symbol) != 6:
+        return None
+    base, quote = symbol[:3], symbol[3:]
+    if base == quote or base not in FOREX_CURRENCIES or quote not in FOREX_CURRENCIES:
+        return None
+    return base, quote
+
 class AlphaVantageDataProvider(DataProvider):
     """Data provider that fetches intraday and options data from Alpha Vantage API"""
 
@@ -66,23 +86,38 @@ class AlphaVantageDataProvider(DataProvider):
             need_aggregation = False
 
         # Define API parameters
-        function = 'TIME_SERIES_INTRADAY' if used_interval != 'daily' else 'TIME_SERIES_DAILY'
-
-        params = {
-            'function': function,
-            'symbol': ticker,
-            'outputsize': 'full',  # Get full data
-            'apikey': self.api_key,
-            'datatype': 'json',
-            'adjusted': 'true',
-            'extended_hours': 'false',
-        }
+        forex_pair = parse_forex_pair(ticker)
+        if forex_pair:
+            # Currency pairs use the FX endpoints, which trade 24x5 and carry no volume
+            function = 'FX_INTRADAY' if used_interval != 'daily' else 'FX_DAILY'
+            params = {
+                'function': function,
+                'from_symbol': forex_pair[0],
+                'to_symbol': forex_pair[1],
+                'outputsize': 'full',
+                'apikey': self.api_key,
+                'datatype': 'json',
+            }
+            if function == 'FX_INTRADAY':
+                params['interval'] = used_interval
+        else:
+            function = 'TIME_SERIES_INTRADAY' if used_interval != 'daily' else 'TIME_SERIES_DAILY'
+
+            params = {
+                'function': function,
+                'symbol': ticker,
+                'outputsize': 'full',  # Get full data
+                'apikey': self.api_key,
+                'datatype': 'json',
+                'adjusted': 'true',
+                'extended_hours': 'false',
+            }
 
-        # Add interval only for intraday data
-        if function == 'TIME_SERIES_INTRADAY':
-            params['interval'] = used_interval
-            # For recent month data - adjust as needed
-            params['month'] = datetime.now().strftime('%Y-%m')
+            # Add interval only for intraday data
+            if function == 'TIME_SERIES_INTRADAY':
+                params['interval'] = used_interval
+                # For recent month data - adjust as needed
+                params['month'] = datetime.now().strftime('%Y-%m')
 
         try:
             # Make request to Alpha Vantage API
@@ -96,7 +131,10 @@ class AlphaVantageDataProvider(DataProvider):
                 raise ValueError(f"Alpha Vantage API error: {data['Error Message']}")
 
             # Extract time series data
-            time_series_key = f"Time Series ({used_interval})" if function == 'TIME_SERIES_INTRADAY' else "Time Series (Daily)"
+            if forex_pair:
+                time_series_key = f"Time Series FX ({used_interval})" if function == 'FX_INTRADAY' else "Time Series FX (Daily)"
+            else:
+                time_series_key = f"Time Series ({used_interval})" if function == 'TIME_SERIES_INTRADAY' else "Time Series (Daily)"
             if time_series_key not in data:
                 raise ValueError(f"No time series data found. Available keys: {data.keys()}")
 
@@ -119,6 +157,8 @@ class AlphaVantageDataProvider(DataProvider):
             # Convert types
             for col in ['open', 'high', 'low', 'close']:
                 df[col] = pd.to_numeric(df[col])
+            if 'volume' not in df.columns:
+                df['volume'] = 0
             df['volume'] = pd.to_numeric(df['volume'], downcast='integer')
 
             # Convert index to datetime and sort
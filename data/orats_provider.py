@@ -123,4 +123,49 @@ class ORATSDataProvider(DataProvider):
 
         except requests.exceptions.RequestException as e:
             print(f"Error fetching options data: {e}")
-            return None
\ No newline at end of file
+            return None
+
+    def get_historical_options_data(self, ticker, trade_date, min_dte=1, max_dte=60):
+        """
+        Get an end-of-day options chain snapshot for a past trade date
+
+        Parameters:
+        ticker (str): Stock ticker symbol
+        trade_date (str): Trade date (YYYY-MM-DD)
+        min_dte (int): Minimum days to expiration to keep
+        max_dte (int): Maximum days to expiration to keep
+
+        Returns:
+        pandas.DataFrame: Options data with the same columns as get_options_data
+        """
+        endpoint = f"{self.base_url}/hist/strikes"
+
+        params = {
+            'ticker': ticker,
+            'tradeDate': trade_date
+        }
+
+        try:
+            response = requests.get(endpoint, headers=self.headers, params=params)
+            response.raise_for_status()
+            data = response.json()
+
+            if 'data' not in data or len(data['data']) == 0:
+                return None
+
+            df = pd.DataFrame(data['data'])
+
+            if 'dte' in df.columns:
+                df = df[(df['dte'] >= min_dte) & (df['dte'] <= max_dte)]
+
+            relevant_columns = [
+                'tradeDate', 'expirDate', 'strike', 'delta', 'gamma',
+                'ticker', 'stockPrice', 'iv', 'callValue', 'putValue'
+            ]
+
+            df = df[[col for col in relevant_columns if col in df.columns]]
+            return df
+
+        except requests.exceptions.RequestException as e:
+            print(f"Error fetching historical options data: {e}")
+            return None
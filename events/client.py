@@ -1,12 +1,52 @@
 # events/client.py
+import gzip
+import hashlib
 import json
 import asyncio
 import nats
+import os
 import re
-from datetime import datetime
+import socket
+from datetime import datetime, timezone
 from nats.js.api import StreamConfig
 from typing import Dict, Any, Callable, Optional, Union, List
 
+
+# Build of this service, reported in heartbeats and event headers
+BUILD_VERSION = os.getenv('TRADINGLAB_VERSION', 'dev')
+BUILD_COMMIT = os.getenv('TRADINGLAB_COMMIT', '')
+
+
+def _decode_payload(msg) -> str:
+    """Return a message's JSON payload, decompressing it when the Go publisher gzipped it."""
+    headers = msg.headers or {}
+    if headers.get('Content-Encoding') == 'gzip':
+        return gzip.decompress(msg.data).decode()
+    return msg.data.decode()
+
+
+def utc_timestamp(value: Optional[datetime] = None) -> str:
+    """Format a time as UTC RFC 3339, the timestamp format used in all event payloads.
+
+    Naive datetimes are taken as local time.
+    """
+    value = value or datetime.now(timezone.utc)
+    return value.astimezone(timezone.utc).isoformat().replace('+00:00', 'Z')
+
+
+def _dedup_headers(subject: str, data: Dict[str, Any], payload: bytes) -> Dict[str, str]:
+    """Build a Nats-Msg-Id header from the subject, event timestamp and payload hash.
+
+    As with the Go publishers, a publish retried after a reconnect is stored once.
+    """
+    timestamp = next((str(data[key]) for key in ('timestamp', 'date', 'time') if key in data), '')
+    digest = hashlib.blake2b(payload, digest_size=8).hexdigest()
+    return {
+        'Nats-Msg-Id': f"{subject}:{timestamp}:{digest}",
+        'Tradinglab-Version': BUILD_VERSION,
+    }
+
+
 class EventClient:
     """Client for interacting with the event messaging system."""
 
@@ -17,6 +57,7 @@ class EventClient:
         self.js = None
         self.subscriptions = {}
         self.request_reply_callbacks = {}
+        self._heartbeat_task = None
 
     async def connect(self):
         """Connect to NATS server and set up JetStream."""
@@ -85,7 +126,7 @@ class EventClient:
         # Define streams with explicit configuration
         streams = [
             # Market data streams
-            ("MARKET_LIVE", ["market.live.*"], {
+            ("MARKET_LIVE", ["market.live.*", "market.bars.>"], {
                 "max_msgs_per_subject": 1,  # Only keep latest message per subject
                 "max_msgs": 10000,
                 "max_bytes": 1024 * 1024 * 50,  # 50MB
@@ -156,7 +197,7 @@ class EventClient:
 
         subject = f"market.live.{ticker}"
         payload = json.dumps(data).encode()
-        await self.js.publish(subject, payload)
+        await self.js.publish(subject, payload, headers=_dedup_headers(subject, data, payload))
 
     async def publish_signal(self, ticker: str, signal_data: Dict[str, Any]) -> None:
         """Publish a trading signal."""
@@ -165,7 +206,7 @@ class EventClient:
 
         subject = f"signals.{ticker}"
         payload = json.dumps(signal_data).encode()
-        await self.js.publish(subject, payload)
+        await self.js.publish(subject, payload, headers=_dedup_headers(subject, signal_data, payload))
 
     async def publish_recommendation(self, ticker: str, recommendation_data: Dict[str, Any]) -> None:
         """Publish an options recommendation."""
@@ -174,10 +215,16 @@ class EventClient:
 
         subject = f"recommendations.{ticker}"
         payload = json.dumps(recommendation_data).encode()
-        await self.js.publish(subject, payload)
+        await self.js.publish(subject, payload, headers=_dedup_headers(subject, recommendation_data, payload))
+
+    async def request_historical_data(self, ticker: str, days: int, interval: str = '15min',
+                                      priority: str = 'normal', as_of: Optional[str] = None) -> None:
+        """Request historical data for a ticker.
 
-    async def request_historical_data(self, ticker: str, days: int, interval: str = '15min') -> None:
-        """Request historical data for a ticker."""
+        priority is 'interactive', 'normal' or 'backfill'; the market data
+        service serves higher priorities first. as_of (RFC 3339) asks for the
+        bars as they were known then rather than as fetched now.
+        """
         import logging
         
         if not self.js:
@@ -191,20 +238,24 @@ class EventClient:
             "ticker": ticker,
             "days": days,
             "interval": interval,
-            "timestamp": str(datetime.now()),
-            "request_id": request_id
+            "timestamp": utc_timestamp(),
+            "request_id": request_id,
+            "priority": priority
         }
+        if as_of:
+            request["as_of"] = as_of
         
         payload = json.dumps(request).encode()
         
-        # Add retry logic for the publish operation
+        # Add retry logic for the publish operation; retries share a message ID so the request is stored once
         max_retries = 3
         backoff_base = 1  # seconds
         
         for attempt in range(max_retries):
             try:
                 # Publish with acknowledgment and timeout
-                ack = await self.js.publish(subject, payload, timeout=5.0)
+                ack = await self.js.publish(subject, payload, timeout=5.0,
+                                            headers={'Nats-Msg-Id': f"{subject}:{request_id}"})
                 logging.info(f"Historical data request for {ticker} published successfully: stream={ack.stream}, seq={ack.seq}")
                 return
             except Exception as e:
@@ -227,7 +278,7 @@ class EventClient:
 
         async def message_handler(msg):
             try:
-                data = json.loads(msg.data.decode())
+                data = json.loads(_decode_payload(msg))
                 await callback(data)
                 await msg.ack()
             except Exception as e:
@@ -261,7 +312,7 @@ class EventClient:
 
         async def message_handler(msg):
             try:
-                data = json.loads(msg.data.decode())
+                data = json.loads(_decode_payload(msg))
                 await callback(data)
                 await msg.ack()
             except Exception as e:
@@ -294,7 +345,7 @@ class EventClient:
 
         async def message_handler(msg):
             try:
-                data = json.loads(msg.data.decode())
+                data = json.loads(_decode_payload(msg))
                 await callback(data)
                 await msg.ack()
             except Exception as e:
@@ -327,7 +378,7 @@ class EventClient:
 
         async def message_handler(msg):
             try:
-                data = json.loads(msg.data.decode())
+                data = json.loads(_decode_payload(msg))
                 await callback(data)
                 await msg.ack()
             except Exception as e:
@@ -351,8 +402,55 @@ class EventClient:
             # Return None instead of raising the exception
             return None
 
+    async def publish_backtest_progress(self, job_id: str, progress: Dict[str, Any]) -> None:
+        """Publish a backtest job's progress on backtest.<job_id>.
+
+        Progress is a plain NATS message, outside every stream; the gateway
+        relays it to the job owner's WebSocket connections.
+        """
+        if not self.nc:
+            raise RuntimeError("Not connected to NATS")
+
+        progress = dict(progress, job_id=job_id, timestamp=utc_timestamp())
+        await self.nc.publish(f"backtest.{job_id}", json.dumps(progress).encode())
+
+    def start_heartbeat(self, service: str, interval: float = 10.0,
+                        stats: Optional[Callable[[], Dict[str, Any]]] = None) -> None:
+        """Publish a heartbeat for the service on ops.heartbeat.<service> every interval seconds.
+
+        Heartbeats are plain NATS messages, outside every stream; the event hub
+        tracks them to report which services are alive. stats, if given,
+        returns a small digest sent with each heartbeat.
+        """
+        import logging
+        subject = f"ops.heartbeat.{service}"
+        heartbeat = {
+            'service': service,
+            'version': BUILD_VERSION,
+            'commit': BUILD_COMMIT,
+            'instance': f"{socket.gethostname()}-{os.getpid()}",
+            'started_at': utc_timestamp(),
+            'interval_seconds': interval,
+        }
+
+        async def beat():
+            while self.nc and not self.nc.is_closed:
+                heartbeat['timestamp'] = utc_timestamp()
+                if stats:
+                    heartbeat['stats'] = stats()
+                try:
+                    await self.nc.publish(subject, json.dumps(heartbeat).encode())
+                except Exception as e:
+                    logging.debug(f"Failed to publish heartbeat on {subject}: {e}")
+                await asyncio.sleep(interval)
+
+        self._heartbeat_task = asyncio.ensure_future(beat())
+
     async def close(self) -> None:
         """Close all subscriptions and connection."""
+        if self._heartbeat_task:
+            self._heartbeat_task.cancel()
+
         for sub in self.subscriptions.values():
             await sub.unsubscribe()
 
@@ -0,0 +1,118 @@
+// pkg/alerts/alerts.go
+package alerts
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/recommendation"
+)
+
+// Signal priorities. Signals are normal priority unless their publisher
+// marks them high, e.g. a scan job or alert configured as high priority.
+const (
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// collectorRetention is how many days of signals a Collector keeps
+const collectorRetention = 7
+
+// Signal is a signal event as published on signals.{ticker}
+type Signal struct {
+	Ticker     string    `json:"ticker"`
+	Strategy   string    `json:"strategy"`
+	SignalType string    `json:"signal_type"`
+	EntryPrice float64   `json:"entry_price"`
+	Stoploss   float64   `json:"stoploss"`
+	Interval   string    `json:"interval,omitempty"`
+	Date       string    `json:"date"`
+	Timestamp  string    `json:"timestamp,omitempty"`
+	Priority   string    `json:"priority,omitempty"`
+	Source     string    `json:"source,omitempty"`
+	Confidence *float64  `json:"confidence,omitempty"` // Signal model's score from 0 to 1; nil when unscored
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// HighPriority reports whether the signal warrants an immediate alert
+func (s Signal) HighPriority() bool {
+	return strings.EqualFold(s.Priority, PriorityHigh)
+}
+
+// PriceAlert is a triggered price rule
+type PriceAlert struct {
+	RuleID    string    `json:"rule_id"`
+	Ticker    string    `json:"ticker"`
+	Condition string    `json:"condition"`
+	Level     float64   `json:"level"`
+	Price     float64   `json:"price"`
+	At        time.Time `json:"at"`
+}
+
+// Digest is one subscriber's summary of a day's signals and recommendations
+type Digest struct {
+	Date            string                          `json:"date"`
+	Subscriber      Subscriber                      `json:"subscriber"`
+	Signals         []Signal                        `json:"signals"`
+	Recommendations []recommendation.Recommendation `json:"recommendations"`
+}
+
+// Empty reports whether there is nothing to send
+func (d Digest) Empty() bool {
+	return len(d.Signals) == 0 && len(d.Recommendations) == 0
+}
+
+// NewDigest builds a subscriber's digest from the day's activity, keeping
+// the tickers on their watchlist
+func NewDigest(date string, sub Subscriber, signals []Signal, recs []recommendation.Recommendation) Digest {
+	d := Digest{Date: date, Subscriber: sub, Signals: []Signal{}, Recommendations: []recommendation.Recommendation{}}
+	for _, s := range signals {
+		if sub.Watches(s.Ticker) {
+			d.Signals = append(d.Signals, s)
+		}
+	}
+	for _, r := range recs {
+		if sub.Watches(r.Ticker) {
+			d.Recommendations = append(d.Recommendations, r)
+		}
+	}
+	return d
+}
+
+// Collector accumulates the day's signals for digests, by exchange session date
+type Collector struct {
+	mu      sync.Mutex
+	signals map[string][]Signal
+}
+
+// NewCollector creates an empty collector
+func NewCollector() *Collector {
+	return &Collector{signals: make(map[string][]Signal)}
+}
+
+// Add records a signal under the session date it was received on, dropping
+// days past the retention window
+func (c *Collector) Add(s Signal) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	date := market.SessionDate(s.ReceivedAt)
+	c.signals[date] = append(c.signals[date], s)
+
+	cutoff := s.ReceivedAt.In(market.ExchangeLocation()).AddDate(0, 0, -collectorRetention).Format(market.SessionDateLayout)
+	for d := range c.signals {
+		if d < cutoff {
+			delete(c.signals, d)
+		}
+	}
+}
+
+// Signals returns the signals received on a session date, oldest first
+func (c *Collector) Signals(date string) []Signal {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Signal(nil), c.signals[date]...)
+}
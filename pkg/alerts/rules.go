@@ -0,0 +1,324 @@
+// pkg/alerts/rules.go
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// Rule kinds
+const (
+	RuleSignal = "signal" // Fires on each matching signal
+	RulePrice  = "price"  // Fires once when the price crosses a level
+)
+
+// Price rule conditions
+const (
+	ConditionAbove = "above"
+	ConditionBelow = "below"
+)
+
+// Delivery channels
+const (
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+)
+
+// Rule is a subscriber's alert and how to deliver it
+type Rule struct {
+	ID            string     `json:"id"`
+	SubscriberID  string     `json:"subscriber_id"`
+	Kind          string     `json:"kind"`
+	Ticker        string     `json:"ticker"`
+	Strategy      string     `json:"strategy,omitempty"`       // Signal rules: empty matches any strategy
+	SignalType    string     `json:"signal_type,omitempty"`    // Signal rules: LONG, SHORT or empty for both
+	MinConfidence float64    `json:"min_confidence,omitempty"` // Signal rules: lowest model confidence that fires; unscored signals never do
+	Condition     string     `json:"condition,omitempty"`      // Price rules: above or below
+	Price         float64    `json:"price,omitempty"`          // Price rules: the level to cross
+	Channels      []string   `json:"channels"`
+	TriggeredAt   *time.Time `json:"triggered_at,omitempty"` // Price rules stay quiet once triggered until re-armed
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Active reports whether the rule can still fire
+func (r Rule) Active() bool {
+	return r.Kind == RuleSignal || r.TriggeredAt == nil
+}
+
+// Delivers reports whether the rule delivers on a channel
+func (r Rule) Delivers(channel string) bool {
+	for _, c := range r.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesSignal reports whether a signal rule fires for a signal
+func (r Rule) MatchesSignal(s Signal) bool {
+	return r.Kind == RuleSignal &&
+		r.Ticker == s.Ticker &&
+		(r.Strategy == "" || strings.EqualFold(r.Strategy, s.Strategy)) &&
+		(r.SignalType == "" || strings.EqualFold(r.SignalType, s.SignalType)) &&
+		(r.MinConfidence == 0 || (s.Confidence != nil && *s.Confidence >= r.MinConfidence))
+}
+
+// crossed reports whether an active price rule fires at a price
+func (r Rule) crossed(price float64) bool {
+	if r.Kind != RulePrice || !r.Active() || price <= 0 {
+		return false
+	}
+	if r.Condition == ConditionAbove {
+		return price >= r.Price
+	}
+	return price <= r.Price
+}
+
+// validate normalizes a new rule and checks it is complete
+func (r *Rule) validate() error {
+	ticker, err := market.ValidateTicker(r.Ticker)
+	if err != nil {
+		return err
+	}
+	r.Ticker = ticker
+	if r.SubscriberID == "" {
+		return fmt.Errorf("subscriber_id is required")
+	}
+
+	switch r.Kind = strings.ToLower(r.Kind); r.Kind {
+	case RuleSignal:
+		r.SignalType = strings.ToUpper(r.SignalType)
+		r.Condition, r.Price = "", 0
+		if r.MinConfidence < 0 || r.MinConfidence > 1 {
+			return fmt.Errorf("min_confidence must be between 0 and 1")
+		}
+	case RulePrice:
+		r.Condition = strings.ToLower(r.Condition)
+		if r.Condition != ConditionAbove && r.Condition != ConditionBelow {
+			return fmt.Errorf("condition must be above or below")
+		}
+		if r.Price <= 0 {
+			return fmt.Errorf("price must be positive")
+		}
+		r.Strategy, r.SignalType, r.MinConfidence = "", "", 0
+	default:
+		return fmt.Errorf("kind must be signal or price")
+	}
+
+	if len(r.Channels) == 0 {
+		r.Channels = []string{ChannelEmail}
+	}
+	for i, c := range r.Channels {
+		c = strings.ToLower(c)
+		if c != ChannelEmail && c != ChannelPush {
+			return fmt.Errorf("unsupported channel %q, expected email or push", c)
+		}
+		r.Channels[i] = c
+	}
+	return nil
+}
+
+// RuleStore keeps alert rules in memory and optionally persists them to a JSON file
+type RuleStore struct {
+	mu     sync.RWMutex
+	rules  map[string]Rule
+	path   string
+	nextID int64
+}
+
+// NewRuleStore creates a rule store. If path is non-empty, existing rules
+// are loaded from it and every change is written back.
+func NewRuleStore(path string) (*RuleStore, error) {
+	s := &RuleStore{
+		rules: make(map[string]Rule),
+		path:  path,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules file: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse alert rules file: %w", err)
+	}
+	for _, r := range rules {
+		s.rules[r.ID] = r
+	}
+	s.nextID = int64(len(rules))
+	return s, nil
+}
+
+// Add creates a rule
+func (s *RuleStore) Add(r Rule) (Rule, error) {
+	if err := r.validate(); err != nil {
+		return Rule{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.nextID++
+	r.ID = fmt.Sprintf("rule-%d-%d", now.Unix(), s.nextID)
+	r.TriggeredAt = nil
+	r.CreatedAt = now
+
+	s.rules[r.ID] = r
+	return r, s.persist()
+}
+
+// Get returns a single rule
+func (s *RuleStore) Get(id string) (Rule, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.rules[id]
+	return r, ok
+}
+
+// Delete removes a rule
+func (s *RuleStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rules[id]; !ok {
+		return fmt.Errorf("alert rule %s not found", id)
+	}
+	delete(s.rules, id)
+	return s.persist()
+}
+
+// DeleteSubscriber removes every rule of a subscriber
+func (s *RuleStore) DeleteSubscriber(subscriberID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, r := range s.rules {
+		if r.SubscriberID == subscriberID {
+			delete(s.rules, id)
+		}
+	}
+	return s.persist()
+}
+
+// Rearm lets a triggered price rule fire again
+func (s *RuleStore) Rearm(id string) (Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.rules[id]
+	if !ok {
+		return Rule{}, fmt.Errorf("alert rule %s not found", id)
+	}
+	r.TriggeredAt = nil
+	s.rules[id] = r
+	return r, s.persist()
+}
+
+// List returns a subscriber's rules, or every rule for an empty ID, oldest first
+func (s *RuleStore) List(subscriberID string) []Rule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rules := make([]Rule, 0)
+	for _, r := range s.rules {
+		if subscriberID == "" || r.SubscriberID == subscriberID {
+			rules = append(rules, r)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CreatedAt.Before(rules[j].CreatedAt) })
+	return rules
+}
+
+// MatchSignal returns the signal rules that fire for a signal
+func (s *RuleStore) MatchSignal(sig Signal) []Rule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []Rule
+	for _, r := range s.rules {
+		if r.MatchesSignal(sig) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// TriggerPrice marks the price rules a ticker's price crosses as triggered
+// and returns them, so each fires once
+func (s *RuleStore) TriggerPrice(ticker string, price float64, at time.Time) ([]Rule, error) {
+	// Most ticks trigger nothing, so check under the read lock first
+	s.mu.RLock()
+	pending := false
+	for _, r := range s.rules {
+		if r.Ticker == ticker && r.crossed(price) {
+			pending = true
+			break
+		}
+	}
+	s.mu.RUnlock()
+	if !pending {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var triggered []Rule
+	for id, r := range s.rules {
+		if r.Ticker != ticker || !r.crossed(price) {
+			continue
+		}
+		when := at
+		r.TriggeredAt = &when
+		s.rules[id] = r
+		triggered = append(triggered, r)
+	}
+	return triggered, s.persist()
+}
+
+// persist writes all rules to the file. Caller holds the lock.
+func (s *RuleStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	rules := make([]Rule, 0, len(s.rules))
+	for _, r := range s.rules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CreatedAt.Before(rules[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create alert rules directory: %w", err)
+	}
+
+	// Write atomically so a crash never leaves a truncated file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write alert rules file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,297 @@
+// pkg/alerts/subscribers.go
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/notify"
+)
+
+// Subscriber is a user who opted in to email notifications for a watchlist
+type Subscriber struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Email     string    `json:"email"`
+	Watchlist []string  `json:"watchlist"` // Empty means every ticker
+	Immediate bool      `json:"immediate"` // Email high-priority signals as they arrive
+	Digest    bool      `json:"digest"`    // Email a daily digest of signals and recommendations
+	Devices   []Device  `json:"devices"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Device is a mobile device registered for push notifications
+type Device struct {
+	Token        string    `json:"token"`
+	Platform     string    `json:"platform"` // notify.PlatformFCM or notify.PlatformAPNs
+	Name         string    `json:"name,omitempty"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// Watches reports whether the subscriber follows a ticker
+func (s Subscriber) Watches(ticker string) bool {
+	if len(s.Watchlist) == 0 {
+		return true
+	}
+	ticker = market.NormalizeTicker(ticker)
+	for _, t := range s.Watchlist {
+		if t == ticker {
+			return true
+		}
+	}
+	return false
+}
+
+// Store keeps subscribers in memory and optionally persists them to a JSON file
+type Store struct {
+	mu          sync.RWMutex
+	subscribers map[string]Subscriber
+	path        string
+	onChange    func(id string)
+}
+
+// NewStore creates a subscriber store. If path is non-empty, existing
+// subscribers are loaded from it and every change is written back.
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		subscribers: make(map[string]Subscriber),
+		path:        path,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscribers file: %w", err)
+	}
+
+	var subscribers []Subscriber
+	if err := json.Unmarshal(data, &subscribers); err != nil {
+		return nil, fmt.Errorf("failed to parse subscribers file: %w", err)
+	}
+	for _, sub := range subscribers {
+		if sub.Devices == nil {
+			sub.Devices = []Device{}
+		}
+		s.subscribers[sub.ID] = sub
+	}
+	return s, nil
+}
+
+// Save creates or replaces a subscriber. Subscribers are identified by
+// email address, so saving a known address updates its preferences.
+func (s *Store) Save(sub Subscriber) (Subscriber, error) {
+	addr, err := mail.ParseAddress(sub.Email)
+	if err != nil {
+		return Subscriber{}, fmt.Errorf("invalid email address %q", sub.Email)
+	}
+	sub.Email = strings.ToLower(addr.Address)
+	sub.ID = subscriberID(sub.Email)
+	if sub.Watchlist, err = normalizeWatchlist(sub.Watchlist); err != nil {
+		return Subscriber{}, err
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	sub.CreatedAt = now
+	sub.Devices = []Device{}
+	if existing, ok := s.subscribers[sub.ID]; ok {
+		// Devices are managed through RegisterDevice and UnregisterDevice
+		sub.CreatedAt = existing.CreatedAt
+		sub.Devices = existing.Devices
+	}
+	sub.UpdatedAt = now
+
+	s.subscribers[sub.ID] = sub
+	err = s.persist()
+	handler := s.onChange
+	s.mu.Unlock()
+
+	if handler != nil {
+		handler(sub.ID)
+	}
+	return sub, err
+}
+
+// Get returns a single subscriber
+func (s *Store) Get(id string) (Subscriber, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sub, ok := s.subscribers[id]
+	return sub, ok
+}
+
+// Lookup returns a subscriber by ID or by email address
+func (s *Store) Lookup(key string) (Subscriber, bool) {
+	if sub, ok := s.Get(key); ok {
+		return sub, true
+	}
+	return s.Get(subscriberID(strings.ToLower(strings.TrimSpace(key))))
+}
+
+// OnChange registers a callback invoked with a subscriber's ID after it is
+// saved or deleted
+func (s *Store) OnChange(handler func(id string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = handler
+}
+
+// Delete removes a subscriber
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	if _, ok := s.subscribers[id]; !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("subscriber %s not found", id)
+	}
+	delete(s.subscribers, id)
+	err := s.persist()
+	handler := s.onChange
+	s.mu.Unlock()
+
+	if handler != nil {
+		handler(id)
+	}
+	return err
+}
+
+// RegisterDevice adds a push device to a subscriber, replacing any earlier
+// registration of the same token
+func (s *Store) RegisterDevice(id string, d Device) (Subscriber, error) {
+	if d.Token == "" {
+		return Subscriber{}, fmt.Errorf("device token is required")
+	}
+	switch d.Platform = strings.ToLower(d.Platform); d.Platform {
+	case notify.PlatformFCM, notify.PlatformAPNs:
+	case "android":
+		d.Platform = notify.PlatformFCM
+	case "ios":
+		d.Platform = notify.PlatformAPNs
+	default:
+		return Subscriber{}, fmt.Errorf("unsupported platform %q, expected fcm or apns", d.Platform)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscribers[id]
+	if !ok {
+		return Subscriber{}, fmt.Errorf("subscriber %s not found", id)
+	}
+
+	// A token belongs to one device, which belongs to one subscriber
+	s.removeToken(d.Token)
+	sub = s.subscribers[id]
+	d.RegisteredAt = time.Now()
+	sub.Devices = append(sub.Devices, d)
+	sub.UpdatedAt = d.RegisteredAt
+	s.subscribers[id] = sub
+	return sub, s.persist()
+}
+
+// UnregisterDevice removes a device token from whichever subscriber holds
+// it, e.g. when the app signs out or the platform reports it invalid
+func (s *Store) UnregisterDevice(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.removeToken(token) {
+		return fmt.Errorf("device %s not found", token)
+	}
+	return s.persist()
+}
+
+// removeToken drops a device token from every subscriber, reporting
+// whether it was found. Caller holds the lock.
+func (s *Store) removeToken(token string) bool {
+	found := false
+	for id, sub := range s.subscribers {
+		devices := make([]Device, 0, len(sub.Devices))
+		for _, d := range sub.Devices {
+			if d.Token == token {
+				found = true
+				continue
+			}
+			devices = append(devices, d)
+		}
+		if len(devices) != len(sub.Devices) {
+			sub.Devices = devices
+			s.subscribers[id] = sub
+		}
+	}
+	return found
+}
+
+// List returns all subscribers ordered by email
+func (s *Store) List() []Subscriber {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.list()
+}
+
+// list returns all subscribers ordered by email. Caller holds the lock.
+func (s *Store) list() []Subscriber {
+	subscribers := make([]Subscriber, 0, len(s.subscribers))
+	for _, sub := range s.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	sort.Slice(subscribers, func(i, j int) bool { return subscribers[i].Email < subscribers[j].Email })
+	return subscribers
+}
+
+// persist writes all subscribers to the file. Caller holds the lock.
+func (s *Store) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create subscribers directory: %w", err)
+	}
+
+	// Write atomically so a crash never leaves a truncated file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write subscribers file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// subscriberID derives a stable ID from an email address
+func subscriberID(email string) string {
+	h := fnv.New64a()
+	h.Write([]byte(email))
+	return fmt.Sprintf("sub-%016x", h.Sum64())
+}
+
+// normalizeWatchlist validates and de-duplicates tickers, ignoring blanks
+func normalizeWatchlist(tickers []string) ([]string, error) {
+	nonBlank := make([]string, 0, len(tickers))
+	for _, t := range tickers {
+		if strings.TrimSpace(t) != "" {
+			nonBlank = append(nonBlank, t)
+		}
+	}
+	return market.ValidateTickers(nonBlank)
+}
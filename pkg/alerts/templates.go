@@ -0,0 +1,235 @@
+// pkg/alerts/templates.go
+package alerts
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/myapp/tradinglab/pkg/notify"
+)
+
+// Template file names looked up in a template directory. Each overrides
+// the built-in template of the same name.
+const (
+	alertHTMLFile  = "alert.html"
+	alertTextFile  = "alert.txt"
+	digestHTMLFile = "digest.html"
+	digestTextFile = "digest.txt"
+	priceHTMLFile  = "price.html"
+	priceTextFile  = "price.txt"
+)
+
+// pushBodyLimit caps push notification bodies, which platforms truncate anyway
+const pushBodyLimit = 180
+
+// templateFuncs are available to every template
+var templateFuncs = map[string]interface{}{
+	"money":   func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"percent": func(v *float64) string { return fmt.Sprintf("%.0f%%", *v*100) },
+}
+
+const defaultAlertHTML = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>{{.SignalType}} {{.Ticker}} at {{money .EntryPrice}}</h2>
+<table>
+<tr><td>Strategy</td><td>{{.Strategy}}</td></tr>
+{{if .Interval}}<tr><td>Interval</td><td>{{.Interval}}</td></tr>
+{{end}}<tr><td>Time</td><td>{{.Date}}</td></tr>
+<tr><td>Entry</td><td>{{money .EntryPrice}}</td></tr>
+{{if .Stoploss}}<tr><td>Stoploss</td><td>{{money .Stoploss}}</td></tr>
+{{end}}{{if .Confidence}}<tr><td>Confidence</td><td>{{percent .Confidence}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`
+
+const defaultAlertText = `{{.SignalType}} {{.Ticker}} at {{money .EntryPrice}}
+Strategy: {{.Strategy}}{{if .Interval}} ({{.Interval}}){{end}}
+Time: {{.Date}}
+{{if .Stoploss}}Stoploss: {{money .Stoploss}}
+{{end}}{{if .Confidence}}Confidence: {{percent .Confidence}}
+{{end}}`
+
+const defaultDigestHTML = `<!DOCTYPE html>
+<html>
+<head>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+</style>
+</head>
+<body>
+<h1>TradingLab digest {{.Date}}</h1>
+{{if .Subscriber.Name}}<p>Hi {{.Subscriber.Name}}, here is today's activity on your watchlist.</p>
+{{end}}
+<h2>Signals</h2>
+{{if .Signals}}<table>
+<tr><th>Ticker</th><th>Strategy</th><th>Time</th><th>Type</th><th>Entry</th><th>Stoploss</th></tr>
+{{range .Signals}}<tr><td>{{.Ticker}}</td><td>{{.Strategy}}</td><td>{{.Date}}</td><td>{{.SignalType}}</td><td>{{money .EntryPrice}}</td><td>{{money .Stoploss}}</td></tr>
+{{end}}</table>
+{{else}}<p>No signals today.</p>
+{{end}}
+<h2>Recommendations</h2>
+{{if .Recommendations}}<table>
+<tr><th>Ticker</th><th>Strategy</th><th>Option</th><th>Strike</th><th>Expiration</th><th>Price</th><th>Status</th></tr>
+{{range .Recommendations}}<tr><td>{{.Ticker}}</td><td>{{.Strategy}}</td><td>{{.OptionType}}</td><td>{{money .Strike}}</td><td>{{.Expiration}}</td><td>{{money .SuggestedPrice}}</td><td>{{.Status}}</td></tr>
+{{end}}</table>
+{{else}}<p>No recommendations today.</p>
+{{end}}</body>
+</html>
+`
+
+const defaultDigestText = `TradingLab digest {{.Date}}
+
+Signals: {{len .Signals}}
+{{range .Signals}}{{printf "%-6s" .Ticker}} {{.SignalType}} {{money .EntryPrice}} ({{.Strategy}}, {{.Date}})
+{{end}}
+Recommendations: {{len .Recommendations}}
+{{range .Recommendations}}{{printf "%-6s" .Ticker}} {{.OptionType}} {{money .Strike}} {{.Expiration}} at {{money .SuggestedPrice}} ({{.Status}})
+{{end}}`
+
+const defaultPriceHTML = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif">
+<h2>{{.Ticker}} is {{.Condition}} {{money .Level}}</h2>
+<p>Last price {{money .Price}} at {{.At.Format "2006-01-02 15:04:05 MST"}}</p>
+</body>
+</html>
+`
+
+const defaultPriceText = `{{.Ticker}} is {{.Condition}} {{money .Level}}
+Last price {{money .Price}} at {{.At.Format "2006-01-02 15:04:05 MST"}}
+`
+
+// Templates renders alert and digest emails
+type Templates struct {
+	alertHTML  *htmltemplate.Template
+	alertText  *texttemplate.Template
+	digestHTML *htmltemplate.Template
+	digestText *texttemplate.Template
+	priceHTML  *htmltemplate.Template
+	priceText  *texttemplate.Template
+}
+
+// LoadTemplates parses the built-in templates, replacing any that have a
+// file of the same name in dir. An empty dir uses the built-ins only.
+func LoadTemplates(dir string) (*Templates, error) {
+	source := func(name, def string) (string, error) {
+		if dir == "" {
+			return def, nil
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			return def, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read template %s: %w", name, err)
+		}
+		return string(data), nil
+	}
+	parseHTML := func(name, def string) (*htmltemplate.Template, error) {
+		src, err := source(name, def)
+		if err != nil {
+			return nil, err
+		}
+		return htmltemplate.New(name).Funcs(templateFuncs).Parse(src)
+	}
+	parseText := func(name, def string) (*texttemplate.Template, error) {
+		src, err := source(name, def)
+		if err != nil {
+			return nil, err
+		}
+		return texttemplate.New(name).Funcs(templateFuncs).Parse(src)
+	}
+
+	t := &Templates{}
+	var err error
+	if t.alertHTML, err = parseHTML(alertHTMLFile, defaultAlertHTML); err != nil {
+		return nil, err
+	}
+	if t.alertText, err = parseText(alertTextFile, defaultAlertText); err != nil {
+		return nil, err
+	}
+	if t.digestHTML, err = parseHTML(digestHTMLFile, defaultDigestHTML); err != nil {
+		return nil, err
+	}
+	if t.digestText, err = parseText(digestTextFile, defaultDigestText); err != nil {
+		return nil, err
+	}
+	if t.priceHTML, err = parseHTML(priceHTMLFile, defaultPriceHTML); err != nil {
+		return nil, err
+	}
+	if t.priceText, err = parseText(priceTextFile, defaultPriceText); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// AlertMessage renders the immediate alert for a signal
+func (t *Templates) AlertMessage(s Signal) (notify.Message, error) {
+	var html, text bytes.Buffer
+	if err := t.alertHTML.Execute(&html, s); err != nil {
+		return notify.Message{}, fmt.Errorf("failed to render alert: %w", err)
+	}
+	if err := t.alertText.Execute(&text, s); err != nil {
+		return notify.Message{}, fmt.Errorf("failed to render alert: %w", err)
+	}
+	return notify.Message{
+		Subject: fmt.Sprintf("TradingLab alert: %s %s at %.2f", s.SignalType, s.Ticker, s.EntryPrice),
+		Text:    text.String(),
+		HTML:    html.String(),
+		JSON:    s,
+	}, nil
+}
+
+// DigestMessage renders a daily digest
+func (t *Templates) DigestMessage(d Digest) (notify.Message, error) {
+	var html, text bytes.Buffer
+	if err := t.digestHTML.Execute(&html, d); err != nil {
+		return notify.Message{}, fmt.Errorf("failed to render digest: %w", err)
+	}
+	if err := t.digestText.Execute(&text, d); err != nil {
+		return notify.Message{}, fmt.Errorf("failed to render digest: %w", err)
+	}
+	return notify.Message{
+		Subject: "TradingLab digest " + d.Date,
+		Text:    text.String(),
+		HTML:    html.String(),
+		JSON:    d,
+	}, nil
+}
+
+// PriceMessage renders the alert for a triggered price rule
+func (t *Templates) PriceMessage(p PriceAlert) (notify.Message, error) {
+	var html, text bytes.Buffer
+	if err := t.priceHTML.Execute(&html, p); err != nil {
+		return notify.Message{}, fmt.Errorf("failed to render price alert: %w", err)
+	}
+	if err := t.priceText.Execute(&text, p); err != nil {
+		return notify.Message{}, fmt.Errorf("failed to render price alert: %w", err)
+	}
+	return notify.Message{
+		Subject: fmt.Sprintf("TradingLab alert: %s %s %.2f", p.Ticker, p.Condition, p.Level),
+		Text:    text.String(),
+		HTML:    html.String(),
+		JSON:    p,
+	}, nil
+}
+
+// PushMessage condenses a rendered alert into a push notification: the
+// subject as title and the start of the plain text as body
+func PushMessage(msg notify.Message, data map[string]string) notify.PushMessage {
+	body := strings.Join(strings.Fields(msg.Text), " ")
+	if runes := []rune(body); len(runes) > pushBodyLimit {
+		body = strings.TrimSpace(string(runes[:pushBodyLimit-3])) + "..."
+	}
+	return notify.PushMessage{Title: msg.Subject, Body: body, Data: data}
+}
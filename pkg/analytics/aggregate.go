@@ -0,0 +1,62 @@
+// pkg/analytics/aggregate.go
+package analytics
+
+import (
+	"sort"
+	"time"
+)
+
+// AggregateCandles combines bars into bars of a longer period, oldest first.
+// Intraday buckets are aligned to anchor past midnight (e.g. the session open)
+// in each candle's own time zone; periods of a day or more group by date.
+// Out-of-order input is sorted first so each bucket's open and close are right.
+func AggregateCandles(candles []Candle, period, anchor time.Duration) []Candle {
+	if period <= 0 {
+		return nil
+	}
+
+	ordered := func(i, j int) bool { return candles[i].Time.Before(candles[j].Time) }
+	if !sort.SliceIsSorted(candles, ordered) {
+		candles = append([]Candle(nil), candles...)
+		sort.SliceStable(candles, func(i, j int) bool { return candles[i].Time.Before(candles[j].Time) })
+	}
+
+	var result []Candle
+	var current time.Time
+	for _, c := range candles {
+		start := bucketStart(c.Time, period, anchor)
+		if len(result) == 0 || !start.Equal(current) {
+			current = start
+			bar := c
+			bar.Time = start
+			result = append(result, bar)
+			continue
+		}
+
+		bar := &result[len(result)-1]
+		if c.High > bar.High {
+			bar.High = c.High
+		}
+		if c.Low < bar.Low {
+			bar.Low = c.Low
+		}
+		bar.Close = c.Close
+		bar.Volume += c.Volume
+	}
+	return result
+}
+
+// bucketStart returns the start of the aggregated bar containing t
+func bucketStart(t time.Time, period, anchor time.Duration) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	if period >= 24*time.Hour {
+		return day
+	}
+
+	offset := t.Sub(day) - anchor
+	buckets := offset / period
+	if offset < 0 && offset%period != 0 {
+		buckets-- // Floor for bars before the anchor
+	}
+	return day.Add(anchor + buckets*period)
+}
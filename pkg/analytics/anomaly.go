@@ -0,0 +1,214 @@
+// pkg/analytics/anomaly.go
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Kinds of anomaly the detector flags
+const (
+	AnomalyPriceJump   = "price_jump"   // Return far outside the ticker's recent returns
+	AnomalyVolumeSpike = "volume_spike" // Volume far above the ticker's recent volume
+	AnomalyStale       = "stale"        // Bar older than the maximum age on arrival
+)
+
+// Floors under the spread of recent returns and volumes, so a ticker that has
+// barely moved is not flagged for an ordinary tick
+const (
+	minReturnStdDev       = 0.001 // Ten basis points per bar
+	minVolumeStdDevFactor = 0.1   // A tenth of the average volume
+)
+
+// AnomalyConfig sets how far a tick must stray before it is flagged
+type AnomalyConfig struct {
+	PriceSigma  float64       // Standard deviations of recent returns a price may jump; zero disables
+	VolumeSigma float64       // Standard deviations above recent volume a bar may trade; zero disables
+	Window      int           // Recent bars the statistics cover
+	MinSamples  int           // Bars needed before jumps and spikes are flagged
+	MaxAge      time.Duration // How old a bar may be on arrival; zero disables
+}
+
+// DefaultAnomalyConfig flags jumps of 6 and volume of 10 standard deviations
+// over the last 100 bars, once 20 have been seen. Stale bars are not flagged,
+// since delayed feeds lag by design.
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		PriceSigma:  6,
+		VolumeSigma: 10,
+		Window:      100,
+		MinSamples:  20,
+	}
+}
+
+// Validate checks the config
+func (c AnomalyConfig) Validate() error {
+	switch {
+	case c.PriceSigma < 0 || c.VolumeSigma < 0:
+		return fmt.Errorf("anomaly sigmas must not be negative")
+	case c.Window < 2:
+		return fmt.Errorf("anomaly window must be at least 2 bars")
+	case c.MinSamples < 2 || c.MinSamples > c.Window:
+		return fmt.Errorf("anomaly minimum samples must be from 2 to the window of %d bars", c.Window)
+	case c.MaxAge < 0:
+		return fmt.Errorf("anomaly maximum age must not be negative")
+	}
+	return nil
+}
+
+// Anomaly is one reason a bar was flagged
+type Anomaly struct {
+	Kind      string  `json:"kind"`
+	Value     float64 `json:"value"`     // Standard deviations for jumps and spikes, seconds of age for stale bars
+	Threshold float64 `json:"threshold"` // The limit Value exceeded, in the same unit
+}
+
+// AnomalyDetector flags bars that stray far from each ticker's recent
+// behaviour. Flagged bars are left out of the statistics and the previous
+// price, so one bad print does not mask the next or widen the bands. A jump
+// confirmed by the following bar is taken as a new price level.
+type AnomalyDetector struct {
+	mu      sync.Mutex
+	cfg     AnomalyConfig
+	tickers map[string]*tickHistory
+}
+
+// tickHistory is a ticker's recent accepted bars
+type tickHistory struct {
+	last    float64  // Close of the last accepted bar
+	suspect float64  // Close of the last bar flagged as a jump, until the next bar
+	returns *rolling // Log returns between accepted bars
+	volumes *rolling
+}
+
+// NewAnomalyDetector creates a detector
+func NewAnomalyDetector(cfg AnomalyConfig) *AnomalyDetector {
+	return &AnomalyDetector{
+		cfg:     cfg,
+		tickers: make(map[string]*tickHistory),
+	}
+}
+
+// Config returns the detector's config
+func (d *AnomalyDetector) Config() AnomalyConfig {
+	return d.cfg
+}
+
+// Check flags a bar received at now, returning nil for a normal bar.
+// Normal bars are folded into the ticker's statistics.
+func (d *AnomalyDetector) Check(ticker string, bar Candle, now time.Time) []Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var found []Anomaly
+	if d.cfg.MaxAge > 0 && !bar.Time.IsZero() {
+		if age := now.Sub(bar.Time); age > d.cfg.MaxAge {
+			found = append(found, Anomaly{Kind: AnomalyStale, Value: age.Seconds(), Threshold: d.cfg.MaxAge.Seconds()})
+		}
+	}
+	if bar.Close <= 0 {
+		return found
+	}
+
+	h, ok := d.tickers[ticker]
+	if !ok {
+		h = &tickHistory{returns: newRolling(d.cfg.Window), volumes: newRolling(d.cfg.Window)}
+		d.tickers[ticker] = h
+	}
+	if h.last == 0 {
+		if found == nil {
+			h.last = bar.Close
+		}
+		return found
+	}
+
+	ret := math.Log(bar.Close / h.last)
+	jump := false
+	if d.cfg.PriceSigma > 0 && h.returns.len() >= d.cfg.MinSamples {
+		mean, std := h.returns.stats()
+		sigma := math.Abs(ret-mean) / math.Max(std, minReturnStdDev)
+		if sigma > d.cfg.PriceSigma {
+			jump = true
+			// The bar after a jump confirms a new level if it is nearer to
+			// the jump than to the last accepted price
+			if h.suspect > 0 && math.Abs(math.Log(bar.Close/h.suspect)) < math.Abs(ret) {
+				jump = false
+				ret = math.Log(bar.Close / h.suspect)
+			} else {
+				found = append(found, Anomaly{Kind: AnomalyPriceJump, Value: sigma, Threshold: d.cfg.PriceSigma})
+			}
+		}
+	}
+	if d.cfg.VolumeSigma > 0 && bar.Volume > 0 && h.volumes.len() >= d.cfg.MinSamples {
+		mean, std := h.volumes.stats()
+		sigma := (bar.Volume - mean) / math.Max(std, mean*minVolumeStdDevFactor)
+		if sigma > d.cfg.VolumeSigma {
+			found = append(found, Anomaly{Kind: AnomalyVolumeSpike, Value: sigma, Threshold: d.cfg.VolumeSigma})
+		}
+	}
+
+	h.suspect = 0
+	if jump {
+		h.suspect = bar.Close
+	}
+	if found != nil {
+		return found
+	}
+	h.returns.add(ret)
+	if bar.Volume > 0 {
+		h.volumes.add(bar.Volume)
+	}
+	h.last = bar.Close
+	return nil
+}
+
+// rolling holds the last n values of a series
+type rolling struct {
+	values []float64
+	next   int
+	full   bool
+}
+
+// newRolling creates a series of n values
+func newRolling(n int) *rolling {
+	return &rolling{values: make([]float64, n)}
+}
+
+// add appends a value, dropping the oldest once full
+func (r *rolling) add(v float64) {
+	r.values[r.next] = v
+	r.next = (r.next + 1) % len(r.values)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// len returns how many values are held
+func (r *rolling) len() int {
+	if r.full {
+		return len(r.values)
+	}
+	return r.next
+}
+
+// stats returns the mean and sample standard deviation of the values
+func (r *rolling) stats() (mean, std float64) {
+	n := r.len()
+	if n == 0 {
+		return 0, 0
+	}
+	for _, v := range r.values[:n] {
+		mean += v
+	}
+	mean /= float64(n)
+	if n < 2 {
+		return mean, 0
+	}
+	var sq float64
+	for _, v := range r.values[:n] {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(n-1))
+}
@@ -0,0 +1,24 @@
+// pkg/analytics/candles.go
+package analytics
+
+import (
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// Candle represents a single OHLCV bar used as analytics input
+type Candle struct {
+	Time   time.Time `json:"time"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
+
+// ParseCandleTime parses a candle date in any format market.ParseTimestamp
+// accepts; dates without a zone are read as UTC
+func ParseCandleTime(value string) (time.Time, error) {
+	return market.ParseTimestamp(value, time.UTC)
+}
@@ -0,0 +1,105 @@
+// pkg/analytics/correlation.go
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// CorrelationMatrix holds the pairwise correlations of daily returns between
+// tickers over a window of sessions
+type CorrelationMatrix struct {
+	Window       int         `json:"window"`       // Daily returns requested per ticker
+	Observations int         `json:"observations"` // Returns used; fewer than Window when history is short
+	Start        time.Time   `json:"start"`        // Session of the first return
+	End          time.Time   `json:"end"`          // Session of the last return
+	Tickers      []string    `json:"tickers"`
+	Values       [][]float64 `json:"values"` // Values[i][j] correlates Tickers[i] with Tickers[j]
+}
+
+// Correlations computes the correlation matrix of close-to-close returns
+// over the last window sessions that every ticker traded. Candles are daily
+// and oldest first. Pairs involving a ticker whose price never moved have
+// no defined correlation and are reported as zero.
+func Correlations(candles map[string][]Candle, tickers []string, window int) CorrelationMatrix {
+	m := CorrelationMatrix{Window: window, Tickers: tickers, Values: make([][]float64, len(tickers))}
+	for i := range m.Values {
+		m.Values[i] = make([]float64, len(tickers))
+		m.Values[i][i] = 1
+	}
+
+	// Align closes on the sessions every ticker has
+	closes := make([]map[string]float64, len(tickers))
+	for i, ticker := range tickers {
+		closes[i] = make(map[string]float64, len(candles[ticker]))
+		for _, c := range candles[ticker] {
+			if c.Close > 0 {
+				closes[i][c.Time.Format("2006-01-02")] = c.Close
+			}
+		}
+	}
+	if len(tickers) == 0 {
+		return m
+	}
+	var days []string
+	for day := range closes[0] {
+		shared := true
+		for i := 1; i < len(tickers) && shared; i++ {
+			_, shared = closes[i][day]
+		}
+		if shared {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	if len(days) > window+1 {
+		days = days[len(days)-window-1:]
+	}
+	if len(days) < 2 {
+		return m
+	}
+	m.Observations = len(days) - 1
+	m.Start, _ = time.Parse("2006-01-02", days[1])
+	m.End, _ = time.Parse("2006-01-02", days[len(days)-1])
+
+	returns := make([][]float64, len(tickers))
+	for i := range tickers {
+		returns[i] = make([]float64, m.Observations)
+		for d := 1; d < len(days); d++ {
+			returns[i][d-1] = closes[i][days[d]]/closes[i][days[d-1]] - 1
+		}
+	}
+	for i := range tickers {
+		for j := i + 1; j < len(tickers); j++ {
+			r := pearson(returns[i], returns[j])
+			m.Values[i][j], m.Values[j][i] = r, r
+		}
+	}
+	return m
+}
+
+// pearson returns the correlation coefficient of two equal-length series,
+// or zero when either has no variance
+func pearson(x, y []float64) float64 {
+	n := float64(len(x))
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
@@ -0,0 +1,54 @@
+// pkg/analytics/indicators.go
+package analytics
+
+import "math"
+
+// TrueRange returns the true range of a candle given the previous close
+func TrueRange(c Candle, prevClose float64) float64 {
+	return math.Max(c.High-c.Low, math.Max(math.Abs(c.High-prevClose), math.Abs(c.Low-prevClose)))
+}
+
+// ATR computes the Average True Range over the given period using Wilder's smoothing.
+// Returns 0 if there are not enough candles.
+func ATR(candles []Candle, period int) float64 {
+	if period <= 0 || len(candles) < period+1 {
+		return 0
+	}
+
+	// Seed with the simple average of the first period true ranges
+	sum := 0.0
+	for i := 1; i <= period; i++ {
+		sum += TrueRange(candles[i], candles[i-1].Close)
+	}
+	atr := sum / float64(period)
+
+	// Wilder's smoothing for the remainder
+	for i := period + 1; i < len(candles); i++ {
+		tr := TrueRange(candles[i], candles[i-1].Close)
+		atr = (atr*float64(period-1) + tr) / float64(period)
+	}
+
+	return atr
+}
+
+// EMA returns the exponential moving average of closes for each candle, seeded
+// with the simple average of the first period closes. Entries before the seed
+// are 0. Returns nil if there are not enough candles.
+func EMA(candles []Candle, period int) []float64 {
+	if period <= 0 || len(candles) < period {
+		return nil
+	}
+
+	values := make([]float64, len(candles))
+	sum := 0.0
+	for i := 0; i < period; i++ {
+		sum += candles[i].Close
+	}
+	values[period-1] = sum / float64(period)
+
+	k := 2 / float64(period+1)
+	for i := period; i < len(candles); i++ {
+		values[i] = candles[i].Close*k + values[i-1]*(1-k)
+	}
+	return values
+}
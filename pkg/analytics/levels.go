@@ -0,0 +1,236 @@
+// pkg/analytics/levels.go
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Level types
+const (
+	LevelSupport    = "support"
+	LevelResistance = "resistance"
+)
+
+// PivotPoints holds classic floor-trader pivot levels
+type PivotPoints struct {
+	Pivot float64 `json:"pivot"`
+	R1    float64 `json:"r1"`
+	R2    float64 `json:"r2"`
+	R3    float64 `json:"r3"`
+	S1    float64 `json:"s1"`
+	S2    float64 `json:"s2"`
+	S3    float64 `json:"s3"`
+}
+
+// Zone represents a support or resistance price zone
+type Zone struct {
+	Type      string    `json:"type"`       // "support" or "resistance"
+	Low       float64   `json:"low"`        // Lower bound of the zone
+	High      float64   `json:"high"`       // Upper bound of the zone
+	Price     float64   `json:"price"`      // Representative (mean) price
+	Touches   int       `json:"touches"`    // Number of swing points in the zone
+	LastTouch time.Time `json:"last_touch"` // Most recent swing point in the zone
+}
+
+// Levels is the full set of levels computed for a ticker and interval
+type Levels struct {
+	Ticker     string      `json:"ticker"`
+	Interval   string      `json:"interval"`
+	LastClose  float64     `json:"last_close"`
+	Pivots     PivotPoints `json:"pivots"`
+	Zones      []Zone      `json:"zones"`
+	ComputedAt time.Time   `json:"computed_at"`
+}
+
+// LevelOptions tunes swing detection and zone clustering
+type LevelOptions struct {
+	SwingWindow   int     // Bars on each side required to confirm a swing point
+	ZoneTolerance float64 // Max relative distance between swing points in one zone
+	MinTouches    int     // Minimum swing points for a zone to be reported
+}
+
+// DefaultLevelOptions returns sensible defaults for intraday and daily data
+func DefaultLevelOptions() LevelOptions {
+	return LevelOptions{
+		SwingWindow:   3,
+		ZoneTolerance: 0.005,
+		MinTouches:    2,
+	}
+}
+
+// swingPoint is a local high or low
+type swingPoint struct {
+	price float64
+	time  time.Time
+}
+
+// CalculatePivotPoints computes classic pivot points from a period's high, low and close
+func CalculatePivotPoints(high, low, close float64) PivotPoints {
+	pivot := (high + low + close) / 3
+	return PivotPoints{
+		Pivot: pivot,
+		R1:    2*pivot - low,
+		R2:    pivot + (high - low),
+		R3:    high + 2*(pivot-low),
+		S1:    2*pivot - high,
+		S2:    pivot - (high - low),
+		S3:    low - 2*(high-pivot),
+	}
+}
+
+// DetectLevels computes pivot points and support/resistance zones from candles.
+// Candles must be ordered oldest first.
+func DetectLevels(ticker, interval string, candles []Candle, opts LevelOptions) Levels {
+	levels := Levels{
+		Ticker:     ticker,
+		Interval:   interval,
+		Zones:      []Zone{},
+		ComputedAt: time.Now(),
+	}
+	if len(candles) == 0 {
+		return levels
+	}
+
+	last := candles[len(candles)-1]
+	levels.LastClose = last.Close
+	levels.Pivots = pivotsFromPreviousSession(candles)
+
+	// Find swing highs and lows
+	var highs, lows []swingPoint
+	for i := opts.SwingWindow; i < len(candles)-opts.SwingWindow; i++ {
+		isHigh, isLow := true, true
+		for j := i - opts.SwingWindow; j <= i+opts.SwingWindow; j++ {
+			if j == i {
+				continue
+			}
+			if candles[j].High >= candles[i].High {
+				isHigh = false
+			}
+			if candles[j].Low <= candles[i].Low {
+				isLow = false
+			}
+		}
+		if isHigh {
+			highs = append(highs, swingPoint{price: candles[i].High, time: candles[i].Time})
+		}
+		if isLow {
+			lows = append(lows, swingPoint{price: candles[i].Low, time: candles[i].Time})
+		}
+	}
+
+	// Cluster all swing points into zones, then classify relative to the last close
+	points := append(highs, lows...)
+	for _, zone := range clusterSwingPoints(points, opts.ZoneTolerance) {
+		if zone.Touches < opts.MinTouches {
+			continue
+		}
+		if zone.Price <= last.Close {
+			zone.Type = LevelSupport
+		} else {
+			zone.Type = LevelResistance
+		}
+		levels.Zones = append(levels.Zones, zone)
+	}
+
+	return levels
+}
+
+// NearestSupport returns the closest support zone at or below price
+func (l Levels) NearestSupport(price float64) (Zone, bool) {
+	var best Zone
+	found := false
+	for _, zone := range l.Zones {
+		if zone.Type == LevelSupport && zone.Price <= price && (!found || zone.Price > best.Price) {
+			best, found = zone, true
+		}
+	}
+	return best, found
+}
+
+// NearestResistance returns the closest resistance zone at or above price
+func (l Levels) NearestResistance(price float64) (Zone, bool) {
+	var best Zone
+	found := false
+	for _, zone := range l.Zones {
+		if zone.Type == LevelResistance && zone.Price >= price && (!found || zone.Price < best.Price) {
+			best, found = zone, true
+		}
+	}
+	return best, found
+}
+
+// pivotsFromPreviousSession computes pivots from the last complete calendar day,
+// or from the whole series if it covers a single day
+func pivotsFromPreviousSession(candles []Candle) PivotPoints {
+	lastDay := candles[len(candles)-1].Time.Format("2006-01-02")
+
+	high, low, close := math.Inf(-1), math.Inf(1), 0.0
+	found := false
+	var prevDay string
+	for i := len(candles) - 1; i >= 0; i-- {
+		day := candles[i].Time.Format("2006-01-02")
+		if day == lastDay {
+			continue
+		}
+		if prevDay == "" {
+			prevDay = day
+			close = candles[i].Close
+		}
+		if day != prevDay {
+			break
+		}
+		high = math.Max(high, candles[i].High)
+		low = math.Min(low, candles[i].Low)
+		found = true
+	}
+
+	if !found {
+		for _, c := range candles {
+			high = math.Max(high, c.High)
+			low = math.Min(low, c.Low)
+		}
+		close = candles[len(candles)-1].Close
+	}
+
+	return CalculatePivotPoints(high, low, close)
+}
+
+// clusterSwingPoints groups nearby swing points into zones
+func clusterSwingPoints(points []swingPoint, tolerance float64) []Zone {
+	if len(points) == 0 {
+		return nil
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i].price < points[j].price })
+
+	var zones []Zone
+	var cluster []swingPoint
+	flush := func() {
+		if len(cluster) == 0 {
+			return
+		}
+		zone := Zone{Low: cluster[0].price, High: cluster[len(cluster)-1].price, Touches: len(cluster)}
+		sum := 0.0
+		for _, p := range cluster {
+			sum += p.price
+			if p.time.After(zone.LastTouch) {
+				zone.LastTouch = p.time
+			}
+		}
+		zone.Price = sum / float64(len(cluster))
+		zones = append(zones, zone)
+		cluster = cluster[:0]
+	}
+
+	for _, p := range points {
+		if len(cluster) > 0 && (p.price-cluster[0].price)/cluster[0].price > tolerance {
+			flush()
+		}
+		cluster = append(cluster, p)
+	}
+	flush()
+
+	return zones
+}
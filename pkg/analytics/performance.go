@@ -0,0 +1,170 @@
+// pkg/analytics/performance.go
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// TradeResult is a completed trade used as input to performance statistics
+type TradeResult struct {
+	Time    time.Time `json:"time"`     // Exit time
+	PnL     float64   `json:"pnl"`      // Realized profit or loss in dollars
+	Return  float64   `json:"return"`   // PnL as a fraction of the capital committed
+	RiskAmt float64   `json:"risk_amt"` // Initial dollar risk; zero if unknown
+}
+
+// PerformanceStats summarizes a series of trades
+type PerformanceStats struct {
+	TotalTrades   int     `json:"total_trades"`
+	Wins          int     `json:"wins"`
+	Losses        int     `json:"losses"`
+	WinRate       float64 `json:"win_rate"`
+	TotalPnL      float64 `json:"total_pnl"`
+	AverageWin    float64 `json:"average_win"`
+	AverageLoss   float64 `json:"average_loss"`
+	ProfitFactor  float64 `json:"profit_factor"`
+	Expectancy    float64 `json:"expectancy"`     // Average PnL per trade
+	AverageR      float64 `json:"average_r"`      // Average R multiple over trades with known risk
+	Sharpe        float64 `json:"sharpe"`         // Per-trade Sharpe ratio (mean / stddev of returns)
+	Sortino       float64 `json:"sortino"`        // Per-trade Sortino ratio (mean / downside deviation)
+	MaxWinStreak  int     `json:"max_win_streak"` // Longest run of consecutive winners
+	MaxLossStreak int     `json:"max_loss_streak"`
+	CurrentStreak int     `json:"current_streak"` // Positive for wins, negative for losses
+}
+
+// RollingPoint is a performance snapshot at the end of a rolling window
+type RollingPoint struct {
+	Time       time.Time `json:"time"`
+	Sharpe     float64   `json:"sharpe"`
+	Sortino    float64   `json:"sortino"`
+	Expectancy float64   `json:"expectancy"`
+	WinRate    float64   `json:"win_rate"`
+}
+
+// ComputePerformance calculates performance statistics for trades in time order
+func ComputePerformance(trades []TradeResult) PerformanceStats {
+	stats := PerformanceStats{TotalTrades: len(trades)}
+	if len(trades) == 0 {
+		return stats
+	}
+
+	sorted := sortedTrades(trades)
+
+	var grossWin, grossLoss, rSum float64
+	var rCount, streak int
+	returns := make([]float64, 0, len(sorted))
+
+	for _, t := range sorted {
+		stats.TotalPnL += t.PnL
+		returns = append(returns, t.Return)
+
+		if t.RiskAmt > 0 {
+			rSum += t.PnL / t.RiskAmt
+			rCount++
+		}
+
+		if t.PnL > 0 {
+			stats.Wins++
+			grossWin += t.PnL
+			if streak < 0 {
+				streak = 0
+			}
+			streak++
+			if streak > stats.MaxWinStreak {
+				stats.MaxWinStreak = streak
+			}
+		} else {
+			stats.Losses++
+			grossLoss += -t.PnL
+			if streak > 0 {
+				streak = 0
+			}
+			streak--
+			if -streak > stats.MaxLossStreak {
+				stats.MaxLossStreak = -streak
+			}
+		}
+	}
+
+	stats.CurrentStreak = streak
+	stats.WinRate = float64(stats.Wins) / float64(stats.TotalTrades)
+	stats.Expectancy = stats.TotalPnL / float64(stats.TotalTrades)
+	if stats.Wins > 0 {
+		stats.AverageWin = grossWin / float64(stats.Wins)
+	}
+	if stats.Losses > 0 {
+		stats.AverageLoss = grossLoss / float64(stats.Losses)
+	}
+	if grossLoss > 0 {
+		stats.ProfitFactor = grossWin / grossLoss
+	}
+	if rCount > 0 {
+		stats.AverageR = rSum / float64(rCount)
+	}
+	stats.Sharpe, stats.Sortino = riskAdjustedRatios(returns)
+
+	return stats
+}
+
+// RollingPerformance computes statistics over a sliding window of trades
+func RollingPerformance(trades []TradeResult, window int) []RollingPoint {
+	sorted := sortedTrades(trades)
+	if window <= 0 || len(sorted) < window {
+		return []RollingPoint{}
+	}
+
+	points := make([]RollingPoint, 0, len(sorted)-window+1)
+	for end := window; end <= len(sorted); end++ {
+		stats := ComputePerformance(sorted[end-window : end])
+		points = append(points, RollingPoint{
+			Time:       sorted[end-1].Time,
+			Sharpe:     stats.Sharpe,
+			Sortino:    stats.Sortino,
+			Expectancy: stats.Expectancy,
+			WinRate:    stats.WinRate,
+		})
+	}
+	return points
+}
+
+// riskAdjustedRatios returns the Sharpe and Sortino ratios of a return series
+func riskAdjustedRatios(returns []float64) (float64, float64) {
+	if len(returns) < 2 {
+		return 0, 0
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	var variance, downside float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	stddev := math.Sqrt(variance / float64(len(returns)-1))
+	downsideDev := math.Sqrt(downside / float64(len(returns)))
+
+	var sharpe, sortino float64
+	if stddev > 0 {
+		sharpe = mean / stddev
+	}
+	if downsideDev > 0 {
+		sortino = mean / downsideDev
+	}
+	return sharpe, sortino
+}
+
+// sortedTrades returns a copy of trades ordered by time
+func sortedTrades(trades []TradeResult) []TradeResult {
+	sorted := make([]TradeResult, len(trades))
+	copy(sorted, trades)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	return sorted
+}
@@ -0,0 +1,138 @@
+// pkg/analytics/sequence.go
+package analytics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// OrderStats counts out-of-order bars seen for a ticker
+type OrderStats struct {
+	Reordered int64 `json:"reordered"` // Arrived out of order but within the window
+	Late      int64 `json:"late"`      // Arrived after a later bar was already released
+}
+
+// SequencedBar is a bar released in timestamp order
+type SequencedBar struct {
+	Ticker string
+	Bar    Candle
+}
+
+// pendingBar is a bar held until its window elapses
+type pendingBar struct {
+	bar     Candle
+	arrived time.Time
+}
+
+// BarSequencer restores timestamp order of bars per ticker. Each bar is held
+// for a window after it arrives, so a bar delayed by up to the window is
+// released before the later bars that overtook it. A bar older than one
+// already released cannot be reordered and is reported as late.
+type BarSequencer struct {
+	mu       sync.Mutex
+	window   time.Duration
+	pending  map[string][]pendingBar // Sorted by bar time
+	released map[string]time.Time    // Latest released bar time per ticker
+	stats    map[string]*OrderStats
+}
+
+// NewBarSequencer creates a sequencer that holds bars for window. A zero
+// window releases bars immediately and only flags late ones.
+func NewBarSequencer(window time.Duration) *BarSequencer {
+	return &BarSequencer{
+		window:   window,
+		pending:  make(map[string][]pendingBar),
+		released: make(map[string]time.Time),
+		stats:    make(map[string]*OrderStats),
+	}
+}
+
+// Window returns how long bars are held
+func (s *BarSequencer) Window() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.window
+}
+
+// Add queues a bar received at now. It returns false if the bar is late, i.e.
+// older than a bar already released for the ticker; late bars are dropped.
+// A bar with the same time as a pending or released bar is a revision and
+// replaces it.
+func (s *BarSequencer) Add(ticker string, bar Candle, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.statsFor(ticker)
+	if last, ok := s.released[ticker]; ok && bar.Time.Before(last) {
+		stats.Late++
+		return false
+	}
+
+	queue := s.pending[ticker]
+	i := sort.Search(len(queue), func(i int) bool { return !queue[i].bar.Time.Before(bar.Time) })
+	switch {
+	case i < len(queue) && queue[i].bar.Time.Equal(bar.Time):
+		queue[i].bar = bar
+	default:
+		if i < len(queue) {
+			stats.Reordered++
+		}
+		queue = append(queue, pendingBar{})
+		copy(queue[i+1:], queue[i:])
+		queue[i] = pendingBar{bar: bar, arrived: now}
+	}
+	s.pending[ticker] = queue
+	return true
+}
+
+// Due releases, in timestamp order per ticker, the bars whose window has
+// elapsed at now. A bar is released only once every earlier pending bar for
+// the ticker is released, so a held bar also holds back later ones.
+func (s *BarSequencer) Due(now time.Time) []SequencedBar {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []SequencedBar
+	for ticker, queue := range s.pending {
+		// A later bar that has waited out the window releases everything before it
+		cut := 0
+		for i, p := range queue {
+			if now.Sub(p.arrived) >= s.window {
+				cut = i + 1
+			}
+		}
+		for _, p := range queue[:cut] {
+			due = append(due, SequencedBar{Ticker: ticker, Bar: p.bar})
+			s.released[ticker] = p.bar.Time
+		}
+		if cut == len(queue) {
+			delete(s.pending, ticker)
+		} else {
+			s.pending[ticker] = queue[cut:]
+		}
+	}
+	return due
+}
+
+// Stats returns the out-of-order counts per ticker
+func (s *BarSequencer) Stats() map[string]OrderStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]OrderStats, len(s.stats))
+	for ticker, stats := range s.stats {
+		result[ticker] = *stats
+	}
+	return result
+}
+
+// statsFor returns a ticker's counters. Caller holds the lock.
+func (s *BarSequencer) statsFor(ticker string) *OrderStats {
+	stats, ok := s.stats[ticker]
+	if !ok {
+		stats = &OrderStats{}
+		s.stats[ticker] = stats
+	}
+	return stats
+}
@@ -0,0 +1,128 @@
+// pkg/analytics/vwap.go
+package analytics
+
+import (
+	"sync"
+	"time"
+)
+
+// IntradayAverages is the running VWAP and TWAP for a ticker's current session
+type IntradayAverages struct {
+	Ticker    string    `json:"ticker"`
+	Session   string    `json:"session"` // Trading date in exchange time
+	VWAP      float64   `json:"vwap"`
+	TWAP      float64   `json:"twap"`
+	Volume    float64   `json:"volume"` // Cumulative session volume
+	Bars      int       `json:"bars"`
+	LastPrice float64   `json:"last_price"`
+	Timestamp time.Time `json:"timestamp"` // Time of the latest bar included
+}
+
+// intradayState accumulates one ticker's session
+type intradayState struct {
+	session     string
+	priceVolume float64 // Sum of typical price * volume
+	volume      float64
+	priceTime   float64 // Sum of price * seconds the price was in effect
+	seconds     float64
+	bars        int
+	lastBar     Candle
+	lastTypical float64
+}
+
+// IntradayTracker maintains running VWAP and TWAP per ticker from live bars,
+// resetting at the start of each trading day
+type IntradayTracker struct {
+	mu       sync.Mutex
+	states   map[string]*intradayState
+	location *time.Location
+}
+
+// NewIntradayTracker creates a tracker that splits sessions on dates in loc
+func NewIntradayTracker(loc *time.Location) *IntradayTracker {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &IntradayTracker{
+		states:   make(map[string]*intradayState),
+		location: loc,
+	}
+}
+
+// Update adds a live bar and returns the updated averages. A bar with the same
+// timestamp as the previous one replaces it, so republished bars are not
+// double counted; older bars are ignored.
+func (t *IntradayTracker) Update(ticker string, bar Candle) (IntradayAverages, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	session := bar.Time.In(t.location).Format("2006-01-02")
+	state, exists := t.states[ticker]
+	if !exists || state.session != session {
+		state = &intradayState{session: session}
+		t.states[ticker] = state
+	}
+
+	typical := (bar.High + bar.Low + bar.Close) / 3
+	if bar.High == 0 || bar.Low == 0 {
+		typical = bar.Close
+	}
+
+	if state.bars > 0 {
+		switch {
+		case bar.Time.Before(state.lastBar.Time):
+			return t.averages(ticker, state), false
+		case bar.Time.Equal(state.lastBar.Time):
+			// Back out the previous version of this bar
+			state.priceVolume -= state.lastTypical * state.lastBar.Volume
+			state.volume -= state.lastBar.Volume
+			state.bars--
+		default:
+			// The previous price was in effect until this bar
+			elapsed := bar.Time.Sub(state.lastBar.Time).Seconds()
+			state.priceTime += state.lastBar.Close * elapsed
+			state.seconds += elapsed
+		}
+	}
+
+	state.priceVolume += typical * bar.Volume
+	state.volume += bar.Volume
+	state.bars++
+	state.lastBar = bar
+	state.lastTypical = typical
+
+	return t.averages(ticker, state), true
+}
+
+// Get returns the current averages for a ticker
+func (t *IntradayTracker) Get(ticker string) (IntradayAverages, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, exists := t.states[ticker]
+	if !exists {
+		return IntradayAverages{}, false
+	}
+	return t.averages(ticker, state), true
+}
+
+// averages computes the published values for a session. Caller holds the lock.
+func (t *IntradayTracker) averages(ticker string, state *intradayState) IntradayAverages {
+	result := IntradayAverages{
+		Ticker:    ticker,
+		Session:   state.session,
+		Volume:    state.volume,
+		Bars:      state.bars,
+		LastPrice: state.lastBar.Close,
+		Timestamp: state.lastBar.Time,
+		VWAP:      state.lastTypical,
+		TWAP:      state.lastBar.Close,
+	}
+	if state.volume > 0 {
+		result.VWAP = state.priceVolume / state.volume
+	}
+	if state.seconds > 0 {
+		result.TWAP = state.priceTime / state.seconds
+	}
+	return result
+}
@@ -0,0 +1,497 @@
+// pkg/artifacts/artifacts.go
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/config"
+	"github.com/myapp/tradinglab/pkg/storage"
+)
+
+// Errors returned for requests naming artifacts that cannot be acted on
+var (
+	ErrUnknownKind = errors.New("unknown artifact kind")
+	ErrNotFound    = errors.New("artifact not found")
+	ErrExists      = errors.New("an artifact with that name already exists")
+)
+
+// trashDir holds a collection's soft-deleted artifacts
+const trashDir = ".trash"
+
+// manifestFile records when a collection's artifacts were archived and deleted
+const manifestFile = ".lifecycle.json"
+
+// Collection is a directory of artifact files of one kind
+type Collection struct {
+	Kind    string
+	Dir     string
+	Pattern string // Glob matching artifact file names, e.g. "daily-*.json"
+	Policy  config.ArtifactPolicy
+}
+
+// Artifact is a stored file and where it is in its lifecycle
+type Artifact struct {
+	Kind       string     `json:"kind"`
+	Name       string     `json:"name"`
+	Size       int64      `json:"size"`
+	Created    time.Time  `json:"created"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	ArchiveKey string     `json:"archive_key,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"` // Set while in the trash
+}
+
+// Summary is what a retention pass did, as kind/name entries
+type Summary struct {
+	Archived []string `json:"archived"`
+	Deleted  []string `json:"deleted"`
+	Purged   []string `json:"purged"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// manifest is a collection's lifecycle state, keyed by artifact name
+type manifest struct {
+	Archived map[string]time.Time `json:"archived,omitempty"`
+	Deleted  map[string]time.Time `json:"deleted,omitempty"`
+}
+
+// Manager applies retention policies to artifact collections: artifacts are
+// copied to the archive, then soft-deleted into their collection's trash,
+// then removed once they have been in the trash for the purge period
+type Manager struct {
+	mu          sync.Mutex
+	collections map[string]Collection
+	archive     storage.Store // Nil disables archival
+}
+
+// NewManager creates a manager archiving to archive, which may be nil
+func NewManager(archive storage.Store, collections ...Collection) *Manager {
+	m := &Manager{collections: make(map[string]Collection), archive: archive}
+	for _, c := range collections {
+		m.collections[c.Kind] = c
+	}
+	return m
+}
+
+// Kinds returns the managed kinds in order
+func (m *Manager) Kinds() []string {
+	kinds := make([]string, 0, len(m.collections))
+	for kind := range m.collections {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Policy returns the policy for a kind
+func (m *Manager) Policy(kind string) (config.ArtifactPolicy, bool) {
+	c, ok := m.collections[kind]
+	return c.Policy, ok
+}
+
+// collection returns the collection for kind, checking name belongs to it
+func (m *Manager) collection(kind, name string) (Collection, error) {
+	c, ok := m.collections[kind]
+	if !ok {
+		return c, fmt.Errorf("%w %q", ErrUnknownKind, kind)
+	}
+	if name != "" {
+		matched, _ := filepath.Match(c.Pattern, name)
+		if !matched || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+			return c, fmt.Errorf("%w: %s/%s", ErrNotFound, kind, name)
+		}
+	}
+	return c, nil
+}
+
+// Save writes an artifact, for kinds whose producers have no store of their own
+func (m *Manager) Save(kind, name string, data []byte) error {
+	c, err := m.collection(kind, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", kind, err)
+	}
+	path := filepath.Join(c.Dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s/%s: %w", kind, name, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads a live artifact. Artifacts in the trash must be restored first.
+func (m *Manager) Load(kind, name string) ([]byte, error) {
+	c, err := m.collection(kind, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(c.Dir, name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, kind, name)
+	}
+	return data, err
+}
+
+// List returns the artifacts of kind, or of every kind if empty, including
+// those in the trash, oldest first
+func (m *Manager) List(kind string) ([]Artifact, error) {
+	kinds := m.Kinds()
+	if kind != "" {
+		if _, err := m.collection(kind, ""); err != nil {
+			return nil, err
+		}
+		kinds = []string{kind}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var list []Artifact
+	for _, k := range kinds {
+		c := m.collections[k]
+		state, err := loadManifest(c)
+		if err != nil {
+			return nil, err
+		}
+		live, trash, err := scan(c, state)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, live...)
+		list = append(list, trash...)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+	return list, nil
+}
+
+// Delete moves an artifact to the trash, where it can be restored until it
+// is purged
+func (m *Manager) Delete(kind, name string) error {
+	c, err := m.collection(kind, name)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, err := loadManifest(c)
+	if err != nil {
+		return err
+	}
+	if err := m.softDelete(c, state, name, time.Now()); err != nil {
+		return err
+	}
+	return saveManifest(c, state)
+}
+
+// Restore moves an artifact back out of the trash
+func (m *Manager) Restore(kind, name string) error {
+	c, err := m.collection(kind, name)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	live, trashed := filepath.Join(c.Dir, name), filepath.Join(c.Dir, trashDir, name)
+	if _, err := os.Stat(trashed); os.IsNotExist(err) {
+		return fmt.Errorf("%w in trash: %s/%s", ErrNotFound, kind, name)
+	}
+	if _, err := os.Stat(live); err == nil {
+		return fmt.Errorf("%w: %s/%s", ErrExists, kind, name)
+	}
+	state, err := loadManifest(c)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(trashed, live); err != nil {
+		return err
+	}
+	delete(state.Deleted, name)
+	return saveManifest(c, state)
+}
+
+// Remove deletes an artifact immediately, whether live or in the trash.
+// Archived copies are kept.
+func (m *Manager) Remove(kind, name string) error {
+	c, err := m.collection(kind, name)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, err := loadManifest(c)
+	if err != nil {
+		return err
+	}
+
+	removed := false
+	for _, path := range []string{filepath.Join(c.Dir, name), filepath.Join(c.Dir, trashDir, name)} {
+		if err := os.Remove(path); err == nil {
+			removed = true
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if !removed {
+		return fmt.Errorf("%w: %s/%s", ErrNotFound, kind, name)
+	}
+	delete(state.Archived, name)
+	delete(state.Deleted, name)
+	return saveManifest(c, state)
+}
+
+// Purge removes trashed artifacts of kind, or of every kind if empty, that
+// were deleted more than olderThan ago; zero purges the whole trash
+func (m *Manager) Purge(kind string, olderThan time.Duration) ([]Artifact, error) {
+	kinds := m.Kinds()
+	if kind != "" {
+		if _, err := m.collection(kind, ""); err != nil {
+			return nil, err
+		}
+		kinds = []string{kind}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	purged := []Artifact{}
+	now := time.Now()
+	for _, k := range kinds {
+		c := m.collections[k]
+		state, err := loadManifest(c)
+		if err != nil {
+			return purged, err
+		}
+		removed, err := purgeTrash(c, state, func(deletedAt time.Time) bool {
+			return now.Sub(deletedAt) >= olderThan
+		})
+		purged = append(purged, removed...)
+		if saveErr := saveManifest(c, state); err == nil {
+			err = saveErr
+		}
+		if err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// Apply runs one retention pass over every collection as of now. Failures
+// are collected in the summary so one bad artifact does not stop the pass.
+// An artifact due for archiving is not deleted until its copy succeeds.
+func (m *Manager) Apply(ctx context.Context, now time.Time) Summary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	summary := Summary{Archived: []string{}, Deleted: []string{}, Purged: []string{}}
+	fail := func(format string, args ...interface{}) {
+		summary.Errors = append(summary.Errors, fmt.Sprintf(format, args...))
+	}
+
+	for _, kind := range m.Kinds() {
+		c := m.collections[kind]
+		state, err := loadManifest(c)
+		if err != nil {
+			fail("%s: %v", kind, err)
+			continue
+		}
+		live, _, err := scan(c, state)
+		if err != nil {
+			fail("%s: %v", kind, err)
+			continue
+		}
+
+		archiveAfter, deleteAfter := time.Duration(c.Policy.ArchiveAfter), time.Duration(c.Policy.DeleteAfter)
+		for _, a := range live {
+			age := now.Sub(a.Created)
+			archiving := archiveAfter > 0 && m.archive != nil
+			if archiving && a.ArchivedAt == nil && age >= archiveAfter {
+				if err := m.archiveOne(ctx, c, a); err != nil {
+					fail("archive %s/%s: %v", kind, a.Name, err)
+				} else {
+					state.Archived[a.Name] = now
+					a.ArchivedAt = &now
+					summary.Archived = append(summary.Archived, kind+"/"+a.Name)
+				}
+			}
+			if deleteAfter <= 0 || age < deleteAfter || (archiving && a.ArchivedAt == nil) {
+				continue
+			}
+			if err := m.softDelete(c, state, a.Name, now); err != nil {
+				fail("delete %s/%s: %v", kind, a.Name, err)
+				continue
+			}
+			summary.Deleted = append(summary.Deleted, kind+"/"+a.Name)
+		}
+
+		purgeAfter := time.Duration(c.Policy.PurgeAfter)
+		if purgeAfter <= 0 {
+			purgeAfter = config.DefaultTrashRetention
+		}
+		purged, err := purgeTrash(c, state, func(deletedAt time.Time) bool {
+			return now.Sub(deletedAt) >= purgeAfter
+		})
+		for _, a := range purged {
+			summary.Purged = append(summary.Purged, kind+"/"+a.Name)
+		}
+		if err != nil {
+			fail("purge %s: %v", kind, err)
+		}
+		if err := saveManifest(c, state); err != nil {
+			fail("%s: %v", kind, err)
+		}
+	}
+	return summary
+}
+
+// archiveOne copies an artifact to the archive under kind/name
+func (m *Manager) archiveOne(ctx context.Context, c Collection, a Artifact) error {
+	f, err := os.Open(filepath.Join(c.Dir, a.Name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.archive.Put(ctx, archiveKey(a.Kind, a.Name), f, a.Size, contentType(a.Name))
+}
+
+// softDelete moves an artifact into the trash and records when
+func (m *Manager) softDelete(c Collection, state *manifest, name string, now time.Time) error {
+	if err := os.MkdirAll(filepath.Join(c.Dir, trashDir), 0755); err != nil {
+		return err
+	}
+	err := os.Rename(filepath.Join(c.Dir, name), filepath.Join(c.Dir, trashDir, name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s/%s", ErrNotFound, c.Kind, name)
+	}
+	if err != nil {
+		return err
+	}
+	state.Deleted[name] = now
+	return nil
+}
+
+// purgeTrash removes trashed artifacts whose deletion time satisfies due
+func purgeTrash(c Collection, state *manifest, due func(deletedAt time.Time) bool) ([]Artifact, error) {
+	_, trash, err := scan(c, state)
+	if err != nil {
+		return nil, err
+	}
+	var purged []Artifact
+	for _, a := range trash {
+		if !due(*a.DeletedAt) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.Dir, trashDir, a.Name)); err != nil && !os.IsNotExist(err) {
+			return purged, err
+		}
+		delete(state.Archived, a.Name)
+		delete(state.Deleted, a.Name)
+		purged = append(purged, a)
+	}
+	return purged, nil
+}
+
+// scan lists a collection's live and trashed artifacts. Trashed artifacts
+// missing from the manifest are dated by their modification time.
+func scan(c Collection, state *manifest) (live, trash []Artifact, err error) {
+	for _, dir := range []string{c.Dir, filepath.Join(c.Dir, trashDir)} {
+		matches, err := filepath.Glob(filepath.Join(dir, c.Pattern))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			a := Artifact{Kind: c.Kind, Name: info.Name(), Size: info.Size(), Created: info.ModTime()}
+			if at, ok := state.Archived[a.Name]; ok {
+				a.ArchivedAt = &at
+				a.ArchiveKey = archiveKey(c.Kind, a.Name)
+			}
+			if dir == c.Dir {
+				live = append(live, a)
+				continue
+			}
+			deletedAt, ok := state.Deleted[a.Name]
+			if !ok {
+				deletedAt = info.ModTime()
+			}
+			a.DeletedAt = &deletedAt
+			trash = append(trash, a)
+		}
+	}
+	return live, trash, nil
+}
+
+// loadManifest reads a collection's lifecycle state
+func loadManifest(c Collection) (*manifest, error) {
+	state := &manifest{}
+	data, err := os.ReadFile(filepath.Join(c.Dir, manifestFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("failed to parse %s lifecycle state: %w", c.Kind, err)
+		}
+	}
+	if state.Archived == nil {
+		state.Archived = make(map[string]time.Time)
+	}
+	if state.Deleted == nil {
+		state.Deleted = make(map[string]time.Time)
+	}
+	return state, nil
+}
+
+// saveManifest writes a collection's lifecycle state
+func saveManifest(c Collection, state *manifest) error {
+	if len(state.Archived) == 0 && len(state.Deleted) == 0 {
+		if err := os.Remove(filepath.Join(c.Dir, manifestFile)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(c.Dir, manifestFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// archiveKey is where an artifact is archived
+func archiveKey(kind, name string) string {
+	return storage.Join(kind, name)
+}
+
+// contentType guesses an artifact's content type from its extension
+func contentType(name string) string {
+	switch filepath.Ext(name) {
+	case ".json":
+		return "application/json"
+	case ".csv":
+		return "text/csv"
+	case ".html":
+		return "text/html; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,198 @@
+// pkg/audit/audit.go
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of an audited action
+const (
+	OutcomeSuccess = "success"
+	OutcomeDenied  = "denied" // Rejected by authentication or authorization
+	OutcomeFailed  = "failed"
+)
+
+// memoryLimit caps the entries kept by a log without a file
+const memoryLimit = 10000
+
+// Entry records who did what, when and from where
+type Entry struct {
+	ID         string                 `json:"id"`
+	Time       time.Time              `json:"time"`
+	Actor      string                 `json:"actor"`
+	Action     string                 `json:"action"` // e.g. alerts.rule.create
+	Outcome    string                 `json:"outcome"`
+	Method     string                 `json:"method,omitempty"`
+	Path       string                 `json:"path,omitempty"`
+	Resource   map[string]string      `json:"resource,omitempty"` // Identifiers of what was acted on
+	Status     int                    `json:"status,omitempty"`
+	RemoteAddr string                 `json:"remote_addr,omitempty"`
+	UserAgent  string                 `json:"user_agent,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+}
+
+// Filter selects entries in a query. Zero fields match everything.
+type Filter struct {
+	Actor  string
+	Action string // Matches the action or, ending in ".", any action under it
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// matches reports whether an entry passes the filter
+func (f Filter) matches(e Entry) bool {
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action &&
+		!(strings.HasSuffix(f.Action, ".") && strings.HasPrefix(e.Action, f.Action)) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Log is an append-only audit log. With a path, every entry is appended to
+// a JSON-lines file and synced before Record returns; without one, recent
+// entries are kept in memory.
+type Log struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	entries []Entry // Used without a file
+	seq     int64
+}
+
+// Open opens or creates the audit log at path, or an in-memory log for an empty path
+func Open(path string) (*Log, error) {
+	l := &Log{path: path}
+	if path == "" {
+		return l, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l.file = file
+	return l, nil
+}
+
+// Record appends an entry, filling in its ID and time
+func (l *Log) Record(e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.Outcome == "" {
+		e.Outcome = OutcomeSuccess
+	}
+	l.seq++
+	e.ID = fmt.Sprintf("audit-%d-%d", e.Time.UnixNano(), l.seq)
+
+	if l.file == nil {
+		l.entries = append(l.entries, e)
+		if len(l.entries) > memoryLimit {
+			l.entries = l.entries[len(l.entries)-memoryLimit:]
+		}
+		return nil
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return l.file.Sync()
+}
+
+// Query returns the entries matching a filter, newest first
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var matched []Entry
+	if l.file == nil {
+		for _, e := range l.entries {
+			if f.matches(e) {
+				matched = append(matched, e)
+			}
+		}
+	} else {
+		var err error
+		if matched, err = l.scan(f); err != nil {
+			return nil, err
+		}
+	}
+
+	// Entries are appended in time order, so the newest are at the end
+	result := make([]Entry, 0, len(matched))
+	for i := len(matched) - 1; i >= 0 && (f.Limit <= 0 || len(result) < f.Limit); i-- {
+		result = append(result, matched[i])
+	}
+	return result, nil
+}
+
+// scan reads the matching entries from the file, oldest first. Caller
+// holds the lock, so no entry is half written.
+func (l *Log) scan(f Filter) ([]Entry, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer file.Close()
+
+	var matched []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // A torn line from a crash mid-write
+		}
+		if f.matches(e) {
+			matched = append(matched, e)
+			// Only the newest Limit entries are returned
+			if f.Limit > 0 && len(matched) > 2*f.Limit {
+				matched = append(matched[:0], matched[len(matched)-f.Limit:]...)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return matched, nil
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
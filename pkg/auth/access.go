@@ -0,0 +1,193 @@
+// pkg/auth/access.go
+package auth
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// InternalNetworks are loopback and private address ranges, where cluster
+// traffic comes from
+var InternalNetworks = []string{"127.0.0.0/8", "::1/128", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+
+// AccessPolicy limits private routes to clients in allowlisted networks.
+// Routes are path patterns, matched exactly or, ending in "*", by prefix;
+// the longest matching pattern decides whether a route is public or private.
+type AccessPolicy struct {
+	Allow          []netip.Prefix // Networks that may reach private routes
+	TrustedProxies []netip.Prefix // Proxies whose X-Forwarded-For is believed
+	Public         []string       // Routes anyone may reach
+	Private        []string       // Routes only allowlisted networks may reach
+	DefaultPrivate bool           // Whether unlisted routes are private
+}
+
+// AccessPolicyFromEnv configures an access policy from ACCESS_ALLOW_CIDRS,
+// ACCESS_TRUSTED_PROXIES, ACCESS_PUBLIC_ROUTES and ACCESS_PRIVATE_ROUTES
+// (comma-separated) and ACCESS_DEFAULT (public or private), starting from a
+// service's defaults. Route lists add to the defaults; CIDR lists replace them.
+func AccessPolicyFromEnv(defaults AccessPolicy) (*AccessPolicy, error) {
+	p := defaults
+	var err error
+	if value := os.Getenv("ACCESS_ALLOW_CIDRS"); value != "" {
+		if p.Allow, err = ParsePrefixes(strings.Split(value, ",")); err != nil {
+			return nil, fmt.Errorf("ACCESS_ALLOW_CIDRS: %w", err)
+		}
+	}
+	if value := os.Getenv("ACCESS_TRUSTED_PROXIES"); value != "" {
+		if p.TrustedProxies, err = ParsePrefixes(strings.Split(value, ",")); err != nil {
+			return nil, fmt.Errorf("ACCESS_TRUSTED_PROXIES: %w", err)
+		}
+	}
+	p.Public = append(append([]string{}, p.Public...), splitList(os.Getenv("ACCESS_PUBLIC_ROUTES"))...)
+	p.Private = append(append([]string{}, p.Private...), splitList(os.Getenv("ACCESS_PRIVATE_ROUTES"))...)
+
+	switch value := strings.ToLower(os.Getenv("ACCESS_DEFAULT")); value {
+	case "":
+	case "public":
+		p.DefaultPrivate = false
+	case "private":
+		p.DefaultPrivate = true
+	default:
+		return nil, fmt.Errorf("ACCESS_DEFAULT must be public or private, got %q", value)
+	}
+	return &p, nil
+}
+
+// ParsePrefixes parses CIDRs, treating a bare address as a single host
+func ParsePrefixes(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", value)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", value)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// MustParsePrefixes parses CIDRs known to be valid
+func MustParsePrefixes(values ...string) []netip.Prefix {
+	prefixes, err := ParsePrefixes(values)
+	if err != nil {
+		panic(err)
+	}
+	return prefixes
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// IsPrivate reports whether a path is limited to allowlisted networks
+func (p *AccessPolicy) IsPrivate(path string) bool {
+	private, longest := p.DefaultPrivate, -1
+	for _, list := range []struct {
+		patterns []string
+		private  bool
+	}{{p.Public, false}, {p.Private, true}} {
+		for _, pattern := range list.patterns {
+			if n := matchRoute(pattern, path); n > longest || (n == longest && list.private) {
+				private, longest = list.private, n
+			}
+		}
+	}
+	return private
+}
+
+// matchRoute returns how specific a matching pattern is, or -1 if it does not match
+func matchRoute(pattern, path string) int {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		if strings.HasPrefix(path, prefix) {
+			return len(prefix)
+		}
+		return -1
+	}
+	if pattern == path {
+		return len(pattern) + 1 // An exact match beats a prefix of the same length
+	}
+	return -1
+}
+
+// ClientIP returns the address a request came from. X-Forwarded-For is
+// followed back through trusted proxies only, so clients cannot spoof an
+// allowlisted address.
+func (p *AccessPolicy) ClientIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	addr = addr.Unmap()
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0 && containsAddr(p.TrustedProxies, addr); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+	}
+	return addr
+}
+
+// Allowed reports whether a request may reach its route
+func (p *AccessPolicy) Allowed(r *http.Request) bool {
+	if !p.IsPrivate(r.URL.Path) {
+		return true
+	}
+	return containsAddr(p.Allow, p.ClientIP(r))
+}
+
+// Middleware rejects requests for private routes from outside the allowlist
+func (p *AccessPolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !p.Allowed(r) {
+			utils.Warn("Denied %s %s from %s: not in the access allowlist", r.Method, r.URL.Path, p.ClientIP(r))
+			http.Error(w, "access denied", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// containsAddr reports whether any prefix contains an address
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,259 @@
+// pkg/auth/auth.go
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Role is a user's level of access
+type Role string
+
+// Roles, from least to most access. RoleNone grants nothing.
+const (
+	RoleNone   Role = "none"
+	RoleViewer Role = "viewer" // Reads market data, signals and reports
+	RoleTrader Role = "trader" // Also runs backtests, manages alerts and records trades
+	RoleAdmin  Role = "admin"  // Also changes runtime configuration and runs operations
+)
+
+// Permissions checked by the gateway
+const (
+	PermRead     = "read"     // Market data, signals, reports and portfolio views
+	PermBacktest = "backtest" // Run backtests
+	PermAlerts   = "alerts"   // Manage alert subscriptions, rules and devices
+	PermTrade    = "trade"    // Open and close positions, fill recommendations, keep the journal
+	PermConfig   = "config"   // Strategies, scans, reports, digests, stream operations and the audit log
+)
+
+// rolePermissions lists what each role may do
+var rolePermissions = map[Role][]string{
+	RoleViewer: {PermRead},
+	RoleTrader: {PermRead, PermBacktest, PermAlerts, PermTrade},
+	RoleAdmin:  {PermRead, PermBacktest, PermAlerts, PermTrade, PermConfig},
+}
+
+// ParseRole parses a role name
+func ParseRole(name string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := rolePermissions[role]; ok || role == RoleNone {
+		return role, nil
+	}
+	return "", fmt.Errorf("unknown role %q, expected viewer, trader, admin or none", name)
+}
+
+// Can reports whether the role grants a permission
+func (r Role) Can(perm string) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions lists the permissions the role grants
+func (r Role) Permissions() []string {
+	return append([]string{}, rolePermissions[r]...)
+}
+
+// Authentication methods
+const (
+	MethodAnonymous  = "anonymous"
+	MethodAdminToken = "admin_token"
+	MethodAPIToken   = "api_token"
+	MethodProxy      = "proxy" // Identified by a trusted authenticating proxy
+)
+
+// Principal is who a request acts as
+type Principal struct {
+	User          string `json:"user"`
+	Role          Role   `json:"role"`
+	Method        string `json:"method"`
+	Authenticated bool   `json:"authenticated"`
+}
+
+// Can reports whether the principal has a permission
+func (p Principal) Can(perm string) bool {
+	return p.Role.Can(perm)
+}
+
+// ErrInvalidToken is returned for a bearer token that matches no user
+var ErrInvalidToken = errors.New("invalid access token")
+
+// APIToken grants a user a role
+type APIToken struct {
+	Token string `json:"token"`
+	User  string `json:"user"`
+	Role  Role   `json:"role"`
+}
+
+// Authenticator identifies the principal of a request from, in order, a
+// bearer token (the admin token or an API token), the user header of a
+// trusted authenticating proxy, or neither
+type Authenticator struct {
+	AdminToken    string
+	Tokens        []APIToken
+	TrustProxy    bool            // Whether to believe X-Forwarded-User
+	UserRoles     map[string]Role // Roles of proxy-authenticated users
+	DefaultRole   Role            // Role of proxy-authenticated users not in UserRoles
+	AnonymousRole Role            // Role of requests without credentials
+}
+
+// FromEnv configures authentication from ADMIN_TOKEN, API_TOKENS_FILE (a
+// JSON array of {"token", "user", "role"}), AUTH_TRUST_PROXY_HEADERS,
+// AUTH_USER_ROLES ("alice@example.com=admin,bob@example.com=trader"),
+// AUTH_DEFAULT_ROLE (default viewer) and AUTH_ANONYMOUS_ROLE. Anonymous
+// requests act as traders unless AUTH_ANONYMOUS_ROLE says otherwise, so a
+// single-user deployment works without configuration.
+func FromEnv() (*Authenticator, error) {
+	a := &Authenticator{
+		AdminToken:    os.Getenv("ADMIN_TOKEN"),
+		TrustProxy:    os.Getenv("AUTH_TRUST_PROXY_HEADERS") == "true",
+		UserRoles:     make(map[string]Role),
+		DefaultRole:   RoleViewer,
+		AnonymousRole: RoleTrader,
+	}
+
+	if path := os.Getenv("API_TOKENS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API tokens file: %w", err)
+		}
+		if err := json.Unmarshal(data, &a.Tokens); err != nil {
+			return nil, fmt.Errorf("failed to parse API tokens file: %w", err)
+		}
+		for i, t := range a.Tokens {
+			if t.Token == "" || t.User == "" {
+				return nil, fmt.Errorf("API token %d needs a token and a user", i)
+			}
+			if a.Tokens[i].Role, err = ParseRole(string(t.Role)); err != nil {
+				return nil, fmt.Errorf("API token for %s: %w", t.User, err)
+			}
+		}
+	}
+
+	if value := os.Getenv("AUTH_USER_ROLES"); value != "" {
+		for _, pair := range strings.Split(value, ",") {
+			user, name, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid AUTH_USER_ROLES entry %q, expected user=role", pair)
+			}
+			role, err := ParseRole(name)
+			if err != nil {
+				return nil, err
+			}
+			a.UserRoles[strings.ToLower(strings.TrimSpace(user))] = role
+		}
+	}
+
+	for env, role := range map[string]*Role{"AUTH_DEFAULT_ROLE": &a.DefaultRole, "AUTH_ANONYMOUS_ROLE": &a.AnonymousRole} {
+		if value := os.Getenv(env); value != "" {
+			parsed, err := ParseRole(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", env, err)
+			}
+			*role = parsed
+		}
+	}
+	return a, nil
+}
+
+// Authenticate identifies the principal of a request. Tokens are read from
+// the Authorization header, or an access_token query parameter for
+// WebSocket clients that cannot set headers.
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("access_token")
+	}
+	if token != "" {
+		if a.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.AdminToken)) == 1 {
+			return Principal{User: "admin", Role: RoleAdmin, Method: MethodAdminToken, Authenticated: true}, nil
+		}
+		for _, t := range a.Tokens {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(t.Token)) == 1 {
+				return Principal{User: t.User, Role: t.Role, Method: MethodAPIToken, Authenticated: true}, nil
+			}
+		}
+		return a.anonymous(r), ErrInvalidToken
+	}
+
+	if user := r.Header.Get("X-Forwarded-User"); user != "" && a.TrustProxy {
+		role, ok := a.UserRoles[strings.ToLower(user)]
+		if !ok {
+			role = a.DefaultRole
+		}
+		return Principal{User: user, Role: role, Method: MethodProxy, Authenticated: true}, nil
+	}
+	return a.anonymous(r), nil
+}
+
+// Require authenticates a request and checks its principal has perm,
+// writing 401 or 403 if not. For services without per-route middleware.
+func (a *Authenticator) Require(w http.ResponseWriter, r *http.Request, perm string) bool {
+	principal, err := a.Authenticate(r)
+	if err == nil && principal.Can(perm) {
+		return true
+	}
+	if !principal.Authenticated {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	http.Error(w, fmt.Sprintf("role %s lacks the %s permission", principal.Role, perm), http.StatusForbidden)
+	return false
+}
+
+// anonymous is the principal of a request without valid credentials. An
+// untrusted proxy user header still names the user for the audit log, but
+// grants nothing beyond the anonymous role.
+func (a *Authenticator) anonymous(r *http.Request) Principal {
+	user := "anonymous"
+	for _, header := range []string{"X-Forwarded-User", "X-Auth-Request-Email", "X-Auth-Request-User"} {
+		if value := r.Header.Get(header); value != "" {
+			user = value
+			break
+		}
+	}
+	return Principal{User: user, Role: a.AnonymousRole, Method: MethodAnonymous}
+}
+
+// Roles lists the roles and their permissions, for clients to explain access
+func Roles() map[Role][]string {
+	roles := make(map[Role][]string, len(rolePermissions))
+	for role, perms := range rolePermissions {
+		sorted := append([]string{}, perms...)
+		sort.Strings(sorted)
+		roles[role] = sorted
+	}
+	return roles
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying a principal
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal a context carries, or one with no access
+func FromContext(ctx context.Context) Principal {
+	if p, ok := PrincipalFrom(ctx); ok {
+		return p
+	}
+	return Principal{User: "anonymous", Role: RoleNone, Method: MethodAnonymous}
+}
+
+// PrincipalFrom returns the principal a context carries, if it carries one.
+// Work the service starts itself carries none.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
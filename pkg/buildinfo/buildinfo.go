@@ -0,0 +1,58 @@
+// pkg/buildinfo/buildinfo.go
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Build details, set at build time with
+// -ldflags "-X github.com/myapp/tradinglab/pkg/buildinfo.Version=v1.2.3 ..."
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = "" // Build time, RFC 3339
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a tree with uncommitted changes
+}
+
+var (
+	infoOnce sync.Once
+	info     Info
+)
+
+// Get returns the build details. A commit and date not injected at build time
+// are taken from the VCS stamp Go records in the binary, when there is one.
+func Get() Info {
+	infoOnce.Do(func() {
+		info = Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	})
+	return info
+}
@@ -0,0 +1,229 @@
+// pkg/chaos/chaos.go
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultDelay is how long a delayed provider call waits
+const defaultDelay = 2 * time.Second
+
+// Config sets the rate of each injected fault; rates are probabilities from 0 to 1
+type Config struct {
+	DropRate      float64       // Published NATS messages silently dropped
+	DropSubjects  []string      // Subject prefixes eligible for drops; empty means all
+	DelayRate     float64       // Provider calls delayed
+	Delay         time.Duration // Added latency of a delayed call
+	GRPCErrorRate float64       // Trading service calls failed before they are sent
+	GRPCCode      codes.Code    // Status code of injected gRPC errors
+	Seed          int64         // Random seed, for reproducible runs
+}
+
+// Injector injects faults for resilience testing. A nil Injector injects
+// nothing, so components can call it unconditionally.
+type Injector struct {
+	cfg Config
+
+	mu  sync.Mutex
+	rng *rand.Rand
+
+	dropped    atomic.Int64
+	delayed    atomic.Int64
+	grpcErrors atomic.Int64
+}
+
+// Stats counts injected faults, with the seed that chose them
+type Stats struct {
+	Dropped    int64 `json:"dropped"`
+	Delayed    int64 `json:"delayed"`
+	GRPCErrors int64 `json:"grpc_errors"`
+	Seed       int64 `json:"seed"` // Set CHAOS_SEED to this to repeat the run's faults
+}
+
+// New creates an injector for cfg
+func New(cfg Config) *Injector {
+	if cfg.Delay <= 0 {
+		cfg.Delay = defaultDelay
+	}
+	if cfg.GRPCCode == codes.OK {
+		cfg.GRPCCode = codes.Unavailable
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	return &Injector{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed))}
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultInjector *Injector
+)
+
+// Default returns the process-wide injector configured by FromEnv, or nil
+// when fault injection is off
+func Default() *Injector {
+	defaultOnce.Do(func() {
+		inj, err := FromEnv()
+		if err != nil {
+			utils.Error("Fault injection disabled: %v", err)
+			return
+		}
+		defaultInjector = inj
+	})
+	return defaultInjector
+}
+
+// FromEnv creates an injector when CHAOS_ENABLED=true, from CHAOS_DROP_RATE,
+// CHAOS_DROP_SUBJECTS, CHAOS_DELAY_RATE, CHAOS_DELAY, CHAOS_GRPC_ERROR_RATE,
+// CHAOS_GRPC_CODE (e.g. "unavailable") and CHAOS_SEED. It returns nil when
+// fault injection is off.
+func FromEnv() (*Injector, error) {
+	if os.Getenv("CHAOS_ENABLED") != "true" {
+		return nil, nil
+	}
+
+	var cfg Config
+	rates := map[string]*float64{
+		"CHAOS_DROP_RATE":       &cfg.DropRate,
+		"CHAOS_DELAY_RATE":      &cfg.DelayRate,
+		"CHAOS_GRPC_ERROR_RATE": &cfg.GRPCErrorRate,
+	}
+	for name, rate := range rates {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			return nil, fmt.Errorf("%s must be between 0 and 1, got %q", name, value)
+		}
+		*rate = parsed
+	}
+
+	if value := os.Getenv("CHAOS_DROP_SUBJECTS"); value != "" {
+		for _, prefix := range strings.Split(value, ",") {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				cfg.DropSubjects = append(cfg.DropSubjects, prefix)
+			}
+		}
+	}
+	if value := os.Getenv("CHAOS_DELAY"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid CHAOS_DELAY %q", value)
+		}
+		cfg.Delay = d
+	}
+	if value := os.Getenv("CHAOS_GRPC_CODE"); value != "" {
+		name := strconv.Quote(strings.ToUpper(value))
+		if err := cfg.GRPCCode.UnmarshalJSON([]byte(name)); err != nil {
+			return nil, fmt.Errorf("invalid CHAOS_GRPC_CODE %q", value)
+		}
+	}
+	if value := os.Getenv("CHAOS_SEED"); value != "" {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CHAOS_SEED %q", value)
+		}
+		cfg.Seed = seed
+	}
+
+	inj := New(cfg)
+	utils.Warn("Fault injection enabled: drop %.2f %v, delay %.2f by %v, gRPC errors %.2f (%s), seed %d",
+		cfg.DropRate, cfg.DropSubjects, cfg.DelayRate, inj.cfg.Delay, cfg.GRPCErrorRate, inj.cfg.GRPCCode, inj.cfg.Seed)
+	return inj, nil
+}
+
+// roll reports whether a fault with probability rate happens
+func (i *Injector) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rng.Float64() < rate
+}
+
+// DropMessage reports whether a message published on subject should be dropped
+func (i *Injector) DropMessage(subject string) bool {
+	if i == nil || i.cfg.DropRate <= 0 {
+		return false
+	}
+	if len(i.cfg.DropSubjects) > 0 {
+		eligible := false
+		for _, prefix := range i.cfg.DropSubjects {
+			if strings.HasPrefix(subject, prefix) {
+				eligible = true
+				break
+			}
+		}
+		if !eligible {
+			return false
+		}
+	}
+	if !i.roll(i.cfg.DropRate) {
+		return false
+	}
+	i.dropped.Add(1)
+	utils.Debug("Fault injection: dropped message on %s", subject)
+	return true
+}
+
+// Delay waits before a provider call at the configured rate, returning early
+// with the context's error if it ends first
+func (i *Injector) Delay(ctx context.Context) error {
+	if i == nil || !i.roll(i.cfg.DelayRate) {
+		return nil
+	}
+	i.delayed.Add(1)
+
+	timer := time.NewTimer(i.cfg.Delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// UnaryClientInterceptor fails gRPC calls at the configured rate without
+// sending them. It returns nil for a nil injector.
+func (i *Injector) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	if i == nil || i.cfg.GRPCErrorRate <= 0 {
+		return nil
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if i.roll(i.cfg.GRPCErrorRate) {
+			i.grpcErrors.Add(1)
+			return status.Errorf(i.cfg.GRPCCode, "fault injection: %s failed", method)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// Stats returns the number of faults injected so far
+func (i *Injector) Stats() Stats {
+	if i == nil {
+		return Stats{}
+	}
+	return Stats{
+		Dropped:    i.dropped.Load(),
+		Delayed:    i.delayed.Load(),
+		GRPCErrors: i.grpcErrors.Load(),
+		Seed:       i.cfg.Seed,
+	}
+}
@@ -0,0 +1,112 @@
+// pkg/client/delta.go
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Delta frame kinds sent by the gateway to WebSocket clients connecting with
+// ?delta=true
+const (
+	DeltaSnapshot = "snapshot" // Data is the full payload
+	DeltaDiff     = "diff"     // Data holds only the fields changed since Base
+)
+
+// ErrDeltaGap is returned for a diff that does not follow the last frame
+// received on its subject. Resubscribing to the subject makes the gateway
+// start again from a snapshot.
+var ErrDeltaGap = errors.New("client: delta frame does not follow the last one received")
+
+// DeltaFrame is the envelope of a delta-encoded message. Diffs carry changed
+// top-level fields in Data and dropped ones in Removed.
+type DeltaFrame struct {
+	Delta   string                     `json:"delta"`
+	Subject string                     `json:"subject"`
+	Seq     uint64                     `json:"seq"`
+	Base    uint64                     `json:"base,omitempty"`
+	Data    map[string]json.RawMessage `json:"data"`
+	Removed []string                   `json:"removed,omitempty"`
+}
+
+// deltaState is the payload last rebuilt for a subject
+type deltaState struct {
+	seq    uint64
+	fields map[string]json.RawMessage
+}
+
+// DeltaDecoder rebuilds full payloads from a delta-encoded stream. Frames
+// from MessagePack connections should be converted with msgpack.ToJSON
+// first. It is safe for concurrent use.
+type DeltaDecoder struct {
+	mu       sync.Mutex
+	subjects map[string]*deltaState
+}
+
+// NewDeltaDecoder creates a decoder with no state
+func NewDeltaDecoder() *DeltaDecoder {
+	return &DeltaDecoder{subjects: make(map[string]*deltaState)}
+}
+
+// Decode returns the full payload a message stands for and its subject.
+// Messages that are not delta frames, such as subscription confirmations and
+// events sent whole, are returned unchanged with the subject they name, if
+// any; a confirmation also clears the state kept for its subject.
+func (d *DeltaDecoder) Decode(msg []byte) (subject string, payload []byte, err error) {
+	var envelope struct {
+		DeltaFrame
+		Event string `json:"event"`
+	}
+	if err := json.Unmarshal(msg, &envelope); err != nil {
+		// Not an object, so not a frame
+		return "", msg, nil
+	}
+	frame := envelope.DeltaFrame
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch frame.Delta {
+	case "":
+		if envelope.Event == "subscribed" || envelope.Event == "unsubscribed" {
+			delete(d.subjects, frame.Subject)
+		}
+		return frame.Subject, msg, nil
+
+	case DeltaSnapshot:
+		fields := make(map[string]json.RawMessage, len(frame.Data))
+		for key, value := range frame.Data {
+			fields[key] = value
+		}
+		d.subjects[frame.Subject] = &deltaState{seq: frame.Seq, fields: fields}
+
+	case DeltaDiff:
+		state, ok := d.subjects[frame.Subject]
+		if !ok || state.seq != frame.Base {
+			delete(d.subjects, frame.Subject)
+			return frame.Subject, nil, ErrDeltaGap
+		}
+		for key, value := range frame.Data {
+			state.fields[key] = value
+		}
+		for _, key := range frame.Removed {
+			delete(state.fields, key)
+		}
+		state.seq = frame.Seq
+
+	default:
+		return frame.Subject, nil, fmt.Errorf("client: unknown delta frame %q", frame.Delta)
+	}
+
+	payload, err = json.Marshal(d.subjects[frame.Subject].fields)
+	return frame.Subject, payload, err
+}
+
+// Reset forgets the state kept for subject
+func (d *DeltaDecoder) Reset(subject string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.subjects, subject)
+}
@@ -0,0 +1,112 @@
+// pkg/clock/clock.go
+package clock
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// Clock separates market time, in which sessions and schedules are evaluated,
+// from display time, used when formatting times for people. Services configure
+// a Clock instead of overwriting time.Local, which would change the behavior
+// of every package in the process.
+type Clock struct {
+	market  *time.Location
+	display *time.Location
+	now     func() time.Time
+	offset  atomic.Int64 // Correction for local clock skew, in nanoseconds
+}
+
+// New creates a clock; nil locations default to the exchange zone for market
+// time and the system zone for display time
+func New(marketLoc, displayLoc *time.Location) *Clock {
+	if marketLoc == nil {
+		marketLoc = market.ExchangeLocation()
+	}
+	if displayLoc == nil {
+		displayLoc = time.Local
+	}
+	return &Clock{market: marketLoc, display: displayLoc, now: time.Now}
+}
+
+// System returns a clock using the exchange zone for market time and the
+// system zone for display
+func System() *Clock {
+	return New(nil, nil)
+}
+
+// FromEnv creates a clock from MARKET_TIMEZONE (default: the exchange zone)
+// and DISPLAY_TIMEZONE (default: defaultDisplay, or the system zone if empty).
+// A configured market zone becomes the process's exchange zone, so sessions,
+// schedules and day boundaries evaluated through market.ExchangeLocation
+// follow it too.
+func FromEnv(defaultDisplay string) (*Clock, error) {
+	marketLoc, err := loadLocation("MARKET_TIMEZONE", "")
+	if err != nil {
+		return nil, err
+	}
+	if marketLoc != nil {
+		market.SetExchangeLocation(marketLoc)
+	}
+	displayLoc, err := loadLocation("DISPLAY_TIMEZONE", defaultDisplay)
+	if err != nil {
+		return nil, err
+	}
+	return New(marketLoc, displayLoc), nil
+}
+
+// loadLocation loads the zone named by an environment variable, or def
+func loadLocation(name, def string) (*time.Location, error) {
+	zone := os.Getenv(name)
+	if zone == "" {
+		zone = def
+	}
+	if zone == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", name, zone, err)
+	}
+	return loc, nil
+}
+
+// Now returns the current instant, corrected by the clock's offset
+func (c *Clock) Now() time.Time {
+	return c.now().Add(c.Offset())
+}
+
+// MarketNow returns the current time in the market zone
+func (c *Clock) MarketNow() time.Time {
+	return c.Now().In(c.market)
+}
+
+// SetOffset corrects the clock by d, the amount a reference clock is ahead
+// of the local one; zero removes the correction
+func (c *Clock) SetOffset(d time.Duration) {
+	c.offset.Store(int64(d))
+}
+
+// Offset returns the correction applied to the local clock
+func (c *Clock) Offset() time.Duration {
+	return time.Duration(c.offset.Load())
+}
+
+// Market returns the zone market sessions and schedules are evaluated in
+func (c *Clock) Market() *time.Location {
+	return c.market
+}
+
+// Display returns the zone times are formatted in
+func (c *Clock) Display() *time.Location {
+	return c.display
+}
+
+// Format formats t in the display zone
+func (c *Clock) Format(t time.Time, layout string) string {
+	return t.In(c.display).Format(layout)
+}
@@ -0,0 +1,130 @@
+// pkg/clock/skew.go
+package clock
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// DefaultSkewThreshold is how far two clocks may differ before they are
+// treated as skewed. Bars are a minute or longer, so a couple of seconds
+// does not move a bar across a boundary.
+const DefaultSkewThreshold = 2 * time.Second
+
+// SkewThreshold returns CLOCK_SKEW_THRESHOLD, or the default when unset or invalid
+func SkewThreshold() time.Duration {
+	if value := os.Getenv("CLOCK_SKEW_THRESHOLD"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err == nil && d > 0 {
+			return d
+		}
+		utils.Warn("Invalid CLOCK_SKEW_THRESHOLD %q, using %v", value, DefaultSkewThreshold)
+	}
+	return DefaultSkewThreshold
+}
+
+// EstimateOffset returns how far a remote clock is ahead of the local one
+// from a round trip that read remote: the remote clock is taken to have been
+// read halfway between sending the request and receiving the reply, so the
+// estimate is good to half the round trip
+func EstimateOffset(sent, remote, received time.Time) (offset, uncertainty time.Duration) {
+	rtt := received.Sub(sent)
+	return remote.Sub(sent.Add(rtt / 2)), rtt / 2
+}
+
+// PeerOffset is the latest clock offset measured for a peer
+type PeerOffset struct {
+	Peer          string    `json:"peer"`
+	OffsetMS      float64   `json:"offset_ms"`      // Positive when the peer is ahead
+	UncertaintyMS float64   `json:"uncertainty_ms"` // How far off the estimate may be
+	MeasuredAt    time.Time `json:"measured_at"`
+	Skewed        bool      `json:"skewed"`
+}
+
+// SkewDetector tracks the clock offsets of peers and warns when one moves
+// beyond the threshold and when it comes back
+type SkewDetector struct {
+	mu        sync.Mutex
+	threshold time.Duration
+	peers     map[string]PeerOffset
+}
+
+// NewSkewDetector creates a detector treating offsets beyond threshold as skew
+func NewSkewDetector(threshold time.Duration) *SkewDetector {
+	return &SkewDetector{threshold: threshold, peers: make(map[string]PeerOffset)}
+}
+
+// Threshold returns the offset beyond which a peer is skewed
+func (d *SkewDetector) Threshold() time.Duration {
+	return d.threshold
+}
+
+// Record stores a peer's measured offset and reports whether it is skewed.
+// An offset within the estimate's uncertainty of the threshold is not
+// counted as skew, so a slow round trip alone never raises a warning.
+func (d *SkewDetector) Record(peer string, offset, uncertainty time.Duration) bool {
+	magnitude := offset
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+	skewed := magnitude-uncertainty > d.threshold
+
+	d.mu.Lock()
+	previous, known := d.peers[peer]
+	d.peers[peer] = PeerOffset{
+		Peer:          peer,
+		OffsetMS:      float64(offset) / float64(time.Millisecond),
+		UncertaintyMS: float64(uncertainty) / float64(time.Millisecond),
+		MeasuredAt:    time.Now(),
+		Skewed:        skewed,
+	}
+	d.mu.Unlock()
+
+	switch {
+	case skewed && (!known || !previous.Skewed):
+		utils.Warn("Clock skew: %s is %v %s of the local clock (threshold %v)",
+			peer, magnitude.Round(time.Millisecond), direction(offset), d.threshold)
+	case !skewed && known && previous.Skewed:
+		utils.Info("Clock skew resolved: %s is within %v of the local clock", peer, d.threshold)
+	}
+	return skewed
+}
+
+// direction describes which way a peer's clock is off
+func direction(offset time.Duration) string {
+	if offset < 0 {
+		return "behind"
+	}
+	return "ahead"
+}
+
+// Forget drops a peer that is no longer measured
+func (d *SkewDetector) Forget(peer string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.peers, peer)
+}
+
+// Peer returns the latest offset measured for a peer
+func (d *SkewDetector) Peer(peer string) (PeerOffset, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	offset, ok := d.peers[peer]
+	return offset, ok
+}
+
+// Peers returns the latest offset of every peer, by name
+func (d *SkewDetector) Peers() []PeerOffset {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	peers := make([]PeerOffset, 0, len(d.peers))
+	for _, offset := range d.peers {
+		peers = append(peers, offset)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Peer < peers[j].Peer })
+	return peers
+}
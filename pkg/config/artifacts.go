@@ -0,0 +1,57 @@
+// pkg/config/artifacts.go
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// ArtifactPolicy is the lifecycle of one kind of stored artifact. Ages are
+// measured from when the artifact was written.
+type ArtifactPolicy struct {
+	ArchiveAfter Duration `json:"archive_after,omitempty"` // Copy to the archive; zero never archives
+	DeleteAfter  Duration `json:"delete_after,omitempty"`  // Soft-delete; zero keeps forever
+	PurgeAfter   Duration `json:"purge_after,omitempty"`   // Time in the trash before removal; zero uses DefaultTrashRetention
+}
+
+// DefaultTrashRetention is how long soft-deleted artifacts can be restored
+const DefaultTrashRetention = 7 * 24 * time.Hour
+
+// DefaultArtifactPolicies keep reports a quarter, archiving them after a
+// month, and backtests and exports a month and a week
+var DefaultArtifactPolicies = map[string]ArtifactPolicy{
+	"reports":   {ArchiveAfter: Duration(30 * 24 * time.Hour), DeleteAfter: Duration(90 * 24 * time.Hour)},
+	"backtests": {DeleteAfter: Duration(30 * 24 * time.Hour)},
+	"exports":   {DeleteAfter: Duration(7 * 24 * time.Hour)},
+}
+
+// LoadArtifactPolicies returns the default policies with any from
+// ARTIFACT_POLICY_PATH replacing them by kind, e.g.
+//
+//	{"reports": {"archive_after": "168h", "delete_after": "2160h"}, "backtests": {"delete_after": "720h", "purge_after": "24h"}}
+func LoadArtifactPolicies() (map[string]ArtifactPolicy, error) {
+	policies := make(map[string]ArtifactPolicy, len(DefaultArtifactPolicies))
+	for kind, policy := range DefaultArtifactPolicies {
+		policies[kind] = policy
+	}
+
+	path := os.Getenv("ARTIFACT_POLICY_PATH")
+	if path == "" {
+		return policies, nil
+	}
+	var overrides map[string]ArtifactPolicy
+	if err := loadJSON(path, &overrides); err != nil {
+		return nil, err
+	}
+	for kind, policy := range overrides {
+		if policy.ArchiveAfter < 0 || policy.DeleteAfter < 0 || policy.PurgeAfter < 0 {
+			return nil, fmt.Errorf("invalid artifact policy %s/%s: durations cannot be negative", path, kind)
+		}
+		if policy.ArchiveAfter > 0 && policy.DeleteAfter > 0 && policy.DeleteAfter < policy.ArchiveAfter {
+			return nil, fmt.Errorf("invalid artifact policy %s/%s: delete_after is before archive_after", path, kind)
+		}
+		policies[kind] = policy
+	}
+	return policies, nil
+}
@@ -0,0 +1,56 @@
+// pkg/config/config.go
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultEnvironment is used when TRADINGLAB_ENV is not set
+const DefaultEnvironment = "dev"
+
+// Environment returns the deployment environment from TRADINGLAB_ENV, e.g.
+// dev, staging or prod
+func Environment() string {
+	if env := strings.ToLower(strings.TrimSpace(os.Getenv("TRADINGLAB_ENV"))); env != "" {
+		return env
+	}
+	return DefaultEnvironment
+}
+
+// Duration is a time.Duration that reads from JSON strings such as "36h"
+type Duration time.Duration
+
+// UnmarshalJSON parses a Go duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"24h\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration as a Go duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// loadJSON reads a JSON config file into v
+func loadJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return nil
+}
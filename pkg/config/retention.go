@@ -0,0 +1,93 @@
+// pkg/config/retention.go
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// AnyRetention matches every asset class or interval in a RetentionPolicy
+const AnyRetention = "*"
+
+// RetentionPolicy keeps historical market data by asset class and interval:
+// max ages keyed by asset class, then by interval, with "*" matching any. A
+// zero max age keeps data forever.
+type RetentionPolicy map[string]map[string]Duration
+
+// DefaultRetentionPolicy keeps intraday data a month and daily bars five
+// years
+var DefaultRetentionPolicy = RetentionPolicy{
+	AnyRetention: {
+		AnyRetention: Duration(30 * 24 * time.Hour),
+		"1day":       Duration(5 * 365 * 24 * time.Hour),
+	},
+}
+
+// LoadRetentionPolicy returns the default policy with the entries from
+// RETENTION_CONFIG_PATH replacing those for the same asset class and
+// interval, e.g.
+//
+//	{"us_equity": {"1min": "168h", "1day": "87600h"}, "forex": {"*": "720h"}, "*": {"1day": "0s"}}
+//
+// The file is read each time, so changes apply on the next retention run.
+func LoadRetentionPolicy() (RetentionPolicy, error) {
+	policy := make(RetentionPolicy, len(DefaultRetentionPolicy))
+	for class, intervals := range DefaultRetentionPolicy {
+		policy[class] = make(map[string]Duration, len(intervals))
+		for interval, maxAge := range intervals {
+			policy[class][interval] = maxAge
+		}
+	}
+
+	path := os.Getenv("RETENTION_CONFIG_PATH")
+	if path == "" {
+		return policy, nil
+	}
+	var overrides RetentionPolicy
+	if err := loadJSON(path, &overrides); err != nil {
+		return nil, err
+	}
+	for class, intervals := range overrides {
+		if policy[class] == nil {
+			policy[class] = make(map[string]Duration, len(intervals))
+		}
+		for interval, maxAge := range intervals {
+			if maxAge < 0 {
+				return nil, fmt.Errorf("invalid retention config %s: %s/%s cannot be negative", path, class, interval)
+			}
+			policy[class][interval] = maxAge
+		}
+	}
+	return policy, nil
+}
+
+// MaxAge returns how long to keep an asset class's data at an interval,
+// preferring the most specific entry. Zero keeps it forever.
+func (p RetentionPolicy) MaxAge(assetClass, interval string) time.Duration {
+	for _, class := range []string{assetClass, AnyRetention} {
+		for _, key := range []string{interval, AnyRetention} {
+			if maxAge, ok := p[class][key]; ok {
+				return time.Duration(maxAge)
+			}
+		}
+	}
+	return 0
+}
+
+// Longest returns the longest max age in the policy, or zero if anything is
+// kept forever
+func (p RetentionPolicy) Longest() time.Duration {
+	var longest time.Duration
+	for _, intervals := range p {
+		for _, maxAge := range intervals {
+			if maxAge == 0 {
+				return 0
+			}
+			if time.Duration(maxAge) > longest {
+				longest = time.Duration(maxAge)
+			}
+		}
+	}
+	return longest
+}
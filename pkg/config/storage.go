@@ -0,0 +1,98 @@
+// pkg/config/storage.go
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ObjectStoreFile and ObjectStoreS3 are the object storage backends
+const (
+	ObjectStoreFile = "file"
+	ObjectStoreS3   = "s3"
+)
+
+// DefaultURLExpiry is how long pre-signed download URLs stay valid
+const DefaultURLExpiry = 15 * time.Minute
+
+// MaxURLExpiry is the longest validity S3 accepts for a pre-signed URL
+const MaxURLExpiry = 7 * 24 * time.Hour
+
+// ObjectStorage configures where exports, published reports and snapshots
+// are written
+type ObjectStorage struct {
+	Backend   string   `json:"backend,omitempty"`    // "file" or "s3"; s3 when a bucket is set
+	Dir       string   `json:"dir,omitempty"`        // Root of the file backend
+	Bucket    string   `json:"bucket,omitempty"`     // S3 bucket
+	Region    string   `json:"region,omitempty"`     // Defaults to us-east-1
+	Endpoint  string   `json:"endpoint,omitempty"`   // For S3-compatible servers such as MinIO
+	Prefix    string   `json:"prefix,omitempty"`     // Prepended to every key in the bucket
+	PathStyle bool     `json:"path_style,omitempty"` // Address the bucket in the path
+	URLExpiry Duration `json:"url_expiry,omitempty"` // Pre-signed URL validity; defaults to DefaultURLExpiry
+}
+
+// LoadObjectStorage reads object storage settings from
+// OBJECT_STORAGE_CONFIG_PATH, e.g.
+//
+//	{"backend": "s3", "bucket": "tradinglab", "region": "us-east-2", "prefix": "prod", "url_expiry": "1h"}
+//
+// with OBJECT_STORAGE_BACKEND, OBJECT_STORAGE_DIR, S3_BUCKET, S3_REGION,
+// S3_ENDPOINT, S3_PREFIX, S3_PATH_STYLE and OBJECT_STORAGE_URL_EXPIRY
+// overriding the file
+func LoadObjectStorage() (ObjectStorage, error) {
+	var cfg ObjectStorage
+	if path := os.Getenv("OBJECT_STORAGE_CONFIG_PATH"); path != "" {
+		if err := loadJSON(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	for env, field := range map[string]*string{
+		"OBJECT_STORAGE_BACKEND": &cfg.Backend,
+		"OBJECT_STORAGE_DIR":     &cfg.Dir,
+		"S3_BUCKET":              &cfg.Bucket,
+		"S3_REGION":              &cfg.Region,
+		"S3_ENDPOINT":            &cfg.Endpoint,
+		"S3_PREFIX":              &cfg.Prefix,
+	} {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+		}
+	}
+	if value := os.Getenv("S3_PATH_STYLE"); value != "" {
+		pathStyle, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid S3_PATH_STYLE %q", value)
+		}
+		cfg.PathStyle = pathStyle
+	}
+	if value := os.Getenv("OBJECT_STORAGE_URL_EXPIRY"); value != "" {
+		expiry, err := time.ParseDuration(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid OBJECT_STORAGE_URL_EXPIRY %q", value)
+		}
+		cfg.URLExpiry = Duration(expiry)
+	}
+
+	if cfg.Backend == "" {
+		cfg.Backend = ObjectStoreFile
+		if cfg.Bucket != "" {
+			cfg.Backend = ObjectStoreS3
+		}
+	}
+	if cfg.URLExpiry == 0 {
+		cfg.URLExpiry = Duration(DefaultURLExpiry)
+	}
+
+	switch {
+	case cfg.Backend != ObjectStoreFile && cfg.Backend != ObjectStoreS3:
+		return cfg, fmt.Errorf("unknown object storage backend %q", cfg.Backend)
+	case cfg.Backend == ObjectStoreS3 && cfg.Bucket == "":
+		return cfg, fmt.Errorf("the s3 object storage backend needs a bucket")
+	case cfg.URLExpiry < 0 || time.Duration(cfg.URLExpiry) > MaxURLExpiry:
+		return cfg, fmt.Errorf("url_expiry must be between 0 and %s", MaxURLExpiry)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,123 @@
+// pkg/config/streams.go
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// StorageFile and StorageMemory are the JetStream storage backends
+const (
+	StorageFile   = "file"
+	StorageMemory = "memory"
+)
+
+// AllStreams is the override key applying to every stream
+const AllStreams = "*"
+
+// maxReplicas is the JetStream limit on stream replicas
+const maxReplicas = 5
+
+// StreamOverride adjusts a stream's defaults. Zero values keep the default.
+type StreamOverride struct {
+	MaxAge   Duration `json:"max_age,omitempty"`   // e.g. "12h"
+	Storage  string   `json:"storage,omitempty"`   // "file" or "memory"
+	Replicas int      `json:"replicas,omitempty"`  // 1-5
+	MaxBytes int64    `json:"max_bytes,omitempty"` // Per-stream disk or memory cap
+	MaxMsgs  int64    `json:"max_msgs,omitempty"`
+}
+
+// StreamOverrides holds per-environment stream overrides keyed by environment
+// and stream name. The "default" environment applies everywhere and "*"
+// applies to every stream, e.g.
+//
+//	{"default": {"*": {"replicas": 1}}, "prod": {"*": {"replicas": 3}}, "dev": {"MARKET_HISTORICAL": {"max_age": "24h"}}}
+type StreamOverrides map[string]map[string]StreamOverride
+
+// LoadStreamOverrides reads overrides from STREAM_CONFIG_PATH. It returns no
+// overrides when the variable is not set.
+func LoadStreamOverrides() (StreamOverrides, error) {
+	path := os.Getenv("STREAM_CONFIG_PATH")
+	if path == "" {
+		return nil, nil
+	}
+
+	var overrides StreamOverrides
+	if err := loadJSON(path, &overrides); err != nil {
+		return nil, err
+	}
+	if err := overrides.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid stream config %s: %w", path, err)
+	}
+	return overrides, nil
+}
+
+// Validate checks every override's values
+func (o StreamOverrides) Validate() error {
+	for env, streams := range o {
+		for name, override := range streams {
+			if err := override.validate(); err != nil {
+				return fmt.Errorf("%s/%s: %w", env, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks an override's values
+func (s StreamOverride) validate() error {
+	if s.Storage != "" && s.Storage != StorageFile && s.Storage != StorageMemory {
+		return fmt.Errorf("storage must be %q or %q", StorageFile, StorageMemory)
+	}
+	if s.Replicas < 0 || s.Replicas > maxReplicas {
+		return fmt.Errorf("replicas must be between 1 and %d", maxReplicas)
+	}
+	if s.MaxAge < 0 || s.MaxBytes < 0 || s.MaxMsgs < 0 {
+		return fmt.Errorf("max_age, max_bytes and max_msgs cannot be negative")
+	}
+	return nil
+}
+
+// Streams returns the stream names an environment's overrides refer to,
+// excluding the "*" wildcard
+func (o StreamOverrides) Streams(env string) []string {
+	var names []string
+	for _, section := range []string{"default", env} {
+		for name := range o[section] {
+			if name != AllStreams {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// For merges the overrides that apply to a stream in an environment, from
+// least to most specific: default/*, default/<stream>, <env>/*, <env>/<stream>
+func (o StreamOverrides) For(env, stream string) StreamOverride {
+	var merged StreamOverride
+	for _, section := range []string{"default", env} {
+		for _, key := range []string{AllStreams, stream} {
+			override, ok := o[section][key]
+			if !ok {
+				continue
+			}
+			if override.MaxAge != 0 {
+				merged.MaxAge = override.MaxAge
+			}
+			if override.Storage != "" {
+				merged.Storage = override.Storage
+			}
+			if override.Replicas != 0 {
+				merged.Replicas = override.Replicas
+			}
+			if override.MaxBytes != 0 {
+				merged.MaxBytes = override.MaxBytes
+			}
+			if override.MaxMsgs != 0 {
+				merged.MaxMsgs = override.MaxMsgs
+			}
+		}
+	}
+	return merged
+}
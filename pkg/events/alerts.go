@@ -0,0 +1,55 @@
+// pkg/events/alerts.go
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// Alert is an alert delivered to a subscriber, relayed to WebSocket clients
+// following their watchlist
+type Alert struct {
+	SubscriberID string            `json:"subscriber_id"`
+	Kind         string            `json:"kind"` // "signal" or "price"
+	Ticker       string            `json:"ticker"`
+	Title        string            `json:"title"`
+	Body         string            `json:"body,omitempty"`
+	Data         map[string]string `json:"data,omitempty"`
+	Timestamp    time.Time         `json:"timestamp"`
+}
+
+// AlertSubject returns the subject a subscriber's alerts for a ticker are
+// published on; "*" covers every ticker
+func AlertSubject(subscriberID, ticker string) (string, error) {
+	if !market.ValidSubjectToken(subscriberID) {
+		return "", fmt.Errorf("subscriber ID %q cannot be used in a subject", subscriberID)
+	}
+	if ticker == "*" {
+		return fmt.Sprintf(SubjectAlertSubscriber, subscriberID), nil
+	}
+	ticker, err := market.ValidateTicker(ticker)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(SubjectAlert, subscriberID, ticker), nil
+}
+
+// PublishAlert publishes an alert for whoever is listening. Alerts are not
+// stored; those delivered while nobody listens are only sent by email or push.
+func (c *EventClient) PublishAlert(alert Alert) error {
+	subject, err := AlertSubject(alert.SubscriberID, alert.Ticker)
+	if err != nil {
+		return err
+	}
+	if alert.Timestamp.IsZero() {
+		alert.Timestamp = time.Now().UTC()
+	}
+	msg, err := c.encodeMsg(subject, alert)
+	if err != nil {
+		return err
+	}
+
+	return c.conn.PublishMsg(msg)
+}
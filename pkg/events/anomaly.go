@@ -0,0 +1,64 @@
+// pkg/events/anomaly.go
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+	"github.com/nats-io/nats.go"
+)
+
+// AnomalyReason is one reason a live bar was flagged
+type AnomalyReason struct {
+	Kind      string  `json:"kind"`      // price_jump, volume_spike or stale
+	Value     float64 `json:"value"`     // Standard deviations for jumps and spikes, seconds of age for stale bars
+	Threshold float64 `json:"threshold"` // The limit Value exceeded
+}
+
+// MarketAnomaly reports a live bar the hub flagged as suspect and kept out
+// of its analytics. Consumers should not act on signals from it.
+type MarketAnomaly struct {
+	Ticker     string          `json:"ticker"`
+	Timestamp  string          `json:"timestamp"` // The bar's own timestamp
+	Price      float64         `json:"price"`
+	Volume     float64         `json:"volume"`
+	Reasons    []AnomalyReason `json:"reasons"`
+	DetectedAt time.Time       `json:"detected_at"`
+}
+
+// PublishMarketAnomaly publishes an anomaly flagged in a ticker's live data
+func (c *EventClient) PublishMarketAnomaly(ctx context.Context, anomaly MarketAnomaly) error {
+	subject, err := tickerSubject(SubjectMarketAnomalyTicker, anomaly.Ticker)
+	if err != nil {
+		return err
+	}
+	if anomaly.DetectedAt.IsZero() {
+		anomaly.DetectedAt = time.Now().UTC()
+	}
+	msg, err := c.encodeMsg(subject, anomaly)
+	if err != nil {
+		return err
+	}
+
+	return c.publish(ctx, msg)
+}
+
+// SubscribeMarketAnomalies delivers anomalies flagged from now on, for every
+// ticker
+func (c *EventClient) SubscribeMarketAnomalies(handler func(MarketAnomaly)) (*nats.Subscription, error) {
+	return c.conn.Subscribe(SubjectMarketAnomalyAll, func(msg *nats.Msg) {
+		data, err := Decode(msg)
+		if err != nil {
+			utils.Error("Dropping message on %s: %v", msg.Subject, err)
+			return
+		}
+		var anomaly MarketAnomaly
+		if err := json.Unmarshal(data, &anomaly); err != nil {
+			utils.Error("Dropping invalid anomaly on %s: %v", msg.Subject, err)
+			return
+		}
+		handler(anomaly)
+	})
+}
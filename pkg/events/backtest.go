@@ -0,0 +1,93 @@
+// pkg/events/backtest.go
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+	"github.com/nats-io/nats.go"
+)
+
+// Stages of a backtest job reported in progress events
+const (
+	BacktestQueued    = "queued"    // Waiting for a worker
+	BacktestRunning   = "running"   // Fetching data and simulating; bar counts follow
+	BacktestDone      = "done"      // Results are on the job
+	BacktestFailed    = "failed"    // Stopped; Error says why
+	BacktestCancelled = "cancelled" // Stopped at the owner's request
+)
+
+// BacktestProgress reports how far a backtest job has got. The trading
+// service counts bars across every profit target or ratio it simulates,
+// with the trades and profit of the one in progress so far.
+type BacktestProgress struct {
+	JobID         string    `json:"job_id"`
+	Stage         string    `json:"stage"`
+	Test          string    `json:"test,omitempty"` // Profit target or ratio being simulated
+	BarsProcessed int       `json:"bars_processed"`
+	TotalBars     int       `json:"total_bars,omitempty"`
+	Percent       float64   `json:"percent"`
+	Trades        int       `json:"trades"`
+	WinRate       float64   `json:"win_rate"`
+	PnL           float64   `json:"pnl"`                    // Dollar profit of the trades so far
+	Generation    int       `json:"generation,omitempty"`   // Generations scored, for optimization jobs
+	BestFitness   float64   `json:"best_fitness,omitempty"` // Best fitness found so far, for optimization jobs
+	Error         string    `json:"error,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// Finished reports whether no more progress will follow
+func (p BacktestProgress) Finished() bool {
+	return p.Stage == BacktestDone || p.Stage == BacktestFailed || p.Stage == BacktestCancelled
+}
+
+// BacktestProgressSubject returns the subject a backtest job's progress is
+// published on
+func BacktestProgressSubject(jobID string) (string, error) {
+	if !market.ValidSubjectToken(jobID) {
+		return "", fmt.Errorf("job ID %q cannot be used in a subject", jobID)
+	}
+	return fmt.Sprintf(SubjectBacktestProgress, jobID), nil
+}
+
+// PublishBacktestProgress publishes a backtest job's progress for whoever is
+// listening. Progress is not stored; the job itself holds the outcome.
+func (c *EventClient) PublishBacktestProgress(progress BacktestProgress) error {
+	subject, err := BacktestProgressSubject(progress.JobID)
+	if err != nil {
+		return err
+	}
+	if progress.Timestamp.IsZero() {
+		progress.Timestamp = time.Now().UTC()
+	}
+	msg, err := c.encodeMsg(subject, progress)
+	if err != nil {
+		return err
+	}
+	return c.conn.PublishMsg(msg)
+}
+
+// SubscribeBacktestProgress delivers a backtest job's progress published
+// from now on
+func (c *EventClient) SubscribeBacktestProgress(jobID string, handler func(BacktestProgress)) (*nats.Subscription, error) {
+	subject, err := BacktestProgressSubject(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return c.conn.Subscribe(subject, func(msg *nats.Msg) {
+		data, err := Decode(msg)
+		if err != nil {
+			utils.Error("Dropping message on %s: %v", msg.Subject, err)
+			return
+		}
+		var progress BacktestProgress
+		if err := json.Unmarshal(data, &progress); err != nil {
+			utils.Error("Dropping invalid progress on %s: %v", msg.Subject, err)
+			return
+		}
+		handler(progress)
+	})
+}
@@ -0,0 +1,54 @@
+// pkg/events/cancel.go
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// RequestCancel asks whichever service holds a request to stop serving it
+type RequestCancel struct {
+	RequestID string    `json:"request_id"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// CancelSubject returns the subject a request's cancellation is published on
+func CancelSubject(requestID string) (string, error) {
+	if _, err := ProgressSubject(requestID); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(SubjectRequestCancel, requestID), nil
+}
+
+// CancelRequest asks for a historical request to be cancelled. Cancelling a
+// request that has finished, or that no service holds, does nothing.
+func (c *EventClient) CancelRequest(ctx context.Context, requestID, reason string) error {
+	subject, err := CancelSubject(requestID)
+	if err != nil {
+		return err
+	}
+	msg, err := c.encodeMsg(subject, RequestCancel{
+		RequestID: requestID,
+		Reason:    reason,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.publish(ctx, msg)
+}
+
+// SubscribeRequestCancels delivers the ID of every request cancelled from now
+// on. Every instance receives every cancellation and ignores requests it does
+// not hold.
+func (c *EventClient) SubscribeRequestCancels(handler func(requestID string)) (*nats.Subscription, error) {
+	return c.conn.Subscribe(SubjectRequestCancelAll, func(msg *nats.Msg) {
+		handler(strings.TrimPrefix(msg.Subject, strings.TrimSuffix(SubjectRequestCancelAll, "*")))
+	})
+}
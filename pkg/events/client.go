@@ -4,25 +4,64 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/myapp/tradinglab/pkg/buildinfo"
+	"github.com/myapp/tradinglab/pkg/chaos"
+	"github.com/myapp/tradinglab/pkg/config"
+	"github.com/myapp/tradinglab/pkg/market"
 	"github.com/myapp/tradinglab/pkg/utils"
 	"github.com/nats-io/nats.go"
 )
 
 // EventClient handles publishing and subscribing to the event system
 type EventClient struct {
-	conn    *nats.Conn
-	js      nats.JetStreamContext
-	streams map[string]bool // Tracks created streams
+	conn          *nats.Conn
+	js            nats.JetStreamContext
+	streams       map[string]bool // Tracks created streams
+	streamConfigs []StreamConfig  // Defaults with retention and environment overrides applied
+	pinnedMaxAge  map[string]bool // Retention streams whose MaxAge an environment override sets
+	compress      bool            // Gzip large payloads (EVENTS_COMPRESSION=gzip)
+	faults        *chaos.Injector // Fault injection for resilience tests; nil when off
+	closed        chan struct{}   // Closed once the connection has closed
+
+	mu        sync.Mutex
+	consumers map[*nats.Subscription]managedConsumer // Durable consumers to delete on Unsubscribe or Close
 }
 
 // NewEventClient creates a new client connected to NATS and sets up streams
 func NewEventClient(natsURL string) (*EventClient, error) {
+	// Resolve stream settings first so a bad config fails before connecting
+	overrides, err := config.LoadStreamOverrides()
+	if err != nil {
+		return nil, err
+	}
+	retention, err := config.LoadRetentionPolicy()
+	if err != nil {
+		return nil, err
+	}
+	env := config.Environment()
+	streamConfigs, err := ApplyOverrides(ApplyRetention(GetStreamConfigs(), retention), overrides, env)
+	if err != nil {
+		return nil, err
+	}
+	pinnedMaxAge := make(map[string]bool)
+	for _, name := range RetentionStreams {
+		pinnedMaxAge[name] = overrides.For(env, name).MaxAge != 0
+	}
+	if overrides != nil {
+		utils.Info("Applied stream config overrides for environment %s", env)
+	}
+
 	// Connect to NATS with more robust options
+	closed := make(chan struct{})
 	nc, err := nats.Connect(natsURL,
+		nats.DrainTimeout(DrainTimeout),
+		nats.ClosedHandler(func(*nats.Conn) { close(closed) }),
 		nats.RetryOnFailedConnect(true),
 		nats.MaxReconnects(60),            // Allow more reconnection attempts
 		nats.ReconnectWait(5*time.Second), // Wait longer between reconnects
@@ -61,14 +100,20 @@ func NewEventClient(natsURL string) (*EventClient, error) {
 	}
 
 	client := &EventClient{
-		conn:    nc,
-		js:      js,
-		streams: make(map[string]bool),
+		conn:          nc,
+		js:            js,
+		streams:       make(map[string]bool),
+		streamConfigs: streamConfigs,
+		pinnedMaxAge:  pinnedMaxAge,
+		compress:      compressionFromEnv(),
+		faults:        chaos.Default(),
+		closed:        closed,
+		consumers:     make(map[*nats.Subscription]managedConsumer),
 	}
 
 	// Set up all streams with retry mechanism
 	for i := 0; i < 3; i++ {
-		err := client.setupStreams()
+		err = client.setupStreams()
 		if err == nil {
 			break
 		}
@@ -85,8 +130,7 @@ func NewEventClient(natsURL string) (*EventClient, error) {
 
 // setupStreams creates all required streams
 func (c *EventClient) setupStreams() error {
-	configs := GetStreamConfigs()
-	for _, cfg := range configs {
+	for _, cfg := range c.streamConfigs {
 		if err := c.createOrUpdateStream(cfg); err != nil {
 			return fmt.Errorf("failed to setup stream %s: %w", cfg.Name, err)
 		}
@@ -95,7 +139,8 @@ func (c *EventClient) setupStreams() error {
 	return nil
 }
 
-// createOrUpdateStream creates or updates a stream
+// createOrUpdateStream creates a stream, or updates an existing one whose
+// settings differ from the configuration
 func (c *EventClient) createOrUpdateStream(cfg StreamConfig) error {
 	streamCfg := &nats.StreamConfig{
 		Name:     cfg.Name,
@@ -104,116 +149,288 @@ func (c *EventClient) createOrUpdateStream(cfg StreamConfig) error {
 		Storage:  cfg.Storage,
 		Replicas: cfg.Replicas,
 		Discard:  cfg.Discard,
+		MaxBytes: unlimited(cfg.MaxBytes),
+		MaxMsgs:  unlimited(cfg.MaxMsgs),
 	}
 
-	_, err := c.js.AddStream(streamCfg)
-	if err != nil {
-		if strings.Contains(err.Error(), "stream name already in use") {
-			// Update existing stream
-			_, err = c.js.UpdateStream(streamCfg)
-			if err != nil {
-				return err
-			}
-			utils.Info("Updated existing stream: %s", cfg.Name)
-		} else {
+	info, err := c.js.StreamInfo(cfg.Name)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		if _, err := c.js.AddStream(streamCfg); err != nil {
 			return err
 		}
-	} else {
 		utils.Info("Created new stream: %s", cfg.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// JetStream cannot change storage in place; keep the existing backend
+	if info.Config.Storage != streamCfg.Storage {
+		utils.Warn("Stream %s uses %s storage but %s is configured; delete the stream to migrate",
+			cfg.Name, info.Config.Storage, streamCfg.Storage)
+		streamCfg.Storage = info.Config.Storage
 	}
 
+	changes := streamChanges(info.Config, *streamCfg)
+	if len(changes) == 0 {
+		return nil
+	}
+	if _, err := c.js.UpdateStream(streamCfg); err != nil {
+		return err
+	}
+	utils.Info("Updated stream %s: %s", cfg.Name, strings.Join(changes, ", "))
 	return nil
 }
 
+// unlimited maps an unset limit to JetStream's -1
+func unlimited(limit int64) int64 {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
+
+// streamChanges describes the settings that differ between a stream and its configuration
+func streamChanges(current, desired nats.StreamConfig) []string {
+	var changes []string
+	if strings.Join(current.Subjects, ",") != strings.Join(desired.Subjects, ",") {
+		changes = append(changes, fmt.Sprintf("subjects %v -> %v", current.Subjects, desired.Subjects))
+	}
+	if current.MaxAge != desired.MaxAge {
+		changes = append(changes, fmt.Sprintf("max age %s -> %s", current.MaxAge, desired.MaxAge))
+	}
+	if current.Replicas != desired.Replicas {
+		changes = append(changes, fmt.Sprintf("replicas %d -> %d", current.Replicas, desired.Replicas))
+	}
+	if current.Discard != desired.Discard {
+		changes = append(changes, fmt.Sprintf("discard %s -> %s", current.Discard, desired.Discard))
+	}
+	if current.MaxBytes != desired.MaxBytes {
+		changes = append(changes, fmt.Sprintf("max bytes %d -> %d", current.MaxBytes, desired.MaxBytes))
+	}
+	if current.MaxMsgs != desired.MaxMsgs {
+		changes = append(changes, fmt.Sprintf("max msgs %d -> %d", current.MaxMsgs, desired.MaxMsgs))
+	}
+	return changes
+}
+
+// publish stamps a message with its pipeline times and publishes it to its
+// stream, unless fault injection drops it
+func (c *EventClient) publish(ctx context.Context, msg *nats.Msg, opts ...nats.PubOpt) error {
+	if c.faults.DropMessage(msg.Subject) {
+		return nil
+	}
+	stamp(ctx, msg)
+	_, err := c.js.PublishMsg(msg, opts...)
+	return err
+}
+
 // PublishMarketLiveData publishes live market data
 func (c *EventClient) PublishMarketLiveData(ctx context.Context, ticker string, data interface{}) error {
-	subject := fmt.Sprintf(SubjectMarketLiveTicker, ticker)
-	payload, err := json.Marshal(data)
+	subject, err := tickerSubject(SubjectMarketLiveTicker, ticker)
+	if err != nil {
+		return err
+	}
+	msg, err := c.encodeMsg(subject, data)
 	if err != nil {
 		return err
 	}
 
-	_, err = c.js.Publish(subject, payload)
-	return err
+	return c.publish(ctx, msg)
+}
+
+// PublishMarketBar publishes a consolidated live bar for an interval
+func (c *EventClient) PublishMarketBar(ctx context.Context, ticker, interval string, data interface{}) error {
+	ticker, err := market.ValidateTicker(ticker)
+	if err != nil {
+		return err
+	}
+	if !market.ValidSubjectToken(interval) {
+		return fmt.Errorf("invalid interval %q", interval)
+	}
+	msg, err := c.encodeMsg(fmt.Sprintf(SubjectMarketBarsTicker, ticker, interval), data)
+	if err != nil {
+		return err
+	}
+
+	return c.publish(ctx, msg)
 }
 
 // PublishMarketDailyData publishes daily market data
 func (c *EventClient) PublishMarketDailyData(ctx context.Context, ticker string, data interface{}) error {
-	subject := fmt.Sprintf(SubjectMarketDailyTicker, ticker)
-	payload, err := json.Marshal(data)
+	subject, err := tickerSubject(SubjectMarketDailyTicker, ticker)
+	if err != nil {
+		return err
+	}
+	msg, err := c.encodeMsg(subject, data)
 	if err != nil {
 		return err
 	}
 
-	_, err = c.js.Publish(subject, payload)
-	return err
+	return c.publish(ctx, msg)
 }
 
 // PublishHistoricalData publishes historical market data
 func (c *EventClient) PublishHistoricalData(ctx context.Context, ticker, timeframe string, days int, data interface{}) error {
-	subject := fmt.Sprintf(SubjectMarketHistoricalData, ticker, timeframe, days)
-	payload, err := json.Marshal(data)
+	subject, err := historicalSubject(SubjectMarketHistoricalData, ticker, timeframe, days)
+	if err != nil {
+		return err
+	}
+	msg, err := c.encodeMsg(subject, data)
 	if err != nil {
 		return err
 	}
 
-	_, err = c.js.Publish(subject, payload)
-	return err
+	return c.publish(ctx, msg)
 }
 
 // RequestHistoricalData requests historical data for a ticker
 func (c *EventClient) RequestHistoricalData(ctx context.Context, ticker, timeframe string, days int, requestData interface{}) error {
-	subject := fmt.Sprintf(SubjectRequestsHistorical, ticker, timeframe, days)
+	subject, err := historicalSubject(SubjectRequestsHistorical, ticker, timeframe, days)
+	if err != nil {
+		return err
+	}
 	payload, err := json.Marshal(requestData)
 	if err != nil {
 		return err
 	}
 
+	msg := nats.NewMsg(subject)
+	msg.Header.Set(nats.MsgIdHdr, requestMessageID(subject, requestData, payload))
+	msg.Header.Set(HeaderVersion, buildinfo.Get().Version)
+	msg.Data = payload
+
 	// Publish to the REQUESTS stream with explicit stream binding
-	_, err = c.js.Publish(subject, payload, nats.ExpectStream(StreamRequests))
-	if err != nil {
+	if err := c.publish(ctx, msg, nats.ExpectStream(StreamRequests)); err != nil {
 		return fmt.Errorf("failed to publish historical request: %w", err)
 	}
 
 	return nil
 }
 
+// requestMessageID derives a request's Nats-Msg-Id from its subject and
+// request_id, as the Python client does, so a retried request is stored once.
+// Requests without an ID fall back to the event message ID.
+func requestMessageID(subject string, requestData interface{}, payload []byte) string {
+	if id := RequestID(payload); id != "" {
+		return subject + ":" + id
+	}
+	return messageID(subject, requestData, payload)
+}
+
 // SubscribeMarketLiveData subscribes to live market data for a ticker
 func (c *EventClient) SubscribeMarketLiveData(ticker string, handler func([]byte)) (*nats.Subscription, error) {
-	subject := fmt.Sprintf(SubjectMarketLiveTicker, ticker)
+	return c.SubscribeMarketLiveDataStamped(ticker, func(data []byte, _ Stamps) {
+		handler(data)
+	})
+}
+
+// SubscribeMarketLiveDataStamped subscribes to live market data for a
+// ticker, passing each event's pipeline stamps to the handler
+func (c *EventClient) SubscribeMarketLiveDataStamped(ticker string, handler func([]byte, Stamps)) (*nats.Subscription, error) {
+	subject, err := tickerSubject(SubjectMarketLiveTicker, ticker)
+	if err != nil {
+		return nil, err
+	}
 	return c.js.Subscribe(subject, func(msg *nats.Msg) {
-		handler(msg.Data)
+		data, err := Decode(msg)
+		if err != nil {
+			utils.Error("Dropping message on %s: %v", msg.Subject, err)
+			msg.Ack()
+			return
+		}
+		handler(data, ReadStamps(msg.Header))
 		msg.Ack()
 	}, nats.DeliverAll())
 }
 
 // SubscribeMarketDailyData subscribes to daily market data for a ticker
 func (c *EventClient) SubscribeMarketDailyData(ticker string, handler func([]byte)) (*nats.Subscription, error) {
-	subject := fmt.Sprintf(SubjectMarketDailyTicker, ticker)
+	subject, err := tickerSubject(SubjectMarketDailyTicker, ticker)
+	if err != nil {
+		return nil, err
+	}
 	return c.js.Subscribe(subject, func(msg *nats.Msg) {
-		handler(msg.Data)
+		data, err := Decode(msg)
+		if err != nil {
+			utils.Error("Dropping message on %s: %v", msg.Subject, err)
+			msg.Ack()
+			return
+		}
+		handler(data)
 		msg.Ack()
 	}, nats.DeliverAll())
 }
 
 // SubscribeHistoricalData subscribes to historical data for specific parameters
 func (c *EventClient) SubscribeHistoricalData(ticker, timeframe string, days int, handler func([]byte)) (*nats.Subscription, error) {
-	subject := fmt.Sprintf(SubjectMarketHistoricalData, ticker, timeframe, days)
+	subject, err := historicalSubject(SubjectMarketHistoricalData, ticker, timeframe, days)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create a unique consumer name
-	consumerName := fmt.Sprintf("historical-consumer-%s-%s-%d-%d",
-		ticker, timeframe, days, time.Now().Unix())
+	// A stable name lets a restarted process reuse the consumer it left
+	// behind instead of creating another
+	name := consumerName(historicalConsumerPrefix, subject)
 
 	// Use more robust subscription options
-	return c.js.Subscribe(subject, func(msg *nats.Msg) {
-		handler(msg.Data)
+	sub, err := c.js.Subscribe(subject, func(msg *nats.Msg) {
+		data, err := Decode(msg)
+		if err != nil {
+			utils.Error("Dropping message on %s: %v", msg.Subject, err)
+			msg.Ack()
+			return
+		}
+		handler(data)
 		msg.Ack()
 	},
 		nats.DeliverAll(),
 		nats.AckExplicit(),
-		nats.Durable(consumerName),
+		nats.Durable(name),
 		nats.ManualAck(),
 		nats.BindStream(StreamMarketHistorical))
+	if err != nil {
+		return nil, err
+	}
+
+	c.trackConsumer(sub, StreamMarketHistorical, name)
+	return sub, nil
+}
+
+// historicalSubject builds a historical subject from normalized parameters so that
+// equivalent requests ("15m" vs "15min") map to the same stream entries.
+// Wildcard tokens pass through, as do unrecognized intervals that are safe
+// subject tokens.
+func historicalSubject(pattern, ticker, timeframe string, days int) (string, error) {
+	if ticker != "*" {
+		normalized, err := market.ValidateTicker(ticker)
+		if err != nil {
+			return "", err
+		}
+		ticker = normalized
+	}
+	if timeframe != "*" {
+		if canonical, err := market.NormalizeInterval(timeframe); err == nil {
+			timeframe = canonical
+		} else if !market.ValidSubjectToken(timeframe) {
+			return "", fmt.Errorf("invalid interval %q", timeframe)
+		}
+	}
+	return fmt.Sprintf(pattern, ticker, timeframe, days), nil
+}
+
+// tickerSubject builds a per-ticker subject. Tickers that would change the
+// subject's meaning are rejected; "*" matches every ticker.
+func tickerSubject(pattern, ticker string) (string, error) {
+	if ticker != "*" {
+		normalized, err := market.ValidateTicker(ticker)
+		if err != nil {
+			return "", err
+		}
+		ticker = normalized
+	}
+	return fmt.Sprintf(pattern, ticker), nil
 }
 
 // SubscribeHistoricalRequests subscribes to historical data requests
@@ -228,7 +445,13 @@ func (c *EventClient) SubscribeHistoricalRequests(handler func(string, string, i
 			var days int
 			fmt.Sscanf(parts[4], "%d", &days)
 
-			handler(ticker, timeframe, days, msg.Data)
+			data, err := Decode(msg)
+			if err != nil {
+				utils.Error("Dropping historical request on %s: %v", msg.Subject, err)
+				msg.Ack()
+				return
+			}
+			handler(ticker, timeframe, days, data)
 			msg.Ack()
 		}
 	}, nats.DeliverAll(), nats.BindStream(StreamRequests))
@@ -236,33 +459,171 @@ func (c *EventClient) SubscribeHistoricalRequests(handler func(string, string, i
 
 // PublishSignal publishes a trading signal
 func (c *EventClient) PublishSignal(ctx context.Context, ticker string, signalData interface{}) error {
-	subject := fmt.Sprintf(SubjectSignalsTicker, ticker)
-	payload, err := json.Marshal(signalData)
+	subject, err := tickerSubject(SubjectSignalsTicker, ticker)
+	if err != nil {
+		return err
+	}
+	msg, err := c.encodeMsg(subject, signalData)
 	if err != nil {
 		return err
 	}
 
-	_, err = c.js.Publish(subject, payload)
-	return err
+	return c.publish(ctx, msg)
 }
 
 // SubscribeSignals subscribes to trading signals for a ticker
 func (c *EventClient) SubscribeSignals(ticker string, handler func([]byte)) (*nats.Subscription, error) {
-	subject := fmt.Sprintf(SubjectSignalsTicker, ticker)
+	subject, err := tickerSubject(SubjectSignalsTicker, ticker)
+	if err != nil {
+		return nil, err
+	}
 	return c.js.Subscribe(subject, func(msg *nats.Msg) {
-		handler(msg.Data)
+		data, err := Decode(msg)
+		if err != nil {
+			utils.Error("Dropping message on %s: %v", msg.Subject, err)
+			msg.Ack()
+			return
+		}
+		handler(data)
 		msg.Ack()
 	}, nats.DeliverAll())
 }
 
-// GetNATS returns the underlying NATS connection
-func (c *EventClient) GetNATS() *nats.Conn {
-	return c.conn
+// PublishRecommendation publishes an options recommendation
+func (c *EventClient) PublishRecommendation(ctx context.Context, ticker string, recommendationData interface{}) error {
+	subject, err := tickerSubject(SubjectRecommendationsTicker, ticker)
+	if err != nil {
+		return err
+	}
+	msg, err := c.encodeMsg(subject, recommendationData)
+	if err != nil {
+		return err
+	}
+
+	return c.publish(ctx, msg)
+}
+
+// PublishRiskEvent publishes a portfolio risk event
+func (c *EventClient) PublishRiskEvent(ctx context.Context, eventType string, eventData interface{}) error {
+	subject := fmt.Sprintf(SubjectRiskEvent, eventType)
+	msg, err := c.encodeMsg(subject, eventData)
+	if err != nil {
+		return err
+	}
+
+	return c.publish(ctx, msg)
+}
+
+// PublishMarketAnalytics publishes derived intraday analytics for a ticker
+func (c *EventClient) PublishMarketAnalytics(ctx context.Context, ticker string, data interface{}) error {
+	subject, err := tickerSubject(SubjectMarketAnalyticsTicker, ticker)
+	if err != nil {
+		return err
+	}
+	msg, err := c.encodeMsg(subject, data)
+	if err != nil {
+		return err
+	}
+
+	return c.publish(ctx, msg)
 }
 
-// Close closes the connection to NATS
-func (c *EventClient) Close() {
-	if c.conn != nil {
-		c.conn.Close()
+// PublishOrderBook publishes an order book snapshot for a ticker
+func (c *EventClient) PublishOrderBook(ctx context.Context, ticker string, data interface{}) error {
+	subject, err := tickerSubject(SubjectMarketBookTicker, ticker)
+	if err != nil {
+		return err
 	}
+	msg, err := c.encodeMsg(subject, data)
+	if err != nil {
+		return err
+	}
+
+	return c.publish(ctx, msg)
+}
+
+// PublishTrade publishes a trade print for a ticker. Trades arrive far more
+// often than other events, so the publish is asynchronous.
+func (c *EventClient) PublishTrade(ctx context.Context, ticker string, data interface{}) error {
+	subject, err := tickerSubject(SubjectTradesTicker, ticker)
+	if err != nil {
+		return err
+	}
+	msg, err := c.encodeMsg(subject, data)
+	if err != nil {
+		return err
+	}
+
+	if c.faults.DropMessage(msg.Subject) {
+		return nil
+	}
+	stamp(ctx, msg)
+	_, err = c.js.PublishMsgAsync(msg)
+	return err
+}
+
+// LatestOrderBook returns the most recent order book snapshot for a ticker,
+// or nats.ErrMsgNotFound if none has been published
+func (c *EventClient) LatestOrderBook(ticker string) ([]byte, error) {
+	subject, err := tickerSubject(SubjectMarketBookTicker, ticker)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := c.js.GetLastMsg(StreamMarketBook, subject)
+	if err != nil {
+		return nil, err
+	}
+	return decodePayload(msg.Header, msg.Data)
+}
+
+// requestReply is the envelope for reference data replies
+type requestReply struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ServeRequests answers core NATS requests on a subject with the handler's result
+func (c *EventClient) ServeRequests(subject string, handler func([]byte) (interface{}, error)) (*nats.Subscription, error) {
+	return c.conn.Subscribe(subject, func(msg *nats.Msg) {
+		var reply requestReply
+		result, err := handler(msg.Data)
+		if err != nil {
+			reply.Error = err.Error()
+		} else if reply.Result, err = json.Marshal(result); err != nil {
+			reply.Error = fmt.Sprintf("failed to encode reply: %v", err)
+		}
+
+		payload, _ := json.Marshal(reply)
+		if err := msg.Respond(payload); err != nil {
+			utils.Error("Failed to respond on %s: %v", subject, err)
+		}
+	})
+}
+
+// Request sends a request to a subject served by ServeRequests and decodes the
+// result into out
+func (c *EventClient) Request(ctx context.Context, subject string, request, out interface{}) error {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	msg, err := c.conn.RequestWithContext(ctx, subject, payload)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", subject, err)
+	}
+
+	var reply requestReply
+	if err := json.Unmarshal(msg.Data, &reply); err != nil {
+		return fmt.Errorf("invalid reply from %s: %w", subject, err)
+	}
+	if reply.Error != "" {
+		return fmt.Errorf("%s", reply.Error)
+	}
+	return json.Unmarshal(reply.Result, out)
+}
+
+// GetNATS returns the underlying NATS connection
+func (c *EventClient) GetNATS() *nats.Conn {
+	return c.conn
 }
@@ -0,0 +1,163 @@
+// pkg/events/consumers.go
+package events
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+	"github.com/nats-io/nats.go"
+)
+
+// historicalConsumerPrefix starts the durable names of historical data subscriptions
+const historicalConsumerPrefix = "historical-consumer-"
+
+// managedConsumerPrefixes identify durable consumers the client creates, so
+// pruning never touches consumers owned by other services
+var managedConsumerPrefixes = []string{historicalConsumerPrefix}
+
+// DefaultConsumerMaxIdle is how long an unbound managed consumer may go
+// without deliveries before it is pruned
+const DefaultConsumerMaxIdle = 24 * time.Hour
+
+// managedConsumer is a durable consumer created for one subscription
+type managedConsumer struct {
+	stream string
+	name   string
+}
+
+// PrunedConsumer describes a consumer removed by PruneConsumers
+type PrunedConsumer struct {
+	Stream     string    `json:"stream"`
+	Name       string    `json:"name"`
+	LastActive time.Time `json:"last_active"`
+	Idle       string    `json:"idle"`
+}
+
+// consumerName builds a stable durable name from prefix and the subscribed
+// subject, so the same filter always maps to the same consumer. Wildcard
+// tokens become "all" and characters not allowed in names become "_".
+func consumerName(prefix, subject string) string {
+	tokens := strings.Split(subject, ".")
+	for i, token := range tokens {
+		if token == "*" || token == ">" {
+			tokens[i] = "all"
+			continue
+		}
+		tokens[i] = strings.Map(func(r rune) rune {
+			switch r {
+			case '*', '>', ' ', '\t', '\r', '\n', '/', '\\':
+				return '_'
+			}
+			return r
+		}, token)
+	}
+	return prefix + strings.Join(tokens, "-")
+}
+
+// isManagedConsumer reports whether name belongs to a consumer the client creates
+func isManagedConsumer(name string) bool {
+	for _, prefix := range managedConsumerPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackConsumer records the durable consumer behind sub, to delete on
+// Unsubscribe or Close
+func (c *EventClient) trackConsumer(sub *nats.Subscription, stream, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consumers[sub] = managedConsumer{stream: stream, name: name}
+}
+
+// Unsubscribe drains sub and deletes its durable consumer, if the client
+// created one for it
+func (c *EventClient) Unsubscribe(sub *nats.Subscription) error {
+	DrainSubscriptions([]*nats.Subscription{sub}, DrainTimeout)
+
+	c.mu.Lock()
+	consumer, ok := c.consumers[sub]
+	delete(c.consumers, sub)
+	c.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return c.deleteConsumer(consumer)
+}
+
+// deleteConsumer removes a consumer from the server; one already gone is not an error
+func (c *EventClient) deleteConsumer(consumer managedConsumer) error {
+	err := c.js.DeleteConsumer(consumer.stream, consumer.name)
+	if err != nil && !errors.Is(err, nats.ErrConsumerNotFound) {
+		return fmt.Errorf("failed to delete consumer %s on %s: %w", consumer.name, consumer.stream, err)
+	}
+	return nil
+}
+
+// deleteConsumers drains every subscription with a managed consumer and
+// deletes the consumers
+func (c *EventClient) deleteConsumers() {
+	c.mu.Lock()
+	consumers := c.consumers
+	c.consumers = make(map[*nats.Subscription]managedConsumer)
+	c.mu.Unlock()
+
+	subs := make([]*nats.Subscription, 0, len(consumers))
+	for sub := range consumers {
+		subs = append(subs, sub)
+	}
+	DrainSubscriptions(subs, DrainTimeout)
+
+	for _, consumer := range consumers {
+		if err := c.deleteConsumer(consumer); err != nil {
+			utils.Warn("%v", err)
+		}
+	}
+}
+
+// PruneConsumers deletes managed consumers on the client's streams that no
+// subscription is bound to and that have had no deliveries for maxIdle, such
+// as those left behind by a crashed process
+func (c *EventClient) PruneConsumers(maxIdle time.Duration) ([]PrunedConsumer, error) {
+	c.mu.Lock()
+	own := make(map[string]bool, len(c.consumers))
+	for _, consumer := range c.consumers {
+		own[consumer.stream+"/"+consumer.name] = true
+	}
+	c.mu.Unlock()
+
+	pruned := []PrunedConsumer{}
+	for _, cfg := range c.streamConfigs {
+		for info := range c.js.ConsumersInfo(cfg.Name) {
+			if !isManagedConsumer(info.Name) || info.PushBound || own[cfg.Name+"/"+info.Name] {
+				continue
+			}
+
+			lastActive := info.Created
+			if info.Delivered.Last != nil && info.Delivered.Last.After(lastActive) {
+				lastActive = *info.Delivered.Last
+			}
+			idle := time.Since(lastActive)
+			if idle < maxIdle {
+				continue
+			}
+
+			if err := c.deleteConsumer(managedConsumer{stream: cfg.Name, name: info.Name}); err != nil {
+				return pruned, err
+			}
+			pruned = append(pruned, PrunedConsumer{
+				Stream:     cfg.Name,
+				Name:       info.Name,
+				LastActive: lastActive,
+				Idle:       idle.Round(time.Second).String(),
+			})
+		}
+	}
+	return pruned, nil
+}
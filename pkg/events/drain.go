@@ -0,0 +1,65 @@
+// pkg/events/drain.go
+package events
+
+import (
+	"errors"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+	"github.com/nats-io/nats.go"
+)
+
+// DrainTimeout bounds how long shutdown waits for in-flight messages
+const DrainTimeout = 10 * time.Second
+
+// DrainSubscriptions stops delivery to subs and waits, up to timeout, for the
+// messages already delivered to be handled and acknowledged
+func DrainSubscriptions(subs []*nats.Subscription, timeout time.Duration) {
+	for _, sub := range subs {
+		err := sub.Drain()
+		if err != nil && !errors.Is(err, nats.ErrBadSubscription) && !errors.Is(err, nats.ErrConnectionClosed) {
+			utils.Warn("Failed to drain subscription on %s: %v", sub.Subject, err)
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for _, sub := range subs {
+		for sub.IsValid() {
+			if time.Now().After(deadline) {
+				utils.Warn("Timed out draining subscription on %s", sub.Subject)
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+// Close drains the connection: subscriptions finish the messages already
+// delivered, pending publishes are flushed and managed consumers are
+// deleted, so shutdown leaves no unacked messages or orphaned consumers
+func (c *EventClient) Close() {
+	if c.conn == nil || c.conn.IsClosed() {
+		return
+	}
+
+	// Asynchronous publishes (trades) must be acknowledged before draining
+	select {
+	case <-c.js.PublishAsyncComplete():
+	case <-time.After(DrainTimeout):
+		utils.Warn("Timed out waiting for %d pending publishes", c.js.PublishAsyncPending())
+	}
+
+	c.deleteConsumers()
+
+	// Drain closes the connection once remaining subscriptions and publishes are done
+	if err := c.conn.Drain(); err != nil {
+		c.conn.Close()
+		return
+	}
+	select {
+	case <-c.closed:
+	case <-time.After(DrainTimeout + time.Second):
+		utils.Warn("Timed out draining NATS connection")
+		c.conn.Close()
+	}
+}
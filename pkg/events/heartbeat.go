@@ -0,0 +1,106 @@
+// pkg/events/heartbeat.go
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/buildinfo"
+	"github.com/myapp/tradinglab/pkg/utils"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultHeartbeatInterval is how often services publish heartbeats
+const DefaultHeartbeatInterval = 10 * time.Second
+
+// Heartbeat announces that a service instance is alive
+type Heartbeat struct {
+	Service         string                 `json:"service"`
+	Version         string                 `json:"version"`
+	Commit          string                 `json:"commit,omitempty"`
+	Instance        string                 `json:"instance"`
+	StartedAt       time.Time              `json:"started_at"`
+	Timestamp       time.Time              `json:"timestamp"`
+	IntervalSeconds float64                `json:"interval_seconds"`
+	Stats           map[string]interface{} `json:"stats,omitempty"` // Small digest of the service's counters
+}
+
+// HeartbeatInterval returns HEARTBEAT_INTERVAL, or the default when unset or invalid
+func HeartbeatInterval() time.Duration {
+	if value := os.Getenv("HEARTBEAT_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err == nil && d > 0 {
+			return d
+		}
+		utils.Warn("Invalid HEARTBEAT_INTERVAL %q, using %v", value, DefaultHeartbeatInterval)
+	}
+	return DefaultHeartbeatInterval
+}
+
+// instanceID identifies this process among instances of the same service
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// StartHeartbeat publishes a heartbeat for service every interval until ctx
+// ends. stats, if set, supplies the digest sent with each heartbeat.
+func (c *EventClient) StartHeartbeat(ctx context.Context, service string, interval time.Duration, stats func() map[string]interface{}) {
+	subject := fmt.Sprintf(SubjectHeartbeat, service)
+	build := buildinfo.Get()
+	hb := Heartbeat{
+		Service:         service,
+		Version:         build.Version,
+		Commit:          build.Commit,
+		Instance:        instanceID(),
+		StartedAt:       time.Now().UTC(),
+		IntervalSeconds: interval.Seconds(),
+	}
+
+	publish := func() {
+		hb.Timestamp = time.Now().UTC()
+		if stats != nil {
+			hb.Stats = stats()
+		}
+		payload, err := json.Marshal(hb)
+		if err != nil {
+			utils.Error("Failed to encode heartbeat: %v", err)
+			return
+		}
+		if err := c.conn.Publish(subject, payload); err != nil {
+			utils.Debug("Failed to publish heartbeat on %s: %v", subject, err)
+		}
+	}
+
+	go func() {
+		publish()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				publish()
+			}
+		}
+	}()
+}
+
+// SubscribeHeartbeats delivers heartbeats from every service
+func (c *EventClient) SubscribeHeartbeats(handler func(Heartbeat)) (*nats.Subscription, error) {
+	return c.conn.Subscribe(SubjectHeartbeatAll, func(msg *nats.Msg) {
+		var hb Heartbeat
+		if err := json.Unmarshal(msg.Data, &hb); err != nil {
+			utils.Error("Dropping invalid heartbeat on %s: %v", msg.Subject, err)
+			return
+		}
+		handler(hb)
+	})
+}
@@ -0,0 +1,125 @@
+// pkg/events/history.go
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Page size limits for history queries
+const (
+	DefaultHistoryLimit = 100
+	MaxHistoryLimit     = 1000
+)
+
+// HistoryQuery selects stored events by publication time. After continues a
+// previous page from its NextCursor.
+type HistoryQuery struct {
+	From  time.Time // Zero reads from the oldest stored event
+	To    time.Time // Zero reads to the newest
+	After uint64    // Stream sequence of the last event already read
+	Limit int
+}
+
+// StoredEvent is an event read back from a stream
+type StoredEvent struct {
+	Seq       uint64          `json:"seq"`
+	Subject   string          `json:"subject"`
+	Published time.Time       `json:"published"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// HistoryPage is one page of stored events. NextCursor is set when more
+// events match; pass it as After to read the next page.
+type HistoryPage struct {
+	Events     []StoredEvent `json:"events"`
+	NextCursor uint64        `json:"next_cursor,omitempty"`
+}
+
+// SignalHistory reads stored signals for a ticker, or for all tickers when
+// ticker is empty, oldest first
+func (c *EventClient) SignalHistory(ticker string, q HistoryQuery) (*HistoryPage, error) {
+	subject := SubjectSignalsAll
+	if ticker != "" {
+		var err error
+		if subject, err = tickerSubject(SubjectSignalsTicker, ticker); err != nil {
+			return nil, err
+		}
+	}
+	return c.history(StreamSignals, subject, q)
+}
+
+// history reads a page of events on subject from stream with a temporary
+// ordered consumer
+func (c *EventClient) history(stream, subject string, q HistoryQuery) (*HistoryPage, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultHistoryLimit
+	}
+	if q.Limit > MaxHistoryLimit {
+		q.Limit = MaxHistoryLimit
+	}
+
+	start := nats.DeliverAll()
+	if q.After > 0 {
+		start = nats.StartSequence(q.After + 1)
+	} else if !q.From.IsZero() {
+		start = nats.StartTime(q.From)
+	}
+
+	sub, err := c.js.SubscribeSync(subject, nats.BindStream(stream), nats.OrderedConsumer(), start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream %s: %w", stream, err)
+	}
+	defer sub.Unsubscribe()
+
+	page := &HistoryPage{Events: []StoredEvent{}}
+	info, err := sub.ConsumerInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream %s: %w", stream, err)
+	}
+	if info.NumPending == 0 {
+		return page, nil
+	}
+
+	for {
+		msg, err := sub.NextMsg(findMessageTimeout)
+		if errors.Is(err, nats.ErrTimeout) {
+			return page, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream %s: %w", stream, err)
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return nil, err
+		}
+		if !q.To.IsZero() && meta.Timestamp.After(q.To) {
+			return page, nil
+		}
+		if len(page.Events) == q.Limit {
+			// A further match exists, so the caller can continue after the last one
+			page.NextCursor = page.Events[len(page.Events)-1].Seq
+			return page, nil
+		}
+
+		data, err := Decode(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode event %d on %s: %w", meta.Sequence.Stream, msg.Subject, err)
+		}
+		page.Events = append(page.Events, StoredEvent{
+			Seq:       meta.Sequence.Stream,
+			Subject:   msg.Subject,
+			Published: meta.Timestamp.UTC(),
+			Data:      json.RawMessage(data),
+		})
+
+		if meta.NumPending == 0 {
+			return page, nil
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// pkg/events/latency.go
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Headers stamping an event with when it passed each stage of the pipeline,
+// as RFC 3339 UTC times with nanoseconds
+const (
+	HeaderFetchedAt   = "Tradinglab-Fetched-At"   // The data was fetched from the provider
+	HeaderPublishedAt = "Tradinglab-Published-At" // The source event was published
+	HeaderProcessedAt = "Tradinglab-Processed-At" // The hub published an event derived from it
+)
+
+// Stamps are the pipeline times of an event; zero times were not stamped
+type Stamps struct {
+	FetchedAt   time.Time
+	PublishedAt time.Time
+	ProcessedAt time.Time
+}
+
+// Origin returns the earliest stamped time, where end-to-end latency starts
+func (s Stamps) Origin() time.Time {
+	for _, t := range []time.Time{s.FetchedAt, s.PublishedAt, s.ProcessedAt} {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Sent returns when the event was last published: when the hub processed
+// it, or else when its source published it
+func (s Stamps) Sent() time.Time {
+	if !s.ProcessedAt.IsZero() {
+		return s.ProcessedAt
+	}
+	return s.PublishedAt
+}
+
+type stampsKey struct{}
+
+// WithStamps returns a context whose publishes carry stamps. A stamped
+// publish time is kept, so an event derived from another keeps the source's
+// times; otherwise the publish time is when the event is published.
+func WithStamps(ctx context.Context, stamps Stamps) context.Context {
+	return context.WithValue(ctx, stampsKey{}, stamps)
+}
+
+// WithFetchTime returns a context whose publishes record data fetched from
+// the provider at t
+func WithFetchTime(ctx context.Context, t time.Time) context.Context {
+	stamps := StampsFromContext(ctx)
+	stamps.FetchedAt = t
+	return WithStamps(ctx, stamps)
+}
+
+// StampsFromContext returns the stamps a context carries
+func StampsFromContext(ctx context.Context) Stamps {
+	if ctx == nil {
+		return Stamps{}
+	}
+	stamps, _ := ctx.Value(stampsKey{}).(Stamps)
+	return stamps
+}
+
+// ReadStamps returns the stamps in an event's headers, ignoring any that
+// are missing or malformed
+func ReadStamps(header nats.Header) Stamps {
+	parse := func(key string) time.Time {
+		t, err := time.Parse(time.RFC3339Nano, header.Get(key))
+		if err != nil {
+			return time.Time{}
+		}
+		return t
+	}
+	return Stamps{
+		FetchedAt:   parse(HeaderFetchedAt),
+		PublishedAt: parse(HeaderPublishedAt),
+		ProcessedAt: parse(HeaderProcessedAt),
+	}
+}
+
+// stamp sets the stamp headers on a message about to be published
+func stamp(ctx context.Context, msg *nats.Msg) {
+	stamps := StampsFromContext(ctx)
+	if stamps.PublishedAt.IsZero() {
+		stamps.PublishedAt = time.Now()
+	}
+	for key, t := range map[string]time.Time{
+		HeaderFetchedAt:   stamps.FetchedAt,
+		HeaderPublishedAt: stamps.PublishedAt,
+		HeaderProcessedAt: stamps.ProcessedAt,
+	} {
+		if !t.IsZero() {
+			msg.Header.Set(key, t.UTC().Format(time.RFC3339Nano))
+		}
+	}
+}
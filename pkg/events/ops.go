@@ -0,0 +1,152 @@
+// pkg/events/ops.go
+package events
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// StreamUsage reports a stream's storage use
+type StreamUsage struct {
+	Name          string     `json:"name"`
+	Subjects      []string   `json:"subjects"`
+	Storage       string     `json:"storage"`
+	Replicas      int        `json:"replicas"`
+	Messages      uint64     `json:"messages"`
+	Bytes         uint64     `json:"bytes"`
+	Consumers     int        `json:"consumers"`
+	FirstSeq      uint64     `json:"first_seq"`
+	LastSeq       uint64     `json:"last_seq"`
+	OldestMessage *time.Time `json:"oldest_message,omitempty"`
+	OldestAge     string     `json:"oldest_age,omitempty"`
+	MaxAge        string     `json:"max_age"`
+	MaxBytes      int64      `json:"max_bytes"` // -1 for unlimited
+}
+
+// PurgeResult reports what a purge removed
+type PurgeResult struct {
+	Stream    string     `json:"stream"`
+	Subject   string     `json:"subject,omitempty"`
+	Before    *time.Time `json:"before,omitempty"`
+	Purged    uint64     `json:"purged"`
+	Remaining uint64     `json:"remaining"`
+}
+
+// ErrUnknownStream is returned for stream names the client does not manage
+var ErrUnknownStream = errors.New("unknown stream")
+
+// findMessageTimeout bounds the lookup of the first message after a cutoff
+const findMessageTimeout = 2 * time.Second
+
+// StreamUsage returns storage usage for every stream the client manages
+func (c *EventClient) StreamUsage() ([]StreamUsage, error) {
+	usage := make([]StreamUsage, 0, len(c.streamConfigs))
+	for _, cfg := range c.streamConfigs {
+		info, err := c.js.StreamInfo(cfg.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get info for stream %s: %w", cfg.Name, err)
+		}
+		usage = append(usage, streamUsage(info))
+	}
+	return usage, nil
+}
+
+// streamUsage summarizes stream info
+func streamUsage(info *nats.StreamInfo) StreamUsage {
+	u := StreamUsage{
+		Name:      info.Config.Name,
+		Subjects:  info.Config.Subjects,
+		Storage:   info.Config.Storage.String(),
+		Replicas:  info.Config.Replicas,
+		Messages:  info.State.Msgs,
+		Bytes:     info.State.Bytes,
+		Consumers: info.State.Consumers,
+		FirstSeq:  info.State.FirstSeq,
+		LastSeq:   info.State.LastSeq,
+		MaxAge:    info.Config.MaxAge.String(),
+		MaxBytes:  info.Config.MaxBytes,
+	}
+	if info.State.Msgs > 0 && !info.State.FirstTime.IsZero() {
+		oldest := info.State.FirstTime
+		u.OldestMessage = &oldest
+		u.OldestAge = time.Since(oldest).Round(time.Second).String()
+	}
+	return u
+}
+
+// PurgeStream removes messages from a stream, optionally only those on a
+// subject and only those older than olderThan (zero purges regardless of age)
+func (c *EventClient) PurgeStream(stream, subject string, olderThan time.Duration) (*PurgeResult, error) {
+	cfg, err := c.streamConfig(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := c.js.StreamInfo(stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info for stream %s: %w", stream, err)
+	}
+
+	result := &PurgeResult{Stream: stream, Subject: subject}
+	req := &nats.StreamPurgeRequest{Subject: subject}
+	if olderThan > 0 {
+		cutoff := time.Now().Add(-olderThan)
+		result.Before = &cutoff
+
+		filter := subject
+		if filter == "" && len(cfg.Subjects) > 0 {
+			filter = cfg.Subjects[0]
+		}
+		seq, err := c.firstSequenceAfter(stream, filter, cutoff, before.State.LastSeq)
+		if err != nil {
+			return nil, err
+		}
+		if seq <= before.State.FirstSeq {
+			// Nothing is older than the cutoff
+			result.Remaining = before.State.Msgs
+			return result, nil
+		}
+		req.Sequence = seq
+	}
+
+	if err := c.js.PurgeStream(stream, req); err != nil {
+		return nil, fmt.Errorf("failed to purge stream %s: %w", stream, err)
+	}
+
+	after, err := c.js.StreamInfo(stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info for stream %s: %w", stream, err)
+	}
+	if before.State.Msgs > after.State.Msgs {
+		result.Purged = before.State.Msgs - after.State.Msgs
+	}
+	result.Remaining = after.State.Msgs
+	return result, nil
+}
+
+// firstSequenceAfter returns the stream sequence of the first message on
+// subject at or after cutoff, or lastSeq+1 when there is none
+func (c *EventClient) firstSequenceAfter(stream, subject string, cutoff time.Time, lastSeq uint64) (uint64, error) {
+	sub, err := c.js.SubscribeSync(subject, nats.BindStream(stream), nats.OrderedConsumer(), nats.StartTime(cutoff))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stream %s: %w", stream, err)
+	}
+	defer sub.Unsubscribe()
+
+	msg, err := sub.NextMsg(findMessageTimeout)
+	if errors.Is(err, nats.ErrTimeout) {
+		return lastSeq + 1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stream %s: %w", stream, err)
+	}
+
+	meta, err := msg.Metadata()
+	if err != nil {
+		return 0, err
+	}
+	return meta.Sequence.Stream, nil
+}
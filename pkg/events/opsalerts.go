@@ -0,0 +1,105 @@
+// pkg/events/opsalerts.go
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+	"github.com/nats-io/nats.go"
+)
+
+// Kinds of ops alert
+const (
+	OpsAlertStaleData = "stale_data" // A watched ticker's live data stopped during market hours
+)
+
+// OpsAlert reports an operational problem to whoever runs TradingLab, as
+// opposed to the market alerts delivered to subscribers. The same kind and
+// ticker is reported again with Resolved set once the problem clears.
+type OpsAlert struct {
+	Kind      string    `json:"kind"`
+	Service   string    `json:"service"` // The service that raised it
+	Ticker    string    `json:"ticker,omitempty"`
+	Message   string    `json:"message"`
+	Since     time.Time `json:"since,omitempty"` // When the problem began
+	Resolved  bool      `json:"resolved,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StreamRestart asks the market data service to restart a ticker's stream
+type StreamRestart struct {
+	Ticker      string    `json:"ticker"`
+	Reason      string    `json:"reason"`
+	RequestedBy string    `json:"requested_by"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// PublishOpsAlert publishes an ops alert for whoever is listening. Alerts
+// are not stored.
+func (c *EventClient) PublishOpsAlert(alert OpsAlert) error {
+	if !market.ValidSubjectToken(alert.Kind) {
+		return fmt.Errorf("alert kind %q cannot be used in a subject", alert.Kind)
+	}
+	if alert.Timestamp.IsZero() {
+		alert.Timestamp = time.Now().UTC()
+	}
+	msg, err := c.encodeMsg(fmt.Sprintf(SubjectOpsAlert, alert.Kind), alert)
+	if err != nil {
+		return err
+	}
+	return c.conn.PublishMsg(msg)
+}
+
+// SubscribeOpsAlerts delivers ops alerts of every kind published from now on
+func (c *EventClient) SubscribeOpsAlerts(handler func(OpsAlert)) (*nats.Subscription, error) {
+	return c.conn.Subscribe(SubjectOpsAlertAll, func(msg *nats.Msg) {
+		data, err := Decode(msg)
+		if err != nil {
+			utils.Error("Dropping message on %s: %v", msg.Subject, err)
+			return
+		}
+		var alert OpsAlert
+		if err := json.Unmarshal(data, &alert); err != nil {
+			utils.Error("Dropping invalid ops alert on %s: %v", msg.Subject, err)
+			return
+		}
+		handler(alert)
+	})
+}
+
+// PublishStreamRestart asks for a ticker's stream to be restarted
+func (c *EventClient) PublishStreamRestart(restart StreamRestart) error {
+	subject, err := tickerSubject(SubjectStreamRestart, restart.Ticker)
+	if err != nil {
+		return err
+	}
+	if restart.Timestamp.IsZero() {
+		restart.Timestamp = time.Now().UTC()
+	}
+	msg, err := c.encodeMsg(subject, restart)
+	if err != nil {
+		return err
+	}
+	return c.conn.PublishMsg(msg)
+}
+
+// SubscribeStreamRestarts delivers stream restart requests for every ticker
+func (c *EventClient) SubscribeStreamRestarts(handler func(StreamRestart)) (*nats.Subscription, error) {
+	return c.conn.Subscribe(SubjectStreamRestartAll, func(msg *nats.Msg) {
+		data, err := Decode(msg)
+		if err != nil {
+			utils.Error("Dropping message on %s: %v", msg.Subject, err)
+			return
+		}
+		var restart StreamRestart
+		if err := json.Unmarshal(data, &restart); err != nil || restart.Ticker == "" {
+			utils.Error("Dropping invalid stream restart on %s", msg.Subject)
+			return
+		}
+		restart.Ticker = market.NormalizeTicker(restart.Ticker)
+		handler(restart)
+	})
+}
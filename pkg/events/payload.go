@@ -0,0 +1,192 @@
+// pkg/events/payload.go
+package events
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/buildinfo"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/nats-io/nats.go"
+)
+
+// HeaderContentEncoding names the compression applied to an event payload
+const HeaderContentEncoding = "Content-Encoding"
+
+// HeaderVersion names the build of the service that published an event
+const HeaderVersion = "Tradinglab-Version"
+
+// EncodingGzip marks a gzip compressed payload
+const EncodingGzip = "gzip"
+
+// compressMinBytes is the smallest payload worth compressing
+const compressMinBytes = 1024
+
+// defaultMaxPayload is the NATS server default, used before the server reports its limit
+const defaultMaxPayload = 1024 * 1024
+
+// chunkOverhead is reserved in each chunk for metadata, headers and JSON framing
+const chunkOverhead = 4096
+
+// compressionFromEnv reports whether EVENTS_COMPRESSION enables gzip payloads
+func compressionFromEnv() bool {
+	return os.Getenv("EVENTS_COMPRESSION") == EncodingGzip
+}
+
+// encodeMsg serializes data for a subject with a deduplication ID, compressing
+// it when enabled and large enough. Timestamps are normalized to UTC RFC 3339.
+// Payloads over the server's max payload are rejected here with a clear error
+// rather than by the server.
+func (c *EventClient) encodeMsg(subject string, data interface{}) (*nats.Msg, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if payload, err = normalizeTimestamps(payload); err != nil {
+		return nil, fmt.Errorf("failed to normalize timestamps: %w", err)
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Header.Set(nats.MsgIdHdr, messageID(subject, data, payload))
+	msg.Header.Set(HeaderVersion, buildinfo.Get().Version)
+	if c.compress && len(payload) >= compressMinBytes {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			return nil, fmt.Errorf("failed to compress payload: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress payload: %w", err)
+		}
+		payload = buf.Bytes()
+		msg.Header.Set(HeaderContentEncoding, EncodingGzip)
+	}
+	msg.Data = payload
+
+	if max := c.conn.MaxPayload(); max > 0 && int64(len(payload)) > max {
+		return nil, fmt.Errorf("%w: %s payload is %d bytes, limit is %d", nats.ErrMaxPayload, subject, len(payload), max)
+	}
+	return msg, nil
+}
+
+// messageID derives a Nats-Msg-Id from the subject (data type and ticker), the
+// event timestamp and a hash of the payload. JetStream drops a republish with
+// the same ID inside the stream's duplicate window, so a publish retried after
+// a reconnect is stored once, while a revised bar for the same timestamp still
+// gets through.
+func messageID(subject string, data interface{}, payload []byte) string {
+	h := fnv.New64a()
+	h.Write(payload)
+	return fmt.Sprintf("%s:%s:%x", subject, eventTimestamp(data), h.Sum64())
+}
+
+// eventTimestamp returns the time an event describes, or "" if unknown
+func eventTimestamp(data interface{}) string {
+	var ts time.Time
+	switch v := data.(type) {
+	case *market.MarketData:
+		ts = v.Timestamp
+	case market.MarketData:
+		ts = v.Timestamp
+	case market.ChunkData:
+		// Each response is a new event even when it repeats earlier data, so a
+		// repeated request is never swallowed; only retries of this publish dedupe
+		return fmt.Sprintf("chunk-%d-%d-%d", v.Metadata.Chunk, v.Metadata.TotalChunks, time.Now().UnixNano())
+	case AsOfChunk:
+		// As-of answers repeat exactly by design, so they need this even more
+		return eventTimestamp(v.ChunkData)
+	case *market.OrderBook:
+		ts = v.Timestamp
+	case *market.Trade:
+		if v.ID != 0 {
+			return strconv.FormatInt(v.ID, 10)
+		}
+		ts = v.Timestamp
+	case market.Trade:
+		if v.ID != 0 {
+			return strconv.FormatInt(v.ID, 10)
+		}
+		ts = v.Timestamp
+	case map[string]interface{}:
+		for _, key := range []string{"timestamp", "date", "time"} {
+			if value, ok := v[key]; ok {
+				return fmt.Sprint(value)
+			}
+		}
+	}
+	if ts.IsZero() {
+		return ""
+	}
+	return ts.UTC().Format(time.RFC3339Nano)
+}
+
+// Decode returns the JSON payload of a message, decompressing it if needed
+func Decode(msg *nats.Msg) ([]byte, error) {
+	return decodePayload(msg.Header, msg.Data)
+}
+
+// decodePayload decompresses data according to its content encoding header
+func decodePayload(header nats.Header, data []byte) ([]byte, error) {
+	switch encoding := header.Get(HeaderContentEncoding); encoding {
+	case "":
+		return data, nil
+	case EncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress payload: %w", err)
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// ChunkBudget returns how many serialized bytes of records fit in one chunk:
+// the server's max payload, or EVENTS_MAX_CHUNK_BYTES if smaller, less room
+// for chunk metadata. The budget is measured before compression.
+func (c *EventClient) ChunkBudget() int {
+	budget := int(c.conn.MaxPayload())
+	if budget <= 0 {
+		budget = defaultMaxPayload
+	}
+	if custom, err := strconv.Atoi(os.Getenv("EVENTS_MAX_CHUNK_BYTES")); err == nil && custom > 0 && custom < budget {
+		budget = custom
+	}
+	if budget -= chunkOverhead; budget < chunkOverhead {
+		budget = chunkOverhead
+	}
+	return budget
+}
+
+// ChunkHistoricalData splits historical data into as few chunks as possible
+// whose serialized records fit within budget bytes. A single record larger
+// than the budget gets a chunk of its own.
+func ChunkHistoricalData(data []*market.MarketData, budget int) ([][]*market.MarketData, error) {
+	var chunks [][]*market.MarketData
+	start, size := 0, 0
+	for i, record := range data {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		recordSize := len(encoded) + 1 // Separating comma
+
+		if i > start && size+recordSize > budget {
+			chunks = append(chunks, data[start:i])
+			start, size = i, 0
+		}
+		size += recordSize
+	}
+	if start < len(data) || len(chunks) == 0 {
+		chunks = append(chunks, data[start:])
+	}
+	return chunks, nil
+}
@@ -0,0 +1,55 @@
+// pkg/events/priority.go
+package events
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Priority orders historical data requests; higher values are served first
+type Priority int
+
+// Historical request priorities, carried as the "priority" field of the request payload
+const (
+	// PriorityBackfill is for batch jobs that nobody is waiting on
+	PriorityBackfill Priority = iota
+	// PriorityNormal is the default for requests that do not say
+	PriorityNormal
+	// PriorityInteractive is for user-facing requests such as chart loads
+	PriorityInteractive
+)
+
+// String returns the payload name of a priority
+func (p Priority) String() string {
+	switch p {
+	case PriorityBackfill:
+		return "backfill"
+	case PriorityInteractive:
+		return "interactive"
+	default:
+		return "normal"
+	}
+}
+
+// ParsePriority parses a priority name, defaulting to PriorityNormal
+func ParsePriority(name string) Priority {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "backfill", "batch", "low":
+		return PriorityBackfill
+	case "interactive", "high":
+		return PriorityInteractive
+	default:
+		return PriorityNormal
+	}
+}
+
+// RequestPriority reads the priority of a historical request payload
+func RequestPriority(reqData []byte) Priority {
+	var request struct {
+		Priority string `json:"priority"`
+	}
+	if err := json.Unmarshal(reqData, &request); err != nil {
+		return PriorityNormal
+	}
+	return ParsePriority(request.Priority)
+}
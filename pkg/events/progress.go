@@ -0,0 +1,132 @@
+// pkg/events/progress.go
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+	"github.com/nats-io/nats.go"
+)
+
+// Stages of a historical request reported in progress events
+const (
+	ProgressQueued     = "queued"     // Waiting for a worker
+	ProgressFetching   = "fetching"   // Fetching from the provider
+	ProgressPublishing = "publishing" // Fetched; publishing chunks
+	ProgressDone       = "done"       // Every chunk published
+	ProgressFailed     = "failed"     // Stopped; Error says why
+	ProgressCancelled  = "cancelled"  // Stopped at the requester's request
+)
+
+// RequestProgress reports how far a historical request has got. The
+// provider returns a request's bars in one call, so FetchedPercent moves
+// from 0 to 100 when the fetch completes; chunks are then counted as they
+// are published.
+type RequestProgress struct {
+	RequestID       string    `json:"request_id"`
+	Ticker          string    `json:"ticker"`
+	Timeframe       string    `json:"timeframe"`
+	Days            int       `json:"days"`
+	Stage           string    `json:"stage"`
+	FetchedPercent  float64   `json:"fetched_percent"`
+	Records         int       `json:"records,omitempty"`
+	ChunksPublished int       `json:"chunks_published"`
+	TotalChunks     int       `json:"total_chunks,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// Finished reports whether no more progress will follow
+func (p RequestProgress) Finished() bool {
+	return p.Stage == ProgressDone || p.Stage == ProgressFailed || p.Stage == ProgressCancelled
+}
+
+// NewRequestID returns a request ID usable as a subject token, e.g.
+// "ws-1718000000000000000-1a2b3c4d"
+func NewRequestID(source string) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", source, time.Now().UnixNano(), hex.EncodeToString(suffix))
+}
+
+// RequestID reads the request_id of a historical request payload
+func RequestID(reqData []byte) string {
+	var request struct {
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(reqData, &request); err != nil {
+		return ""
+	}
+	return request.RequestID
+}
+
+// RequestAsOf reads the as_of time of a historical request payload, which
+// asks for bars as they were known then rather than as fetched now. It is
+// zero when the request has none or it does not parse.
+func RequestAsOf(reqData []byte) time.Time {
+	var request struct {
+		AsOf string `json:"as_of"`
+	}
+	if err := json.Unmarshal(reqData, &request); err != nil || request.AsOf == "" {
+		return time.Time{}
+	}
+	asOf, err := market.ParseTimestamp(request.AsOf, nil)
+	if err != nil {
+		return time.Time{}
+	}
+	return asOf
+}
+
+// ProgressSubject returns the subject progress for a request is published
+// on. IDs that are not a single subject token have no progress subject.
+func ProgressSubject(requestID string) (string, error) {
+	if !market.ValidSubjectToken(requestID) {
+		return "", fmt.Errorf("request ID %q cannot be used in a subject", requestID)
+	}
+	return fmt.Sprintf(SubjectRequestProgress, requestID), nil
+}
+
+// PublishRequestProgress publishes the progress of a historical request
+func (c *EventClient) PublishRequestProgress(ctx context.Context, progress RequestProgress) error {
+	subject, err := ProgressSubject(progress.RequestID)
+	if err != nil {
+		return err
+	}
+	if progress.Timestamp.IsZero() {
+		progress.Timestamp = time.Now().UTC()
+	}
+	msg, err := c.encodeMsg(subject, progress)
+	if err != nil {
+		return err
+	}
+
+	return c.publish(ctx, msg)
+}
+
+// SubscribeRequestProgress delivers the progress of a historical request
+// published from now on
+func (c *EventClient) SubscribeRequestProgress(requestID string, handler func(RequestProgress)) (*nats.Subscription, error) {
+	subject, err := ProgressSubject(requestID)
+	if err != nil {
+		return nil, err
+	}
+	return c.conn.Subscribe(subject, func(msg *nats.Msg) {
+		data, err := Decode(msg)
+		if err != nil {
+			utils.Error("Dropping message on %s: %v", msg.Subject, err)
+			return
+		}
+		var progress RequestProgress
+		if err := json.Unmarshal(data, &progress); err != nil {
+			utils.Error("Dropping invalid progress on %s: %v", msg.Subject, err)
+			return
+		}
+		handler(progress)
+	})
+}
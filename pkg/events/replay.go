@@ -0,0 +1,48 @@
+// pkg/events/replay.go
+package events
+
+import (
+	"fmt"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+	"github.com/nats-io/nats.go"
+)
+
+// SubscribeReplay delivers messages on subject in stream order together with
+// their stream sequence and pipeline stamps. With after set, it first
+// replays the messages stored after that sequence and then continues with
+// live ones, without a gap; with after zero it delivers only new messages.
+func (c *EventClient) SubscribeReplay(subject string, after uint64, handler func(data []byte, seq uint64, stamps Stamps)) (*nats.Subscription, error) {
+	start := nats.DeliverNew()
+	if after > 0 {
+		start = nats.StartSequence(after + 1)
+	}
+
+	return c.js.Subscribe(subject, func(msg *nats.Msg) {
+		meta, err := msg.Metadata()
+		if err != nil {
+			utils.Error("Dropping message without metadata on %s: %v", msg.Subject, err)
+			return
+		}
+		data, err := Decode(msg)
+		if err != nil {
+			utils.Error("Dropping message on %s: %v", msg.Subject, err)
+			return
+		}
+		handler(data, meta.Sequence.Stream, ReadStamps(msg.Header))
+	}, nats.OrderedConsumer(), start)
+}
+
+// FirstSequence returns the oldest sequence still stored in the stream
+// holding subject. Replays from before it have lost messages.
+func (c *EventClient) FirstSequence(subject string) (uint64, error) {
+	stream, err := c.js.StreamNameBySubject(subject)
+	if err != nil {
+		return 0, fmt.Errorf("no stream for %s: %w", subject, err)
+	}
+	info, err := c.js.StreamInfo(stream)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get info for stream %s: %w", stream, err)
+	}
+	return info.State.FirstSeq, nil
+}
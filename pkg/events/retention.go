@@ -0,0 +1,133 @@
+// pkg/events/retention.go
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/config"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/nats-io/nats.go"
+)
+
+// RetentionStreams hold historical market data, which is kept per asset
+// class and interval by a config.RetentionPolicy rather than by one stream
+// MaxAge
+var RetentionStreams = []string{StreamMarketDaily, StreamMarketHistorical}
+
+// RetentionPurge reports messages removed from one subject
+type RetentionPurge struct {
+	Stream     string `json:"stream"`
+	Subject    string `json:"subject"`
+	AssetClass string `json:"asset_class"`
+	Interval   string `json:"interval"`
+	MaxAge     string `json:"max_age"`
+	Purged     uint64 `json:"purged"`
+}
+
+// RetentionSummary reports what a retention run did
+type RetentionSummary struct {
+	StreamMaxAge map[string]string `json:"stream_max_age"` // Zero is unlimited
+	Purged       []RetentionPurge  `json:"purged"`
+	Errors       []string          `json:"errors,omitempty"`
+}
+
+// isRetentionStream reports whether a stream's data is kept by the retention policy
+func isRetentionStream(name string) bool {
+	for _, stream := range RetentionStreams {
+		if stream == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyRetention sets the MaxAge of the retention streams to the policy's
+// longest max age, so the stream limit never removes data the policy keeps.
+// Shorter max ages are enforced per subject by EnforceRetention.
+func ApplyRetention(configs []StreamConfig, policy config.RetentionPolicy) []StreamConfig {
+	result := make([]StreamConfig, len(configs))
+	for i, cfg := range configs {
+		if isRetentionStream(cfg.Name) {
+			cfg.MaxAge = int64(policy.Longest())
+		}
+		result[i] = cfg
+	}
+	return result
+}
+
+// subjectSeries returns the ticker and interval of a historical market data
+// subject
+func subjectSeries(subject string) (ticker, interval string, ok bool) {
+	tokens := strings.Split(subject, ".")
+	switch {
+	case len(tokens) == 3 && tokens[0] == "market" && tokens[1] == "daily":
+		return tokens[2], market.Interval1Day, true
+	case len(tokens) >= 5 && tokens[0] == "market" && tokens[1] == "historical" &&
+		(tokens[2] == "data" || tokens[2] == "sync"):
+		interval := tokens[4]
+		if normalized, err := market.NormalizeInterval(interval); err == nil {
+			interval = normalized
+		}
+		return tokens[3], interval, true
+	}
+	return "", "", false
+}
+
+// EnforceRetention applies a retention policy: it updates the retention
+// streams' MaxAge unless an environment override pins it, then purges each
+// subject's messages older than its asset class and interval allow
+func (c *EventClient) EnforceRetention(ctx context.Context, policy config.RetentionPolicy) (*RetentionSummary, error) {
+	summary := &RetentionSummary{StreamMaxAge: make(map[string]string), Purged: []RetentionPurge{}}
+
+	for _, cfg := range c.streamConfigs {
+		if !isRetentionStream(cfg.Name) {
+			continue
+		}
+		if !c.pinnedMaxAge[cfg.Name] {
+			cfg.MaxAge = int64(policy.Longest())
+			if err := c.createOrUpdateStream(cfg); err != nil {
+				return summary, fmt.Errorf("failed to update stream %s: %w", cfg.Name, err)
+			}
+		}
+		summary.StreamMaxAge[cfg.Name] = time.Duration(cfg.MaxAge).String()
+
+		info, err := c.js.StreamInfo(cfg.Name, &nats.StreamInfoRequest{SubjectsFilter: ">"})
+		if err != nil {
+			return summary, fmt.Errorf("failed to get info for stream %s: %w", cfg.Name, err)
+		}
+		for subject := range info.State.Subjects {
+			if err := ctx.Err(); err != nil {
+				return summary, err
+			}
+			ticker, interval, ok := subjectSeries(subject)
+			if !ok {
+				continue
+			}
+			assetClass := market.AssetClassOf(ticker)
+			maxAge := policy.MaxAge(assetClass, interval)
+			if maxAge == 0 {
+				continue
+			}
+
+			result, err := c.PurgeStream(cfg.Name, subject, maxAge)
+			if err != nil {
+				summary.Errors = append(summary.Errors, fmt.Sprintf("%s %s: %v", cfg.Name, subject, err))
+				continue
+			}
+			if result.Purged > 0 {
+				summary.Purged = append(summary.Purged, RetentionPurge{
+					Stream:     cfg.Name,
+					Subject:    subject,
+					AssetClass: assetClass,
+					Interval:   interval,
+					MaxAge:     maxAge.String(),
+					Purged:     result.Purged,
+				})
+			}
+		}
+	}
+	return summary, nil
+}
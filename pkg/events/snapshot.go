@@ -0,0 +1,149 @@
+// pkg/events/snapshot.go
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// exportFetchTimeout bounds the wait for the next message while exporting
+const exportFetchTimeout = 5 * time.Second
+
+// maxSnapshotRecord bounds one line of a stream export
+const maxSnapshotRecord = 16 * 1024 * 1024
+
+// snapshotRecord is one exported message. Data holds the payload exactly as
+// stored, so compressed payloads stay compressed.
+type snapshotRecord struct {
+	Seq       uint64      `json:"seq"`
+	Subject   string      `json:"subject"`
+	Published time.Time   `json:"published"`
+	Header    nats.Header `json:"header,omitempty"`
+	Data      []byte      `json:"data"`
+}
+
+// StreamNames returns the names of the streams the client manages
+func (c *EventClient) StreamNames() []string {
+	names := make([]string, 0, len(c.streamConfigs))
+	for _, cfg := range c.streamConfigs {
+		names = append(names, cfg.Name)
+	}
+	return names
+}
+
+// streamConfig returns the managed config for a stream
+func (c *EventClient) streamConfig(stream string) (*StreamConfig, error) {
+	for i := range c.streamConfigs {
+		if c.streamConfigs[i].Name == stream {
+			return &c.streamConfigs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownStream, stream)
+}
+
+// ExportStream writes every message stored in a stream when the export
+// starts to w as JSON lines, oldest first, returning how many were written
+func (c *EventClient) ExportStream(ctx context.Context, stream string, w io.Writer) (uint64, error) {
+	if _, err := c.streamConfig(stream); err != nil {
+		return 0, err
+	}
+	info, err := c.js.StreamInfo(stream)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get info for stream %s: %w", stream, err)
+	}
+	if info.State.Msgs == 0 {
+		return 0, nil
+	}
+	lastSeq := info.State.LastSeq
+
+	sub, err := c.js.SubscribeSync("", nats.BindStream(stream), nats.OrderedConsumer(), nats.DeliverAll())
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stream %s: %w", stream, err)
+	}
+	defer sub.Unsubscribe()
+
+	out := json.NewEncoder(w)
+	var written uint64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		msg, err := sub.NextMsg(exportFetchTimeout)
+		if err != nil {
+			return written, fmt.Errorf("failed to read stream %s after %d messages: %w", stream, written, err)
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			return written, err
+		}
+		if meta.Sequence.Stream > lastSeq {
+			return written, nil
+		}
+
+		record := snapshotRecord{
+			Seq:       meta.Sequence.Stream,
+			Subject:   msg.Subject,
+			Published: meta.Timestamp,
+			Header:    msg.Header,
+			Data:      msg.Data,
+		}
+		if err := out.Encode(record); err != nil {
+			return written, err
+		}
+		written++
+		if meta.Sequence.Stream == lastSeq || meta.NumPending == 0 {
+			return written, nil
+		}
+	}
+}
+
+// ImportStream republishes messages written by ExportStream into a stream,
+// in order. Restored messages get new stream sequences and timestamps. With
+// replace the stream is purged first; otherwise it must be empty, so a
+// restore never duplicates messages.
+func (c *EventClient) ImportStream(ctx context.Context, stream string, r io.Reader, replace bool) (uint64, error) {
+	if _, err := c.streamConfig(stream); err != nil {
+		return 0, err
+	}
+	if replace {
+		if err := c.js.PurgeStream(stream); err != nil {
+			return 0, fmt.Errorf("failed to purge stream %s: %w", stream, err)
+		}
+	} else {
+		info, err := c.js.StreamInfo(stream)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get info for stream %s: %w", stream, err)
+		}
+		if info.State.Msgs > 0 {
+			return 0, fmt.Errorf("stream %s has %d messages; restore with replace to overwrite them", stream, info.State.Msgs)
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxSnapshotRecord)
+	var restored uint64
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return restored, err
+		}
+		var record snapshotRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return restored, fmt.Errorf("invalid record after %d messages: %w", restored, err)
+		}
+		msg := &nats.Msg{Subject: record.Subject, Header: record.Header, Data: record.Data}
+		if _, err := c.js.PublishMsg(msg, nats.ExpectStream(stream), nats.Context(ctx)); err != nil {
+			return restored, fmt.Errorf("failed to restore message %d to %s: %w", record.Seq, stream, err)
+		}
+		restored++
+	}
+	if err := scanner.Err(); err != nil {
+		return restored, fmt.Errorf("failed to read stream export: %w", err)
+	}
+	return restored, nil
+}
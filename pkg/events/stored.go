@@ -0,0 +1,152 @@
+// pkg/events/stored.go
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/nats-io/nats.go"
+)
+
+// Where stored historical bars were read from
+const (
+	StoredSourceChunks = "stream" // The last complete set of chunks published for the request
+	StoredSourceSync   = "sync"   // Bars published by incremental sync
+)
+
+// AsOfChunk is a chunk of bars served as known at an earlier time. The
+// as_of field sets it apart from current data on the same subject.
+type AsOfChunk struct {
+	market.ChunkData
+	AsOf string `json:"as_of,omitempty"`
+}
+
+// StoredHistoricalData reads historical bars persisted in the MARKET_HISTORICAL
+// stream, for serving when the trading service is down. It prefers the last
+// complete set of chunks published for exactly these parameters, then bars
+// from incremental sync within the last days, and returns the bars oldest
+// first with the source they came from. nats.ErrMsgNotFound is returned when
+// neither has any.
+func (c *EventClient) StoredHistoricalData(ticker, timeframe string, days int) ([]*market.MarketData, string, error) {
+	subject, err := historicalSubject(SubjectMarketHistoricalData, ticker, timeframe, days)
+	if err != nil {
+		return nil, "", err
+	}
+	bars, err := c.lastCompleteChunks(subject)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(bars) > 0 {
+		return bars, StoredSourceChunks, nil
+	}
+
+	subject, err = syncSubject(ticker, timeframe)
+	if err != nil {
+		return nil, "", err
+	}
+	bars, err = c.syncedBars(subject, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(bars) > 0 {
+		return bars, StoredSourceSync, nil
+	}
+	return nil, "", nats.ErrMsgNotFound
+}
+
+// lastCompleteChunks returns the bars of the last complete set of chunks on
+// subject, or nil if no set is complete
+func (c *EventClient) lastCompleteChunks(subject string) ([]*market.MarketData, error) {
+	var current, complete []*market.MarketData
+	next := 1
+	err := c.readStored(subject, nats.DeliverAll(), func(data []byte) {
+		// Chunks served as of an earlier time are not current data
+		var chunk AsOfChunk
+		if err := json.Unmarshal(data, &chunk); err != nil || chunk.AsOf != "" {
+			return
+		}
+		meta := chunk.Metadata
+		switch {
+		case meta.Chunk == 1:
+			current, next = nil, 1
+		case meta.Chunk != next:
+			// A chunk went missing, so this set can never complete
+			current, next = nil, 0
+			return
+		}
+		current = append(current, chunk.Data...)
+		next++
+		if meta.Chunk == meta.TotalChunks {
+			complete, current, next = current, nil, 0
+		}
+	})
+	return complete, err
+}
+
+// syncedBars returns the bars on subject from since on, keeping the last
+// version of each bar since sync republishes the last bar it synced
+func (c *EventClient) syncedBars(subject string, since time.Time) ([]*market.MarketData, error) {
+	byTime := make(map[int64]*market.MarketData)
+	// A bar is published after it starts, so earlier messages hold only older bars
+	err := c.readStored(subject, nats.StartTime(since), func(data []byte) {
+		var chunk market.ChunkData
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return
+		}
+		for _, bar := range chunk.Data {
+			if bar != nil && !bar.Timestamp.Before(since) {
+				byTime[bar.Timestamp.UnixNano()] = bar
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bars := make([]*market.MarketData, 0, len(byTime))
+	for _, bar := range byTime {
+		bars = append(bars, bar)
+	}
+	sort.Slice(bars, func(i, j int) bool { return bars[i].Timestamp.Before(bars[j].Timestamp) })
+	return bars, nil
+}
+
+// readStored passes each decoded message on subject in MARKET_HISTORICAL from
+// start on to handle, oldest first
+func (c *EventClient) readStored(subject string, start nats.SubOpt, handle func([]byte)) error {
+	sub, err := c.js.SubscribeSync(subject, nats.BindStream(StreamMarketHistorical), nats.OrderedConsumer(), start)
+	if err != nil {
+		return fmt.Errorf("failed to read stream %s: %w", StreamMarketHistorical, err)
+	}
+	defer sub.Unsubscribe()
+
+	info, err := sub.ConsumerInfo()
+	if err != nil {
+		return fmt.Errorf("failed to read stream %s: %w", StreamMarketHistorical, err)
+	}
+	if info.NumPending == 0 {
+		return nil
+	}
+
+	for {
+		msg, err := sub.NextMsg(findMessageTimeout)
+		if errors.Is(err, nats.ErrTimeout) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read stream %s: %w", StreamMarketHistorical, err)
+		}
+		if data, err := Decode(msg); err == nil {
+			handle(data)
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil || meta.NumPending == 0 {
+			return err
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package events
 
-import "github.com/nats-io/nats.go"
+import (
+	"fmt"
+
+	"github.com/myapp/tradinglab/pkg/config"
+	"github.com/nats-io/nats.go"
+)
 
 // Stream definitions for the event system
 const (
@@ -16,6 +21,17 @@ const (
 	StreamRecommendations = "RECOMMENDATIONS"
 	// StreamRequests handles data requests from clients
 	StreamRequests = "REQUESTS"
+	// StreamRisk handles portfolio risk events
+	StreamRisk = "RISK"
+	// StreamMarketAnalytics handles derived intraday analytics such as VWAP,
+	// and anomalies flagged in live data
+	StreamMarketAnalytics = "MARKET_ANALYTICS"
+	// StreamMarketBook handles order book depth snapshots
+	StreamMarketBook = "MARKET_BOOK"
+	// StreamTrades handles individual trade prints (time & sales)
+	StreamTrades = "TRADES"
+	// StreamSummaries handles cross-ticker summaries such as the daily summary
+	StreamSummaries = "SUMMARIES"
 )
 
 // Subject patterns for each stream
@@ -24,6 +40,10 @@ const (
 	SubjectMarketLiveTicker = "market.live.%s" // e.g., market.live.AAPL
 	SubjectMarketLiveAll    = "market.live.*"  // All tickers
 
+	// Subject patterns for consolidated live bars, published when an interval closes
+	SubjectMarketBarsTicker = "market.bars.%s.%s" // ticker, interval, e.g., market.bars.AAPL.5min
+	SubjectMarketBarsAll    = "market.bars.>"     // All tickers and intervals
+
 	// Subject patterns for market daily data
 	SubjectMarketDailyTicker = "market.daily.%s" // e.g., market.daily.AAPL
 	SubjectMarketDailyAll    = "market.daily.*"  // All tickers
@@ -34,6 +54,11 @@ const (
 	SubjectMarketHistoricalData    = "market.historical.data.%s.%s.%d"    // ticker, timeframe, days
 	SubjectMarketHistoricalAll     = "market.historical.data.>"           // All historical data (use > for multi-level wildcard)
 
+	// Subject patterns for incremental historical sync; each message holds
+	// only the bars added since the previous sync
+	SubjectMarketHistoricalSync    = "market.historical.sync.%s.%s" // ticker, timeframe
+	SubjectMarketHistoricalSyncAll = "market.historical.sync.>"
+
 	// Subject patterns for signals
 	SubjectSignalsTicker = "signals.%s" // e.g., signals.AAPL
 	SubjectSignalsAll    = "signals.*"  // All signals
@@ -44,6 +69,60 @@ const (
 
 	// Subject patterns for data requests
 	SubjectRequestsHistorical = "requests.historical.%s.%s.%d" // ticker, timeframe, days
+	SubjectRequestProgress    = "requests.progress.%s"         // request ID
+	SubjectRequestCancel      = "requests.cancel.%s"           // request ID
+	SubjectRequestCancelAll   = "requests.cancel.*"
+
+	// Subject patterns for risk events
+	SubjectRiskEvent = "risk.%s" // e.g., risk.daily_loss_limit
+	SubjectRiskAll   = "risk.*"  // All risk events
+
+	// Subject patterns for intraday analytics
+	SubjectMarketAnalyticsTicker = "market.analytics.%s" // e.g., market.analytics.AAPL
+	SubjectMarketAnalyticsAll    = "market.analytics.*"  // All tickers
+
+	// Subject patterns for live data anomalies flagged by the hub
+	SubjectMarketAnomalyTicker = "market.anomaly.%s" // e.g., market.anomaly.AAPL
+	SubjectMarketAnomalyAll    = "market.anomaly.*"  // All tickers
+
+	// Subject patterns for order book snapshots
+	SubjectMarketBookTicker = "market.book.%s" // e.g., market.book.AAPL
+	SubjectMarketBookAll    = "market.book.*"  // All tickers
+
+	// Subject patterns for trade prints
+	SubjectTradesTicker = "market.trades.%s" // e.g., market.trades.AAPL
+	SubjectTradesAll    = "market.trades.*"  // All tickers
+
+	// Subject patterns for cross-ticker summaries
+	SubjectSummaryDaily = "summary.daily.%s" // Market date, e.g., summary.daily.2024-03-01
+	SubjectSummaryAll   = "summary.>"        // All summaries
+
+	// Subjects for reference data queries. These use core NATS request/reply
+	// and are deliberately outside every stream.
+	SubjectReferenceSymbolSearch = "reference.symbols.search"
+	SubjectReferenceMarketClock  = "reference.market.clock"
+
+	// Subject patterns for alerts delivered to subscribers. Alerts are core
+	// NATS messages, outside every stream, relayed to WebSocket clients.
+	SubjectAlert           = "alerts.%s.%s" // subscriber ID, ticker
+	SubjectAlertSubscriber = "alerts.%s.*"  // All tickers of a subscriber
+
+	// Subject for a backtest job's progress. Progress is core NATS, outside
+	// every stream, relayed to the job owner's WebSocket connections.
+	SubjectBacktestProgress = "backtest.%s" // job ID
+
+	// Subjects for service liveness. Heartbeats are core NATS messages,
+	// outside every stream; the hub answers service queries.
+	SubjectHeartbeat    = "ops.heartbeat.%s" // e.g., ops.heartbeat.gateway
+	SubjectHeartbeatAll = "ops.heartbeat.*"  // All services
+	SubjectServices     = "ops.services"
+
+	// Subjects for ops alerts and the stream restarts they may prompt. Both
+	// are core NATS messages, outside every stream.
+	SubjectOpsAlert         = "ops.alerts.%s"          // Alert kind, e.g., ops.alerts.stale_data
+	SubjectOpsAlertAll      = "ops.alerts.*"           // All kinds
+	SubjectStreamRestart    = "ops.streams.restart.%s" // e.g., ops.streams.restart.AAPL
+	SubjectStreamRestartAll = "ops.streams.restart.*"  // All tickers
 )
 
 // StreamConfig defines the configuration for each stream
@@ -55,6 +134,8 @@ type StreamConfig struct {
 	Replicas  int
 	Discard   nats.DiscardPolicy
 	Retention nats.RetentionPolicy
+	MaxBytes  int64 // Zero for unlimited
+	MaxMsgs   int64 // Zero for unlimited
 }
 
 // GetStreamConfigs returns all stream configurations
@@ -62,7 +143,7 @@ func GetStreamConfigs() []StreamConfig {
 	return []StreamConfig{
 		{
 			Name:      StreamMarketLive,
-			Subjects:  []string{SubjectMarketLiveAll},
+			Subjects:  []string{SubjectMarketLiveAll, SubjectMarketBarsAll},
 			MaxAge:    24 * 60 * 60 * 1e9, // 24 hours in nanoseconds
 			Storage:   nats.MemoryStorage,
 			Replicas:  1,
@@ -72,7 +153,7 @@ func GetStreamConfigs() []StreamConfig {
 		{
 			Name:      StreamMarketDaily,
 			Subjects:  []string{SubjectMarketDailyAll},
-			MaxAge:    30 * 24 * 60 * 60 * 1e9, // 30 days in nanoseconds
+			MaxAge:    30 * 24 * 60 * 60 * 1e9, // Replaced by the retention policy's longest max age
 			Storage:   nats.FileStorage,
 			Replicas:  1,
 			Discard:   nats.DiscardOld,
@@ -80,8 +161,8 @@ func GetStreamConfigs() []StreamConfig {
 		},
 		{
 			Name:      StreamMarketHistorical,
-			Subjects:  []string{SubjectMarketHistoricalAll},
-			MaxAge:    30 * 24 * 60 * 60 * 1e9, // 30 days in nanoseconds
+			Subjects:  []string{SubjectMarketHistoricalAll, SubjectMarketHistoricalSyncAll},
+			MaxAge:    30 * 24 * 60 * 60 * 1e9, // Replaced by the retention policy's longest max age
 			Storage:   nats.FileStorage,
 			Replicas:  1,
 			Discard:   nats.DiscardOld,
@@ -105,6 +186,51 @@ func GetStreamConfigs() []StreamConfig {
 			Discard:   nats.DiscardOld,
 			Retention: nats.LimitsPolicy,
 		},
+		{
+			Name:      StreamRisk,
+			Subjects:  []string{SubjectRiskAll},
+			MaxAge:    30 * 24 * 60 * 60 * 1e9, // 30 days in nanoseconds
+			Storage:   nats.FileStorage,
+			Replicas:  1,
+			Discard:   nats.DiscardOld,
+			Retention: nats.LimitsPolicy,
+		},
+		{
+			Name:      StreamMarketAnalytics,
+			Subjects:  []string{SubjectMarketAnalyticsAll, SubjectMarketAnomalyAll},
+			MaxAge:    24 * 60 * 60 * 1e9, // 24 hours in nanoseconds
+			Storage:   nats.MemoryStorage,
+			Replicas:  1,
+			Discard:   nats.DiscardOld,
+			Retention: nats.LimitsPolicy,
+		},
+		{
+			Name:      StreamMarketBook,
+			Subjects:  []string{SubjectMarketBookAll},
+			MaxAge:    1 * 60 * 60 * 1e9, // 1 hour in nanoseconds
+			Storage:   nats.MemoryStorage,
+			Replicas:  1,
+			Discard:   nats.DiscardOld,
+			Retention: nats.LimitsPolicy,
+		},
+		{
+			Name:      StreamTrades,
+			Subjects:  []string{SubjectTradesAll},
+			MaxAge:    15 * 60 * 1e9, // 15 minutes in nanoseconds; the tape is high volume
+			Storage:   nats.MemoryStorage,
+			Replicas:  1,
+			Discard:   nats.DiscardOld,
+			Retention: nats.LimitsPolicy,
+		},
+		{
+			Name:      StreamSummaries,
+			Subjects:  []string{SubjectSummaryAll},
+			MaxAge:    365 * 24 * 60 * 60 * 1e9, // 1 year in nanoseconds; one small summary a day
+			Storage:   nats.FileStorage,
+			Replicas:  1,
+			Discard:   nats.DiscardOld,
+			Retention: nats.LimitsPolicy,
+		},
 		{
 			Name:      StreamRequests,
 			Subjects:  []string{"requests.>"},
@@ -116,3 +242,42 @@ func GetStreamConfigs() []StreamConfig {
 		},
 	}
 }
+
+// ApplyOverrides returns the stream configurations with an environment's
+// overrides applied. Overrides naming an unknown stream are rejected.
+func ApplyOverrides(configs []StreamConfig, overrides config.StreamOverrides, env string) ([]StreamConfig, error) {
+	known := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		known[cfg.Name] = true
+	}
+	for _, name := range overrides.Streams(env) {
+		if !known[name] {
+			return nil, fmt.Errorf("stream config overrides unknown stream %s", name)
+		}
+	}
+
+	result := make([]StreamConfig, len(configs))
+	for i, cfg := range configs {
+		override := overrides.For(env, cfg.Name)
+		if override.MaxAge != 0 {
+			cfg.MaxAge = int64(override.MaxAge)
+		}
+		switch override.Storage {
+		case config.StorageFile:
+			cfg.Storage = nats.FileStorage
+		case config.StorageMemory:
+			cfg.Storage = nats.MemoryStorage
+		}
+		if override.Replicas != 0 {
+			cfg.Replicas = override.Replicas
+		}
+		if override.MaxBytes != 0 {
+			cfg.MaxBytes = override.MaxBytes
+		}
+		if override.MaxMsgs != 0 {
+			cfg.MaxMsgs = override.MaxMsgs
+		}
+		result[i] = cfg
+	}
+	return result, nil
+}
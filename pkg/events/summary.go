@@ -0,0 +1,116 @@
+// pkg/events/summary.go
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// SummaryDateLayout is the layout of a daily summary's market date
+const SummaryDateLayout = "2006-01-02"
+
+// TickerMove is a watched ticker's move over the session
+type TickerMove struct {
+	Ticker        string  `json:"ticker"`
+	Open          float64 `json:"open"` // First price of the session
+	Last          float64 `json:"last"`
+	High          float64 `json:"high"`
+	Low           float64 `json:"low"`
+	ChangePercent float64 `json:"change_percent"`
+	Volume        float64 `json:"volume"`
+	Bars          int64   `json:"bars"`
+}
+
+// SignalSummary counts the signals generated during the session
+type SignalSummary struct {
+	Total    int64            `json:"total"`
+	ByType   map[string]int64 `json:"by_type"`
+	ByTicker map[string]int64 `json:"by_ticker"`
+}
+
+// DataQuality reports how complete and orderly the session's live data was
+type DataQuality struct {
+	TickersWatched  int      `json:"tickers_watched"`
+	TickersWithData int      `json:"tickers_with_data"`
+	Missing         []string `json:"missing"` // Watched tickers without a live bar all session
+	LiveEvents      int64    `json:"live_events"`
+	ReorderedBars   int64    `json:"reordered_bars"`
+	LateBars        int64    `json:"late_bars"`
+	AnomalousBars   int64    `json:"anomalous_bars"` // Live bars flagged as suspect and kept out of the summary
+	Errors          int64    `json:"errors"`
+}
+
+// DailySummary is the cross-ticker summary of a market day, compiled by the
+// hub at the close
+type DailySummary struct {
+	Date        string        `json:"date"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Gainers     []TickerMove  `json:"gainers"` // Biggest gains first
+	Losers      []TickerMove  `json:"losers"`  // Biggest losses first
+	Tickers     []TickerMove  `json:"tickers"` // Every watched ticker with data, by symbol
+	Signals     SignalSummary `json:"signals"`
+	Quality     DataQuality   `json:"quality"`
+}
+
+// summarySubject returns the subject of the summary for a market date
+func summarySubject(date string) (string, error) {
+	if _, err := time.Parse(SummaryDateLayout, date); err != nil {
+		return "", fmt.Errorf("invalid summary date %q, expected YYYY-MM-DD", date)
+	}
+	return fmt.Sprintf(SubjectSummaryDaily, date), nil
+}
+
+// PublishDailySummary publishes a day's summary, replacing any earlier one
+// for the same date
+func (c *EventClient) PublishDailySummary(ctx context.Context, summary DailySummary) error {
+	subject, err := summarySubject(summary.Date)
+	if err != nil {
+		return err
+	}
+	msg, err := c.encodeMsg(subject, summary)
+	if err != nil {
+		return err
+	}
+
+	return c.publish(ctx, msg)
+}
+
+// DailySummary returns the latest summary published for a market date, or
+// nats.ErrMsgNotFound if there is none
+func (c *EventClient) DailySummary(date string) (*DailySummary, error) {
+	subject, err := summarySubject(date)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := c.js.GetLastMsg(StreamSummaries, subject)
+	if err != nil {
+		return nil, err
+	}
+	data, err := decodePayload(msg.Header, msg.Data)
+	if err != nil {
+		return nil, err
+	}
+	var summary DailySummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("invalid daily summary on %s: %w", subject, err)
+	}
+	return &summary, nil
+}
+
+// LatestDailySummary returns the most recent summary published for a market
+// date within lookback days of from, or nats.ErrMsgNotFound if there is none
+func (c *EventClient) LatestDailySummary(from time.Time, lookback int) (*DailySummary, error) {
+	for i := 0; i <= lookback; i++ {
+		summary, err := c.DailySummary(from.AddDate(0, 0, -i).Format(SummaryDateLayout))
+		if errors.Is(err, nats.ErrMsgNotFound) {
+			continue
+		}
+		return summary, err
+	}
+	return nil, nats.ErrMsgNotFound
+}
@@ -0,0 +1,142 @@
+// pkg/events/sync.go
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+	"github.com/nats-io/nats.go"
+)
+
+// BucketHistoricalSync is the key-value bucket holding incremental sync cursors
+const BucketHistoricalSync = "HISTORICAL_SYNC"
+
+// SyncCursor records the last bar published by incremental sync for a ticker and interval
+type SyncCursor struct {
+	Ticker    string    `json:"ticker"`
+	Interval  string    `json:"interval"`
+	LastBar   time.Time `json:"last_bar"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// syncKey returns the bucket key for a ticker and interval
+func syncKey(ticker, interval string) string {
+	if canonical, err := market.NormalizeInterval(interval); err == nil {
+		interval = canonical
+	}
+	return market.NormalizeTicker(ticker) + "." + interval
+}
+
+// syncBucket returns the sync cursor bucket, creating it if needed
+func (c *EventClient) syncBucket() (nats.KeyValue, error) {
+	kv, err := c.js.KeyValue(BucketHistoricalSync)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = c.js.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:      BucketHistoricalSync,
+			Description: "Last bar published by incremental historical sync",
+			Storage:     nats.FileStorage,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s bucket: %w", BucketHistoricalSync, err)
+	}
+	return kv, nil
+}
+
+// SyncCursor returns the stored cursor for a ticker and interval, or nil if
+// the pair has never been synced
+func (c *EventClient) SyncCursor(ticker, interval string) (*SyncCursor, error) {
+	kv, err := c.syncBucket()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := kv.Get(syncKey(ticker, interval))
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync cursor: %w", err)
+	}
+
+	var cursor SyncCursor
+	if err := json.Unmarshal(entry.Value(), &cursor); err != nil {
+		return nil, fmt.Errorf("failed to parse sync cursor: %w", err)
+	}
+	return &cursor, nil
+}
+
+// SetSyncCursor stores the cursor for a ticker and interval
+func (c *EventClient) SetSyncCursor(cursor SyncCursor) error {
+	kv, err := c.syncBucket()
+	if err != nil {
+		return err
+	}
+
+	cursor.UpdatedAt = utils.Now()
+	value, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+	if _, err := kv.Put(syncKey(cursor.Ticker, cursor.Interval), value); err != nil {
+		return fmt.Errorf("failed to store sync cursor: %w", err)
+	}
+	return nil
+}
+
+// PublishHistoricalSync publishes bars added since the previous sync
+func (c *EventClient) PublishHistoricalSync(ctx context.Context, ticker, timeframe string, data interface{}) error {
+	subject, err := syncSubject(ticker, timeframe)
+	if err != nil {
+		return err
+	}
+	msg, err := c.encodeMsg(subject, data)
+	if err != nil {
+		return err
+	}
+
+	return c.publish(ctx, msg)
+}
+
+// SubscribeHistoricalSync subscribes to incremental sync updates for a ticker
+// and interval; "*" matches all of either
+func (c *EventClient) SubscribeHistoricalSync(ticker, timeframe string, handler func([]byte)) (*nats.Subscription, error) {
+	subject, err := syncSubject(ticker, timeframe)
+	if err != nil {
+		return nil, err
+	}
+	return c.js.Subscribe(subject, func(msg *nats.Msg) {
+		data, err := Decode(msg)
+		if err != nil {
+			utils.Error("Dropping message on %s: %v", msg.Subject, err)
+			msg.Ack()
+			return
+		}
+		handler(data)
+		msg.Ack()
+	}, nats.DeliverNew(), nats.BindStream(StreamMarketHistorical))
+}
+
+// syncSubject returns the sync subject with normalized ticker and interval
+func syncSubject(ticker, timeframe string) (string, error) {
+	if ticker != "*" {
+		normalized, err := market.ValidateTicker(ticker)
+		if err != nil {
+			return "", err
+		}
+		ticker = normalized
+	}
+	if timeframe != "*" {
+		if canonical, err := market.NormalizeInterval(timeframe); err == nil {
+			timeframe = canonical
+		} else if !market.ValidSubjectToken(timeframe) {
+			return "", fmt.Errorf("invalid interval %q", timeframe)
+		}
+	}
+	return fmt.Sprintf(SubjectMarketHistoricalSync, ticker, timeframe), nil
+}
@@ -0,0 +1,120 @@
+// pkg/events/timestamps.go
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// timestampKeys are JSON fields holding a point in time
+var timestampKeys = map[string]bool{
+	"timestamp": true,
+	"time":      true,
+	"date":      true,
+}
+
+// timestampSuffixes mark other fields holding a point in time, e.g. updated_at
+var timestampSuffixes = []string{"_at", "_time", "_timestamp", "_date"}
+
+// isTimestampKey reports whether a JSON field holds a point in time
+func isTimestampKey(key string) bool {
+	if timestampKeys[key] {
+		return true
+	}
+	for _, suffix := range timestampSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTimestamps rewrites the timestamp fields of a JSON payload as UTC
+// RFC 3339, so every event carries one format whatever produced it, and adds
+// the exchange session_date to bars. Timestamps without a zone are taken as
+// exchange time. Bare dates and values that do not parse are left as they are.
+func normalizeTimestamps(payload []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return payload, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber() // Keep numbers exactly as encoded
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if !normalizeValue(value) {
+		return payload, nil
+	}
+	return json.Marshal(value)
+}
+
+// normalizeValue normalizes timestamps in a decoded JSON value in place and
+// reports whether anything changed
+func normalizeValue(value interface{}) bool {
+	changed := false
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if normalizeValue(item) {
+				changed = true
+			}
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			if s, ok := item.(string); ok && isTimestampKey(key) {
+				if normalized, ok := normalizeTimestamp(s); ok && normalized != s {
+					v[key] = normalized
+					changed = true
+				}
+				continue
+			}
+			if normalizeValue(item) {
+				changed = true
+			}
+		}
+		if addSessionDate(v) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// normalizeTimestamp formats a date-time string as UTC RFC 3339
+func normalizeTimestamp(value string) (string, bool) {
+	// Bare dates name a day, not an instant
+	if len(value) <= len(market.SessionDateLayout) {
+		return value, false
+	}
+	t, err := market.ParseTimestamp(value, market.ExchangeLocation())
+	if err != nil {
+		return value, false
+	}
+	return market.FormatTimestamp(t), true
+}
+
+// addSessionDate adds the exchange session date to an object that looks like
+// a bar: a timestamp and a close
+func addSessionDate(obj map[string]interface{}) bool {
+	if _, ok := obj["session_date"]; ok {
+		return false
+	}
+	if _, ok := obj["close"]; !ok {
+		return false
+	}
+	s, ok := obj["timestamp"].(string)
+	if !ok {
+		return false
+	}
+	t, err := market.ParseTimestamp(s, market.ExchangeLocation())
+	if err != nil || t.IsZero() {
+		return false
+	}
+	obj["session_date"] = market.SessionDate(t)
+	return true
+}
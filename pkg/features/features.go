@@ -0,0 +1,394 @@
+// pkg/features/features.go
+package features
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+	"github.com/myapp/tradinglab/pkg/parquet"
+)
+
+// Labels a feature matrix can be built with
+const (
+	// LabelForwardReturn is the percent change in close Horizon bars ahead
+	LabelForwardReturn = "forward_return"
+	// LabelDirection is 1 when the forward return exceeds Threshold percent,
+	// -1 when it falls below -Threshold and 0 otherwise
+	LabelDirection = "direction"
+	// LabelSignalOutcome is 1 when a signal's target is reached within
+	// Horizon bars, -1 when its stop is hit first and 0 when neither is.
+	// Only bars with a signal are kept.
+	LabelSignalOutcome = "signal_outcome"
+)
+
+// LabelColumn names the label, the last column of a matrix
+const LabelColumn = "label"
+
+// Limits on a spec, which bound the work of building a matrix
+const (
+	MaxLookback = 200
+	MaxWindow   = 500
+	MaxWindows  = 10
+	MaxHorizon  = 500
+)
+
+// Spec describes the features and label of a matrix. Zero fields take
+// their defaults.
+type Spec struct {
+	Lookback  int     `json:"lookback"`  // Lagged one-bar returns per row (default 5)
+	Windows   []int   `json:"windows"`   // Periods of the rolling indicators (default 5 and 20)
+	Label     string  `json:"label"`     // LabelForwardReturn (default), LabelDirection or LabelSignalOutcome
+	Horizon   int     `json:"horizon"`   // Bars ahead the label looks (default 5)
+	Threshold float64 `json:"threshold"` // Percent move a direction label must exceed
+	TargetR   float64 `json:"target_r"`  // Signal target as a multiple of its risk (default 2)
+}
+
+// WithDefaults fills in unset fields
+func (s Spec) WithDefaults() Spec {
+	if s.Lookback == 0 {
+		s.Lookback = 5
+	}
+	if len(s.Windows) == 0 {
+		s.Windows = []int{5, 20}
+	}
+	if s.Label == "" {
+		s.Label = LabelForwardReturn
+	}
+	if s.Horizon == 0 {
+		s.Horizon = 5
+	}
+	if s.TargetR == 0 {
+		s.TargetR = 2
+	}
+	return s
+}
+
+// Validate checks the spec, with defaults applied, is usable
+func (s Spec) Validate() error {
+	s = s.WithDefaults()
+	switch {
+	case s.Lookback < 1 || s.Lookback > MaxLookback:
+		return fmt.Errorf("lookback must be between 1 and %d", MaxLookback)
+	case len(s.Windows) > MaxWindows:
+		return fmt.Errorf("at most %d windows are allowed", MaxWindows)
+	case s.Label != LabelForwardReturn && s.Label != LabelDirection && s.Label != LabelSignalOutcome:
+		return fmt.Errorf("label must be %s, %s or %s", LabelForwardReturn, LabelDirection, LabelSignalOutcome)
+	case s.Horizon < 1 || s.Horizon > MaxHorizon:
+		return fmt.Errorf("horizon must be between 1 and %d", MaxHorizon)
+	case s.Threshold < 0 || math.IsNaN(s.Threshold):
+		return fmt.Errorf("threshold cannot be negative")
+	case s.TargetR <= 0 || math.IsNaN(s.TargetR):
+		return fmt.Errorf("target_r must be positive")
+	}
+	seen := make(map[int]bool, len(s.Windows))
+	for _, w := range s.Windows {
+		if w < 2 || w > MaxWindow {
+			return fmt.Errorf("windows must be between 2 and %d", MaxWindow)
+		}
+		if seen[w] {
+			return fmt.Errorf("window %d is repeated", w)
+		}
+		seen[w] = true
+	}
+	return nil
+}
+
+// Signal is a strategy entry the matrix marks and labels
+type Signal struct {
+	Time      time.Time
+	Direction int // 1 for long, -1 for short
+	Entry     float64
+	Stop      float64
+}
+
+// Matrix is one row of features and a label per bar. The label is always
+// known when the row is built, so the matrix can be trained on as is.
+type Matrix struct {
+	Columns []string // Feature names, then LabelColumn
+	Times   []time.Time
+	Rows    [][]float64
+}
+
+// Build computes a feature matrix from bars, oldest first, and the
+// signals a strategy generated on them. Each row holds, as of its bar's
+// close, the last Lookback one-bar returns, per window the gap of the close
+// to its moving average, the volatility of returns, RSI, ATR as a percent
+// of the close and volume relative to its average, the bar's range and
+// body, and with signals the bar's signal direction and risk. Bars before
+// the longest window or without Horizon bars after them have no row.
+func Build(candles []analytics.Candle, signals []Signal, spec Spec) (Matrix, error) {
+	if err := spec.Validate(); err != nil {
+		return Matrix{}, err
+	}
+	spec = spec.WithDefaults()
+
+	bars := append([]analytics.Candle(nil), candles...)
+	sort.SliceStable(bars, func(i, j int) bool { return bars[i].Time.Before(bars[j].Time) })
+	n := len(bars)
+
+	returns := make([]float64, n)
+	for i := 1; i < n; i++ {
+		returns[i] = pctChange(bars[i-1].Close, bars[i].Close)
+	}
+
+	var columns []string
+	var series [][]float64
+	add := func(name string, values []float64) {
+		columns = append(columns, name)
+		series = append(series, values)
+	}
+	for k := 1; k <= spec.Lookback; k++ {
+		lagged := make([]float64, n)
+		for i := k; i < n; i++ {
+			lagged[i] = returns[i-k+1]
+		}
+		add(fmt.Sprintf("return_%d", k), lagged)
+	}
+	start := spec.Lookback
+	for _, w := range spec.Windows {
+		add(fmt.Sprintf("sma_gap_%d", w), smaGap(bars, w))
+		add(fmt.Sprintf("volatility_%d", w), volatility(returns, w))
+		add(fmt.Sprintf("rsi_%d", w), rsi(bars, w))
+		add(fmt.Sprintf("atr_pct_%d", w), atrPct(bars, w))
+		add(fmt.Sprintf("volume_ratio_%d", w), volumeRatio(bars, w))
+		if w > start {
+			start = w
+		}
+	}
+	rangePct, bodyPct := make([]float64, n), make([]float64, n)
+	for i, c := range bars {
+		if c.Close != 0 {
+			rangePct[i] = (c.High - c.Low) / c.Close * 100
+		}
+		bodyPct[i] = pctChange(c.Open, c.Close)
+	}
+	add("range_pct", rangePct)
+	add("body_pct", bodyPct)
+
+	// Signals are placed on the last bar at or before their time
+	marked := make(map[int]Signal)
+	for _, s := range signals {
+		i := sort.Search(n, func(i int) bool { return bars[i].Time.After(s.Time) }) - 1
+		if i >= 0 && s.Entry > 0 && s.Direction != 0 && risk(s) > 0 {
+			marked[i] = s
+		}
+	}
+	if signals != nil {
+		direction, riskPct := make([]float64, n), make([]float64, n)
+		for i, s := range marked {
+			direction[i] = float64(s.Direction)
+			riskPct[i] = risk(s) / s.Entry * 100
+		}
+		add("signal", direction)
+		add("signal_risk_pct", riskPct)
+	}
+
+	m := Matrix{Columns: append(columns, LabelColumn)}
+	for i := start; i+spec.Horizon < n; i++ {
+		var label float64
+		switch spec.Label {
+		case LabelForwardReturn:
+			label = pctChange(bars[i].Close, bars[i+spec.Horizon].Close)
+		case LabelDirection:
+			switch change := pctChange(bars[i].Close, bars[i+spec.Horizon].Close); {
+			case change > spec.Threshold:
+				label = 1
+			case change < -spec.Threshold:
+				label = -1
+			}
+		case LabelSignalOutcome:
+			s, ok := marked[i]
+			if !ok {
+				continue
+			}
+			label = outcome(bars[i+1:i+1+spec.Horizon], s, spec.TargetR)
+		}
+
+		row := make([]float64, 0, len(m.Columns))
+		for _, values := range series {
+			row = append(row, values[i])
+		}
+		m.Times = append(m.Times, bars[i].Time)
+		m.Rows = append(m.Rows, append(row, label))
+	}
+	if len(m.Rows) == 0 {
+		if spec.Label == LabelSignalOutcome {
+			return m, fmt.Errorf("no signal of the %d bars has %d bars of history and %d after it", n, start, spec.Horizon)
+		}
+		return m, fmt.Errorf("%d bars are too few for %d bars of history and %d after each row", n, start, spec.Horizon)
+	}
+	return m, nil
+}
+
+// WriteCSV writes the matrix with a header row, its time column first
+func (m Matrix) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write(append([]string{"time"}, m.Columns...))
+	record := make([]string, len(m.Columns)+1)
+	for i, row := range m.Rows {
+		record[0] = m.Times[i].UTC().Format(time.RFC3339)
+		for j, value := range row {
+			record[j+1] = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteParquet writes the matrix as a Parquet file with a timestamp column
+// and a double column per feature and the label
+func (m Matrix) WriteParquet(w io.Writer) error {
+	columns := []parquet.Column{parquet.Timestamps("time", m.Times)}
+	for j, name := range m.Columns {
+		values := make([]float64, len(m.Rows))
+		for i, row := range m.Rows {
+			values[i] = row[j]
+		}
+		columns = append(columns, parquet.Doubles(name, values))
+	}
+	return parquet.Write(w, columns...)
+}
+
+// outcome follows a signal over the bars after it: 1 if its target is
+// reached, -1 if its stop is hit first or in the same bar, 0 if neither
+func outcome(after []analytics.Candle, s Signal, targetR float64) float64 {
+	target := s.Entry + float64(s.Direction)*targetR*risk(s)
+	for _, c := range after {
+		if s.Direction > 0 {
+			if c.Low <= s.Stop {
+				return -1
+			}
+			if c.High >= target {
+				return 1
+			}
+		} else {
+			if c.High >= s.Stop {
+				return -1
+			}
+			if c.Low <= target {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// risk is the distance from a signal's entry to its stop on the losing side
+func risk(s Signal) float64 {
+	return float64(s.Direction) * (s.Entry - s.Stop)
+}
+
+// pctChange is the percent change from one price to another, 0 from 0
+func pctChange(from, to float64) float64 {
+	if from == 0 {
+		return 0
+	}
+	return (to/from - 1) * 100
+}
+
+// smaGap is the percent gap of each close above its w-bar simple average
+func smaGap(bars []analytics.Candle, w int) []float64 {
+	values := make([]float64, len(bars))
+	sum := 0.0
+	for i, c := range bars {
+		sum += c.Close
+		if i >= w {
+			sum -= bars[i-w].Close
+		}
+		if i >= w-1 {
+			values[i] = pctChange(sum/float64(w), c.Close)
+		}
+	}
+	return values
+}
+
+// volatility is the standard deviation of the last w one-bar returns, from
+// the bar after the first
+func volatility(returns []float64, w int) []float64 {
+	values := make([]float64, len(returns))
+	sum, squares := 0.0, 0.0
+	for i := 1; i < len(returns); i++ {
+		sum += returns[i]
+		squares += returns[i] * returns[i]
+		if i > w {
+			sum -= returns[i-w]
+			squares -= returns[i-w] * returns[i-w]
+		}
+		if i >= w {
+			mean := sum / float64(w)
+			values[i] = math.Sqrt(math.Max(0, squares/float64(w)-mean*mean))
+		}
+	}
+	return values
+}
+
+// rsi is the relative strength index of closes with Wilder's smoothing,
+// seeded with the average gain and loss of the first w changes
+func rsi(bars []analytics.Candle, w int) []float64 {
+	values := make([]float64, len(bars))
+	gain, loss := 0.0, 0.0
+	for i := 1; i < len(bars); i++ {
+		change := bars[i].Close - bars[i-1].Close
+		up, down := math.Max(change, 0), math.Max(-change, 0)
+		if i <= w {
+			gain += up / float64(w)
+			loss += down / float64(w)
+		} else {
+			gain = (gain*float64(w-1) + up) / float64(w)
+			loss = (loss*float64(w-1) + down) / float64(w)
+		}
+		if i >= w {
+			switch {
+			case loss == 0 && gain == 0:
+				values[i] = 50
+			case loss == 0:
+				values[i] = 100
+			default:
+				values[i] = 100 - 100/(1+gain/loss)
+			}
+		}
+	}
+	return values
+}
+
+// atrPct is the average true range with Wilder's smoothing, as
+// analytics.ATR computes it, as a percent of the close
+func atrPct(bars []analytics.Candle, w int) []float64 {
+	values := make([]float64, len(bars))
+	atr := 0.0
+	for i := 1; i < len(bars); i++ {
+		tr := analytics.TrueRange(bars[i], bars[i-1].Close)
+		if i <= w {
+			atr += tr / float64(w)
+		} else {
+			atr = (atr*float64(w-1) + tr) / float64(w)
+		}
+		if i >= w && bars[i].Close != 0 {
+			values[i] = atr / bars[i].Close * 100
+		}
+	}
+	return values
+}
+
+// volumeRatio is each bar's volume over the average of the last w bars'
+func volumeRatio(bars []analytics.Candle, w int) []float64 {
+	values := make([]float64, len(bars))
+	sum := 0.0
+	for i, c := range bars {
+		sum += c.Volume
+		if i >= w {
+			sum -= bars[i-w].Volume
+		}
+		if i >= w-1 && sum > 0 {
+			values[i] = c.Volume / (sum / float64(w))
+		}
+	}
+	return values
+}
@@ -0,0 +1,54 @@
+// pkg/fundamentals/filter.go
+package fundamentals
+
+import (
+	"fmt"
+
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// Filter bounds a fundamental field, e.g. {"field": "pe_ratio", "max": 25}
+type Filter struct {
+	Field string   `json:"field"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+// Validate checks the field is known and at least one bound is set
+func (f Filter) Validate() error {
+	known := false
+	for _, field := range market.FundamentalFields {
+		if field == f.Field {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("unknown fundamental field %q", f.Field)
+	}
+	if f.Min == nil && f.Max == nil {
+		return fmt.Errorf("filter on %s needs a min or max", f.Field)
+	}
+	if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+		return fmt.Errorf("filter on %s has min above max", f.Field)
+	}
+	return nil
+}
+
+// Match reports whether a snapshot passes every filter. A field the provider
+// did not report fails its filter. The reason names the first failing filter.
+func Match(snapshot *market.Fundamentals, filters []Filter) (bool, string) {
+	for _, f := range filters {
+		value, ok := snapshot.Value(f.Field)
+		if !ok {
+			return false, fmt.Sprintf("%s not reported", f.Field)
+		}
+		if f.Min != nil && value < *f.Min {
+			return false, fmt.Sprintf("%s %.4g below %.4g", f.Field, value, *f.Min)
+		}
+		if f.Max != nil && value > *f.Max {
+			return false, fmt.Sprintf("%s %.4g above %.4g", f.Field, value, *f.Max)
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,154 @@
+// pkg/fundamentals/store.go
+package fundamentals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// maxHistory caps the snapshots kept per ticker
+const maxHistory = 365
+
+// ErrUnavailable is returned when no snapshot exists and none can be fetched
+var ErrUnavailable = errors.New("fundamentals unavailable")
+
+// Fetcher retrieves current fundamentals for a ticker from a provider
+type Fetcher func(ctx context.Context, ticker string) (*market.Fundamentals, error)
+
+// Store caches fundamentals and persists one snapshot per ticker per day as
+// JSON files in a directory
+type Store struct {
+	mu    sync.Mutex // Held across fetches so provider rate limits are respected
+	dir   string
+	ttl   time.Duration
+	fetch Fetcher // nil serves stored snapshots only
+}
+
+// NewStore creates a store that refetches snapshots older than ttl
+func NewStore(dir string, ttl time.Duration, fetch Fetcher) *Store {
+	return &Store{dir: dir, ttl: ttl, fetch: fetch}
+}
+
+// Get returns the latest fundamentals for a ticker, fetching a new snapshot
+// when the stored one is stale. A failed fetch falls back to the stored snapshot.
+func (s *Store) Get(ctx context.Context, ticker string) (*market.Fundamentals, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history, err := s.load(ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *market.Fundamentals
+	if len(history) > 0 {
+		latest = &history[len(history)-1]
+		if time.Since(latest.FetchedAt) < s.ttl {
+			return latest, nil
+		}
+	}
+
+	if s.fetch == nil {
+		if latest != nil {
+			return latest, nil
+		}
+		return nil, ErrUnavailable
+	}
+
+	snapshot, err := s.fetch(ctx, ticker)
+	if err != nil {
+		if latest != nil {
+			utils.Warn("Failed to refresh fundamentals for %s, using snapshot from %s: %v",
+				ticker, latest.FetchedAt.Format(time.RFC3339), err)
+			return latest, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	snapshot.Ticker = ticker
+
+	// Keep one snapshot per day, replacing an earlier one from the same day
+	day := snapshot.FetchedAt.Format("2006-01-02")
+	if latest != nil && latest.FetchedAt.Format("2006-01-02") == day {
+		history = history[:len(history)-1]
+	}
+	history = append(history, *snapshot)
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+
+	if err := s.save(ticker, history); err != nil {
+		utils.Error("Failed to persist fundamentals for %s: %v", ticker, err)
+	}
+	return snapshot, nil
+}
+
+// History returns the stored snapshots for a ticker, oldest first
+func (s *Store) History(ticker string) ([]market.Fundamentals, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load(ticker)
+}
+
+// load reads the stored snapshots for a ticker. Caller holds the lock.
+func (s *Store) load(ticker string) ([]market.Fundamentals, error) {
+	path, err := s.path(ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fundamentals: %w", err)
+	}
+
+	var history []market.Fundamentals
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse fundamentals: %w", err)
+	}
+	return history, nil
+}
+
+// save writes the snapshots for a ticker atomically. Caller holds the lock.
+func (s *Store) save(ticker string, history []market.Fundamentals) error {
+	path, err := s.path(ticker)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create fundamentals directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write fundamentals: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// path returns the file holding a ticker's snapshots
+func (s *Store) path(ticker string) (string, error) {
+	if ticker == "" || strings.ContainsAny(ticker, `/\.`) {
+		return "", fmt.Errorf("invalid ticker %q", ticker)
+	}
+	return filepath.Join(s.dir, ticker+".json"), nil
+}
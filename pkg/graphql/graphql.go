@@ -0,0 +1,454 @@
+// pkg/graphql/graphql.go
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Request is a GraphQL request as posted by clients
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of executing a request
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// Error is a GraphQL error, located by its path in the result
+type Error struct {
+	Message   string        `json:"message"`
+	Locations []Location    `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+// Location is a position in the query
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// ResolveFunc resolves a field from its parent value and arguments
+type ResolveFunc func(ctx context.Context, parent interface{}, args map[string]interface{}) (interface{}, error)
+
+// Object is an object type. Fields without a resolver, and fields not
+// declared at all, are read from the parent's JSON form, so plain data
+// only needs declaring where it has arguments or nested types.
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Field is a field of an object type
+type Field struct {
+	Type    *Object // Type of the value, or of each list element; nil for plain data
+	Resolve ResolveFunc
+	Cost    int // Complexity of resolving the field; zero counts as 1
+	Size    int // Expected elements of a list value, multiplying the cost of its selections; zero for single values
+}
+
+// Schema is an executable schema
+type Schema struct {
+	Query       *Object
+	MaxParallel int // Root fields resolving at once across all requests; zero is unlimited
+
+	semOnce sync.Once
+	sem     chan struct{}
+}
+
+// maxComplexityDepth bounds the nesting Complexity follows, which fragment
+// spreads on plain data could otherwise repeat without end
+const maxComplexityDepth = 32
+
+// ErrTooDeep is returned by Complexity for selections nested too deeply
+var ErrTooDeep = errors.New("query is nested too deeply")
+
+// Execute runs a query against the schema. Root fields resolve in
+// parallel, at most MaxParallel at once; nested fields resolve in order.
+func (s *Schema) Execute(ctx context.Context, req Request) Response {
+	e, op, err := s.executor(ctx, req)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	data := e.selectObject(s.Query, nil, op.selections, nil, true)
+	return Response{Data: data, Errors: e.errors}
+}
+
+// Complexity estimates the cost of running a request: each selected field
+// costs its Cost, and the selections of a list field Size times over.
+// Fragments are expanded and @skip and @include applied. Requests that do
+// not parse return the error Execute would report.
+func (s *Schema) Complexity(req Request) (int, error) {
+	e, op, err := s.executor(context.Background(), req)
+	if err != nil {
+		return 0, err
+	}
+	return e.complexity(s.Query, op.selections, 0)
+}
+
+// executor parses a request and prepares the executor for its operation
+func (s *Schema) executor(ctx context.Context, req Request) (*executor, *operation, error) {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vars := make(map[string]interface{}, len(op.vars))
+	for _, v := range op.vars {
+		if value, ok := req.Variables[v.name]; ok {
+			vars[v.name] = value
+		} else if v.hasDefault {
+			vars[v.name] = v.def
+		}
+	}
+
+	s.semOnce.Do(func() {
+		if s.MaxParallel > 0 {
+			s.sem = make(chan struct{}, s.MaxParallel)
+		}
+	})
+	return &executor{ctx: ctx, doc: doc, vars: vars, sem: s.sem}, op, nil
+}
+
+// operation picks the operation to run
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document has several operations")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// executor holds the state of one request's execution
+type executor struct {
+	ctx    context.Context
+	doc    *document
+	vars   map[string]interface{}
+	sem    chan struct{} // Slots for resolving root fields; nil is unlimited
+	mu     sync.Mutex
+	errors []Error
+}
+
+// complexity sums the cost of the selections on obj
+func (e *executor) complexity(obj *Object, sels []selection, depth int) (int, error) {
+	if depth > maxComplexityDepth {
+		return 0, ErrTooDeep
+	}
+	total := 0
+	for _, f := range e.collectFields(obj, sels, nil, make(map[string]bool)) {
+		cost, size := 1, 1
+		var typ *Object
+		if obj != nil {
+			if def := obj.Fields[f.name]; def != nil {
+				cost, size, typ = max(def.Cost, 1), max(def.Size, 1), def.Type
+			}
+		}
+		nested, err := e.complexity(typ, f.selections, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		total += cost + size*nested
+	}
+	return total, nil
+}
+
+// fail records a field error
+func (e *executor) fail(f *field, path []interface{}, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errors = append(e.errors, Error{
+		Message:   err.Error(),
+		Locations: []Location{f.loc},
+		Path:      append([]interface{}(nil), path...),
+	})
+}
+
+// selectObject resolves the selections on one object value
+func (e *executor) selectObject(obj *Object, parent interface{}, sels []selection, path []interface{}, parallel bool) *orderedMap {
+	fields := e.collectFields(obj, sels, nil, make(map[string]bool))
+	result := &orderedMap{values: make(map[string]interface{}, len(fields))}
+	for _, f := range fields {
+		result.keys = append(result.keys, f.responseKey())
+	}
+
+	var data map[string]interface{}
+	var once sync.Once
+	source := func() map[string]interface{} {
+		once.Do(func() { data = toMap(parent) })
+		return data
+	}
+
+	if !parallel {
+		for _, f := range fields {
+			result.values[f.responseKey()] = e.resolveField(obj, parent, source, f, appendPath(path, f.responseKey()))
+		}
+		return result
+	}
+
+	values := make([]interface{}, len(fields))
+	var wg sync.WaitGroup
+	for i, f := range fields {
+		wg.Add(1)
+		go func(i int, f *field) {
+			defer wg.Done()
+			if e.sem != nil {
+				select {
+				case e.sem <- struct{}{}:
+					defer func() { <-e.sem }()
+				case <-e.ctx.Done():
+					e.fail(f, appendPath(path, f.responseKey()), e.ctx.Err())
+					return
+				}
+			}
+			values[i] = e.resolveField(obj, parent, source, f, appendPath(path, f.responseKey()))
+		}(i, f)
+	}
+	wg.Wait()
+	for i, f := range fields {
+		result.values[f.responseKey()] = values[i]
+	}
+	return result
+}
+
+// collectFields flattens fragments and directives into the fields to
+// resolve, merging the selections of fields sharing a response key
+func (e *executor) collectFields(obj *Object, sels []selection, fields []*field, visited map[string]bool) []*field {
+	for _, sel := range sels {
+		switch s := sel.(type) {
+		case *field:
+			if !e.included(s.directives) {
+				continue
+			}
+			merged := false
+			for i, existing := range fields {
+				if existing.responseKey() == s.responseKey() {
+					copied := *existing
+					copied.selections = append(append([]selection(nil), existing.selections...), s.selections...)
+					fields[i] = &copied
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				fields = append(fields, s)
+			}
+		case *fragmentSpread:
+			frag, ok := e.doc.fragments[s.name]
+			if !ok || visited[s.name] || !e.included(s.directives) || !typeMatches(obj, frag.typeCondition) {
+				continue
+			}
+			visited[s.name] = true
+			fields = e.collectFields(obj, frag.selections, fields, visited)
+		case *inlineFragment:
+			if !e.included(s.directives) || !typeMatches(obj, s.typeCondition) {
+				continue
+			}
+			fields = e.collectFields(obj, s.selections, fields, visited)
+		}
+	}
+	return fields
+}
+
+// typeMatches reports whether a fragment's type condition applies to obj
+func typeMatches(obj *Object, typeCondition string) bool {
+	return typeCondition == "" || obj == nil || obj.Name == typeCondition
+}
+
+// included evaluates @skip and @include
+func (e *executor) included(directives []directive) bool {
+	for _, d := range directives {
+		cond, _ := e.value(d.args["if"]).(bool)
+		if (d.name == "skip" && cond) || (d.name == "include" && !cond) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveField resolves one field and completes its value
+func (e *executor) resolveField(obj *Object, parent interface{}, source func() map[string]interface{}, f *field, path []interface{}) interface{} {
+	if f.name == "__typename" {
+		if obj == nil {
+			return nil
+		}
+		return obj.Name
+	}
+
+	var def *Field
+	if obj != nil {
+		def = obj.Fields[f.name]
+	}
+
+	var value interface{}
+	if def != nil && def.Resolve != nil {
+		args := make(map[string]interface{}, len(f.args))
+		for name, arg := range f.args {
+			args[name] = e.value(arg)
+		}
+		var err error
+		if value, err = def.Resolve(e.ctx, parent, args); err != nil {
+			e.fail(f, path, err)
+			return nil
+		}
+	} else {
+		var ok bool
+		if value, ok = source()[f.name]; !ok && def == nil && obj != nil && obj.Fields != nil {
+			e.fail(f, path, fmt.Errorf("cannot query field %q on type %s", f.name, obj.Name))
+			return nil
+		}
+	}
+
+	var typ *Object
+	if def != nil {
+		typ = def.Type
+	}
+	return e.complete(typ, value, f, path)
+}
+
+// complete applies a field's selections to its value, element by element for lists
+func (e *executor) complete(typ *Object, value interface{}, f *field, path []interface{}) interface{} {
+	if value == nil || len(f.selections) == 0 {
+		return value
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = e.complete(typ, rv.Index(i).Interface(), f, appendPath(path, i))
+		}
+		return list
+	}
+	return e.selectObject(typ, value, f.selections, path, false)
+}
+
+// value resolves variables in an argument value
+func (e *executor) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case variableRef:
+		return e.vars[string(v)]
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = e.value(item)
+		}
+		return list
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			obj[name] = e.value(item)
+		}
+		return obj
+	}
+	return v
+}
+
+// toMap returns the JSON form of an object value
+func toMap(v interface{}) map[string]interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	json.Unmarshal(data, &m)
+	return m
+}
+
+// appendPath extends a result path without sharing the parent's backing array
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	return append(append(make([]interface{}, 0, len(path)+1), path...), key)
+}
+
+// orderedMap is a result object that keeps fields in query order
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// String returns a string argument, or def when absent
+func String(args map[string]interface{}, name, def string) string {
+	if s, ok := args[name].(string); ok && s != "" {
+		return s
+	}
+	return def
+}
+
+// Int returns an integer argument, or def when absent. Variables decoded
+// from JSON arrive as float64.
+func Int(args map[string]interface{}, name string, def int) int {
+	switch n := args[name].(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return def
+}
+
+// Floats returns a list of numbers argument, or nil when absent
+func Floats(args map[string]interface{}, name string) []float64 {
+	list, _ := args[name].([]interface{})
+	var floats []float64
+	for _, item := range list {
+		switch n := item.(type) {
+		case int:
+			floats = append(floats, float64(n))
+		case float64:
+			floats = append(floats, n)
+		}
+	}
+	return floats
+}
@@ -0,0 +1,574 @@
+// pkg/graphql/parser.go
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind classifies lexical tokens
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+// token is a lexical token with its position in the query
+type token struct {
+	kind  tokenKind
+	value string
+	loc   Location
+}
+
+// lex splits a query into tokens. Commas are insignificant in GraphQL and
+// are skipped with whitespace and comments.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	line, lineStart := 1, 0
+	for i := 0; i < len(src); {
+		c := src[i]
+		loc := Location{Line: line, Column: i - lineStart + 1}
+
+		switch {
+		case c == '\n':
+			i++
+			line, lineStart = line+1, i
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "\xef\xbb\xbf"):
+			i += 3
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, token{tokPunct, "...", loc})
+			i += 3
+		case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+			tokens = append(tokens, token{tokPunct, string(c), loc})
+			i++
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokName, src[start:i], loc})
+		case c == '-' || isDigit(c):
+			start := i
+			kind := tokInt
+			if c == '-' {
+				i++
+			}
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			if i < len(src) && src[i] == '.' {
+				kind = tokFloat
+				i++
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				kind = tokFloat
+				i++
+				if i < len(src) && (src[i] == '+' || src[i] == '-') {
+					i++
+				}
+				for i < len(src) && isDigit(src[i]) {
+					i++
+				}
+			}
+			if src[start:i] == "-" {
+				return nil, fmt.Errorf("invalid number at %d:%d", loc.Line, loc.Column)
+			}
+			tokens = append(tokens, token{kind, src[start:i], loc})
+		case strings.HasPrefix(src[i:], `"""`):
+			end := strings.Index(src[i+3:], `"""`)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated block string at %d:%d", loc.Line, loc.Column)
+			}
+			value := src[i+3 : i+3+end]
+			tokens = append(tokens, token{tokString, strings.TrimSpace(value), loc})
+			line += strings.Count(value, "\n")
+			i += end + 6
+		case c == '"':
+			value, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%v at %d:%d", err, loc.Line, loc.Column)
+			}
+			tokens = append(tokens, token{tokString, value, loc})
+			i += n
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, fmt.Errorf("unexpected character %q at %d:%d", r, loc.Line, loc.Column)
+		}
+	}
+	tokens = append(tokens, token{kind: tokEOF, loc: Location{Line: line, Column: len(src) - lineStart + 1}})
+	return tokens, nil
+}
+
+// lexString reads a quoted string, returning its value and length in src
+func lexString(src string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		switch c := src[i]; c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\n':
+			return "", 0, fmt.Errorf("unterminated string")
+		case '\\':
+			i++
+			if i >= len(src) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			switch esc := src[i]; esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if i+4 >= len(src) {
+					return "", 0, fmt.Errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(src[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid unicode escape")
+				}
+				b.WriteRune(rune(code))
+				i += 4
+			default:
+				return "", 0, fmt.Errorf("invalid escape \\%c", esc)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// document is a parsed query
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a query; mutations and subscriptions are rejected when parsed
+type operation struct {
+	name       string
+	vars       []varDef
+	selections []selection
+}
+
+// varDef declares an operation variable and its default
+type varDef struct {
+	name       string
+	def        interface{}
+	hasDefault bool
+}
+
+// selection is a *field, *fragmentSpread or *inlineFragment
+type selection interface{}
+
+// field selects a field, optionally under an alias
+type field struct {
+	alias      string
+	name       string
+	args       map[string]interface{} // Values are literals, variableRefs, lists and objects of them
+	directives []directive
+	selections []selection
+	loc        Location
+}
+
+// responseKey is the field's key in the result
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// fragmentSpread includes a named fragment
+type fragmentSpread struct {
+	name       string
+	directives []directive
+}
+
+// inlineFragment includes selections, optionally for one type only
+type inlineFragment struct {
+	typeCondition string
+	directives    []directive
+	selections    []selection
+}
+
+// fragment is a named fragment definition
+type fragment struct {
+	typeCondition string
+	selections    []selection
+}
+
+// directive is a directive such as @include(if: $flag)
+type directive struct {
+	name string
+	args map[string]interface{}
+}
+
+// variableRef refers to an operation variable in an argument value
+type variableRef string
+
+// parser builds a document from tokens
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse parses a query document
+func parse(query string) (*document, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	doc := &document{fragments: make(map[string]*fragment)}
+
+	for p.peek().kind != tokEOF {
+		switch tok := p.peek(); {
+		case tok.kind == tokPunct && tok.value == "{":
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{selections: selections})
+		case tok.kind == tokName && tok.value == "query":
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case tok.kind == tokName && tok.value == "fragment":
+			p.next()
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.keyword("on"); err != nil {
+				return nil, err
+			}
+			typeCondition, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = &fragment{typeCondition: typeCondition, selections: selections}
+		case tok.kind == tokName && (tok.value == "mutation" || tok.value == "subscription"):
+			return nil, fmt.Errorf("%s operations are not supported", tok.value)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document has no operations")
+	}
+	return doc, nil
+}
+
+// operation parses "query Name($var: Type = default) { ... }"
+func (p *parser) operation() (*operation, error) {
+	p.next() // query
+	op := &operation{}
+	if p.peek().kind == tokName {
+		op.name = p.next().value
+	}
+
+	if p.punct("(") {
+		for !p.punct(")") {
+			if !p.punct("$") {
+				return nil, p.unexpected()
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if !p.punct(":") {
+				return nil, p.unexpected()
+			}
+			if err := p.skipType(); err != nil {
+				return nil, err
+			}
+			v := varDef{name: name}
+			if p.punct("=") {
+				if v.def, err = p.value(true); err != nil {
+					return nil, err
+				}
+				v.hasDefault = true
+			}
+			op.vars = append(op.vars, v)
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	op.selections, err = p.selectionSet()
+	return op, err
+}
+
+// skipType consumes a type reference such as [String!]!; types are not checked
+func (p *parser) skipType() error {
+	if p.punct("[") {
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if !p.punct("]") {
+			return p.unexpected()
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	p.punct("!")
+	return nil
+}
+
+// selectionSet parses "{ selection ... }"
+func (p *parser) selectionSet() ([]selection, error) {
+	if !p.punct("{") {
+		return nil, p.unexpected()
+	}
+	var selections []selection
+	for !p.punct("}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("empty selection set")
+	}
+	return selections, nil
+}
+
+// selection parses a field, fragment spread or inline fragment
+func (p *parser) selection() (selection, error) {
+	if p.punct("...") {
+		if tok := p.peek(); tok.kind == tokName && tok.value != "on" {
+			p.next()
+			directives, err := p.directives()
+			return &fragmentSpread{name: tok.value, directives: directives}, err
+		}
+		frag := &inlineFragment{}
+		if p.peek().kind == tokName {
+			p.next() // on
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			frag.typeCondition = name
+		}
+		var err error
+		if frag.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		frag.selections, err = p.selectionSet()
+		return frag, err
+	}
+
+	f := &field{loc: p.peek().loc}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if p.punct(":") {
+		f.alias = name
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	f.name = name
+
+	if f.args, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind == tokPunct && tok.value == "{" {
+		if f.selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// arguments parses an optional "(name: value ...)" list
+func (p *parser) arguments() (map[string]interface{}, error) {
+	if !p.punct("(") {
+		return nil, nil
+	}
+	args := make(map[string]interface{})
+	for !p.punct(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if !p.punct(":") {
+			return nil, p.unexpected()
+		}
+		if args[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// directives parses any "@name(args)" directives
+func (p *parser) directives() ([]directive, error) {
+	var directives []directive
+	for p.punct("@") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, directive{name: name, args: args})
+	}
+	return directives, nil
+}
+
+// value parses an argument value; constant values may not use variables
+func (p *parser) value(constant bool) (interface{}, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokInt:
+		n, err := strconv.Atoi(tok.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %s at %d:%d", tok.value, tok.loc.Line, tok.loc.Column)
+		}
+		return n, nil
+	case tokFloat:
+		return strconv.ParseFloat(tok.value, 64)
+	case tokString:
+		return tok.value, nil
+	case tokName:
+		switch tok.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return tok.value, nil // Enum values are passed as strings
+	case tokPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				break
+			}
+			name, err := p.name()
+			return variableRef(name), err
+		case "[":
+			list := []interface{}{}
+			for !p.punct("]") {
+				item, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, item)
+			}
+			return list, nil
+		case "{":
+			obj := make(map[string]interface{})
+			for !p.punct("}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if !p.punct(":") {
+					return nil, p.unexpected()
+				}
+				if obj[name], err = p.value(constant); err != nil {
+					return nil, err
+				}
+			}
+			return obj, nil
+		}
+	}
+	p.pos--
+	return nil, p.unexpected()
+}
+
+// peek returns the next token without consuming it
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// next consumes the next token
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// punct consumes the next token if it is the punctuator s
+func (p *parser) punct(s string) bool {
+	if tok := p.peek(); tok.kind == tokPunct && tok.value == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// name consumes a name
+func (p *parser) name() (string, error) {
+	if p.peek().kind != tokName {
+		return "", p.unexpected()
+	}
+	return p.next().value, nil
+}
+
+// keyword consumes the name s
+func (p *parser) keyword(s string) error {
+	if tok := p.peek(); tok.kind != tokName || tok.value != s {
+		return p.unexpected()
+	}
+	p.next()
+	return nil
+}
+
+// unexpected describes the next token as a syntax error
+func (p *parser) unexpected() error {
+	tok := p.peek()
+	if tok.kind == tokEOF {
+		return fmt.Errorf("unexpected end of query at %d:%d", tok.loc.Line, tok.loc.Column)
+	}
+	return fmt.Errorf("unexpected %q at %d:%d", tok.value, tok.loc.Line, tok.loc.Column)
+}
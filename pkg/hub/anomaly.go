@@ -0,0 +1,58 @@
+// pkg/hub/anomaly.go
+package hub
+
+import (
+	"context"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// SetAnomalyConfig sets how far live bars may stray before they are flagged.
+// Call before Start.
+func (h *EventHub) SetAnomalyConfig(cfg analytics.AnomalyConfig) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.anomalies = analytics.NewAnomalyDetector(cfg)
+}
+
+// anomalyDetector returns the hub's anomaly detector
+func (h *EventHub) anomalyDetector() *analytics.AnomalyDetector {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.anomalies
+}
+
+// flagAnomalies checks a live bar against the ticker's recent bars. A suspect
+// bar is counted, published as an anomaly event and reported true, so it is
+// kept out of the session summary and intraday analytics.
+func (h *EventHub) flagAnomalies(ctx context.Context, ticker string, marketData map[string]interface{}, stamps events.Stamps) bool {
+	bar := liveBar(marketData, h.clock.Market())
+	found := h.anomalyDetector().Check(ticker, bar, time.Now())
+	if len(found) == 0 {
+		return false
+	}
+
+	anomaly := events.MarketAnomaly{Ticker: ticker, Price: bar.Close, Volume: bar.Volume}
+	anomaly.Timestamp, _ = marketData["timestamp"].(string)
+	for _, a := range found {
+		anomaly.Reasons = append(anomaly.Reasons, events.AnomalyReason{Kind: a.Kind, Value: a.Value, Threshold: a.Threshold})
+	}
+
+	h.mu.Lock()
+	stats := h.stats.TickerStats[ticker]
+	stats.AnomalousBars++
+	h.stats.TickerStats[ticker] = stats
+	h.currentDay().anomalousBars++
+	h.mu.Unlock()
+
+	utils.Warn("Flagged live bar for %s at %s as %s, keeping it out of analytics",
+		ticker, anomaly.Timestamp, found[0].Kind)
+	stamps.ProcessedAt = time.Now()
+	if err := h.client.PublishMarketAnomaly(events.WithStamps(ctx, stamps), anomaly); err != nil {
+		utils.Error("Failed to publish anomaly for %s: %v", ticker, err)
+	}
+	return true
+}
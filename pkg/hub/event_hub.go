@@ -9,8 +9,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/myapp/tradinglab/pkg/analytics"
+	"github.com/myapp/tradinglab/pkg/clock"
 	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
 	"github.com/myapp/tradinglab/pkg/utils"
+	"github.com/nats-io/nats.go"
 )
 
 // EventHub manages the routing, transformation, and coordination of events
@@ -22,6 +26,18 @@ type EventHub struct {
 	stats           EventStats
 	watchedTickers  []string
 	failedStreams   map[string]SubscriptionConfig // Tracks failed subscription attempts
+	intraday        *analytics.IntradayTracker    // Running VWAP/TWAP per ticker
+	sequencer       *analytics.BarSequencer       // Restores bar order before intraday analytics
+	anomalies       *analytics.AnomalyDetector    // Flags suspect live bars before they reach analytics
+	clock           *clock.Clock                  // Market zone for sessions, display zone for logs
+	services        map[string]ServiceStatus      // Latest heartbeat per service instance
+	liveStamps      map[string]events.Stamps      // Pipeline stamps of the latest live bar per ticker
+	lastLive        map[string]time.Time          // When each ticker's latest live bar arrived
+	staleConfig     StaleConfig                   // When the watchdog reports watched tickers stale
+	staleTickers    map[string]*staleTicker       // The watchdog's state per watched ticker
+	skew            *clock.SkewDetector           // Clock offsets of service instances from their heartbeats
+	day             *dayStats                     // The market day's moves, signals and data quality for the daily summary
+	summaryMovers   int                           // Gainers and losers listed in the daily summary
 	ctx             context.Context
 	cancel          context.CancelFunc
 }
@@ -32,6 +48,8 @@ type Subscription struct {
 	Handler  func([]byte)
 	Consumer string
 	Active   bool // Whether the subscription is currently active
+
+	sub *nats.Subscription // Drained on Close
 }
 
 // SubscriptionConfig holds information needed to retry a subscription
@@ -63,9 +81,16 @@ type TickerStats struct {
 	DailyEvents      int64     `json:"daily_events"`
 	HistoricalEvents int64     `json:"historical_events"`
 	SignalEvents     int64     `json:"signal_events"`
+	ReorderedBars    int64     `json:"reordered_bars"` // Live bars put back in order
+	LateBars         int64     `json:"late_bars"`      // Live bars dropped as too late to reorder
+	AnomalousBars    int64     `json:"anomalous_bars"` // Live bars flagged as suspect and kept out of analytics
+	StaleAlerts      int64     `json:"stale_alerts"`   // Times live data stopped for longer than the stale threshold
 	LastEventTime    time.Time `json:"last_event_time"`
 }
 
+// DefaultReorderWindow is how long live bars are held to restore their order
+const DefaultReorderWindow = 2 * time.Second
+
 // NewEventHub creates a new event hub
 func NewEventHub(client *events.EventClient) *EventHub {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -79,6 +104,16 @@ func NewEventHub(client *events.EventClient) *EventHub {
 		},
 		watchedTickers: []string{},
 		failedStreams:  make(map[string]SubscriptionConfig),
+		intraday:       analytics.NewIntradayTracker(market.ExchangeLocation()),
+		sequencer:      analytics.NewBarSequencer(DefaultReorderWindow),
+		anomalies:      analytics.NewAnomalyDetector(analytics.DefaultAnomalyConfig()),
+		clock:          clock.System(),
+		services:       make(map[string]ServiceStatus),
+		liveStamps:     make(map[string]events.Stamps),
+		lastLive:       make(map[string]time.Time),
+		staleConfig:    StaleConfig{Threshold: DefaultStaleThreshold},
+		staleTickers:   make(map[string]*staleTicker),
+		skew:           clock.NewSkewDetector(clock.SkewThreshold()),
 		ctx:            ctx,
 		cancel:         cancel,
 	}
@@ -119,6 +154,13 @@ func (h *EventHub) Start(ctx context.Context) error {
 		h.registerFailedStream("signals", events.SubjectSignalsAll)
 	}
 
+	// Track service heartbeats
+	if err := h.subscribeToHeartbeats(); err != nil {
+		utils.Warn("Warning: failed to subscribe to heartbeats: %v", err)
+		startupErrors = append(startupErrors, fmt.Sprintf("heartbeats: %v", err))
+		h.registerFailedStream("heartbeats", events.SubjectHeartbeatAll)
+	}
+
 	// Register handler for historical data requests
 	h.RegisterRequestHandler("historical", h.handleHistoricalDataRequest)
 
@@ -136,6 +178,12 @@ func (h *EventHub) Start(ctx context.Context) error {
 	// Start background process to retry failed streams
 	go h.retryFailedStreams()
 
+	// Release held live bars once their reorder window elapses
+	go h.releaseHeldBars(ctx)
+
+	// Report watched tickers whose live data stops during market hours
+	go h.watchStaleTickers(ctx)
+
 	// Log startup status
 	if len(startupErrors) > 0 {
 		if criticalError {
@@ -150,6 +198,23 @@ func (h *EventHub) Start(ctx context.Context) error {
 	return nil
 }
 
+// SetClock sets the time zones used for intraday sessions and log output.
+// Call before Start.
+func (h *EventHub) SetClock(c *clock.Clock) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clock = c
+	h.intraday = analytics.NewIntradayTracker(c.Market())
+}
+
+// SetReorderWindow sets how long live bars are held to restore their order.
+// Call before Start; zero disables reordering and only drops late bars.
+func (h *EventHub) SetReorderWindow(window time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sequencer = analytics.NewBarSequencer(window)
+}
+
 // SetWatchedTickers updates the list of tickers to watch
 func (h *EventHub) SetWatchedTickers(tickers []string) {
 	h.mu.Lock()
@@ -175,7 +240,7 @@ func (h *EventHub) RegisterRequestHandler(requestType string, handler RequestHan
 
 // subscribeToMarketLiveData subscribes to all live market data events
 func (h *EventHub) subscribeToMarketLiveData(ctx context.Context) error {
-	_, err := h.client.SubscribeMarketLiveData("*", func(data []byte) {
+	sub, err := h.client.SubscribeMarketLiveDataStamped("*", func(data []byte, stamps events.Stamps) {
 		// Update stats
 		h.mu.Lock()
 		h.stats.TotalEvents++
@@ -200,9 +265,18 @@ func (h *EventHub) subscribeToMarketLiveData(ctx context.Context) error {
 			stats.LiveEvents++
 			stats.LastEventTime = time.Now()
 			h.stats.TickerStats[ticker] = stats
+			h.liveStamps[ticker] = stamps
+			h.lastLive[ticker] = stats.LastEventTime
 			h.mu.Unlock()
 
 			utils.Debug("Processed live market data for %s", ticker)
+
+			// Suspect bars go no further than the anomaly event
+			if h.flagAnomalies(ctx, ticker, marketData, stamps) {
+				return
+			}
+			h.recordSessionBar(ticker, marketData)
+			h.updateIntradayAnalytics(ctx, ticker, marketData)
 		}
 	})
 
@@ -215,6 +289,7 @@ func (h *EventHub) subscribeToMarketLiveData(ctx context.Context) error {
 		Subject:  events.SubjectMarketLiveAll,
 		Handler:  func(data []byte) {},
 		Consumer: "EventHub",
+		sub:      sub,
 	})
 	h.mu.Unlock()
 
@@ -222,9 +297,120 @@ func (h *EventHub) subscribeToMarketLiveData(ctx context.Context) error {
 	return nil
 }
 
+// updateIntradayAnalytics queues a live bar for a watched ticker so it reaches
+// the running VWAP and TWAP in timestamp order
+func (h *EventHub) updateIntradayAnalytics(ctx context.Context, ticker string, marketData map[string]interface{}) {
+	if !h.isWatched(ticker) {
+		return
+	}
+
+	bar := liveBar(marketData, h.clock.Market())
+	if bar.Time.IsZero() {
+		utils.Debug("Skipping intraday analytics for %s: invalid timestamp %v", ticker, marketData["timestamp"])
+		return
+	}
+	if bar.Close == 0 {
+		return
+	}
+
+	if !h.barSequencer().Add(ticker, bar, time.Now()) {
+		utils.Warn("Dropping late live bar for %s at %s: a later bar was already processed",
+			ticker, bar.Time.Format(time.RFC3339))
+		return
+	}
+	h.applyDueBars(ctx)
+}
+
+// liveBar reads a live market data event as a bar, in the market zone. The
+// close falls back to the price, and the time is zero if the timestamp is
+// invalid.
+func liveBar(marketData map[string]interface{}, loc *time.Location) analytics.Candle {
+	var bar analytics.Candle
+	timestamp, _ := marketData["timestamp"].(string)
+	if barTime, err := market.ParseTimestamp(timestamp, loc); err == nil {
+		bar.Time = barTime
+	}
+	bar.High, _ = marketData["high"].(float64)
+	bar.Low, _ = marketData["low"].(float64)
+	bar.Close, _ = marketData["close"].(float64)
+	bar.Volume, _ = marketData["volume"].(float64)
+	if bar.Close == 0 {
+		bar.Close, _ = marketData["price"].(float64)
+	}
+	return bar
+}
+
+// barSequencer returns the hub's bar sequencer
+func (h *EventHub) barSequencer() *analytics.BarSequencer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.sequencer
+}
+
+// releaseHeldBars periodically applies bars whose reorder window has elapsed
+func (h *EventHub) releaseHeldBars(ctx context.Context) {
+	interval := h.barSequencer().Window() / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.applyDueBars(ctx)
+		}
+	}
+}
+
+// applyDueBars folds released bars into the running averages in order
+func (h *EventHub) applyDueBars(ctx context.Context) {
+	for _, due := range h.barSequencer().Due(time.Now()) {
+		h.applyIntradayBar(ctx, due.Ticker, due.Bar)
+	}
+}
+
+// applyIntradayBar folds a live bar into the ticker's running VWAP and TWAP
+// and publishes the result, stamped with the source bar's fetch and publish
+// times and the time the hub processed it
+func (h *EventHub) applyIntradayBar(ctx context.Context, ticker string, bar analytics.Candle) {
+	averages, updated := h.intraday.Update(ticker, bar)
+	if !updated {
+		return
+	}
+
+	h.mu.Lock()
+	stamps := h.liveStamps[ticker]
+	h.mu.Unlock()
+	stamps.ProcessedAt = time.Now()
+
+	if err := h.client.PublishMarketAnalytics(events.WithStamps(ctx, stamps), ticker, averages); err != nil {
+		utils.Error("Failed to publish intraday analytics for %s: %v", ticker, err)
+	}
+}
+
+// isWatched reports whether a ticker is on the watch list; an empty list watches everything
+func (h *EventHub) isWatched(ticker string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.watchedTickers) == 0 {
+		return true
+	}
+	for _, watched := range h.watchedTickers {
+		if watched == ticker {
+			return true
+		}
+	}
+	return false
+}
+
 // subscribeToMarketDailyData subscribes to daily market data events
 func (h *EventHub) subscribeToMarketDailyData(ctx context.Context) error {
-	_, err := h.client.SubscribeMarketDailyData("*", func(data []byte) {
+	sub, err := h.client.SubscribeMarketDailyData("*", func(data []byte) {
 		// Update stats
 		h.mu.Lock()
 		h.stats.TotalEvents++
@@ -264,6 +450,7 @@ func (h *EventHub) subscribeToMarketDailyData(ctx context.Context) error {
 		Subject:  events.SubjectMarketDailyAll,
 		Handler:  func(data []byte) {},
 		Consumer: "EventHub",
+		sub:      sub,
 	})
 	h.mu.Unlock()
 
@@ -273,7 +460,7 @@ func (h *EventHub) subscribeToMarketDailyData(ctx context.Context) error {
 
 // subscribeToHistoricalData subscribes to historical data events
 func (h *EventHub) subscribeToHistoricalData(ctx context.Context) error {
-	_, err := h.client.SubscribeHistoricalData("*", "*", 0, func(data []byte) {
+	sub, err := h.client.SubscribeHistoricalData("*", "*", 0, func(data []byte) {
 		// Update stats
 		h.mu.Lock()
 		h.stats.TotalEvents++
@@ -326,6 +513,7 @@ func (h *EventHub) subscribeToHistoricalData(ctx context.Context) error {
 		Subject:  events.SubjectMarketHistoricalAll,
 		Handler:  func(data []byte) {},
 		Consumer: "EventHub",
+		sub:      sub,
 	})
 	h.mu.Unlock()
 
@@ -335,7 +523,7 @@ func (h *EventHub) subscribeToHistoricalData(ctx context.Context) error {
 
 // subscribeToSignals subscribes to trading signal events
 func (h *EventHub) subscribeToSignals(ctx context.Context) error {
-	_, err := h.client.SubscribeSignals("*", func(data []byte) {
+	sub, err := h.client.SubscribeSignals("*", func(data []byte) {
 		// Update stats
 		h.mu.Lock()
 		h.stats.TotalEvents++
@@ -363,6 +551,7 @@ func (h *EventHub) subscribeToSignals(ctx context.Context) error {
 			h.mu.Unlock()
 
 			signalType, _ := signalData["signal_type"].(string)
+			h.recordSessionSignal(ticker, signalType)
 			utils.Debug("Processed %s signal for %s", signalType, ticker)
 		}
 	})
@@ -376,6 +565,7 @@ func (h *EventHub) subscribeToSignals(ctx context.Context) error {
 		Subject:  events.SubjectSignalsAll,
 		Handler:  func(data []byte) {},
 		Consumer: "EventHub",
+		sub:      sub,
 	})
 	h.mu.Unlock()
 
@@ -386,7 +576,7 @@ func (h *EventHub) subscribeToSignals(ctx context.Context) error {
 // subscribeToRequests subscribes to data request events
 func (h *EventHub) subscribeToRequests(ctx context.Context) error {
 	// Subscribe to historical data requests
-	_, err := h.client.SubscribeHistoricalRequests(func(ticker, timeframe string, days int, reqData []byte) {
+	sub, err := h.client.SubscribeHistoricalRequests(func(ticker, timeframe string, days int, reqData []byte) {
 		// Update stats
 		h.mu.Lock()
 		h.stats.TotalEvents++
@@ -424,6 +614,7 @@ func (h *EventHub) subscribeToRequests(ctx context.Context) error {
 		Subject:  "requests.historical.*.*.*",
 		Handler:  func(data []byte) {},
 		Consumer: "EventHub",
+		sub:      sub,
 	})
 	h.mu.Unlock()
 
@@ -443,6 +634,7 @@ func (h *EventHub) handleHistoricalDataRequest(ctx context.Context, ticker, time
 
 	// Extract requestID if available
 	requestID, _ := request["request_id"].(string)
+	priority, _ := request["priority"].(string)
 	if requestID == "" {
 		requestID = fmt.Sprintf("%s-%s-%d-%d", ticker, timeframe, days, time.Now().UnixNano())
 	}
@@ -455,7 +647,8 @@ func (h *EventHub) handleHistoricalDataRequest(ctx context.Context, ticker, time
 		"timeframe":  timeframe,
 		"days":       days,
 		"source":     "event_hub",
-		"timestamp":  utils.FormatTime(utils.Now(), time.RFC3339),
+		"priority":   events.ParsePriority(priority).String(),
+		"timestamp":  market.FormatTimestamp(h.clock.Now()),
 	}
 
 	// Forward the request
@@ -486,15 +679,17 @@ func (h *EventHub) reportStats(ctx context.Context) {
 				totalEvents, liveEvents, dailyEvents, histEvents, signalEvents, reqEvents, errCount)
 
 			// Log per-ticker stats for active tickers (with recent events)
+			order := h.barSequencer().Stats()
 			h.mu.Lock()
 			activeTickerCount := 0
 			for ticker, stats := range h.stats.TickerStats {
 				// Only log stats for tickers with activity in the last 10 minutes
 				if time.Since(stats.LastEventTime) < 10*time.Minute {
 					activeTickerCount++
-					utils.Debug("  %s: Live: %d, Daily: %d, Historical: %d, Signals: %d, Last: %s",
+					utils.Debug("  %s: Live: %d, Daily: %d, Historical: %d, Signals: %d, Reordered: %d, Late: %d, Last: %s",
 						ticker, stats.LiveEvents, stats.DailyEvents, stats.HistoricalEvents,
-						stats.SignalEvents, utils.FormatTime(stats.LastEventTime, "15:04:05"))
+						stats.SignalEvents, order[ticker].Reordered, order[ticker].Late,
+						h.clock.Format(stats.LastEventTime, "15:04:05"))
 				}
 			}
 			h.mu.Unlock()
@@ -508,6 +703,8 @@ func (h *EventHub) reportStats(ctx context.Context) {
 
 // GetStats returns the current statistics
 func (h *EventHub) GetStats() EventStats {
+	order := h.barSequencer().Stats()
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -517,6 +714,8 @@ func (h *EventHub) GetStats() EventStats {
 	// Copy the ticker stats map
 	stats.TickerStats = make(map[string]TickerStats, len(h.stats.TickerStats))
 	for ticker, tickerStats := range h.stats.TickerStats {
+		tickerStats.ReorderedBars = order[ticker].Reordered
+		tickerStats.LateBars = order[ticker].Late
 		stats.TickerStats[ticker] = tickerStats
 	}
 
@@ -580,6 +779,8 @@ func (h *EventHub) retryStreams() {
 			err = h.subscribeToSignals(h.ctx)
 		case "requests":
 			err = h.subscribeToRequests(h.ctx)
+		case "heartbeats":
+			err = h.subscribeToHeartbeats()
 		}
 
 		// If successful, remove from failed streams
@@ -612,6 +813,7 @@ func (h *EventHub) GetStreamStatus() map[string]bool {
 		"historical": true,
 		"signals":    true,
 		"requests":   true,
+		"heartbeats": true,
 	}
 
 	// Mark failed streams as false
@@ -622,17 +824,25 @@ func (h *EventHub) GetStreamStatus() map[string]bool {
 	return status
 }
 
-// Close stops all subscriptions and cleans up resources
+// Close drains all subscriptions, so messages already delivered are handled
+// and acknowledged, then stops background goroutines. The client's Close
+// flushes publishes and closes the connection.
 func (h *EventHub) Close() {
 	h.mu.Lock()
-	defer h.mu.Unlock()
-
 	utils.Info("Shutting down Event Hub with %d active subscriptions", len(h.subscriptions))
+	subs := make([]*nats.Subscription, 0, len(h.subscriptions))
+	for _, s := range h.subscriptions {
+		if s.sub != nil {
+			subs = append(subs, s.sub)
+		}
+	}
+	h.mu.Unlock()
+
+	// Handlers take h.mu, so drain without holding it
+	events.DrainSubscriptions(subs, events.DrainTimeout)
 
 	// Cancel context to stop background goroutines
 	if h.cancel != nil {
 		h.cancel()
 	}
-
-	// Client handles the NATS connections
-}
\ No newline at end of file
+}
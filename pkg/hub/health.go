@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
-	
+
+	"github.com/myapp/tradinglab/pkg/buildinfo"
+	"github.com/myapp/tradinglab/pkg/metrics"
 	"github.com/myapp/tradinglab/pkg/utils"
 )
 
@@ -13,6 +15,7 @@ type HealthResponse struct {
 	Status    string     `json:"status"`
 	Timestamp time.Time  `json:"timestamp"`
 	Version   string     `json:"version"`
+	Commit    string     `json:"commit,omitempty"`
 	Stats     EventStats `json:"stats"`
 }
 
@@ -23,11 +26,13 @@ func (h *EventHub) StartHealthServer(addr string) error {
 	// Health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		stats := h.GetStats()
+		build := buildinfo.Get()
 
 		response := HealthResponse{
 			Status:    "UP",
 			Timestamp: time.Now(),
-			Version:   "1.0.0",
+			Version:   build.Version,
+			Commit:    build.Commit,
 			Stats:     stats,
 		}
 
@@ -39,7 +44,10 @@ func (h *EventHub) StartHealthServer(addr string) error {
 		}
 	})
 
+	// Prometheus metrics, when RegisterMetrics was called with the default registry
+	mux.Handle("/metrics", metrics.Handler())
+
 	// Start HTTP server
 	utils.Info("Starting health server on %s", addr)
 	return http.ListenAndServe(addr, mux)
-}
\ No newline at end of file
+}
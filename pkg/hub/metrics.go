@@ -0,0 +1,77 @@
+// pkg/hub/metrics.go
+package hub
+
+import (
+	"github.com/myapp/tradinglab/pkg/metrics"
+)
+
+// RegisterMetrics exports the hub's event statistics to reg. Counters are
+// read from GetStats on each scrape, so the once-a-minute log and /metrics
+// always agree.
+func (h *EventHub) RegisterMetrics(reg *metrics.Registry) {
+	eventCounts := reg.NewCounterVec("eventhub_events_total",
+		"Events handled per stream", "stream")
+	errorCount := reg.NewCounterVec("eventhub_errors_total",
+		"Requests that failed to be handled")
+	tickerEvents := reg.NewCounterVec("eventhub_ticker_events_total",
+		"Events handled per stream and ticker", "stream", "ticker")
+	reordered := reg.NewCounterVec("eventhub_reordered_bars_total",
+		"Live bars put back in order per ticker", "ticker")
+	late := reg.NewCounterVec("eventhub_late_bars_total",
+		"Live bars dropped as too late to reorder per ticker", "ticker")
+	anomalous := reg.NewCounterVec("eventhub_anomalous_bars_total",
+		"Live bars flagged as suspect per ticker", "ticker")
+	staleAlerts := reg.NewCounterVec("eventhub_stale_alerts_total",
+		"Times a watched ticker's live data went stale", "ticker")
+	stale := reg.NewGaugeVec("eventhub_ticker_stale",
+		"Whether a watched ticker's live data is currently stale", "ticker")
+	lastEvent := reg.NewGaugeVec("eventhub_ticker_last_event_timestamp_seconds",
+		"Unix time of the last event per ticker", "ticker")
+	streamUp := reg.NewGaugeVec("eventhub_stream_up",
+		"Whether the hub is subscribed to a stream", "stream")
+	clockOffset := reg.NewGaugeVec("eventhub_service_clock_offset_seconds",
+		"Clock offset of a service instance ahead of the hub, from its heartbeats", "service", "instance")
+
+	reg.OnScrape(func() {
+		stats := h.GetStats()
+
+		eventCounts.With("live").Set(float64(stats.LiveEvents))
+		eventCounts.With("daily").Set(float64(stats.DailyEvents))
+		eventCounts.With("historical").Set(float64(stats.HistoricalEvents))
+		eventCounts.With("signals").Set(float64(stats.SignalEvents))
+		eventCounts.With("requests").Set(float64(stats.Requests))
+		errorCount.With().Set(float64(stats.ErrorCount))
+
+		for ticker, ts := range stats.TickerStats {
+			tickerEvents.With("live", ticker).Set(float64(ts.LiveEvents))
+			tickerEvents.With("daily", ticker).Set(float64(ts.DailyEvents))
+			tickerEvents.With("historical", ticker).Set(float64(ts.HistoricalEvents))
+			tickerEvents.With("signals", ticker).Set(float64(ts.SignalEvents))
+			reordered.With(ticker).Set(float64(ts.ReorderedBars))
+			late.With(ticker).Set(float64(ts.LateBars))
+			anomalous.With(ticker).Set(float64(ts.AnomalousBars))
+			staleAlerts.With(ticker).Set(float64(ts.StaleAlerts))
+			if !ts.LastEventTime.IsZero() {
+				lastEvent.With(ticker).SetTime(ts.LastEventTime)
+			}
+		}
+
+		stale.Reset()
+		for _, ticker := range h.StaleTickers() {
+			stale.With(ticker).Set(1)
+		}
+
+		for stream, up := range h.GetStreamStatus() {
+			value := 0.0
+			if up {
+				value = 1
+			}
+			streamUp.With(stream).Set(value)
+		}
+
+		clockOffset.Reset()
+		for _, service := range h.Services() {
+			clockOffset.With(service.Service, service.Instance).Set(service.ClockOffsetMS / 1000)
+		}
+	})
+}
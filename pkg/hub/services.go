@@ -0,0 +1,122 @@
+// pkg/hub/services.go
+package hub
+
+import (
+	"sort"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// missedHeartbeats is how many heartbeat intervals may pass before an
+// instance is reported down
+const missedHeartbeats = 3
+
+// forgetServiceAfter is how long a silent instance stays listed
+const forgetServiceAfter = time.Hour
+
+// ServiceStatus is the latest heartbeat from a service instance. The clock
+// offset compares the heartbeat's timestamp with when the hub received it,
+// so it includes the few milliseconds of delivery.
+type ServiceStatus struct {
+	events.Heartbeat
+	LastSeen      time.Time `json:"last_seen"`
+	Alive         bool      `json:"alive"`
+	ClockOffsetMS float64   `json:"clock_offset_ms"` // Positive when the instance's clock is ahead of the hub's
+	ClockSkewed   bool      `json:"clock_skewed"`
+}
+
+// subscribeToHeartbeats tracks service heartbeats and answers service queries
+func (h *EventHub) subscribeToHeartbeats() error {
+	sub, err := h.client.SubscribeHeartbeats(h.recordHeartbeat)
+	if err != nil {
+		return err
+	}
+
+	served, err := h.client.ServeRequests(events.SubjectServices, func([]byte) (interface{}, error) {
+		return h.Services(), nil
+	})
+	if err != nil {
+		sub.Unsubscribe()
+		return err
+	}
+
+	h.mu.Lock()
+	h.subscriptions = append(h.subscriptions,
+		&Subscription{
+			Subject:  events.SubjectHeartbeatAll,
+			Handler:  func(data []byte) {},
+			Consumer: "EventHub",
+			sub:      sub,
+		},
+		&Subscription{
+			Subject:  events.SubjectServices,
+			Handler:  func(data []byte) {},
+			Consumer: "EventHub",
+			sub:      served,
+		})
+	h.mu.Unlock()
+
+	utils.Info("Subscribed to service heartbeats")
+	return nil
+}
+
+// recordHeartbeat stores the latest heartbeat of an instance and checks its
+// clock against the hub's
+func (h *EventHub) recordHeartbeat(hb events.Heartbeat) {
+	received := time.Now()
+	offset := hb.Timestamp.Sub(received)
+	skewed := h.skew.Record(servicePeer(hb), offset, 0)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, known := h.services[hb.Instance]; !known {
+		utils.Info("Service %s %s (%s) is up", hb.Service, hb.Version, hb.Instance)
+	}
+	h.services[hb.Instance] = ServiceStatus{
+		Heartbeat:     hb,
+		LastSeen:      received,
+		ClockOffsetMS: float64(offset) / float64(time.Millisecond),
+		ClockSkewed:   skewed,
+	}
+}
+
+// servicePeer names an instance in clock skew warnings
+func servicePeer(hb events.Heartbeat) string {
+	return hb.Service + "/" + hb.Instance
+}
+
+// Services returns the latest heartbeat of each known service instance,
+// marking those that have missed several heartbeats as down
+func (h *EventHub) Services() []ServiceStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	services := make([]ServiceStatus, 0, len(h.services))
+	for instance, status := range h.services {
+		silent := now.Sub(status.LastSeen)
+		if silent > forgetServiceAfter {
+			delete(h.services, instance)
+			h.skew.Forget(servicePeer(status.Heartbeat))
+			continue
+		}
+
+		interval := time.Duration(status.IntervalSeconds * float64(time.Second))
+		if interval <= 0 {
+			interval = events.DefaultHeartbeatInterval
+		}
+		status.Alive = silent <= missedHeartbeats*interval
+		services = append(services, status)
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Service != services[j].Service {
+			return services[i].Service < services[j].Service
+		}
+		return services[i].Instance < services[j].Instance
+	})
+	return services
+}
@@ -0,0 +1,196 @@
+// pkg/hub/summary.go
+package hub
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// DefaultSummaryMovers is how many gainers and losers a daily summary lists
+const DefaultSummaryMovers = 5
+
+// dayStats accumulates a market day's activity for the daily summary
+type dayStats struct {
+	date          string
+	moves         map[string]*events.TickerMove
+	signals       events.SignalSummary
+	liveEvents    int64
+	anomalousBars int64                           // Live bars flagged as suspect
+	orderBase     map[string]analytics.OrderStats // Sequencer counts when the day began
+	errorBase     int64                           // Error count when the day began
+}
+
+// currentDay returns the stats of the current market day, starting a new
+// day when the date has changed. The caller holds h.mu.
+func (h *EventHub) currentDay() *dayStats {
+	date := h.clock.MarketNow().Format(events.SummaryDateLayout)
+	if h.day == nil || h.day.date != date {
+		h.day = &dayStats{
+			date:  date,
+			moves: make(map[string]*events.TickerMove),
+			signals: events.SignalSummary{
+				ByType:   make(map[string]int64),
+				ByTicker: make(map[string]int64),
+			},
+			orderBase: h.sequencer.Stats(),
+			errorBase: h.stats.ErrorCount,
+		}
+	}
+	return h.day
+}
+
+// recordSessionBar folds a live bar into the day's move for its ticker
+func (h *EventHub) recordSessionBar(ticker string, marketData map[string]interface{}) {
+	price, _ := marketData["close"].(float64)
+	if price == 0 {
+		price, _ = marketData["price"].(float64)
+	}
+	if price == 0 {
+		return
+	}
+	high, _ := marketData["high"].(float64)
+	low, _ := marketData["low"].(float64)
+	volume, _ := marketData["volume"].(float64)
+	if high == 0 {
+		high = price
+	}
+	if low == 0 {
+		low = price
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	day := h.currentDay()
+	day.liveEvents++
+	move, ok := day.moves[ticker]
+	if !ok {
+		move = &events.TickerMove{Ticker: ticker, Open: price, High: high, Low: low}
+		day.moves[ticker] = move
+	}
+	move.Last = price
+	move.High = max(move.High, high)
+	move.Low = min(move.Low, low)
+	move.Volume += volume
+	move.Bars++
+	move.ChangePercent = (move.Last - move.Open) / move.Open * 100
+}
+
+// recordSessionSignal counts a signal towards the day's summary
+func (h *EventHub) recordSessionSignal(ticker, signalType string) {
+	if signalType == "" {
+		signalType = "unknown"
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	day := h.currentDay()
+	day.signals.Total++
+	day.signals.ByType[signalType]++
+	day.signals.ByTicker[ticker]++
+}
+
+// SetSummaryMovers sets how many gainers and losers the daily summary lists
+func (h *EventHub) SetSummaryMovers(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.summaryMovers = n
+}
+
+// DailySummary compiles the current market day's summary: the biggest
+// movers among watched tickers, signal counts and live data quality
+func (h *EventHub) DailySummary() events.DailySummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	day := h.currentDay()
+
+	watched := h.watchedTickers
+	if len(watched) == 0 {
+		for ticker := range day.moves {
+			watched = append(watched, ticker)
+		}
+	}
+
+	summary := events.DailySummary{
+		Date:        day.date,
+		GeneratedAt: time.Now().UTC(),
+		Gainers:     []events.TickerMove{},
+		Losers:      []events.TickerMove{},
+		Tickers:     []events.TickerMove{},
+		Signals: events.SignalSummary{
+			Total:    day.signals.Total,
+			ByType:   make(map[string]int64, len(day.signals.ByType)),
+			ByTicker: make(map[string]int64, len(day.signals.ByTicker)),
+		},
+		Quality: events.DataQuality{
+			TickersWatched: len(watched),
+			Missing:        []string{},
+			LiveEvents:     day.liveEvents,
+			AnomalousBars:  day.anomalousBars,
+			Errors:         h.stats.ErrorCount - day.errorBase,
+		},
+	}
+	for signalType, n := range day.signals.ByType {
+		summary.Signals.ByType[signalType] = n
+	}
+	for ticker, n := range day.signals.ByTicker {
+		summary.Signals.ByTicker[ticker] = n
+	}
+
+	for _, ticker := range watched {
+		if move, ok := day.moves[ticker]; ok {
+			summary.Tickers = append(summary.Tickers, *move)
+		} else {
+			summary.Quality.Missing = append(summary.Quality.Missing, ticker)
+		}
+	}
+	summary.Quality.TickersWithData = len(summary.Tickers)
+	sort.Strings(summary.Quality.Missing)
+
+	for ticker, stats := range h.sequencer.Stats() {
+		base := day.orderBase[ticker]
+		summary.Quality.ReorderedBars += stats.Reordered - base.Reordered
+		summary.Quality.LateBars += stats.Late - base.Late
+	}
+
+	// Movers by change, then symbol so ties list the same way every time
+	sort.Slice(summary.Tickers, func(i, j int) bool {
+		a, b := summary.Tickers[i], summary.Tickers[j]
+		if a.ChangePercent != b.ChangePercent {
+			return a.ChangePercent > b.ChangePercent
+		}
+		return a.Ticker < b.Ticker
+	})
+	movers := h.summaryMovers
+	if movers <= 0 {
+		movers = DefaultSummaryMovers
+	}
+	for _, move := range summary.Tickers {
+		if move.ChangePercent > 0 && len(summary.Gainers) < movers {
+			summary.Gainers = append(summary.Gainers, move)
+		}
+	}
+	for i := len(summary.Tickers) - 1; i >= 0; i-- {
+		if move := summary.Tickers[i]; move.ChangePercent < 0 && len(summary.Losers) < movers {
+			summary.Losers = append(summary.Losers, move)
+		}
+	}
+	sort.Slice(summary.Tickers, func(i, j int) bool { return summary.Tickers[i].Ticker < summary.Tickers[j].Ticker })
+
+	return summary
+}
+
+// PublishDailySummary compiles the day's summary and publishes it on the
+// summary subject for its date
+func (h *EventHub) PublishDailySummary(ctx context.Context) (events.DailySummary, error) {
+	summary := h.DailySummary()
+	if err := h.client.PublishDailySummary(ctx, summary); err != nil {
+		return summary, err
+	}
+	utils.Info("Published daily summary for %s: %d tickers with data, %d missing, %d signals",
+		summary.Date, summary.Quality.TickersWithData, len(summary.Quality.Missing), summary.Signals.Total)
+	return summary, nil
+}
@@ -0,0 +1,155 @@
+// pkg/hub/watchdog.go
+package hub
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// DefaultStaleThreshold is how long a watched ticker may go without live
+// data during market hours before it is reported stale
+const DefaultStaleThreshold = 5 * time.Minute
+
+// hubService names the hub in the ops alerts it raises
+const hubService = "event-hub"
+
+// StaleConfig sets when watched tickers' live data counts as stale
+type StaleConfig struct {
+	Threshold time.Duration // Longest gap in live data; zero disables the watchdog
+	Restart   bool          // Ask the market data service to restart a stale ticker's stream
+	AllHours  bool          // Watch around the clock, not only in the ticker's market hours
+}
+
+// staleTicker is the watchdog's view of one watched ticker
+type staleTicker struct {
+	watchedFrom time.Time // Start of the watch in the current session
+	alerted     time.Time // When the ticker was reported stale; zero while fresh
+}
+
+// SetStaleConfig sets the stale data watchdog's threshold and whether it
+// restarts stale streams. Call before Start.
+func (h *EventHub) SetStaleConfig(cfg StaleConfig) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.staleConfig = cfg
+}
+
+// StaleTickers lists the watched tickers currently reported stale
+func (h *EventHub) StaleTickers() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stale := []string{}
+	for ticker, st := range h.staleTickers {
+		if !st.alerted.IsZero() {
+			stale = append(stale, ticker)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// watchStaleTickers checks watched tickers for stale live data until ctx ends
+func (h *EventHub) watchStaleTickers(ctx context.Context) {
+	h.mu.Lock()
+	threshold := h.staleConfig.Threshold
+	h.mu.Unlock()
+	if threshold <= 0 {
+		return
+	}
+
+	interval := min(max(threshold/4, 100*time.Millisecond), time.Minute)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.checkStaleTickers(time.Now())
+		}
+	}
+}
+
+// checkStaleTickers reports watched tickers in session that have had no live
+// data for longer than the threshold, and those whose data has resumed. A
+// ticker's gap is counted from its last live bar or, if later, the start of
+// the session, so a quiet night does not count against it.
+func (h *EventHub) checkStaleTickers(now time.Time) {
+	var alerts []events.OpsAlert
+	h.mu.Lock()
+	cfg := h.staleConfig
+	for _, ticker := range h.watchedTickers {
+		st, ok := h.staleTickers[ticker]
+		if !ok {
+			st = &staleTicker{}
+			h.staleTickers[ticker] = st
+		}
+		if !cfg.AllHours && !market.InSession(ticker, now) {
+			*st = staleTicker{}
+			continue
+		}
+		if st.watchedFrom.IsZero() {
+			st.watchedFrom = now
+		}
+
+		last := h.lastLive[ticker]
+		since := last
+		if since.Before(st.watchedFrom) {
+			since = st.watchedFrom
+		}
+		switch {
+		case st.alerted.IsZero() && now.Sub(since) > cfg.Threshold:
+			st.alerted = now
+			stats := h.stats.TickerStats[ticker]
+			stats.StaleAlerts++
+			h.stats.TickerStats[ticker] = stats
+			alerts = append(alerts, events.OpsAlert{
+				Kind:    events.OpsAlertStaleData,
+				Service: hubService,
+				Ticker:  ticker,
+				Message: fmt.Sprintf("No live data for %s in %s", ticker, now.Sub(since).Round(time.Second)),
+				Since:   since,
+			})
+		case !st.alerted.IsZero() && last.After(st.alerted):
+			alerts = append(alerts, events.OpsAlert{
+				Kind:     events.OpsAlertStaleData,
+				Service:  hubService,
+				Ticker:   ticker,
+				Message:  fmt.Sprintf("Live data for %s resumed", ticker),
+				Since:    st.alerted,
+				Resolved: true,
+			})
+			st.alerted = time.Time{}
+		}
+	}
+	h.mu.Unlock()
+
+	for _, alert := range alerts {
+		if alert.Resolved {
+			utils.Info("%s", alert.Message)
+		} else {
+			utils.Warn("%s", alert.Message)
+		}
+		if err := h.client.PublishOpsAlert(alert); err != nil {
+			utils.Error("Failed to publish stale data alert for %s: %v", alert.Ticker, err)
+		}
+		if alert.Resolved || !cfg.Restart {
+			continue
+		}
+		err := h.client.PublishStreamRestart(events.StreamRestart{
+			Ticker:      alert.Ticker,
+			Reason:      alert.Message,
+			RequestedBy: hubService,
+		})
+		if err != nil {
+			utils.Error("Failed to request a stream restart for %s: %v", alert.Ticker, err)
+		}
+	}
+}
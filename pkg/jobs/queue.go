@@ -0,0 +1,389 @@
+// pkg/jobs/queue.go
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("job not found")
+	ErrFinished   = errors.New("job has already finished")
+	ErrQueueFull  = errors.New("job queue is full")
+	ErrOwnerLimit = errors.New("too many unfinished jobs")
+	ErrClosed     = errors.New("job queue is closed")
+)
+
+// Job states
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateDone      = "done"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// Func runs a job. It should return promptly once ctx is cancelled.
+type Func func(ctx context.Context) (interface{}, error)
+
+// idKey carries a job's ID in the context its Func runs under
+type idKey struct{}
+
+// IDFrom returns the ID of the job whose context ctx is, or "" outside a job
+func IDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+// Limits bounds the work a queue accepts and runs
+type Limits struct {
+	Workers         int           // Jobs running at once across all owners
+	RunningPerOwner int           // Jobs one owner may have running at once
+	PendingPerOwner int           // Unfinished jobs one owner may have, queued or running; zero is unlimited
+	MaxQueued       int           // Jobs waiting across all owners; zero is unlimited
+	Retain          time.Duration // How long finished jobs stay visible
+}
+
+// Job is a snapshot of a job's state
+type Job struct {
+	ID       string      `json:"id"`
+	Owner    string      `json:"owner"`
+	Kind     string      `json:"kind"`
+	State    string      `json:"state"`
+	Position int         `json:"position,omitempty"` // Jobs ahead of a queued job, plus one
+	Created  time.Time   `json:"created_at"`
+	Started  *time.Time  `json:"started_at,omitempty"`
+	Finished *time.Time  `json:"finished_at,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Result   interface{} `json:"result,omitempty"`
+}
+
+// Stats counts a queue's jobs by state
+type Stats struct {
+	Workers int `json:"workers"`
+	Queued  int `json:"queued"`
+	Running int `json:"running"`
+}
+
+// job is a queued, running or retained job
+type job struct {
+	Job
+	run    Func
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{} // Closed when the job finishes
+}
+
+// Queue runs jobs first in first out on a fixed pool of workers. A queued
+// job is passed over while its owner has RunningPerOwner jobs running, so
+// one owner's sweep cannot hold every worker while others wait.
+type Queue struct {
+	mu      sync.Mutex
+	limits  Limits
+	jobs    map[string]*job
+	pending []*job // In submission order
+	running map[string]int
+	owners  map[string]int // Unfinished jobs by owner
+	nextID  int64
+	wake    chan struct{}
+	ctx     context.Context
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewQueue starts a queue's workers. Workers and RunningPerOwner below one
+// are treated as one.
+func NewQueue(limits Limits) *Queue {
+	if limits.Workers < 1 {
+		limits.Workers = 1
+	}
+	if limits.RunningPerOwner < 1 {
+		limits.RunningPerOwner = 1
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	q := &Queue{
+		limits:  limits,
+		jobs:    make(map[string]*job),
+		running: make(map[string]int),
+		owners:  make(map[string]int),
+		wake:    make(chan struct{}, limits.Workers),
+		ctx:     ctx,
+		stop:    stop,
+	}
+	for i := 0; i < limits.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Limits returns the queue's limits
+func (q *Queue) Limits() Limits {
+	return q.limits
+}
+
+// Submit queues a job for owner. ctx carries values such as the caller's
+// identity into the job, along with the job's ID for IDFrom; its
+// cancellation does not cancel the job.
+func (q *Queue) Submit(ctx context.Context, owner, kind string, run Func) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.ctx.Err() != nil {
+		return Job{}, ErrClosed
+	}
+	q.prune(time.Now())
+	if q.limits.MaxQueued > 0 && len(q.pending) >= q.limits.MaxQueued {
+		return Job{}, ErrQueueFull
+	}
+	if q.limits.PendingPerOwner > 0 && q.owners[owner] >= q.limits.PendingPerOwner {
+		return Job{}, fmt.Errorf("%w: at most %d per user", ErrOwnerLimit, q.limits.PendingPerOwner)
+	}
+
+	now := time.Now()
+	q.nextID++
+	id := fmt.Sprintf("job-%d-%d", now.Unix(), q.nextID)
+	jobCtx, cancel := context.WithCancel(context.WithValue(context.WithoutCancel(ctx), idKey{}, id))
+	j := &job{
+		Job: Job{
+			ID:      id,
+			Owner:   owner,
+			Kind:    kind,
+			State:   StateQueued,
+			Created: now,
+		},
+		run:    run,
+		ctx:    jobCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	q.jobs[j.ID] = j
+	q.pending = append(q.pending, j)
+	q.owners[owner]++
+	q.signal()
+	return q.snapshot(j), nil
+}
+
+// Get returns a job by ID
+func (q *Queue) Get(id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j, exists := q.jobs[id]
+	if !exists {
+		return Job{}, ErrNotFound
+	}
+	return q.snapshot(j), nil
+}
+
+// Wait blocks until a job finishes or ctx ends, and returns the job as it
+// then stands
+func (q *Queue) Wait(ctx context.Context, id string) (Job, error) {
+	q.mu.Lock()
+	j, exists := q.jobs[id]
+	q.mu.Unlock()
+	if !exists {
+		return Job{}, ErrNotFound
+	}
+
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if j.Finished != nil {
+		return q.snapshot(j), nil
+	}
+	return q.snapshot(j), ctx.Err()
+}
+
+// List returns an owner's jobs, or every job when owner is empty, newest
+// first. Results are left out.
+func (q *Queue) List(owner string) []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.prune(time.Now())
+	list := make([]Job, 0)
+	for _, j := range q.jobs {
+		if owner != "" && j.Owner != owner {
+			continue
+		}
+		snapshot := q.snapshot(j)
+		snapshot.Result = nil
+		list = append(list, snapshot)
+	}
+	sort.Slice(list, func(i, k int) bool {
+		return list[i].Created.After(list[k].Created) ||
+			(list[i].Created.Equal(list[k].Created) && list[i].ID > list[k].ID)
+	})
+	return list
+}
+
+// Cancel stops a queued or running job. A running job is marked cancelled
+// at once; its function is left to notice the cancelled context.
+func (q *Queue) Cancel(id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j, exists := q.jobs[id]
+	if !exists {
+		return Job{}, ErrNotFound
+	}
+
+	switch j.State {
+	case StateQueued:
+		for i, p := range q.pending {
+			if p == j {
+				q.pending = append(q.pending[:i], q.pending[i+1:]...)
+				break
+			}
+		}
+	case StateRunning:
+		q.running[j.Owner]--
+		q.signal()
+	default:
+		return q.snapshot(j), ErrFinished
+	}
+	j.cancel()
+	q.finish(j, StateCancelled, nil, "cancelled by request")
+	return q.snapshot(j), nil
+}
+
+// Stats returns the number of queued and running jobs
+func (q *Queue) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := Stats{Workers: q.limits.Workers, Queued: len(q.pending)}
+	for _, n := range q.running {
+		stats.Running += n
+	}
+	return stats
+}
+
+// Close cancels every unfinished job and waits for the workers to exit
+func (q *Queue) Close() {
+	q.mu.Lock()
+	q.stop()
+	for _, j := range q.jobs {
+		if j.State == StateQueued || j.State == StateRunning {
+			j.cancel()
+			q.finish(j, StateCancelled, nil, "queue closed")
+		}
+	}
+	q.pending = nil
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+// work runs jobs until the queue is closed
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for {
+		j := q.next()
+		if j == nil {
+			select {
+			case <-q.wake:
+				continue
+			case <-q.ctx.Done():
+				return
+			}
+		}
+
+		result, err := j.run(j.ctx)
+
+		q.mu.Lock()
+		// Cancelled jobs were finished and released by Cancel or Close
+		if j.State == StateRunning {
+			q.running[j.Owner]--
+			if err != nil {
+				q.finish(j, StateFailed, nil, err.Error())
+			} else {
+				q.finish(j, StateDone, result, "")
+			}
+		}
+		j.cancel()
+		q.signal()
+		q.mu.Unlock()
+	}
+}
+
+// next takes the oldest queued job whose owner is below their running
+// limit and marks it running, or returns nil when none can start
+func (q *Queue) next() *job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, j := range q.pending {
+		if q.running[j.Owner] >= q.limits.RunningPerOwner {
+			continue
+		}
+		q.pending = append(q.pending[:i], q.pending[i+1:]...)
+		now := time.Now()
+		j.State = StateRunning
+		j.Started = &now
+		q.running[j.Owner]++
+		return j
+	}
+	return nil
+}
+
+// finish records a job's outcome and releases its owner's slot. Callers
+// hold mu.
+func (q *Queue) finish(j *job, state string, result interface{}, message string) {
+	now := time.Now()
+	j.State = state
+	j.Finished = &now
+	j.Result = result
+	j.Error = message
+	close(j.done)
+	q.owners[j.Owner]--
+	if q.owners[j.Owner] <= 0 {
+		delete(q.owners, j.Owner)
+	}
+	if q.running[j.Owner] <= 0 {
+		delete(q.running, j.Owner)
+	}
+}
+
+// signal wakes an idle worker without blocking. Callers hold mu.
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// snapshot copies a job, with its position if queued. Callers hold mu.
+func (q *Queue) snapshot(j *job) Job {
+	snapshot := j.Job
+	if j.State == StateQueued {
+		for i, p := range q.pending {
+			if p == j {
+				snapshot.Position = i + 1
+				break
+			}
+		}
+	}
+	return snapshot
+}
+
+// prune drops jobs that finished more than Retain ago. Callers hold mu.
+func (q *Queue) prune(now time.Time) {
+	if q.limits.Retain <= 0 {
+		return
+	}
+	for id, j := range q.jobs {
+		if j.Finished != nil && now.Sub(*j.Finished) > q.limits.Retain {
+			delete(q.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// pkg/journal/journal.go
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a journaled trade with review annotations
+type Entry struct {
+	ID          string    `json:"id"`
+	Ticker      string    `json:"ticker"`
+	Strategy    string    `json:"strategy,omitempty"`
+	Direction   string    `json:"direction,omitempty"`
+	Quantity    int       `json:"quantity,omitempty"`
+	EntryPrice  float64   `json:"entry_price,omitempty"`
+	ExitPrice   float64   `json:"exit_price,omitempty"`
+	PnL         float64   `json:"pnl"`
+	Simulated   bool      `json:"simulated"`
+	PositionID  string    `json:"position_id,omitempty"`
+	TradeTime   time.Time `json:"trade_time"`
+	Tags        []string  `json:"tags"`
+	Screenshots []string  `json:"screenshots"`
+	Notes       string    `json:"notes"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Annotation holds the fields of an entry that may be edited after creation
+type Annotation struct {
+	Tags        *[]string `json:"tags,omitempty"`
+	Screenshots *[]string `json:"screenshots,omitempty"`
+	Notes       *string   `json:"notes,omitempty"`
+	Strategy    *string   `json:"strategy,omitempty"`
+}
+
+// Query filters journal entries. Zero values match everything.
+type Query struct {
+	Ticker   string
+	Strategy string
+	Tag      string
+	From     time.Time
+	To       time.Time
+}
+
+// Store keeps journal entries in memory and optionally persists them to a JSON file
+type Store struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+	path    string
+	nextID  int64
+}
+
+// NewStore creates a journal store. If path is non-empty, existing entries are
+// loaded from it and every change is written back.
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		entries: make(map[string]Entry),
+		path:    path,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal file: %w", err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse journal file: %w", err)
+	}
+	for _, e := range entries {
+		s.entries[e.ID] = e
+	}
+	s.nextID = int64(len(entries))
+
+	return s, nil
+}
+
+// Add creates a new journal entry
+func (s *Store) Add(e Entry) (Entry, error) {
+	if e.Ticker == "" {
+		return Entry{}, fmt.Errorf("ticker is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.nextID++
+	e.ID = fmt.Sprintf("j-%d-%d", now.Unix(), s.nextID)
+	e.Tags = normalizeTags(e.Tags)
+	if e.Screenshots == nil {
+		e.Screenshots = []string{}
+	}
+	if e.TradeTime.IsZero() {
+		e.TradeTime = now
+	}
+	e.CreatedAt = now
+	e.UpdatedAt = now
+
+	s.entries[e.ID] = e
+	return e, s.persist()
+}
+
+// Annotate updates the editable fields of an entry
+func (s *Store) Annotate(id string, a Annotation) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.entries[id]
+	if !exists {
+		return Entry{}, fmt.Errorf("journal entry %s not found", id)
+	}
+
+	if a.Tags != nil {
+		e.Tags = normalizeTags(*a.Tags)
+	}
+	if a.Screenshots != nil {
+		e.Screenshots = *a.Screenshots
+	}
+	if a.Notes != nil {
+		e.Notes = *a.Notes
+	}
+	if a.Strategy != nil {
+		e.Strategy = *a.Strategy
+	}
+	e.UpdatedAt = time.Now()
+
+	s.entries[id] = e
+	return e, s.persist()
+}
+
+// Get returns a single entry
+func (s *Store) Get(id string) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, exists := s.entries[id]
+	return e, exists
+}
+
+// Delete removes an entry
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[id]; !exists {
+		return fmt.Errorf("journal entry %s not found", id)
+	}
+	delete(s.entries, id)
+	return s.persist()
+}
+
+// Find returns entries matching the query, most recent trade first
+func (s *Store) Find(q Query) []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tag := strings.ToLower(q.Tag)
+	results := make([]Entry, 0)
+	for _, e := range s.entries {
+		if q.Ticker != "" && !strings.EqualFold(e.Ticker, q.Ticker) {
+			continue
+		}
+		if q.Strategy != "" && !strings.EqualFold(e.Strategy, q.Strategy) {
+			continue
+		}
+		if !q.From.IsZero() && e.TradeTime.Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && e.TradeTime.After(q.To) {
+			continue
+		}
+		if tag != "" && !hasTag(e.Tags, tag) {
+			continue
+		}
+		results = append(results, e)
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].TradeTime.After(results[j].TradeTime) })
+	return results
+}
+
+// persist writes all entries to the journal file. Caller holds the lock.
+func (s *Store) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.Before(entries[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	// Write atomically so a crash never leaves a truncated journal
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write journal file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// normalizeTags lowercases, trims and de-duplicates tags
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+	return result
+}
+
+// hasTag reports whether tags contains the (lowercased) tag
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -6,20 +6,30 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata/stream"
+	"github.com/myapp/tradinglab/pkg/chaos"
 	"github.com/myapp/tradinglab/pkg/utils"
 )
 
 // AlpacaProvider implements market data fetching from Alpaca API
 type AlpacaProvider struct {
+	mu               sync.RWMutex // Guards the clients and credentials, which rotation replaces
 	alpacaClient     *alpaca.Client
 	marketDataClient *marketdata.Client
+	apiKey           string // Kept for the streaming clients
+	apiSecret        string
+	rotated          chan struct{} // Closed and replaced when credentials change
 	paperTrading     bool
 	dataFeed         marketdata.Feed        // Data feed to use (IEX, SIP)
 	lastValidData    map[string]*MarketData // Cache last valid data by ticker
+	fallbackPolicy   FallbackPolicy         // What to serve when real data is unavailable
+	cache            *ResponseCache         // Memoizes historical queries; nil disables
+	faults           *chaos.Injector        // Delays API calls in resilience tests; nil when off
 }
 
 // NewAlpacaProvider creates a new Alpaca data provider using the official SDK
@@ -57,21 +67,90 @@ func NewAlpacaProvider(apiKey, apiSecret string, paperTrading bool) (*AlpacaProv
 	}
 	utils.Info("Using Alpaca data feed: %s", dataFeed)
 
+	// Determine how to behave when real data is unavailable
+	fallbackPolicy := FallbackPolicyFromEnv()
+	utils.Info("Using fallback data policy: %s", fallbackPolicy)
+
 	return &AlpacaProvider{
 		alpacaClient:     alpacaClient,
 		marketDataClient: marketDataClient,
+		apiKey:           apiKey,
+		apiSecret:        apiSecret,
+		rotated:          make(chan struct{}),
 		paperTrading:     paperTrading,
 		dataFeed:         dataFeed,
 		lastValidData:    make(map[string]*MarketData),
+		fallbackPolicy:   fallbackPolicy,
+		faults:           chaos.Default(),
 	}, nil
 }
 
+// SetCredentials replaces the API key and secret, rebuilding the API
+// clients. Requests in flight finish on the old clients; trade streams
+// reconnect with the new credentials.
+func (p *AlpacaProvider) SetCredentials(apiKey, apiSecret string) error {
+	if apiKey == "" || apiSecret == "" {
+		return fmt.Errorf("Alpaca API key and secret are required")
+	}
+
+	alpacaClient := alpaca.NewClient(alpaca.ClientOpts{APIKey: apiKey, APISecret: apiSecret})
+	marketDataClient := marketdata.NewClient(marketdata.ClientOpts{APIKey: apiKey, APISecret: apiSecret})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.alpacaClient = alpacaClient
+	p.marketDataClient = marketDataClient
+	p.apiKey = apiKey
+	p.apiSecret = apiSecret
+	close(p.rotated)
+	p.rotated = make(chan struct{})
+	return nil
+}
+
+// VerifyCredentials checks an API key and secret authenticate, without
+// using them for anything else
+func (p *AlpacaProvider) VerifyCredentials(apiKey, apiSecret string) error {
+	client := alpaca.NewClient(alpaca.ClientOpts{APIKey: apiKey, APISecret: apiSecret})
+	if _, err := client.GetClock(); err != nil {
+		return fmt.Errorf("Alpaca rejected the credentials: %w", err)
+	}
+	return nil
+}
+
+// tradingAPI returns the current account and trading client
+func (p *AlpacaProvider) tradingAPI() *alpaca.Client {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.alpacaClient
+}
+
+// dataAPI returns the current market data client
+func (p *AlpacaProvider) dataAPI() *marketdata.Client {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.marketDataClient
+}
+
+// credentials returns the current API key and secret
+func (p *AlpacaProvider) credentials() (string, string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.apiKey, p.apiSecret
+}
+
+// rotation returns a channel closed when the credentials next change
+func (p *AlpacaProvider) rotation() <-chan struct{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.rotated
+}
+
 // IsMarketOpen checks if the market is currently open
 func (p *AlpacaProvider) IsMarketOpen(ctx context.Context) (bool, error) {
 	utils.Debug("Making request to Alpaca API to get market clock")
 
 	// Use the Alpaca SDK to get the market clock
-	clock, err := p.alpacaClient.GetClock()
+	clock, err := p.tradingAPI().GetClock()
 	if err != nil {
 		// Check for 401 unauthorized error
 		if strings.Contains(err.Error(), "request is not authorized") ||
@@ -79,9 +158,8 @@ func (p *AlpacaProvider) IsMarketOpen(ctx context.Context) (bool, error) {
 			utils.Debug("Alpaca API authentication failed: %v", err)
 			utils.Warn("Authentication failure when checking market status. This may be due to invalid API keys or expired credentials")
 
-			// Set fallback value based on current time (Eastern Time)
-			loc, _ := time.LoadLocation("America/New_York")
-			now := time.Now().In(loc)
+			// Set fallback value based on current time in exchange time
+			now := time.Now().In(ExchangeLocation())
 
 			// Regular market hours are 9:30 AM - 4:00 PM ET, Mon-Fri
 			hour, min, sec := now.Clock()
@@ -109,6 +187,29 @@ func (p *AlpacaProvider) IsMarketOpen(ctx context.Context) (bool, error) {
 	return clock.IsOpen, nil
 }
 
+// MarketClock is the provider's market clock
+type MarketClock struct {
+	Timestamp time.Time `json:"timestamp"`
+	IsOpen    bool      `json:"is_open"`
+	NextOpen  time.Time `json:"next_open"`
+	NextClose time.Time `json:"next_close"`
+}
+
+// Clock fetches the market clock. Unlike IsMarketOpen it never falls back
+// to a local estimate, so it reports whether the trading API is reachable.
+func (p *AlpacaProvider) Clock(ctx context.Context) (*MarketClock, error) {
+	clock, err := p.tradingAPI().GetClock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market clock: %w", err)
+	}
+	return &MarketClock{
+		Timestamp: clock.Timestamp,
+		IsOpen:    clock.IsOpen,
+		NextOpen:  clock.NextOpen,
+		NextClose: clock.NextClose,
+	}, nil
+}
+
 // GetLatestData fetches real-time market data for a ticker
 func (p *AlpacaProvider) GetLatestData(ctx context.Context, ticker string) (*MarketData, error) {
 	utils.Debug("Fetching latest data for ticker %s", ticker)
@@ -131,7 +232,7 @@ func (p *AlpacaProvider) GetLatestData(ctx context.Context, ticker string) (*Mar
 	}
 
 	utils.Debug("Making request to Alpaca API for latest quote for %s using %s feed", ticker, p.dataFeed)
-	quote, err := p.marketDataClient.GetLatestQuote(ticker, request)
+	quote, err := p.dataAPI().GetLatestQuote(ticker, request)
 	if err != nil {
 		utils.Debug("Error getting latest quote for %s: %v", ticker, err)
 		utils.Warn("Failed to get latest quote for %s: %v, falling back to bars", ticker, err)
@@ -271,7 +372,7 @@ func (p *AlpacaProvider) GetMostRecentData(ctx context.Context, ticker string) (
 	}
 
 	// If all else fails, check if we have cached data
-	if cachedData, ok := p.lastValidData[ticker]; ok {
+	if cachedData, ok := p.lastValidData[ticker]; ok && p.fallbackPolicy.AllowsCached() {
 		utils.Info("Using cached data for %s", ticker)
 		// Return a copy with updated timestamp
 		dataCopy := *cachedData
@@ -280,9 +381,18 @@ func (p *AlpacaProvider) GetMostRecentData(ctx context.Context, ticker string) (
 		return &dataCopy, nil
 	}
 
-	// Last resort: generate sample data
-	utils.Warn("No data available for %s, generating sample data", ticker)
-	return p.generateSampleData(ticker), nil
+	// Last resort: generate sample data, but only when explicitly allowed
+	if p.fallbackPolicy.AllowsSample() {
+		utils.Warn("No data available for %s, generating sample data", ticker)
+		return p.generateSampleData(ticker), nil
+	}
+
+	return nil, fmt.Errorf("no data available for %s (fallback policy: %s)", ticker, p.fallbackPolicy)
+}
+
+// FallbackPolicy returns the configured fallback data policy
+func (p *AlpacaProvider) FallbackPolicy() FallbackPolicy {
+	return p.fallbackPolicy
 }
 
 // GetDailyData fetches end-of-day data for a ticker
@@ -321,17 +431,56 @@ func (p *AlpacaProvider) GetDailyData(ctx context.Context, ticker string) (*Mark
 func (p *AlpacaProvider) GetHistoricalData(ctx context.Context, ticker string, days int, timeframe string) ([]*MarketData, error) {
 	utils.Debug("Fetching historical data for %s, %d days, timeframe %s", ticker, days, timeframe)
 
+	// Normalize parameters so equivalent requests resolve to the same range
+	params, err := NormalizeHistoricalParams(ticker, timeframe, days)
+	if err != nil {
+		utils.Error("Invalid historical data parameters: %s, %s, %d - %v", ticker, timeframe, days, err)
+		return nil, err
+	}
+
 	// Convert timeframe to Alpaca format
-	alpacaTimeframe, err := convertToAlpacaTimeframe(timeframe)
+	alpacaTimeframe, err := convertToAlpacaTimeframe(params.Interval)
 	if err != nil {
-		utils.Error("Invalid timeframe format: %s - %v", timeframe, err)
+		utils.Error("Invalid timeframe format: %s - %v", params.Interval, err)
+		return nil, err
+	}
+
+	key := HistoricalCacheKey("alpaca-"+strings.ToLower(string(p.dataFeed)), params)
+	return p.cache.Historical(key, func() ([]*MarketData, error) {
+		// Calculate time range aligned to bar boundaries
+		start, end := params.DateRange(time.Now())
+		data, err := p.fetchHistoricalBars(ctx, params.Ticker, params.Interval, alpacaTimeframe, start, end)
+		if err == nil && len(data) == 0 {
+			return nil, fmt.Errorf("no historical data found for %s", params.Ticker)
+		}
+		return data, err
+	})
+}
+
+// GetHistoricalDataSince fetches the bars of an interval from since (inclusive)
+// to now, for incremental syncs that only need what is new. An empty result
+// is not an error. Responses are not cached.
+func (p *AlpacaProvider) GetHistoricalDataSince(ctx context.Context, ticker, timeframe string, since time.Time) ([]*MarketData, error) {
+	interval, err := NormalizeInterval(timeframe)
+	if err != nil {
+		return nil, err
+	}
+	alpacaTimeframe, err := convertToAlpacaTimeframe(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	barLength := intervalDurations[interval]
+	end := time.Now().UTC().Truncate(barLength).Add(barLength)
+	return p.fetchHistoricalBars(ctx, NormalizeTicker(ticker), interval, alpacaTimeframe, since, end)
+}
+
+// fetchHistoricalBars requests historical bars between start and end from Alpaca
+func (p *AlpacaProvider) fetchHistoricalBars(ctx context.Context, ticker, timeframe string, alpacaTimeframe marketdata.TimeFrame, start, end time.Time) ([]*MarketData, error) {
+	if err := p.faults.Delay(ctx); err != nil {
 		return nil, err
 	}
 
-	// Calculate time range
-	now := time.Now()
-	end := now
-	start := now.AddDate(0, 0, -days)
 	utils.Debug("Historical data period: %s to %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
 
 	// Get bars using the SDK
@@ -345,7 +494,7 @@ func (p *AlpacaProvider) GetHistoricalData(ctx context.Context, ticker string, d
 
 	// Get bars for the requested symbol
 	utils.Debug("Making request to Alpaca API for historical bars for %s", ticker)
-	bars, err := p.marketDataClient.GetBars(ticker, barsRequest)
+	bars, err := p.dataAPI().GetBars(ticker, barsRequest)
 	if err != nil {
 		utils.Error("Failed to get historical bars for %s: %v", ticker, err)
 		return nil, fmt.Errorf("failed to get historical bars: %w", err)
@@ -381,15 +530,238 @@ func (p *AlpacaProvider) GetHistoricalData(ctx context.Context, ticker string, d
 		data = append(data, marketData)
 	}
 
-	if len(data) == 0 {
-		return nil, fmt.Errorf("no historical data found for %s", ticker)
+	return data, nil
+}
+
+// SetResponseCache enables caching of historical queries
+func (p *AlpacaProvider) SetResponseCache(cache *ResponseCache) {
+	p.cache = cache
+}
+
+// GetOrderBook returns a depth snapshot for a ticker. Alpaca's equity feeds
+// only carry the national best bid and offer, so the book has one level per side.
+func (p *AlpacaProvider) GetOrderBook(ctx context.Context, ticker string) (*OrderBook, error) {
+	if err := p.faults.Delay(ctx); err != nil {
+		return nil, err
 	}
 
-	return data, nil
+	quote, err := p.dataAPI().GetLatestQuote(ticker, marketdata.GetLatestQuoteRequest{
+		Feed: p.dataFeed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest quote for %s: %w", ticker, err)
+	}
+	return orderBookFromQuote(ticker, quote)
+}
+
+// orderBookFromQuote builds a one-level book from the best bid and offer
+func orderBookFromQuote(ticker string, quote *marketdata.Quote) (*OrderBook, error) {
+	if quote.BidPrice <= 0 || quote.AskPrice <= 0 {
+		return nil, fmt.Errorf("no two-sided quote available for %s", ticker)
+	}
+
+	book := &OrderBook{
+		Ticker:    ticker,
+		Timestamp: quote.Timestamp,
+		Bids:      []BookLevel{{Price: quote.BidPrice, Size: float64(quote.BidSize), Exchange: quote.BidExchange}},
+		Asks:      []BookLevel{{Price: quote.AskPrice, Size: float64(quote.AskSize), Exchange: quote.AskExchange}},
+		Source:    "Alpaca",
+	}
+	book.Summarize()
+	return book, nil
+}
+
+// LatestSnapshot is a ticker's result from a batch poll
+type LatestSnapshot struct {
+	Data *MarketData
+	Book *OrderBook // nil when no two-sided quote is available or the market is closed
+}
+
+// GetLatestBatch fetches live data for several tickers in one poll: while the
+// market is open, one latest-quotes call and one latest-bars call cover every
+// ticker; when it is closed, only the bars call is made. Tickers missing from
+// the batch response fall back to GetMostRecentData.
+func (p *AlpacaProvider) GetLatestBatch(ctx context.Context, tickers []string) (map[string]*LatestSnapshot, error) {
+	if len(tickers) == 0 {
+		return map[string]*LatestSnapshot{}, nil
+	}
+
+	if err := p.faults.Delay(ctx); err != nil {
+		return nil, err
+	}
+
+	isOpen, err := p.IsMarketOpen(ctx)
+	if err != nil {
+		utils.Warn("Failed to check market status: %v", err)
+	}
+
+	var quotes map[string]marketdata.Quote
+	if isOpen {
+		quotes, err = p.dataAPI().GetLatestQuotes(tickers, marketdata.GetLatestQuoteRequest{Feed: p.dataFeed})
+		if err != nil {
+			utils.Warn("Failed to get latest quotes for %d tickers: %v, falling back to bars", len(tickers), err)
+			isOpen = false
+		}
+	}
+
+	bars, err := p.dataAPI().GetLatestBars(tickers, marketdata.GetLatestBarRequest{Feed: p.dataFeed})
+	if err != nil {
+		utils.Warn("Failed to get latest bars for %d tickers: %v", len(tickers), err)
+	}
+
+	dataType := "recent"
+	if isOpen {
+		dataType = "live"
+	}
+
+	snapshots := make(map[string]*LatestSnapshot, len(tickers))
+	for _, ticker := range tickers {
+		bar, hasBar := bars[ticker]
+		quote, hasQuote := quotes[ticker]
+		if !hasBar && !hasQuote {
+			// Not in the batch; use the single-ticker fallbacks
+			data, err := p.GetMostRecentData(ctx, ticker)
+			if err != nil {
+				utils.Error("No data available for %s: %v", ticker, err)
+				continue
+			}
+			snapshots[ticker] = &LatestSnapshot{Data: data}
+			continue
+		}
+
+		data := &MarketData{
+			Ticker:   ticker,
+			Interval: "1min",
+			Source:   "Alpaca",
+			DataType: dataType,
+		}
+		if hasBar {
+			data.Timestamp = bar.Timestamp
+			data.Price = bar.Close
+			data.Open = bar.Open
+			data.High = bar.High
+			data.Low = bar.Low
+			data.Close = bar.Close
+			data.Volume = int64(bar.Volume)
+			data.VWAP = bar.VWAP
+			data.TradeCount = int(bar.TradeCount)
+		}
+
+		snapshot := &LatestSnapshot{Data: data}
+		if hasQuote {
+			// Price at the quote midpoint, as GetLatestData does
+			midPrice := (quote.BidPrice + quote.AskPrice) / 2
+			data.Timestamp = quote.Timestamp
+			data.Price = midPrice
+			if !hasBar {
+				data.Open, data.High, data.Low, data.Close = midPrice, midPrice, midPrice, midPrice
+				data.Source = "Alpaca Quotes"
+			}
+			if book, err := orderBookFromQuote(ticker, &quote); err == nil {
+				snapshot.Book = book
+			}
+		}
+
+		p.lastValidData[ticker] = data
+		snapshots[ticker] = snapshot
+	}
+
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no data available for %d tickers", len(tickers))
+	}
+	return snapshots, nil
+}
+
+// StreamTrades streams trade prints for the given tickers over Alpaca's
+// websocket feed, calling handler for each trade. The SDK reconnects on
+// transient errors, and the stream reconnects with new credentials when they
+// are rotated; StreamTrades blocks until ctx is cancelled or the stream
+// terminates with an unrecoverable error.
+func (p *AlpacaProvider) StreamTrades(ctx context.Context, tickers []string, handler func(Trade)) error {
+	if len(tickers) == 0 {
+		return fmt.Errorf("no tickers to stream trades for")
+	}
+
+	for {
+		rotated := p.rotation()
+		streamCtx, cancel := context.WithCancel(ctx)
+		apiKey, apiSecret := p.credentials()
+		client := stream.NewStocksClient(p.dataFeed,
+			stream.WithCredentials(apiKey, apiSecret),
+			stream.WithReconnectSettings(0, 5*time.Second),
+			stream.WithTrades(func(t stream.Trade) {
+				handler(Trade{
+					Ticker:     t.Symbol,
+					Timestamp:  t.Timestamp,
+					Price:      t.Price,
+					Size:       int64(t.Size),
+					Exchange:   t.Exchange,
+					ID:         t.ID,
+					Conditions: t.Conditions,
+					Tape:       t.Tape,
+					Source:     "Alpaca",
+				})
+			}, tickers...),
+		)
+
+		if err := client.Connect(streamCtx); err != nil {
+			cancel()
+			return fmt.Errorf("failed to connect to trade stream: %w", err)
+		}
+		utils.Info("Streaming trades for %d tickers from %s feed", len(tickers), p.dataFeed)
+
+		select {
+		case <-ctx.Done():
+			cancel()
+			return nil
+		case err := <-client.Terminated():
+			cancel()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("trade stream terminated: %w", err)
+		case <-rotated:
+			// Feeds allow one connection per account, so the old stream is
+			// closed before the new one connects
+			utils.Info("Reconnecting trade stream with rotated credentials")
+			cancel()
+			<-client.Terminated()
+		}
+	}
+}
+
+// ListSymbols fetches the active US equity assets as symbol reference data
+func (p *AlpacaProvider) ListSymbols(ctx context.Context) ([]Symbol, error) {
+	assets, err := p.tradingAPI().GetAssets(alpaca.GetAssetsRequest{
+		Status:     string(alpaca.AssetActive),
+		AssetClass: string(alpaca.USEquity),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list assets: %w", err)
+	}
+
+	symbols := make([]Symbol, 0, len(assets))
+	for _, asset := range assets {
+		symbols = append(symbols, Symbol{
+			Ticker:       asset.Symbol,
+			Name:         asset.Name,
+			Exchange:     asset.Exchange,
+			AssetClass:   string(asset.Class),
+			Tradable:     asset.Tradable,
+			Shortable:    asset.Shortable,
+			Marginable:   asset.Marginable,
+			Fractionable: asset.Fractionable,
+		})
+	}
+	return symbols, nil
 }
 
 // getLatestMinuteBar fetches the most recent 1-minute bar for a ticker
 func (p *AlpacaProvider) getLatestMinuteBar(ctx context.Context, ticker string) (*marketdata.Bar, error) {
+	if err := p.faults.Delay(ctx); err != nil {
+		return nil, err
+	}
+
 	// Get current time
 	now := time.Now().UTC()
 
@@ -408,7 +780,7 @@ func (p *AlpacaProvider) getLatestMinuteBar(ctx context.Context, ticker string)
 	}
 
 	// Get bars for the requested symbol
-	bars, err := p.marketDataClient.GetBars(ticker, barsRequest)
+	bars, err := p.dataAPI().GetBars(ticker, barsRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get minute bars: %w", err)
 	}
@@ -422,6 +794,10 @@ func (p *AlpacaProvider) getLatestMinuteBar(ctx context.Context, ticker string)
 
 // getLatestDailyBar fetches the most recent daily bar for a ticker
 func (p *AlpacaProvider) getLatestDailyBar(ctx context.Context, ticker string) (*marketdata.Bar, error) {
+	if err := p.faults.Delay(ctx); err != nil {
+		return nil, err
+	}
+
 	// Get current time
 	now := time.Now().UTC()
 
@@ -440,7 +816,7 @@ func (p *AlpacaProvider) getLatestDailyBar(ctx context.Context, ticker string) (
 	}
 
 	// Get bars for the requested symbol
-	bars, err := p.marketDataClient.GetBars(ticker, barsRequest)
+	bars, err := p.dataAPI().GetBars(ticker, barsRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get daily bars: %w", err)
 	}
@@ -484,28 +860,35 @@ func (p *AlpacaProvider) generateSampleData(ticker string) *MarketData {
 		Close:     basePrice,
 		Volume:    500000 + (now.Unix() % 1000000), // Some pseudo-random volume
 		Interval:  "1min",
-		Source:    "Alpaca (Simulated)",
+		Source:    SourceSimulated,
 		DataType:  "generated",
 	}
 }
 
 // convertToAlpacaTimeframe converts common interval notation to Alpaca timeframe format
 func convertToAlpacaTimeframe(interval string) (marketdata.TimeFrame, error) {
-	// Convert to lowercase for easier comparison
-	interval = strings.ToLower(interval)
+	// Normalize to the canonical interval name first
+	canonical, err := NormalizeInterval(interval)
+	if err != nil {
+		return marketdata.TimeFrame{}, err
+	}
 
-	switch interval {
-	case "1m", "1min", "1minute":
+	switch canonical {
+	case Interval1Min:
 		return marketdata.OneMin, nil
-	case "5m", "5min", "5minute":
+	case Interval5Min:
 		return marketdata.NewTimeFrame(5, marketdata.Min), nil
-	case "15m", "15min", "15minute":
+	case Interval15Min:
 		return marketdata.NewTimeFrame(15, marketdata.Min), nil
-	case "30m", "30min", "30minute":
+	case Interval30Min:
 		return marketdata.NewTimeFrame(30, marketdata.Min), nil
-	case "1h", "1hour", "60min":
+	case Interval60Min:
 		return marketdata.OneHour, nil
-	case "1d", "1day", "daily":
+	case Interval120Min:
+		return marketdata.NewTimeFrame(2, marketdata.Hour), nil
+	case Interval240Min:
+		return marketdata.NewTimeFrame(4, marketdata.Hour), nil
+	case Interval1Day:
 		return marketdata.OneDay, nil
 	default:
 		return marketdata.TimeFrame{}, fmt.Errorf("unsupported interval: %s", interval)
@@ -5,16 +5,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"sync"
 	"time"
 )
 
 // AlphaVantageProvider implements market data fetching from Alpha Vantage API
 type AlphaVantageProvider struct {
+	mu         sync.RWMutex // Guards apiKey, which rotation replaces
 	apiKey     string
 	baseURL    string
 	httpClient *http.Client
+	cache      *ResponseCache // Memoizes historical queries; nil disables
 }
 
 // MarketData represents OHLCV market data
@@ -46,13 +51,31 @@ func NewAlphaVantageProvider(apiKey string) (*AlphaVantageProvider, error) {
 	}, nil
 }
 
+// SetAPIKey replaces the API key used by subsequent requests
+func (p *AlphaVantageProvider) SetAPIKey(apiKey string) error {
+	if apiKey == "" {
+		return fmt.Errorf("Alpha Vantage API key is required")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.apiKey = apiKey
+	return nil
+}
+
+// key returns the current API key
+func (p *AlphaVantageProvider) key() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.apiKey
+}
+
 // GetLatestData fetches the latest market data for the specified ticker
 func (p *AlphaVantageProvider) GetLatestData(ctx context.Context, ticker string) (*MarketData, error) {
 	// Build URL for Global Quote endpoint
 	params := url.Values{}
 	params.Add("function", "GLOBAL_QUOTE")
 	params.Add("symbol", ticker)
-	params.Add("apikey", p.apiKey)
+	params.Add("apikey", p.key())
 
 	// Construct request URL
 	requestURL := fmt.Sprintf("%s?%s", p.baseURL, params.Encode())
@@ -144,6 +167,193 @@ func (p *AlphaVantageProvider) GetLatestData(ctx context.Context, ticker string)
 	return data, nil
 }
 
+// GetForexRate fetches the latest exchange rate for a currency pair
+func (p *AlphaVantageProvider) GetForexRate(ctx context.Context, pair string) (*MarketData, error) {
+	base, quote, ok := ParseForexPair(pair)
+	if !ok {
+		return nil, fmt.Errorf("invalid currency pair: %s", pair)
+	}
+
+	params := url.Values{}
+	params.Add("function", "CURRENCY_EXCHANGE_RATE")
+	params.Add("from_currency", base)
+	params.Add("to_currency", quote)
+
+	var result struct {
+		Rate struct {
+			ExchangeRate  string `json:"5. Exchange Rate"`
+			LastRefreshed string `json:"6. Last Refreshed"`
+			BidPrice      string `json:"8. Bid Price"`
+			AskPrice      string `json:"9. Ask Price"`
+		} `json:"Realtime Currency Exchange Rate"`
+	}
+	if err := p.query(ctx, params, &result); err != nil {
+		return nil, err
+	}
+
+	rate, err := parseFloat(result.Rate.ExchangeRate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exchange rate: %w", err)
+	}
+
+	// Alpha Vantage reports exchange rate times in UTC
+	timestamp, err := time.Parse("2006-01-02 15:04:05", result.Rate.LastRefreshed)
+	if err != nil {
+		timestamp = time.Now().UTC()
+	}
+
+	return &MarketData{
+		Ticker:    base + quote,
+		Timestamp: timestamp,
+		Price:     rate,
+		Open:      rate,
+		High:      rate,
+		Low:       rate,
+		Close:     rate,
+		Interval:  Interval1Min,
+		Source:    "Alpha Vantage",
+		DataType:  "live",
+	}, nil
+}
+
+// GetForexHistorical fetches historical bars for a currency pair. Forex bars
+// carry no volume. The 120min and 240min intervals are not offered.
+func (p *AlphaVantageProvider) GetForexHistorical(ctx context.Context, pair string, days int, interval string) ([]*MarketData, error) {
+	base, quote, ok := ParseForexPair(pair)
+	if !ok {
+		return nil, fmt.Errorf("invalid currency pair: %s", pair)
+	}
+
+	params, err := NormalizeHistoricalParams(base+quote, interval, days)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.cache.Historical(HistoricalCacheKey("alphavantage", params), func() ([]*MarketData, error) {
+		return p.fetchForexHistorical(ctx, base, quote, params)
+	})
+}
+
+// fetchForexHistorical requests historical forex bars from Alpha Vantage
+func (p *AlphaVantageProvider) fetchForexHistorical(ctx context.Context, base, quote string, params HistoricalParams) ([]*MarketData, error) {
+	query := url.Values{}
+	query.Add("from_symbol", base)
+	query.Add("to_symbol", quote)
+	query.Add("outputsize", "full")
+
+	seriesKey := "Time Series FX (Daily)"
+	switch params.Interval {
+	case Interval1Day:
+		query.Add("function", "FX_DAILY")
+	case Interval1Min, Interval5Min, Interval15Min, Interval30Min, Interval60Min:
+		query.Add("function", "FX_INTRADAY")
+		query.Add("interval", params.Interval)
+		seriesKey = fmt.Sprintf("Time Series FX (%s)", params.Interval)
+	default:
+		return nil, fmt.Errorf("interval %s is not available for forex", params.Interval)
+	}
+
+	var result map[string]json.RawMessage
+	if err := p.query(ctx, query, &result); err != nil {
+		return nil, err
+	}
+
+	var series map[string]map[string]string
+	if raw, ok := result[seriesKey]; !ok {
+		return nil, fmt.Errorf("no forex data returned for %s", params.Ticker)
+	} else if err := json.Unmarshal(raw, &series); err != nil {
+		return nil, fmt.Errorf("failed to decode forex series: %w", err)
+	}
+
+	start, _ := params.DateRange(time.Now())
+	data := make([]*MarketData, 0, len(series))
+	for stamp, bar := range series {
+		timestamp, err := time.Parse("2006-01-02 15:04:05", stamp)
+		if err != nil {
+			if timestamp, err = time.Parse("2006-01-02", stamp); err != nil {
+				continue
+			}
+		}
+		if timestamp.Before(start) {
+			continue
+		}
+
+		open, _ := parseFloat(bar["1. open"])
+		high, _ := parseFloat(bar["2. high"])
+		low, _ := parseFloat(bar["3. low"])
+		closePrice, _ := parseFloat(bar["4. close"])
+		data = append(data, &MarketData{
+			Ticker:    params.Ticker,
+			Timestamp: timestamp,
+			Price:     closePrice,
+			Open:      open,
+			High:      high,
+			Low:       low,
+			Close:     closePrice,
+			Interval:  params.Interval,
+			Source:    "Alpha Vantage",
+			DataType:  "historical",
+		})
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no historical data found for %s", params.Ticker)
+	}
+
+	sort.Slice(data, func(i, j int) bool { return data[i].Timestamp.Before(data[j].Timestamp) })
+	return data, nil
+}
+
+// SetResponseCache enables caching of historical queries
+func (p *AlphaVantageProvider) SetResponseCache(cache *ResponseCache) {
+	p.cache = cache
+}
+
+// query calls the Alpha Vantage API and decodes the response, surfacing the
+// error and rate limit messages it returns with a 200 status
+func (p *AlphaVantageProvider) query(ctx context.Context, params url.Values, out interface{}) error {
+	params.Set("apikey", p.key())
+	requestURL := fmt.Sprintf("%s?%s", p.baseURL, params.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var apiError struct {
+		ErrorMessage string `json:"Error Message"`
+		Note         string `json:"Note"`
+		Information  string `json:"Information"`
+	}
+	if json.Unmarshal(body, &apiError) == nil {
+		for _, message := range []string{apiError.ErrorMessage, apiError.Note, apiError.Information} {
+			if message != "" {
+				return fmt.Errorf("Alpha Vantage API error: %s", message)
+			}
+		}
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
 // Helper to parse float from string
 func parseFloat(s string) (float64, error) {
 	var f float64
@@ -0,0 +1,382 @@
+// pkg/market/barhistory.go
+package market
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// ErrNoHistory is returned for an as-of query when no bars of the series
+// had been recorded by then
+var ErrNoHistory = errors.New("no bars recorded as of that time")
+
+// barHistoryExt is the extension of series files
+const barHistoryExt = ".jsonl"
+
+// BarHistory records every version of the historical bars fetched from
+// providers, so a correction or backfill adds a revision rather than
+// replacing what was served before. As-of queries return bars as they were
+// known at a point in time, which lets a backtest be rerun on exactly the
+// inputs it first saw. Bars still forming are not recorded. Series are kept
+// in memory and, when a directory is set, in files that new revisions are
+// appended to so they survive restarts. A nil history records nothing.
+type BarHistory struct {
+	mu          sync.Mutex
+	dir         string // Empty keeps series in memory only
+	series      map[string]*barSeries
+	corrections int64
+}
+
+// barSeries is every revision of one ticker's bars at one interval
+type barSeries struct {
+	Ticker   string                  `json:"ticker"`
+	Interval string                  `json:"interval"`
+	Bars     map[int64][]barRevision `json:"-"` // By bar start in Unix seconds, oldest revision first
+}
+
+// barRevision is a bar's values from the time they were first seen
+type barRevision struct {
+	KnownAt time.Time   `json:"known_at"`
+	Bar     *MarketData `json:"bar"`
+}
+
+// BarHistoryStats reports how much history is kept and how often providers
+// have revised it
+type BarHistoryStats struct {
+	Series      int    `json:"series"`
+	Bars        int    `json:"bars"`
+	Revisions   int    `json:"revisions"`
+	Corrections int64  `json:"corrections"` // Revised bars recorded since startup
+	Dir         string `json:"dir,omitempty"`
+}
+
+// NewBarHistory creates a history that persists series in dir, or keeps
+// them in memory only if dir is empty
+func NewBarHistory(dir string) *BarHistory {
+	return &BarHistory{dir: dir, series: make(map[string]*barSeries)}
+}
+
+// BarHistoryFromEnv creates a history persisted in MARKET_HISTORY_DIR, kept
+// in memory only when it is unset
+func BarHistoryFromEnv() *BarHistory {
+	return NewBarHistory(os.Getenv("MARKET_HISTORY_DIR"))
+}
+
+// Record stores bars fetched at knownAt. Bars seen before with the same
+// values are left alone, as are bars still forming at knownAt; new bars and
+// changed values become revisions. It returns the number of bars whose
+// values changed.
+func (h *BarHistory) Record(ticker, interval string, bars []*MarketData, knownAt time.Time) int {
+	if h == nil || len(bars) == 0 {
+		return 0
+	}
+	length, _ := IntervalDuration(interval)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	series := h.get(ticker, interval)
+	var added []barRevision
+	corrected := 0
+	for _, bar := range bars {
+		if bar == nil || bar.Timestamp.Add(length).After(knownAt) {
+			continue
+		}
+		start := bar.Timestamp.Unix()
+		revisions := series.Bars[start]
+		if n := len(revisions); n > 0 {
+			if sameBar(revisions[n-1].Bar, bar) {
+				continue
+			}
+			corrected++
+		}
+		copied := *bar
+		revision := barRevision{KnownAt: knownAt, Bar: &copied}
+		series.Bars[start] = append(revisions, revision)
+		added = append(added, revision)
+	}
+	if len(added) == 0 {
+		return 0
+	}
+
+	if corrected > 0 {
+		h.corrections += int64(corrected)
+		utils.Info("Recorded %d revised %s bars for %s", corrected, interval, ticker)
+	}
+	if h.dir != "" {
+		if err := h.appendRevisions(series, added); err != nil {
+			utils.Warn("Failed to persist bar history for %s (%s): %v", ticker, interval, err)
+		}
+	}
+	return corrected
+}
+
+// AsOf returns the bars of the days before asOf as they were known at asOf,
+// oldest first. Bars first recorded after asOf are left out, and revised
+// bars have the values they had then.
+func (h *BarHistory) AsOf(ticker, interval string, days int, asOf time.Time) ([]*MarketData, error) {
+	if h == nil {
+		return nil, fmt.Errorf("bar history is not enabled")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	series := h.get(ticker, interval)
+	from := asOf.AddDate(0, 0, -days).Unix()
+	var bars []*MarketData
+	for start, revisions := range series.Bars {
+		if start < from || start > asOf.Unix() {
+			continue
+		}
+		// Revisions are in the order they were seen, so the last one
+		// known by then wins
+		var known *MarketData
+		for _, revision := range revisions {
+			if revision.KnownAt.After(asOf) {
+				break
+			}
+			known = revision.Bar
+		}
+		if known != nil {
+			copied := *known
+			bars = append(bars, &copied)
+		}
+	}
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("%w: %s %s at %s", ErrNoHistory, ticker, interval, asOf.Format(time.RFC3339))
+	}
+
+	sort.Slice(bars, func(i, j int) bool { return bars[i].Timestamp.Before(bars[j].Timestamp) })
+	return bars, nil
+}
+
+// Trim drops the bars of each series that started longer ago than maxAge
+// returns for its ticker and interval, including series only on disk, and
+// returns the number of bars dropped. A zero max age keeps a series whole.
+func (h *BarHistory) Trim(maxAge func(ticker, interval string) time.Duration, now time.Time) (int, error) {
+	if h == nil {
+		return 0, nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.dir != "" {
+		paths, err := filepath.Glob(filepath.Join(h.dir, "*"+barHistoryExt))
+		if err != nil {
+			return 0, err
+		}
+		for _, path := range paths {
+			header, err := readSeriesHeader(path)
+			if err != nil {
+				utils.Warn("Skipping unreadable bar history %s: %v", path, err)
+				continue
+			}
+			h.get(header.Ticker, header.Interval)
+		}
+	}
+
+	dropped := 0
+	for _, series := range h.series {
+		age := maxAge(series.Ticker, series.Interval)
+		if age <= 0 {
+			continue
+		}
+		cutoff := now.Add(-age).Unix()
+		trimmed := 0
+		for start := range series.Bars {
+			if start < cutoff {
+				delete(series.Bars, start)
+				trimmed++
+			}
+		}
+		if trimmed == 0 {
+			continue
+		}
+		dropped += trimmed
+		if h.dir != "" {
+			if err := h.rewrite(series); err != nil {
+				return dropped, fmt.Errorf("failed to trim bar history for %s (%s): %w", series.Ticker, series.Interval, err)
+			}
+		}
+	}
+	return dropped, nil
+}
+
+// Stats returns the size of the history
+func (h *BarHistory) Stats() BarHistoryStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := BarHistoryStats{Series: len(h.series), Corrections: h.corrections, Dir: h.dir}
+	for _, series := range h.series {
+		stats.Bars += len(series.Bars)
+		for _, revisions := range series.Bars {
+			stats.Revisions += len(revisions)
+		}
+	}
+	return stats
+}
+
+// get returns a series from memory or disk, or a new empty one. Caller
+// holds the lock.
+func (h *BarHistory) get(ticker, interval string) *barSeries {
+	key := ticker + ":" + interval
+	if series, ok := h.series[key]; ok {
+		return series
+	}
+
+	series := &barSeries{Ticker: ticker, Interval: interval, Bars: make(map[int64][]barRevision)}
+	if h.dir != "" {
+		if err := h.load(series); err != nil && !os.IsNotExist(err) {
+			utils.Warn("Ignoring unreadable bar history for %s (%s): %v", ticker, interval, err)
+			series.Bars = make(map[int64][]barRevision)
+		}
+	}
+	h.series[key] = series
+	return series
+}
+
+// load replays a series file into series: a header line naming the series,
+// then one revision per line in the order they were seen. Caller holds the
+// lock.
+func (h *BarHistory) load(series *barSeries) error {
+	f, err := os.Open(h.path(series.Ticker, series.Interval))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(f))
+	var header barSeries
+	if err := decoder.Decode(&header); err != nil {
+		return fmt.Errorf("failed to parse bar history: %w", err)
+	}
+	if header.Ticker != series.Ticker || header.Interval != series.Interval {
+		return fmt.Errorf("bar history is for %s (%s)", header.Ticker, header.Interval)
+	}
+	for decoder.More() {
+		var revision barRevision
+		if err := decoder.Decode(&revision); err != nil {
+			// A revision cut short by a crash ends the file; rewrite it
+			// so later revisions are not appended after the damage
+			utils.Warn("Truncated bar history for %s (%s): %v", series.Ticker, series.Interval, err)
+			return h.rewrite(series)
+		}
+		if revision.Bar == nil {
+			continue
+		}
+		start := revision.Bar.Timestamp.Unix()
+		series.Bars[start] = append(series.Bars[start], revision)
+	}
+	return nil
+}
+
+// appendRevisions adds revisions to the end of a series file, creating it
+// with its header if needed. Caller holds the lock.
+func (h *BarHistory) appendRevisions(series *barSeries, revisions []barRevision) error {
+	if err := os.MkdirAll(h.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	path := h.path(series.Ticker, series.Interval)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open bar history: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	if info.Size() == 0 {
+		encoder.Encode(barSeries{Ticker: series.Ticker, Interval: series.Interval})
+	}
+	for _, revision := range revisions {
+		if err := encoder.Encode(revision); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if _, err := f.WriteString(buf.String()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write bar history: %w", err)
+	}
+	return f.Close()
+}
+
+// rewrite replaces a series file with the series' current revisions,
+// atomically. Caller holds the lock.
+func (h *BarHistory) rewrite(series *barSeries) error {
+	starts := make([]int64, 0, len(series.Bars))
+	for start := range series.Bars {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	encoder.Encode(barSeries{Ticker: series.Ticker, Interval: series.Interval})
+	for _, start := range starts {
+		for _, revision := range series.Bars[start] {
+			if err := encoder.Encode(revision); err != nil {
+				return err
+			}
+		}
+	}
+
+	path := h.path(series.Ticker, series.Interval)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(buf.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write bar history: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// readSeriesHeader reads the series a file holds from its first line
+func readSeriesHeader(path string) (barSeries, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return barSeries{}, err
+	}
+	defer f.Close()
+
+	var header barSeries
+	if err := json.NewDecoder(bufio.NewReader(f)).Decode(&header); err != nil {
+		return barSeries{}, fmt.Errorf("failed to parse bar history: %w", err)
+	}
+	if header.Ticker == "" || header.Interval == "" {
+		return barSeries{}, fmt.Errorf("bar history has no series header")
+	}
+	return header, nil
+}
+
+// path returns the file holding a series; names are hashed so any ticker is
+// a safe name
+func (h *BarHistory) path(ticker, interval string) string {
+	sum := sha1.Sum([]byte(ticker + ":" + interval))
+	return filepath.Join(h.dir, hex.EncodeToString(sum[:])+barHistoryExt)
+}
+
+// sameBar reports whether two versions of a bar have the same prices and
+// volume
+func sameBar(a, b *MarketData) bool {
+	return a.Open == b.Open && a.High == b.High && a.Low == b.Low &&
+		a.Close == b.Close && a.Volume == b.Volume
+}
@@ -0,0 +1,251 @@
+// pkg/market/cache.go
+package market
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// defaultResponseCacheTTL is how long provider responses are reused
+const defaultResponseCacheTTL = 15 * time.Minute
+
+// ResponseCache memoizes provider responses for identical historical queries
+// within a TTL. Entries are kept in memory and, when a directory is set, as
+// JSON files so they survive restarts. A nil cache disables caching.
+type ResponseCache struct {
+	mu      sync.Mutex
+	dir     string // Empty keeps entries in memory only
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+	hits    int64
+	misses  int64
+}
+
+// cacheEntry is a cached response as stored on disk
+type cacheEntry struct {
+	Key      string        `json:"key"`
+	StoredAt time.Time     `json:"stored_at"`
+	Data     []*MarketData `json:"data"`
+}
+
+// CacheStats reports cache effectiveness
+type CacheStats struct {
+	Entries int     `json:"entries"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+	TTL     string  `json:"ttl"`
+	Dir     string  `json:"dir,omitempty"`
+}
+
+// NewResponseCache creates a cache that reuses responses for ttl and persists
+// them in dir, removing expired files left from earlier runs
+func NewResponseCache(dir string, ttl time.Duration) *ResponseCache {
+	c := &ResponseCache{
+		dir:     dir,
+		ttl:     ttl,
+		entries: make(map[string]*cacheEntry),
+	}
+	c.prune()
+	return c
+}
+
+// ResponseCacheFromEnv creates a cache from MARKET_CACHE_TTL (default 15m,
+// 0 disables caching) and MARKET_CACHE_DIR (default "market-cache", "none"
+// keeps entries in memory only). It returns nil when caching is disabled.
+func ResponseCacheFromEnv() *ResponseCache {
+	ttl := defaultResponseCacheTTL
+	if value := os.Getenv("MARKET_CACHE_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			utils.Warn("Invalid MARKET_CACHE_TTL %q, using %v", value, ttl)
+		} else {
+			ttl = parsed
+		}
+	}
+	if ttl == 0 {
+		return nil
+	}
+
+	dir := os.Getenv("MARKET_CACHE_DIR")
+	switch dir {
+	case "":
+		dir = "market-cache"
+	case "none":
+		dir = ""
+	}
+	return NewResponseCache(dir, ttl)
+}
+
+// HistoricalCacheKey identifies a historical query to a provider. Parameters
+// are normalized, so equivalent queries share an entry.
+func HistoricalCacheKey(provider string, params HistoricalParams) string {
+	return fmt.Sprintf("%s:historical:%s:%s:%d", provider, params.Ticker, params.Interval, params.Days)
+}
+
+// Historical returns the cached bars for key, or calls fetch and caches its
+// result. Errors are not cached.
+func (c *ResponseCache) Historical(key string, fetch func() ([]*MarketData, error)) ([]*MarketData, error) {
+	if c == nil {
+		return fetch()
+	}
+
+	if data, ok := c.get(key); ok {
+		utils.Debug("Provider cache hit for %s", key)
+		return data, nil
+	}
+
+	data, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.put(key, data)
+	return data, nil
+}
+
+// Stats returns hit and miss counts
+func (c *ResponseCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := CacheStats{
+		Entries: len(c.entries),
+		Hits:    c.hits,
+		Misses:  c.misses,
+		TTL:     c.ttl.String(),
+		Dir:     c.dir,
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+	return stats
+}
+
+// get returns an unexpired entry from memory or disk
+func (c *ResponseCache) get(key string) ([]*MarketData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok && c.dir != "" {
+		if loaded, err := c.load(key); err == nil {
+			entry, ok = loaded, true
+			c.entries[key] = entry
+		} else if !os.IsNotExist(err) {
+			utils.Warn("Ignoring unreadable provider cache entry for %s: %v", key, err)
+		}
+	}
+
+	if !ok || time.Since(entry.StoredAt) >= c.ttl {
+		if ok {
+			c.remove(key)
+		}
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	return entry.Data, true
+}
+
+// put stores an entry in memory and on disk
+func (c *ResponseCache) put(key string, data []*MarketData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop expired entries from memory so unrepeated queries do not accumulate
+	for k, e := range c.entries {
+		if time.Since(e.StoredAt) >= c.ttl {
+			delete(c.entries, k)
+		}
+	}
+
+	entry := &cacheEntry{Key: key, StoredAt: time.Now(), Data: data}
+	c.entries[key] = entry
+	if c.dir == "" {
+		return
+	}
+	if err := c.save(entry); err != nil {
+		utils.Warn("Failed to persist provider cache entry for %s: %v", key, err)
+	}
+}
+
+// remove drops an entry from memory and disk. Caller holds the lock.
+func (c *ResponseCache) remove(key string) {
+	delete(c.entries, key)
+	if c.dir != "" {
+		os.Remove(c.path(key))
+	}
+}
+
+// load reads an entry from disk. Caller holds the lock.
+func (c *ResponseCache) load(key string) (*cacheEntry, error) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse cache entry: %w", err)
+	}
+	if entry.Key != key {
+		return nil, fmt.Errorf("cache entry is for %s", entry.Key)
+	}
+	return &entry, nil
+}
+
+// save writes an entry atomically. Caller holds the lock.
+func (c *ResponseCache) save(entry *cacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	path := c.path(entry.Key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// prune removes expired entries left on disk
+func (c *ResponseCache) prune() {
+	if c.dir == "" {
+		return
+	}
+
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		info, err := file.Info()
+		if err == nil && time.Since(info.ModTime()) >= c.ttl {
+			os.Remove(filepath.Join(c.dir, file.Name()))
+		}
+	}
+}
+
+// path returns the file holding an entry; keys are hashed so any ticker is a safe name
+func (c *ResponseCache) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
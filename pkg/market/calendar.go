@@ -0,0 +1,118 @@
+// pkg/market/calendar.go
+package market
+
+import "time"
+
+// EarlyCloseMinute is when the session ends on half days: 13:00
+const EarlyCloseMinute = 13 * 60
+
+// SessionHours returns the open and close of the regular session on the
+// date of day in exchange time, in minutes after midnight. ok is false on
+// weekends and exchange holidays. Sessions close early the day before
+// Independence Day, the day after Thanksgiving and on Christmas Eve.
+func SessionHours(day time.Time) (open, close int, ok bool) {
+	et := day.In(ExchangeLocation())
+	y, m, d := et.Date()
+	date := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if !isTradingDay(date) {
+		return 0, 0, false
+	}
+	if isEarlyClose(date) {
+		return SessionOpenMinute, EarlyCloseMinute, true
+	}
+	return SessionOpenMinute, SessionCloseMinute, true
+}
+
+// isTradingDay reports whether date, at midnight UTC, is a weekday other
+// than an exchange holiday
+func isTradingDay(date time.Time) bool {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	for _, holiday := range exchangeHolidays(date.Year()) {
+		if holiday.Equal(date) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEarlyClose reports whether the session on a trading date closes at 13:00
+func isEarlyClose(date time.Time) bool {
+	y := date.Year()
+	thanksgiving := nthWeekday(y, time.November, time.Thursday, 4)
+	switch {
+	case date.Equal(thanksgiving.AddDate(0, 0, 1)):
+		return true
+	case date.Month() == time.July && date.Day() == 3:
+		return true
+	case date.Month() == time.December && date.Day() == 24:
+		return true
+	}
+	return false
+}
+
+// exchangeHolidays returns the full-day closures of a year at midnight UTC.
+// Holidays on a Saturday are observed the Friday before and on a Sunday the
+// Monday after, except New Year's Day, which is not moved back into the
+// previous year.
+func exchangeHolidays(y int) []time.Time {
+	holidays := []time.Time{
+		nthWeekday(y, time.January, time.Monday, 3),  // Martin Luther King Jr. Day
+		nthWeekday(y, time.February, time.Monday, 3), // Washington's Birthday
+		easter(y).AddDate(0, 0, -2),                  // Good Friday
+		lastWeekday(y, time.May, time.Monday),        // Memorial Day
+		observed(time.Date(y, time.July, 4, 0, 0, 0, 0, time.UTC)),
+		nthWeekday(y, time.September, time.Monday, 1),  // Labor Day
+		nthWeekday(y, time.November, time.Thursday, 4), // Thanksgiving
+		observed(time.Date(y, time.December, 25, 0, 0, 0, 0, time.UTC)),
+	}
+	if newYear := time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC); newYear.Weekday() != time.Saturday {
+		holidays = append(holidays, observed(newYear))
+	}
+	if y >= 2022 {
+		holidays = append(holidays, observed(time.Date(y, time.June, 19, 0, 0, 0, 0, time.UTC)))
+	}
+	return holidays
+}
+
+// observed moves a weekend holiday to the nearest weekday
+func observed(date time.Time) time.Time {
+	switch date.Weekday() {
+	case time.Saturday:
+		return date.AddDate(0, 0, -1)
+	case time.Sunday:
+		return date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// nthWeekday returns the nth given weekday of a month
+func nthWeekday(y int, m time.Month, weekday time.Weekday, n int) time.Time {
+	first := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+7*(n-1))
+}
+
+// lastWeekday returns the last given weekday of a month
+func lastWeekday(y int, m time.Month, weekday time.Weekday) time.Time {
+	last := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC)
+	offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+	return last.AddDate(0, 0, -offset)
+}
+
+// easter returns Easter Sunday of a year in the Gregorian calendar
+func easter(y int) time.Time {
+	a := y % 19
+	b, c := y/100, y%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(y, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
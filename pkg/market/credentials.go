@@ -0,0 +1,181 @@
+// pkg/market/credentials.go
+package market
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// Credentials is an Alpaca API key pair
+type Credentials struct {
+	APIKey    string
+	APISecret string
+}
+
+// CredentialLoader reads the current credentials from wherever they are kept
+type CredentialLoader func(ctx context.Context) (Credentials, error)
+
+// CredentialStatus reports the watcher's reload history
+type CredentialStatus struct {
+	Reloads    int       `json:"reloads"`
+	LastReload time.Time `json:"last_reload,omitempty"`
+	LastCheck  time.Time `json:"last_check,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// CredentialWatcher swaps rotated credentials into an Alpaca provider
+// without a restart. New credentials are verified before they replace the
+// old ones, so a bad rotation leaves the provider working.
+type CredentialWatcher struct {
+	provider *AlpacaProvider
+	load     CredentialLoader
+
+	mu      sync.Mutex // Serializes reloads
+	current string     // Fingerprint of the credentials in use
+	status  CredentialStatus
+}
+
+// NewCredentialWatcher creates a watcher for a provider's credentials
+func NewCredentialWatcher(provider *AlpacaProvider, load CredentialLoader) *CredentialWatcher {
+	apiKey, apiSecret := provider.credentials()
+	return &CredentialWatcher{
+		provider: provider,
+		load:     load,
+		current:  credentialFingerprint(Credentials{APIKey: apiKey, APISecret: apiSecret}),
+	}
+}
+
+// Reload reads the credentials and, if they changed, verifies them and
+// rebuilds the provider's clients. It reports whether they were replaced.
+func (w *CredentialWatcher) Reload(ctx context.Context) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.status.LastCheck = time.Now()
+	changed, err := w.reload(ctx)
+	if err != nil {
+		w.status.LastError = err.Error()
+		return false, err
+	}
+	w.status.LastError = ""
+	if changed {
+		w.status.Reloads++
+		w.status.LastReload = w.status.LastCheck
+	}
+	return changed, nil
+}
+
+// reload does the work of Reload. Caller holds the lock.
+func (w *CredentialWatcher) reload(ctx context.Context) (bool, error) {
+	creds, err := w.load(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to load credentials: %w", err)
+	}
+	fingerprint := credentialFingerprint(creds)
+	if fingerprint == w.current {
+		return false, nil
+	}
+
+	if err := w.provider.VerifyCredentials(creds.APIKey, creds.APISecret); err != nil {
+		return false, err
+	}
+	if err := w.provider.SetCredentials(creds.APIKey, creds.APISecret); err != nil {
+		return false, err
+	}
+	w.current = fingerprint
+	utils.Info("Reloaded Alpaca credentials")
+	return true, nil
+}
+
+// Status returns the reload history
+func (w *CredentialWatcher) Status() CredentialStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+// WatchFiles reloads the credentials whenever the given files, or files in
+// the given directories, change. Files are polled every interval rather than
+// watched through inotify, since mounted Kubernetes secrets are updated by
+// swapping a symlink that inotify watches on the files miss.
+func (w *CredentialWatcher) WatchFiles(ctx context.Context, paths []string, interval time.Duration) {
+	if len(paths) == 0 {
+		return
+	}
+	last := fileFingerprint(paths)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current := fileFingerprint(paths)
+			if current == last {
+				continue
+			}
+			last = current
+			utils.Info("Credential files changed, reloading")
+			if _, err := w.Reload(ctx); err != nil {
+				utils.Error("Keeping current Alpaca credentials: %v", err)
+			}
+		}
+	}()
+}
+
+// credentialFingerprint hashes credentials so changes are detected without
+// keeping a second copy of them
+func credentialFingerprint(c Credentials) string {
+	sum := sha256.Sum256([]byte(c.APIKey + "\x00" + c.APISecret))
+	return hex.EncodeToString(sum[:])
+}
+
+// fileFingerprint summarizes the size and modification time of files,
+// following symlinks and expanding directories one level
+func fileFingerprint(paths []string) string {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			// Kubernetes keeps the real files in hidden ..timestamp directories
+			if e.Name()[0] != '.' {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		fmt.Fprintf(h, "%s:%d:%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
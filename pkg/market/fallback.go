@@ -0,0 +1,71 @@
+// pkg/market/fallback.go
+package market
+
+import (
+	"os"
+	"strings"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// SourceSimulated is the source label attached to all synthetic data
+const SourceSimulated = "simulated"
+
+// FallbackPolicy controls what a provider does when real data is unavailable
+type FallbackPolicy string
+
+const (
+	// FallbackOff returns an error instead of falling back to any substitute data
+	FallbackOff FallbackPolicy = "off"
+	// FallbackCachedOnly allows serving the last valid data, but never synthetic data
+	FallbackCachedOnly FallbackPolicy = "cached-only"
+	// FallbackLabeledSample allows cached data and, as a last resort, synthetic
+	// data labeled with SourceSimulated
+	FallbackLabeledSample FallbackPolicy = "labeled-sample"
+)
+
+// DefaultFallbackPolicy is used when no policy is configured
+const DefaultFallbackPolicy = FallbackCachedOnly
+
+// ParseFallbackPolicy converts a configuration value to a FallbackPolicy
+func ParseFallbackPolicy(value string) (FallbackPolicy, bool) {
+	switch FallbackPolicy(strings.ToLower(strings.TrimSpace(value))) {
+	case FallbackOff:
+		return FallbackOff, true
+	case FallbackCachedOnly:
+		return FallbackCachedOnly, true
+	case FallbackLabeledSample:
+		return FallbackLabeledSample, true
+	default:
+		return DefaultFallbackPolicy, false
+	}
+}
+
+// FallbackPolicyFromEnv reads the FALLBACK_DATA_POLICY environment variable
+func FallbackPolicyFromEnv() FallbackPolicy {
+	value := os.Getenv("FALLBACK_DATA_POLICY")
+	if value == "" {
+		return DefaultFallbackPolicy
+	}
+
+	policy, ok := ParseFallbackPolicy(value)
+	if !ok {
+		utils.Warn("Unknown FALLBACK_DATA_POLICY value '%s', using default (%s)", value, DefaultFallbackPolicy)
+	}
+	return policy
+}
+
+// AllowsCached reports whether the policy permits serving previously cached data
+func (p FallbackPolicy) AllowsCached() bool {
+	return p == FallbackCachedOnly || p == FallbackLabeledSample
+}
+
+// AllowsSample reports whether the policy permits generating synthetic data
+func (p FallbackPolicy) AllowsSample() bool {
+	return p == FallbackLabeledSample
+}
+
+// IsSimulated reports whether the data point was synthetically generated
+func (d *MarketData) IsSimulated() bool {
+	return d != nil && d.Source == SourceSimulated
+}
@@ -0,0 +1,82 @@
+// pkg/market/forex.go
+package market
+
+import (
+	"strings"
+	"time"
+)
+
+// Asset classes
+const (
+	AssetClassEquity = "us_equity"
+	AssetClassForex  = "forex"
+)
+
+// ForexRolloverMinute is the New York time the 24x5 forex week opens on Sunday
+// and closes on Friday
+const ForexRolloverMinute = 17 * 60 // 17:00
+
+// forexCurrencies lists the ISO codes accepted in currency pairs
+var forexCurrencies = map[string]bool{
+	"USD": true, "EUR": true, "JPY": true, "GBP": true, "AUD": true, "CAD": true,
+	"CHF": true, "NZD": true, "SEK": true, "NOK": true, "DKK": true, "HKD": true,
+	"SGD": true, "MXN": true, "ZAR": true, "CNH": true, "CNY": true, "TRY": true,
+	"PLN": true, "CZK": true, "HUF": true, "ILS": true, "INR": true, "KRW": true,
+	"BRL": true,
+}
+
+// ParseForexPair splits a currency pair written as EURUSD, EUR/USD, EUR_USD or
+// EUR-USD into its base and quote currencies
+func ParseForexPair(pair string) (base, quote string, ok bool) {
+	symbol := strings.ToUpper(strings.TrimSpace(pair))
+	symbol = strings.NewReplacer("/", "", "_", "", "-", "").Replace(symbol)
+	if len(symbol) != 6 {
+		return "", "", false
+	}
+
+	base, quote = symbol[:3], symbol[3:]
+	if base == quote || !forexCurrencies[base] || !forexCurrencies[quote] {
+		return "", "", false
+	}
+	return base, quote, true
+}
+
+// IsForexPair reports whether a ticker is a currency pair
+func IsForexPair(ticker string) bool {
+	_, _, ok := ParseForexPair(ticker)
+	return ok
+}
+
+// AssetClassOf returns the asset class of a ticker
+func AssetClassOf(ticker string) string {
+	if IsForexPair(ticker) {
+		return AssetClassForex
+	}
+	return AssetClassEquity
+}
+
+// InForexSession reports whether the forex market is open at t. Holidays are
+// not considered.
+func InForexSession(t time.Time) bool {
+	et := t.In(ExchangeLocation())
+	minute := et.Hour()*60 + et.Minute()
+
+	switch et.Weekday() {
+	case time.Saturday:
+		return false
+	case time.Sunday:
+		return minute >= ForexRolloverMinute
+	case time.Friday:
+		return minute < ForexRolloverMinute
+	default:
+		return true
+	}
+}
+
+// InSession reports whether the market for a ticker's asset class is open at t
+func InSession(ticker string, t time.Time) bool {
+	if IsForexPair(ticker) {
+		return InForexSession(t)
+	}
+	return InRegularSession(t)
+}
@@ -0,0 +1,126 @@
+// pkg/market/fundamentals.go
+package market
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Fundamentals is a snapshot of company fundamentals. Fields the provider does
+// not report are nil.
+type Fundamentals struct {
+	Ticker            string    `json:"ticker"`
+	Name              string    `json:"name"`
+	Exchange          string    `json:"exchange"`
+	Sector            string    `json:"sector"`
+	Industry          string    `json:"industry"`
+	MarketCap         *float64  `json:"market_cap"`
+	PERatio           *float64  `json:"pe_ratio"`
+	ForwardPE         *float64  `json:"forward_pe"`
+	PEGRatio          *float64  `json:"peg_ratio"`
+	EPS               *float64  `json:"eps"`
+	DividendYield     *float64  `json:"dividend_yield"`
+	Beta              *float64  `json:"beta"`
+	ProfitMargin      *float64  `json:"profit_margin"`
+	RevenueTTM        *float64  `json:"revenue_ttm"`
+	BookValue         *float64  `json:"book_value"`
+	Week52High        *float64  `json:"week52_high"`
+	Week52Low         *float64  `json:"week52_low"`
+	SharesOutstanding *float64  `json:"shares_outstanding"`
+	LatestQuarter     string    `json:"latest_quarter,omitempty"`
+	Source            string    `json:"source"`
+	FetchedAt         time.Time `json:"fetched_at"`
+}
+
+// FundamentalFields lists the numeric fundamentals that can be filtered on
+var FundamentalFields = []string{
+	"market_cap", "pe_ratio", "forward_pe", "peg_ratio", "eps", "dividend_yield", "beta",
+	"profit_margin", "revenue_ttm", "book_value", "week52_high", "week52_low", "shares_outstanding",
+}
+
+// Value returns a numeric field by its JSON name
+func (f *Fundamentals) Value(field string) (float64, bool) {
+	var value *float64
+	switch field {
+	case "market_cap":
+		value = f.MarketCap
+	case "pe_ratio":
+		value = f.PERatio
+	case "forward_pe":
+		value = f.ForwardPE
+	case "peg_ratio":
+		value = f.PEGRatio
+	case "eps":
+		value = f.EPS
+	case "dividend_yield":
+		value = f.DividendYield
+	case "beta":
+		value = f.Beta
+	case "profit_margin":
+		value = f.ProfitMargin
+	case "revenue_ttm":
+		value = f.RevenueTTM
+	case "book_value":
+		value = f.BookValue
+	case "week52_high":
+		value = f.Week52High
+	case "week52_low":
+		value = f.Week52Low
+	case "shares_outstanding":
+		value = f.SharesOutstanding
+	}
+	if value == nil {
+		return 0, false
+	}
+	return *value, true
+}
+
+// GetFundamentals fetches the company overview for a ticker
+func (p *AlphaVantageProvider) GetFundamentals(ctx context.Context, ticker string) (*Fundamentals, error) {
+	params := url.Values{}
+	params.Add("function", "OVERVIEW")
+	params.Add("symbol", ticker)
+
+	var overview map[string]string
+	if err := p.query(ctx, params, &overview); err != nil {
+		return nil, err
+	}
+	if overview["Symbol"] == "" {
+		return nil, fmt.Errorf("no fundamentals available for %s", ticker)
+	}
+
+	number := func(key string) *float64 {
+		value, err := strconv.ParseFloat(overview[key], 64)
+		if err != nil {
+			return nil // "None", "-" and empty values
+		}
+		return &value
+	}
+
+	return &Fundamentals{
+		Ticker:            overview["Symbol"],
+		Name:              overview["Name"],
+		Exchange:          overview["Exchange"],
+		Sector:            overview["Sector"],
+		Industry:          overview["Industry"],
+		MarketCap:         number("MarketCapitalization"),
+		PERatio:           number("PERatio"),
+		ForwardPE:         number("ForwardPE"),
+		PEGRatio:          number("PEGRatio"),
+		EPS:               number("EPS"),
+		DividendYield:     number("DividendYield"),
+		Beta:              number("Beta"),
+		ProfitMargin:      number("ProfitMargin"),
+		RevenueTTM:        number("RevenueTTM"),
+		BookValue:         number("BookValue"),
+		Week52High:        number("52WeekHigh"),
+		Week52Low:         number("52WeekLow"),
+		SharesOutstanding: number("SharesOutstanding"),
+		LatestQuarter:     overview["LatestQuarter"],
+		Source:            "Alpha Vantage",
+		FetchedAt:         time.Now(),
+	}, nil
+}
@@ -0,0 +1,57 @@
+// pkg/market/orderbook.go
+package market
+
+import "time"
+
+// BookLevel is the resting size at one price on one side of the book
+type BookLevel struct {
+	Price    float64 `json:"price"`
+	Size     float64 `json:"size"`
+	Exchange string  `json:"exchange,omitempty"`
+}
+
+// OrderBook is a bid/ask depth snapshot with derived spread and liquidity figures.
+// Feeds that only publish top of book produce a single level per side.
+type OrderBook struct {
+	Ticker    string      `json:"ticker"`
+	Timestamp time.Time   `json:"timestamp"`
+	Bids      []BookLevel `json:"bids"` // Best bid first
+	Asks      []BookLevel `json:"asks"` // Best ask first
+	Levels    int         `json:"levels"`
+	MidPrice  float64     `json:"mid_price"`
+	Spread    float64     `json:"spread"`
+	SpreadBps float64     `json:"spread_bps"`
+	BidSize   float64     `json:"bid_size"`  // Total size across bid levels
+	AskSize   float64     `json:"ask_size"`  // Total size across ask levels
+	Imbalance float64     `json:"imbalance"` // (bid - ask) / (bid + ask) size, from -1 to 1
+	Source    string      `json:"source"`
+}
+
+// Summarize fills in the derived fields from the bid and ask levels
+func (b *OrderBook) Summarize() {
+	b.Levels = len(b.Bids)
+	if len(b.Asks) > b.Levels {
+		b.Levels = len(b.Asks)
+	}
+
+	b.BidSize, b.AskSize = 0, 0
+	for _, level := range b.Bids {
+		b.BidSize += level.Size
+	}
+	for _, level := range b.Asks {
+		b.AskSize += level.Size
+	}
+	if total := b.BidSize + b.AskSize; total > 0 {
+		b.Imbalance = (b.BidSize - b.AskSize) / total
+	}
+
+	if len(b.Bids) == 0 || len(b.Asks) == 0 {
+		return
+	}
+	bid, ask := b.Bids[0].Price, b.Asks[0].Price
+	b.MidPrice = (bid + ask) / 2
+	b.Spread = ask - bid
+	if b.MidPrice > 0 {
+		b.SpreadBps = b.Spread / b.MidPrice * 10000
+	}
+}
@@ -0,0 +1,142 @@
+// pkg/market/params.go
+package market
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Canonical interval names used in cache keys, NATS subjects and provider calls
+const (
+	Interval1Min   = "1min"
+	Interval5Min   = "5min"
+	Interval15Min  = "15min"
+	Interval30Min  = "30min"
+	Interval60Min  = "60min"
+	Interval120Min = "120min"
+	Interval240Min = "240min"
+	Interval1Day   = "1day"
+)
+
+// MaxHistoricalDays is the largest lookback accepted for historical requests
+const MaxHistoricalDays = 365
+
+// maxIntervalDays bounds the lookback of intraday intervals, so one request
+// cannot pull hundreds of thousands of bars from a provider
+var maxIntervalDays = map[string]int{
+	Interval1Min:  30,
+	Interval5Min:  90,
+	Interval15Min: 180,
+	Interval30Min: 180,
+}
+
+// intervalAliases maps accepted spellings (lowercased) to canonical interval names.
+// Note the UI uses uppercase "M" for minutes ("15M"), which lowercases to "15m".
+var intervalAliases = map[string]string{
+	"1m": Interval1Min, "1min": Interval1Min, "1minute": Interval1Min,
+	"5m": Interval5Min, "5min": Interval5Min, "5minute": Interval5Min,
+	"15m": Interval15Min, "15min": Interval15Min, "15minute": Interval15Min,
+	"30m": Interval30Min, "30min": Interval30Min, "30minute": Interval30Min,
+	"1h": Interval60Min, "1hour": Interval60Min, "60m": Interval60Min, "60min": Interval60Min,
+	"2h": Interval120Min, "2hour": Interval120Min, "120m": Interval120Min, "120min": Interval120Min,
+	"4h": Interval240Min, "4hour": Interval240Min, "240m": Interval240Min, "240min": Interval240Min,
+	"1d": Interval1Day, "1day": Interval1Day, "day": Interval1Day, "daily": Interval1Day,
+}
+
+// intervalDurations holds the bar length of each canonical interval
+var intervalDurations = map[string]time.Duration{
+	Interval1Min:   time.Minute,
+	Interval5Min:   5 * time.Minute,
+	Interval15Min:  15 * time.Minute,
+	Interval30Min:  30 * time.Minute,
+	Interval60Min:  time.Hour,
+	Interval120Min: 2 * time.Hour,
+	Interval240Min: 4 * time.Hour,
+	Interval1Day:   24 * time.Hour,
+}
+
+// NormalizeInterval converts any supported interval spelling to its canonical form
+func NormalizeInterval(interval string) (string, error) {
+	canonical, ok := intervalAliases[strings.ToLower(strings.TrimSpace(interval))]
+	if !ok {
+		return "", fmt.Errorf("unsupported interval: %s", interval)
+	}
+	return canonical, nil
+}
+
+// IntervalDuration returns the bar length of an interval in any supported spelling
+func IntervalDuration(interval string) (time.Duration, error) {
+	canonical, err := NormalizeInterval(interval)
+	if err != nil {
+		return 0, err
+	}
+	return intervalDurations[canonical], nil
+}
+
+// MaxDays returns the longest lookback accepted for a canonical interval
+func MaxDays(interval string) int {
+	if days, ok := maxIntervalDays[interval]; ok {
+		return days
+	}
+	return MaxHistoricalDays
+}
+
+// NormalizeTicker returns the canonical (trimmed, uppercase) form of a ticker symbol.
+// Currency pairs are written without a separator, e.g. EUR/USD becomes EURUSD.
+func NormalizeTicker(ticker string) string {
+	if base, quote, ok := ParseForexPair(ticker); ok {
+		return base + quote
+	}
+	return strings.ToUpper(strings.TrimSpace(ticker))
+}
+
+// HistoricalParams identifies a historical data request in normalized form
+type HistoricalParams struct {
+	Ticker   string
+	Interval string
+	Days     int
+}
+
+// NormalizeHistoricalParams validates and normalizes historical request parameters
+// so that equivalent requests ("15m" vs "15min", "spy" vs "SPY") share cache entries
+// and NATS subjects
+func NormalizeHistoricalParams(ticker, interval string, days int) (HistoricalParams, error) {
+	ticker, err := ValidateTicker(ticker)
+	if err != nil {
+		return HistoricalParams{}, err
+	}
+
+	canonical, err := NormalizeInterval(interval)
+	if err != nil {
+		return HistoricalParams{}, err
+	}
+
+	if err := ValidateDays(days); err != nil {
+		return HistoricalParams{}, err
+	}
+	if limit := MaxDays(canonical); days > limit {
+		return HistoricalParams{}, fmt.Errorf("days must be between 1 and %d for %s bars", limit, canonical)
+	}
+
+	return HistoricalParams{
+		Ticker:   ticker,
+		Interval: canonical,
+		Days:     days,
+	}, nil
+}
+
+// CacheKey returns a stable key for these parameters
+func (p HistoricalParams) CacheKey() string {
+	return fmt.Sprintf("%s:%d:%s", p.Ticker, p.Days, p.Interval)
+}
+
+// DateRange returns the time range covered by these parameters as of now.
+// The end is aligned to the close of the current bar so that repeated requests
+// within the same bar resolve to the same range.
+func (p HistoricalParams) DateRange(now time.Time) (time.Time, time.Time) {
+	barLength := intervalDurations[p.Interval]
+	end := now.UTC().Truncate(barLength).Add(barLength)
+	start := end.AddDate(0, 0, -p.Days)
+	return start, end
+}
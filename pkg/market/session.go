@@ -0,0 +1,49 @@
+// pkg/market/session.go
+package market
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Regular US equity session times in exchange time
+const (
+	SessionOpenMinute  = 9*60 + 30 // 09:30
+	SessionCloseMinute = 16 * 60   // 16:00
+)
+
+// DefaultExchangeZone is the exchange time zone unless MARKET_TIMEZONE
+// configures another
+const DefaultExchangeZone = "America/New_York"
+
+// exchangeLocation is the configured exchange time zone; nil uses the default
+var exchangeLocation atomic.Pointer[time.Location]
+
+// SetExchangeLocation sets the zone ExchangeLocation returns. The process's
+// clock sets it from MARKET_TIMEZONE; nil restores the default.
+func SetExchangeLocation(loc *time.Location) {
+	exchangeLocation.Store(loc)
+}
+
+// ExchangeLocation returns the exchange time zone, falling back to UTC
+func ExchangeLocation() *time.Location {
+	if loc := exchangeLocation.Load(); loc != nil {
+		return loc
+	}
+	loc, err := time.LoadLocation(DefaultExchangeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// InRegularSession reports whether t falls within regular trading hours on a
+// weekday. Exchange holidays are not considered.
+func InRegularSession(t time.Time) bool {
+	et := t.In(ExchangeLocation())
+	if et.Weekday() == time.Saturday || et.Weekday() == time.Sunday {
+		return false
+	}
+	minute := et.Hour()*60 + et.Minute()
+	return minute >= SessionOpenMinute && minute < SessionCloseMinute
+}
@@ -0,0 +1,146 @@
+// pkg/market/symbols.go
+package market
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// Symbol is reference data for a tradable instrument
+type Symbol struct {
+	Ticker       string `json:"ticker"`
+	Name         string `json:"name"`
+	Exchange     string `json:"exchange"`
+	AssetClass   string `json:"asset_class"`
+	Tradable     bool   `json:"tradable"`
+	Shortable    bool   `json:"shortable"`
+	Marginable   bool   `json:"marginable"`
+	Fractionable bool   `json:"fractionable"`
+}
+
+// Symbol search result limits
+const (
+	DefaultSymbolResults = 10
+	MaxSymbolResults     = 50
+)
+
+// SymbolSearch is a symbol search query
+type SymbolSearch struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+// SymbolLoader fetches the full symbol list from a provider
+type SymbolLoader func(ctx context.Context) ([]Symbol, error)
+
+// SymbolDirectory caches the provider's symbol list and searches it
+type SymbolDirectory struct {
+	mu       sync.RWMutex
+	symbols  []Symbol
+	loadedAt time.Time
+	ttl      time.Duration
+	load     SymbolLoader
+}
+
+// NewSymbolDirectory creates a directory that reloads symbols once they are older than ttl
+func NewSymbolDirectory(load SymbolLoader, ttl time.Duration) *SymbolDirectory {
+	return &SymbolDirectory{load: load, ttl: ttl}
+}
+
+// Search returns up to limit symbols matching a query, ranked by exact ticker,
+// ticker prefix, name word prefix and then name substring matches
+func (d *SymbolDirectory) Search(ctx context.Context, query string, limit int) ([]Symbol, error) {
+	symbols, err := d.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToUpper(strings.TrimSpace(query))
+	if query == "" {
+		return []Symbol{}, nil
+	}
+
+	type match struct {
+		symbol Symbol
+		rank   int
+	}
+	var matches []match
+	for _, s := range symbols {
+		if rank, ok := matchRank(s, query); ok {
+			matches = append(matches, match{s, rank})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].symbol.Ticker < matches[j].symbol.Ticker
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result := make([]Symbol, len(matches))
+	for i, m := range matches {
+		result[i] = m.symbol
+	}
+	return result, nil
+}
+
+// matchRank scores how well a symbol matches an upper-cased query; lower is better
+func matchRank(s Symbol, query string) (int, bool) {
+	name := strings.ToUpper(s.Name)
+	switch {
+	case s.Ticker == query:
+		return 0, true
+	case strings.HasPrefix(s.Ticker, query):
+		return 1, true
+	case strings.HasPrefix(name, query):
+		return 2, true
+	case strings.Contains(name, " "+query):
+		return 3, true
+	case strings.Contains(name, query):
+		return 4, true
+	}
+	return 0, false
+}
+
+// current returns the cached symbols, reloading them when stale. A failed reload
+// keeps serving the previous list.
+func (d *SymbolDirectory) current(ctx context.Context) ([]Symbol, error) {
+	d.mu.RLock()
+	symbols, loadedAt := d.symbols, d.loadedAt
+	d.mu.RUnlock()
+
+	if symbols != nil && time.Since(loadedAt) < d.ttl {
+		return symbols, nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Another caller may have reloaded while we waited for the lock
+	if d.symbols != nil && time.Since(d.loadedAt) < d.ttl {
+		return d.symbols, nil
+	}
+
+	loaded, err := d.load(ctx)
+	if err != nil {
+		if d.symbols != nil {
+			utils.Warn("Failed to refresh symbols, serving list from %s: %v", d.loadedAt.Format(time.RFC3339), err)
+			return d.symbols, nil
+		}
+		return nil, err
+	}
+
+	d.symbols = loaded
+	d.loadedAt = time.Now()
+	utils.Info("Loaded %d symbols", len(loaded))
+	return d.symbols, nil
+}
@@ -0,0 +1,70 @@
+// pkg/market/timestamps.go
+package market
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SessionDateLayout formats the exchange session date of a bar
+const SessionDateLayout = "2006-01-02"
+
+// zonedLayouts are accepted timestamp formats that carry a zone or offset
+var zonedLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700 MST", // time.Time.String
+	time.RFC1123Z,
+}
+
+// localLayouts are accepted timestamp formats without a zone
+var localLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	SessionDateLayout,
+}
+
+// ParseTimestamp parses a timestamp in any format producers are known to
+// emit: RFC 3339 with or without fractional seconds, space separated dates,
+// bare dates and Unix seconds or milliseconds. Values without a zone are read
+// in loc, or in UTC if loc is nil.
+func ParseTimestamp(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	for _, layout := range zonedLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+		// Milliseconds from 1973 on exceed any plausible count of seconds
+		if n >= 1e11 {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp format: %q", value)
+}
+
+// FormatTimestamp formats t as UTC RFC 3339, the form used in all payloads.
+// Fractional seconds are kept only when present.
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// SessionDate returns the exchange trading date t falls on, so consumers can
+// group bars by session without converting zones themselves
+func SessionDate(t time.Time) string {
+	return t.In(ExchangeLocation()).Format(SessionDateLayout)
+}
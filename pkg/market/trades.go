@@ -0,0 +1,17 @@
+// pkg/market/trades.go
+package market
+
+import "time"
+
+// Trade is a single trade print from the tape
+type Trade struct {
+	Ticker     string    `json:"ticker"`
+	Timestamp  time.Time `json:"timestamp"`
+	Price      float64   `json:"price"`
+	Size       int64     `json:"size"`
+	Exchange   string    `json:"exchange"`
+	ID         int64     `json:"id"`
+	Conditions []string  `json:"conditions,omitempty"`
+	Tape       string    `json:"tape,omitempty"`
+	Source     string    `json:"source"`
+}
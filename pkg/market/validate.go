@@ -0,0 +1,67 @@
+// pkg/market/validate.go
+package market
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxTickerLength is the longest ticker symbol accepted
+const MaxTickerLength = 12
+
+// MaxTickerList is the most tickers accepted in one list, such as a watchlist
+// or stream subscription
+const MaxTickerList = 100
+
+// tickerPattern matches a normalized ticker. Tickers become NATS subject
+// tokens, so dots, wildcards and whitespace are never allowed; class shares
+// are written with a dash (BRK-B).
+var tickerPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9-]*$`)
+
+// ValidateTicker normalizes a ticker symbol and checks it is safe to use in
+// NATS subjects, cache keys and provider calls
+func ValidateTicker(ticker string) (string, error) {
+	normalized := NormalizeTicker(ticker)
+	if normalized == "" {
+		return "", fmt.Errorf("ticker is required")
+	}
+	if len(normalized) > MaxTickerLength || !tickerPattern.MatchString(normalized) {
+		return "", fmt.Errorf("invalid ticker %.32q: use up to %d letters, digits or dashes", ticker, MaxTickerLength)
+	}
+	return normalized, nil
+}
+
+// ValidateTickers validates a list of tickers, dropping duplicates and keeping order
+func ValidateTickers(tickers []string) ([]string, error) {
+	if len(tickers) > MaxTickerList {
+		return nil, fmt.Errorf("at most %d tickers are accepted, got %d", MaxTickerList, len(tickers))
+	}
+	seen := make(map[string]bool, len(tickers))
+	valid := make([]string, 0, len(tickers))
+	for _, ticker := range tickers {
+		normalized, err := ValidateTicker(ticker)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[normalized] {
+			seen[normalized] = true
+			valid = append(valid, normalized)
+		}
+	}
+	return valid, nil
+}
+
+// ValidateDays checks a historical lookback is within range
+func ValidateDays(days int) error {
+	if days <= 0 || days > MaxHistoricalDays {
+		return fmt.Errorf("days must be between 1 and %d", MaxHistoricalDays)
+	}
+	return nil
+}
+
+// ValidSubjectToken reports whether a value can be used as one token of a
+// NATS subject without changing its meaning
+func ValidSubjectToken(token string) bool {
+	return token != "" && !strings.ContainsAny(token, ".*> \t\r\n")
+}
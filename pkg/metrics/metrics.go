@@ -0,0 +1,372 @@
+// pkg/metrics/metrics.go
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are histogram upper bounds in seconds, suited to request
+// latencies from a few milliseconds to tens of seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+// Default is the registry services register their metrics with
+var Default = NewRegistry()
+
+// metricType is a Prometheus metric type
+type metricType string
+
+const (
+	counterType   metricType = "counter"
+	gaugeType     metricType = "gauge"
+	histogramType metricType = "histogram"
+)
+
+// Registry holds metric families and renders them in the Prometheus text
+// exposition format
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+	names    map[string]bool
+	hooks    []func()
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// OnScrape registers fn to run before each scrape, for gauges computed from
+// state owned elsewhere such as ages or queue lengths
+func (r *Registry) OnScrape(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, fn)
+}
+
+// register adds a family, panicking on a duplicate name since that is a
+// programming error
+func (r *Registry) register(f *family) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[f.name] {
+		panic(fmt.Sprintf("metrics: %s registered twice", f.name))
+	}
+	r.names[f.name] = true
+	r.families = append(r.families, f)
+	return f
+}
+
+// NewCounterVec registers a counter partitioned by labels
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(newFamily(name, help, counterType, labels, nil))}
+}
+
+// NewGaugeVec registers a gauge partitioned by labels
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(newFamily(name, help, gaugeType, labels, nil))}
+}
+
+// NewHistogramVec registers a histogram partitioned by labels. Buckets are
+// upper bounds in increasing order; DefaultBuckets is used when nil.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &HistogramVec{r.register(newFamily(name, help, histogramType, labels, buckets))}
+}
+
+// Write renders every family in the text exposition format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	hooks := append([]func(){}, r.hooks...)
+	families := append([]*family{}, r.families...)
+	r.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+
+	buf := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(buf)
+	}
+	return buf.Flush()
+}
+
+// Handler serves the registry for Prometheus to scrape
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// Handler serves the default registry
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// family is a named metric and its series, one per label value combination
+type family struct {
+	name    string
+	help    string
+	typ     metricType
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is one labelled time series. Counters and gauges use value;
+// histograms use counts, sum and count.
+type series struct {
+	labels []string
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newFamily(name, help string, typ metricType, labels []string, buckets []float64) *family {
+	return &family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+}
+
+// get returns the series for label values, creating it on first use
+func (f *family) get(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), values...)}
+		if f.typ == histogramType {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// delete removes the series for label values
+func (f *family) delete(values []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.series, strings.Join(values, "\xff"))
+}
+
+// reset removes every series
+func (f *family) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.series = make(map[string]*series)
+}
+
+// write renders the family, with series sorted by label values so output
+// is stable between scrapes
+func (f *family) write(w *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.typ)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.typ != histogramType {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, f.labelSet(s.labels, ""), formatFloat(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelSet(s.labels, formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelSet(s.labels, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, f.labelSet(s.labels, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, f.labelSet(s.labels, ""), s.count)
+	}
+}
+
+// labelSet renders {name="value",...}, adding le for histogram buckets
+func (f *family) labelSet(values []string, le string) string {
+	if len(values) == 0 && le == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(values)+1)
+	for i, value := range values {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", f.labels[i], escapeLabel(value)))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf("le=\"%s\"", le))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct{ f *family }
+
+// Counter is one series of a CounterVec
+type Counter struct {
+	f *family
+	s *series
+}
+
+// With returns the counter for label values, in registration order
+func (v *CounterVec) With(values ...string) Counter {
+	return Counter{v.f, v.f.get(values)}
+}
+
+// Delete removes the series for label values
+func (v *CounterVec) Delete(values ...string) { v.f.delete(values) }
+
+// Inc adds one
+func (c Counter) Inc() { c.Add(1) }
+
+// Add adds delta, which must not be negative
+func (c Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	c.f.mu.Lock()
+	c.s.value += delta
+	c.f.mu.Unlock()
+}
+
+// Set sets the counter to a total kept elsewhere, for counters exported
+// from existing statistics on each scrape. Totals must not decrease.
+func (c Counter) Set(total float64) {
+	c.f.mu.Lock()
+	c.s.value = total
+	c.f.mu.Unlock()
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct{ f *family }
+
+// Gauge is one series of a GaugeVec
+type Gauge struct {
+	f *family
+	s *series
+}
+
+// With returns the gauge for label values, in registration order
+func (v *GaugeVec) With(values ...string) Gauge {
+	return Gauge{v.f, v.f.get(values)}
+}
+
+// Delete removes the series for label values
+func (v *GaugeVec) Delete(values ...string) { v.f.delete(values) }
+
+// Reset removes every series, for gauges rebuilt from scratch on each scrape
+func (v *GaugeVec) Reset() { v.f.reset() }
+
+// Set sets the gauge to value
+func (g Gauge) Set(value float64) {
+	g.f.mu.Lock()
+	g.s.value = value
+	g.f.mu.Unlock()
+}
+
+// SetTime sets the gauge to t in Unix seconds
+func (g Gauge) SetTime(t time.Time) {
+	g.Set(float64(t.UnixNano()) / 1e9)
+}
+
+// Add adds delta
+func (g Gauge) Add(delta float64) {
+	g.f.mu.Lock()
+	g.s.value += delta
+	g.f.mu.Unlock()
+}
+
+// Inc adds one
+func (g Gauge) Inc() { g.Add(1) }
+
+// Dec subtracts one
+func (g Gauge) Dec() { g.Add(-1) }
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct{ f *family }
+
+// Histogram is one series of a HistogramVec
+type Histogram struct {
+	f *family
+	s *series
+}
+
+// With returns the histogram for label values, in registration order
+func (v *HistogramVec) With(values ...string) Histogram {
+	return Histogram{v.f, v.f.get(values)}
+}
+
+// Observe records a value
+func (h Histogram) Observe(value float64) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	for i, bound := range h.f.buckets {
+		if value <= bound {
+			h.s.counts[i]++
+			break
+		}
+	}
+	h.s.sum += value
+	h.s.count++
+}
+
+// ObserveSince records the seconds elapsed since start
+func (h Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// formatFloat renders a sample value as Prometheus expects
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeLabel escapes a label value
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// escapeHelp escapes help text
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
@@ -0,0 +1,176 @@
+// pkg/notify/notify.go
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+// Message is a notification with optional plain text, HTML and JSON bodies
+type Message struct {
+	Subject string
+	Text    string
+	HTML    string
+	JSON    interface{} // Sent as the webhook payload
+}
+
+// Notifier delivers messages to a channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Webhook posts the JSON body of a message to a URL
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhook creates a webhook notifier
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Name identifies the channel in logs
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Notify posts the message payload as JSON
+func (w *Webhook) Notify(ctx context.Context, msg Message) error {
+	payload := msg.JSON
+	if payload == nil {
+		payload = map[string]string{"subject": msg.Subject, "text": msg.Text}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Email sends messages through an SMTP server
+type Email struct {
+	Addr     string // host:port
+	From     string
+	To       []string
+	Username string
+	Password string
+}
+
+// EmailFromEnv creates an email notifier for the given recipients using
+// SMTP_ADDR, SMTP_FROM, SMTP_USERNAME and SMTP_PASSWORD. It returns nil when
+// SMTP is not configured or there are no recipients.
+func EmailFromEnv(recipients string) *Email {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" || recipients == "" {
+		return nil
+	}
+
+	var to []string
+	for _, r := range strings.Split(recipients, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			to = append(to, r)
+		}
+	}
+	if len(to) == 0 {
+		return nil
+	}
+
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "tradinglab@localhost"
+	}
+
+	return &Email{
+		Addr:     addr,
+		From:     from,
+		To:       to,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+	}
+}
+
+// Name identifies the channel in logs
+func (e *Email) Name() string {
+	return "email"
+}
+
+// Notify sends the message as an HTML email, or plain text when there is no HTML body
+func (e *Email) Notify(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if e.Username != "" {
+		host := e.Addr
+		if idx := strings.LastIndex(host, ":"); idx >= 0 {
+			host = host[:idx]
+		}
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	contentType, body := "text/plain", msg.Text
+	if msg.HTML != "" {
+		contentType, body = "text/html", msg.HTML
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", e.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: %s; charset=UTF-8\r\n\r\n", contentType)
+	buf.WriteString(body)
+
+	// net/smtp does not take a context, so run it in the background and honor cancellation
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(e.Addr, auth, e.From, e.To, buf.Bytes())
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Send delivers a message to every notifier and returns the combined errors
+func Send(ctx context.Context, notifiers []Notifier, msg Message) error {
+	var failures []string
+	for _, n := range notifiers {
+		if err := n.Notify(ctx, msg); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", n.Name(), err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("notification failed: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
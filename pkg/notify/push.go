@@ -0,0 +1,395 @@
+// pkg/notify/push.go
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Push platforms
+const (
+	PlatformFCM  = "fcm"  // Android and web clients via Firebase Cloud Messaging
+	PlatformAPNs = "apns" // iOS clients via the Apple Push Notification service
+)
+
+// ErrInvalidToken is returned when a device token is no longer registered,
+// so callers can forget it
+var ErrInvalidToken = errors.New("device token is no longer valid")
+
+// PushMessage is a mobile push notification
+type PushMessage struct {
+	Title string
+	Body  string
+	Data  map[string]string // Delivered to the app alongside the notification
+}
+
+// Pusher delivers push notifications to devices on one platform
+type Pusher interface {
+	Platform() string
+	Push(ctx context.Context, token string, msg PushMessage) error
+}
+
+// FCM sends push notifications through the Firebase Cloud Messaging HTTP v1 API
+type FCM struct {
+	ProjectID   string
+	ClientEmail string
+	TokenURI    string
+	key         *rsa.PrivateKey
+	Client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expires     time.Time
+}
+
+// FCMFromEnv creates an FCM pusher from the service account JSON file named
+// by FCM_CREDENTIALS_FILE. It returns nil when FCM is not configured.
+func FCMFromEnv() (*FCM, error) {
+	path := os.Getenv("FCM_CREDENTIALS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read FCM credentials: %w", err)
+	}
+	return NewFCM(data)
+}
+
+// NewFCM creates an FCM pusher from a service account JSON key
+func NewFCM(credentials []byte) (*FCM, error) {
+	var account struct {
+		ProjectID   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(credentials, &account); err != nil {
+		return nil, fmt.Errorf("invalid FCM credentials: %w", err)
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" {
+		return nil, fmt.Errorf("FCM credentials are missing project_id or client_email")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("FCM credentials have no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FCM private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("FCM private key is not an RSA key")
+	}
+
+	return &FCM{
+		ProjectID:   account.ProjectID,
+		ClientEmail: account.ClientEmail,
+		TokenURI:    account.TokenURI,
+		key:         key,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Platform identifies the pusher
+func (f *FCM) Platform() string {
+	return PlatformFCM
+}
+
+// Push sends a notification to one registration token
+func (f *FCM) Push(ctx context.Context, token string, msg PushMessage) error {
+	accessToken, err := f.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        token,
+			"notification": map[string]string{"title": msg.Title, "body": msg.Body},
+			"data":         msg.Data,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", f.ProjectID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("FCM request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode == http.StatusNotFound || strings.Contains(string(detail), "UNREGISTERED") {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("FCM returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+}
+
+// token returns a cached OAuth access token, exchanging a signed service
+// account assertion for a new one when it is about to expire
+func (f *FCM) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.accessToken != "" && time.Until(f.expires) > time.Minute {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT("RS256", "", map[string]interface{}{
+		"iss":   f.ClientEmail,
+		"scope": "https://www.googleapis.com/auth/firebase.messaging",
+		"aud":   f.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}, func(digest []byte) ([]byte, error) {
+		return rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("FCM token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("FCM token request returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid FCM token response: %w", err)
+	}
+	f.accessToken = result.AccessToken
+	f.expires = now.Add(time.Duration(result.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
+
+// apnsTokenLifetime is how long an APNs provider token is reused. Apple
+// rejects tokens older than an hour and throttles more frequent refreshes.
+const apnsTokenLifetime = 50 * time.Minute
+
+// APNs sends push notifications through Apple's HTTP/2 provider API using
+// token-based authentication
+type APNs struct {
+	KeyID  string
+	TeamID string
+	Topic  string // The app's bundle ID
+	Host   string
+	key    *ecdsa.PrivateKey
+	Client *http.Client
+
+	mu     sync.Mutex
+	jwt    string
+	issued time.Time
+}
+
+// APNsFromEnv creates an APNs pusher from APNS_KEY_FILE (a .p8 signing key),
+// APNS_KEY_ID, APNS_TEAM_ID and APNS_TOPIC. APNS_SANDBOX=true targets the
+// development environment. It returns nil when APNs is not configured.
+func APNsFromEnv() (*APNs, error) {
+	path := os.Getenv("APNS_KEY_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read APNs key: %w", err)
+	}
+	return NewAPNs(data, os.Getenv("APNS_KEY_ID"), os.Getenv("APNS_TEAM_ID"), os.Getenv("APNS_TOPIC"), os.Getenv("APNS_SANDBOX") == "true")
+}
+
+// NewAPNs creates an APNs pusher from a PEM encoded signing key
+func NewAPNs(keyPEM []byte, keyID, teamID, topic string, sandbox bool) (*APNs, error) {
+	if keyID == "" || teamID == "" || topic == "" {
+		return nil, fmt.Errorf("APNs requires a key ID, team ID and topic")
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("APNs key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid APNs key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("APNs key is not an ECDSA key")
+	}
+
+	host := "https://api.push.apple.com"
+	if sandbox {
+		host = "https://api.sandbox.push.apple.com"
+	}
+	return &APNs{
+		KeyID:  keyID,
+		TeamID: teamID,
+		Topic:  topic,
+		Host:   host,
+		key:    key,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Platform identifies the pusher
+func (a *APNs) Platform() string {
+	return PlatformAPNs
+}
+
+// Push sends an alert notification to one device token
+func (a *APNs) Push(ctx context.Context, token string, msg PushMessage) error {
+	providerToken, err := a.token()
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range msg.Data {
+		if k != "aps" {
+			payload[k] = v
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Host+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", a.Topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("APNs request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var reason struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&reason)
+	if resp.StatusCode == http.StatusGone || reason.Reason == "BadDeviceToken" || reason.Reason == "Unregistered" {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("APNs returned status %d: %s", resp.StatusCode, reason.Reason)
+}
+
+// token returns the provider token, signing a new one when it is about to expire
+func (a *APNs) token() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.jwt != "" && time.Since(a.issued) < apnsTokenLifetime {
+		return a.jwt, nil
+	}
+
+	now := time.Now()
+	token, err := signJWT("ES256", a.KeyID, map[string]interface{}{
+		"iss": a.TeamID,
+		"iat": now.Unix(),
+	}, func(digest []byte) ([]byte, error) {
+		r, s, err := ecdsa.Sign(rand.Reader, a.key, digest)
+		if err != nil {
+			return nil, err
+		}
+		// JWS encodes ES256 signatures as fixed-width r || s
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	a.jwt, a.issued = token, now
+	return token, nil
+}
+
+// signJWT builds a compact JWT, signing the SHA-256 digest of its header and claims
+func signJWT(alg, keyID string, claims map[string]interface{}, sign func(digest []byte) ([]byte, error)) (string, error) {
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if keyID != "" {
+		header["kid"] = keyID
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(headerJSON) + "." + enc.EncodeToString(claimsJSON)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := sign(digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
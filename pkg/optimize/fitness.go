@@ -0,0 +1,65 @@
+// pkg/optimize/fitness.go
+package optimize
+
+import (
+	"fmt"
+	"math"
+)
+
+// Fitness functions
+const (
+	// FitnessSharpe scores a backtest by its annualized Sharpe ratio
+	FitnessSharpe = "sharpe"
+	// FitnessProfitFactor scores a backtest by its profit factor, divided
+	// by 1 + DrawdownPenalty × its maximum drawdown as a fraction
+	FitnessProfitFactor = "profit_factor"
+)
+
+// maxProfitFactor caps the profit factor scored, so runs without a losing
+// trade do not swamp every other
+const maxProfitFactor = 10
+
+// Metrics are the backtest results fitness is scored from
+type Metrics struct {
+	SharpeRatio    float64
+	ProfitFactor   float64
+	MaxDrawdownPct float64
+	TotalTrades    int
+}
+
+// Fitness scores backtests for the optimizer, higher being better
+type Fitness struct {
+	Name            string  `json:"name"`
+	DrawdownPenalty float64 `json:"drawdown_penalty"` // Profit factor only
+	MinTrades       int     `json:"min_trades"`       // Fewer trades make a run ineligible
+}
+
+// DefaultFitness scores by Sharpe ratio, ignoring runs of under 5 trades
+var DefaultFitness = Fitness{Name: FitnessSharpe, DrawdownPenalty: 1, MinTrades: 5}
+
+// Validate checks the fitness function is known and its settings usable
+func (f Fitness) Validate() error {
+	if f.Name != FitnessSharpe && f.Name != FitnessProfitFactor {
+		return fmt.Errorf("fitness must be %s or %s", FitnessSharpe, FitnessProfitFactor)
+	}
+	if f.DrawdownPenalty < 0 || math.IsNaN(f.DrawdownPenalty) {
+		return fmt.Errorf("drawdown_penalty cannot be negative")
+	}
+	if f.MinTrades < 0 {
+		return fmt.Errorf("min_trades cannot be negative")
+	}
+	return nil
+}
+
+// Score returns a backtest's fitness, or false when it made too few trades
+// to be judged
+func (f Fitness) Score(m Metrics) (float64, bool) {
+	if m.TotalTrades < f.MinTrades || m.TotalTrades == 0 {
+		return 0, false
+	}
+	if f.Name == FitnessProfitFactor {
+		pf := math.Min(m.ProfitFactor, maxProfitFactor)
+		return pf / (1 + f.DrawdownPenalty*m.MaxDrawdownPct/100), true
+	}
+	return m.SharpeRatio, true
+}
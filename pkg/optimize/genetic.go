@@ -0,0 +1,366 @@
+// pkg/optimize/genetic.go
+package optimize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoEligible is returned when no candidate made enough trades to score
+var ErrNoEligible = errors.New("no candidate was eligible for scoring")
+
+// Limits on a search, which runs up to population × generations backtests
+const (
+	MaxPopulation  = 200
+	MaxGenerations = 200
+	MaxParallelism = 16
+)
+
+// Evaluator backtests a candidate's parameters and scores it, returning
+// false when the run cannot be judged. It should return promptly once ctx
+// is cancelled.
+type Evaluator func(ctx context.Context, params map[string]interface{}) (float64, bool, error)
+
+// Config tunes the genetic algorithm. Zero fields take their defaults.
+type Config struct {
+	Population     int     `json:"population"`      // Candidates per generation (default 20)
+	Generations    int     `json:"generations"`     // Most generations run (default 20)
+	Elite          int     `json:"elite"`           // Best candidates carried over unchanged (default 2)
+	TournamentSize int     `json:"tournament_size"` // Candidates competing to be a parent (default 3)
+	CrossoverRate  float64 `json:"crossover_rate"`  // Chance a child mixes two parents (default 0.8)
+	MutationRate   float64 `json:"mutation_rate"`   // Chance each gene mutates (default 0.2)
+	Parallelism    int     `json:"parallelism"`     // Backtests run at once (default 4)
+	Patience       int     `json:"patience"`        // Generations without improvement before stopping early (default 5)
+	MinImprovement float64 `json:"min_improvement"` // Gain in best fitness that counts as improvement
+	Seed           int64   `json:"seed"`            // Random seed, for reproducible searches
+
+	OnGeneration func(Generation) `json:"-"` // Called after each generation is scored
+}
+
+// WithDefaults fills in unset fields, choosing a seed if none is set
+func (c Config) WithDefaults() Config {
+	for _, field := range []struct {
+		value *int
+		def   int
+	}{
+		{&c.Population, 20}, {&c.Generations, 20}, {&c.Elite, 2},
+		{&c.TournamentSize, 3}, {&c.Parallelism, 4}, {&c.Patience, 5},
+	} {
+		if *field.value == 0 {
+			*field.value = field.def
+		}
+	}
+	if c.CrossoverRate == 0 {
+		c.CrossoverRate = 0.8
+	}
+	if c.MutationRate == 0 {
+		c.MutationRate = 0.2
+	}
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+	}
+	return c
+}
+
+// Validate checks the configuration, with defaults applied, is usable
+func (c Config) Validate() error {
+	c = c.WithDefaults()
+	switch {
+	case c.Population < 2 || c.Population > MaxPopulation:
+		return fmt.Errorf("population must be between 2 and %d", MaxPopulation)
+	case c.Generations < 1 || c.Generations > MaxGenerations:
+		return fmt.Errorf("generations must be between 1 and %d", MaxGenerations)
+	case c.Elite < 0 || c.Elite >= c.Population:
+		return fmt.Errorf("elite must be below the population")
+	case c.TournamentSize < 1 || c.TournamentSize > c.Population:
+		return fmt.Errorf("tournament_size must be between 1 and the population")
+	case c.CrossoverRate < 0 || c.CrossoverRate > 1, c.MutationRate < 0 || c.MutationRate > 1:
+		return fmt.Errorf("crossover_rate and mutation_rate must be between 0 and 1")
+	case c.Parallelism < 1 || c.Parallelism > MaxParallelism:
+		return fmt.Errorf("parallelism must be between 1 and %d", MaxParallelism)
+	case c.Patience < 1:
+		return fmt.Errorf("patience must be at least 1")
+	case c.MinImprovement < 0:
+		return fmt.Errorf("min_improvement cannot be negative")
+	}
+	return nil
+}
+
+// Candidate is a set of parameter values and its fitness
+type Candidate struct {
+	Params  map[string]interface{} `json:"params"`
+	Fitness float64                `json:"fitness"`
+}
+
+// Generation summarizes one generation of a search
+type Generation struct {
+	Number    int     `json:"generation"`
+	Best      float64 `json:"best"` // Best fitness found so far
+	Mean      float64 `json:"mean"` // Mean fitness of the generation's eligible candidates
+	Eligible  int     `json:"eligible"`
+	Evaluated int     `json:"evaluated"` // Backtests run; repeated candidates are scored once
+	Failed    int     `json:"failed"`
+}
+
+// Result is the outcome of a search
+type Result struct {
+	Best         Candidate    `json:"best"`
+	History      []Generation `json:"history"`
+	Evaluations  int          `json:"evaluations"`
+	StoppedEarly bool         `json:"stopped_early"` // Best fitness stopped improving before the last generation
+	Seed         int64        `json:"seed"`
+}
+
+// genome holds one gene per dimension of the space
+type genome []float64
+
+// key identifies a genome so repeated candidates are scored once
+func (g genome) key() string {
+	parts := make([]string, len(g))
+	for i, gene := range g {
+		parts[i] = strconv.FormatFloat(gene, 'g', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+// params decodes a genome to parameter values
+func (s Space) params(g genome) map[string]interface{} {
+	params := make(map[string]interface{}, len(s))
+	for i, dim := range s {
+		params[dim.Name] = dim.value(g[i])
+	}
+	return params
+}
+
+// search is the state of one run of the genetic algorithm
+type search struct {
+	space    Space
+	cfg      Config
+	evaluate Evaluator
+	rng      *rand.Rand
+	scores   map[string]float64 // Fitness by genome key; ineligible and failed runs score negative infinity
+}
+
+// Run searches the space for the parameters the evaluator scores highest.
+// Each generation's new candidates are backtested in parallel; the search
+// stops after cfg.Generations, or early once the best fitness has not
+// improved for cfg.Patience generations.
+func Run(ctx context.Context, space Space, cfg Config, evaluate Evaluator) (Result, error) {
+	if len(space) == 0 {
+		return Result{}, fmt.Errorf("at least one parameter must be optimized")
+	}
+	if err := cfg.Validate(); err != nil {
+		return Result{}, err
+	}
+	cfg = cfg.WithDefaults()
+
+	s := &search{
+		space:    space,
+		cfg:      cfg,
+		evaluate: evaluate,
+		rng:      rand.New(rand.NewSource(cfg.Seed)),
+		scores:   make(map[string]float64),
+	}
+	result := Result{Best: Candidate{Fitness: math.Inf(-1)}, Seed: cfg.Seed}
+
+	population := make([]genome, cfg.Population)
+	for i := range population {
+		population[i] = s.random()
+	}
+
+	stale := 0
+	for number := 1; ; number++ {
+		gen, err := s.score(ctx, population)
+		if err != nil {
+			return result, err
+		}
+		gen.Number = number
+		result.Evaluations += gen.Evaluated
+
+		// Track the best candidate, counting generations without progress
+		best := s.fittest(population)
+		fitness := s.scores[best.key()]
+		if fitness > result.Best.Fitness+cfg.MinImprovement {
+			stale = 0
+		} else {
+			stale++
+		}
+		if fitness > result.Best.Fitness {
+			result.Best = Candidate{Params: space.params(best), Fitness: fitness}
+		}
+		gen.Best = result.Best.Fitness
+		if math.IsInf(gen.Best, -1) {
+			gen.Best = 0
+		}
+		result.History = append(result.History, gen)
+		if cfg.OnGeneration != nil {
+			cfg.OnGeneration(gen)
+		}
+
+		if number == cfg.Generations {
+			break
+		}
+		if stale >= cfg.Patience {
+			result.StoppedEarly = true
+			break
+		}
+		population = s.breed(population)
+	}
+
+	if math.IsInf(result.Best.Fitness, -1) {
+		return result, ErrNoEligible
+	}
+	return result, nil
+}
+
+// random draws a genome uniformly from the space
+func (s *search) random() genome {
+	g := make(genome, len(s.space))
+	for i, dim := range s.space {
+		g[i] = dim.random(s.rng)
+	}
+	return g
+}
+
+// score evaluates the population's candidates not scored before, at most
+// cfg.Parallelism at a time, and summarizes the generation. It fails if
+// every new backtest failed, as retrying the same way is unlikely to help.
+func (s *search) score(ctx context.Context, population []genome) (Generation, error) {
+	var pending []genome
+	queued := make(map[string]bool)
+	for _, g := range population {
+		key := g.key()
+		if _, scored := s.scores[key]; !scored && !queued[key] {
+			queued[key] = true
+			pending = append(pending, g)
+		}
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		lastErr error
+	)
+	gen := Generation{Evaluated: len(pending)}
+	sem := make(chan struct{}, s.cfg.Parallelism)
+	for _, g := range pending {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(g genome) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fitness, eligible, err := s.evaluate(ctx, s.space.params(g))
+			if err != nil || !eligible {
+				fitness = math.Inf(-1)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				gen.Failed++
+				lastErr = err
+			}
+			if ctx.Err() == nil {
+				s.scores[g.key()] = fitness
+			}
+		}(g)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return gen, err
+	}
+	if gen.Failed > 0 && gen.Failed == gen.Evaluated {
+		return gen, fmt.Errorf("every backtest of the generation failed: %w", lastErr)
+	}
+
+	total := 0.0
+	for _, g := range population {
+		if fitness := s.scores[g.key()]; !math.IsInf(fitness, -1) {
+			total += fitness
+			gen.Eligible++
+		}
+	}
+	if gen.Eligible > 0 {
+		gen.Mean = total / float64(gen.Eligible)
+	}
+	return gen, nil
+}
+
+// fittest returns the population's highest scoring genome
+func (s *search) fittest(population []genome) genome {
+	best := population[0]
+	for _, g := range population[1:] {
+		if s.scores[g.key()] > s.scores[best.key()] {
+			best = g
+		}
+	}
+	return best
+}
+
+// breed makes the next generation: the elite carried over, then children
+// of tournament-selected parents, mixed by uniform crossover and mutated
+func (s *search) breed(population []genome) []genome {
+	ranked := append([]genome(nil), population...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return s.scores[ranked[i].key()] > s.scores[ranked[j].key()]
+	})
+
+	next := make([]genome, 0, len(population))
+	kept := make(map[string]bool)
+	for _, g := range ranked {
+		if len(next) == s.cfg.Elite {
+			break
+		}
+		if !kept[g.key()] {
+			kept[g.key()] = true
+			next = append(next, g)
+		}
+	}
+
+	for len(next) < len(population) {
+		first, second := s.tournament(population), s.tournament(population)
+		child := append(genome(nil), first...)
+		if s.rng.Float64() < s.cfg.CrossoverRate {
+			for i := range child {
+				if s.rng.Intn(2) == 1 {
+					child[i] = second[i]
+				}
+			}
+		}
+		for i, dim := range s.space {
+			if s.rng.Float64() < s.cfg.MutationRate {
+				child[i] = dim.mutate(child[i], s.rng)
+			}
+		}
+		next = append(next, child)
+	}
+	return next
+}
+
+// tournament picks the fittest of cfg.TournamentSize random candidates
+func (s *search) tournament(population []genome) genome {
+	best := population[s.rng.Intn(len(population))]
+	for i := 1; i < s.cfg.TournamentSize; i++ {
+		g := population[s.rng.Intn(len(population))]
+		if s.scores[g.key()] > s.scores[best.key()] {
+			best = g
+		}
+	}
+	return best
+}
@@ -0,0 +1,181 @@
+// pkg/optimize/space.go
+package optimize
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/myapp/tradinglab/pkg/strategy"
+)
+
+// Dimension is a strategy parameter the optimizer varies. Numeric
+// dimensions range over [Min, Max]; bool and string ones over Options.
+type Dimension struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"` // strategy.ParamInt, ParamFloat, ParamBool or ParamString
+	Min     float64  `json:"min,omitempty"`
+	Max     float64  `json:"max,omitempty"`
+	Options []string `json:"options,omitempty"`
+}
+
+// Space is the parameters the optimizer varies, ordered by name
+type Space []Dimension
+
+// Range narrows a parameter's search range. Numeric parameters default to
+// their declared bounds and string parameters to their declared options.
+type Range struct {
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Options []string `json:"options,omitempty"`
+}
+
+// SpaceFor builds the space of a strategy's parameters named in ranges.
+// Ranges must lie within the parameters' declared bounds and options.
+func SpaceFor(def strategy.Definition, ranges map[string]Range) (Space, error) {
+	if def.Rules != nil {
+		return nil, fmt.Errorf("strategy %s is rule-based and has no parameters to optimize", def.Name)
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("at least one parameter must be optimized")
+	}
+
+	declared := make(map[string]strategy.ParamSpec, len(def.Params))
+	for _, spec := range def.Params {
+		declared[spec.Name] = spec
+	}
+
+	space := make(Space, 0, len(ranges))
+	for name, r := range ranges {
+		spec, ok := declared[name]
+		if !ok {
+			return nil, fmt.Errorf("strategy %s has no parameter %q", def.Name, name)
+		}
+		dim, err := dimensionFor(spec, r)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		space = append(space, dim)
+	}
+	sort.Slice(space, func(i, j int) bool { return space[i].Name < space[j].Name })
+	return space, nil
+}
+
+// dimensionFor checks a requested range against a parameter's declaration
+func dimensionFor(spec strategy.ParamSpec, r Range) (Dimension, error) {
+	dim := Dimension{Name: spec.Name, Type: spec.Type}
+	switch spec.Type {
+	case strategy.ParamInt, strategy.ParamFloat:
+		if len(r.Options) > 0 {
+			return dim, fmt.Errorf("numeric parameters take min and max, not options")
+		}
+		lo, hi := r.Min, r.Max
+		if lo == nil {
+			lo = spec.Min
+		}
+		if hi == nil {
+			hi = spec.Max
+		}
+		if lo == nil || hi == nil {
+			return dim, fmt.Errorf("min and max are required as the parameter is unbounded")
+		}
+		if (spec.Min != nil && *lo < *spec.Min) || (spec.Max != nil && *hi > *spec.Max) {
+			return dim, fmt.Errorf("range must lie within %s", specBounds(spec))
+		}
+		dim.Min, dim.Max = *lo, *hi
+		if spec.Type == strategy.ParamInt {
+			dim.Min, dim.Max = math.Ceil(dim.Min), math.Floor(dim.Max)
+		}
+		if dim.Min >= dim.Max {
+			return dim, fmt.Errorf("min must be below max")
+		}
+	case strategy.ParamBool:
+		if r.Min != nil || r.Max != nil || len(r.Options) > 0 {
+			return dim, fmt.Errorf("boolean parameters take no range")
+		}
+		dim.Options = []string{"false", "true"}
+	case strategy.ParamString:
+		if r.Min != nil || r.Max != nil {
+			return dim, fmt.Errorf("string parameters take options, not min and max")
+		}
+		dim.Options = spec.Options
+		if len(r.Options) > 0 {
+			dim.Options = nil
+			for _, option := range r.Options {
+				matched := ""
+				for _, declared := range spec.Options {
+					if strings.EqualFold(declared, option) {
+						matched = declared
+					}
+				}
+				if matched == "" {
+					return dim, fmt.Errorf("option %q is not one of: %s", option, strings.Join(spec.Options, ", "))
+				}
+				dim.Options = append(dim.Options, matched)
+			}
+		}
+		if len(dim.Options) < 2 {
+			return dim, fmt.Errorf("at least 2 options are needed")
+		}
+	default:
+		return dim, fmt.Errorf("unsupported parameter type %q", spec.Type)
+	}
+	return dim, nil
+}
+
+// specBounds describes a parameter's declared range
+func specBounds(spec strategy.ParamSpec) string {
+	lo, hi := "-inf", "+inf"
+	if spec.Min != nil {
+		lo = strconv.FormatFloat(*spec.Min, 'f', -1, 64)
+	}
+	if spec.Max != nil {
+		hi = strconv.FormatFloat(*spec.Max, 'f', -1, 64)
+	}
+	return "[" + lo + ", " + hi + "]"
+}
+
+// categorical reports whether a dimension's genes index its options
+func (d Dimension) categorical() bool {
+	return len(d.Options) > 0
+}
+
+// random draws a gene uniformly from the dimension
+func (d Dimension) random(rng *rand.Rand) float64 {
+	switch {
+	case d.categorical():
+		return float64(rng.Intn(len(d.Options)))
+	case d.Type == strategy.ParamInt:
+		return d.Min + float64(rng.Intn(int(d.Max-d.Min)+1))
+	}
+	return d.Min + rng.Float64()*(d.Max-d.Min)
+}
+
+// mutate perturbs a gene: numeric genes by a normal step of a tenth of the
+// range, categorical ones by drawing another option
+func (d Dimension) mutate(gene float64, rng *rand.Rand) float64 {
+	if d.categorical() {
+		return d.random(rng)
+	}
+	gene += rng.NormFloat64() * (d.Max - d.Min) / 10
+	if d.Type == strategy.ParamInt {
+		gene = math.Round(gene)
+	}
+	return math.Max(d.Min, math.Min(d.Max, gene))
+}
+
+// value decodes a gene to the parameter value it stands for
+func (d Dimension) value(gene float64) interface{} {
+	switch {
+	case d.Type == strategy.ParamBool:
+		return d.Options[int(gene)] == "true"
+	case d.categorical():
+		return d.Options[int(gene)]
+	case d.Type == strategy.ParamInt:
+		return int64(gene)
+	}
+	return gene
+}
@@ -0,0 +1,193 @@
+// pkg/parquet/parquet.go
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// ContentType identifies Parquet files
+const ContentType = "application/vnd.apache.parquet"
+
+// magic opens and closes every Parquet file
+const magic = "PAR1"
+
+// createdBy is recorded as the writer of files
+const createdBy = "tradinglab"
+
+// Physical types, encodings and other enumerations of the Parquet format
+const (
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	repetitionRequired = 0
+	encodingPlain      = 0
+	encodingRLE        = 3
+	codecUncompressed  = 0
+	pageData           = 0
+)
+
+// Column is a named column of values, all present
+type Column struct {
+	name      string
+	physical  int32
+	converted int32 // -1 when the column has no converted type
+	rows      int
+	data      []byte // PLAIN encoded values
+}
+
+// Doubles makes a column of 64-bit floats
+func Doubles(name string, values []float64) Column {
+	data := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(data[8*i:], math.Float64bits(v))
+	}
+	return Column{name: name, physical: typeDouble, converted: -1, rows: len(values), data: data}
+}
+
+// Int64s makes a column of 64-bit integers
+func Int64s(name string, values []int64) Column {
+	data := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(data[8*i:], uint64(v))
+	}
+	return Column{name: name, physical: typeInt64, converted: -1, rows: len(values), data: data}
+}
+
+// Timestamps makes a column of UTC timestamps with millisecond precision
+func Timestamps(name string, values []time.Time) Column {
+	millis := make([]int64, len(values))
+	for i, t := range values {
+		millis[i] = t.UnixMilli()
+	}
+	c := Int64s(name, millis)
+	c.converted = convertedTimestampMillis
+	return c
+}
+
+// Strings makes a column of UTF-8 strings
+func Strings(name string, values []string) Column {
+	var buf bytes.Buffer
+	var size [4]byte
+	for _, v := range values {
+		binary.LittleEndian.PutUint32(size[:], uint32(len(v)))
+		buf.Write(size[:])
+		buf.WriteString(v)
+	}
+	return Column{name: name, physical: typeByteArray, converted: convertedUTF8, rows: len(values), data: buf.Bytes()}
+}
+
+// Write writes the columns as a Parquet file of one row group, with each
+// column in a single uncompressed data page. Columns must be of equal length.
+func Write(w io.Writer, columns ...Column) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("parquet: at least one column is required")
+	}
+	rows := columns[0].rows
+	for _, c := range columns {
+		if c.rows != rows {
+			return fmt.Errorf("parquet: column %s has %d rows, expected %d", c.name, c.rows, rows)
+		}
+		if len(c.data) > math.MaxInt32 {
+			return fmt.Errorf("parquet: column %s is too large for one page", c.name)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(magic)
+
+	type chunk struct {
+		offset int64
+		size   int64
+	}
+	chunks := make([]chunk, len(columns))
+	for i, c := range columns {
+		header := &compact{}
+		header.begin()
+		header.i32Field(1, pageData)
+		header.i32Field(2, int32(len(c.data)))
+		header.i32Field(3, int32(len(c.data)))
+		header.structField(5)
+		header.i32Field(1, int32(rows))
+		header.i32Field(2, encodingPlain)
+		header.i32Field(3, encodingRLE)
+		header.i32Field(4, encodingRLE)
+		header.end()
+		header.end()
+
+		chunks[i] = chunk{offset: int64(buf.Len()), size: int64(header.buf.Len() + len(c.data))}
+		buf.Write(header.buf.Bytes())
+		buf.Write(c.data)
+	}
+
+	meta := &compact{}
+	meta.begin()
+	meta.i32Field(1, 1)
+
+	meta.listField(2, compactStruct, len(columns)+1)
+	meta.begin()
+	meta.stringField(4, "schema")
+	meta.i32Field(5, int32(len(columns)))
+	meta.end()
+	for _, c := range columns {
+		meta.begin()
+		meta.i32Field(1, c.physical)
+		meta.i32Field(3, repetitionRequired)
+		meta.stringField(4, c.name)
+		if c.converted >= 0 {
+			meta.i32Field(6, c.converted)
+		}
+		meta.end()
+	}
+
+	meta.i64Field(3, int64(rows))
+
+	var total int64
+	for _, ch := range chunks {
+		total += ch.size
+	}
+	meta.listField(4, compactStruct, 1)
+	meta.begin()
+	meta.listField(1, compactStruct, len(columns))
+	for i, c := range columns {
+		meta.begin()
+		meta.i64Field(2, chunks[i].offset)
+		meta.structField(3)
+		meta.i32Field(1, c.physical)
+		meta.listField(2, compactI32, 2)
+		meta.varint(zigzag(encodingPlain))
+		meta.varint(zigzag(encodingRLE))
+		meta.listField(3, compactBinary, 1)
+		meta.bytes(c.name)
+		meta.i32Field(4, codecUncompressed)
+		meta.i64Field(5, int64(rows))
+		meta.i64Field(6, chunks[i].size)
+		meta.i64Field(7, chunks[i].size)
+		meta.i64Field(9, chunks[i].offset)
+		meta.end()
+		meta.end()
+	}
+	meta.i64Field(2, total)
+	meta.i64Field(3, int64(rows))
+	meta.end()
+
+	meta.stringField(6, createdBy)
+	meta.end()
+
+	buf.Write(meta.buf.Bytes())
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(meta.buf.Len()))
+	buf.Write(size[:])
+	buf.WriteString(magic)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
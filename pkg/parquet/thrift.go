@@ -0,0 +1,97 @@
+// pkg/parquet/thrift.go
+package parquet
+
+import "bytes"
+
+// Thrift compact protocol types used by Parquet metadata
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compact writes Thrift structs with the compact protocol, which Parquet
+// uses for page headers and file metadata. Structs are opened with begin
+// and closed with end; fields must be written in increasing ID order.
+type compact struct {
+	buf  bytes.Buffer
+	last []int16 // ID of the last field written, per open struct
+}
+
+// begin opens a struct, as a list element or the top-level value
+func (c *compact) begin() {
+	c.last = append(c.last, 0)
+}
+
+// end closes the innermost open struct
+func (c *compact) end() {
+	c.buf.WriteByte(0)
+	c.last = c.last[:len(c.last)-1]
+}
+
+// field writes a field header, as a delta from the previous field when short
+func (c *compact) field(id int16, kind byte) {
+	last := &c.last[len(c.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		c.buf.WriteByte(byte(delta)<<4 | kind)
+	} else {
+		c.buf.WriteByte(kind)
+		c.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (c *compact) i32Field(id int16, v int32) {
+	c.field(id, compactI32)
+	c.varint(zigzag(int64(v)))
+}
+
+func (c *compact) i64Field(id int16, v int64) {
+	c.field(id, compactI64)
+	c.varint(zigzag(v))
+}
+
+func (c *compact) stringField(id int16, s string) {
+	c.field(id, compactBinary)
+	c.bytes(s)
+}
+
+// structField opens a struct-valued field; close it with end
+func (c *compact) structField(id int16) {
+	c.field(id, compactStruct)
+	c.begin()
+}
+
+// listField writes the header of a list of n elements of kind, which
+// follow: varints for integers, bytes for strings, begin and end for structs
+func (c *compact) listField(id int16, kind byte, n int) {
+	c.field(id, compactList)
+	if n < 15 {
+		c.buf.WriteByte(byte(n)<<4 | kind)
+		return
+	}
+	c.buf.WriteByte(0xf0 | kind)
+	c.varint(uint64(n))
+}
+
+// bytes writes a length-prefixed string
+func (c *compact) bytes(s string) {
+	c.varint(uint64(len(s)))
+	c.buf.WriteString(s)
+}
+
+// varint writes an unsigned LEB128 integer
+func (c *compact) varint(v uint64) {
+	for v >= 0x80 {
+		c.buf.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+	c.buf.WriteByte(byte(v))
+}
+
+// zigzag maps signed integers to unsigned so small magnitudes stay short
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
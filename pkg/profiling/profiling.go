@@ -0,0 +1,64 @@
+// pkg/profiling/profiling.go
+package profiling
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/buildinfo"
+)
+
+// Prefix is the path the debug endpoints are served under
+const Prefix = "/debug/"
+
+func init() {
+	expvar.Publish("build", expvar.Func(func() interface{} { return buildinfo.Get() }))
+	expvar.Publish("goroutines", expvar.Func(func() interface{} { return runtime.NumGoroutine() }))
+}
+
+// EnabledFromEnv reports whether DEBUG_ENDPOINTS turns the debug endpoints on
+func EnabledFromEnv() bool {
+	return os.Getenv("DEBUG_ENDPOINTS") == "true"
+}
+
+// Guard serves pprof profiles under /debug/pprof/ and expvar variables at
+// /debug/vars when enabled and authorize admits the caller, and passes other
+// paths to next. Every /debug/ path is answered here, so the handlers that
+// net/http/pprof and expvar register on http.DefaultServeMux are never
+// reachable without the flag and the check. Debug responses have no write
+// deadline, so CPU profiles and traces may run longer than the server's write
+// timeout.
+func Guard(next http.Handler, enabled bool, authorize func(http.ResponseWriter, *http.Request) bool) http.Handler {
+	debug := http.NewServeMux()
+	debug.HandleFunc("/debug/pprof/", pprof.Index)
+	debug.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	debug.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	debug.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	debug.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	debug.Handle("/debug/vars", expvar.Handler())
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, Prefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !enabled {
+			http.NotFound(w, r)
+			return
+		}
+		if !authorize(w, r) {
+			return
+		}
+		// Hiding the server stops pprof rejecting durations over its
+		// write timeout; the deadline itself is cleared here
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		r = r.WithContext(context.WithValue(r.Context(), http.ServerContextKey, nil))
+		debug.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,408 @@
+// pkg/recommendation/recommendation.go
+package recommendation
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// Lifecycle statuses. A recommendation starts open, is filled when acted on
+// and ends closed (exited before expiration) or expired.
+const (
+	StatusOpen    = "open"
+	StatusFilled  = "filled"
+	StatusExpired = "expired"
+	StatusClosed  = "closed"
+)
+
+// Outcome results
+const (
+	ResultWin       = "win"
+	ResultLoss      = "loss"
+	ResultBreakeven = "breakeven"
+	ResultUnfilled  = "unfilled" // Expired without being acted on
+)
+
+// contractMultiplier is the number of shares per option contract
+const contractMultiplier = 100
+
+// Recommendation is an options recommendation and its lifecycle
+type Recommendation struct {
+	ID             string       `json:"id"`
+	Ticker         string       `json:"ticker"`
+	Strategy       string       `json:"strategy,omitempty"`
+	Date           string       `json:"date"` // Signal bar that produced the recommendation
+	SignalType     string       `json:"signal_type"`
+	StockPrice     float64      `json:"stock_price"`
+	Stoploss       float64      `json:"stoploss"`
+	OptionType     string       `json:"option_type"` // CALL or PUT
+	Strike         float64      `json:"strike"`
+	Expiration     string       `json:"expiration"` // YYYY-MM-DD
+	SuggestedPrice float64      `json:"suggested_price"`
+	Status         string       `json:"status"`
+	FillPrice      float64      `json:"fill_price,omitempty"`
+	FilledAt       *time.Time   `json:"filled_at,omitempty"`
+	Outcome        *Outcome     `json:"outcome,omitempty"`
+	History        []Transition `json:"history"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// Outcome is the result of a recommendation, per contract. SuggestedPnL is
+// what the trade would have made at the suggested price, so slippage shows
+// as the difference from PnL.
+type Outcome struct {
+	Result       string    `json:"result"`
+	ExitPrice    float64   `json:"exit_price"`
+	PnL          float64   `json:"pnl"`
+	PnLPct       float64   `json:"pnl_pct"`
+	SuggestedPnL float64   `json:"suggested_pnl"`
+	At           time.Time `json:"at"`
+}
+
+// Transition records a status change
+type Transition struct {
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+	Note   string    `json:"note,omitempty"`
+}
+
+// Stats summarizes how recommendations turned out
+type Stats struct {
+	Total        int            `json:"total"`
+	ByStatus     map[string]int `json:"by_status"`
+	Wins         int            `json:"wins"`
+	Losses       int            `json:"losses"`
+	Breakeven    int            `json:"breakeven"`
+	Unfilled     int            `json:"unfilled"`
+	WinRate      float64        `json:"win_rate"` // Of recommendations with a profit or loss
+	FillRate     float64        `json:"fill_rate"`
+	TotalPnL     float64        `json:"total_pnl"`
+	AvgPnLPct    float64        `json:"avg_pnl_pct"`
+	SuggestedPnL float64        `json:"suggested_pnl"`
+}
+
+// Query filters recommendations. Zero values match everything.
+type Query struct {
+	Ticker   string
+	Strategy string
+	Status   string
+}
+
+// ID derives a stable ID from the fields identifying a recommendation, so
+// the same suggestion returned by repeated queries is tracked once
+func ID(r Recommendation) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s|%.4f|%s", market.NormalizeTicker(r.Ticker), r.Strategy, r.Date,
+		strings.ToUpper(r.SignalType), strings.ToUpper(r.OptionType), r.Strike, r.Expiration)
+	return fmt.Sprintf("rec-%016x", h.Sum64())
+}
+
+// ExpiresAt returns when the option expires: the close on its expiration date
+func (r Recommendation) ExpiresAt() (time.Time, error) {
+	day, err := time.ParseInLocation(market.SessionDateLayout, r.Expiration, market.ExchangeLocation())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiration %q", r.Expiration)
+	}
+	return day.Add(16 * time.Hour), nil
+}
+
+// IntrinsicValue returns the option's value at expiration for an underlying price
+func (r Recommendation) IntrinsicValue(underlying float64) float64 {
+	if strings.EqualFold(r.OptionType, "PUT") {
+		return math.Max(0, r.Strike-underlying)
+	}
+	return math.Max(0, underlying-r.Strike)
+}
+
+// Store keeps recommendations in memory and optionally persists them to a JSON file
+type Store struct {
+	mu      sync.RWMutex
+	records map[string]Recommendation
+	path    string
+}
+
+// NewStore creates a recommendation store. If path is non-empty, existing
+// recommendations are loaded from it and every change is written back.
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		records: make(map[string]Recommendation),
+		path:    path,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recommendations file: %w", err)
+	}
+
+	var records []Recommendation
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse recommendations file: %w", err)
+	}
+	for _, r := range records {
+		s.records[r.ID] = r
+	}
+	return s, nil
+}
+
+// Track starts tracking a recommendation as open, or returns the tracked one
+// if the same recommendation was seen before. created reports whether the
+// recommendation is new.
+func (s *Store) Track(r Recommendation) (tracked Recommendation, created bool, err error) {
+	if r.Ticker == "" {
+		return Recommendation{}, false, fmt.Errorf("ticker is required")
+	}
+	r.Ticker = market.NormalizeTicker(r.Ticker)
+	r.ID = ID(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[r.ID]; ok {
+		return existing, false, nil
+	}
+
+	now := time.Now()
+	r.Status = StatusOpen
+	r.History = []Transition{{Status: StatusOpen, At: now}}
+	r.CreatedAt = now
+	r.UpdatedAt = now
+	s.records[r.ID] = r
+	return r, true, s.persist()
+}
+
+// Fill records that a recommendation was acted on at price
+func (s *Store) Fill(id string, price float64, at time.Time) (Recommendation, error) {
+	if price <= 0 {
+		return Recommendation{}, fmt.Errorf("fill price must be positive")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[id]
+	if !ok {
+		return Recommendation{}, fmt.Errorf("recommendation %s not found", id)
+	}
+	if r.Status != StatusOpen {
+		return Recommendation{}, fmt.Errorf("recommendation %s is %s, not open", id, r.Status)
+	}
+
+	r.FillPrice = price
+	r.FilledAt = &at
+	s.transition(&r, StatusFilled, at, fmt.Sprintf("filled at %.2f", price))
+	return r, s.persist()
+}
+
+// Close records the exit of a filled recommendation at price
+func (s *Store) Close(id string, price float64, at time.Time) (Recommendation, error) {
+	if price < 0 {
+		return Recommendation{}, fmt.Errorf("exit price must not be negative")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[id]
+	if !ok {
+		return Recommendation{}, fmt.Errorf("recommendation %s not found", id)
+	}
+	if r.Status != StatusFilled {
+		return Recommendation{}, fmt.Errorf("recommendation %s is %s, not filled", id, r.Status)
+	}
+
+	r.Outcome = outcome(r, price, at)
+	s.transition(&r, StatusClosed, at, fmt.Sprintf("closed at %.2f", price))
+	return r, s.persist()
+}
+
+// Expire ends every open or filled recommendation whose option expired by
+// now. Filled ones settle at intrinsic value, using underlying to look up
+// the closing price of the ticker on its expiration date; those without a
+// price yet are left for a later run.
+func (s *Store) Expire(now time.Time, underlying func(ticker string, expiration time.Time) (float64, bool)) ([]Recommendation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []Recommendation
+	for id, r := range s.records {
+		if r.Status != StatusOpen && r.Status != StatusFilled {
+			continue
+		}
+		expiresAt, err := r.ExpiresAt()
+		if err != nil || now.Before(expiresAt) {
+			continue
+		}
+
+		if r.Status == StatusOpen {
+			r.Outcome = &Outcome{Result: ResultUnfilled, At: expiresAt}
+			s.transition(&r, StatusExpired, expiresAt, "expired unfilled")
+		} else {
+			price, ok := underlying(r.Ticker, expiresAt)
+			if !ok {
+				continue
+			}
+			value := r.IntrinsicValue(price)
+			r.Outcome = outcome(r, value, expiresAt)
+			s.transition(&r, StatusExpired, expiresAt,
+				fmt.Sprintf("settled at %.2f with %s at %.2f", value, r.Ticker, price))
+		}
+		s.records[id] = r
+		expired = append(expired, r)
+	}
+
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	return expired, s.persist()
+}
+
+// Get returns a single recommendation
+func (s *Store) Get(id string) (Recommendation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r, ok := s.records[id]
+	return r, ok
+}
+
+// Find returns recommendations matching the query, newest first
+func (s *Store) Find(q Query) []Recommendation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]Recommendation, 0)
+	for _, r := range s.records {
+		if q.Ticker != "" && !strings.EqualFold(r.Ticker, q.Ticker) {
+			continue
+		}
+		if q.Strategy != "" && !strings.EqualFold(r.Strategy, q.Strategy) {
+			continue
+		}
+		if q.Status != "" && r.Status != q.Status {
+			continue
+		}
+		results = append(results, r)
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].CreatedAt.After(results[j].CreatedAt) })
+	return results
+}
+
+// Summarize computes accuracy stats for recommendations
+func Summarize(records []Recommendation) Stats {
+	stats := Stats{ByStatus: make(map[string]int)}
+	var filled, decided int
+	var pnlPct float64
+	for _, r := range records {
+		stats.Total++
+		stats.ByStatus[r.Status]++
+		if r.FillPrice > 0 {
+			filled++
+		}
+		if r.Outcome == nil {
+			continue
+		}
+
+		switch r.Outcome.Result {
+		case ResultWin:
+			stats.Wins++
+		case ResultLoss:
+			stats.Losses++
+		case ResultBreakeven:
+			stats.Breakeven++
+		case ResultUnfilled:
+			stats.Unfilled++
+			continue
+		}
+		decided++
+		stats.TotalPnL += r.Outcome.PnL
+		stats.SuggestedPnL += r.Outcome.SuggestedPnL
+		pnlPct += r.Outcome.PnLPct
+	}
+
+	if stats.Total > 0 {
+		stats.FillRate = float64(filled) / float64(stats.Total)
+	}
+	if decided > 0 {
+		stats.WinRate = float64(stats.Wins) / float64(decided)
+		stats.AvgPnLPct = pnlPct / float64(decided)
+	}
+	return stats
+}
+
+// outcome computes the per-contract result of exiting a filled recommendation at exit
+func outcome(r Recommendation, exit float64, at time.Time) *Outcome {
+	o := &Outcome{
+		ExitPrice:    exit,
+		PnL:          (exit - r.FillPrice) * contractMultiplier,
+		SuggestedPnL: (exit - r.SuggestedPrice) * contractMultiplier,
+		At:           at,
+	}
+	if r.FillPrice > 0 {
+		o.PnLPct = (exit - r.FillPrice) / r.FillPrice * 100
+	}
+
+	switch {
+	case o.PnL > 0:
+		o.Result = ResultWin
+	case o.PnL < 0:
+		o.Result = ResultLoss
+	default:
+		o.Result = ResultBreakeven
+	}
+	return o
+}
+
+// transition moves r to status and stores it. Caller holds the lock.
+func (s *Store) transition(r *Recommendation, status string, at time.Time, note string) {
+	r.Status = status
+	r.History = append(r.History, Transition{Status: status, At: at, Note: note})
+	r.UpdatedAt = time.Now()
+	s.records[r.ID] = *r
+}
+
+// persist writes all recommendations to the file. Caller holds the lock.
+func (s *Store) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	records := make([]Recommendation, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt.Before(records[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create recommendations directory: %w", err)
+	}
+
+	// Write atomically so a crash never leaves a truncated file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write recommendations file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,39 @@
+// pkg/reference/builtin.go
+package reference
+
+// builtin seeds the store with common large caps so sector limits and scans work
+// without a data file. Index membership changes over time; load a current
+// constituents file with REFERENCE_DATA_PATH for complete coverage.
+var builtin = []Classification{
+	{Ticker: "AAPL", Name: "Apple Inc.", Sector: "Information Technology", Industry: "Technology Hardware, Storage & Peripherals", Indexes: []string{IndexSP500, IndexNasdaq100, IndexDJIA}},
+	{Ticker: "MSFT", Name: "Microsoft Corporation", Sector: "Information Technology", Industry: "Software", Indexes: []string{IndexSP500, IndexNasdaq100, IndexDJIA}},
+	{Ticker: "NVDA", Name: "NVIDIA Corporation", Sector: "Information Technology", Industry: "Semiconductors & Semiconductor Equipment", Indexes: []string{IndexSP500, IndexNasdaq100, IndexDJIA}},
+	{Ticker: "AVGO", Name: "Broadcom Inc.", Sector: "Information Technology", Industry: "Semiconductors & Semiconductor Equipment", Indexes: []string{IndexSP500, IndexNasdaq100}},
+	{Ticker: "AMD", Name: "Advanced Micro Devices, Inc.", Sector: "Information Technology", Industry: "Semiconductors & Semiconductor Equipment", Indexes: []string{IndexSP500, IndexNasdaq100}},
+	{Ticker: "INTC", Name: "Intel Corporation", Sector: "Information Technology", Industry: "Semiconductors & Semiconductor Equipment", Indexes: []string{IndexSP500, IndexNasdaq100}},
+	{Ticker: "GOOGL", Name: "Alphabet Inc. Class A", Sector: "Communication Services", Industry: "Interactive Media & Services", Indexes: []string{IndexSP500, IndexNasdaq100}},
+	{Ticker: "GOOG", Name: "Alphabet Inc. Class C", Sector: "Communication Services", Industry: "Interactive Media & Services", Indexes: []string{IndexSP500, IndexNasdaq100}},
+	{Ticker: "META", Name: "Meta Platforms, Inc.", Sector: "Communication Services", Industry: "Interactive Media & Services", Indexes: []string{IndexSP500, IndexNasdaq100}},
+	{Ticker: "NFLX", Name: "Netflix, Inc.", Sector: "Communication Services", Industry: "Entertainment", Indexes: []string{IndexSP500, IndexNasdaq100}},
+	{Ticker: "DIS", Name: "The Walt Disney Company", Sector: "Communication Services", Industry: "Entertainment", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "AMZN", Name: "Amazon.com, Inc.", Sector: "Consumer Discretionary", Industry: "Broadline Retail", Indexes: []string{IndexSP500, IndexNasdaq100, IndexDJIA}},
+	{Ticker: "TSLA", Name: "Tesla, Inc.", Sector: "Consumer Discretionary", Industry: "Automobiles", Indexes: []string{IndexSP500, IndexNasdaq100}},
+	{Ticker: "HD", Name: "The Home Depot, Inc.", Sector: "Consumer Discretionary", Industry: "Specialty Retail", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "PG", Name: "The Procter & Gamble Company", Sector: "Consumer Staples", Industry: "Household Products", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "KO", Name: "The Coca-Cola Company", Sector: "Consumer Staples", Industry: "Beverages", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "PEP", Name: "PepsiCo, Inc.", Sector: "Consumer Staples", Industry: "Beverages", Indexes: []string{IndexSP500, IndexNasdaq100}},
+	{Ticker: "COST", Name: "Costco Wholesale Corporation", Sector: "Consumer Staples", Industry: "Consumer Staples Distribution & Retail", Indexes: []string{IndexSP500, IndexNasdaq100}},
+	{Ticker: "WMT", Name: "Walmart Inc.", Sector: "Consumer Staples", Industry: "Consumer Staples Distribution & Retail", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "JPM", Name: "JPMorgan Chase & Co.", Sector: "Financials", Industry: "Banks", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "GS", Name: "The Goldman Sachs Group, Inc.", Sector: "Financials", Industry: "Capital Markets", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "V", Name: "Visa Inc.", Sector: "Financials", Industry: "Financial Services", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "MA", Name: "Mastercard Incorporated", Sector: "Financials", Industry: "Financial Services", Indexes: []string{IndexSP500}},
+	{Ticker: "JNJ", Name: "Johnson & Johnson", Sector: "Health Care", Industry: "Pharmaceuticals", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "LLY", Name: "Eli Lilly and Company", Sector: "Health Care", Industry: "Pharmaceuticals", Indexes: []string{IndexSP500}},
+	{Ticker: "UNH", Name: "UnitedHealth Group Incorporated", Sector: "Health Care", Industry: "Health Care Providers & Services", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "XOM", Name: "Exxon Mobil Corporation", Sector: "Energy", Industry: "Oil, Gas & Consumable Fuels", Indexes: []string{IndexSP500}},
+	{Ticker: "CVX", Name: "Chevron Corporation", Sector: "Energy", Industry: "Oil, Gas & Consumable Fuels", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "BA", Name: "The Boeing Company", Sector: "Industrials", Industry: "Aerospace & Defense", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "CAT", Name: "Caterpillar Inc.", Sector: "Industrials", Industry: "Machinery", Indexes: []string{IndexSP500, IndexDJIA}},
+	{Ticker: "NEE", Name: "NextEra Energy, Inc.", Sector: "Utilities", Industry: "Electric Utilities", Indexes: []string{IndexSP500}},
+}
@@ -0,0 +1,83 @@
+// pkg/reference/exposure.go
+package reference
+
+import "sort"
+
+// Unclassified groups tickers without reference data
+const Unclassified = "unknown"
+
+// ExposureGroup is the exposure held in one sector, industry or index
+type ExposureGroup struct {
+	Name     string   `json:"name"`
+	Exposure float64  `json:"exposure"`
+	Weight   float64  `json:"weight"` // Share of total exposure
+	Tickers  []string `json:"tickers"`
+}
+
+// ExposureBreakdown splits portfolio exposure by classification. A ticker can
+// belong to several indexes, so index weights need not sum to one.
+type ExposureBreakdown struct {
+	Total      float64         `json:"total"`
+	Sectors    []ExposureGroup `json:"sectors"`
+	Industries []ExposureGroup `json:"industries"`
+	Indexes    []ExposureGroup `json:"indexes"`
+}
+
+// Breakdown groups exposures by ticker into sectors, industries and indexes,
+// largest first
+func (s *Store) Breakdown(exposures map[string]float64) ExposureBreakdown {
+	sectors := make(map[string]*ExposureGroup)
+	industries := make(map[string]*ExposureGroup)
+	indexes := make(map[string]*ExposureGroup)
+
+	add := func(groups map[string]*ExposureGroup, name, ticker string, exposure float64) {
+		group, ok := groups[name]
+		if !ok {
+			group = &ExposureGroup{Name: name, Tickers: []string{}}
+			groups[name] = group
+		}
+		group.Exposure += exposure
+		group.Tickers = append(group.Tickers, ticker)
+	}
+
+	var breakdown ExposureBreakdown
+	for ticker, exposure := range exposures {
+		breakdown.Total += exposure
+
+		c, ok := s.Get(ticker)
+		if !ok {
+			add(sectors, Unclassified, ticker, exposure)
+			add(industries, Unclassified, ticker, exposure)
+			continue
+		}
+		add(sectors, c.Sector, ticker, exposure)
+		add(industries, c.Industry, ticker, exposure)
+		for _, index := range c.Indexes {
+			add(indexes, index, ticker, exposure)
+		}
+	}
+
+	breakdown.Sectors = sortedGroups(sectors, breakdown.Total)
+	breakdown.Industries = sortedGroups(industries, breakdown.Total)
+	breakdown.Indexes = sortedGroups(indexes, breakdown.Total)
+	return breakdown
+}
+
+// sortedGroups computes weights and orders groups by exposure, largest first
+func sortedGroups(groups map[string]*ExposureGroup, total float64) []ExposureGroup {
+	result := make([]ExposureGroup, 0, len(groups))
+	for _, group := range groups {
+		if total > 0 {
+			group.Weight = group.Exposure / total
+		}
+		sort.Strings(group.Tickers)
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Exposure != result[j].Exposure {
+			return result[i].Exposure > result[j].Exposure
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,236 @@
+// pkg/reference/reference.go
+package reference
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Index identifiers used for membership
+const (
+	IndexSP500     = "SP500"
+	IndexNasdaq100 = "NASDAQ100"
+	IndexDJIA      = "DJIA"
+)
+
+// Classification is the sector, industry and index membership of a ticker
+type Classification struct {
+	Ticker   string   `json:"ticker"`
+	Name     string   `json:"name"`
+	Sector   string   `json:"sector"`
+	Industry string   `json:"industry"`
+	Indexes  []string `json:"indexes"`
+}
+
+// InIndex reports whether the ticker is a member of an index
+func (c Classification) InIndex(index string) bool {
+	for _, member := range c.Indexes {
+		if strings.EqualFold(member, index) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter selects classifications. Zero values match everything.
+type Filter struct {
+	Sector   string
+	Industry string
+	Index    string
+}
+
+// matches reports whether a classification passes the filter
+func (f Filter) matches(c Classification) bool {
+	if f.Sector != "" && !strings.EqualFold(c.Sector, f.Sector) {
+		return false
+	}
+	if f.Industry != "" && !strings.EqualFold(c.Industry, f.Industry) {
+		return false
+	}
+	if f.Index != "" && !c.InIndex(f.Index) {
+		return false
+	}
+	return true
+}
+
+// SectorSummary lists the industries and number of tickers in a sector
+type SectorSummary struct {
+	Sector     string   `json:"sector"`
+	Tickers    int      `json:"tickers"`
+	Industries []string `json:"industries"`
+}
+
+// Store holds reference classifications keyed by ticker
+type Store struct {
+	mu      sync.RWMutex
+	entries map[string]Classification
+}
+
+// NewStore creates a store seeded with the built-in classifications
+func NewStore() *Store {
+	s := &Store{entries: make(map[string]Classification)}
+	for _, c := range builtin {
+		s.entries[c.Ticker] = c
+	}
+	return s
+}
+
+// LoadFile merges classifications from a JSON array or a CSV file with the
+// header ticker,name,sector,industry,indexes (indexes separated by "|").
+// Entries in the file replace built-in entries for the same ticker.
+func (s *Store) LoadFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open reference data: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Classification
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		entries, err = parseCSV(f)
+	} else {
+		err = json.NewDecoder(f).Decode(&entries)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse reference data: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loaded := 0
+	for _, c := range entries {
+		c.Ticker = strings.ToUpper(strings.TrimSpace(c.Ticker))
+		if c.Ticker == "" {
+			continue
+		}
+		if c.Indexes == nil {
+			c.Indexes = []string{}
+		}
+		s.entries[c.Ticker] = c
+		loaded++
+	}
+	return loaded, nil
+}
+
+// parseCSV reads classifications from CSV with a header row
+func parseCSV(r io.Reader) ([]Classification, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["ticker"]; !ok {
+		return nil, fmt.Errorf("missing ticker column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var entries []Classification
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		c := Classification{
+			Ticker:   field(record, "ticker"),
+			Name:     field(record, "name"),
+			Sector:   field(record, "sector"),
+			Industry: field(record, "industry"),
+			Indexes:  []string{},
+		}
+		for _, index := range strings.Split(field(record, "indexes"), "|") {
+			if index = strings.TrimSpace(index); index != "" {
+				c.Indexes = append(c.Indexes, index)
+			}
+		}
+		entries = append(entries, c)
+	}
+	return entries, nil
+}
+
+// Get returns the classification for a ticker
+func (s *Store) Get(ticker string) (Classification, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.entries[strings.ToUpper(ticker)]
+	return c, ok
+}
+
+// List returns the classifications matching a filter, sorted by ticker
+func (s *Store) List(filter Filter) []Classification {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []Classification{}
+	for _, c := range s.entries {
+		if filter.matches(c) {
+			result = append(result, c)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Ticker < result[j].Ticker })
+	return result
+}
+
+// Tickers returns the tickers matching a filter, sorted
+func (s *Store) Tickers(filter Filter) []string {
+	entries := s.List(filter)
+	tickers := make([]string, len(entries))
+	for i, c := range entries {
+		tickers[i] = c.Ticker
+	}
+	return tickers
+}
+
+// Sectors summarizes the sectors in the store, sorted by name
+func (s *Store) Sectors() []SectorSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bySector := make(map[string]*SectorSummary)
+	industries := make(map[string]map[string]bool)
+	for _, c := range s.entries {
+		summary, ok := bySector[c.Sector]
+		if !ok {
+			summary = &SectorSummary{Sector: c.Sector, Industries: []string{}}
+			bySector[c.Sector] = summary
+			industries[c.Sector] = make(map[string]bool)
+		}
+		summary.Tickers++
+		if c.Industry != "" && !industries[c.Sector][c.Industry] {
+			industries[c.Sector][c.Industry] = true
+			summary.Industries = append(summary.Industries, c.Industry)
+		}
+	}
+
+	result := make([]SectorSummary, 0, len(bySector))
+	for _, summary := range bySector {
+		sort.Strings(summary.Industries)
+		result = append(result, *summary)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Sector < result[j].Sector })
+	return result
+}
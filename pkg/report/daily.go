@@ -0,0 +1,213 @@
+// pkg/report/daily.go
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DateLayout is the format of report dates
+const DateLayout = "2006-01-02"
+
+// TickerSummary is the daily bar and change for one ticker
+type TickerSummary struct {
+	Ticker    string  `json:"ticker"`
+	Open      float64 `json:"open"`
+	High      float64 `json:"high"`
+	Low       float64 `json:"low"`
+	Close     float64 `json:"close"`
+	Volume    float64 `json:"volume"`
+	PrevClose float64 `json:"prev_close,omitempty"`
+	Change    float64 `json:"change"`
+	ChangePct float64 `json:"change_pct"`
+	Error     string  `json:"error,omitempty"` // Set when data for the ticker was unavailable
+}
+
+// SignalEntry is a signal triggered during the day
+type SignalEntry struct {
+	Ticker     string  `json:"ticker"`
+	Strategy   string  `json:"strategy"`
+	Date       string  `json:"date"`
+	SignalType string  `json:"signal_type"`
+	EntryPrice float64 `json:"entry_price"`
+	Stoploss   float64 `json:"stoploss"`
+}
+
+// PaperTrade is a paper position closed during the day
+type PaperTrade struct {
+	Ticker     string  `json:"ticker"`
+	Direction  string  `json:"direction"`
+	Quantity   int     `json:"quantity"`
+	EntryPrice float64 `json:"entry_price"`
+	ExitPrice  float64 `json:"exit_price"`
+	PnL        float64 `json:"pnl"`
+}
+
+// PaperSummary summarizes paper-trading activity for the day
+type PaperSummary struct {
+	RealizedPnL   float64      `json:"realized_pnl"`
+	UnrealizedPnL float64      `json:"unrealized_pnl"` // Open positions marked at the day's close
+	OpenPositions int          `json:"open_positions"`
+	ClosedTrades  []PaperTrade `json:"closed_trades"`
+}
+
+// Daily is the end-of-day report
+type Daily struct {
+	Date        string          `json:"date"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Tickers     []TickerSummary `json:"tickers"`
+	Signals     []SignalEntry   `json:"signals"`
+	Paper       PaperSummary    `json:"paper"`
+}
+
+// Store persists daily reports as JSON files in a directory
+type Store struct {
+	dir string
+}
+
+// NewStore creates a report store rooted at dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Save writes a report, replacing any earlier report for the same date
+func (s *Store) Save(r *Daily) error {
+	if _, err := time.Parse(DateLayout, r.Date); err != nil {
+		return fmt.Errorf("invalid report date %q", r.Date)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	path := s.path(r.Date)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads the report for a date
+func (s *Store) Load(date string) (*Daily, error) {
+	if _, err := time.Parse(DateLayout, date); err != nil {
+		return nil, fmt.Errorf("invalid report date %q", date)
+	}
+
+	data, err := os.ReadFile(s.path(date))
+	if err != nil {
+		return nil, err
+	}
+
+	var r Daily
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse report: %w", err)
+	}
+	return &r, nil
+}
+
+// Dates lists the dates with stored reports, newest first
+func (s *Store) Dates() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.dir, "daily-*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make([]string, 0, len(matches))
+	for _, match := range matches {
+		date := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), "daily-"), ".json")
+		if _, err := time.Parse(DateLayout, date); err == nil {
+			dates = append(dates, date)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(dates)))
+	return dates, nil
+}
+
+// path returns the file a report for date is stored in
+func (s *Store) path(date string) string {
+	return filepath.Join(s.dir, "daily-"+date+".json")
+}
+
+// dailyTemplate renders a report as a standalone HTML page
+var dailyTemplate = template.Must(template.New("daily").Funcs(template.FuncMap{
+	"money": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"pct":   func(v float64) string { return fmt.Sprintf("%+.2f%%", v) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>TradingLab daily report {{.Date}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.up { color: #087f23; } .down { color: #b71c1c; }
+</style>
+</head>
+<body>
+<h1>Daily report {{.Date}}</h1>
+<p>Generated {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+
+<h2>Market summary</h2>
+<table>
+<tr><th>Ticker</th><th>Open</th><th>High</th><th>Low</th><th>Close</th><th>Change</th><th>Volume</th></tr>
+{{range .Tickers}}{{if .Error}}<tr><td>{{.Ticker}}</td><td colspan="6">{{.Error}}</td></tr>
+{{else}}<tr><td>{{.Ticker}}</td><td>{{money .Open}}</td><td>{{money .High}}</td><td>{{money .Low}}</td><td>{{money .Close}}</td><td class="{{if ge .Change 0.0}}up{{else}}down{{end}}">{{money .Change}} ({{pct .ChangePct}})</td><td>{{printf "%.0f" .Volume}}</td></tr>
+{{end}}{{end}}</table>
+
+<h2>Signals</h2>
+{{if .Signals}}<table>
+<tr><th>Ticker</th><th>Strategy</th><th>Time</th><th>Type</th><th>Entry</th><th>Stoploss</th></tr>
+{{range .Signals}}<tr><td>{{.Ticker}}</td><td>{{.Strategy}}</td><td>{{.Date}}</td><td>{{.SignalType}}</td><td>{{money .EntryPrice}}</td><td>{{money .Stoploss}}</td></tr>
+{{end}}</table>
+{{else}}<p>No signals triggered.</p>
+{{end}}
+<h2>Paper trading</h2>
+<p>Realized P&amp;L: {{money .Paper.RealizedPnL}} &middot; Unrealized P&amp;L: {{money .Paper.UnrealizedPnL}} &middot; Open positions: {{.Paper.OpenPositions}}</p>
+{{if .Paper.ClosedTrades}}<table>
+<tr><th>Ticker</th><th>Direction</th><th>Quantity</th><th>Entry</th><th>Exit</th><th>P&amp;L</th></tr>
+{{range .Paper.ClosedTrades}}<tr><td>{{.Ticker}}</td><td>{{.Direction}}</td><td>{{.Quantity}}</td><td>{{money .EntryPrice}}</td><td>{{money .ExitPrice}}</td><td class="{{if ge .PnL 0.0}}up{{else}}down{{end}}">{{money .PnL}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// RenderHTML renders a report as an HTML page
+func RenderHTML(r *Daily) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := dailyTemplate.Execute(&buf, r); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Text returns a short plain text summary of a report
+func Text(r *Daily) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Daily report %s\n\n", r.Date)
+	for _, t := range r.Tickers {
+		if t.Error != "" {
+			fmt.Fprintf(&b, "%-6s unavailable\n", t.Ticker)
+			continue
+		}
+		fmt.Fprintf(&b, "%-6s %10.2f %+7.2f%%\n", t.Ticker, t.Close, t.ChangePct)
+	}
+	fmt.Fprintf(&b, "\nSignals: %d\n", len(r.Signals))
+	fmt.Fprintf(&b, "Paper realized P&L: %.2f, unrealized: %.2f, open positions: %d\n",
+		r.Paper.RealizedPnL, r.Paper.UnrealizedPnL, r.Paper.OpenPositions)
+	return b.String()
+}
@@ -0,0 +1,59 @@
+// pkg/risk/costs.go
+package risk
+
+import (
+	"fmt"
+	"math"
+)
+
+// CostModel describes the friction of executing an order. The zero value
+// fills every order in full at the quoted price without commission.
+type CostModel struct {
+	CommissionFixed   float64 `json:"commission_fixed"`   // Dollars per order
+	CommissionPercent float64 `json:"commission_percent"` // Percent of the order's notional
+	SpreadPercent     float64 `json:"spread_percent"`     // Bid-ask spread as a percent of price; orders cross half of it
+	MaxVolumePercent  float64 `json:"max_volume_percent"` // Largest fill as a percent of the bar's volume; zero fills in full
+}
+
+// Fill is the modeled execution of an order
+type Fill struct {
+	Price      float64 `json:"price"`      // Quoted price moved against the order by half the spread
+	Quantity   int     `json:"quantity"`   // Filled quantity, at most the order's
+	Commission float64 `json:"commission"` // Dollars
+	Slippage   float64 `json:"slippage"`   // Dollars paid against the quoted price
+}
+
+// Validate checks the model's values are within range
+func (m CostModel) Validate() error {
+	if m.CommissionFixed < 0 || m.CommissionPercent < 0 || m.SpreadPercent < 0 || m.MaxVolumePercent < 0 {
+		return fmt.Errorf("execution costs cannot be negative")
+	}
+	if m.SpreadPercent >= 100 || m.MaxVolumePercent > 100 {
+		return fmt.Errorf("spread_percent must be below 100 and max_volume_percent at most 100")
+	}
+	return nil
+}
+
+// Fill models an order for quantity at the quoted price. buying is true for
+// orders that buy, opening a long or covering a short. volume is the volume
+// of the bar the order fills in, zero when unknown, which leaves the fill
+// uncapped.
+func (m CostModel) Fill(price float64, quantity int, buying bool, volume float64) Fill {
+	fill := Fill{Price: price, Quantity: quantity}
+	if m.MaxVolumePercent > 0 && volume > 0 {
+		fill.Quantity = min(quantity, int(math.Floor(volume*m.MaxVolumePercent/100)))
+	}
+
+	half := price * m.SpreadPercent / 200
+	if buying {
+		fill.Price += half
+	} else {
+		fill.Price -= half
+	}
+	fill.Slippage = half * float64(fill.Quantity)
+
+	if fill.Quantity > 0 {
+		fill.Commission = m.CommissionFixed + fill.Price*float64(fill.Quantity)*m.CommissionPercent/100
+	}
+	return fill
+}
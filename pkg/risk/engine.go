@@ -0,0 +1,427 @@
+// pkg/risk/engine.go
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// Risk event types
+const (
+	EventExposureLimit  = "exposure_limit"
+	EventSectorLimit    = "sector_limit"
+	EventDailyLossLimit = "daily_loss_limit"
+)
+
+// UnknownSector is used for tickers without sector information
+const UnknownSector = "unknown"
+
+// Position represents an open simulated or real position
+type Position struct {
+	ID         string    `json:"id"`
+	Ticker     string    `json:"ticker"`
+	Sector     string    `json:"sector"`
+	Direction  string    `json:"direction"` // LONG or SHORT
+	Quantity   int       `json:"quantity"`
+	EntryPrice float64   `json:"entry_price"`
+	Stoploss   float64   `json:"stoploss,omitempty"`
+	Strategy   string    `json:"strategy,omitempty"`
+	Simulated  bool      `json:"simulated"`
+	OpenedAt   time.Time `json:"opened_at"`
+
+	// Execution of the entry under the engine's cost model. EntryPrice is
+	// the fill price; BarVolume, when given, caps the filled quantity.
+	QuotedPrice       float64 `json:"quoted_price,omitempty"`
+	Commission        float64 `json:"commission,omitempty"`
+	Slippage          float64 `json:"slippage,omitempty"`
+	RequestedQuantity int     `json:"requested_quantity,omitempty"` // Set when the fill was capped
+	BarVolume         float64 `json:"bar_volume,omitempty"`
+}
+
+// Exposure returns the notional value of the position
+func (p Position) Exposure() float64 {
+	return float64(p.Quantity) * p.EntryPrice
+}
+
+// InitialRisk returns the dollar amount at risk based on the stoploss, or zero if unknown
+func (p Position) InitialRisk() float64 {
+	if p.Stoploss <= 0 {
+		return 0
+	}
+	return math.Abs(p.EntryPrice-p.Stoploss) * float64(p.Quantity)
+}
+
+// PnL returns the profit or loss of the position at price
+func (p Position) PnL(price float64) float64 {
+	pnl := (price - p.EntryPrice) * float64(p.Quantity)
+	if p.Direction == DirectionShort {
+		return -pnl
+	}
+	return pnl
+}
+
+// ClosedPosition records a position that has been closed. RealizedPnL is
+// net of entry and exit commissions.
+type ClosedPosition struct {
+	Position
+	ExitPrice       float64   `json:"exit_price"`
+	QuotedExitPrice float64   `json:"quoted_exit_price,omitempty"`
+	ExitCommission  float64   `json:"exit_commission,omitempty"`
+	ExitSlippage    float64   `json:"exit_slippage,omitempty"`
+	RealizedPnL     float64   `json:"realized_pnl"`
+	ClosedAt        time.Time `json:"closed_at"`
+}
+
+// Limits defines portfolio-level risk limits. Zero disables a limit.
+type Limits struct {
+	MaxTickerExposure float64 `json:"max_ticker_exposure"` // Max notional per ticker in dollars
+	MaxSectorExposure float64 `json:"max_sector_exposure"` // Max notional per sector in dollars
+	MaxDailyLoss      float64 `json:"max_daily_loss"`      // Max realized loss per day in dollars (positive number)
+}
+
+// Violation describes a limit that a proposed trade would breach
+type Violation struct {
+	Type    string  `json:"type"`
+	Subject string  `json:"subject"` // Ticker, sector or "portfolio"
+	Limit   float64 `json:"limit"`
+	Value   float64 `json:"value"`
+	Message string  `json:"message"`
+}
+
+// CheckResult is the outcome of a pre-trade risk check
+type CheckResult struct {
+	Allowed    bool        `json:"allowed"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// Event is published when a risk threshold is hit
+type Event struct {
+	Type      string    `json:"type"`
+	Ticker    string    `json:"ticker,omitempty"`
+	Violation Violation `json:"violation"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Status summarizes the current state of the portfolio
+type Status struct {
+	Limits          Limits             `json:"limits"`
+	Costs           CostModel          `json:"costs"`
+	OpenPositions   int                `json:"open_positions"`
+	TotalExposure   float64            `json:"total_exposure"`
+	TickerExposure  map[string]float64 `json:"ticker_exposure"`
+	SectorExposure  map[string]float64 `json:"sector_exposure"`
+	DailyRealized   float64            `json:"daily_realized_pnl"`
+	DailyLossHalted bool               `json:"daily_loss_halted"`
+}
+
+// Engine tracks open positions and enforces portfolio limits
+type Engine struct {
+	mu        sync.Mutex
+	limits    Limits
+	costs     CostModel
+	positions map[string]Position
+	closed    []ClosedPosition
+	sectors   map[string]string
+	daily     float64 // Realized P&L for the current day
+	day       string  // Date the daily P&L applies to
+	nextID    int64
+	onEvent   func(Event)
+	location  *time.Location
+}
+
+// NewEngine creates a risk engine with the given limits
+func NewEngine(limits Limits) *Engine {
+	return &Engine{
+		limits:    limits,
+		positions: make(map[string]Position),
+		sectors:   make(map[string]string),
+		location:  market.ExchangeLocation(),
+	}
+}
+
+// OnEvent registers a callback invoked whenever a risk threshold is hit
+func (e *Engine) OnEvent(handler func(Event)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onEvent = handler
+}
+
+// SetSector assigns a sector to a ticker for sector exposure limits
+func (e *Engine) SetSector(ticker, sector string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sectors[ticker] = sector
+}
+
+// Limits returns the configured limits
+func (e *Engine) Limits() Limits {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.limits
+}
+
+// SetCosts sets the cost model positions are filled under
+func (e *Engine) SetCosts(costs CostModel) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.costs = costs
+}
+
+// Costs returns the cost model positions are filled under
+func (e *Engine) Costs() CostModel {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.costs
+}
+
+// Check evaluates whether opening the given notional exposure in a ticker would breach limits
+func (e *Engine) Check(ticker string, exposure float64) CheckResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rollDay(time.Now())
+	violations := e.violations(ticker, e.sectorOf(ticker), exposure)
+	return CheckResult{
+		Allowed:    len(violations) == 0,
+		Violations: violations,
+	}
+}
+
+// Open records a new position after checking limits. The position is rejected
+// if it would breach any limit. The entry is filled under the cost model,
+// so the recorded price and quantity may differ from the requested ones.
+// A ticker with a known sector keeps it; a position naming another is
+// rejected. A sector given for an unknown ticker is kept once the position
+// is accepted.
+func (e *Engine) Open(pos Position) (Position, CheckResult, error) {
+	e.mu.Lock()
+	now := time.Now()
+	e.rollDay(now)
+
+	if pos.Ticker == "" || pos.Quantity <= 0 || pos.EntryPrice <= 0 {
+		e.mu.Unlock()
+		return Position{}, CheckResult{}, fmt.Errorf("ticker, positive quantity and entry price are required")
+	}
+	if pos.Direction == "" {
+		pos.Direction = DirectionLong
+	}
+	known, isKnown := e.sectors[pos.Ticker]
+	switch {
+	case isKnown && pos.Sector != "" && !strings.EqualFold(pos.Sector, known):
+		e.mu.Unlock()
+		return Position{}, CheckResult{}, fmt.Errorf("%s is in sector %s, not %s", pos.Ticker, known, pos.Sector)
+	case isKnown:
+		pos.Sector = known
+	case pos.Sector == "":
+		pos.Sector = UnknownSector
+	}
+
+	fill := e.costs.Fill(pos.EntryPrice, pos.Quantity, pos.Direction != DirectionShort, pos.BarVolume)
+	if fill.Quantity <= 0 {
+		e.mu.Unlock()
+		return Position{}, CheckResult{}, fmt.Errorf("bar volume of %.0f is too low to fill any quantity", pos.BarVolume)
+	}
+	pos.RequestedQuantity = 0
+	if fill.Quantity < pos.Quantity {
+		pos.RequestedQuantity = pos.Quantity
+	}
+	pos.QuotedPrice, pos.EntryPrice, pos.Quantity = pos.EntryPrice, fill.Price, fill.Quantity
+	pos.Commission, pos.Slippage = fill.Commission, fill.Slippage
+
+	violations := e.violations(pos.Ticker, pos.Sector, pos.Exposure())
+	result := CheckResult{Allowed: len(violations) == 0, Violations: violations}
+	if !result.Allowed {
+		handler := e.onEvent
+		e.mu.Unlock()
+		e.emit(handler, pos.Ticker, violations)
+		return Position{}, result, fmt.Errorf("position would breach risk limits")
+	}
+
+	if !isKnown && pos.Sector != UnknownSector {
+		e.sectors[pos.Ticker] = pos.Sector
+	}
+	e.nextID++
+	pos.ID = fmt.Sprintf("pos-%d-%d", now.Unix(), e.nextID)
+	pos.OpenedAt = now
+	e.positions[pos.ID] = pos
+	e.mu.Unlock()
+
+	return pos, result, nil
+}
+
+// Close closes an open position at the given exit price and records realized
+// P&L. The exit is filled in full under the cost model.
+func (e *Engine) Close(id string, exitPrice float64) (ClosedPosition, error) {
+	e.mu.Lock()
+	now := time.Now()
+	e.rollDay(now)
+
+	pos, exists := e.positions[id]
+	if !exists {
+		e.mu.Unlock()
+		return ClosedPosition{}, fmt.Errorf("position %s not found", id)
+	}
+	delete(e.positions, id)
+
+	fill := e.costs.Fill(exitPrice, pos.Quantity, pos.Direction == DirectionShort, 0)
+	pnl := pos.PnL(fill.Price) - pos.Commission - fill.Commission
+
+	closed := ClosedPosition{
+		Position:        pos,
+		ExitPrice:       fill.Price,
+		QuotedExitPrice: exitPrice,
+		ExitCommission:  fill.Commission,
+		ExitSlippage:    fill.Slippage,
+		RealizedPnL:     pnl,
+		ClosedAt:        now,
+	}
+	e.closed = append(e.closed, closed)
+	e.daily += pnl
+
+	var violations []Violation
+	if e.limits.MaxDailyLoss > 0 && -e.daily >= e.limits.MaxDailyLoss {
+		violations = append(violations, e.dailyLossViolation())
+	}
+	handler := e.onEvent
+	e.mu.Unlock()
+
+	e.emit(handler, pos.Ticker, violations)
+	return closed, nil
+}
+
+// Positions returns all open positions ordered by open time
+func (e *Engine) Positions() []Position {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	positions := make([]Position, 0, len(e.positions))
+	for _, pos := range e.positions {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].OpenedAt.Before(positions[j].OpenedAt) })
+	return positions
+}
+
+// ClosedPositions returns all positions closed since startup
+func (e *Engine) ClosedPositions() []ClosedPosition {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	closed := make([]ClosedPosition, len(e.closed))
+	copy(closed, e.closed)
+	return closed
+}
+
+// Status returns a summary of current exposure and daily P&L
+func (e *Engine) Status() Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rollDay(time.Now())
+	status := Status{
+		Limits:         e.limits,
+		Costs:          e.costs,
+		OpenPositions:  len(e.positions),
+		TickerExposure: make(map[string]float64),
+		SectorExposure: make(map[string]float64),
+		DailyRealized:  e.daily,
+	}
+	for _, pos := range e.positions {
+		status.TotalExposure += pos.Exposure()
+		status.TickerExposure[pos.Ticker] += pos.Exposure()
+		status.SectorExposure[pos.Sector] += pos.Exposure()
+	}
+	status.DailyLossHalted = e.limits.MaxDailyLoss > 0 && -e.daily >= e.limits.MaxDailyLoss
+	return status
+}
+
+// violations computes limit breaches for additional exposure in a ticker in
+// sector. Caller holds the lock.
+func (e *Engine) violations(ticker, sector string, exposure float64) []Violation {
+	var violations []Violation
+
+	if e.limits.MaxDailyLoss > 0 && -e.daily >= e.limits.MaxDailyLoss {
+		violations = append(violations, e.dailyLossViolation())
+	}
+
+	tickerExposure, sectorExposure := exposure, exposure
+	for _, pos := range e.positions {
+		if pos.Ticker == ticker {
+			tickerExposure += pos.Exposure()
+		}
+		if pos.Sector == sector {
+			sectorExposure += pos.Exposure()
+		}
+	}
+
+	if e.limits.MaxTickerExposure > 0 && tickerExposure > e.limits.MaxTickerExposure {
+		violations = append(violations, Violation{
+			Type:    EventExposureLimit,
+			Subject: ticker,
+			Limit:   e.limits.MaxTickerExposure,
+			Value:   tickerExposure,
+			Message: fmt.Sprintf("exposure in %s would be $%.2f, limit is $%.2f", ticker, tickerExposure, e.limits.MaxTickerExposure),
+		})
+	}
+
+	if e.limits.MaxSectorExposure > 0 && sector != UnknownSector && sectorExposure > e.limits.MaxSectorExposure {
+		violations = append(violations, Violation{
+			Type:    EventSectorLimit,
+			Subject: sector,
+			Limit:   e.limits.MaxSectorExposure,
+			Value:   sectorExposure,
+			Message: fmt.Sprintf("exposure in sector %s would be $%.2f, limit is $%.2f", sector, sectorExposure, e.limits.MaxSectorExposure),
+		})
+	}
+
+	return violations
+}
+
+// dailyLossViolation describes the daily loss limit breach. Caller holds the lock.
+func (e *Engine) dailyLossViolation() Violation {
+	return Violation{
+		Type:    EventDailyLossLimit,
+		Subject: "portfolio",
+		Limit:   e.limits.MaxDailyLoss,
+		Value:   -e.daily,
+		Message: fmt.Sprintf("daily realized loss $%.2f has reached the limit of $%.2f", -e.daily, e.limits.MaxDailyLoss),
+	}
+}
+
+// sectorOf returns the sector for a ticker. Caller holds the lock.
+func (e *Engine) sectorOf(ticker string) string {
+	if sector, ok := e.sectors[ticker]; ok {
+		return sector
+	}
+	return UnknownSector
+}
+
+// rollDay resets daily P&L when the trading day changes. Caller holds the lock.
+func (e *Engine) rollDay(now time.Time) {
+	day := now.In(e.location).Format("2006-01-02")
+	if day != e.day {
+		e.day = day
+		e.daily = 0
+	}
+}
+
+// emit sends risk events for violations without holding the lock
+func (e *Engine) emit(handler func(Event), ticker string, violations []Violation) {
+	if handler == nil {
+		return
+	}
+	for _, v := range violations {
+		handler(Event{
+			Type:      v.Type,
+			Ticker:    ticker,
+			Violation: v,
+			Timestamp: time.Now(),
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// pkg/risk/sizing.go
+package risk
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Signal directions
+const (
+	DirectionLong  = "LONG"
+	DirectionShort = "SHORT"
+)
+
+// SizingRequest holds the inputs for a position size calculation
+type SizingRequest struct {
+	AccountSize   float64 `json:"account_size"`   // Total account equity in dollars
+	RiskPercent   float64 `json:"risk_percent"`   // Percentage of the account to risk on the trade
+	Entry         float64 `json:"entry"`          // Entry price
+	Stoploss      float64 `json:"stoploss"`       // Stop price; derived from ATR when zero
+	Direction     string  `json:"direction"`      // LONG or SHORT
+	ATR           float64 `json:"atr"`            // Average True Range of the instrument
+	ATRMultiplier float64 `json:"atr_multiplier"` // Stop distance in ATRs when no stoploss is given
+	OptionPrice   float64 `json:"option_price"`   // Optional option premium for contract sizing
+}
+
+// SizingResult holds the suggested position size
+type SizingResult struct {
+	Shares         int     `json:"shares"`
+	Contracts      int     `json:"contracts,omitempty"`
+	Stoploss       float64 `json:"stoploss"`
+	StopSource     string  `json:"stop_source"` // "signal" or "atr"
+	RiskPerShare   float64 `json:"risk_per_share"`
+	DollarRisk     float64 `json:"dollar_risk"`
+	PositionValue  float64 `json:"position_value"`
+	AccountPercent float64 `json:"account_percent"` // Position value as a percentage of the account
+	ATR            float64 `json:"atr,omitempty"`
+}
+
+// DefaultATRMultiplier is the stop distance used when none is provided
+const DefaultATRMultiplier = 2.0
+
+// CalculatePositionSize returns the number of shares (and option contracts, if an
+// option price is given) that risks the requested percentage of the account
+func CalculatePositionSize(req SizingRequest) (SizingResult, error) {
+	if req.AccountSize <= 0 {
+		return SizingResult{}, fmt.Errorf("account size must be positive")
+	}
+	if req.RiskPercent <= 0 || req.RiskPercent > 100 {
+		return SizingResult{}, fmt.Errorf("risk percent must be between 0 and 100")
+	}
+	if req.Entry <= 0 {
+		return SizingResult{}, fmt.Errorf("entry price must be positive")
+	}
+
+	result := SizingResult{
+		Stoploss:   req.Stoploss,
+		StopSource: "signal",
+		ATR:        req.ATR,
+	}
+
+	// Derive the stop from volatility if the signal did not provide one
+	short := strings.EqualFold(req.Direction, DirectionShort)
+	if result.Stoploss <= 0 {
+		if req.ATR <= 0 {
+			return SizingResult{}, fmt.Errorf("either stoploss or ATR is required")
+		}
+		multiplier := req.ATRMultiplier
+		if multiplier <= 0 {
+			multiplier = DefaultATRMultiplier
+		}
+		if short {
+			result.Stoploss = req.Entry + multiplier*req.ATR
+		} else {
+			result.Stoploss = req.Entry - multiplier*req.ATR
+		}
+		result.StopSource = "atr"
+	}
+
+	// A stop on the wrong side of entry would already be hit
+	switch {
+	case short && result.Stoploss <= req.Entry:
+		return SizingResult{}, fmt.Errorf("stoploss must be above entry for a short")
+	case !short && result.Stoploss >= req.Entry:
+		return SizingResult{}, fmt.Errorf("stoploss must be below entry for a long")
+	case result.Stoploss <= 0:
+		return SizingResult{}, fmt.Errorf("the ATR-derived stop would be at or below zero")
+	}
+
+	result.RiskPerShare = math.Abs(req.Entry - result.Stoploss)
+
+	maxRisk := req.AccountSize * req.RiskPercent / 100
+	result.Shares = int(math.Floor(maxRisk / result.RiskPerShare))
+
+	// Never size beyond what the account can afford
+	if affordable := int(math.Floor(req.AccountSize / req.Entry)); result.Shares > affordable {
+		result.Shares = affordable
+	}
+
+	result.DollarRisk = float64(result.Shares) * result.RiskPerShare
+	result.PositionValue = float64(result.Shares) * req.Entry
+	result.AccountPercent = result.PositionValue / req.AccountSize * 100
+
+	// For options, cap the premium at risk to the same dollar amount
+	if req.OptionPrice > 0 {
+		result.Contracts = int(math.Floor(maxRisk / (req.OptionPrice * 100)))
+	}
+
+	return result, nil
+}
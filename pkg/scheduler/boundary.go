@@ -0,0 +1,85 @@
+// pkg/scheduler/boundary.go
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+// NextBoundary returns the first bar boundary after now: the next multiple of
+// period counted from midnight in loc, plus offset. A 15 minute period fires
+// at :00, :15, :30 and :45; a one minute period at :00 of every minute.
+// period must be positive.
+func NextBoundary(now time.Time, period, offset time.Duration, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	next := day.Add(local.Sub(day).Truncate(period)).Add(offset)
+	for !next.After(now) {
+		next = next.Add(period)
+	}
+	// Periods that do not divide a day restart from the next midnight
+	if tomorrow := day.AddDate(0, 0, 1).Add(offset); next.After(tomorrow) {
+		next = tomorrow
+	}
+	return next
+}
+
+// BoundaryTicker delivers ticks on bar boundaries. Each wait is computed from
+// the wall clock, so ticks do not drift; ticks are dropped for slow receivers
+// as with time.Ticker.
+type BoundaryTicker struct {
+	C    <-chan time.Time
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewBoundaryTicker creates a ticker firing at offset past each period
+// boundary in loc. It panics if period is not positive, as time.NewTicker does.
+func NewBoundaryTicker(period, offset time.Duration, loc *time.Location) *BoundaryTicker {
+	return NewBoundaryTickerFunc(period, offset, loc, time.Now)
+}
+
+// NewBoundaryTickerFunc is NewBoundaryTicker with boundaries found from now,
+// such as a clock corrected for skew, rather than the local clock
+func NewBoundaryTickerFunc(period, offset time.Duration, loc *time.Location, now func() time.Time) *BoundaryTicker {
+	if period <= 0 {
+		panic("non-positive interval for NewBoundaryTicker")
+	}
+	c := make(chan time.Time, 1)
+	t := &BoundaryTicker{C: c, stop: make(chan struct{})}
+
+	go func() {
+		var last time.Time
+		for {
+			current := now()
+			next := NextBoundary(current, period, offset, loc)
+			if !next.After(last) {
+				// The clock stepped back; never fire the same boundary twice
+				next = NextBoundary(last, period, offset, loc)
+			}
+			timer := time.NewTimer(next.Sub(current))
+			select {
+			case <-t.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			last = next
+
+			select {
+			case c <- next:
+			default:
+			}
+		}
+	}()
+	return t
+}
+
+// Stop turns off the ticker
+func (t *BoundaryTicker) Stop() {
+	t.once.Do(func() { close(t.stop) })
+}
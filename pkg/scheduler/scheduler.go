@@ -0,0 +1,205 @@
+// pkg/scheduler/scheduler.go
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// JobFunc is the work performed on each scheduled run
+type JobFunc func(ctx context.Context) error
+
+// JobStatus reports the state of a scheduled job
+type JobStatus struct {
+	Name      string    `json:"name"`
+	Schedule  string    `json:"schedule"`
+	Timezone  string    `json:"timezone"`
+	NextRun   time.Time `json:"next_run"`
+	LastRun   time.Time `json:"last_run,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	Runs      int       `json:"runs"`
+	Skipped   int       `json:"skipped"` // Runs skipped because the previous run was still going
+	Running   bool      `json:"running"`
+}
+
+// job is a registered job and its run state
+type job struct {
+	name     string
+	schedule *Schedule
+	location *time.Location
+	run      JobFunc
+	timeout  time.Duration
+	status   JobStatus
+}
+
+// Scheduler runs jobs on cron schedules
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]*job
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+// New creates a scheduler
+func New() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		jobs:   make(map[string]*job),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Add registers a job. Schedules are evaluated in loc; timeout bounds each run
+// and zero means no limit. Jobs added after Start begin immediately.
+func (s *Scheduler) Add(name, spec string, loc *time.Location, timeout time.Duration, run JobFunc) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("job %s already exists", name)
+	}
+
+	j := &job{
+		name:     name,
+		schedule: schedule,
+		location: loc,
+		run:      run,
+		timeout:  timeout,
+		status: JobStatus{
+			Name:     name,
+			Schedule: spec,
+			Timezone: loc.String(),
+		},
+	}
+	s.jobs[name] = j
+
+	if s.started {
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+	return nil
+}
+
+// Start begins running all registered jobs
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+}
+
+// Stop cancels running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// RunNow triggers a job immediately, outside its schedule
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.Lock()
+	j, exists := s.jobs[name]
+	s.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("job %s not found", name)
+	}
+	if !s.execute(j) {
+		return fmt.Errorf("job %s is already running", name)
+	}
+	return nil
+}
+
+// Jobs returns the status of every job ordered by name
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		status := j.status
+		status.NextRun = j.schedule.Next(time.Now().In(j.location))
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// loop waits for each scheduled time and runs the job
+func (s *Scheduler) loop(j *job) {
+	defer s.wg.Done()
+
+	for {
+		next := j.schedule.Next(time.Now().In(j.location))
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Runs happen in the background so a slow run never shifts the schedule
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.execute(j)
+		}()
+	}
+}
+
+// execute runs a job unless it is already running and records the outcome.
+// It reports whether the job ran.
+func (s *Scheduler) execute(j *job) bool {
+	s.mu.Lock()
+	if j.status.Running {
+		j.status.Skipped++
+		s.mu.Unlock()
+		return false
+	}
+	j.status.Running = true
+	j.status.LastRun = time.Now()
+	s.mu.Unlock()
+
+	ctx := s.ctx
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+	err := j.run(ctx)
+
+	s.mu.Lock()
+	j.status.Running = false
+	j.status.Runs++
+	j.status.LastError = ""
+	if err != nil {
+		j.status.LastError = err.Error()
+	}
+	s.mu.Unlock()
+	return true
+}
@@ -0,0 +1,101 @@
+// pkg/secrets/aws.go
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/sigv4"
+)
+
+// AWSSecretsManager reads secrets from the JSON fields of one AWS Secrets
+// Manager secret. Requests are signed with the standard AWS credential
+// environment variables, which are re-read on every fetch.
+type AWSSecretsManager struct {
+	Region   string
+	SecretID string // Secret name or ARN
+	Endpoint string // Overrides the regional endpoint, e.g. for a local emulator
+	Client   *http.Client
+}
+
+// AWSFromEnv configures Secrets Manager from AWS_SECRET_ID, AWS_REGION (or
+// AWS_DEFAULT_REGION) and optionally AWS_SECRETS_MANAGER_ENDPOINT
+func AWSFromEnv() (*AWSSecretsManager, error) {
+	a := &AWSSecretsManager{
+		Region:   os.Getenv("AWS_REGION"),
+		SecretID: os.Getenv("AWS_SECRET_ID"),
+		Endpoint: os.Getenv("AWS_SECRETS_MANAGER_ENDPOINT"),
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	if a.Region == "" {
+		a.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if a.Region == "" || a.SecretID == "" {
+		return nil, fmt.Errorf("AWS_REGION and AWS_SECRET_ID are required for the aws secrets backend")
+	}
+	if a.Endpoint == "" {
+		a.Endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", a.Region)
+	}
+	return a, nil
+}
+
+// Name identifies the source in logs
+func (a *AWSSecretsManager) Name() string {
+	return "aws"
+}
+
+// Fetch reads the current version of the secret and returns the requested fields
+func (a *AWSSecretsManager) Fetch(ctx context.Context, keys []string) (map[string]string, error) {
+	creds, err := sigv4.CredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	body, _ := json.Marshal(map[string]string{"SecretId": a.SecretID})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	sigv4.Sign(req, sigv4.PayloadHash(body), creds, a.Region, "secretsmanager", time.Now())
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("secrets manager request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return nil, fmt.Errorf("secrets manager returned status %d: %s %s", resp.StatusCode, apiErr.Type, apiErr.Message)
+	}
+
+	var result struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode secrets manager response: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(result.SecretString), &fields); err != nil {
+		return nil, fmt.Errorf("secret %s must be a JSON object of key/value pairs", a.SecretID)
+	}
+
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value, ok := lookup(fields, key); ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
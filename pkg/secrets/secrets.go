@@ -0,0 +1,283 @@
+// pkg/secrets/secrets.go
+package secrets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/utils"
+)
+
+// DefaultDir is where Kubernetes secrets are mounted for the file backend
+const DefaultDir = "/var/run/secrets/tradinglab"
+
+// DefaultRefreshInterval is how often watched secrets are checked for rotation
+const DefaultRefreshInterval = time.Minute
+
+// ErrNotFound is returned for a secret no source has
+var ErrNotFound = errors.New("secret not found")
+
+// Source loads secrets by name, e.g. ALPACA_API_KEY
+type Source interface {
+	Name() string
+	// Fetch returns the values of the keys it has; missing keys are left out
+	Fetch(ctx context.Context, keys []string) (map[string]string, error)
+}
+
+// Env reads secrets from environment variables. A KEY_FILE variable names
+// a file holding the value of KEY and takes precedence over KEY itself.
+type Env struct{}
+
+// Name identifies the source in logs
+func (Env) Name() string {
+	return "env"
+}
+
+// Fetch reads each key from the environment
+func (Env) Fetch(ctx context.Context, keys []string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if path := os.Getenv(key + "_FILE"); path != "" {
+			value, err := readSecretFile(path)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = value
+		} else if value := os.Getenv(key); value != "" {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// Files reads secrets from a directory with one file per key, as mounted
+// from a Kubernetes secret. Files are named after the key or its lowercase form.
+type Files struct {
+	Dir string
+}
+
+// Name identifies the source in logs
+func (f Files) Name() string {
+	return "file"
+}
+
+// Fetch reads each key's file, re-reading on every call so rotated
+// secrets are picked up
+func (f Files) Fetch(ctx context.Context, keys []string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		for _, name := range []string{key, strings.ToLower(key)} {
+			value, err := readSecretFile(filepath.Join(f.Dir, name))
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			values[key] = value
+			break
+		}
+	}
+	return values, nil
+}
+
+// Chain tries sources in order, taking each key from the first source that has it
+type Chain []Source
+
+// Name lists the chained sources
+func (c Chain) Name() string {
+	names := make([]string, len(c))
+	for i, s := range c {
+		names[i] = s.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// Fetch fills keys from each source in turn
+func (c Chain) Fetch(ctx context.Context, keys []string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	for _, source := range c {
+		var missing []string
+		for _, key := range keys {
+			if _, ok := values[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) == 0 {
+			break
+		}
+
+		found, err := source.Fetch(ctx, missing)
+		if err != nil {
+			return nil, fmt.Errorf("%s secrets: %w", source.Name(), err)
+		}
+		for key, value := range found {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+// Manager loads secrets from a source and watches them for rotation
+type Manager struct {
+	Source   Source
+	Interval time.Duration // How often Watch checks for rotation; zero disables it
+}
+
+// FromEnv creates a manager for the backend named by SECRETS_BACKEND:
+//
+//	env (default)  environment variables, or files named by KEY_FILE
+//	file           one file per key in SECRETS_DIR
+//	vault          a Vault KV v2 secret, see VaultFromEnv
+//	aws            an AWS Secrets Manager secret, see AWSFromEnv
+//
+// Keys the backend lacks fall back to the environment. Watched secrets are
+// re-read every SECRETS_REFRESH_INTERVAL (default 1m, "off" disables).
+func FromEnv() (*Manager, error) {
+	var backend Source
+	switch name := strings.ToLower(os.Getenv("SECRETS_BACKEND")); name {
+	case "", "env":
+	case "file":
+		dir := os.Getenv("SECRETS_DIR")
+		if dir == "" {
+			dir = DefaultDir
+		}
+		backend = Files{Dir: dir}
+	case "vault":
+		vault, err := VaultFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		backend = vault
+	case "aws":
+		aws, err := AWSFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		backend = aws
+	default:
+		return nil, fmt.Errorf("unsupported SECRETS_BACKEND %q, expected env, file, vault or aws", name)
+	}
+
+	m := &Manager{Source: Env{}, Interval: DefaultRefreshInterval}
+	if backend != nil {
+		m.Source = Chain{backend, Env{}}
+	}
+
+	switch value := os.Getenv("SECRETS_REFRESH_INTERVAL"); value {
+	case "":
+	case "off":
+		m.Interval = 0
+	default:
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid SECRETS_REFRESH_INTERVAL %q", value)
+		}
+		m.Interval = interval
+	}
+	return m, nil
+}
+
+// Name identifies the manager's source in logs
+func (m *Manager) Name() string {
+	return m.Source.Name()
+}
+
+// Fetch returns the values of the keys the source has
+func (m *Manager) Fetch(ctx context.Context, keys ...string) (map[string]string, error) {
+	return m.Source.Fetch(ctx, keys)
+}
+
+// Get returns one secret, or ErrNotFound
+func (m *Manager) Get(ctx context.Context, key string) (string, error) {
+	values, err := m.Source.Fetch(ctx, []string{key})
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return value, nil
+}
+
+// Watch re-reads a set of secrets every interval until ctx is done and calls
+// onChange with all their values whenever any of them has been rotated.
+// Transient fetch errors keep the current values.
+func (m *Manager) Watch(ctx context.Context, keys []string, onChange func(map[string]string)) {
+	if m.Interval <= 0 {
+		return
+	}
+
+	initial, err := m.Source.Fetch(ctx, keys)
+	if err != nil {
+		utils.Warn("Failed to read %s secrets to watch: %v", m.Name(), err)
+	}
+	last := fingerprint(initial)
+
+	go func() {
+		ticker := time.NewTicker(m.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			values, err := m.Source.Fetch(ctx, keys)
+			if err != nil {
+				utils.Warn("Failed to refresh %s secrets: %v", m.Name(), err)
+				continue
+			}
+			if current := fingerprint(values); current != last {
+				utils.Info("Detected rotated %s secrets for %s", m.Name(), strings.Join(keys, ", "))
+				last = current
+				onChange(values)
+			}
+		}
+	}()
+}
+
+// fingerprint hashes secret values so rotation is detected without keeping
+// a second copy of them
+func fingerprint(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%s\x00", key, values[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// readSecretFile reads a secret file, trimming the trailing newline editors add
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// lookup finds a key in a secret's fields by its name or lowercase form
+func lookup(fields map[string]interface{}, key string) (string, bool) {
+	for _, name := range []string{key, strings.ToLower(key)} {
+		if value, ok := fields[name]; ok && value != nil {
+			return fmt.Sprint(value), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,100 @@
+// pkg/secrets/vault.go
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Vault reads secrets from the fields of one HashiCorp Vault KV v2 secret
+type Vault struct {
+	Addr      string // e.g. https://vault.example.com:8200
+	Mount     string // KV engine mount, default "secret"
+	Path      string // Secret path within the mount, e.g. tradinglab/market-data
+	Namespace string // Vault Enterprise namespace, optional
+	Token     string
+	TokenFile string // Re-read on every fetch so tokens renewed by an agent are used
+	Client    *http.Client
+}
+
+// VaultFromEnv configures Vault from VAULT_ADDR, VAULT_SECRET_PATH,
+// VAULT_MOUNT, VAULT_NAMESPACE and VAULT_TOKEN or VAULT_TOKEN_FILE
+func VaultFromEnv() (*Vault, error) {
+	v := &Vault{
+		Addr:      strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"),
+		Mount:     os.Getenv("VAULT_MOUNT"),
+		Path:      strings.Trim(os.Getenv("VAULT_SECRET_PATH"), "/"),
+		Namespace: os.Getenv("VAULT_NAMESPACE"),
+		Token:     os.Getenv("VAULT_TOKEN"),
+		TokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}
+	if v.Mount == "" {
+		v.Mount = "secret"
+	}
+	if v.Addr == "" || v.Path == "" {
+		return nil, fmt.Errorf("VAULT_ADDR and VAULT_SECRET_PATH are required for the vault secrets backend")
+	}
+	if v.Token == "" && v.TokenFile == "" {
+		return nil, fmt.Errorf("VAULT_TOKEN or VAULT_TOKEN_FILE is required for the vault secrets backend")
+	}
+	return v, nil
+}
+
+// Name identifies the source in logs
+func (v *Vault) Name() string {
+	return "vault"
+}
+
+// Fetch reads the latest version of the secret and returns the requested fields
+func (v *Vault) Fetch(ctx context.Context, keys []string) (map[string]string, error) {
+	token := v.Token
+	if v.TokenFile != "" {
+		var err error
+		if token, err = readSecretFile(v.TokenFile); err != nil {
+			return nil, err
+		}
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/data/%s", v.Addr, v.Mount, v.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned status %d for %s/%s", resp.StatusCode, v.Mount, v.Path)
+	}
+
+	var result struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if value, ok := lookup(result.Data.Data, key); ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
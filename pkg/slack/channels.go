@@ -0,0 +1,192 @@
+// pkg/slack/channels.go
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// Subscription is a channel's subscription to the signal streams of its tickers
+type Subscription struct {
+	ChannelID   string    `json:"channel_id"`
+	ChannelName string    `json:"channel_name,omitempty"`
+	TeamID      string    `json:"team_id,omitempty"`
+	Tickers     []string  `json:"tickers"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ChannelStore keeps channel subscriptions in memory and optionally persists
+// them to a JSON file
+type ChannelStore struct {
+	mu       sync.RWMutex
+	channels map[string]Subscription
+	path     string
+}
+
+// NewChannelStore creates a channel store. If path is non-empty, existing
+// subscriptions are loaded from it and every change is written back.
+func NewChannelStore(path string) (*ChannelStore, error) {
+	s := &ChannelStore{
+		channels: make(map[string]Subscription),
+		path:     path,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read slack channels file: %w", err)
+	}
+
+	var subs []Subscription
+	if err := json.Unmarshal(data, &subs); err != nil {
+		return nil, fmt.Errorf("failed to parse slack channels file: %w", err)
+	}
+	for _, sub := range subs {
+		s.channels[sub.ChannelID] = sub
+	}
+	return s, nil
+}
+
+// Subscribe adds tickers to a channel's subscription and returns it
+func (s *ChannelStore) Subscribe(cmd Command, tickers []string) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.channels[cmd.ChannelID]
+	if !ok {
+		sub = Subscription{ChannelID: cmd.ChannelID, TeamID: cmd.TeamID}
+	}
+	if cmd.ChannelName != "" {
+		sub.ChannelName = cmd.ChannelName
+	}
+	for _, ticker := range tickers {
+		ticker = market.NormalizeTicker(ticker)
+		if ticker != "" && !contains(sub.Tickers, ticker) {
+			sub.Tickers = append(sub.Tickers, ticker)
+		}
+	}
+	sort.Strings(sub.Tickers)
+	sub.UpdatedAt = time.Now()
+
+	s.channels[sub.ChannelID] = sub
+	return sub, s.persist()
+}
+
+// Unsubscribe removes tickers from a channel's subscription, or all of them
+// when none are given, and returns what remains
+func (s *ChannelStore) Unsubscribe(channelID string, tickers []string) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.channels[channelID]
+	if !ok {
+		return Subscription{ChannelID: channelID, Tickers: []string{}}, nil
+	}
+
+	remaining := make([]string, 0, len(sub.Tickers))
+	if len(tickers) > 0 {
+		for i := range tickers {
+			tickers[i] = market.NormalizeTicker(tickers[i])
+		}
+		for _, ticker := range sub.Tickers {
+			if !contains(tickers, ticker) {
+				remaining = append(remaining, ticker)
+			}
+		}
+	}
+	sub.Tickers = remaining
+	sub.UpdatedAt = time.Now()
+
+	if len(remaining) == 0 {
+		delete(s.channels, channelID)
+	} else {
+		s.channels[channelID] = sub
+	}
+	return sub, s.persist()
+}
+
+// Get returns a channel's subscription
+func (s *ChannelStore) Get(channelID string) (Subscription, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sub, ok := s.channels[channelID]
+	return sub, ok
+}
+
+// Channels returns the IDs of channels subscribed to a ticker
+func (s *ChannelStore) Channels(ticker string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []string
+	for id, sub := range s.channels {
+		if contains(sub.Tickers, ticker) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// List returns all subscriptions, ordered by channel ID
+func (s *ChannelStore) List() []Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list()
+}
+
+// list returns all subscriptions. Caller holds the lock.
+func (s *ChannelStore) list() []Subscription {
+	subs := make([]Subscription, 0, len(s.channels))
+	for _, sub := range s.channels {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ChannelID < subs[j].ChannelID })
+	return subs
+}
+
+// persist writes all subscriptions to the file. Caller holds the lock.
+func (s *ChannelStore) persist() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create slack channels directory: %w", err)
+	}
+
+	// Write atomically so a crash never leaves a truncated file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write slack channels file: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// contains reports whether a ticker is in a list
+func contains(tickers []string, ticker string) bool {
+	for _, t := range tickers {
+		if t == ticker {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+// pkg/slack/slack.go
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the Slack Web API base URL
+const DefaultAPIURL = "https://slack.com/api"
+
+// maxRequestAge bounds how old a signed request may be, to stop replays
+const maxRequestAge = 5 * time.Minute
+
+// Response types for slash command replies
+const (
+	Ephemeral = "ephemeral"  // Only visible to the user who ran the command
+	InChannel = "in_channel" // Posted to the channel for everyone
+)
+
+// VerifyRequest checks a request's Slack signature: an HMAC-SHA256 of
+// "v0:{timestamp}:{body}" keyed with the app's signing secret
+func VerifyRequest(secret string, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid request timestamp")
+	}
+	if math.Abs(now.Sub(time.Unix(ts, 0)).Seconds()) > maxRequestAge.Seconds() {
+		return fmt.Errorf("request timestamp is too old")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return fmt.Errorf("invalid request signature")
+	}
+	return nil
+}
+
+// Command is a slash command invocation
+type Command struct {
+	Command     string // e.g. /tl
+	Text        string // Everything after the command
+	TeamID      string
+	ChannelID   string
+	ChannelName string
+	UserID      string
+	UserName    string
+	ResponseURL string // Accepts delayed replies for 30 minutes
+}
+
+// ParseCommand reads a slash command from its form-encoded payload
+func ParseCommand(form url.Values) Command {
+	return Command{
+		Command:     form.Get("command"),
+		Text:        strings.TrimSpace(form.Get("text")),
+		TeamID:      form.Get("team_id"),
+		ChannelID:   form.Get("channel_id"),
+		ChannelName: form.Get("channel_name"),
+		UserID:      form.Get("user_id"),
+		UserName:    form.Get("user_name"),
+		ResponseURL: form.Get("response_url"),
+	}
+}
+
+// Args splits the command text into its subcommand and arguments
+func (c Command) Args() (string, []string) {
+	fields := strings.Fields(c.Text)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return strings.ToLower(fields[0]), fields[1:]
+}
+
+// Response is a slash command reply in Slack's mrkdwn format
+type Response struct {
+	ResponseType    string `json:"response_type"`
+	Text            string `json:"text"`
+	ReplaceOriginal bool   `json:"replace_original,omitempty"`
+}
+
+// Reply creates an ephemeral response
+func Reply(format string, args ...interface{}) Response {
+	return Response{ResponseType: Ephemeral, Text: fmt.Sprintf(format, args...)}
+}
+
+// Client calls the Slack Web API with a bot token
+type Client struct {
+	Token   string
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewClient creates a Web API client
+func NewClient(token string) *Client {
+	return &Client{Token: token, BaseURL: DefaultAPIURL, HTTP: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// ClientFromEnv creates a client from SLACK_BOT_TOKEN, with SLACK_API_URL
+// overriding the API base URL. It returns nil when no token is set.
+func ClientFromEnv() *Client {
+	token := os.Getenv("SLACK_BOT_TOKEN")
+	if token == "" {
+		return nil
+	}
+	c := NewClient(token)
+	if base := os.Getenv("SLACK_API_URL"); base != "" {
+		c.BaseURL = strings.TrimRight(base, "/")
+	}
+	return c
+}
+
+// PostMessage posts a mrkdwn message to a channel the bot is a member of
+func (c *Client) PostMessage(ctx context.Context, channel, text string) error {
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	payload := map[string]interface{}{"channel": channel, "text": text, "mrkdwn": true}
+	if err := c.post(ctx, c.BaseURL+"/chat.postMessage", c.Token, payload, &result); err != nil {
+		return err
+	}
+	// The Web API reports failures in the body with a 200 status
+	if !result.OK {
+		return fmt.Errorf("slack chat.postMessage failed: %s", result.Error)
+	}
+	return nil
+}
+
+// Respond sends a delayed reply to a slash command's response URL
+func Respond(ctx context.Context, responseURL string, resp Response) error {
+	return (&Client{HTTP: &http.Client{Timeout: 10 * time.Second}}).post(ctx, responseURL, "", resp, nil)
+}
+
+// post sends a JSON payload, decoding the response into result when non-nil
+func (c *Client) post(ctx context.Context, url, token string, payload, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode slack payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack returned status %d", resp.StatusCode)
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
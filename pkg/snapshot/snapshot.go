@@ -0,0 +1,493 @@
+// pkg/snapshot/snapshot.go
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/config"
+	"github.com/myapp/tradinglab/pkg/storage"
+)
+
+// Prefix is where snapshots are kept in the object store, one directory per
+// snapshot ID
+const Prefix = "snapshots"
+
+// manifestName is written last, so a snapshot without one is incomplete
+const manifestName = "manifest.json"
+
+// storeArchive holds the persistence store's files
+const storeArchive = "store.tar.gz"
+
+// ErrNotFound is returned for an unknown snapshot ID
+var ErrNotFound = errors.New("snapshot not found")
+
+// Streams exports and imports JetStream stream contents. It is implemented
+// by events.EventClient.
+type Streams interface {
+	StreamNames() []string
+	ExportStream(ctx context.Context, stream string, w io.Writer) (uint64, error)
+	ImportStream(ctx context.Context, stream string, r io.Reader, replace bool) (uint64, error)
+}
+
+// Location is a file or directory of the persistence store
+type Location struct {
+	Name string
+	Path string
+}
+
+// Manifest describes a completed snapshot
+type Manifest struct {
+	ID          string        `json:"id"`
+	Created     time.Time     `json:"created"`
+	Environment string        `json:"environment"`
+	Streams     []StreamEntry `json:"streams"`
+	Store       []StoreEntry  `json:"store,omitempty"`
+}
+
+// StreamEntry is one stream's export in a snapshot
+type StreamEntry struct {
+	Name     string `json:"name"`
+	Messages uint64 `json:"messages"`
+	Size     int64  `json:"size"` // Compressed bytes
+	Key      string `json:"key"`
+}
+
+// StoreEntry is one persistence store location in a snapshot
+type StoreEntry struct {
+	Name  string `json:"name"`
+	Dir   bool   `json:"dir,omitempty"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// Options selects what a snapshot captures or a restore writes. Nil Streams
+// means every stream; an empty non-nil slice means none.
+type Options struct {
+	Streams []string
+	Store   bool
+	Replace bool // Restore only: overwrite non-empty streams and existing files
+}
+
+// RestoreResult reports what a restore wrote
+type RestoreResult struct {
+	ID      string            `json:"id"`
+	Streams map[string]uint64 `json:"streams"`
+	Store   []string          `json:"store,omitempty"`
+	Skipped []string          `json:"skipped,omitempty"` // Store locations not configured here
+}
+
+// Snapshotter writes snapshots of streams and the persistence store to an
+// object store and restores them
+type Snapshotter struct {
+	store     storage.Store
+	streams   Streams
+	locations []Location
+}
+
+// New creates a snapshotter. locations are the persistence store's files and
+// directories, usually LocationsFromEnv.
+func New(store storage.Store, streams Streams, locations ...Location) *Snapshotter {
+	return &Snapshotter{store: store, streams: streams, locations: locations}
+}
+
+// storeLocations names the persistence store's environment variables, with
+// the defaults the gateway uses
+var storeLocations = []struct {
+	name, env, fallback string
+}{
+	{"journal", "JOURNAL_PATH", ""},
+	{"recommendations", "RECOMMENDATIONS_PATH", ""},
+	{"alert-subscribers", "ALERT_SUBSCRIBERS_PATH", ""},
+	{"alert-rules", "ALERT_RULES_PATH", ""},
+	{"slack-channels", "SLACK_CHANNELS_PATH", ""},
+	{"strategies", "STRATEGIES_PATH", ""},
+	{"scan-jobs", "SCAN_JOBS_PATH", ""},
+	{"audit", "AUDIT_LOG_PATH", ""},
+	{"reports", "REPORTS_DIR", "reports"},
+	{"fundamentals", "FUNDAMENTALS_DIR", "fundamentals"},
+	{"market-cache", "MARKET_CACHE_DIR", "market-cache"},
+}
+
+// LocationsFromEnv returns the persistence store locations configured in the
+// environment, leaving out stores that are kept in memory
+func LocationsFromEnv() []Location {
+	var locations []Location
+	for _, l := range storeLocations {
+		path := os.Getenv(l.env)
+		if path == "" {
+			path = l.fallback
+		}
+		if path == "" || path == "none" {
+			continue
+		}
+		locations = append(locations, Location{Name: l.name, Path: path})
+	}
+	return locations
+}
+
+// ParseStreams reads a comma-separated stream list: empty selects every
+// stream and "none" selects none
+func ParseStreams(value string) []string {
+	if value == "" {
+		return nil
+	}
+	streams := []string{}
+	if value == "none" {
+		return streams
+	}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.ToUpper(strings.TrimSpace(name)); name != "" {
+			streams = append(streams, name)
+		}
+	}
+	return streams
+}
+
+// key returns the object key of a file in a snapshot
+func key(id string, parts ...string) string {
+	return storage.Join(append([]string{Prefix, id}, parts...)...)
+}
+
+// Create snapshots the selected streams and, with opts.Store, the
+// persistence store
+func (s *Snapshotter) Create(ctx context.Context, opts Options) (*Manifest, error) {
+	streams := opts.Streams
+	if streams == nil {
+		streams = s.streams.StreamNames()
+	}
+
+	now := time.Now().UTC()
+	manifest := &Manifest{
+		ID:          now.Format("20060102T150405.000Z"),
+		Created:     now,
+		Environment: config.Environment(),
+		Streams:     []StreamEntry{},
+	}
+
+	for _, name := range streams {
+		entry := StreamEntry{Name: name, Key: key(manifest.ID, "streams", name+".jsonl.gz")}
+		size, err := s.upload(ctx, entry.Key, "application/gzip", func(w io.Writer) error {
+			gz := gzip.NewWriter(w)
+			count, err := s.streams.ExportStream(ctx, name, gz)
+			entry.Messages = count
+			if err != nil {
+				return err
+			}
+			return gz.Close()
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot stream %s: %w", name, err)
+		}
+		entry.Size = size
+		manifest.Streams = append(manifest.Streams, entry)
+	}
+
+	if opts.Store && len(s.locations) > 0 {
+		_, err := s.upload(ctx, key(manifest.ID, storeArchive), "application/gzip", func(w io.Writer) error {
+			entries, err := writeStore(w, s.locations)
+			manifest.Store = entries
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot the persistence store: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, key(manifest.ID, manifestName), bytes.NewReader(data), int64(len(data)), "application/json"); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// upload writes an object through a temporary file, since stores need the
+// size up front, and returns its size
+func (s *Snapshotter) upload(ctx context.Context, key, contentType string, write func(io.Writer) error) (int64, error) {
+	tmp, err := os.CreateTemp("", "snapshot-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := write(tmp); err != nil {
+		return 0, err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size, s.store.Put(ctx, key, tmp, size, contentType)
+}
+
+// writeStore writes the locations that exist as a gzipped tar. A file is
+// stored under its location name and a directory's files under name/.
+func writeStore(w io.Writer, locations []Location) ([]StoreEntry, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	var entries []StoreEntry
+	for _, location := range locations {
+		info, err := os.Stat(location.Path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entry := StoreEntry{Name: location.Name, Dir: info.IsDir()}
+		err = filepath.Walk(location.Path, func(p string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+			name := location.Name
+			if entry.Dir {
+				rel, err := filepath.Rel(location.Path, p)
+				if err != nil {
+					return err
+				}
+				name += "/" + filepath.ToSlash(rel)
+			}
+			if err := addFile(tw, p, name, info); err != nil {
+				return err
+			}
+			entry.Files++
+			entry.Bytes += info.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive %s: %w", location.Name, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return entries, gz.Close()
+}
+
+// addFile copies a file into a tar
+func addFile(tw *tar.Writer, path, name string, info os.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, f, header.Size)
+	return err
+}
+
+// List returns completed snapshots, oldest first
+func (s *Snapshotter) List(ctx context.Context) ([]Manifest, error) {
+	objects, err := s.store.List(ctx, Prefix+"/")
+	if err != nil {
+		return nil, err
+	}
+	manifests := []Manifest{}
+	for _, object := range objects {
+		if !strings.HasSuffix(object.Key, "/"+manifestName) {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(object.Key, Prefix+"/"), "/"+manifestName)
+		manifest, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, *manifest)
+	}
+	return manifests, nil
+}
+
+// Get reads a snapshot's manifest
+func (s *Snapshotter) Get(ctx context.Context, id string) (*Manifest, error) {
+	if strings.Contains(id, "/") || !storage.ValidKey(id) {
+		return nil, ErrNotFound
+	}
+	body, err := s.store.Get(ctx, key(id, manifestName))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest for snapshot %s: %w", id, err)
+	}
+	return &manifest, nil
+}
+
+// Restore writes a snapshot's streams and, with opts.Store, its persistence
+// store files into this environment. Store files should only be restored
+// while the services using them are stopped, since they keep them in memory.
+func (s *Snapshotter) Restore(ctx context.Context, id string, opts Options) (*RestoreResult, error) {
+	manifest, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result := &RestoreResult{ID: id, Streams: make(map[string]uint64)}
+
+	selected := make(map[string]bool)
+	for _, name := range opts.Streams {
+		selected[name] = true
+	}
+	for _, entry := range manifest.Streams {
+		if opts.Streams != nil && !selected[entry.Name] {
+			continue
+		}
+		delete(selected, entry.Name)
+		count, err := s.restoreStream(ctx, entry, opts.Replace)
+		result.Streams[entry.Name] = count
+		if err != nil {
+			return result, fmt.Errorf("failed to restore stream %s: %w", entry.Name, err)
+		}
+	}
+	for name := range selected {
+		return result, fmt.Errorf("snapshot %s has no stream %s", id, name)
+	}
+
+	if opts.Store && len(manifest.Store) > 0 {
+		if err := s.restoreStore(ctx, id, opts.Replace, result); err != nil {
+			return result, fmt.Errorf("failed to restore the persistence store: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// restoreStream imports one stream's export
+func (s *Snapshotter) restoreStream(ctx context.Context, entry StreamEntry, replace bool) (uint64, error) {
+	body, err := s.store.Get(ctx, entry.Key)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return 0, err
+	}
+	return s.streams.ImportStream(ctx, entry.Name, gz, replace)
+}
+
+// restoreStore extracts the persistence store into the locations configured
+// here. Existing files are only overwritten with replace, which also clears
+// directories first.
+func (s *Snapshotter) restoreStore(ctx context.Context, id string, replace bool, result *RestoreResult) error {
+	body, err := s.store.Get(ctx, key(id, storeArchive))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return err
+	}
+
+	paths := make(map[string]string, len(s.locations))
+	for _, location := range s.locations {
+		paths[location.Name] = location.Path
+	}
+	started := make(map[string]bool)
+	skipped := make(map[string]bool)
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || !storage.ValidKey(header.Name) {
+			continue
+		}
+
+		name, rel, inDir := strings.Cut(header.Name, "/")
+		root, ok := paths[name]
+		if !ok {
+			if !skipped[name] {
+				skipped[name] = true
+				result.Skipped = append(result.Skipped, name)
+			}
+			continue
+		}
+		if !started[name] {
+			started[name] = true
+			result.Store = append(result.Store, name)
+			if replace && inDir {
+				if err := os.RemoveAll(root); err != nil {
+					return err
+				}
+			}
+		}
+
+		target := root
+		if inDir {
+			target = filepath.Join(root, filepath.FromSlash(rel))
+		}
+		mode := os.FileMode(header.Mode).Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := restoreFile(tr, target, mode, replace); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", header.Name, err)
+		}
+	}
+	return nil
+}
+
+// restoreFile writes a file through a temporary file and renames it into
+// place, refusing to overwrite an existing file unless replace is set
+func restoreFile(r io.Reader, target string, mode os.FileMode, replace bool) error {
+	if _, err := os.Stat(target); err == nil && !replace {
+		return fmt.Errorf("%s exists; restore with replace to overwrite it", target)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
@@ -0,0 +1,239 @@
+// pkg/storage/s3.go
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/sigv4"
+)
+
+// S3Config locates a bucket on AWS S3 or an S3-compatible server such as
+// MinIO
+type S3Config struct {
+	Bucket    string
+	Region    string
+	Endpoint  string // Defaults to the regional AWS endpoint
+	Prefix    string // Prepended to every key
+	PathStyle bool   // Address the bucket in the path, as most S3-compatible servers need
+}
+
+// S3Store keeps objects in an S3 bucket. Requests are signed with the
+// standard AWS credential environment variables.
+type S3Store struct {
+	cfg    S3Config
+	base   *url.URL
+	client *http.Client
+}
+
+// NewS3Store creates a store for a bucket
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("an S3 bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+
+	base, err := url.Parse(cfg.Endpoint)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if !cfg.PathStyle {
+		base.Host = cfg.Bucket + "." + base.Host
+	}
+	return &S3Store{cfg: cfg, base: base, client: &http.Client{Timeout: 5 * time.Minute}}, nil
+}
+
+// Name identifies the store in logs
+func (s *S3Store) Name() string {
+	return "s3://" + Join(s.cfg.Bucket, s.cfg.Prefix)
+}
+
+// objectURL returns the URL of a key, escaping each segment as signing expects
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.base
+	segments := strings.Split(Join(s.cfg.Prefix, key), "/")
+	if s.cfg.PathStyle {
+		segments = append([]string{s.cfg.Bucket}, segments...)
+	}
+	escaped := make([]string, len(segments))
+	for i, segment := range segments {
+		escaped[i] = sigv4.Escape(segment)
+	}
+	u.Path = "/" + strings.Join(segments, "/")
+	u.RawPath = "/" + strings.Join(escaped, "/")
+	return &u
+}
+
+// bucketURL returns the URL of the bucket itself
+func (s *S3Store) bucketURL() *url.URL {
+	u := *s.base
+	u.Path = "/"
+	if s.cfg.PathStyle {
+		u.Path = "/" + s.cfg.Bucket
+	}
+	return &u
+}
+
+// PresignGet returns a URL that downloads a key without credentials until
+// expires has passed
+func (s *S3Store) PresignGet(key string, expires time.Duration) (string, error) {
+	if !ValidKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	creds, err := sigv4.CredentialsFromEnv()
+	if err != nil {
+		return "", err
+	}
+	return sigv4.Presign(http.MethodGet, s.objectURL(key), creds, s.cfg.Region, "s3", time.Now(), expires).String(), nil
+}
+
+// do signs and sends a request, returning an error for unexpected statuses
+func (s *S3Store) do(req *http.Request, ok ...int) (*http.Response, error) {
+	creds, err := sigv4.CredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", sigv4.UnsignedPayload)
+	sigv4.Sign(req, sigv4.UnsignedPayload, creds, s.cfg.Region, "s3", time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s failed: %w", req.Method, err)
+	}
+	for _, code := range ok {
+		if resp.StatusCode == code {
+			return resp, nil
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && req.Method == http.MethodGet {
+		return nil, ErrNotFound
+	}
+	var apiErr struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&apiErr)
+	return nil, fmt.Errorf("S3 %s returned status %d: %s %s", req.Method, resp.StatusCode, apiErr.Code, apiErr.Message)
+}
+
+// Put uploads an object in a single request
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	if !ValidKey(key) {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get downloads an object
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !ValidKey(key) {
+		return nil, fmt.Errorf("storage: invalid key %q", key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes an object
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if !ValidKey(key) {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, http.StatusOK, http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List pages through ListObjectsV2 for keys under prefix
+func (s *S3Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	fullPrefix := s.cfg.Prefix
+	if fullPrefix != "" {
+		fullPrefix += "/"
+	}
+	fullPrefix += prefix
+
+	var objects []Object
+	token := ""
+	for {
+		u := s.bucketURL()
+		query := url.Values{"list-type": {"2"}, "prefix": {fullPrefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req, http.StatusOK)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode S3 listing: %w", err)
+		}
+
+		for _, c := range page.Contents {
+			key := c.Key
+			if s.cfg.Prefix != "" {
+				key = strings.TrimPrefix(key, s.cfg.Prefix+"/")
+			}
+			objects = append(objects, Object{Key: key, Size: c.Size, Modified: c.LastModified})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
@@ -0,0 +1,196 @@
+// pkg/storage/storage.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/config"
+)
+
+// ErrNotFound is returned for a key with no object
+var ErrNotFound = errors.New("storage: object not found")
+
+// Object describes a stored object
+type Object struct {
+	Key      string    `json:"key"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// Store is an object store: a flat namespace of keys using "/" as a
+// separator by convention
+type Store interface {
+	// Put writes an object of size bytes, replacing any existing one
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens an object, or returns ErrNotFound
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes an object; deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// List returns the objects whose keys start with prefix, sorted by key
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Name identifies the store in logs
+	Name() string
+}
+
+// Presigner is implemented by stores that can hand out time-limited download
+// URLs, so large objects are fetched from the store rather than through the
+// gateway
+type Presigner interface {
+	PresignGet(key string, expires time.Duration) (string, error)
+}
+
+// Open creates the store an object storage config describes
+func Open(cfg config.ObjectStorage) (Store, error) {
+	switch cfg.Backend {
+	case config.ObjectStoreS3:
+		return NewS3Store(S3Config{
+			Bucket:    cfg.Bucket,
+			Region:    cfg.Region,
+			Endpoint:  cfg.Endpoint,
+			Prefix:    cfg.Prefix,
+			PathStyle: cfg.PathStyle,
+		})
+	case config.ObjectStoreFile, "":
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("the file object storage backend needs a directory")
+		}
+		return NewFileStore(cfg.Dir), nil
+	default:
+		return nil, fmt.Errorf("unknown object storage backend %q", cfg.Backend)
+	}
+}
+
+// ValidKey reports whether key is usable with every backend: non-empty,
+// relative, and free of "." and ".." segments
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// FileStore keeps objects as files under a directory, for single-node
+// deployments and tests, or a mounted bucket
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store rooted at dir
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Name identifies the store in logs
+func (s *FileStore) Name() string {
+	return "file:" + s.dir
+}
+
+// path returns the file an object is kept in
+func (s *FileStore) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the object to a temporary file and renames it into place
+func (s *FileStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Get opens the object's file
+func (s *FileStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete removes the object's file
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List walks the directory for files under prefix, skipping temporary files
+func (s *FileStore) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.Walk(s.dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".put-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, Object{Key: key, Size: info.Size(), Modified: info.ModTime()})
+		}
+		return nil
+	})
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, err
+}
+
+// Join builds a key from segments, ignoring empty ones
+func Join(segments ...string) string {
+	var parts []string
+	for _, segment := range segments {
+		if segment = strings.Trim(segment, "/"); segment != "" {
+			parts = append(parts, segment)
+		}
+	}
+	return path.Join(parts...)
+}
@@ -0,0 +1,47 @@
+// pkg/strategy/builtin.go
+package strategy
+
+// RedCandle mirrors the parameters of the Python RedCandleStrategy
+var RedCandle = Definition{
+	Name:        "RedCandle",
+	Description: "Trades breakouts of the candle that breaks the first red candle of the day",
+	Params: []ParamSpec{
+		{
+			Name:        "use_additional_filters",
+			Type:        ParamBool,
+			Default:     false,
+			Description: "Require RSI and volume confirmation before entering",
+		},
+		{
+			Name:        "rsi_period",
+			Type:        ParamInt,
+			Default:     14,
+			Min:         bound(2),
+			Max:         bound(100),
+			Description: "Lookback period for the RSI filter",
+		},
+		{
+			Name:        "rsi_threshold",
+			Type:        ParamInt,
+			Default:     30,
+			Min:         bound(0),
+			Max:         bound(100),
+			Description: "RSI level used by the additional filters",
+		},
+		{
+			Name:        "volume_factor",
+			Type:        ParamFloat,
+			Default:     1.5,
+			Min:         bound(0),
+			Max:         bound(10),
+			Description: "Minimum volume as a multiple of the average volume",
+		},
+	},
+}
+
+// DefaultRegistry returns a registry containing the built-in strategies
+func DefaultRegistry() *Registry {
+	registry := NewRegistry()
+	registry.Register(RedCandle)
+	return registry
+}
@@ -0,0 +1,84 @@
+// pkg/strategy/confirm.go
+package strategy
+
+import (
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+)
+
+// Higher timeframe trend states
+const (
+	TrendUp      = "up"
+	TrendDown    = "down"
+	TrendNeutral = "neutral"
+	TrendUnknown = "unknown"
+)
+
+// DefaultConfirmPeriod is the EMA length used for the higher timeframe trend
+const DefaultConfirmPeriod = 20
+
+// ConfirmSpec requires signals to agree with the trend on a higher timeframe,
+// e.g. 15min entries confirmed by the 1h trend
+type ConfirmSpec struct {
+	Interval     string `json:"interval"`
+	Period       int    `json:"period,omitempty"`        // EMA length on the higher timeframe
+	AnnotateOnly bool   `json:"annotate_only,omitempty"` // Keep unconfirmed signals, only reporting the state
+}
+
+// Confirmation is the higher timeframe state attached to a signal
+type Confirmation struct {
+	Interval  string    `json:"interval"`
+	Trend     string    `json:"trend"`
+	Confirmed bool      `json:"confirmed"`
+	Close     float64   `json:"close,omitempty"`
+	EMA       float64   `json:"ema,omitempty"`
+	BarTime   time.Time `json:"bar_time,omitempty"` // Start of the higher timeframe bar used
+	Reason    string    `json:"reason,omitempty"`   // Why the trend could not be determined
+}
+
+// Confirm checks a signal against the trend of the latest higher timeframe bar
+// completed by asOf. The trend is up when the bar closes above a rising EMA and
+// down when it closes below a falling one; LONG signals need an up trend and
+// SHORT signals a down trend.
+func Confirm(bars []analytics.Candle, barLength time.Duration, period int, signalType string, asOf time.Time) Confirmation {
+	result := Confirmation{Trend: TrendUnknown}
+	if period <= 0 {
+		period = DefaultConfirmPeriod
+	}
+
+	last := -1
+	for i, bar := range bars {
+		if bar.Time.Add(barLength).After(asOf) {
+			break
+		}
+		last = i
+	}
+	if last < period {
+		result.Reason = "not enough higher timeframe history"
+		return result
+	}
+
+	ema := analytics.EMA(bars[:last+1], period)
+	bar := bars[last]
+	result.BarTime = bar.Time
+	result.Close = bar.Close
+	result.EMA = ema[last]
+
+	switch {
+	case bar.Close > ema[last] && ema[last] > ema[last-1]:
+		result.Trend = TrendUp
+	case bar.Close < ema[last] && ema[last] < ema[last-1]:
+		result.Trend = TrendDown
+	default:
+		result.Trend = TrendNeutral
+	}
+
+	switch signalType {
+	case "LONG":
+		result.Confirmed = result.Trend == TrendUp
+	case "SHORT":
+		result.Confirmed = result.Trend == TrendDown
+	}
+	return result
+}
@@ -0,0 +1,250 @@
+// pkg/strategy/custom.go
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// ExpressionEngine is the trading service strategy that evaluates rule expressions
+const ExpressionEngine = "Expression"
+
+// Rules defines a user strategy as rule expressions evaluated on every bar.
+// Stop expressions are optional; by default longs stop at the signal bar's low
+// and shorts at its high.
+type Rules struct {
+	Long      string `json:"long,omitempty"`
+	Short     string `json:"short,omitempty"`
+	LongStop  string `json:"long_stop,omitempty"`
+	ShortStop string `json:"short_stop,omitempty"`
+}
+
+// Validate parses every rule expression
+func (r Rules) Validate() error {
+	if r.Long == "" && r.Short == "" {
+		return fmt.Errorf("at least one of long or short rules is required")
+	}
+	for name, expr := range r.Parameters() {
+		if _, err := ParseRule(expr); err != nil {
+			return fmt.Errorf("invalid %s rule: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Parameters encodes the non-empty rules as trading service strategy parameters
+func (r Rules) Parameters() map[string]string {
+	params := make(map[string]string)
+	for name, expr := range map[string]string{
+		"long":       r.Long,
+		"short":      r.Short,
+		"long_stop":  r.LongStop,
+		"short_stop": r.ShortStop,
+	} {
+		if expr != "" {
+			params[name] = expr
+		}
+	}
+	return params
+}
+
+// namePattern matches strategy names, which appear in URLs, file stores and
+// scan job names
+var namePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// MaxNameLength is the longest strategy name accepted
+const MaxNameLength = 64
+
+// ValidateName checks a strategy name starts with a letter and holds only
+// letters, digits, underscores and dashes
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("strategy name is required")
+	}
+	if len(name) > MaxNameLength || !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid strategy name %.64q: use up to %d letters, digits, underscores or dashes, starting with a letter", name, MaxNameLength)
+	}
+	return nil
+}
+
+// Define adds or replaces a user strategy built from rule expressions,
+// trading only within session when it is given
+func (r *Registry) Define(name, description string, rules Rules, session *SessionFilter) (Definition, error) {
+	if err := ValidateName(name); err != nil {
+		return Definition{}, err
+	}
+	if name == ExpressionEngine {
+		return Definition{}, fmt.Errorf("strategy name %s is reserved", name)
+	}
+	if err := rules.Validate(); err != nil {
+		return Definition{}, err
+	}
+	if session != nil {
+		if err := session.Validate(); err != nil {
+			return Definition{}, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.strategies[name]; exists && existing.Rules == nil {
+		return Definition{}, fmt.Errorf("cannot replace built-in strategy %s", name)
+	}
+
+	def := Definition{
+		Name:        name,
+		Description: description,
+		Params:      []ParamSpec{},
+		Rules:       &rules,
+		UpdatedAt:   time.Now(),
+		Session:     session,
+	}
+	r.strategies[name] = def
+
+	if err := r.persist(); err != nil {
+		return def, fmt.Errorf("strategy defined but not saved: %w", err)
+	}
+	return def, nil
+}
+
+// Remove deletes a user strategy
+func (r *Registry) Remove(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	def, exists := r.strategies[name]
+	if !exists {
+		return fmt.Errorf("strategy %s not found", name)
+	}
+	if def.Rules == nil {
+		return fmt.Errorf("cannot remove built-in strategy %s", name)
+	}
+	delete(r.strategies, name)
+
+	return r.persist()
+}
+
+// EngineName returns the trading service strategy that runs the named strategy
+func (r *Registry) EngineName(name string) string {
+	if def, exists := r.Get(name); exists && def.Rules != nil {
+		return ExpressionEngine
+	}
+	return name
+}
+
+// LoadFile loads user strategies from path and writes later changes back to it.
+// A missing file is not an error.
+func (r *Registry) LoadFile(path string) error {
+	r.mu.Lock()
+	r.path = path
+	r.mu.Unlock()
+
+	_, err := r.Reload()
+	return err
+}
+
+// Reload re-reads the strategy file if it changed since it was last loaded.
+// It reports whether user strategies were replaced.
+func (r *Registry) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(r.path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat strategy file: %w", err)
+	}
+	if info.ModTime().Equal(r.modTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read strategy file: %w", err)
+	}
+
+	var defs []Definition
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return false, fmt.Errorf("failed to parse strategy file: %w", err)
+	}
+
+	// Validate everything before replacing so a bad edit keeps the previous set
+	for _, def := range defs {
+		if def.Rules == nil {
+			return false, fmt.Errorf("strategy %s has no rules", def.Name)
+		}
+		if err := def.Rules.Validate(); err != nil {
+			return false, fmt.Errorf("strategy %s: %w", def.Name, err)
+		}
+		if def.Session != nil {
+			if err := def.Session.Validate(); err != nil {
+				return false, fmt.Errorf("strategy %s: %w", def.Name, err)
+			}
+		}
+		if existing, exists := r.strategies[def.Name]; (exists && existing.Rules == nil) || def.Name == ExpressionEngine {
+			return false, fmt.Errorf("strategy %s conflicts with a built-in strategy", def.Name)
+		}
+	}
+
+	for name, def := range r.strategies {
+		if def.Rules != nil {
+			delete(r.strategies, name)
+		}
+	}
+	for _, def := range defs {
+		def.Params = []ParamSpec{}
+		r.strategies[def.Name] = def
+	}
+	r.modTime = info.ModTime()
+
+	return true, nil
+}
+
+// persist writes user strategies to the strategy file. Caller holds the lock.
+func (r *Registry) persist() error {
+	if r.path == "" {
+		return nil
+	}
+
+	defs := make([]Definition, 0)
+	for _, def := range r.strategies {
+		if def.Rules != nil {
+			defs = append(defs, def)
+		}
+	}
+
+	data, err := json.MarshalIndent(defs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create strategy directory: %w", err)
+	}
+
+	// Write atomically so the reloader never sees a partial file
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write strategy file: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return err
+	}
+
+	// Our own write should not trigger a reload
+	if info, err := os.Stat(r.path); err == nil {
+		r.modTime = info.ModTime()
+	}
+	return nil
+}
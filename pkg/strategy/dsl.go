@@ -0,0 +1,413 @@
+// pkg/strategy/dsl.go
+package strategy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Price series available in rule expressions
+var dslSeries = map[string]bool{
+	"open":   true,
+	"high":   true,
+	"low":    true,
+	"close":  true,
+	"volume": true,
+}
+
+// Indicator functions available in rule expressions and their accepted argument counts.
+// Arguments are a lookback period, optionally preceded by a price series.
+var dslFunctions = map[string][2]int{
+	"rsi":     {1, 2}, // rsi(14) or rsi(close, 14)
+	"sma":     {1, 2}, // sma(20) or sma(volume, 20)
+	"ema":     {1, 2},
+	"atr":     {1, 1}, // atr(14)
+	"highest": {1, 2}, // Highest value over the previous n bars
+	"lowest":  {1, 2}, // Lowest value over the previous n bars
+	"prev":    {1, 2}, // prev(close) or prev(close, 2)
+}
+
+// Node is a parsed rule expression
+type Node interface {
+	String() string
+}
+
+// NumberNode is a numeric literal
+type NumberNode struct {
+	Value float64
+}
+
+// SeriesNode references a price series of the current bar
+type SeriesNode struct {
+	Name string
+}
+
+// CallNode is an indicator function call
+type CallNode struct {
+	Func string
+	Args []Node
+}
+
+// UnaryNode is a negation or logical NOT
+type UnaryNode struct {
+	Op      string
+	Operand Node
+}
+
+// BinaryNode is an arithmetic, comparison or logical operation
+type BinaryNode struct {
+	Op          string
+	Left, Right Node
+}
+
+func (n NumberNode) String() string { return strconv.FormatFloat(n.Value, 'f', -1, 64) }
+func (n SeriesNode) String() string { return n.Name }
+
+func (n CallNode) String() string {
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s(%s)", n.Func, strings.Join(args, ", "))
+}
+
+func (n UnaryNode) String() string {
+	if n.Op == "NOT" {
+		return "NOT " + n.Operand.String()
+	}
+	return n.Op + n.Operand.String()
+}
+
+func (n BinaryNode) String() string {
+	return fmt.Sprintf("(%s %s %s)", n.Left.String(), n.Op, n.Right.String())
+}
+
+// ParseRule parses a rule expression such as "close < open AND rsi(14) < 30"
+func ParseRule(expr string) (Node, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	p := &ruleParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].offset)
+	}
+	return node, nil
+}
+
+// token kinds
+const (
+	tokNumber = iota
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind   int
+	text   string
+	offset int
+}
+
+// tokenize splits an expression into tokens. Keywords are upper-cased, identifiers lower-cased.
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, string(runes[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			word := string(runes[start:i])
+			switch upper := strings.ToUpper(word); upper {
+			case "AND", "OR", "NOT":
+				tokens = append(tokens, token{tokOp, upper, start})
+			default:
+				tokens = append(tokens, token{tokIdent, strings.ToLower(word), start})
+			}
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case strings.ContainsRune("<>=!", r):
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{tokOp, string(runes[i : i+2]), i})
+				i += 2
+			} else if r == '<' || r == '>' {
+				tokens = append(tokens, token{tokOp, string(r), i})
+				i++
+			} else {
+				return nil, fmt.Errorf("unexpected %q at position %d", r, i)
+			}
+		case strings.ContainsRune("+-*/", r):
+			tokens = append(tokens, token{tokOp, string(r), i})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", r, i)
+		}
+	}
+	return tokens, nil
+}
+
+// ruleParser is a recursive descent parser over tokens
+type ruleParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *ruleParser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *ruleParser) acceptOp(ops ...string) (string, bool) {
+	if t := p.peek(); t != nil && t.kind == tokOp {
+		for _, op := range ops {
+			if t.text == op {
+				p.pos++
+				return op, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (p *ruleParser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("OR"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryNode{Op: "OR", Left: left, Right: right}
+	}
+}
+
+func (p *ruleParser) parseAnd() (Node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("AND"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryNode{Op: "AND", Left: left, Right: right}
+	}
+}
+
+func (p *ruleParser) parseNot() (Node, error) {
+	if _, ok := p.acceptOp("NOT"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return UnaryNode{Op: "NOT", Operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *ruleParser) parseComparison() (Node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.acceptOp("<", "<=", ">", ">=", "==", "!="); ok {
+		right, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		return BinaryNode{Op: op, Left: left, Right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseSum() (Node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryNode{Op: op, Left: left, Right: right}
+	}
+}
+
+func (p *ruleParser) parseTerm() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = BinaryNode{Op: op, Left: left, Right: right}
+	}
+}
+
+func (p *ruleParser) parseUnary() (Node, error) {
+	if _, ok := p.acceptOp("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return UnaryNode{Op: "-", Operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *ruleParser) parsePrimary() (Node, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+
+	switch t.kind {
+	case tokNumber:
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.text, t.offset)
+		}
+		return NumberNode{Value: value}, nil
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next := p.peek(); next == nil || next.kind != tokRParen {
+			return nil, fmt.Errorf("missing closing parenthesis for position %d", t.offset)
+		}
+		p.pos++
+		return node, nil
+	case tokIdent:
+		if next := p.peek(); next != nil && next.kind == tokLParen {
+			p.pos++
+			return p.parseCall(*t)
+		}
+		if !dslSeries[t.text] {
+			return nil, fmt.Errorf("unknown series %q at position %d", t.text, t.offset)
+		}
+		return SeriesNode{Name: t.text}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.offset)
+}
+
+// parseCall parses function arguments after the opening parenthesis
+func (p *ruleParser) parseCall(name token) (Node, error) {
+	arity, exists := dslFunctions[name.text]
+	if !exists {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.offset)
+	}
+
+	call := CallNode{Func: name.text}
+	for {
+		t := p.peek()
+		if t == nil {
+			return nil, fmt.Errorf("missing closing parenthesis for %s", name.text)
+		}
+		if t.kind == tokRParen && len(call.Args) == 0 {
+			p.pos++
+			break
+		}
+
+		arg, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		if next := p.peek(); next != nil && next.kind == tokComma {
+			p.pos++
+			continue
+		}
+		if next := p.peek(); next == nil || next.kind != tokRParen {
+			return nil, fmt.Errorf("missing closing parenthesis for %s", name.text)
+		}
+		p.pos++
+		break
+	}
+
+	if len(call.Args) < arity[0] || len(call.Args) > arity[1] {
+		return nil, fmt.Errorf("%s expects %d to %d arguments, got %d", name.text, arity[0], arity[1], len(call.Args))
+	}
+	return call, validateCallArgs(call)
+}
+
+// validateCallArgs checks that arguments are a series and/or a positive integer period
+func validateCallArgs(call CallNode) error {
+	args := call.Args
+	if call.Func == "prev" {
+		if _, ok := args[0].(SeriesNode); !ok {
+			return fmt.Errorf("prev expects a price series as its first argument")
+		}
+		args = args[1:]
+	} else if len(args) == 2 {
+		if _, ok := args[0].(SeriesNode); !ok {
+			return fmt.Errorf("%s expects a price series as its first argument", call.Func)
+		}
+		args = args[1:]
+	}
+
+	for _, arg := range args {
+		number, ok := arg.(NumberNode)
+		if !ok || number.Value < 1 || number.Value != float64(int(number.Value)) {
+			return fmt.Errorf("%s expects a positive integer period", call.Func)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+// pkg/strategy/plugin.go
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/analytics"
+)
+
+// PluginManifestFile is the manifest each plugin directory must contain
+const PluginManifestFile = "plugin.json"
+
+// Default per-plugin resource limits
+const (
+	DefaultPluginTimeout     = 10 * time.Second
+	DefaultPluginMemoryMB    = 256
+	DefaultPluginMaxOutputKB = 1024
+	DefaultPluginConcurrency = 2
+)
+
+// Signal is an entry signal produced by a strategy
+type Signal struct {
+	Date       string  `json:"date"`
+	SignalType string  `json:"signal_type"` // LONG or SHORT
+	EntryPrice float64 `json:"entry_price"`
+	Stoploss   float64 `json:"stoploss"`
+}
+
+// Strategy generates entry signals from historical candles
+type Strategy interface {
+	Definition() Definition
+	GenerateSignals(ctx context.Context, req SignalInput) ([]Signal, error)
+}
+
+// SignalInput is the data a strategy evaluates
+type SignalInput struct {
+	Ticker   string             `json:"ticker"`
+	Interval string             `json:"interval"`
+	Candles  []analytics.Candle `json:"candles"`
+	Params   map[string]string  `json:"params"`
+}
+
+// PluginLimits bounds the resources a plugin process may use
+type PluginLimits struct {
+	TimeoutSeconds int `json:"timeout_seconds"` // Wall clock limit per run, also used as the CPU time limit
+	MemoryMB       int `json:"memory_mb"`       // Heap and other writable memory limit; reserved address space is not counted
+	MaxOutputKB    int `json:"max_output_kb"`   // Largest accepted response
+	Concurrency    int `json:"concurrency"`     // Simultaneous runs allowed
+}
+
+// PluginManifest describes a strategy plugin. Command runs relative to the
+// plugin directory, e.g. ["./redcandle-v2"] or ["wasmtime", "run", "strategy.wasm"].
+type PluginManifest struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Command     []string     `json:"command"`
+	Params      []ParamSpec  `json:"params"`
+	Limits      PluginLimits `json:"limits"`
+}
+
+// Plugin is a strategy implemented by an external executable. Each run starts
+// a fresh process that reads a SignalInput as JSON on stdin and writes
+// {"signals": [...]} or {"error": "..."} as JSON on stdout.
+type Plugin struct {
+	manifest PluginManifest
+	dir      string
+	slots    chan struct{}
+}
+
+// pluginResponse is the JSON a plugin writes to stdout
+type pluginResponse struct {
+	Signals []Signal `json:"signals"`
+	Error   string   `json:"error"`
+}
+
+// LoadPlugin reads and validates the manifest in a plugin directory
+func LoadPlugin(dir string) (*Plugin, error) {
+	data, err := os.ReadFile(filepath.Join(dir, PluginManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin manifest: %w", err)
+	}
+
+	var manifest PluginManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin manifest: %w", err)
+	}
+	if manifest.Name == "" || len(manifest.Command) == 0 {
+		return nil, fmt.Errorf("plugin manifest requires name and command")
+	}
+	if err := ValidateName(manifest.Name); err != nil {
+		return nil, err
+	}
+	if manifest.Params == nil {
+		manifest.Params = []ParamSpec{}
+	}
+
+	limits := &manifest.Limits
+	if limits.TimeoutSeconds <= 0 {
+		limits.TimeoutSeconds = int(DefaultPluginTimeout / time.Second)
+	}
+	if limits.MemoryMB <= 0 {
+		limits.MemoryMB = DefaultPluginMemoryMB
+	}
+	if limits.MaxOutputKB <= 0 {
+		limits.MaxOutputKB = DefaultPluginMaxOutputKB
+	}
+	if limits.Concurrency <= 0 {
+		limits.Concurrency = DefaultPluginConcurrency
+	}
+
+	// Resolve the executable now so a broken plugin fails at startup
+	if _, err := exec.LookPath(resolveCommand(dir, manifest.Command[0])); err != nil {
+		return nil, fmt.Errorf("plugin command not executable: %w", err)
+	}
+
+	return &Plugin{
+		manifest: manifest,
+		dir:      dir,
+		slots:    make(chan struct{}, limits.Concurrency),
+	}, nil
+}
+
+// LoadPlugins loads every plugin directory under root. Plugins that fail to
+// load are reported in the error map and skipped.
+func LoadPlugins(root string) ([]*Plugin, map[string]error) {
+	failures := make(map[string]error)
+	if root == "" {
+		return nil, failures
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			failures[root] = err
+		}
+		return nil, failures
+	}
+
+	var plugins []*Plugin
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		plugin, err := LoadPlugin(dir)
+		if err != nil {
+			failures[dir] = err
+			continue
+		}
+		plugins = append(plugins, plugin)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].manifest.Name < plugins[j].manifest.Name })
+	return plugins, failures
+}
+
+// Definition returns the strategy definition declared by the plugin manifest
+func (p *Plugin) Definition() Definition {
+	return Definition{
+		Name:        p.manifest.Name,
+		Description: p.manifest.Description,
+		Params:      p.manifest.Params,
+		Plugin:      true,
+	}
+}
+
+// Limits returns the resource limits applied to the plugin
+func (p *Plugin) Limits() PluginLimits {
+	return p.manifest.Limits
+}
+
+// GenerateSignals runs the plugin process on the given candles
+func (p *Plugin) GenerateSignals(ctx context.Context, req SignalInput) ([]Signal, error) {
+	// Bound concurrent runs of this plugin
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode plugin input: %w", err)
+	}
+
+	limits := p.manifest.Limits
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(limits.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, resolveCommand(p.dir, p.manifest.Command[0]), p.manifest.Command[1:]...)
+	cmd.Dir = p.dir
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH")} // Do not leak gateway credentials
+	cmd.Stdin = bytes.NewReader(input)
+	isolate(cmd)
+	if err := applyLimits(cmd, limits); err != nil {
+		return nil, fmt.Errorf("failed to apply limits to plugin %s: %w", p.manifest.Name, err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &limitedWriter{w: &stderr, remaining: 4096}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", p.manifest.Name, err)
+	}
+
+	maxOutput := int64(limits.MaxOutputKB) * 1024
+	output, readErr := io.ReadAll(io.LimitReader(stdout, maxOutput+1))
+	if int64(len(output)) > maxOutput {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("plugin %s exceeded output limit of %d KB", p.manifest.Name, limits.MaxOutputKB)
+	}
+	waitErr := cmd.Wait()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("plugin %s timed out after %ds", p.manifest.Name, limits.TimeoutSeconds)
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read plugin %s output: %w", p.manifest.Name, readErr)
+	}
+	if waitErr != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w: %s", p.manifest.Name, waitErr, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var resp pluginResponse
+	if err := json.Unmarshal(output, &resp); err != nil {
+		return nil, fmt.Errorf("plugin %s returned invalid output: %w", p.manifest.Name, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", p.manifest.Name, resp.Error)
+	}
+	return resp.Signals, nil
+}
+
+// PluginSet holds loaded plugins by strategy name
+type PluginSet struct {
+	mu      sync.RWMutex
+	plugins map[string]*Plugin
+}
+
+// NewPluginSet creates an empty plugin set
+func NewPluginSet() *PluginSet {
+	return &PluginSet{plugins: make(map[string]*Plugin)}
+}
+
+// Add registers a plugin in the set and its definition in the registry
+func (s *PluginSet) Add(plugin *Plugin, registry *Registry) error {
+	def := plugin.Definition()
+	if _, exists := registry.Get(def.Name); exists || def.Name == ExpressionEngine {
+		return fmt.Errorf("plugin %s conflicts with an existing strategy", def.Name)
+	}
+	if err := registry.Register(def); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.plugins[def.Name] = plugin
+	return nil
+}
+
+// Get returns the plugin implementing a strategy
+func (s *PluginSet) Get(name string) (*Plugin, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	plugin, exists := s.plugins[name]
+	return plugin, exists
+}
+
+// resolveCommand makes relative executable paths relative to the plugin directory
+func resolveCommand(dir, command string) string {
+	if filepath.IsAbs(command) || filepath.Base(command) == command {
+		return command
+	}
+	return filepath.Join(dir, command)
+}
+
+// limitedWriter discards writes beyond a byte budget
+type limitedWriter struct {
+	w         io.Writer
+	remaining int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if l.remaining <= 0 {
+		return n, nil
+	}
+	if len(p) > l.remaining {
+		p = p[:l.remaining]
+	}
+	l.remaining -= len(p)
+	l.w.Write(p)
+	return n, nil
+}
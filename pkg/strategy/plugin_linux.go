@@ -0,0 +1,81 @@
+//go:build linux
+
+// pkg/strategy/plugin_linux.go
+package strategy
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// pluginLimitsEnv marks a run of the current binary that sets the plugin's
+// limits on itself and then executes the plugin in its place, so the limits
+// hold from the plugin's first instruction and pass to anything it forks
+const pluginLimitsEnv = "TRADINGLAB_PLUGIN_LIMITS"
+
+func init() {
+	if spec, ok := os.LookupEnv(pluginLimitsEnv); ok {
+		execLimited(spec, os.Args[1:])
+	}
+}
+
+// isolate runs the plugin in its own process group so timeouts kill any children
+func isolate(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pdeathsig: syscall.SIGKILL}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// applyLimits starts the plugin through the current binary, which sets its
+// memory and CPU time limits before executing the plugin
+func applyLimits(cmd *exec.Cmd, limits PluginLimits) error {
+	if cmd.Err != nil {
+		return nil // Start reports the plugin cannot be found
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd.Args = append([]string{self, cmd.Path}, cmd.Args...)
+	cmd.Path = self
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d:%d", pluginLimitsEnv, limits.MemoryMB, limits.TimeoutSeconds))
+	return nil
+}
+
+// execLimited sets the limits in spec on this process and executes the
+// plugin at args[0] with the arguments args[1:]. It only returns by exiting.
+func execLimited(spec string, args []string) {
+	var memoryMB, cpu uint64
+	if _, err := fmt.Sscanf(spec, "%d:%d", &memoryMB, &cpu); err != nil || len(args) < 2 {
+		fmt.Fprintf(os.Stderr, "invalid plugin launch %q\n", spec)
+		os.Exit(126)
+	}
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, pluginLimitsEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+
+	// RLIMIT_DATA counts writable memory the plugin uses rather than address
+	// space it reserves, so runtimes that reserve gigabytes up front, like Go
+	// or wasmtime, still start
+	memory := memoryMB * 1024 * 1024
+	if err := unix.Setrlimit(unix.RLIMIT_DATA, &unix.Rlimit{Cur: memory, Max: memory}); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to limit plugin memory: %v\n", err)
+		os.Exit(126)
+	}
+	if err := unix.Setrlimit(unix.RLIMIT_CPU, &unix.Rlimit{Cur: cpu, Max: cpu}); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to limit plugin CPU time: %v\n", err)
+		os.Exit(126)
+	}
+	err := unix.Exec(args[0], args[1:], env)
+	fmt.Fprintf(os.Stderr, "failed to execute plugin: %v\n", err)
+	os.Exit(127)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+// pkg/strategy/plugin_other.go
+package strategy
+
+import "os/exec"
+
+// isolate is a no-op where process groups are not managed; the timeout still applies
+func isolate(cmd *exec.Cmd) {}
+
+// applyLimits is a no-op where per-process resource limits are unavailable
+func applyLimits(cmd *exec.Cmd, limits PluginLimits) error {
+	return nil
+}
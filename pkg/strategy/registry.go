@@ -0,0 +1,246 @@
+// pkg/strategy/registry.go
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Parameter value types
+const (
+	ParamInt    = "int"
+	ParamFloat  = "float"
+	ParamBool   = "bool"
+	ParamString = "string"
+)
+
+// DefaultStrategy is used when a request does not name a strategy
+const DefaultStrategy = "RedCandle"
+
+// ParamSpec describes a configurable strategy parameter
+type ParamSpec struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Default     interface{} `json:"default"`
+	Min         *float64    `json:"min,omitempty"`
+	Max         *float64    `json:"max,omitempty"`
+	Options     []string    `json:"options,omitempty"` // Allowed values for string parameters
+	Description string      `json:"description"`
+}
+
+// Definition declares a strategy and its parameters
+type Definition struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Params      []ParamSpec `json:"params"`
+	Rules       *Rules      `json:"rules,omitempty"`      // Set for user strategies defined by rule expressions
+	Plugin      bool        `json:"plugin,omitempty"`     // Implemented by an external plugin
+	UpdatedAt   time.Time   `json:"updated_at,omitempty"` // Last change to a user strategy
+
+	Session *SessionFilter `json:"session,omitempty"` // Time of day entries are allowed; the whole session when unset
+}
+
+// Registry holds the strategies known to the platform
+type Registry struct {
+	mu         sync.RWMutex
+	strategies map[string]Definition
+	path       string    // File user strategies are persisted to
+	modTime    time.Time // Modification time of the file when last loaded or saved
+}
+
+// NewRegistry creates an empty strategy registry
+func NewRegistry() *Registry {
+	return &Registry{strategies: make(map[string]Definition)}
+}
+
+// Register adds or replaces a built-in strategy definition
+func (r *Registry) Register(def Definition) error {
+	if def.Name == "" {
+		return fmt.Errorf("strategy name is required")
+	}
+	for _, spec := range def.Params {
+		if _, err := spec.format(spec.Default); err != nil {
+			return fmt.Errorf("invalid default for %s.%s: %w", def.Name, spec.Name, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.strategies[def.Name] = def
+	return nil
+}
+
+// Get returns a strategy definition by name
+func (r *Registry) Get(name string) (Definition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, exists := r.strategies[name]
+	return def, exists
+}
+
+// SetSession sets or, when nil, clears the session filter of a built-in or
+// plugin strategy. User strategies carry theirs in their definition.
+func (r *Registry) SetSession(name string, session *SessionFilter) error {
+	if session != nil {
+		if err := session.Validate(); err != nil {
+			return fmt.Errorf("strategy %s: %w", name, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	def, exists := r.strategies[name]
+	if !exists {
+		return fmt.Errorf("strategy %s not found", name)
+	}
+	if def.Rules != nil {
+		return fmt.Errorf("strategy %s is a user strategy; set its session when defining it", name)
+	}
+	def.Session = session
+	r.strategies[name] = def
+	return nil
+}
+
+// List returns all registered strategies ordered by name
+func (r *Registry) List() []Definition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]Definition, 0, len(r.strategies))
+	for _, def := range r.strategies {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// Resolve validates parameter values for a strategy and fills in defaults.
+// The result holds every declared parameter encoded as a string for transport.
+func (r *Registry) Resolve(name string, values map[string]interface{}) (map[string]string, error) {
+	def, exists := r.Get(name)
+	if !exists {
+		return nil, fmt.Errorf("unknown strategy %q", name)
+	}
+
+	// User strategies are configured by their rules rather than parameters
+	if def.Rules != nil {
+		if len(values) > 0 {
+			return nil, fmt.Errorf("strategy %s is rule-based and takes no parameters", name)
+		}
+		return def.Rules.Parameters(), nil
+	}
+
+	declared := make(map[string]ParamSpec, len(def.Params))
+	for _, spec := range def.Params {
+		declared[spec.Name] = spec
+	}
+	for key := range values {
+		if _, ok := declared[key]; !ok {
+			return nil, fmt.Errorf("strategy %s has no parameter %q", name, key)
+		}
+	}
+
+	resolved := make(map[string]string, len(def.Params))
+	for _, spec := range def.Params {
+		value, provided := values[spec.Name]
+		if !provided {
+			value = spec.Default
+		}
+		encoded, err := spec.format(value)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", spec.Name, err)
+		}
+		resolved[spec.Name] = encoded
+	}
+	return resolved, nil
+}
+
+// format checks a value against the spec and encodes it as a string
+func (s ParamSpec) format(value interface{}) (string, error) {
+	switch s.Type {
+	case ParamInt:
+		n, err := toFloat(value)
+		if err != nil || n != float64(int64(n)) {
+			return "", fmt.Errorf("expected an integer, got %v", value)
+		}
+		if err := s.checkBounds(n); err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(int64(n), 10), nil
+	case ParamFloat:
+		n, err := toFloat(value)
+		if err != nil {
+			return "", fmt.Errorf("expected a number, got %v", value)
+		}
+		if err := s.checkBounds(n); err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case ParamBool:
+		switch v := value.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return "", fmt.Errorf("expected a boolean, got %q", v)
+			}
+			return strconv.FormatBool(b), nil
+		}
+		return "", fmt.Errorf("expected a boolean, got %v", value)
+	case ParamString:
+		v, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("expected a string, got %v", value)
+		}
+		if len(s.Options) > 0 {
+			for _, option := range s.Options {
+				if strings.EqualFold(option, v) {
+					return option, nil
+				}
+			}
+			return "", fmt.Errorf("must be one of: %s", strings.Join(s.Options, ", "))
+		}
+		return v, nil
+	}
+	return "", fmt.Errorf("unsupported parameter type %q", s.Type)
+}
+
+// checkBounds verifies a numeric value lies within the declared range
+func (s ParamSpec) checkBounds(n float64) error {
+	if s.Min != nil && n < *s.Min {
+		return fmt.Errorf("must be at least %v", *s.Min)
+	}
+	if s.Max != nil && n > *s.Max {
+		return fmt.Errorf("must be at most %v", *s.Max)
+	}
+	return nil
+}
+
+// toFloat converts JSON numbers, Go numeric types and numeric strings to float64
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, fmt.Errorf("not a number")
+}
+
+// bound returns a pointer for use as a parameter limit
+func bound(v float64) *float64 {
+	return &v
+}
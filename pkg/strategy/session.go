@@ -0,0 +1,86 @@
+// pkg/strategy/session.go
+package strategy
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// SessionFilter limits a strategy's entries to part of the regular session,
+// e.g. 09:45 to 15:30 exchange time, or skipping the first 15 minutes.
+// Offsets follow the market calendar, so on half days SkipCloseMinutes
+// counts back from the early close and End is capped by it.
+type SessionFilter struct {
+	Start            string `json:"start,omitempty"` // HH:MM exchange time, inclusive
+	End              string `json:"end,omitempty"`   // HH:MM exchange time, exclusive
+	SkipOpenMinutes  int    `json:"skip_open_minutes,omitempty"`
+	SkipCloseMinutes int    `json:"skip_close_minutes,omitempty"`
+}
+
+// Validate checks the clock times parse and the filter leaves part of a
+// full session open
+func (f SessionFilter) Validate() error {
+	if f.SkipOpenMinutes < 0 || f.SkipCloseMinutes < 0 {
+		return fmt.Errorf("session offsets cannot be negative")
+	}
+	for _, value := range []string{f.Start, f.End} {
+		if _, err := clockMinute(value); err != nil {
+			return err
+		}
+	}
+	if from, to := f.window(market.SessionOpenMinute, market.SessionCloseMinute); from >= to {
+		return fmt.Errorf("session filter leaves no time to trade")
+	}
+	return nil
+}
+
+// Window returns the minutes after midnight, exchange time, entries are
+// allowed in on the date of day. ok is false when the market is closed or
+// the filter leaves no time on that date.
+func (f SessionFilter) Window(day time.Time) (from, to int, ok bool) {
+	open, close, ok := market.SessionHours(day)
+	if !ok {
+		return 0, 0, false
+	}
+	from, to = f.window(open, close)
+	return from, to, from < to
+}
+
+// Allows reports whether an entry at t falls within the filter's window
+func (f SessionFilter) Allows(t time.Time) bool {
+	from, to, ok := f.Window(t)
+	if !ok {
+		return false
+	}
+	et := t.In(market.ExchangeLocation())
+	minute := et.Hour()*60 + et.Minute()
+	return minute >= from && minute < to
+}
+
+// window narrows a session's hours by the filter. Clock times were checked
+// by Validate.
+func (f SessionFilter) window(open, close int) (from, to int) {
+	from, to = open+f.SkipOpenMinutes, close-f.SkipCloseMinutes
+	if start, _ := clockMinute(f.Start); start > from {
+		from = start
+	}
+	if end, _ := clockMinute(f.End); end > 0 && end < to {
+		to = end
+	}
+	return from, to
+}
+
+// clockMinute parses an HH:MM clock time into minutes after midnight. An
+// empty value is zero.
+func clockMinute(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid session time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
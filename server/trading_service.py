@@ -5,11 +5,13 @@ import asyncio
 import pandas as pd
 import grpc
 import json
+import time
 from datetime import datetime, timedelta
 
 # Import local modules
-from strategy import RedCandleStrategy, StreamingStrategyAdapter
-from analysis import OptionsRecommender, StrategyBacktester
+from strategy import RedCandleStrategy, ExpressionStrategy, StreamingStrategyAdapter
+from analysis import OptionsRecommender, StrategyBacktester, OptionsBacktester, CostModel, PortfolioBacktester, lttb
+from data import ORATSDataProvider
 from events.client import EventClient
 from utils.timezone import now, format_datetime, parse_datetime
 
@@ -17,6 +19,18 @@ from utils.timezone import now, format_datetime, parse_datetime
 from proto import trading_pb2
 from proto import trading_pb2_grpc
 
+# Shortest gap between progress events published for one backtest job
+BACKTEST_PROGRESS_INTERVAL = 0.5
+
+# Most points returned in a backtest's equity curve; minute bars over years
+# are downsampled so responses stay small
+BACKTEST_CURVE_POINTS = 5000
+
+
+class BacktestCancelled(Exception):
+    """Raised from a backtest's progress callback once its caller has gone."""
+
+
 class TradingServiceServicer(trading_pb2_grpc.TradingServiceServicer):
     """Implementation of the TradingService gRPC server."""
 
@@ -24,7 +38,9 @@ class TradingServiceServicer(trading_pb2_grpc.TradingServiceServicer):
         """Initialize the trading service with required components."""
         # Initialize strategy components
         self.strategies = {
-            'RedCandle': RedCandleStrategy()
+            'RedCandle': RedCandleStrategy(),
+            # Runs user strategies defined through the gateway; rules arrive as parameters
+            'Expression': ExpressionStrategy()
         }
 
         self.recommender = OptionsRecommender(
@@ -68,6 +84,12 @@ class TradingServiceServicer(trading_pb2_grpc.TradingServiceServicer):
             # Set up subscription for historical data responses
             await self._setup_historical_data_subscription()
 
+            # Announce the service to the event hub
+            self.event_client.start_heartbeat('trading-service', stats=lambda: {
+                'strategies': len(self.strategies),
+                'active_tickers': len(self.active_tickers),
+            })
+
             logging.info("Event client and strategy adapters initialized successfully")
         except Exception as e:
             logging.error(f"Failed to initialize event client: {e}")
@@ -85,8 +107,10 @@ class TradingServiceServicer(trading_pb2_grpc.TradingServiceServicer):
             timeframe = data.get('interval')
             days = data.get('days')
 
-            # Create cache key
+            # Create cache key; data as of a past time is kept apart from current data
             cache_key = f"{ticker}_{timeframe}_{days}"
+            if data.get('as_of'):
+                cache_key += f"@{data['as_of']}"
 
             # Store in cache
             self.historical_data_cache[cache_key] = data
@@ -96,13 +120,19 @@ class TradingServiceServicer(trading_pb2_grpc.TradingServiceServicer):
         await self.event_client.subscribe_market_historical('*', handle_historical_data)
         logging.info("Subscribed to historical data responses")
 
-    async def _get_historical_data(self, ticker, days, interval='15min', timeout=25):
-        """Get historical data through the event system."""
+    async def _get_historical_data(self, ticker, days, interval='15min', timeout=25, as_of=None):
+        """Get historical data through the event system.
+
+        as_of (RFC 3339) asks for bars as they were known then, so reruns of
+        a backtest see the same inputs after corrections and backfills.
+        """
         if not self.event_client:
             raise ValueError("Event client not initialized")
 
         # Create cache key
         cache_key = f"{ticker}_{interval}_{days}"
+        if as_of:
+            cache_key += f"@{as_of}"
 
         # Check cache first
         if cache_key in self.historical_data_cache:
@@ -118,7 +148,8 @@ class TradingServiceServicer(trading_pb2_grpc.TradingServiceServicer):
 
         # Publish request
         logging.info(f"Publishing historical data request for {ticker}, {days} days, interval {interval}")
-        await self.event_client.request_historical_data(ticker, days, interval)
+        # A caller is waiting on this, so it goes ahead of batch backfills
+        await self.event_client.request_historical_data(ticker, days, interval, priority='interactive', as_of=as_of)
 
         # Wait for response with improved timeout and polling
         start_time = now()
@@ -140,6 +171,30 @@ class TradingServiceServicer(trading_pb2_grpc.TradingServiceServicer):
         logging.error(f"Timeout waiting for historical data for {cache_key} after {timeout} seconds")
         raise TimeoutError(f"Timeout waiting for historical data for {cache_key}")
 
+    def _strategy_for(self, strategy_name, parameters):
+        """Return the named strategy, configured with request parameters when given.
+
+        Parameters arrive as strings already validated by the gateway and are
+        converted to the type of the strategy's current attribute.
+        """
+        strategy = self.strategies[strategy_name]
+        if not parameters:
+            return strategy
+
+        kwargs = {}
+        for name, value in parameters.items():
+            current = getattr(strategy, name, None)
+            if isinstance(current, bool):
+                kwargs[name] = value.lower() == 'true'
+            elif isinstance(current, int):
+                kwargs[name] = int(float(value))
+            elif isinstance(current, float):
+                kwargs[name] = float(value)
+            else:
+                kwargs[name] = value
+
+        return type(strategy)(**kwargs)
+
     def GetHistoricalData(self, request, context):
         """Get historical data for a ticker."""
         try:
@@ -225,7 +280,7 @@ class TradingServiceServicer(trading_pb2_grpc.TradingServiceServicer):
                 return trading_pb2.SignalResponse()
 
             # Apply strategy
-            strategy = self.strategies[strategy_name]
+            strategy = self._strategy_for(strategy_name, request.parameters)
             df = strategy.generate_signals(df)
 
             # Filter for entry signals only
@@ -273,10 +328,31 @@ class TradingServiceServicer(trading_pb2_grpc.TradingServiceServicer):
                 context.set_details(f"Strategy {strategy_name} not found")
                 return trading_pb2.BacktestResponse()
 
-            # Get data and generate signals
+            settings = dict(
+                    profit_targets=profit_targets,
+                    risk_reward_ratios=risk_reward_ratios,
+                    profit_targets_dollar=profit_targets_dollar,
+                    contracts=2,
+                    contract_value=50,
+                    costs=CostModel(
+                        commission_fixed=request.costs.commission_fixed,
+                        commission_percent=request.costs.commission_percent,
+                        spread_percent=request.costs.spread_percent,
+                        max_volume_percent=request.costs.max_volume_percent
+                    ),
+                    session_windows={
+                        w.date: (w.start_minute, w.end_minute) for w in request.session_windows
+                    } if request.session_filtered else None
+            )
+
             loop = asyncio.get_event_loop()
+            settings['progress'] = self._backtest_progress(request.job_id, context, loop)
+            if request.tickers:
+                return self._run_portfolio_backtest(request, context, loop, settings)
+
+            # Get data and generate signals
             try:
-                data = loop.run_until_complete(self._get_historical_data(ticker, days, interval))
+                data = loop.run_until_complete(self._get_historical_data(ticker, days, interval, as_of=request.as_of))
                 df = pd.DataFrame(data)
             except (TimeoutError, ValueError) as e:
                 logging.warning(f"Failed to get data from event system: {e}")
@@ -285,48 +361,52 @@ class TradingServiceServicer(trading_pb2_grpc.TradingServiceServicer):
                 return trading_pb2.BacktestResponse()
 
             # Apply strategy
-            strategy = self.strategies[strategy_name]
+            strategy = self._strategy_for(strategy_name, request.parameters)
             df = strategy.generate_signals(df)
 
             # Run backtest
             backtester = StrategyBacktester(strategy_name=strategy_name)
-            backtester.backtest(
-                    df=df,
-                    profit_targets=profit_targets,
-                    risk_reward_ratios=risk_reward_ratios,
-                    profit_targets_dollar=profit_targets_dollar,
-                    contracts=2,
-                    contract_value=50
-            )
+            backtester.backtest(df=df, **settings)
 
             # Get summary stats
             summary = backtester.get_summary_stats()
 
+            # Measure against the benchmark over the same period; results
+            # are still returned without it
+            benchmark = request.benchmark or 'SPY'
+            benchmarks = {}
+            try:
+                benchmark_data = loop.run_until_complete(
+                    self._get_historical_data(benchmark, days, interval, as_of=request.as_of))
+                benchmarks = backtester.benchmark_metrics(df, pd.DataFrame(benchmark_data), benchmark)
+            except (TimeoutError, ValueError, KeyError) as e:
+                logging.warning(f"No {benchmark} benchmark for {ticker} backtest: {e}")
+
             # Create a new response
             response = trading_pb2.BacktestResponse()
 
             # Add results to the map
             for test_name, stats in summary.items():
+                curve = backtester.equity_curve(df, test_name)
+                stats['sharpe_ratio'] = StrategyBacktester.sharpe_ratio(curve)
+
                 # Access the map entry - this creates a default entry if it doesn't exist
                 result_entry = response.results[test_name]
+                self._set_backtest_result(result_entry, stats)
 
-                # Set each field individually
-                result_entry.win_rate = float(stats['win_rate'])
+                if test_name in benchmarks:
+                    result_entry.benchmark.CopyFrom(trading_pb2.BenchmarkMetrics(**benchmarks[test_name]))
 
-                # Handle infinity for profit_factor
-                pf = stats['profit_factor']
-                result_entry.profit_factor = 999999.0 if pf == float('inf') else float(pf)
-
-                result_entry.total_return = float(stats['total_return'])
-                result_entry.total_return_pct = float(stats.get('total_return_pct', 0))
-                result_entry.total_trades = int(stats['total_trades'])
-                result_entry.winning_trades = int(stats['winning_trades'])
-                result_entry.losing_trades = int(stats['losing_trades'])
-                result_entry.max_drawdown = float(stats.get('max_drawdown', 0))
-                result_entry.max_drawdown_pct = float(stats.get('max_drawdown_pct', 0))
+                for i in lttb(curve.to_numpy(), BACKTEST_CURVE_POINTS):
+                    result_entry.equity_curve.add(date=curve.index[i].isoformat(), equity=float(curve.iloc[i]))
 
             return response
 
+        except BacktestCancelled:
+            logging.info(f"RunBacktest for {request.ticker or ', '.join(request.tickers)} cancelled by the caller")
+            context.set_code(grpc.StatusCode.CANCELLED)
+            context.set_details("Backtest cancelled")
+            return trading_pb2.BacktestResponse()
         except Exception as e:
             logging.error(f"Error in RunBacktest: {str(e)}")
             import traceback
@@ -335,6 +415,246 @@ class TradingServiceServicer(trading_pb2_grpc.TradingServiceServicer):
             context.set_details(f"Internal error: {str(e)}")
             return trading_pb2.BacktestResponse()
 
+    def _backtest_progress(self, job_id, context, loop):
+        """Return a backtest progress callback that stops the backtest once the caller
+        has gone and, for jobs, publishes progress on backtest.<job_id> at most every
+        BACKTEST_PROGRESS_INTERVAL seconds."""
+        last_published = [0.0]
+
+        def report(progress):
+            if not context.is_active():
+                raise BacktestCancelled()
+            if not job_id or not self.event_client:
+                return
+            if time.monotonic() - last_published[0] < BACKTEST_PROGRESS_INTERVAL:
+                return
+            last_published[0] = time.monotonic()
+
+            total = progress['total_bars']
+            progress = dict(progress, stage='running',
+                            percent=progress['bars_processed'] / total * 100 if total else 0.0)
+            try:
+                loop.run_until_complete(self.event_client.publish_backtest_progress(job_id, progress))
+            except Exception as e:
+                logging.debug(f"Failed to publish progress of backtest job {job_id}: {e}")
+
+        return report
+
+    def _set_backtest_result(self, result_entry, stats):
+        """Copy a backtest's summary stats into a BacktestResult message."""
+        result_entry.win_rate = float(stats['win_rate'])
+
+        # Handle infinity for profit_factor
+        pf = stats['profit_factor']
+        result_entry.profit_factor = 999999.0 if pf == float('inf') else float(pf)
+
+        result_entry.total_return = float(stats['total_return'])
+        result_entry.total_return_pct = float(stats.get('total_return_pct', 0))
+        result_entry.total_trades = int(stats['total_trades'])
+        result_entry.winning_trades = int(stats['winning_trades'])
+        result_entry.losing_trades = int(stats['losing_trades'])
+        result_entry.max_drawdown = float(stats.get('max_drawdown', 0))
+        result_entry.max_drawdown_pct = float(stats.get('max_drawdown_pct', 0))
+        result_entry.commission = float(stats.get('commission', 0))
+        result_entry.slippage = float(stats.get('slippage', 0))
+        result_entry.partial_fills = int(stats.get('partial_fills', 0))
+        result_entry.sharpe_ratio = float(stats.get('sharpe_ratio', 0))
+
+    def _run_portfolio_backtest(self, request, context, loop, settings):
+        """Backtest a strategy across request.tickers sharing one pool of capital."""
+        interval = request.interval if request.interval else '15min'
+        logging.info(f"RunBacktest portfolio request for {', '.join(request.tickers)}, strategy: {request.strategy}, interval: {interval}")
+
+        # Tickers without data are left out rather than failing the portfolio
+        frames = {}
+        missing = []
+        for ticker in request.tickers:
+            try:
+                data = loop.run_until_complete(
+                    self._get_historical_data(ticker, request.days, interval, as_of=request.as_of))
+                df = pd.DataFrame(data)
+            except (TimeoutError, ValueError) as e:
+                logging.warning(f"No data for {ticker} in portfolio backtest: {e}")
+                missing.append(ticker)
+                continue
+            if df.empty:
+                missing.append(ticker)
+                continue
+            strategy = self._strategy_for(request.strategy, request.parameters)
+            frames[ticker] = strategy.generate_signals(df)
+
+        if not frames:
+            context.set_code(grpc.StatusCode.INTERNAL)
+            context.set_details("Failed to get historical data for any portfolio ticker")
+            return trading_pb2.BacktestResponse()
+
+        try:
+            portfolio = PortfolioBacktester(
+                    strategy_name=request.strategy,
+                    initial_capital=request.allocation.initial_capital or 100000.0,
+                    allocation=request.allocation.method or 'equal',
+                    weights=dict(request.allocation.weights)
+            )
+        except ValueError as e:
+            context.set_code(grpc.StatusCode.INVALID_ARGUMENT)
+            context.set_details(str(e))
+            return trading_pb2.BacktestResponse()
+        results = portfolio.backtest(frames, **settings)
+
+        response = trading_pb2.BacktestResponse()
+        response.missing.extend(missing)
+        for test_name, stats in results.items():
+            self._set_backtest_result(response.results[test_name], stats)
+
+            entry = response.portfolio[test_name]
+            entry.initial_capital = float(stats['initial_capital'])
+            entry.final_equity = float(stats['final_equity'])
+            for point in stats['equity_curve']:
+                entry.equity_curve.add(date=point['date'], equity=float(point['equity']))
+            for ticker, symbol in stats['symbols'].items():
+                symbol_entry = entry.symbols[ticker]
+                symbol_entry.weight = float(symbol['weight'])
+                symbol_entry.capital = float(symbol['capital'])
+                symbol_entry.pnl = float(symbol['pnl'])
+                if symbol['stats']:
+                    self._set_backtest_result(symbol_entry.result, symbol['stats'])
+
+        return response
+
+    def _get_options_history(self, ticker, df, days_to_expiration):
+        """Fetch historical chain snapshots for every trading date in df from ORATS."""
+        provider = ORATSDataProvider()
+
+        chains = []
+        for trade_date in sorted({d.strftime('%Y-%m-%d') for d in df.index}):
+            chain = provider.get_historical_options_data(
+                    ticker,
+                    trade_date,
+                    min_dte=1,
+                    max_dte=days_to_expiration + 15
+            )
+            if chain is not None and not chain.empty:
+                chains.append(chain)
+
+        if not chains:
+            return None
+        return pd.concat(chains, ignore_index=True)
+
+    def RunOptionsBacktest(self, request, context):
+        """Backtest options trades opened on a strategy's signals."""
+        try:
+            ticker = request.ticker
+            days = request.days
+            strategy_name = request.strategy
+            interval = request.interval if request.interval else '15min'
+            pricing = request.pricing if request.pricing else 'model'
+            days_to_expiration = request.days_to_expiration if request.days_to_expiration > 0 else 30
+
+            logging.info(f"RunOptionsBacktest request for {ticker}, strategy: {strategy_name}, interval: {interval}, pricing: {pricing}")
+
+            # Check if strategy exists
+            if strategy_name not in self.strategies:
+                context.set_code(grpc.StatusCode.INVALID_ARGUMENT)
+                context.set_details(f"Strategy {strategy_name} not found")
+                return trading_pb2.OptionsBacktestResponse()
+
+            if pricing not in ('model', 'chain'):
+                context.set_code(grpc.StatusCode.INVALID_ARGUMENT)
+                context.set_details(f"Unknown pricing {pricing}, expected model or chain")
+                return trading_pb2.OptionsBacktestResponse()
+
+            if pricing == 'chain' and not os.getenv('ORATS_API_KEY'):
+                context.set_code(grpc.StatusCode.FAILED_PRECONDITION)
+                context.set_details("Chain pricing requires ORATS_API_KEY")
+                return trading_pb2.OptionsBacktestResponse()
+
+            # Get data and generate signals
+            loop = asyncio.get_event_loop()
+            try:
+                data = loop.run_until_complete(self._get_historical_data(ticker, days, interval))
+                df = pd.DataFrame(data)
+            except (TimeoutError, ValueError) as e:
+                logging.warning(f"Failed to get data from event system: {e}")
+                context.set_code(grpc.StatusCode.INTERNAL)
+                context.set_details(f"Failed to get historical data: {e}")
+                return trading_pb2.OptionsBacktestResponse()
+
+            # Apply strategy
+            strategy = self._strategy_for(strategy_name, request.parameters)
+            df = strategy.generate_signals(df)
+
+            options_df = None
+            if pricing == 'chain':
+                options_df = self._get_options_history(ticker, df, days_to_expiration)
+                if options_df is None:
+                    context.set_code(grpc.StatusCode.NOT_FOUND)
+                    context.set_details(f"No historical options chains found for {ticker}")
+                    return trading_pb2.OptionsBacktestResponse()
+
+            # Run backtest
+            backtester = OptionsBacktester(
+                    days_to_expiration=days_to_expiration,
+                    target_delta=request.target_delta if request.target_delta > 0 else 0.45,
+                    risk_reward_ratio=request.risk_reward_ratio if request.risk_reward_ratio > 0 else 2.0,
+                    premium_target_pct=request.premium_target_pct,
+                    premium_stop_pct=request.premium_stop_pct,
+                    contracts=request.contracts if request.contracts > 0 else 1,
+                    commission=request.commission
+            )
+            result = backtester.backtest(df, options_df)
+
+            # Convert to response format
+            response = trading_pb2.OptionsBacktestResponse()
+            response.pricing = result['pricing']
+
+            summary = result['summary']
+            response.summary.total_trades = int(summary['total_trades'])
+            response.summary.winning_trades = int(summary['winning_trades'])
+            response.summary.losing_trades = int(summary['losing_trades'])
+            response.summary.win_rate = float(summary['win_rate'])
+            response.summary.total_pnl = float(summary['total_pnl'])
+            response.summary.avg_pnl = float(summary['avg_pnl'])
+            response.summary.avg_premium = float(summary['avg_premium'])
+            response.summary.avg_return_pct = float(summary['avg_return_pct'])
+
+            # Handle infinity for profit_factor
+            pf = summary['profit_factor']
+            response.summary.profit_factor = 999999.0 if pf == float('inf') else float(pf)
+
+            response.summary.max_drawdown = float(summary['max_drawdown'])
+            response.summary.expired_worthless = int(summary['expired_worthless'])
+            response.summary.avg_hold_days = float(summary['avg_hold_days'])
+            response.summary.total_theta_cost = float(summary['total_theta_cost'])
+
+            for trade in result['trades']:
+                entry = response.trades.add()
+                entry.entry_date = format_datetime(trade['entry_date'], '%Y-%m-%d %H:%M:%S')
+                entry.exit_date = format_datetime(trade['exit_date'], '%Y-%m-%d %H:%M:%S')
+                entry.signal_type = trade['signal_type']
+                entry.option_type = trade['option_type']
+                entry.strike = float(trade['strike'])
+                entry.expiration = str(trade['expiration'])
+                entry.entry_premium = float(trade['entry_premium'])
+                entry.exit_premium = float(trade['exit_premium'])
+                entry.underlying_entry = float(trade['underlying_entry'])
+                entry.underlying_exit = float(trade['underlying_exit'])
+                entry.exit_reason = trade['exit_reason']
+                entry.pnl = float(trade['pnl'])
+                entry.return_pct = float(trade['return_pct'])
+                entry.hold_days = float(trade['hold_days'])
+                entry.theta_cost = float(trade['theta_cost'])
+                entry.iv = float(trade['iv'])
+
+            return response
+
+        except Exception as e:
+            logging.error(f"Error in RunOptionsBacktest: {str(e)}")
+            import traceback
+            logging.error(traceback.format_exc())
+            context.set_code(grpc.StatusCode.INTERNAL)
+            context.set_details(f"Internal error: {str(e)}")
+            return trading_pb2.OptionsBacktestResponse()
+
     def GetOptionsRecommendations(self, request, context):
         """Get options recommendations for a ticker."""
         try:
@@ -1,5 +1,6 @@
 from .base import Strategy
 from .red_candle import RedCandleStrategy
+from .expression import ExpressionStrategy
 from .streaming_adapater import StreamingStrategyAdapter
 
-__all__ = ['Strategy', 'RedCandleStrategy', 'StreamingStrategyAdapter']
\ No newline at end of file
+__all__ = ['Strategy', 'RedCandleStrategy', 'ExpressionStrategy', 'StreamingStrategyAdapter']
\ No newline at end of file
@@ -0,0 +1,303 @@
+import re
+import numpy as np
+import pandas as pd
+from .base import Strategy
+
+
+class ExpressionStrategy(Strategy):
+    """
+    Strategy defined by rule expressions, compiled at runtime
+
+    Rules are boolean expressions evaluated on every bar, for example:
+        close < open AND rsi(14) < 30
+
+    Supported syntax:
+    - Series: open, high, low, close, volume
+    - Indicators: rsi(n), sma(n), ema(n), atr(n), highest(n), lowest(n);
+      all but atr accept a series first, e.g. sma(volume, 20)
+    - prev(series) or prev(series, n) for the value n bars ago
+    - Arithmetic: + - * /, comparisons: < <= > >= == !=
+    - Logic: AND, OR, NOT and parentheses
+
+    The gateway validates expressions before they reach this strategy.
+    """
+
+    def __init__(self, long='', short='', long_stop='', short_stop=''):
+        """
+        Initialize an expression strategy
+
+        Parameters:
+        long (str): Rule that triggers a long entry
+        short (str): Rule that triggers a short entry
+        long_stop (str): Stoploss price for long entries (defaults to the signal bar's low)
+        short_stop (str): Stoploss price for short entries (defaults to the signal bar's high)
+        """
+        self.long = long
+        self.short = short
+        self.long_stop = long_stop
+        self.short_stop = short_stop
+
+    def generate_signals(self, df):
+        """
+        Generate trading signals by evaluating the rule expressions
+
+        Parameters:
+        df (pandas.DataFrame): Historical price data with OHLCV columns
+
+        Returns:
+        pandas.DataFrame: Data with signals added
+        """
+        df = df.copy()
+
+        if not isinstance(df.index, pd.DatetimeIndex):
+            if 'date' in df.columns:
+                df = df.set_index('date')
+            else:
+                raise ValueError("DataFrame must have a datetime index or a 'date' column")
+
+        false = pd.Series(False, index=df.index)
+        df['long_entry'] = self._evaluate_rule(self.long, df) if self.long else false
+        df['short_entry'] = self._evaluate_rule(self.short, df) if self.short else false
+
+        # A bar matching both rules is ambiguous, so take neither
+        both = df['long_entry'] & df['short_entry']
+        df.loc[both, ['long_entry', 'short_entry']] = False
+
+        long_stop = compile_expression(self.long_stop)(df) if self.long_stop else df['low']
+        short_stop = compile_expression(self.short_stop)(df) if self.short_stop else df['high']
+
+        df['stoploss'] = np.nan
+        df['signal_type'] = ''
+        df.loc[df['long_entry'], 'stoploss'] = long_stop[df['long_entry']]
+        df.loc[df['long_entry'], 'signal_type'] = 'LONG'
+        df.loc[df['short_entry'], 'stoploss'] = short_stop[df['short_entry']]
+        df.loc[df['short_entry'], 'signal_type'] = 'SHORT'
+
+        df['entry_signal'] = df['long_entry'] | df['short_entry']
+
+        return df
+
+    @staticmethod
+    def _evaluate_rule(expression, df):
+        """Evaluate a rule as a boolean series; bars without enough history are False"""
+        result = compile_expression(expression)(df)
+        if not isinstance(result, pd.Series):
+            result = pd.Series(result, index=df.index)
+        return result.fillna(False).astype(bool)
+
+
+# Compiled expressions keyed by source text
+_compiled = {}
+
+_TOKEN_PATTERN = re.compile(r'\s*(?:(\d+\.?\d*|\.\d+)|([A-Za-z_]\w*)|(<=|>=|==|!=|[<>+\-*/(),]))')
+
+_SERIES = {'open', 'high', 'low', 'close', 'volume'}
+
+
+def compile_expression(expression):
+    """Compile a rule expression into a function of a price DataFrame"""
+    if expression not in _compiled:
+        _compiled[expression] = _Parser(_tokenize(expression)).parse()
+    return _compiled[expression]
+
+
+def _tokenize(expression):
+    tokens = []
+    pos = 0
+    expression = expression.rstrip()
+    while pos < len(expression):
+        match = _TOKEN_PATTERN.match(expression, pos)
+        if not match:
+            raise ValueError(f"Unexpected character at position {pos} in rule: {expression}")
+        number, word, op = match.groups()
+        if number is not None:
+            tokens.append(('num', float(number)))
+        elif word is not None:
+            upper = word.upper()
+            if upper in ('AND', 'OR', 'NOT'):
+                tokens.append(('op', upper))
+            else:
+                tokens.append(('ident', word.lower()))
+        else:
+            tokens.append(('op', op))
+        pos = match.end()
+    return tokens
+
+
+class _Parser:
+    """Recursive descent parser producing closures over a DataFrame"""
+
+    def __init__(self, tokens):
+        self.tokens = tokens
+        self.pos = 0
+
+    def parse(self):
+        node = self._or()
+        if self.pos < len(self.tokens):
+            raise ValueError(f"Unexpected token {self.tokens[self.pos][1]!r} in rule")
+        return node
+
+    def _peek(self):
+        return self.tokens[self.pos] if self.pos < len(self.tokens) else (None, None)
+
+    def _accept(self, *ops):
+        kind, value = self._peek()
+        if kind == 'op' and value in ops:
+            self.pos += 1
+            return value
+        return None
+
+    def _expect(self, op):
+        if not self._accept(op):
+            raise ValueError(f"Expected {op!r} in rule")
+
+    def _or(self):
+        left = self._and()
+        while self._accept('OR'):
+            right = self._and()
+            left = (lambda l, r: lambda df: _as_bool(l(df)) | _as_bool(r(df)))(left, right)
+        return left
+
+    def _and(self):
+        left = self._not()
+        while self._accept('AND'):
+            right = self._not()
+            left = (lambda l, r: lambda df: _as_bool(l(df)) & _as_bool(r(df)))(left, right)
+        return left
+
+    def _not(self):
+        if self._accept('NOT'):
+            operand = self._not()
+            return lambda df: ~_as_bool(operand(df))
+        return self._comparison()
+
+    def _comparison(self):
+        left = self._sum()
+        op = self._accept('<', '<=', '>', '>=', '==', '!=')
+        if not op:
+            return left
+        right = self._sum()
+        compare = {
+            '<': lambda a, b: a < b,
+            '<=': lambda a, b: a <= b,
+            '>': lambda a, b: a > b,
+            '>=': lambda a, b: a >= b,
+            '==': lambda a, b: a == b,
+            '!=': lambda a, b: a != b,
+        }[op]
+        return lambda df: compare(left(df), right(df))
+
+    def _sum(self):
+        left = self._term()
+        while True:
+            op = self._accept('+', '-')
+            if not op:
+                return left
+            right = self._term()
+            if op == '+':
+                left = (lambda l, r: lambda df: l(df) + r(df))(left, right)
+            else:
+                left = (lambda l, r: lambda df: l(df) - r(df))(left, right)
+
+    def _term(self):
+        left = self._unary()
+        while True:
+            op = self._accept('*', '/')
+            if not op:
+                return left
+            right = self._unary()
+            if op == '*':
+                left = (lambda l, r: lambda df: l(df) * r(df))(left, right)
+            else:
+                left = (lambda l, r: lambda df: l(df) / r(df))(left, right)
+
+    def _unary(self):
+        if self._accept('-'):
+            operand = self._unary()
+            return lambda df: -operand(df)
+        return self._primary()
+
+    def _primary(self):
+        kind, value = self._peek()
+        if kind is None:
+            raise ValueError("Unexpected end of rule")
+        self.pos += 1
+
+        if kind == 'num':
+            return lambda df: value
+        if kind == 'op' and value == '(':
+            node = self._or()
+            self._expect(')')
+            return node
+        if kind == 'ident':
+            if self._accept('('):
+                return self._call(value)
+            if value not in _SERIES:
+                raise ValueError(f"Unknown series {value!r} in rule")
+            return lambda df: df[value]
+        raise ValueError(f"Unexpected token {value!r} in rule")
+
+    def _call(self, name):
+        args = []
+        if not self._accept(')'):
+            while True:
+                kind, value = self._peek()
+                self.pos += 1
+                if kind == 'ident' and value in _SERIES:
+                    args.append(value)
+                elif kind == 'num':
+                    args.append(int(value))
+                else:
+                    raise ValueError(f"Invalid argument to {name}()")
+                if self._accept(')'):
+                    break
+                self._expect(',')
+
+        if name not in _FUNCTIONS:
+            raise ValueError(f"Unknown function {name!r} in rule")
+
+        # Default the source series to close and the prev offset to one bar
+        if name == 'prev':
+            series, period = args[0], (args[1] if len(args) > 1 else 1)
+        elif len(args) == 2:
+            series, period = args
+        else:
+            series, period = 'close', args[0]
+
+        func = _FUNCTIONS[name]
+        return lambda df: func(df, series, period)
+
+
+def _as_bool(value):
+    if isinstance(value, pd.Series):
+        return value.fillna(False).astype(bool)
+    return bool(value)
+
+
+def _rsi(df, series, period):
+    delta = df[series].diff()
+    gain = delta.where(delta > 0, 0).rolling(window=period).mean()
+    loss = (-delta.where(delta < 0, 0)).rolling(window=period).mean()
+    rs = gain / loss
+    return 100 - (100 / (1 + rs))
+
+
+def _atr(df, series, period):
+    prev_close = df['close'].shift(1)
+    true_range = pd.concat([
+        df['high'] - df['low'],
+        (df['high'] - prev_close).abs(),
+        (df['low'] - prev_close).abs(),
+    ], axis=1).max(axis=1)
+    return true_range.rolling(window=period).mean()
+
+
+_FUNCTIONS = {
+    'rsi': _rsi,
+    'sma': lambda df, series, period: df[series].rolling(window=period).mean(),
+    'ema': lambda df, series, period: df[series].ewm(span=period, adjust=False).mean(),
+    'atr': _atr,
+    'highest': lambda df, series, period: df[series].shift(1).rolling(window=period).max(),
+    'lowest': lambda df, series, period: df[series].shift(1).rolling(window=period).min(),
+    'prev': lambda df, series, period: df[series].shift(period),
+}
@@ -0,0 +1,157 @@
+// tests/integration/alerts_test.go
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestAlertDigest subscribes an email address to a watchlist and checks
+// published signals for it, and only for it, land in the day's digest
+func TestAlertDigest(t *testing.T) {
+	suffix := time.Now().UnixNano() % 1000000
+	watched, other := fmt.Sprintf("AD%d", suffix), fmt.Sprintf("AO%d", suffix)
+	nats := natsURL(t)
+	gateway := startGateway(t, nats, startTradingService(t).Addr, "ALERT_DIGEST_SCHEDULE=off")
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"name": "Test", "email": "Trader@Example.com", "watchlist": []string{watched}, "digest": true,
+	})
+	resp, err := http.Post(gateway+"/api/alerts/subscribers", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	var sub map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&sub)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || sub["email"] != "trader@example.com" {
+		t.Fatalf("Unexpected subscribe response %d: %v", resp.StatusCode, sub)
+	}
+
+	client, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, ticker := range []string{other, watched} {
+		signal := map[string]interface{}{"ticker": ticker, "strategy": "RedCandle", "signal_type": "LONG", "entry_price": 50.0, "date": "2024-03-04 10:00:00"}
+		if err := client.PublishSignal(ctx, ticker, signal); err != nil {
+			t.Fatalf("Failed to publish signal: %v", err)
+		}
+	}
+
+	var digest struct {
+		Signals []map[string]interface{} `json:"signals"`
+	}
+	waitFor(t, 10*time.Second, "digest signal", func() bool {
+		getJSON(t, gateway+"/api/alerts/subscribers/"+sub["id"].(string)+"/digest", http.StatusOK, &digest)
+		return len(digest.Signals) > 0
+	})
+	if len(digest.Signals) != 1 || digest.Signals[0]["ticker"] != watched {
+		t.Errorf("Expected only the watched ticker's signal, got %v", digest.Signals)
+	}
+
+	// Unsubscribing removes the address
+	req, _ := http.NewRequest(http.MethodDelete, gateway+"/api/alerts/subscribers/"+sub["id"].(string), nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to unsubscribe: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected 204 unsubscribing, got %d", resp.StatusCode)
+	}
+	getJSON(t, gateway+"/api/alerts/subscribers/"+sub["id"].(string), http.StatusNotFound, nil)
+}
+
+// TestAlertPriceRule registers a push device and a price rule, and checks a
+// tick crossing the level triggers the rule once until it is re-armed
+func TestAlertPriceRule(t *testing.T) {
+	ticker := fmt.Sprintf("AP%d", time.Now().UnixNano()%1000000)
+	nats := natsURL(t)
+	gateway := startGateway(t, nats, startTradingService(t).Addr, "ALERT_DIGEST_SCHEDULE=off")
+
+	post := func(path string, payload interface{}, status int, v interface{}) {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		resp, err := http.Post(gateway+path, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to post %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("Expected %d from %s, got %d", status, path, resp.StatusCode)
+		}
+		if v != nil {
+			json.NewDecoder(resp.Body).Decode(v)
+		}
+	}
+
+	var sub struct {
+		ID      string                   `json:"id"`
+		Devices []map[string]interface{} `json:"devices"`
+	}
+	post("/api/alerts/subscribers", map[string]interface{}{"email": "push@example.com", "watchlist": []string{ticker}}, http.StatusOK, &sub)
+	post("/api/alerts/subscribers/"+sub.ID+"/devices", map[string]string{"token": "device-token", "platform": "android"}, http.StatusCreated, &sub)
+	if len(sub.Devices) != 1 || sub.Devices[0]["platform"] != "fcm" {
+		t.Fatalf("Expected one fcm device, got %v", sub.Devices)
+	}
+	post("/api/alerts/rules", map[string]interface{}{"subscriber_id": sub.ID, "kind": "price", "ticker": ticker, "condition": "warp", "price": 100}, http.StatusBadRequest, nil)
+
+	var rule map[string]interface{}
+	post("/api/alerts/rules", map[string]interface{}{
+		"subscriber_id": sub.ID, "kind": "price", "ticker": ticker, "condition": "above", "price": 100, "channels": []string{"push"},
+	}, http.StatusCreated, &rule)
+	ruleURL := gateway + "/api/alerts/rules/" + rule["id"].(string)
+
+	client, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	tick := func(price float64) {
+		if err := client.PublishMarketLiveData(ctx, ticker, map[string]interface{}{"ticker": ticker, "price": price}); err != nil {
+			t.Fatalf("Failed to publish tick: %v", err)
+		}
+	}
+
+	// Below the level nothing fires
+	tick(99)
+	time.Sleep(500 * time.Millisecond)
+	getJSON(t, ruleURL, http.StatusOK, &rule)
+	if rule["triggered_at"] != nil {
+		t.Fatalf("Rule triggered below its level: %v", rule)
+	}
+
+	tick(101)
+	waitFor(t, 10*time.Second, "price rule trigger", func() bool {
+		rule = nil
+		getJSON(t, ruleURL, http.StatusOK, &rule)
+		return rule["triggered_at"] != nil
+	})
+
+	post("/api/alerts/rules/"+rule["id"].(string)+"/rearm", nil, http.StatusOK, &rule)
+	if rule["triggered_at"] != nil {
+		t.Errorf("Expected re-armed rule, got %v", rule)
+	}
+
+	// Unsubscribing removes the subscriber's rules
+	req, _ := http.NewRequest(http.MethodDelete, gateway+"/api/alerts/subscribers/"+sub.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to unsubscribe: %v", err)
+	}
+	resp.Body.Close()
+	getJSON(t, ruleURL, http.StatusNotFound, nil)
+}
@@ -0,0 +1,155 @@
+// tests/integration/anomaly_test.go
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestLiveDataAnomalies checks the hub flags a bad print in live data,
+// publishes it as an anomaly and keeps it out of the session summary, and
+// that the gateway withholds signal alerts for the ticker afterwards
+func TestLiveDataAnomalies(t *testing.T) {
+	ticker := fmt.Sprintf("AN%d", time.Now().UnixNano()%1000000)
+	h := NewHarness(t, ticker)
+	ctx := context.Background()
+
+	gateway := startGateway(t, h.NATSURL, h.Trading.Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"TRADINGVIEW_WEBHOOK_SECRET=s3cret",
+	)
+	post := func(path string, payload interface{}, status int, v interface{}) {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		resp, err := http.Post(gateway+path, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to post %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("Expected %d from %s, got %d", status, path, resp.StatusCode)
+		}
+		if v != nil {
+			json.NewDecoder(resp.Body).Decode(v)
+		}
+	}
+	var sub struct {
+		ID string `json:"id"`
+	}
+	post("/api/alerts/subscribers", map[string]interface{}{"email": "anomaly@example.com", "watchlist": []string{ticker}}, http.StatusOK, &sub)
+	post("/api/alerts/rules", map[string]interface{}{"subscriber_id": sub.ID, "kind": "signal", "ticker": ticker}, http.StatusCreated, nil)
+
+	nc, err := nats.Connect(h.NATSURL)
+	if err != nil {
+		t.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	defer nc.Close()
+	anomalies := make(chan *nats.Msg, 8)
+	anomalySub, err := nc.ChanSubscribe(fmt.Sprintf(events.SubjectMarketAnomalyTicker, ticker), anomalies)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to anomalies: %v", err)
+	}
+	defer anomalySub.Unsubscribe()
+	alerts := make(chan *nats.Msg, 8)
+	alertSub, err := nc.ChanSubscribe(fmt.Sprintf(events.SubjectAlertSubscriber, sub.ID), alerts)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to alerts: %v", err)
+	}
+	defer alertSub.Unsubscribe()
+
+	signal := func() {
+		t.Helper()
+		post("/api/webhooks/tradingview", map[string]interface{}{
+			"secret": "s3cret", "ticker": ticker, "action": "buy", "price": 100, "stoploss": 99,
+		}, http.StatusAccepted, nil)
+	}
+	expectAlert := func(want bool) {
+		t.Helper()
+		select {
+		case msg := <-alerts:
+			if !want {
+				t.Errorf("Unexpected alert: %s", msg.Data)
+			}
+		case <-time.After(2 * time.Second):
+			if want {
+				t.Error("Timed out waiting for the alert")
+			}
+		}
+	}
+
+	// Before any anomaly, signals are alerted on
+	signal()
+	expectAlert(true)
+
+	// Enough steady bars to learn the ticker's returns
+	bars, err := h.Provider.PublishBars(ctx, ticker, 30)
+	if err != nil {
+		t.Fatalf("Failed to publish bars: %v", err)
+	}
+	steady := bars[len(bars)-1].Close
+	waitFor(t, 10*time.Second, "steady bars to reach the hub", func() bool {
+		return h.Hub.GetStats().TickerStats[ticker].LiveEvents == 30
+	})
+
+	// A print at three times the price is flagged
+	bad := *bars[len(bars)-1]
+	bad.Timestamp = bad.Timestamp.Add(time.Minute)
+	bad.Price, bad.Close, bad.High = steady*3, steady*3, steady*3
+	if err := h.Provider.client.PublishMarketLiveData(ctx, ticker, &bad); err != nil {
+		t.Fatalf("Failed to publish bad bar: %v", err)
+	}
+	select {
+	case msg := <-anomalies:
+		data, err := events.Decode(msg)
+		if err != nil {
+			t.Fatalf("Failed to decode anomaly: %v", err)
+		}
+		var anomaly events.MarketAnomaly
+		json.Unmarshal(data, &anomaly)
+		if anomaly.Ticker != ticker || anomaly.Price != steady*3 || len(anomaly.Reasons) != 1 || anomaly.Reasons[0].Kind != "price_jump" {
+			t.Errorf("Expected a price jump at %v, got %+v", steady*3, anomaly)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the anomaly")
+	}
+
+	// The next steady bar is not flagged, and the bad one is left out of the summary
+	h.Provider.next = h.Provider.next.Add(time.Minute)
+	if _, err := h.Provider.PublishBars(ctx, ticker, 1); err != nil {
+		t.Fatalf("Failed to publish bar: %v", err)
+	}
+	waitFor(t, 10*time.Second, "the next bar to reach the hub", func() bool {
+		return h.Hub.GetStats().TickerStats[ticker].LiveEvents == 32
+	})
+	if n := h.Hub.GetStats().TickerStats[ticker].AnomalousBars; n != 1 {
+		t.Errorf("Expected one anomalous bar counted, got %d", n)
+	}
+	select {
+	case msg := <-anomalies:
+		t.Errorf("Unexpected anomaly: %s", msg.Data)
+	default:
+	}
+	summary := h.Hub.DailySummary()
+	if summary.Quality.AnomalousBars < 1 {
+		t.Errorf("Expected the anomaly in the summary's data quality, got %+v", summary.Quality)
+	}
+	for _, move := range summary.Tickers {
+		if move.Ticker == ticker && move.High >= steady*3 {
+			t.Errorf("Expected the bad print kept out of the session move, got %+v", move)
+		}
+	}
+
+	// The gateway saw the anomaly before the hub saw the next bar, and
+	// withholds signal alerts while the ticker is quarantined
+	signal()
+	expectAlert(false)
+}
@@ -0,0 +1,106 @@
+// tests/integration/artifacts_test.go
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestArtifactRetention checks a retention pass archives and soft-deletes an
+// old report, that it can be restored from the trash, and that purging
+// empties the trash
+func TestArtifactRetention(t *testing.T) {
+	reports, archive := t.TempDir(), t.TempDir()
+	policyPath := filepath.Join(t.TempDir(), "artifacts.json")
+	policy := `{"reports": {"archive_after": "1h", "delete_after": "2h"}}`
+	if err := os.WriteFile(policyPath, []byte(policy), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+
+	old, recent := "daily-2024-01-02.json", "daily-2024-01-03.json"
+	for _, name := range []string{old, recent} {
+		if err := os.WriteFile(filepath.Join(reports, name), []byte(`{"date":"x"}`), 0644); err != nil {
+			t.Fatalf("Failed to write report: %v", err)
+		}
+	}
+	threeHoursAgo := time.Now().Add(-3 * time.Hour)
+	os.Chtimes(filepath.Join(reports, old), threeHoursAgo, threeHoursAgo)
+
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"ADMIN_TOKEN=artifact-admin",
+		"REPORTS_DIR="+reports,
+		"ARTIFACTS_DIR="+t.TempDir(),
+		"ARTIFACT_ARCHIVE_DIR="+archive,
+		"ARTIFACT_POLICY_PATH="+policyPath,
+	)
+
+	call := func(method, path string, status int, v interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest(method, gateway+path, nil)
+		req.Header.Set("Authorization", "Bearer artifact-admin")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("%s %s: expected %d, got %d", method, path, status, resp.StatusCode)
+		}
+		if v != nil {
+			json.NewDecoder(resp.Body).Decode(v)
+		}
+	}
+
+	getJSON(t, gateway+"/api/ops/artifacts", http.StatusUnauthorized, nil)
+
+	var summary struct {
+		Archived []string `json:"archived"`
+		Deleted  []string `json:"deleted"`
+		Errors   []string `json:"errors"`
+	}
+	call(http.MethodPost, "/api/ops/artifacts/retention", http.StatusOK, &summary)
+	if len(summary.Archived) != 1 || summary.Archived[0] != "reports/"+old ||
+		len(summary.Deleted) != 1 || summary.Deleted[0] != "reports/"+old || len(summary.Errors) != 0 {
+		t.Fatalf("Expected only the old report archived and deleted, got %+v", summary)
+	}
+	if _, err := os.Stat(filepath.Join(archive, "reports", old)); err != nil {
+		t.Errorf("Expected the old report in the archive: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(reports, old)); !os.IsNotExist(err) {
+		t.Errorf("Expected the old report out of the reports directory")
+	}
+
+	// Deleted reports are listed with their deletion time and can come back
+	var listing struct {
+		Artifacts []struct {
+			Name      string     `json:"name"`
+			DeletedAt *time.Time `json:"deleted_at"`
+		} `json:"artifacts"`
+	}
+	call(http.MethodGet, "/api/ops/artifacts?kind=reports", http.StatusOK, &listing)
+	if len(listing.Artifacts) != 2 || listing.Artifacts[0].Name != old || listing.Artifacts[0].DeletedAt == nil {
+		t.Fatalf("Expected the old report listed as deleted, got %+v", listing.Artifacts)
+	}
+	call(http.MethodPost, "/api/ops/artifacts/reports/"+old+"/restore", http.StatusNoContent, nil)
+	getJSON(t, gateway+"/api/reports/daily/2024-01-02", http.StatusOK, nil)
+
+	// Soft-delete the recent report by hand, then purge the trash
+	call(http.MethodDelete, "/api/ops/artifacts/reports/"+recent, http.StatusNoContent, nil)
+	getJSON(t, gateway+"/api/reports/daily/2024-01-03", http.StatusNotFound, nil)
+	var purge struct {
+		Purged []struct {
+			Name string `json:"name"`
+		} `json:"purged"`
+	}
+	call(http.MethodPost, "/api/ops/artifacts/purge", http.StatusOK, &purge)
+	if len(purge.Purged) != 1 || purge.Purged[0].Name != recent {
+		t.Errorf("Expected the recent report purged, got %+v", purge.Purged)
+	}
+	call(http.MethodPost, "/api/ops/artifacts/reports/"+recent+"/restore", http.StatusNotFound, nil)
+	call(http.MethodDelete, "/api/ops/artifacts/unknown/x.json", http.StatusNotFound, nil)
+}
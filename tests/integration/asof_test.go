@@ -0,0 +1,160 @@
+// tests/integration/asof_test.go
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// TestBarHistoryAsOf checks corrected bars are kept as revisions, so an
+// as-of query returns bars as they were known then, including after the
+// history is reloaded from disk, that bars still forming are not recorded
+// and that trimming drops old bars from disk too
+func TestBarHistoryAsOf(t *testing.T) {
+	dir := t.TempDir()
+	history := market.NewBarHistory(dir)
+	day := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	bar := func(offset int, close float64) *market.MarketData {
+		return &market.MarketData{Ticker: "AOF", Timestamp: day.Add(time.Duration(offset) * time.Hour),
+			Open: 100, High: 102, Low: 99, Close: close, Volume: 1000}
+	}
+
+	firstFetch := day.Add(3 * time.Hour)
+	if n := history.Record("AOF", "1hour", []*market.MarketData{bar(0, 101), bar(1, 101.5)}, firstFetch); n != 0 {
+		t.Errorf("Expected no corrections on the first fetch, got %d", n)
+	}
+	// A later fetch corrects the first bar and adds one more
+	secondFetch := day.Add(5 * time.Hour)
+	if n := history.Record("AOF", "1hour", []*market.MarketData{bar(0, 100.5), bar(1, 101.5), bar(2, 102)}, secondFetch); n != 1 {
+		t.Errorf("Expected 1 correction, got %d", n)
+	}
+
+	check := func(h *market.BarHistory, asOf time.Time, closes ...float64) {
+		t.Helper()
+		bars, err := h.AsOf("AOF", "1hour", 5, asOf)
+		if err != nil {
+			t.Fatalf("As-of query at %s failed: %v", asOf, err)
+		}
+		if len(bars) != len(closes) {
+			t.Fatalf("Expected %d bars as of %s, got %d", len(closes), asOf, len(bars))
+		}
+		for i, want := range closes {
+			if bars[i].Close != want {
+				t.Errorf("Bar %d as of %s: expected close %v, got %v", i, asOf, want, bars[i].Close)
+			}
+		}
+	}
+	check(history, firstFetch.Add(time.Minute), 101, 101.5)
+	check(history, secondFetch, 100.5, 101.5, 102)
+
+	if _, err := history.AsOf("AOF", "1hour", 5, firstFetch.Add(-time.Minute)); !errors.Is(err, market.ErrNoHistory) {
+		t.Errorf("Expected ErrNoHistory before the first fetch, got %v", err)
+	}
+	// A bar still forming is left for a later fetch
+	if n := history.Record("AOF", "1hour", []*market.MarketData{bar(4, 103)}, day.Add(4*time.Hour+30*time.Minute)); n != 0 {
+		t.Errorf("Expected no corrections for a forming bar, got %d", n)
+	}
+	if stats := history.Stats(); stats.Bars != 3 || stats.Revisions != 4 || stats.Corrections != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	check(market.NewBarHistory(dir), firstFetch.Add(time.Minute), 101, 101.5)
+
+	// Trimming drops bars that started before the cutoff
+	dropped, err := market.NewBarHistory(dir).Trim(func(ticker, interval string) time.Duration {
+		return 90 * time.Minute
+	}, day.Add(2*time.Hour))
+	if err != nil || dropped != 1 {
+		t.Errorf("Expected 1 bar trimmed, got %d: %v", dropped, err)
+	}
+	check(market.NewBarHistory(dir), secondFetch, 101.5, 102)
+}
+
+// TestBacktestAsOf checks as_of is passed to the trading service in UTC,
+// that as-of runs are cached apart from current ones and that invalid or
+// future times are refused
+func TestBacktestAsOf(t *testing.T) {
+	gateway, trading := contractGateway(t)
+	ticker := fmt.Sprintf("AO%d", time.Now().UnixNano()%1000000)
+	url := gateway + "/api/backtest?ticker=" + ticker + "&days=10&interval=1day"
+
+	getJSON(t, url+"&as_of=2026-03-06T16:00:00-05:00", http.StatusOK, nil)
+	req := onlyCall(t, trading, "RunBacktest", 30*time.Second).(*pb.BacktestRequest)
+	if req.AsOf != "2026-03-06T21:00:00Z" {
+		t.Errorf("Expected as_of in UTC, got %q", req.AsOf)
+	}
+
+	resp, err := http.Get(url + "&as_of=2026-03-06T16:00:00-05:00")
+	if err != nil {
+		t.Fatalf("Repeat as-of backtest failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Data-Source") != "cache" {
+		t.Errorf("Expected a repeated as-of backtest served from the cache")
+	}
+
+	getJSON(t, url, http.StatusOK, nil)
+	if calls := trading.Calls("RunBacktest"); len(calls) != 2 || calls[1].Request.(*pb.BacktestRequest).AsOf != "" {
+		t.Errorf("Expected a current run apart from the as-of one, got %d calls", len(calls))
+	}
+
+	getJSON(t, url+"&as_of=yesterday", http.StatusBadRequest, nil)
+	getJSON(t, url+"&as_of="+time.Now().Add(time.Hour).UTC().Format(time.RFC3339), http.StatusBadRequest, nil)
+}
+
+// TestRepeatedAsOfAnswers checks answering the same as-of request twice
+// delivers both answers, though their chunks are identical by design
+func TestRepeatedAsOfAnswers(t *testing.T) {
+	ctx := context.Background()
+	ticker := fmt.Sprintf("AO%d", time.Now().UnixNano()%1000000)
+
+	client, err := events.NewEventClient(natsURL(t))
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+
+	received := make(chan []byte, 4)
+	sub, err := client.SubscribeHistoricalData(ticker, "1day", 5, func(data []byte) { received <- data })
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	asOf := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	chunk := events.AsOfChunk{
+		ChunkData: market.ChunkData{
+			Data: []*market.MarketData{{Ticker: ticker, Timestamp: asOf.AddDate(0, 0, -1),
+				Open: 100, High: 101, Low: 99, Close: 100, Volume: 1000}},
+			Metadata: market.ChunkMetadata{Ticker: ticker, Timeframe: "1day", Days: 5,
+				Chunk: 1, TotalChunks: 1, DataType: "historical"},
+		},
+		AsOf: market.FormatTimestamp(asOf),
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.PublishHistoricalData(ctx, ticker, "1day", 5, chunk); err != nil {
+			t.Fatalf("Failed to publish as-of chunk: %v", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case data := <-received:
+			var got events.AsOfChunk
+			if err := json.Unmarshal(data, &got); err != nil || got.AsOf != chunk.AsOf {
+				t.Errorf("Expected the as-of chunk, got %s", data)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected both answers delivered, got %d", i)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// tests/integration/audit_test.go
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestAuditLog makes audited calls as a proxied user and an unauthorized
+// caller, and checks the audit log records who did what and the outcome
+func TestAuditLog(t *testing.T) {
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"ADMIN_TOKEN=audit-admin",
+		"AUDIT_LOG_PATH=audit/audit.log",
+	)
+
+	do := func(method, path, user, token string, payload interface{}) int {
+		t.Helper()
+		var body bytes.Buffer
+		if payload != nil {
+			json.NewEncoder(&body).Encode(payload)
+		}
+		req, _ := http.NewRequest(method, gateway+path, &body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		if user != "" {
+			req.Header.Set("X-Forwarded-User", user)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := do(http.MethodPost, "/api/alerts/subscribers", "trader@example.com", "", map[string]interface{}{
+		"email": "trader@example.com", "watchlist": []string{"SPY"},
+	}); status != http.StatusOK {
+		t.Fatalf("Expected 200 subscribing, got %d", status)
+	}
+	if status := do(http.MethodPost, "/api/ops/consumers/prune", "", "wrong", nil); status != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 pruning without the admin token, got %d", status)
+	}
+	// Reads are not audited
+	do(http.MethodGet, "/api/alerts/subscribers", "trader@example.com", "", nil)
+
+	if status := do(http.MethodGet, "/api/audit", "", "", nil); status != http.StatusForbidden && status != http.StatusUnauthorized {
+		t.Errorf("Expected the audit log to require the admin token, got %d", status)
+	}
+
+	var entries []map[string]interface{}
+	req, _ := http.NewRequest(http.MethodGet, gateway+"/api/audit?action=watchlist.", nil)
+	req.Header.Set("Authorization", "Bearer audit-admin")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to query audit log: %v", err)
+	}
+	json.NewDecoder(resp.Body).Decode(&entries)
+	resp.Body.Close()
+	if len(entries) != 1 {
+		t.Fatalf("Expected one watchlist entry, got %v", entries)
+	}
+	if e := entries[0]; e["actor"] != "trader@example.com" || e["action"] != "watchlist.subscribe" ||
+		e["remote_addr"] != "203.0.113.7" || e["outcome"] != "success" {
+		t.Errorf("Unexpected audit entry %v", e)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, gateway+"/api/audit?action=ops.consumers.prune", nil)
+	req.Header.Set("Authorization", "Bearer audit-admin")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to query audit log: %v", err)
+	}
+	entries = nil
+	json.NewDecoder(resp.Body).Decode(&entries)
+	resp.Body.Close()
+	if len(entries) != 1 || entries[0]["outcome"] != "denied" || entries[0]["actor"] != "anonymous" {
+		t.Errorf("Expected one denied prune entry, got %v", entries)
+	}
+}
@@ -0,0 +1,56 @@
+// tests/integration/backtestcache_test.go
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestBacktestCache checks identical backtests are served from the cache
+// without calling the trading service again, and that refresh=true or any
+// changed parameter runs them afresh
+func TestBacktestCache(t *testing.T) {
+	gateway, trading := contractGateway(t)
+	ticker := fmt.Sprintf("BC%d", time.Now().UnixNano()%1000000)
+	url := gateway + "/api/backtest?ticker=" + ticker + "&days=10&interval=1day"
+
+	get := func(url string) *http.Response {
+		t.Helper()
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", url, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", url, resp.StatusCode)
+		}
+		return resp
+	}
+
+	first := get(url)
+	if first.Header.Get("X-Data-Source") == "cache" {
+		t.Errorf("Expected the first run computed")
+	}
+	second := get(url)
+	if second.Header.Get("X-Data-Source") != "cache" || second.Header.Get("X-Data-Age") == "" {
+		t.Errorf("Expected the repeat served from the cache, got headers %v", second.Header)
+	}
+	if artifact := first.Header.Get("X-Backtest-Artifact"); artifact == "" || second.Header.Get("X-Backtest-Artifact") != artifact {
+		t.Errorf("Expected cached results to point at the original artifact %q, got %q", artifact, second.Header.Get("X-Backtest-Artifact"))
+	}
+	if calls := len(trading.Calls("RunBacktest")); calls != 1 {
+		t.Errorf("Expected 1 trading service call for identical backtests, got %d", calls)
+	}
+
+	if resp := get(url + "&refresh=true"); resp.Header.Get("X-Data-Source") == "cache" {
+		t.Errorf("Expected refresh to bypass the cache")
+	}
+	get(url + "&profit_targets=3")
+	if calls := len(trading.Calls("RunBacktest")); calls != 3 {
+		t.Errorf("Expected refreshed and changed backtests to run, got %d calls", calls)
+	}
+
+	getJSON(t, url+"&refresh=maybe", http.StatusBadRequest, nil)
+}
@@ -0,0 +1,136 @@
+// tests/integration/backtestprogress_test.go
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// TestBacktestProgress checks a backtest job's progress is relayed to a
+// WebSocket client following it, from its current state to the stage it
+// finishes in, and that the client can cancel the job
+func TestBacktestProgress(t *testing.T) {
+	natsAddr := natsURL(t)
+	trading := startTradingService(t)
+	gateway := startGateway(t, natsAddr, trading.Addr)
+	ticker := fmt.Sprintf("BP%d", time.Now().UnixNano()%1000000)
+
+	responder, err := events.NewEventClient(natsAddr)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer responder.Close()
+
+	submit := func() string {
+		t.Helper()
+		resp, err := http.Post(gateway+"/api/backtest/jobs?ticker="+ticker+"&days=30", "application/json", nil)
+		if err != nil {
+			t.Fatalf("Failed to submit job: %v", err)
+		}
+		defer resp.Body.Close()
+		var job struct {
+			ID string `json:"id"`
+		}
+		json.NewDecoder(resp.Body).Decode(&job)
+		if resp.StatusCode != http.StatusAccepted || job.ID == "" {
+			t.Fatalf("Expected a queued job, got %d", resp.StatusCode)
+		}
+		return job.ID
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(gateway, "http")+"/api/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to gateway websocket: %v", err)
+	}
+	defer conn.Close()
+	send := func(msg map[string]interface{}) {
+		t.Helper()
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("Failed to send %v: %v", msg, err)
+		}
+	}
+	// next reads the next backtest progress event, or an error
+	next := func() map[string]interface{} {
+		t.Helper()
+		for {
+			conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("Failed to read message: %v", err)
+			}
+			if msg["event"] == "backtest_progress" || msg["error"] != nil {
+				return msg
+			}
+		}
+	}
+
+	// A running job is reported running first, then with the progress the
+	// trading service publishes for it
+	trading.Block.Store(true)
+	running := submit()
+	waitFor(t, 10*time.Second, "the job to reach the trading service", func() bool {
+		return len(trading.Calls("RunBacktest")) == 1
+	})
+	if req := trading.Calls("RunBacktest")[0].Request.(*pb.BacktestRequest); req.JobId != running {
+		t.Errorf("Expected the trading service told the job ID %s, got %q", running, req.JobId)
+	}
+
+	send(map[string]interface{}{"action": "subscribe", "type": "backtest", "job_id": running})
+	if msg := next(); msg["job_id"] != running || msg["stage"] != events.BacktestRunning {
+		t.Fatalf("Expected the job's running state, got %v", msg)
+	}
+	err = responder.PublishBacktestProgress(events.BacktestProgress{
+		JobID: running, Stage: events.BacktestRunning, Test: "2R",
+		BarsProcessed: 60, TotalBars: 120, Percent: 50, Trades: 4, WinRate: 0.5, PnL: 310,
+	})
+	if err != nil {
+		t.Fatalf("Failed to publish progress: %v", err)
+	}
+	if msg := next(); msg["bars_processed"] != 60.0 || msg["percent"] != 50.0 || msg["trades"] != 4.0 {
+		t.Errorf("Expected the published progress relayed, got %v", msg)
+	}
+
+	// Cancelling over the WebSocket stops the job and reports it
+	send(map[string]interface{}{"action": "cancel", "job_id": running})
+	if msg := next(); msg["stage"] != events.BacktestCancelled {
+		t.Errorf("Expected the job reported cancelled, got %v", msg)
+	}
+	var job struct {
+		State string `json:"state"`
+	}
+	getJSON(t, gateway+"/api/backtest/jobs/"+running, http.StatusOK, &job)
+	if job.State != "cancelled" {
+		t.Errorf("Expected the job cancelled, got %s", job.State)
+	}
+
+	// A job that runs to completion ends with done
+	trading.Block.Store(false)
+	done := submit()
+	send(map[string]interface{}{"action": "subscribe", "type": "backtest", "job_id": done})
+	for {
+		msg := next()
+		if msg["error"] != nil || msg["job_id"] != done {
+			t.Fatalf("Expected progress of %s, got %v", done, msg)
+		}
+		if msg["stage"] == events.BacktestDone {
+			if msg["percent"] != 100.0 {
+				t.Errorf("Expected a finished job at 100%%, got %v", msg)
+			}
+			break
+		}
+	}
+
+	send(map[string]interface{}{"action": "subscribe", "type": "backtest", "job_id": "job-0-0"})
+	if msg := next(); msg["error"] == nil {
+		t.Errorf("Expected an error following an unknown job, got %v", msg)
+	}
+}
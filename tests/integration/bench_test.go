@@ -0,0 +1,117 @@
+// tests/integration/bench_test.go
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// benchClient connects an event client for a benchmark
+func benchClient(b *testing.B, url string) *events.EventClient {
+	b.Helper()
+	client, err := events.NewEventClient(url)
+	if err != nil {
+		b.Fatalf("Failed to create event client: %v", err)
+	}
+	b.Cleanup(client.Close)
+	return client
+}
+
+// benchBar returns a live bar; i makes each bar distinct so none is deduplicated
+func benchBar(ticker string, i int) *market.MarketData {
+	price := 100 + float64(i%100)/10
+	return &market.MarketData{
+		Ticker:    ticker,
+		Timestamp: time.Unix(int64(i), 0),
+		Price:     price,
+		Open:      price,
+		High:      price + 0.1,
+		Low:       price - 0.1,
+		Close:     price,
+		Volume:    100,
+		Interval:  "1min",
+		DataType:  "live",
+	}
+}
+
+// benchTicker returns a ticker unique to this run
+func benchTicker(prefix string) string {
+	return fmt.Sprintf("%s%d", prefix, time.Now().UnixNano()%1000000)
+}
+
+// BenchmarkPublishLiveData measures acknowledged JetStream publishes from one goroutine
+func BenchmarkPublishLiveData(b *testing.B) {
+	client := benchClient(b, natsURL(b))
+	ticker := benchTicker("BP")
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := client.PublishMarketLiveData(ctx, ticker, benchBar(ticker, i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/s")
+}
+
+// BenchmarkPublishLiveDataParallel measures publishes from concurrent goroutines
+// sharing one client, as the market data service does across tickers
+func BenchmarkPublishLiveDataParallel(b *testing.B) {
+	client := benchClient(b, natsURL(b))
+	ticker := benchTicker("BPP")
+	ctx := context.Background()
+	var seq atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := int(seq.Add(1))
+			if err := client.PublishMarketLiveData(ctx, ticker, benchBar(ticker, i)); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/s")
+}
+
+// BenchmarkPublishSubscribe measures throughput from publish until a
+// subscriber on another connection has received every message
+func BenchmarkPublishSubscribe(b *testing.B) {
+	url := natsURL(b)
+	publisher := benchClient(b, url)
+	subscriber := benchClient(b, url)
+	ticker := benchTicker("BPS")
+	ctx := context.Background()
+
+	var received atomic.Int64
+	done := make(chan struct{})
+	sub, err := subscriber.SubscribeMarketLiveData(ticker, func([]byte) {
+		if received.Add(1) == int64(b.N) {
+			close(done)
+		}
+	})
+	if err != nil {
+		b.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := publisher.PublishMarketLiveData(ctx, ticker, benchBar(ticker, i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Minute):
+		b.Fatalf("Received %d of %d messages", received.Load(), b.N)
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "msgs/s")
+}
@@ -0,0 +1,41 @@
+// tests/integration/chaos_test.go
+package integration
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestInjectedGRPCErrorsDegradeGateway checks that trading service failures
+// injected by the gateway's fault layer put it into degraded mode
+func TestInjectedGRPCErrorsDegradeGateway(t *testing.T) {
+	trading := startTradingService(t)
+	gateway := startGateway(t, natsURL(t), trading.Addr,
+		"CHAOS_ENABLED=true",
+		"CHAOS_GRPC_ERROR_RATE=1",
+		"CHAOS_SEED=1",
+	)
+
+	getJSON(t, gateway+"/api/historical-data?ticker=SPY&days=5&interval=1day", http.StatusInternalServerError, nil)
+	if calls := len(trading.Calls("GetHistoricalData")); calls != 0 {
+		t.Errorf("Injected errors should fail calls before they are sent, trading service saw %d", calls)
+	}
+
+	var status struct {
+		Mode           string `json:"mode"`
+		FaultInjection struct {
+			GRPCErrors int64 `json:"grpc_errors"`
+			Seed       int64 `json:"seed"`
+		} `json:"fault_injection"`
+	}
+	getJSON(t, gateway+"/api/status", http.StatusOK, &status)
+	if status.Mode != "degraded" {
+		t.Errorf("Expected degraded mode after 3 failures, got %q", status.Mode)
+	}
+	if status.FaultInjection.GRPCErrors != 3 {
+		t.Errorf("Expected 3 injected errors, got %d", status.FaultInjection.GRPCErrors)
+	}
+	if status.FaultInjection.Seed != 1 {
+		t.Errorf("Expected the configured seed reported, got %d", status.FaultInjection.Seed)
+	}
+}
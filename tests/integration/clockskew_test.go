@@ -0,0 +1,72 @@
+// tests/integration/clockskew_test.go
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	eventhub "github.com/myapp/tradinglab/pkg/hub"
+	"github.com/nats-io/nats.go"
+)
+
+// TestClockSkew checks the hub flags a service instance whose heartbeats
+// carry a clock well ahead of its own, and not one that is in step
+func TestClockSkew(t *testing.T) {
+	h := NewHarness(t)
+
+	nc, err := nats.Connect(h.NATSURL)
+	if err != nil {
+		t.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	defer nc.Close()
+
+	suffix := time.Now().UnixNano()
+	inStep := fmt.Sprintf("in-step-%d", suffix)
+	ahead := fmt.Sprintf("ahead-%d", suffix)
+	beat := func(instance string, offset time.Duration) {
+		t.Helper()
+		payload, _ := json.Marshal(events.Heartbeat{
+			Service:         "skew-test",
+			Instance:        instance,
+			Timestamp:       time.Now().Add(offset).UTC(),
+			IntervalSeconds: 10,
+		})
+		if err := nc.Publish(fmt.Sprintf(events.SubjectHeartbeat, "skew-test"), payload); err != nil {
+			t.Fatalf("Failed to publish heartbeat: %v", err)
+		}
+	}
+	beat(inStep, 0)
+	beat(ahead, 10*time.Minute)
+
+	find := func(instance string) (eventhub.ServiceStatus, bool) {
+		for _, s := range h.Hub.Services() {
+			if s.Instance == instance {
+				return s, true
+			}
+		}
+		return eventhub.ServiceStatus{}, false
+	}
+	waitFor(t, 5*time.Second, "heartbeats to reach the hub", func() bool {
+		_, a := find(inStep)
+		_, b := find(ahead)
+		return a && b
+	})
+
+	if s, _ := find(inStep); s.ClockSkewed {
+		t.Errorf("Expected the in-step instance not to be skewed, got offset %.0fms", s.ClockOffsetMS)
+	}
+	s, _ := find(ahead)
+	if !s.ClockSkewed || s.ClockOffsetMS < float64(9*time.Minute/time.Millisecond) {
+		t.Errorf("Expected the instance about 10 minutes ahead to be skewed, got %+v", s)
+	}
+
+	// Back in step, the skew clears
+	beat(ahead, 0)
+	waitFor(t, 5*time.Second, "skew to clear", func() bool {
+		s, _ := find(ahead)
+		return !s.ClockSkewed
+	})
+}
@@ -0,0 +1,248 @@
+// tests/integration/contract_test.go
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// contractGateway runs the gateway against a fake trading service only
+func contractGateway(t *testing.T) (string, *fakeTradingService) {
+	t.Helper()
+	trading := startTradingService(t)
+	return startGateway(t, natsURL(t), trading.Addr), trading
+}
+
+// TestTradingServiceContract checks how the gateway calls each trading
+// service RPC and maps the responses to JSON, so a proto change that breaks
+// the REST layer fails here
+func TestTradingServiceContract(t *testing.T) {
+	gateway, trading := contractGateway(t)
+
+	t.Run("GetHistoricalData", func(t *testing.T) {
+		trading.Reset()
+		var candles []map[string]interface{}
+		getJSON(t, gateway+"/api/historical-data?ticker=%20spy&days=5&interval=15M", http.StatusOK, &candles)
+
+		req := onlyCall(t, trading, "GetHistoricalData", 20*time.Second).(*pb.HistoricalDataRequest)
+		if req.Ticker != "SPY" || req.Days != 5 || req.Interval != "15min" {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		if len(candles) != 5 {
+			t.Fatalf("Expected 5 candles, got %d", len(candles))
+		}
+		want := map[string]interface{}{
+			"date":   "2024-03-01 00:00:00",
+			"open":   100.0,
+			"high":   101.0,
+			"low":    99.0,
+			"close":  100.5,
+			"volume": 1000000.0,
+		}
+		if !reflect.DeepEqual(candles[0], want) {
+			t.Errorf("Unexpected candle mapping:\n got %v\nwant %v", candles[0], want)
+		}
+	})
+
+	t.Run("GetHistoricalDataRetries", func(t *testing.T) {
+		trading.Reset()
+		trading.FailNext("GetHistoricalData", 2)
+		getJSON(t, gateway+"/api/historical-data?ticker=SPY&days=6&interval=1day", http.StatusOK, nil)
+		if calls := len(trading.Calls("GetHistoricalData")); calls != 3 {
+			t.Errorf("Expected 3 attempts, got %d", calls)
+		}
+
+		// Nothing cached for this query, so exhausted retries are an error
+		trading.Reset()
+		trading.Fail.Store(true)
+		getJSON(t, gateway+"/api/historical-data?ticker=SPY&days=7&interval=1day", http.StatusInternalServerError, nil)
+		if calls := len(trading.Calls("GetHistoricalData")); calls != 3 {
+			t.Errorf("Expected 3 attempts, got %d", calls)
+		}
+	})
+
+	t.Run("GenerateSignals", func(t *testing.T) {
+		trading.Reset()
+		trading.FailNext("GenerateSignals", 1)
+		var signals []map[string]interface{}
+		getJSON(t, gateway+"/api/signals?ticker=spy&days=10&interval=1h", http.StatusOK, &signals)
+
+		calls := trading.Calls("GenerateSignals")
+		if len(calls) != 2 {
+			t.Fatalf("Expected a retry after one failure, got %d attempts", len(calls))
+		}
+		checkDeadline(t, calls[0], 20*time.Second)
+		req := calls[1].Request.(*pb.SignalRequest)
+		if req.Ticker != "SPY" || req.Days != 10 || req.Interval != "60min" || req.Strategy == "" {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		if len(signals) != 1 {
+			t.Fatalf("Expected 1 signal, got %d", len(signals))
+		}
+		for key, want := range map[string]interface{}{
+			"date":        "2024-03-04 10:00:00",
+			"signal_type": "LONG",
+			"entry_price": 101.5,
+			"stoploss":    99.5,
+		} {
+			if signals[0][key] != want {
+				t.Errorf("Signal %s: expected %v, got %v", key, want, signals[0][key])
+			}
+		}
+	})
+
+	t.Run("RunBacktest", func(t *testing.T) {
+		trading.Reset()
+		var results map[string]map[string]interface{}
+		getJSON(t, gateway+"/api/backtest?ticker=SPY&days=20&interval=15min&profit_targets=1,2&risk_reward_ratios=1.5,x&profit_targets_dollar=100", http.StatusOK, &results)
+
+		req := onlyCall(t, trading, "RunBacktest", 30*time.Second).(*pb.BacktestRequest)
+		if req.Ticker != "SPY" || req.Days != 20 || req.Interval != "15min" || req.Strategy == "" || req.Benchmark != "SPY" {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+		if !reflect.DeepEqual(req.ProfitTargets, []float64{1, 2}) ||
+			!reflect.DeepEqual(req.RiskRewardRatios, []float64{1.5}) ||
+			!reflect.DeepEqual(req.ProfitTargetsDollar, []float64{100}) {
+			t.Errorf("Unexpected targets: %v %v %v", req.ProfitTargets, req.RiskRewardRatios, req.ProfitTargetsDollar)
+		}
+
+		want := map[string]interface{}{
+			"win_rate":         0.6,
+			"profit_factor":    1.8,
+			"total_return":     1250.0,
+			"total_return_pct": 12.5,
+			"total_trades":     10.0,
+			"winning_trades":   6.0,
+			"losing_trades":    4.0,
+			"max_drawdown":     300.0,
+			"max_drawdown_pct": 3.0,
+			"commission":       0.0,
+			"slippage":         0.0,
+			"partial_fills":    0.0,
+			"sharpe_ratio":     1.2,
+		}
+		if !reflect.DeepEqual(results["2R"], want) {
+			t.Errorf("Unexpected result mapping:\n got %v\nwant %v", results["2R"], want)
+		}
+
+		// Relative metrics can be measured against another benchmark
+		trading.Reset()
+		getJSON(t, gateway+"/api/backtest?ticker=SPY&benchmark=qqq", http.StatusOK, nil)
+		if req := onlyCall(t, trading, "RunBacktest", 30*time.Second).(*pb.BacktestRequest); req.Benchmark != "QQQ" {
+			t.Errorf("Expected the QQQ benchmark, got %q", req.Benchmark)
+		}
+		getJSON(t, gateway+"/api/backtest?ticker=SPY&benchmark=not+a+ticker", http.StatusBadRequest, nil)
+
+		// Backtests are not retried
+		trading.Reset()
+		trading.FailNext("RunBacktest", 1)
+		getJSON(t, gateway+"/api/backtest?ticker=SPY", http.StatusInternalServerError, nil)
+		if calls := len(trading.Calls("RunBacktest")); calls != 1 {
+			t.Errorf("Expected 1 attempt, got %d", calls)
+		}
+	})
+
+	t.Run("GetOptionsRecommendations", func(t *testing.T) {
+		trading.Reset()
+		var recs []map[string]interface{}
+		getJSON(t, gateway+"/api/recommendations?ticker=SPY&days=15&interval=1day&strategy=RedCandle", http.StatusOK, &recs)
+
+		req := onlyCall(t, trading, "GetOptionsRecommendations", 10*time.Second).(*pb.RecommendationRequest)
+		want := &pb.RecommendationRequest{Ticker: "SPY", Days: 15, Strategy: "RedCandle", Interval: "1day"}
+		if req.Ticker != want.Ticker || req.Days != want.Days || req.Strategy != want.Strategy || req.Interval != want.Interval {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		if len(recs) != 1 {
+			t.Fatalf("Expected 1 recommendation, got %d", len(recs))
+		}
+		for key, want := range map[string]interface{}{
+			"ticker":      "SPY",
+			"date":        "2024-03-04 10:00:00",
+			"signal_type": "LONG",
+			"stock_price": 101.5,
+			"stoploss":    99.5,
+			"option_type": "CALL",
+			"strike":      102.0,
+			"expiration":  "2024-03-15",
+			"delta":       0.45,
+			"iv":          0.22,
+			"price":       1.35,
+		} {
+			if recs[0][key] != want {
+				t.Errorf("Recommendation %s: expected %v, got %v", key, want, recs[0][key])
+			}
+		}
+
+		// Each recommendation is tracked by ID from the moment it is returned
+		id, _ := recs[0]["id"].(string)
+		if id == "" || recs[0]["status"] != "open" {
+			t.Fatalf("Expected an open tracked recommendation, got id %q status %v", id, recs[0]["status"])
+		}
+		var tracked map[string]interface{}
+		getJSON(t, gateway+"/api/recommendations/"+id, http.StatusOK, &tracked)
+		if tracked["status"] != "open" || tracked["suggested_price"] != 1.35 || tracked["expiration"] != "2024-03-15" {
+			t.Errorf("Unexpected tracked recommendation: %v", tracked)
+		}
+	})
+
+	t.Run("GetOptionsRecommendationsTimeout", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("waits for the gateway's 10s deadline")
+		}
+		trading.Reset()
+		trading.Block.Store(true)
+
+		start := time.Now()
+		getJSON(t, gateway+"/api/recommendations?ticker=SPY", http.StatusInternalServerError, nil)
+		if elapsed := time.Since(start); elapsed < 9*time.Second || elapsed > 14*time.Second {
+			t.Errorf("Expected the call to give up after about 10s, took %v", elapsed)
+		}
+	})
+}
+
+// onlyCall returns the single request made to method, checking the caller's
+// deadline was close to timeout
+func onlyCall(t *testing.T, trading *fakeTradingService, method string, timeout time.Duration) interface{} {
+	t.Helper()
+	calls := trading.Calls(method)
+	if len(calls) != 1 {
+		t.Fatalf("Expected 1 call to %s, got %d", method, len(calls))
+	}
+	checkDeadline(t, calls[0], timeout)
+	return calls[0].Request
+}
+
+// checkDeadline checks a call arrived with a deadline close to timeout
+func checkDeadline(t *testing.T, call rpcCall, timeout time.Duration) {
+	t.Helper()
+	if call.Deadline <= 0 || call.Deadline > timeout || call.Deadline < timeout-5*time.Second {
+		t.Errorf("Expected a deadline of about %v, got %v", timeout, call.Deadline)
+	}
+}
+
+// getJSON fetches a URL, checks the status and decodes the body into v if set
+func getJSON(t *testing.T, url string, status int, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		t.Fatalf("GET %s: expected %d, got %d", url, status, resp.StatusCode)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("GET %s: invalid JSON: %v", url, err)
+		}
+	}
+}
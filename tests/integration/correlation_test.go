@@ -0,0 +1,68 @@
+// tests/integration/correlation_test.go
+package integration
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestCorrelationMatrix checks correlations are computed from daily data
+// over each requested window, served from the cache on repeat requests, and
+// that tickers without daily data are reported rather than failing the
+// request
+func TestCorrelationMatrix(t *testing.T) {
+	suffix := time.Now().UnixNano() % 100000
+	first, second, third := fmt.Sprintf("CA%d", suffix), fmt.Sprintf("CB%d", suffix), fmt.Sprintf("CC%d", suffix)
+	trading := startTradingService(t)
+	gateway := startGateway(t, natsURL(t), trading.Addr, "CORRELATION_SCHEDULE=off")
+
+	type report struct {
+		Tickers  []string `json:"tickers"`
+		Missing  []string `json:"missing"`
+		Matrices []struct {
+			Window       int         `json:"window"`
+			Observations int         `json:"observations"`
+			Tickers      []string    `json:"tickers"`
+			Values       [][]float64 `json:"values"`
+		} `json:"matrices"`
+	}
+
+	// The fake trading service serves every ticker the same prices, so
+	// each pair moves in lockstep
+	url := gateway + "/api/analytics/correlation?tickers=" + second + "," + first + "&windows=10,5"
+	var got report
+	getJSON(t, url, http.StatusOK, &got)
+	if len(got.Tickers) != 2 || got.Tickers[0] != first || len(got.Matrices) != 2 {
+		t.Fatalf("Expected two matrices for %s and %s, got %+v", first, second, got)
+	}
+	for i, window := range []int{5, 10} {
+		m := got.Matrices[i]
+		if m.Window != window || m.Observations != window {
+			t.Errorf("Expected %d returns in the %d-session window, got %+v", window, window, m)
+		}
+		if len(m.Values) != 2 || math.Abs(m.Values[0][1]-1) > 1e-9 || m.Values[1][0] != m.Values[0][1] || m.Values[0][0] != 1 {
+			t.Errorf("Expected perfect correlation in the %d-session window, got %v", window, m.Values)
+		}
+	}
+
+	// A repeat request is served from the cache
+	calls := len(trading.Calls("GetHistoricalData"))
+	getJSON(t, url, http.StatusOK, &got)
+	if n := len(trading.Calls("GetHistoricalData")); n != calls {
+		t.Errorf("Expected the cached matrices, but the trading service was called %d more times", n-calls)
+	}
+
+	// Tickers without daily data are listed and left out of the matrices
+	trading.FailNext("GetHistoricalData", 1)
+	got = report{}
+	getJSON(t, gateway+"/api/analytics/correlation?tickers="+first+","+second+","+third+"&windows=5", http.StatusOK, &got)
+	if len(got.Missing) != 1 || got.Missing[0] != first || len(got.Matrices) != 1 || len(got.Matrices[0].Tickers) != 2 {
+		t.Errorf("Expected %s missing and a matrix of the other two, got %+v", first, got)
+	}
+
+	getJSON(t, gateway+"/api/analytics/correlation?tickers="+first, http.StatusBadRequest, nil)
+	getJSON(t, gateway+"/api/analytics/correlation?tickers="+first+","+second+"&windows=1000", http.StatusBadRequest, nil)
+}
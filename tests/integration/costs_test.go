@@ -0,0 +1,91 @@
+// tests/integration/costs_test.go
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// TestExecutionCosts checks paper positions are filled across the spread,
+// capped by bar volume and charged commission, and that backtests are sent
+// the same costs unless the request overrides them
+func TestExecutionCosts(t *testing.T) {
+	ticker := fmt.Sprintf("EC%d", time.Now().UnixNano()%1000000)
+	trading := startTradingService(t)
+	gateway := startGateway(t, natsURL(t), trading.Addr,
+		"EXECUTION_COMMISSION_FIXED=1",
+		"EXECUTION_SPREAD_PERCENT=0.2",
+		"EXECUTION_MAX_VOLUME_PERCENT=10")
+
+	open := func(quantity int, volume float64) (*http.Response, map[string]interface{}) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]interface{}{
+			"ticker": ticker, "direction": "LONG", "quantity": quantity, "entry_price": 100.0, "bar_volume": volume, "simulated": true,
+		})
+		resp, err := http.Post(gateway+"/api/risk/positions", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to open position: %v", err)
+		}
+		defer resp.Body.Close()
+		var pos map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&pos)
+		return resp, pos
+	}
+	near := func(v interface{}, want float64) bool {
+		f, _ := v.(float64)
+		return math.Abs(f-want) < 1e-6
+	}
+
+	// Ten percent of a 500 share bar fills 50 of the 100 requested, half a
+	// 0.2% spread above the quote
+	resp, pos := open(100, 500)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201 opening position, got %d", resp.StatusCode)
+	}
+	if !near(pos["quantity"], 50) || !near(pos["requested_quantity"], 100) || !near(pos["entry_price"], 100.1) ||
+		!near(pos["quoted_price"], 100) || !near(pos["commission"], 1) || !near(pos["slippage"], 5) {
+		t.Errorf("Unexpected entry fill: %v", pos)
+	}
+
+	// The exit sells half a spread below the quote and pays commission again
+	req, _ := http.NewRequest(http.MethodDelete, gateway+"/api/risk/positions/"+pos["id"].(string)+"?exit_price=110", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to close position: %v", err)
+	}
+	var closed map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&closed)
+	resp.Body.Close()
+	if !near(closed["exit_price"], 109.89) || !near(closed["exit_commission"], 1) || !near(closed["realized_pnl"], 9.79*50-2) {
+		t.Errorf("Unexpected exit fill: %v", closed)
+	}
+
+	// A bar too thin for a single share fills nothing
+	if resp, _ := open(100, 5); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unfillable position, got %d", resp.StatusCode)
+	}
+
+	// Backtests default to the paper trading costs
+	getJSON(t, gateway+"/api/backtest?ticker=SPY", http.StatusOK, nil)
+	bt := onlyCall(t, trading, "RunBacktest", 30*time.Second).(*pb.BacktestRequest)
+	if c := bt.Costs; c == nil || c.CommissionFixed != 1 || c.SpreadPercent != 0.2 || c.MaxVolumePercent != 10 || c.CommissionPercent != 0 {
+		t.Errorf("Expected the default costs, got %+v", bt.Costs)
+	}
+
+	trading.Reset()
+	getJSON(t, gateway+"/api/backtest?ticker=SPY&spread_percent=0.5&commission_percent=0.01", http.StatusOK, nil)
+	bt = onlyCall(t, trading, "RunBacktest", 30*time.Second).(*pb.BacktestRequest)
+	if c := bt.Costs; c == nil || c.SpreadPercent != 0.5 || c.CommissionPercent != 0.01 || c.CommissionFixed != 1 {
+		t.Errorf("Expected overridden costs, got %+v", bt.Costs)
+	}
+
+	getJSON(t, gateway+"/api/backtest?ticker=SPY&commission_fixed=-1", http.StatusBadRequest, nil)
+	getJSON(t, gateway+"/api/backtest?ticker=SPY&max_volume_percent=x", http.StatusBadRequest, nil)
+}
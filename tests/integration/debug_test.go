@@ -0,0 +1,85 @@
+// tests/integration/debug_test.go
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/profiling"
+)
+
+// TestDebugEndpoints checks pprof and expvar are hidden unless
+// DEBUG_ENDPOINTS is set, and then need an admin credential
+func TestDebugEndpoints(t *testing.T) {
+	nats, trading := natsURL(t), startTradingService(t).Addr
+
+	get := func(url, token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", url, err)
+		}
+		return resp
+	}
+
+	disabled := startGateway(t, nats, trading, "ALERT_DIGEST_SCHEDULE=off", "ADMIN_TOKEN=debug-admin")
+	resp := get(disabled+"/debug/pprof/", "debug-admin")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 with debug endpoints disabled, got %d", resp.StatusCode)
+	}
+
+	enabled := startGateway(t, nats, trading, "ALERT_DIGEST_SCHEDULE=off", "ADMIN_TOKEN=debug-admin", "DEBUG_ENDPOINTS=true")
+	resp = get(enabled+"/debug/pprof/goroutine?debug=1", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", resp.StatusCode)
+	}
+
+	resp = get(enabled+"/debug/pprof/goroutine?debug=1", "debug-admin")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for the goroutine profile, got %d", resp.StatusCode)
+	}
+
+	resp = get(enabled+"/debug/vars", "debug-admin")
+	defer resp.Body.Close()
+	var vars map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil {
+		t.Fatalf("Failed to decode /debug/vars: %v", err)
+	}
+	if _, ok := vars["goroutines"]; !ok {
+		t.Errorf("Expected a goroutines variable in /debug/vars, got keys %v", vars)
+	}
+}
+
+// TestDebugProfileOutlastsWriteTimeout checks a CPU profile longer than the
+// server's write timeout is still served in full
+func TestDebugProfileOutlastsWriteTimeout(t *testing.T) {
+	allow := func(http.ResponseWriter, *http.Request) bool { return true }
+	server := httptest.NewUnstartedServer(profiling.Guard(http.NotFoundHandler(), true, allow))
+	server.Config.WriteTimeout = time.Second
+	server.Start()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/debug/pprof/profile?seconds=2")
+	if err != nil {
+		t.Fatalf("Profile request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Profile was cut off: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || len(body) == 0 {
+		t.Errorf("Expected a 200 profile, got %d: %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,56 @@
+// tests/integration/dependencies_test.go
+package integration
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestDependencyFailuresTrackedSeparately checks failures of one dependency
+// degrade the gateway and are reported against that dependency alone, and
+// that calls succeeding against another dependency do not recover it
+func TestDependencyFailuresTrackedSeparately(t *testing.T) {
+	trading := startTradingService(t)
+	gateway := startGateway(t, natsURL(t), trading.Addr,
+		"SERVICE_PROBE_INTERVAL=0",
+		"SERVICE_MIN_MODE_DURATION=0s",
+		"SERVICE_RECOVERY_SUCCESSES=1",
+	)
+
+	type dependency struct {
+		Status   string `json:"status"`
+		Calls    int    `json:"calls"`
+		Failures int    `json:"failures"`
+	}
+	var status struct {
+		Mode         string                `json:"mode"`
+		Description  string                `json:"description"`
+		Dependencies map[string]dependency `json:"dependencies"`
+	}
+
+	// No market data service answers symbol searches in the harness
+	for i := 0; i < 3; i++ {
+		getJSON(t, gateway+"/api/symbols/search?q=AP", http.StatusServiceUnavailable, nil)
+	}
+	getJSON(t, gateway+"/api/status", http.StatusOK, &status)
+	if status.Mode != "degraded" || !strings.Contains(status.Description, "provider") {
+		t.Errorf("Expected degraded mode blaming the provider, got %q: %s", status.Mode, status.Description)
+	}
+	if provider := status.Dependencies["provider"]; provider.Status != "failing" || provider.Failures != 3 {
+		t.Errorf("Expected the provider failing with 3 failures, got %+v", provider)
+	}
+	if nats := status.Dependencies["nats"]; nats.Status != "ok" || nats.Failures != 0 {
+		t.Errorf("Expected NATS ok, got %+v", nats)
+	}
+
+	// The trading service answering does not clear the provider's failures
+	getJSON(t, gateway+"/api/historical-data?ticker=SPY&days=5&interval=1day", http.StatusOK, nil)
+	getJSON(t, gateway+"/api/status", http.StatusOK, &status)
+	if grpc := status.Dependencies["grpc"]; grpc.Status != "ok" || grpc.Calls != 1 || grpc.Failures != 0 {
+		t.Errorf("Expected one successful gRPC call, got %+v", grpc)
+	}
+	if status.Mode != "degraded" {
+		t.Errorf("Expected the gateway to stay degraded while the provider fails, got %q", status.Mode)
+	}
+}
@@ -0,0 +1,62 @@
+// tests/integration/diagnostics_test.go
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestDiagnostics checks /api/diagnostics reports each probe, needs the
+// config permission, and fails when the market data service cannot answer
+// the provider clock probe
+func TestDiagnostics(t *testing.T) {
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"ADMIN_TOKEN=diag-admin",
+	)
+
+	resp, err := http.Get(gateway + "/api/diagnostics")
+	if err != nil {
+		t.Fatalf("Failed to request diagnostics: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an anonymous caller, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, gateway+"/api/diagnostics", nil)
+	req.Header.Set("Authorization", "Bearer diag-admin")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to request diagnostics: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var report struct {
+		Status string `json:"status"`
+		Checks []struct {
+			Name      string  `json:"name"`
+			Pass      bool    `json:"pass"`
+			LatencyMS float64 `json:"latency_ms"`
+			Error     string  `json:"error"`
+		} `json:"checks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode diagnostics: %v", err)
+	}
+
+	// No market data service runs in the harness, so the clock probe fails
+	if resp.StatusCode != http.StatusServiceUnavailable || report.Status != "fail" {
+		t.Errorf("Expected 503 and status fail, got %d and %q", resp.StatusCode, report.Status)
+	}
+	want := map[string]bool{"nats": true, "jetstream": true, "grpc": true, "provider_clock": false, "cache": true}
+	if len(report.Checks) != len(want) {
+		t.Fatalf("Expected %d checks, got %+v", len(want), report.Checks)
+	}
+	for _, check := range report.Checks {
+		if pass, ok := want[check.Name]; !ok || check.Pass != pass {
+			t.Errorf("Check %s: expected pass=%v, got pass=%v (%s)", check.Name, pass, check.Pass, check.Error)
+		}
+	}
+}
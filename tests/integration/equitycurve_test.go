@@ -0,0 +1,92 @@
+// tests/integration/equitycurve_test.go
+package integration
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestBacktestEquityCurve checks a stored backtest's equity curve and
+// drawdown are served downsampled, keeping the ends and the turning points,
+// for single-ticker and portfolio backtests
+func TestBacktestEquityCurve(t *testing.T) {
+	gateway, _ := contractGateway(t)
+	ticker := fmt.Sprintf("EC%d", time.Now().UnixNano()%1000000)
+
+	// artifact runs a backtest and returns the name it was stored under
+	artifact := func(url string) string {
+		t.Helper()
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("Backtest failed: %v", err)
+		}
+		resp.Body.Close()
+		name := resp.Header.Get("X-Backtest-Artifact")
+		if resp.StatusCode != http.StatusOK || name == "" {
+			t.Fatalf("Expected a stored backtest, got %d", resp.StatusCode)
+		}
+		return name
+	}
+	type chart struct {
+		Points int `json:"points"`
+		Series map[string][]struct {
+			Date        string  `json:"date"`
+			Equity      float64 `json:"equity"`
+			Drawdown    float64 `json:"drawdown"`
+			DrawdownPct float64 `json:"drawdown_pct"`
+		} `json:"series"`
+	}
+
+	id := artifact(gateway + "/api/backtest?ticker=" + ticker + "&days=30")
+	base := gateway + "/api/backtest/" + id
+
+	var equity chart
+	getJSON(t, base+"/equity-curve", http.StatusOK, &equity)
+	curve := equity.Series["2R"]
+	if equity.Points != 500 || len(curve) != 500 {
+		t.Fatalf("Expected the curve downsampled to 500 points, got %d", len(curve))
+	}
+	if curve[0].Date != "2024-03-04T14:30:00Z" || curve[len(curve)-1].Equity != 1250 {
+		t.Errorf("Expected the first and last bars kept, got %+v and %+v", curve[0], curve[len(curve)-1])
+	}
+	peak := 0.0
+	for i, point := range curve {
+		peak = math.Max(peak, point.Equity)
+		if i > 0 && point.Date <= curve[i-1].Date {
+			t.Fatalf("Expected points in time order, got %s after %s", point.Date, curve[i-1].Date)
+		}
+	}
+	if peak != 1550 {
+		t.Errorf("Expected the peak of 1550 kept, got %v", peak)
+	}
+
+	getJSON(t, base+"/equity-curve?points=50&test=2R", http.StatusOK, &equity)
+	if len(equity.Series["2R"]) != 50 {
+		t.Errorf("Expected 50 points, got %d", len(equity.Series["2R"]))
+	}
+
+	var drawdown chart
+	getJSON(t, base+"/drawdown?points=100", http.StatusOK, &drawdown)
+	dd := drawdown.Series["2R"]
+	if len(dd) != 100 || dd[0].Drawdown != 0 {
+		t.Fatalf("Expected 100 drawdown points starting at zero, got %d", len(dd))
+	}
+	if last := dd[len(dd)-1]; math.Abs(last.Drawdown-300) > 1e-9 || math.Abs(last.DrawdownPct-300.0/1550*100) > 1e-9 {
+		t.Errorf("Expected a drawdown of 300 from the 1550 peak, got %+v", last)
+	}
+
+	getJSON(t, base+"/equity-curve?test=3R", http.StatusNotFound, nil)
+	getJSON(t, base+"/drawdown?points=2", http.StatusBadRequest, nil)
+	getJSON(t, gateway+"/api/backtest/20240101T000000.000000000-NONE-RedCandle.json/equity-curve", http.StatusNotFound, nil)
+
+	// Portfolio curves are daily equity from the initial capital
+	id = artifact(gateway + "/api/backtest/portfolio?tickers=AAPL,MSFT&days=30&capital=10000")
+	getJSON(t, gateway+"/api/backtest/"+id+"/drawdown", http.StatusOK, &drawdown)
+	dd = drawdown.Series["2R"]
+	if len(dd) != 3 || dd[0].Date != "2024-03-04" || dd[2].Drawdown != 300 {
+		t.Errorf("Expected the portfolio's daily drawdowns in date order, got %+v", dd)
+	}
+}
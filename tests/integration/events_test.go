@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"log"
-	"os"
 	"testing"
 	"time"
 
@@ -14,11 +13,8 @@ import (
 
 // TestEventFlow tests the complete flow of events through the system
 func TestEventFlow(t *testing.T) {
-	// Get NATS URL from environment or use default for testing
-	natsURL := os.Getenv("NATS_URL")
-	if natsURL == "" {
-		natsURL = "nats://localhost:4222"
-	}
+	// Use NATS_URL or a nats-server started for the test
+	natsURL := natsURL(t)
 
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -42,8 +38,8 @@ func TestEventFlow(t *testing.T) {
 	receivedEvents := make(chan map[string]interface{}, 5)
 
 	// Subscribe to test events
-	testTicker := "TEST_TICKER"
-	_, err = subscriber.SubscribeMarketData(testTicker, func(data []byte) {
+	testTicker := "TESTTICKER"
+	_, err = subscriber.SubscribeMarketLiveData(testTicker, func(data []byte) {
 		var event map[string]interface{}
 		if err := json.Unmarshal(data, &event); err != nil {
 			t.Errorf("Failed to unmarshal event: %v", err)
@@ -68,7 +64,7 @@ func TestEventFlow(t *testing.T) {
 			"test_id":   i,
 		}
 
-		if err := publisher.PublishMarketData(ctx, testTicker, testEvent); err != nil {
+		if err := publisher.PublishMarketLiveData(ctx, testTicker, testEvent); err != nil {
 			t.Fatalf("Failed to publish test event: %v", err)
 		}
 		log.Printf("Published test event %d", i)
@@ -0,0 +1,64 @@
+// tests/integration/exports_test.go
+package integration
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHistoricalExport checks an export is written to the file object store
+// under ARTIFACTS_DIR and can be downloaded through the gateway
+func TestHistoricalExport(t *testing.T) {
+	artifactsDir := t.TempDir()
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"ARTIFACTS_DIR="+artifactsDir,
+	)
+
+	resp, err := http.Post(gateway+"/api/exports/historical?ticker=aapl&interval=1day&days=5", "", nil)
+	if err != nil {
+		t.Fatalf("Export request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
+	}
+	var export struct {
+		Download struct {
+			Key string `json:"key"`
+			URL string `json:"url"`
+		} `json:"download"`
+		Rows int `json:"rows"`
+	}
+	json.NewDecoder(resp.Body).Decode(&export)
+	if !strings.HasPrefix(export.Download.Key, "exports/") || export.Download.URL != "/api/downloads/"+export.Download.Key || export.Rows == 0 {
+		t.Fatalf("Unexpected export response %+v", export)
+	}
+	if _, err := os.Stat(filepath.Join(artifactsDir, filepath.FromSlash(export.Download.Key))); err != nil {
+		t.Errorf("Expected the export in the artifacts directory: %v", err)
+	}
+
+	download, err := http.Get(gateway + export.Download.URL)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer download.Body.Close()
+	if download.StatusCode != http.StatusOK || !strings.HasPrefix(download.Header.Get("Content-Type"), "text/csv") {
+		t.Fatalf("Expected a CSV download, got %d %s", download.StatusCode, download.Header.Get("Content-Type"))
+	}
+	records, err := csv.NewReader(download.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(records) != export.Rows+1 || records[0][0] != "time" {
+		t.Errorf("Expected a header and %d rows, got %d records", export.Rows, len(records))
+	}
+
+	getJSON(t, gateway+"/api/downloads/exports/missing.csv", http.StatusNotFound, nil)
+	getJSON(t, gateway+"/api/downloads/snapshots/x.tar", http.StatusNotFound, nil)
+}
@@ -0,0 +1,144 @@
+// tests/integration/features_test.go
+package integration
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFeatureExport checks feature matrices are built from bars as a job,
+// labelled by forward returns or by signal outcomes, and stored as CSV or
+// Parquet for download
+func TestFeatureExport(t *testing.T) {
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"ARTIFACTS_DIR="+t.TempDir(),
+	)
+
+	type result struct {
+		State  string `json:"state"`
+		Error  string `json:"error"`
+		Result struct {
+			Download struct {
+				URL string `json:"url"`
+			} `json:"download"`
+			Rows    int      `json:"rows"`
+			Columns []string `json:"columns"`
+			Signals int      `json:"signals"`
+		} `json:"result"`
+	}
+	export := func(query, body string, status int) result {
+		t.Helper()
+		resp, err := http.Post(gateway+"/api/exports/features?ticker=aapl&"+query, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to submit export: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("Exporting %s %s: expected %d, got %d", query, body, status, resp.StatusCode)
+		}
+		var job struct {
+			ID string `json:"id"`
+		}
+		var finished result
+		if json.NewDecoder(resp.Body).Decode(&job); job.ID == "" {
+			return finished
+		}
+		waitFor(t, 30*time.Second, "the export to finish", func() bool {
+			getJSON(t, gateway+"/api/backtest/jobs/"+job.ID, http.StatusOK, &finished)
+			return finished.State == "done" || finished.State == "failed"
+		})
+		return finished
+	}
+	download := func(url, contentType string) []byte {
+		t.Helper()
+		resp, err := http.Get(gateway + url)
+		if err != nil {
+			t.Fatalf("Download failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), contentType) {
+			t.Fatalf("Expected a %s download, got %d %s", contentType, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		data, _ := io.ReadAll(resp.Body)
+		return data
+	}
+
+	// 30 daily bars leave rows from the 20-bar window to 5 bars before the end
+	job := export("days=30", "", http.StatusAccepted)
+	if job.State != "done" || job.Result.Rows != 5 {
+		t.Fatalf("Expected 5 rows exported, got %s with %d: %s", job.State, job.Result.Rows, job.Error)
+	}
+	records, err := csv.NewReader(bytes.NewReader(download(job.Result.Download.URL, "text/csv"))).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(records) != 6 || strings.Join(records[0], ",") != strings.Join(job.Result.Columns, ",") {
+		t.Fatalf("Expected the header %v and 5 rows, got %v", job.Result.Columns, records)
+	}
+	header := records[0]
+	if header[0] != "time" || header[1] != "return_1" || header[len(header)-1] != "label" {
+		t.Errorf("Expected time, features then the label, got %v", header)
+	}
+	if records[1][0] != "2024-03-21T00:00:00Z" {
+		t.Errorf("Expected the first row on the 21st bar, got %s", records[1][0])
+	}
+	label, _ := strconv.ParseFloat(records[1][len(header)-1], 64)
+	if want := (125.5/120.5 - 1) * 100; math.Abs(label-want) > 1e-9 {
+		t.Errorf("Expected the 5-bar forward return %v as the label, got %v", want, label)
+	}
+
+	// The fake signal on the fourth bar reaches 2R two bars later
+	job = export("days=30&strategy=RedCandle&format=parquet",
+		`{"lookback": 2, "windows": [2], "label": "signal_outcome", "horizon": 3}`, http.StatusAccepted)
+	if job.State != "done" || job.Result.Rows != 1 || job.Result.Signals != 1 {
+		t.Fatalf("Expected one signal row exported, got %s with %d: %s", job.State, job.Result.Rows, job.Error)
+	}
+	if columns := strings.Join(job.Result.Columns, ","); !strings.Contains(columns, "signal,signal_risk_pct,label") {
+		t.Errorf("Expected signal columns, got %s", columns)
+	}
+	data := download(job.Result.Download.URL, "application/vnd.apache.parquet")
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatalf("Expected a Parquet file, got %d bytes", len(data))
+	}
+	footer := binary.LittleEndian.Uint32(data[len(data)-8:])
+	if int(footer) >= len(data)-12 || !bytes.Contains(data[len(data)-8-int(footer):], []byte("signal_risk_pct")) {
+		t.Errorf("Expected the schema in the Parquet footer")
+	}
+
+	// A signal whose target is not reached in time has no outcome
+	job = export("days=30&strategy=RedCandle",
+		`{"lookback": 2, "windows": [2], "label": "signal_outcome", "horizon": 1}`, http.StatusAccepted)
+	records, _ = csv.NewReader(bytes.NewReader(download(job.Result.Download.URL, "text/csv"))).ReadAll()
+	if len(records) != 2 || records[1][len(records[1])-1] != "0" {
+		t.Errorf("Expected one row labelled 0, got %v", records)
+	}
+
+	// Too few bars for the windows fails the job
+	job = export("days=10", `{"windows": [20]}`, http.StatusAccepted)
+	if job.State != "failed" || !strings.Contains(job.Error, "too few") {
+		t.Errorf("Expected the export failed for lack of bars, got %s: %s", job.State, job.Error)
+	}
+
+	for _, tc := range []struct{ query, body string }{
+		{"format=xlsx", ""},
+		{"", `{"label": "signal_outcome"}`},
+		{"", `{"label": "price"}`},
+		{"", `{"windows": [1]}`},
+		{"", `{"windows": [5, 5]}`},
+		{"", `{"horizon": -1}`},
+		{"", `{"lookback": `},
+		{"strategy=Unknown", ""},
+	} {
+		export(tc.query, tc.body, http.StatusBadRequest)
+	}
+}
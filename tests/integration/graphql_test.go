@@ -0,0 +1,150 @@
+// tests/integration/graphql_test.go
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// graphqlResponse is a GraphQL result as returned by the gateway
+type graphqlResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message string        `json:"message"`
+		Path    []interface{} `json:"path"`
+	} `json:"errors"`
+}
+
+// TestGraphQL queries the gateway's GraphQL endpoint, checking field
+// selection, arguments, nested resolution and batching
+func TestGraphQL(t *testing.T) {
+	gateway, trading := contractGateway(t)
+
+	t.Run("FieldSelection", func(t *testing.T) {
+		trading.Reset()
+		var resp graphqlResponse
+		postGraphQL(t, gateway, map[string]interface{}{
+			"query":     `query View($days: Int) { candles(ticker: "spy", days: $days, interval: "1day") { close } portfolio { positions { ticker } } }`,
+			"variables": map[string]interface{}{"days": 3},
+		}, &resp)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("Unexpected errors: %+v", resp.Errors)
+		}
+
+		req := onlyCall(t, trading, "GetHistoricalData", 30*time.Second).(*pb.HistoricalDataRequest)
+		if req.Ticker != "SPY" || req.Days != 3 || req.Interval != "1day" {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		candles, _ := resp.Data["candles"].([]interface{})
+		if len(candles) != 3 {
+			t.Fatalf("Expected 3 candles, got %v", resp.Data["candles"])
+		}
+		if want := map[string]interface{}{"close": 100.5}; !reflect.DeepEqual(candles[0], want) {
+			t.Errorf("Expected only the selected field, got %v", candles[0])
+		}
+		if portfolio, _ := resp.Data["portfolio"].(map[string]interface{}); portfolio == nil {
+			t.Errorf("Expected a portfolio, got %v", resp.Data["portfolio"])
+		}
+	})
+
+	t.Run("NestedTicker", func(t *testing.T) {
+		trading.Reset()
+		var resp graphqlResponse
+		postGraphQL(t, gateway, map[string]interface{}{
+			"query": `{ ticker(symbol: "qqq") { symbol signals(days: 10) { signal_type entry_price } best: backtest { name win_rate } } }`,
+		}, &resp)
+		if len(resp.Errors) > 0 {
+			t.Fatalf("Unexpected errors: %+v", resp.Errors)
+		}
+
+		ticker, _ := resp.Data["ticker"].(map[string]interface{})
+		if ticker["symbol"] != "QQQ" {
+			t.Fatalf("Unexpected ticker: %v", resp.Data["ticker"])
+		}
+		want := []interface{}{map[string]interface{}{"signal_type": "LONG", "entry_price": 101.5}}
+		if !reflect.DeepEqual(ticker["signals"], want) {
+			t.Errorf("Unexpected signals: %v", ticker["signals"])
+		}
+		want = []interface{}{map[string]interface{}{"name": "2R", "win_rate": 0.6}}
+		if !reflect.DeepEqual(ticker["best"], want) {
+			t.Errorf("Unexpected backtest: %v", ticker["best"])
+		}
+		if req := trading.Calls("GenerateSignals")[0].Request.(*pb.SignalRequest); req.Ticker != "QQQ" || req.Days != 10 {
+			t.Errorf("Unexpected signal request: %+v", req)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		var resp []graphqlResponse
+		postGraphQL(t, gateway, []map[string]interface{}{
+			{"query": `{ tickers { symbol } }`},
+			{"query": `{ ticker(symbol: "SPY") { nonexistent } }`},
+		}, &resp)
+		if len(resp) != 2 {
+			t.Fatalf("Expected 2 responses, got %d", len(resp))
+		}
+		if tickers, _ := resp[0].Data["tickers"].([]interface{}); len(tickers) == 0 || len(resp[0].Errors) > 0 {
+			t.Errorf("Unexpected tickers response: %+v", resp[0])
+		}
+		if len(resp[1].Errors) != 1 || !reflect.DeepEqual(resp[1].Errors[0].Path, []interface{}{"ticker", "nonexistent"}) {
+			t.Errorf("Expected an error for the unknown field, got %+v", resp[1].Errors)
+		}
+	})
+
+	t.Run("TooComplex", func(t *testing.T) {
+		trading.Reset()
+		var query strings.Builder
+		query.WriteString("{")
+		for i := 0; i < 50; i++ {
+			fmt.Fprintf(&query, ` b%d: backtest(ticker: "SPY") { name }`, i)
+		}
+		query.WriteString(" }")
+		data, _ := json.Marshal(map[string]interface{}{"query": query.String()})
+		resp, err := http.Post(gateway+"/api/graphql", "application/json", bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("POST /api/graphql failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an aliased fan-out, got %d", resp.StatusCode)
+		}
+		if calls := len(trading.Calls("RunBacktest")); calls != 0 {
+			t.Errorf("Expected no backtests run, trading service saw %d", calls)
+		}
+	})
+
+	t.Run("SyntaxError", func(t *testing.T) {
+		var resp graphqlResponse
+		postGraphQL(t, gateway, map[string]interface{}{"query": `{ tickers { symbol }`}, &resp)
+		if len(resp.Errors) != 1 || resp.Data != nil {
+			t.Errorf("Expected a single error and no data, got %+v", resp)
+		}
+	})
+}
+
+// postGraphQL posts a GraphQL request or batch and decodes the response into v
+func postGraphQL(t *testing.T, gateway string, body interface{}, v interface{}) {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	resp, err := http.Post(gateway+"/api/graphql", "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("POST /api/graphql failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /api/graphql: expected 200, got %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("POST /api/graphql: invalid JSON: %v", err)
+	}
+}
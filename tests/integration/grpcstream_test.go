@@ -0,0 +1,73 @@
+// tests/integration/grpcstream_test.go
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// TestMarketDataGRPCStream subscribes to live data over the gateway's gRPC
+// stream and checks published ticks arrive mapped to updates
+func TestMarketDataGRPCStream(t *testing.T) {
+	ticker := fmt.Sprintf("GS%d", time.Now().UnixNano()%1000000)
+	nats := natsURL(t)
+	grpcAddr := fmt.Sprintf("127.0.0.1:%d", freePort(t))
+	startGateway(t, nats, startTradingService(t).Addr, "GRPC_LISTEN_ADDR="+grpcAddr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("Failed to dial gateway gRPC: %v", err)
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewMarketDataServiceClient(conn).StreamMarketData(ctx, &pb.MarketDataSubscription{Tickers: []string{ticker}})
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+
+	client, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+
+	// The subscription starts asynchronously, so publish until a tick arrives
+	received := make(chan *pb.MarketDataUpdate, 1)
+	go func() {
+		update, err := stream.Recv()
+		if err == nil {
+			received <- update
+		}
+	}()
+	ticks := time.NewTicker(200 * time.Millisecond)
+	defer ticks.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for a streamed update")
+		case <-ticks.C:
+			tick := map[string]interface{}{"ticker": ticker, "price": 42.5, "close": 42.5, "volume": 100, "source": "test"}
+			if err := client.PublishMarketLiveData(ctx, ticker, tick); err != nil {
+				t.Fatalf("Failed to publish tick: %v", err)
+			}
+		case update := <-received:
+			if update.Type != "market" || update.Ticker != ticker || update.Price != 42.5 || update.Volume != 100 || update.Source != "test" {
+				t.Errorf("Unexpected update: %+v", update)
+			}
+			if len(update.Payload) == 0 {
+				t.Error("Expected the original event as payload")
+			}
+			return
+		}
+	}
+}
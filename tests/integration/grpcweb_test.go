@@ -0,0 +1,122 @@
+// tests/integration/grpcweb_test.go
+package integration
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestGRPCWeb calls the trading service through the gateway's gRPC-Web
+// endpoint in binary and text encodings
+func TestGRPCWeb(t *testing.T) {
+	gateway, trading := contractGateway(t)
+
+	// An empty frame is a request with every field at its default
+	emptyRequest := []byte{0, 0, 0, 0, 0}
+
+	t.Run("Binary", func(t *testing.T) {
+		trading.Reset()
+		frames, trailers := grpcWebCall(t, gateway+"/trading.TradingService/GetHistoricalData", "application/grpc-web+proto", emptyRequest)
+		if trailers["grpc-status"] != "0" {
+			t.Fatalf("Expected OK status, got trailers %v", trailers)
+		}
+		if len(frames) != 1 {
+			t.Errorf("Expected 1 response message, got %d", len(frames))
+		}
+		if calls := len(trading.Calls("GetHistoricalData")); calls != 1 {
+			t.Errorf("Expected the call to reach the trading service once, got %d", calls)
+		}
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		trading.Reset()
+		body := []byte(base64.StdEncoding.EncodeToString(emptyRequest))
+		_, trailers := grpcWebCall(t, gateway+"/trading.TradingService/GetHistoricalData", "application/grpc-web-text", body)
+		if trailers["grpc-status"] != "0" {
+			t.Fatalf("Expected OK status, got trailers %v", trailers)
+		}
+	})
+
+	t.Run("Unimplemented", func(t *testing.T) {
+		_, trailers := grpcWebCall(t, gateway+"/trading.TradingService/NoSuchMethod", "application/grpc-web+proto", emptyRequest)
+		if trailers["grpc-status"] != "12" {
+			t.Errorf("Expected UNIMPLEMENTED, got trailers %v", trailers)
+		}
+	})
+}
+
+// grpcWebCall posts a gRPC-Web request and returns the response messages and trailers
+func grpcWebCall(t *testing.T, url, contentType string, body []byte) ([][]byte, map[string]string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Grpc-Web", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST %s: expected 200, got %d", url, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != contentType {
+		t.Errorf("Expected content type %s, got %s", contentType, got)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if strings.HasPrefix(contentType, "application/grpc-web-text") {
+		data = decodeBase64Chunks(t, data)
+	}
+
+	var messages [][]byte
+	trailers := make(map[string]string)
+	for len(data) >= 5 {
+		flag, size := data[0], binary.BigEndian.Uint32(data[1:5])
+		if len(data) < 5+int(size) {
+			t.Fatalf("Truncated frame")
+		}
+		payload := data[5 : 5+size]
+		data = data[5+size:]
+		if flag&0x80 == 0 {
+			messages = append(messages, payload)
+			continue
+		}
+		for _, line := range strings.Split(string(payload), "\r\n") {
+			if name, value, ok := strings.Cut(line, ":"); ok {
+				trailers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return messages, trailers
+}
+
+// decodeBase64Chunks decodes a body made of separately padded base64 chunks
+func decodeBase64Chunks(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var decoded []byte
+	for len(data) > 0 {
+		// A chunk ends after its padding, or at the end of the body
+		end := len(data)
+		if i := bytes.IndexByte(data, '='); i >= 0 {
+			end = i
+			for end < len(data) && data[end] == '=' {
+				end++
+			}
+		}
+		chunk, err := base64.StdEncoding.DecodeString(string(data[:end]))
+		if err != nil {
+			t.Fatalf("Invalid base64 response: %v", err)
+		}
+		decoded = append(decoded, chunk...)
+		data = data[end:]
+	}
+	return decoded
+}
@@ -0,0 +1,519 @@
+// tests/integration/harness_test.go
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	eventhub "github.com/myapp/tradinglab/pkg/hub"
+	"github.com/myapp/tradinglab/pkg/market"
+	pb "github.com/myapp/tradinglab/proto"
+	"github.com/nats-io/nats.go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Harness runs the event pipeline without Docker: a NATS server with
+// JetStream, a fake trading service, a fake market provider and the hub in
+// this process, and the gateway built from source as a child process
+type Harness struct {
+	NATSURL    string
+	GatewayURL string
+	Hub        *eventhub.EventHub
+	Trading    *fakeTradingService
+	Provider   *fakeProvider
+}
+
+// natsURL returns NATS_URL if set, and otherwise starts a nats-server from
+// PATH (or NATS_SERVER_BIN) for the test. The test is skipped when neither
+// is available.
+func natsURL(t testing.TB) string {
+	t.Helper()
+	if url := os.Getenv("NATS_URL"); url != "" {
+		return url
+	}
+
+	bin := os.Getenv("NATS_SERVER_BIN")
+	if bin == "" {
+		var err error
+		if bin, err = exec.LookPath("nats-server"); err != nil {
+			t.Skip("set NATS_URL or install nats-server to run integration tests")
+		}
+	}
+
+	port := freePort(t)
+	cmd := exec.Command(bin, "-js", "-a", "127.0.0.1", "-p", fmt.Sprint(port), "-sd", t.TempDir())
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start nats-server: %v", err)
+	}
+	t.Cleanup(func() { stopProcess(cmd) })
+
+	url := fmt.Sprintf("nats://127.0.0.1:%d", port)
+	waitFor(t, 10*time.Second, "nats-server", func() bool {
+		nc, err := nats.Connect(url)
+		if err != nil {
+			return false
+		}
+		nc.Close()
+		return true
+	})
+	return url
+}
+
+// NewHarness starts every component of the pipeline, with the hub watching tickers
+func NewHarness(t *testing.T, tickers ...string) *Harness {
+	t.Helper()
+	h := &Harness{NATSURL: natsURL(t)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// Hub
+	hubClient, err := events.NewEventClient(h.NATSURL)
+	if err != nil {
+		t.Fatalf("Failed to create hub client: %v", err)
+	}
+	t.Cleanup(hubClient.Close)
+
+	h.Hub = eventhub.NewEventHub(hubClient)
+	h.Hub.SetWatchedTickers(tickers)
+	h.Hub.SetReorderWindow(0)
+	if err := h.Hub.Start(ctx); err != nil {
+		t.Fatalf("Failed to start event hub: %v", err)
+	}
+	t.Cleanup(h.Hub.Close)
+
+	// Fake provider, publishing the way the market data service does
+	providerClient, err := events.NewEventClient(h.NATSURL)
+	if err != nil {
+		t.Fatalf("Failed to create provider client: %v", err)
+	}
+	t.Cleanup(providerClient.Close)
+	h.Provider = &fakeProvider{client: providerClient, price: 100}
+
+	// Trading service and gateway
+	h.Trading = startTradingService(t)
+	h.GatewayURL = startGateway(t, h.NATSURL, h.Trading.Addr)
+	return h
+}
+
+// fakeProvider generates deterministic one-minute bars and publishes them on
+// the live subjects like the market data service
+type fakeProvider struct {
+	client *events.EventClient
+	mu     sync.Mutex
+	next   time.Time
+	price  float64
+}
+
+// PublishBars publishes count consecutive bars for ticker and returns them
+func (p *fakeProvider) PublishBars(ctx context.Context, ticker string, count int) ([]*market.MarketData, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next.IsZero() {
+		p.next = time.Now().Truncate(time.Minute).Add(-time.Duration(count) * time.Minute)
+	}
+
+	bars := make([]*market.MarketData, 0, count)
+	for i := 0; i < count; i++ {
+		p.price += 0.25
+		bar := &market.MarketData{
+			Ticker:    ticker,
+			Timestamp: p.next,
+			Price:     p.price,
+			Open:      p.price - 0.1,
+			High:      p.price + 0.2,
+			Low:       p.price - 0.2,
+			Close:     p.price,
+			Volume:    1000,
+			Interval:  "1min",
+			Source:    "fake",
+			DataType:  "live",
+		}
+		if err := p.client.PublishMarketLiveData(ctx, ticker, bar); err != nil {
+			return bars, err
+		}
+		bars = append(bars, bar)
+		p.next = p.next.Add(time.Minute)
+	}
+	return bars, nil
+}
+
+// rpcCall records a request the fake trading service received
+type rpcCall struct {
+	Request  interface{}
+	Deadline time.Duration // Time left on the caller's deadline on arrival, 0 if none
+}
+
+// fakeTradingService implements the trading service with canned responses,
+// recording each request. Calls fail while Fail is set or failures are queued
+// with FailNext, and hang until the caller gives up while Block is set.
+type fakeTradingService struct {
+	pb.UnimplementedTradingServiceServer
+	Addr  string
+	Fail  atomic.Bool
+	Block atomic.Bool
+
+	mu       sync.Mutex
+	calls    map[string][]rpcCall
+	failures map[string]int
+	sharpe   func(params map[string]string) float64
+}
+
+// startTradingService serves the fake trading service on a free port
+func startTradingService(t *testing.T) *fakeTradingService {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen for trading service: %v", err)
+	}
+
+	svc := &fakeTradingService{
+		Addr:     lis.Addr().String(),
+		calls:    make(map[string][]rpcCall),
+		failures: make(map[string]int),
+	}
+	server := grpc.NewServer()
+	pb.RegisterTradingServiceServer(server, svc)
+	healthpb.RegisterHealthServer(server, &fakeHealth{svc: svc})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return svc
+}
+
+// fakeHealth answers gRPC health checks for the fake trading service, which
+// is not serving while Fail is set
+type fakeHealth struct {
+	healthpb.UnimplementedHealthServer
+	svc *fakeTradingService
+}
+
+func (h *fakeHealth) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if h.svc.Fail.Load() {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+// FailNext makes the next n calls to method fail as unavailable
+func (s *fakeTradingService) FailNext(method string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = n
+}
+
+// Calls returns the requests method has received
+func (s *fakeTradingService) Calls(method string) []rpcCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]rpcCall(nil), s.calls[method]...)
+}
+
+// SetSharpe makes backtests report the Sharpe ratio fn gives for their
+// strategy parameters rather than a fixed one
+func (s *fakeTradingService) SetSharpe(fn func(params map[string]string) float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sharpe = fn
+}
+
+// Reset forgets recorded calls and queued failures
+func (s *fakeTradingService) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = make(map[string][]rpcCall)
+	s.failures = make(map[string]int)
+	s.sharpe = nil
+	s.Fail.Store(false)
+	s.Block.Store(false)
+}
+
+// record stores a call and returns the error the call should fail with, if any
+func (s *fakeTradingService) record(ctx context.Context, method string, req interface{}) error {
+	call := rpcCall{Request: req}
+	if deadline, ok := ctx.Deadline(); ok {
+		call.Deadline = time.Until(deadline)
+	}
+
+	s.mu.Lock()
+	s.calls[method] = append(s.calls[method], call)
+	fail := s.failures[method] > 0
+	if fail {
+		s.failures[method]--
+	}
+	s.mu.Unlock()
+
+	if s.Block.Load() {
+		<-ctx.Done()
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	if fail || s.Fail.Load() {
+		return status.Error(codes.Unavailable, "trading service down")
+	}
+	return nil
+}
+
+// GetHistoricalData returns one daily candle per requested day
+func (s *fakeTradingService) GetHistoricalData(ctx context.Context, req *pb.HistoricalDataRequest) (*pb.HistoricalDataResponse, error) {
+	if err := s.record(ctx, "GetHistoricalData", req); err != nil {
+		return nil, err
+	}
+
+	resp := &pb.HistoricalDataResponse{}
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < int(req.Days); i++ {
+		price := 100 + float64(i)
+		resp.Candles = append(resp.Candles, &pb.OHLCV{
+			Date:   start.AddDate(0, 0, i).Format("2006-01-02 15:04:05"),
+			Open:   price,
+			High:   price + 1,
+			Low:    price - 1,
+			Close:  price + 0.5,
+			Volume: 1000000,
+		})
+	}
+	return resp, nil
+}
+
+// GenerateSignals returns a single long signal
+func (s *fakeTradingService) GenerateSignals(ctx context.Context, req *pb.SignalRequest) (*pb.SignalResponse, error) {
+	if err := s.record(ctx, "GenerateSignals", req); err != nil {
+		return nil, err
+	}
+	return &pb.SignalResponse{Signals: []*pb.Signal{
+		{Date: "2024-03-04 10:00:00", SignalType: "LONG", EntryPrice: 101.5, Stoploss: 99.5},
+	}}, nil
+}
+
+// RunBacktest returns results for one profit target. Portfolios split
+// the return evenly between their tickers.
+func (s *fakeTradingService) RunBacktest(ctx context.Context, req *pb.BacktestRequest) (*pb.BacktestResponse, error) {
+	if err := s.record(ctx, "RunBacktest", req); err != nil {
+		return nil, err
+	}
+	resp := &pb.BacktestResponse{Results: map[string]*pb.BacktestResult{
+		"2R": {
+			WinRate:        0.6,
+			ProfitFactor:   1.8,
+			TotalReturn:    1250,
+			TotalReturnPct: 12.5,
+			TotalTrades:    10,
+			WinningTrades:  6,
+			LosingTrades:   4,
+			MaxDrawdown:    300,
+			MaxDrawdownPct: 3,
+			SharpeRatio:    1.2,
+		},
+	}}
+	s.mu.Lock()
+	if s.sharpe != nil {
+		resp.Results["2R"].SharpeRatio = s.sharpe(req.Parameters)
+	}
+	s.mu.Unlock()
+	if len(req.Tickers) == 0 {
+		resp.Results["2R"].EquityCurve = fakeEquityCurve()
+		return resp, nil
+	}
+
+	capital := req.Allocation.InitialCapital
+	portfolio := &pb.PortfolioResult{
+		InitialCapital: capital,
+		FinalEquity:    capital + 1250,
+		EquityCurve: []*pb.EquityPoint{
+			{Date: "2024-03-05", Equity: capital + 1550},
+			{Date: "2024-03-04", Equity: capital + 1000},
+			{Date: "2024-03-06", Equity: capital + 1250},
+		},
+		Symbols: make(map[string]*pb.PortfolioSymbol),
+	}
+	share := 1 / float64(len(req.Tickers))
+	for _, ticker := range req.Tickers {
+		portfolio.Symbols[ticker] = &pb.PortfolioSymbol{
+			Weight:  share,
+			Capital: capital * share,
+			Pnl:     1250 * share,
+			Result:  resp.Results["2R"],
+		}
+	}
+	resp.Portfolio = map[string]*pb.PortfolioResult{"2R": portfolio}
+	return resp, nil
+}
+
+// GetOptionsRecommendations returns a single call recommendation
+// fakeEquityCurve is a minute-bar equity curve of fakeEquityBars points,
+// rising to 1550 over the first third, falling 300 over the second and
+// flat at 1250 for the rest, like the fake backtest's results
+func fakeEquityCurve() []*pb.EquityPoint {
+	start := time.Date(2024, 3, 4, 14, 30, 0, 0, time.UTC)
+	third := fakeEquityBars / 3
+	curve := make([]*pb.EquityPoint, fakeEquityBars)
+	for i := range curve {
+		equity := 1250.0
+		switch {
+		case i < third:
+			equity = 1550 * float64(i) / float64(third-1)
+		case i < 2*third:
+			equity = 1550 - 300*float64(i-third)/float64(third-1)
+		}
+		curve[i] = &pb.EquityPoint{Date: start.Add(time.Duration(i) * time.Minute).Format(time.RFC3339), Equity: equity}
+	}
+	return curve
+}
+
+// fakeEquityBars is the length of the fake backtest's equity curve
+const fakeEquityBars = 3000
+
+func (s *fakeTradingService) GetOptionsRecommendations(ctx context.Context, req *pb.RecommendationRequest) (*pb.RecommendationResponse, error) {
+	if err := s.record(ctx, "GetOptionsRecommendations", req); err != nil {
+		return nil, err
+	}
+	return &pb.RecommendationResponse{Recommendations: []*pb.OptionsRecommendation{
+		{
+			Date:       "2024-03-04 10:00:00",
+			SignalType: "LONG",
+			StockPrice: 101.5,
+			Stoploss:   99.5,
+			OptionType: "CALL",
+			Strike:     102,
+			Expiration: "2024-03-15",
+			Delta:      0.45,
+			Iv:         0.22,
+			Price:      1.35,
+		},
+	}}, nil
+}
+
+// gatewayBuild holds the gateway binary, built once for all tests
+var gatewayBuild struct {
+	once sync.Once
+	dir  string
+	bin  string
+	err  error
+}
+
+// TestMain removes the gateway binary after the tests
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if gatewayBuild.dir != "" {
+		os.RemoveAll(gatewayBuild.dir)
+	}
+	os.Exit(code)
+}
+
+// buildGateway builds the gateway on first use and returns the binary's path
+func buildGateway(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not found, cannot build the gateway")
+	}
+
+	b := &gatewayBuild
+	b.once.Do(func() {
+		if b.dir, b.err = os.MkdirTemp("", "tradinglab-gateway"); b.err != nil {
+			return
+		}
+		b.bin = filepath.Join(b.dir, "gateway")
+		out, err := exec.Command("go", "build", "-o", b.bin, "github.com/myapp/tradinglab/cmd/gateway").CombinedOutput()
+		if err != nil {
+			b.err = fmt.Errorf("%w\n%s", err, out)
+		}
+	})
+	if b.err != nil {
+		t.Fatalf("Failed to build gateway: %v", b.err)
+	}
+	return b.bin
+}
+
+// startGateway builds the gateway and runs it against the harness services
+// with any extra environment, returning its base URL once it is healthy
+func startGateway(t *testing.T, natsURL, tradingAddr string, env ...string) string {
+	t.Helper()
+	bin := buildGateway(t)
+	dir := t.TempDir()
+
+	addr := fmt.Sprintf("127.0.0.1:%d", freePort(t))
+	cmd := exec.Command(bin)
+	cmd.Dir = dir // Keep the gateway's file stores out of the tree
+	cmd.Env = append(os.Environ(),
+		"NATS_URL="+natsURL,
+		"TRADINGLAB_SERVICE_URL="+tradingAddr,
+		"LISTEN_ADDR="+addr,
+		"GRPC_LISTEN_ADDR=off", // Tests that stream over gRPC pick a free port
+	)
+	cmd.Env = append(cmd.Env, env...)
+	if testing.Verbose() {
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start gateway: %v", err)
+	}
+	t.Cleanup(func() { stopProcess(cmd) })
+
+	url := "http://" + addr
+	waitFor(t, 30*time.Second, "gateway", func() bool {
+		resp, err := http.Get(url + "/api/health")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	})
+	return url
+}
+
+// stopProcess asks a child process to shut down, killing it if it does not
+func stopProcess(cmd *exec.Cmd) {
+	if cmd.Process == nil {
+		return
+	}
+	cmd.Process.Signal(syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		<-done
+	}
+}
+
+// freePort returns a TCP port that was free a moment ago
+func freePort(t testing.TB) int {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	defer lis.Close()
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+// waitFor polls cond until it holds, failing the test after timeout
+func waitFor(t testing.TB, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
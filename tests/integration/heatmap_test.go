@@ -0,0 +1,123 @@
+// tests/integration/heatmap_test.go
+package integration
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestHeatmap checks the heatmap measures each watched ticker's live price
+// against its previous stored close and its session volume against the
+// average daily volume, and groups tiles by sector
+func TestHeatmap(t *testing.T) {
+	suffix := time.Now().UnixNano() % 100000
+	up, down, unclassified, missing := fmt.Sprintf("HU%d", suffix), fmt.Sprintf("HD%d", suffix), fmt.Sprintf("HC%d", suffix), fmt.Sprintf("HM%d", suffix)
+
+	referencePath := filepath.Join(t.TempDir(), "reference.csv")
+	csv := fmt.Sprintf("ticker,name,sector\n%s,Up Corp,Tech\n%s,Down Corp,Tech\n", up, down)
+	if err := os.WriteFile(referencePath, []byte(csv), 0644); err != nil {
+		t.Fatalf("Failed to write reference data: %v", err)
+	}
+
+	nats := natsURL(t)
+	gateway := startGateway(t, nats, startTradingService(t).Addr,
+		"WATCH_TICKERS="+strings.Join([]string{up, down, unclassified, missing}, ","),
+		"REFERENCE_DATA_PATH="+referencePath)
+
+	publisher, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer publisher.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The fake trading service's last daily close is 129.5 on a volume of
+	// one million
+	ticks := []struct {
+		ticker string
+		price  float64
+		volume float64
+	}{
+		{up, 142.45, 200000},
+		{up, 142.45, 300000},
+		{down, 116.55, 1500000},
+		{unclassified, 129.5, 0},
+	}
+	for _, tick := range ticks {
+		data := map[string]interface{}{"ticker": tick.ticker, "price": tick.price, "volume": tick.volume}
+		if err := publisher.PublishMarketLiveData(ctx, tick.ticker, data); err != nil {
+			t.Fatalf("Failed to publish tick: %v", err)
+		}
+	}
+
+	type tile struct {
+		Ticker        string  `json:"ticker"`
+		Sector        string  `json:"sector"`
+		ChangePercent float64 `json:"change_percent"`
+		Basis         string  `json:"basis"`
+		Volume        int64   `json:"volume"`
+		VolumeRatio   float64 `json:"volume_ratio"`
+	}
+	var heatmap struct {
+		Tiles   []tile `json:"tiles"`
+		Sectors []struct {
+			Sector        string  `json:"sector"`
+			ChangePercent float64 `json:"change_percent"`
+			VolumeRatio   float64 `json:"volume_ratio"`
+			Tiles         []tile  `json:"tiles"`
+		} `json:"sectors"`
+		Missing []string `json:"missing"`
+	}
+	waitFor(t, 10*time.Second, "every tick in the heatmap", func() bool {
+		getJSON(t, gateway+"/api/heatmap", http.StatusOK, &heatmap)
+		return len(heatmap.Tiles) == 3 && heatmap.Tiles[2].Volume == 500000
+	})
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+	if len(heatmap.Missing) != 1 || heatmap.Missing[0] != missing {
+		t.Errorf("Expected %s reported missing, got %v", missing, heatmap.Missing)
+	}
+	for _, tile := range heatmap.Tiles {
+		var change, ratio float64
+		sector := "Tech"
+		switch tile.Ticker {
+		case up:
+			change, ratio = 10, 0.5
+		case down:
+			change, ratio = -10, 1.5
+		case unclassified:
+			sector = "unknown"
+		}
+		if tile.Basis != "previous_close" || !near(tile.ChangePercent, change) || !near(tile.VolumeRatio, ratio) || tile.Sector != sector {
+			t.Errorf("Expected %s in %s to move %.0f%% on %.1fx volume from the previous close, got %+v", tile.Ticker, sector, change, ratio, tile)
+		}
+	}
+
+	heatmap.Tiles = nil
+	getJSON(t, gateway+"/api/heatmap?group=sector", http.StatusOK, &heatmap)
+	if len(heatmap.Tiles) != 0 || len(heatmap.Sectors) != 2 {
+		t.Fatalf("Expected two sectors and no ungrouped tiles, got %+v", heatmap)
+	}
+	tech := heatmap.Sectors[0]
+	if tech.Sector != "Tech" || len(tech.Tiles) != 2 || !near(tech.ChangePercent, 0) || !near(tech.VolumeRatio, 1) {
+		t.Errorf("Expected Tech flat on average volume over two tiles, got %+v", tech)
+	}
+
+	// Explicit tickers replace the watchlist
+	heatmap.Sectors = nil
+	getJSON(t, gateway+"/api/heatmap?tickers="+up, http.StatusOK, &heatmap)
+	if len(heatmap.Tiles) != 1 || heatmap.Tiles[0].Ticker != up || len(heatmap.Missing) != 0 {
+		t.Errorf("Expected only %s, got %+v", up, heatmap)
+	}
+	getJSON(t, gateway+"/api/heatmap?group=industry", http.StatusBadRequest, nil)
+}
@@ -0,0 +1,67 @@
+// tests/integration/history_test.go
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestSignalHistoryPaging reads stored signals back a page at a time
+func TestSignalHistoryPaging(t *testing.T) {
+	ticker := fmt.Sprintf("SH%d", time.Now().UnixNano()%1000000)
+	client, err := events.NewEventClient(natsURL(t))
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	const count = 5
+	for i := 1; i <= count; i++ {
+		if err := client.PublishSignal(ctx, ticker, map[string]interface{}{"ticker": ticker, "n": i}); err != nil {
+			t.Fatalf("Failed to publish signal %d: %v", i, err)
+		}
+	}
+
+	var seen []int
+	query := events.HistoryQuery{Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > count {
+			t.Fatalf("Paging did not terminate")
+		}
+		page, err := client.SignalHistory(ticker, query)
+		if err != nil {
+			t.Fatalf("Failed to read signal history: %v", err)
+		}
+		for _, event := range page.Events {
+			var signal struct{ N int }
+			if err := json.Unmarshal(event.Data, &signal); err != nil {
+				t.Fatalf("Invalid stored signal %s: %v", event.Data, err)
+			}
+			seen = append(seen, signal.N)
+		}
+		if page.NextCursor == 0 {
+			break
+		}
+		query.After = page.NextCursor
+	}
+
+	if fmt.Sprint(seen) != "[1 2 3 4 5]" {
+		t.Errorf("Expected signals 1 to 5 in order, got %v", seen)
+	}
+
+	// Nothing was published in the future
+	page, err := client.SignalHistory(ticker, events.HistoryQuery{From: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("Failed to read signal history: %v", err)
+	}
+	if len(page.Events) != 0 {
+		t.Errorf("Expected no signals after now, got %d", len(page.Events))
+	}
+}
@@ -0,0 +1,197 @@
+// tests/integration/jobs_test.go
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestBacktestJobs checks backtest jobs queue behind a bounded worker pool,
+// report their queue position, can be cancelled while queued or running,
+// and are refused past the per-user limit
+func TestBacktestJobs(t *testing.T) {
+	trading := startTradingService(t)
+	gateway := startGateway(t, natsURL(t), trading.Addr,
+		"BACKTEST_WORKERS=1",
+		"BACKTEST_JOBS_PER_USER=3")
+	ticker := fmt.Sprintf("BJ%d", time.Now().UnixNano()%1000000)
+
+	type job struct {
+		ID       string                 `json:"id"`
+		State    string                 `json:"state"`
+		Position int                    `json:"position"`
+		Error    string                 `json:"error"`
+		Result   map[string]interface{} `json:"result"`
+	}
+	submit := func(status int) job {
+		t.Helper()
+		resp, err := http.Post(gateway+"/api/backtest/jobs?ticker="+ticker+"&days=30", "application/json", nil)
+		if err != nil {
+			t.Fatalf("Failed to submit job: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("Expected %d submitting job, got %d", status, resp.StatusCode)
+		}
+		var j job
+		json.NewDecoder(resp.Body).Decode(&j)
+		return j
+	}
+	cancel := func(id string, status int) job {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodDelete, gateway+"/api/backtest/jobs/"+id, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to cancel job: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("Expected %d cancelling job, got %d", status, resp.StatusCode)
+		}
+		var j job
+		json.NewDecoder(resp.Body).Decode(&j)
+		return j
+	}
+	get := func(id string) job {
+		t.Helper()
+		var j job
+		getJSON(t, gateway+"/api/backtest/jobs/"+id, http.StatusOK, &j)
+		return j
+	}
+
+	// The one worker holds the first job while the trading service hangs
+	trading.Block.Store(true)
+	first := submit(http.StatusAccepted)
+	waitFor(t, 10*time.Second, "the first job to start", func() bool { return get(first.ID).State == "running" })
+
+	second := submit(http.StatusAccepted)
+	third := submit(http.StatusAccepted)
+	if second.Position != 1 || third.Position != 2 {
+		t.Errorf("Expected queue positions 1 and 2, got %d and %d", second.Position, third.Position)
+	}
+	submit(http.StatusTooManyRequests)
+
+	// Cancelling a queued job moves the ones behind it up
+	if j := cancel(second.ID, http.StatusOK); j.State != "cancelled" {
+		t.Errorf("Expected the queued job cancelled, got %+v", j)
+	}
+	if j := get(third.ID); j.State != "queued" || j.Position != 1 {
+		t.Errorf("Expected the last job first in line, got %+v", j)
+	}
+
+	// Cancelling the running job frees the worker for the next
+	trading.Block.Store(false)
+	cancel(first.ID, http.StatusOK)
+	waitFor(t, 10*time.Second, "the last job to finish", func() bool { return get(third.ID).State == "done" })
+
+	done := get(third.ID)
+	results, _ := done.Result["results"].(map[string]interface{})
+	if _, ok := results["2R"]; !ok {
+		t.Errorf("Expected backtest results on the finished job, got %+v", done)
+	}
+	if calls := trading.Calls("RunBacktest"); len(calls) != 2 {
+		t.Errorf("Expected the cancelled queued job never run, got %d calls", len(calls))
+	}
+
+	cancel(third.ID, http.StatusConflict)
+	getJSON(t, gateway+"/api/backtest/jobs/job-0-0", http.StatusNotFound, nil)
+
+	var list struct {
+		Jobs []job `json:"jobs"`
+	}
+	getJSON(t, gateway+"/api/backtest/jobs", http.StatusOK, &list)
+	if len(list.Jobs) != 3 || list.Jobs[0].ID != third.ID {
+		t.Errorf("Expected 3 jobs newest first, got %+v", list.Jobs)
+	}
+
+	// Invalid backtests are refused before they are queued
+	resp, err := http.Post(gateway+"/api/backtest/jobs?ticker="+ticker+"&interval=7min", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid backtest, got %d", resp.StatusCode)
+	}
+}
+
+// TestSynchronousBacktestsQueue checks backtests answered synchronously wait
+// for a worker and count against the per-user limit like queued jobs
+func TestSynchronousBacktestsQueue(t *testing.T) {
+	trading := startTradingService(t)
+	gateway := startGateway(t, natsURL(t), trading.Addr,
+		"BACKTEST_WORKERS=1",
+		"BACKTEST_JOBS_PER_USER=2")
+	ticker := fmt.Sprintf("SQ%d", time.Now().UnixNano()%1000000)
+
+	type job struct {
+		ID    string `json:"id"`
+		State string `json:"state"`
+	}
+	var list struct {
+		Jobs []job `json:"jobs"`
+	}
+	queued := func() bool {
+		getJSON(t, gateway+"/api/backtest/jobs", http.StatusOK, &list)
+		for _, j := range list.Jobs {
+			if j.State == "queued" {
+				return true
+			}
+		}
+		return false
+	}
+
+	// A job holds the one worker while the trading service hangs
+	trading.Block.Store(true)
+	resp, err := http.Post(gateway+"/api/backtest/jobs?ticker="+ticker+"&days=30", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Failed to submit job: %v", err)
+	}
+	var running job
+	json.NewDecoder(resp.Body).Decode(&running)
+	resp.Body.Close()
+	waitFor(t, 10*time.Second, "the job to start", func() bool {
+		getJSON(t, gateway+"/api/backtest/jobs/"+running.ID, http.StatusOK, &running)
+		return running.State == "running"
+	})
+
+	// A synchronous backtest waits its turn instead of calling the trading service
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(gateway + "/api/backtest?ticker=" + ticker + "&days=30")
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	waitFor(t, 10*time.Second, "the backtest to queue", queued)
+	if calls := len(trading.Calls("RunBacktest")); calls != 1 {
+		t.Errorf("Expected the waiting backtest not to reach the trading service, got %d calls", calls)
+	}
+
+	// The running job and the waiting backtest use up the user's limit
+	getJSON(t, gateway+"/api/backtest?ticker="+ticker+"&days=60", http.StatusTooManyRequests, nil)
+
+	// Freeing the worker lets the waiting backtest run
+	trading.Block.Store(false)
+	req, _ := http.NewRequest(http.MethodDelete, gateway+"/api/backtest/jobs/"+running.ID, nil)
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	select {
+	case code := <-status:
+		if code != http.StatusOK {
+			t.Errorf("Expected the waiting backtest to succeed, got %d", code)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the synchronous backtest")
+	}
+	if calls := len(trading.Calls("RunBacktest")); calls != 2 {
+		t.Errorf("Expected 2 calls to the trading service, got %d", calls)
+	}
+}
@@ -0,0 +1,130 @@
+// tests/integration/latency_test.go
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestDeliveryLatency checks published events carry their pipeline stamps
+// and that the gateway reports the latency of delivering them to a
+// WebSocket client
+func TestDeliveryLatency(t *testing.T) {
+	ticker := fmt.Sprintf("LT%d", time.Now().UnixNano()%1000000)
+	natsAddr := natsURL(t)
+	gateway := startGateway(t, natsAddr, startTradingService(t).Addr, "ADMIN_TOKEN=latency-admin")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, "ws"+strings.TrimPrefix(gateway, "http")+"/api/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to gateway websocket: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteJSON(map[string]string{"action": "subscribe", "type": "market", "ticker": ticker}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	read := func() map[string]interface{} {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		return msg
+	}
+	if msg := read(); msg["event"] != "subscribed" {
+		t.Fatalf("Expected subscription confirmation, got %v", msg)
+	}
+
+	nc, err := nats.Connect(natsAddr)
+	if err != nil {
+		t.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	defer nc.Close()
+	raw, err := nc.SubscribeSync("market.live." + ticker)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to NATS: %v", err)
+	}
+
+	publisher, err := events.NewEventClient(natsAddr)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer publisher.Close()
+
+	// Each tick was fetched from the provider 50ms before it is published
+	const ticks = 3
+	const fetchDelay = 50 * time.Millisecond
+	for i := 0; i < ticks; i++ {
+		fetched := events.WithFetchTime(ctx, time.Now().Add(-fetchDelay))
+		tick := map[string]interface{}{"ticker": ticker, "price": 100 + float64(i), "volume": 100}
+		if err := publisher.PublishMarketLiveData(fetched, ticker, tick); err != nil {
+			t.Fatalf("Failed to publish tick: %v", err)
+		}
+		read()
+	}
+
+	msg, err := raw.NextMsg(5 * time.Second)
+	if err != nil {
+		t.Fatalf("Failed to receive the raw event: %v", err)
+	}
+	stamps := events.ReadStamps(msg.Header)
+	if stamps.FetchedAt.IsZero() || stamps.PublishedAt.IsZero() || stamps.PublishedAt.Sub(stamps.FetchedAt) < fetchDelay {
+		t.Fatalf("Expected fetch and publish stamps %v apart, got %+v", fetchDelay, stamps)
+	}
+
+	type summary struct {
+		Count int64   `json:"count"`
+		P50   float64 `json:"p50_ms"`
+		P99   float64 `json:"p99_ms"`
+	}
+	var report struct {
+		Window int                           `json:"window"`
+		Kinds  map[string]map[string]summary `json:"kinds"`
+	}
+	fetch := func() {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, gateway+"/api/ops/latency", nil)
+		req.Header.Set("Authorization", "Bearer latency-admin")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /api/ops/latency failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /api/ops/latency: expected 200, got %d", resp.StatusCode)
+		}
+		json.NewDecoder(resp.Body).Decode(&report)
+	}
+
+	getJSON(t, gateway+"/api/ops/latency", http.StatusUnauthorized, nil)
+	waitFor(t, 5*time.Second, "delivery latency to be recorded", func() bool {
+		fetch()
+		return report.Kinds["market.live"]["end_to_end"].Count >= ticks
+	})
+
+	live := report.Kinds["market.live"]
+	if live["end_to_end"].P50 < float64(fetchDelay/time.Millisecond) {
+		t.Errorf("Expected end-to-end latency to include the %v since fetch, got %+v", fetchDelay, live["end_to_end"])
+	}
+	if live["fetch_to_publish"].Count < ticks || live["fetch_to_publish"].P50 < float64(fetchDelay/time.Millisecond) {
+		t.Errorf("Expected fetch-to-publish latency of at least %v, got %+v", fetchDelay, live["fetch_to_publish"])
+	}
+	if live["sent_to_delivery"].Count < ticks || live["sent_to_delivery"].P99 > live["end_to_end"].P99 {
+		t.Errorf("Expected delivery latency within the end-to-end latency, got %+v", live)
+	}
+	if _, ok := live["publish_to_process"]; ok {
+		t.Errorf("Expected no hub stage for events the hub did not process, got %+v", live["publish_to_process"])
+	}
+}
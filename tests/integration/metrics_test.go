@@ -0,0 +1,54 @@
+// tests/integration/metrics_test.go
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/metrics"
+)
+
+// TestHubMetrics checks the hub's event counts are exported per stream and
+// ticker in the Prometheus text format
+func TestHubMetrics(t *testing.T) {
+	ticker := fmt.Sprintf("MT%d", time.Now().UnixNano()%1000000)
+	h := NewHarness(t, ticker)
+
+	reg := metrics.NewRegistry()
+	h.Hub.RegisterMetrics(reg)
+	server := httptest.NewServer(reg.Handler())
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := h.Provider.PublishBars(ctx, ticker, 3); err != nil {
+		t.Fatalf("Failed to publish bars: %v", err)
+	}
+
+	want := fmt.Sprintf(`eventhub_ticker_events_total{stream="live",ticker="%s"} 3`, ticker)
+	var body string
+	waitFor(t, 10*time.Second, "live events in /metrics", func() bool {
+		resp, err := server.Client().Get(server.URL)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		body = string(data)
+		return strings.Contains(body, want)
+	})
+
+	for _, line := range []string{
+		"# TYPE eventhub_events_total counter",
+		`eventhub_stream_up{stream="live"} 1`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected %q in metrics output:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// tests/integration/optimize_test.go
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// TestBacktestOptimize checks a genetic search runs as a backtest job,
+// varying only the parameters in its space, scoring each candidate once,
+// finding the fittest region and stopping early once the best fitness
+// stops improving
+func TestBacktestOptimize(t *testing.T) {
+	gateway, trading := contractGateway(t)
+	url := gateway + fmt.Sprintf("/api/backtest/optimize?ticker=OP%d&days=30", time.Now().UnixNano()%1000000)
+
+	type result struct {
+		State  string `json:"state"`
+		Error  string `json:"error"`
+		Result struct {
+			Best struct {
+				Params  map[string]interface{} `json:"params"`
+				Fitness float64                `json:"fitness"`
+			} `json:"best"`
+			History      []map[string]interface{} `json:"history"`
+			Evaluations  int                      `json:"evaluations"`
+			StoppedEarly bool                     `json:"stopped_early"`
+			Test         string                   `json:"test"`
+			Artifact     string                   `json:"artifact"`
+		} `json:"result"`
+	}
+	submit := func(body string, status int) result {
+		t.Helper()
+		resp, err := http.Post(url, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to submit optimization: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("Submitting %s: expected %d, got %d", body, status, resp.StatusCode)
+		}
+		var job struct {
+			ID string `json:"id"`
+		}
+		var finished result
+		if json.NewDecoder(resp.Body).Decode(&job); job.ID == "" {
+			return finished
+		}
+		waitFor(t, 60*time.Second, "the optimization to finish", func() bool {
+			getJSON(t, gateway+"/api/backtest/jobs/"+job.ID, http.StatusOK, &finished)
+			return finished.State == "done" || finished.State == "failed"
+		})
+		return finished
+	}
+
+	// Fitness peaks at an RSI period of 23
+	trading.SetSharpe(func(params map[string]string) float64 {
+		period, _ := strconv.Atoi(params["rsi_period"])
+		return 3 - math.Abs(float64(period-23))/10
+	})
+	job := submit(`{"space": {"rsi_period": {"min": 5, "max": 40}}, "params": {"volume_factor": 2},
+		"population": 20, "generations": 30, "patience": 10, "seed": 7}`, http.StatusAccepted)
+	if job.State != "done" {
+		t.Fatalf("Expected the optimization done, got %s: %s", job.State, job.Error)
+	}
+	best := job.Result.Best
+	period, _ := best.Params["rsi_period"].(float64)
+	if best.Fitness < 2.8 || best.Fitness != 3-math.Abs(period-23)/10 {
+		t.Errorf("Expected an RSI period near 23, got %v scoring %v", best.Params, best.Fitness)
+	}
+	if job.Result.Test != "2R" || job.Result.Artifact == "" {
+		t.Errorf("Expected the best candidate's 2R run stored, got test %q and artifact %q", job.Result.Test, job.Result.Artifact)
+	}
+
+	// Each distinct candidate is backtested once, plus the best again to
+	// store it, and only the optimized parameter varies
+	calls := trading.Calls("RunBacktest")
+	if job.Result.Evaluations > 36 || len(calls) != job.Result.Evaluations+1 {
+		t.Errorf("Expected at most 36 distinct candidates and one more run, got %d evaluations and %d runs",
+			job.Result.Evaluations, len(calls))
+	}
+	for _, call := range calls {
+		if params := call.Request.(*pb.BacktestRequest).Parameters; params["volume_factor"] != "2" || params["rsi_threshold"] != "30" {
+			t.Fatalf("Expected fixed parameters kept, got %v", params)
+		}
+	}
+
+	// A flat landscape stops once patience runs out
+	trading.Reset()
+	job = submit(`{"space": {"volume_factor": {"min": 0.5, "max": 3}}, "fitness": "profit_factor",
+		"population": 6, "generations": 20, "patience": 3}`, http.StatusAccepted)
+	if job.State != "done" || !job.Result.StoppedEarly || len(job.Result.History) != 4 {
+		t.Errorf("Expected the search stopped after 4 generations, got %s after %d", job.State, len(job.Result.History))
+	}
+	if want := 1.8 / (1 + 0.03); math.Abs(job.Result.Best.Fitness-want) > 1e-9 {
+		t.Errorf("Expected the profit factor scored with its drawdown penalty, %v, got %v", want, job.Result.Best.Fitness)
+	}
+
+	// Candidates without enough trades cannot be scored
+	job = submit(`{"space": {"use_additional_filters": {}}, "min_trades": 50, "population": 4, "generations": 2}`, http.StatusAccepted)
+	if job.State != "failed" || !strings.Contains(job.Error, "eligible") {
+		t.Errorf("Expected the optimization failed for lack of eligible candidates, got %s: %s", job.State, job.Error)
+	}
+
+	for _, body := range []string{
+		`{}`,
+		`{"space": {"lookback": {"min": 1, "max": 5}}}`,
+		`{"space": {"rsi_period": {"min": 5, "max": 500}}}`,
+		`{"space": {"rsi_period": {"min": 30, "max": 10}}}`,
+		`{"space": {"rsi_period": {}}, "fitness": "sortino"}`,
+		`{"space": {"rsi_period": {}}, "population": 1000}`,
+		`{"space": {"rsi_period": {}}, "parallelism": 2}`,
+	} {
+		submit(body, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,120 @@
+// tests/integration/pipeline_test.go
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestPipeline follows watched ticks from the provider through the hub and
+// the gateway's websocket, then checks the gateway serves cached history
+// when the trading service goes down
+func TestPipeline(t *testing.T) {
+	// A fresh ticker keeps counts exact on a shared NATS server
+	ticker := fmt.Sprintf("IT%d", time.Now().UnixNano()%1000000)
+	h := NewHarness(t, ticker)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Subscribe to the ticker over the gateway websocket
+	wsURL := "ws" + strings.TrimPrefix(h.GatewayURL, "http") + "/api/ws"
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to gateway websocket: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(map[string]string{"action": "subscribe", "type": "market", "ticker": ticker}); err != nil {
+		t.Fatalf("Failed to send subscription: %v", err)
+	}
+	if msg := readWS(t, conn); msg["event"] != "subscribed" {
+		t.Fatalf("Expected subscription confirmation, got %v", msg)
+	}
+
+	// Tick
+	const count = 5
+	bars, err := h.Provider.PublishBars(ctx, ticker, count)
+	if err != nil {
+		t.Fatalf("Failed to publish bars: %v", err)
+	}
+
+	// WS delivery, in order and with normalized timestamps
+	for i, bar := range bars {
+		msg := readWS(t, conn)
+		if msg["ticker"] != ticker {
+			t.Fatalf("Bar %d: expected ticker %s, got %v", i, ticker, msg["ticker"])
+		}
+		want := bar.Timestamp.UTC().Format(time.RFC3339)
+		if msg["timestamp"] != want {
+			t.Errorf("Bar %d: expected timestamp %s, got %v", i, want, msg["timestamp"])
+		}
+		if msg["session_date"] == nil {
+			t.Errorf("Bar %d: missing session_date", i)
+		}
+	}
+
+	// Hub stats
+	waitFor(t, 10*time.Second, "hub live event stats", func() bool {
+		return h.Hub.GetStats().TickerStats[ticker].LiveEvents == count
+	})
+
+	// REST cache: a successful response is cached and served once the
+	// trading service fails
+	path := fmt.Sprintf("/api/historical-data?ticker=%s&days=5&interval=1day", ticker)
+	fresh, header := getBody(t, h.GatewayURL+path)
+	if header.Get("X-Data-Source") != "" {
+		t.Fatalf("Expected a live response, got source %s", header.Get("X-Data-Source"))
+	}
+
+	h.Trading.Fail.Store(true)
+	cached, header := getBody(t, h.GatewayURL+path)
+	if header.Get("X-Data-Source") != "cache" {
+		t.Fatalf("Expected a cached response, got source %q", header.Get("X-Data-Source"))
+	}
+	if string(cached) != string(fresh) {
+		t.Errorf("Cached response differs from the original:\n%s\n%s", fresh, cached)
+	}
+}
+
+// readWS reads one JSON message from the websocket
+func readWS(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("Failed to read websocket message: %v", err)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("Invalid websocket message %s: %v", data, err)
+	}
+	return msg
+}
+
+// getBody fetches a URL, failing the test on anything but 200
+func getBody(t *testing.T, url string) ([]byte, http.Header) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s returned %d: %s", url, resp.StatusCode, body)
+	}
+	return body, resp.Header
+}
@@ -0,0 +1,82 @@
+// tests/integration/pnl_test.go
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestPnLStream opens a paper position, subscribes to its P&L and checks
+// live ticks mark it to market
+func TestPnLStream(t *testing.T) {
+	ticker := fmt.Sprintf("PL%d", time.Now().UnixNano()%1000000)
+	nats := natsURL(t)
+	gateway := startGateway(t, nats, startTradingService(t).Addr, "PNL_UPDATE_INTERVAL=100ms")
+
+	position, _ := json.Marshal(map[string]interface{}{
+		"ticker": ticker, "direction": "LONG", "quantity": 10, "entry_price": 100.0, "simulated": true,
+	})
+	resp, err := http.Post(gateway+"/api/risk/positions", "application/json", bytes.NewReader(position))
+	if err != nil {
+		t.Fatalf("Failed to open position: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201 opening position, got %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, "ws"+strings.TrimPrefix(gateway, "http")+"/api/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to gateway websocket: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteJSON(map[string]string{"action": "subscribe", "type": "pnl", "ticker": ticker}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if msg := readWS(t, conn); msg["event"] != "subscribed" {
+		t.Fatalf("Expected subscription confirmation, got %v", msg)
+	}
+
+	// Before any tick the position is marked at entry
+	msg := readWS(t, conn)
+	if msg["event"] != "pnl" || msg["unrealized_pnl"] != 0.0 {
+		t.Fatalf("Expected an initial flat P&L update, got %v", msg)
+	}
+
+	client, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+	for _, price := range []float64{101, 102.5} {
+		if err := client.PublishMarketLiveData(ctx, ticker, map[string]interface{}{"ticker": ticker, "price": price}); err != nil {
+			t.Fatalf("Failed to publish tick: %v", err)
+		}
+	}
+
+	// Updates are throttled, so the last tick wins eventually
+	for {
+		msg = readWS(t, conn)
+		if msg["unrealized_pnl"] == 25.0 {
+			break
+		}
+	}
+	positions, _ := msg["positions"].([]interface{})
+	if len(positions) != 1 {
+		t.Fatalf("Expected 1 position, got %v", msg["positions"])
+	}
+	if pos := positions[0].(map[string]interface{}); pos["mark_price"] != 102.5 || pos["marked"] != true {
+		t.Errorf("Unexpected position P&L: %v", pos)
+	}
+}
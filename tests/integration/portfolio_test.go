@@ -0,0 +1,87 @@
+// tests/integration/portfolio_test.go
+package integration
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// TestPortfolioBacktest checks portfolio backtests send every ticker with
+// the allocation rule, return the equity curve in date order with each
+// ticker's breakdown, and reject malformed portfolios
+func TestPortfolioBacktest(t *testing.T) {
+	gateway, trading := contractGateway(t)
+
+	var resp struct {
+		Tickers    []string                          `json:"tickers"`
+		Allocation string                            `json:"allocation"`
+		Results    map[string]map[string]interface{} `json:"results"`
+		Portfolio  map[string]struct {
+			InitialCapital float64 `json:"initial_capital"`
+			FinalEquity    float64 `json:"final_equity"`
+			EquityCurve    []struct {
+				Date   string  `json:"date"`
+				Equity float64 `json:"equity"`
+			} `json:"equity_curve"`
+			Symbols map[string]map[string]interface{} `json:"symbols"`
+		} `json:"portfolio"`
+		Missing []string `json:"missing"`
+	}
+	getJSON(t, gateway+"/api/backtest/portfolio?tickers=aapl,%20msft,AAPL&days=30&benchmark=QQQ", http.StatusOK, &resp)
+
+	req := onlyCall(t, trading, "RunBacktest", 30*time.Second).(*pb.BacktestRequest)
+	if !reflect.DeepEqual(req.Tickers, []string{"AAPL", "MSFT"}) || req.Ticker != "" || req.Benchmark != "" {
+		t.Errorf("Unexpected tickers in request: %+v", req)
+	}
+	if req.Allocation == nil || req.Allocation.Method != "equal" || req.Allocation.InitialCapital != 100000 {
+		t.Errorf("Expected an equal split of 100000, got %+v", req.Allocation)
+	}
+
+	if resp.Allocation != "equal" || len(resp.Tickers) != 2 || resp.Missing == nil {
+		t.Errorf("Unexpected portfolio response: %+v", resp)
+	}
+	if resp.Results["2R"]["total_return"] != 1250.0 {
+		t.Errorf("Expected portfolio-level results, got %v", resp.Results)
+	}
+	portfolio := resp.Portfolio["2R"]
+	if portfolio.FinalEquity != 101250 || len(portfolio.EquityCurve) != 3 || portfolio.EquityCurve[0].Date != "2024-03-04" {
+		t.Errorf("Unexpected equity curve: %+v", portfolio)
+	}
+	for _, ticker := range []string{"AAPL", "MSFT"} {
+		symbol := portfolio.Symbols[ticker]
+		if symbol["weight"] != 0.5 || symbol["capital"] != 50000.0 || symbol["result"] == nil {
+			t.Errorf("Unexpected breakdown for %s: %v", ticker, symbol)
+		}
+	}
+
+	// Explicit weights and capital are passed through as given
+	trading.Reset()
+	getJSON(t, gateway+"/api/backtest/portfolio?tickers=AAPL,MSFT,NVDA&allocation=weights&weights=AAPL:3,MSFT:1&capital=50000", http.StatusOK, nil)
+	req = onlyCall(t, trading, "RunBacktest", 30*time.Second).(*pb.BacktestRequest)
+	if want := map[string]float64{"AAPL": 3, "MSFT": 1}; !reflect.DeepEqual(req.Allocation.Weights, want) || req.Allocation.InitialCapital != 50000 {
+		t.Errorf("Unexpected weighted allocation: %+v", req.Allocation)
+	}
+
+	trading.Reset()
+	for _, query := range []string{
+		"",
+		"tickers=AAPL",
+		"tickers=AAPL,AAPL",
+		"tickers=AAPL,BAD$",
+		"tickers=AAPL,MSFT&allocation=momentum",
+		"tickers=AAPL,MSFT&allocation=weights",
+		"tickers=AAPL,MSFT&allocation=weights&weights=AAPL:1,TSLA:1",
+		"tickers=AAPL,MSFT&allocation=weights&weights=AAPL:0,MSFT:0",
+		"tickers=AAPL,MSFT&weights=AAPL:1",
+		"tickers=AAPL,MSFT&capital=-5",
+	} {
+		getJSON(t, gateway+"/api/backtest/portfolio?"+query, http.StatusBadRequest, nil)
+	}
+	if calls := trading.Calls("RunBacktest"); len(calls) != 0 {
+		t.Errorf("Expected invalid portfolios rejected before the trading service, got %d calls", len(calls))
+	}
+}
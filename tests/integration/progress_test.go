@@ -0,0 +1,239 @@
+// tests/integration/progress_test.go
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestHistoricalProgress checks a historical request made over the
+// WebSocket carries a request ID and that the progress published for it is
+// relayed to the client until the request is done
+func TestHistoricalProgress(t *testing.T) {
+	ticker := fmt.Sprintf("PG%d", time.Now().UnixNano()%1000000)
+	natsAddr := natsURL(t)
+	gateway := startGateway(t, natsAddr, startTradingService(t).Addr)
+
+	responder, err := events.NewEventClient(natsAddr)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer responder.Close()
+
+	// Stand in for the market data service: report each stage of the
+	// request as it would
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	sub, err := responder.SubscribeHistoricalRequests(func(requestTicker, timeframe string, days int, reqData []byte) {
+		if requestTicker != ticker {
+			return
+		}
+		progress := events.RequestProgress{
+			RequestID: events.RequestID(reqData),
+			Ticker:    requestTicker,
+			Timeframe: timeframe,
+			Days:      days,
+		}
+		for _, stage := range []events.RequestProgress{
+			{Stage: events.ProgressQueued},
+			{Stage: events.ProgressFetching},
+			{Stage: events.ProgressPublishing, FetchedPercent: 100, Records: 40, TotalChunks: 2},
+			{Stage: events.ProgressPublishing, FetchedPercent: 100, Records: 40, TotalChunks: 2, ChunksPublished: 1},
+			{Stage: events.ProgressDone, FetchedPercent: 100, Records: 40, TotalChunks: 2, ChunksPublished: 2},
+		} {
+			stage.RequestID, stage.Ticker, stage.Timeframe, stage.Days = progress.RequestID, progress.Ticker, progress.Timeframe, progress.Days
+			if err := responder.PublishRequestProgress(ctx, stage); err != nil {
+				t.Errorf("Failed to publish progress: %v", err)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("Failed to subscribe to historical requests: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, "ws"+strings.TrimPrefix(gateway, "http")+"/api/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to gateway websocket: %v", err)
+	}
+	defer conn.Close()
+
+	// Invalid parameters are refused
+	if err := conn.WriteJSON(map[string]interface{}{"action": "historical", "ticker": ticker, "interval": "7weeks", "days": 5}); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	read := func() map[string]interface{} {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		return msg
+	}
+	if msg := read(); msg["error"] == nil {
+		t.Fatalf("Expected an error for an invalid interval, got %v", msg)
+	}
+
+	if err := conn.WriteJSON(map[string]interface{}{"action": "historical", "ticker": ticker, "interval": "5min", "days": 5}); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+
+	var requestID string
+	var stages []string
+	for len(stages) == 0 || stages[len(stages)-1] != events.ProgressDone {
+		msg := read()
+		switch msg["event"] {
+		case "historical_requested":
+			requestID, _ = msg["request_id"].(string)
+			if requestID == "" || msg["progress"] != fmt.Sprintf(events.SubjectRequestProgress, requestID) {
+				t.Fatalf("Expected a request ID and its progress subject, got %v", msg)
+			}
+			if msg["subject"] != fmt.Sprintf(events.SubjectMarketHistoricalData, ticker, "5min", 5) {
+				t.Errorf("Expected the data subject of the request, got %v", msg["subject"])
+			}
+		case "historical_progress":
+			if requestID == "" {
+				t.Fatalf("Expected progress only after the request was confirmed, got %v", msg)
+			}
+			if msg["request_id"] != requestID {
+				t.Fatalf("Expected progress for %s, got %v", requestID, msg)
+			}
+			stages = append(stages, msg["stage"].(string))
+		}
+	}
+
+	want := []string{events.ProgressQueued, events.ProgressFetching, events.ProgressPublishing, events.ProgressPublishing, events.ProgressDone}
+	if strings.Join(stages, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected stages %v, got %v", want, stages)
+	}
+}
+
+// TestCancelRequest checks a historical request can be cancelled over the
+// API and the WebSocket, and that the cancelled stage reaches the client
+func TestCancelRequest(t *testing.T) {
+	ticker := fmt.Sprintf("CN%d", time.Now().UnixNano()%1000000)
+	natsAddr := natsURL(t)
+	gateway := startGateway(t, natsAddr, startTradingService(t).Addr)
+
+	responder, err := events.NewEventClient(natsAddr)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer responder.Close()
+
+	// Stand in for the market data service: hold requests until they are
+	// cancelled, then report them cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cancels := make(chan string, 10)
+	held := make(chan events.RequestProgress, 10)
+	sub, err := responder.SubscribeHistoricalRequests(func(requestTicker, timeframe string, days int, reqData []byte) {
+		if requestTicker != ticker {
+			return
+		}
+		progress := events.RequestProgress{RequestID: events.RequestID(reqData), Ticker: requestTicker, Timeframe: timeframe, Days: days, Stage: events.ProgressQueued}
+		responder.PublishRequestProgress(ctx, progress)
+		held <- progress
+	})
+	if err != nil {
+		t.Fatalf("Failed to subscribe to historical requests: %v", err)
+	}
+	defer sub.Unsubscribe()
+	cancelSub, err := responder.SubscribeRequestCancels(func(requestID string) { cancels <- requestID })
+	if err != nil {
+		t.Fatalf("Failed to subscribe to cancellations: %v", err)
+	}
+	defer cancelSub.Unsubscribe()
+
+	// The API publishes the cancellation for any well-formed ID
+	del := func(id string, status int) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodDelete, gateway+"/api/requests/"+id, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("DELETE /api/requests/%s failed: %v", id, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("DELETE /api/requests/%s: expected %d, got %d", id, status, resp.StatusCode)
+		}
+	}
+	apiID := events.NewRequestID("api")
+	del(apiID, http.StatusAccepted)
+	select {
+	case id := <-cancels:
+		if id != apiID {
+			t.Errorf("Expected cancellation of %s, got %s", apiID, id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the cancellation of %s to be published", apiID)
+	}
+	del("bad%2Eid", http.StatusBadRequest)
+
+	// A WebSocket client cancels its own request and sees it stop
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, "ws"+strings.TrimPrefix(gateway, "http")+"/api/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to gateway websocket: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteJSON(map[string]interface{}{"action": "historical", "ticker": ticker, "interval": "1day", "days": 30}); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	read := func() map[string]interface{} {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		return msg
+	}
+
+	var requestID string
+	for requestID == "" {
+		if msg := read(); msg["event"] == "historical_requested" {
+			requestID, _ = msg["request_id"].(string)
+		}
+	}
+	var progress events.RequestProgress
+	select {
+	case progress = <-held:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the request to reach the responder")
+	}
+	if err := conn.WriteJSON(map[string]interface{}{"action": "cancel", "request_id": requestID}); err != nil {
+		t.Fatalf("Failed to send cancel: %v", err)
+	}
+	select {
+	case id := <-cancels:
+		if id != requestID || progress.RequestID != requestID {
+			t.Fatalf("Expected cancellation of %s, got %s", requestID, id)
+		}
+		progress.Stage = events.ProgressCancelled
+		responder.PublishRequestProgress(ctx, progress)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the WebSocket cancellation to be published")
+	}
+
+	for {
+		msg := read()
+		if msg["event"] != "historical_progress" {
+			continue
+		}
+		if msg["stage"] == events.ProgressCancelled {
+			break
+		}
+		if msg["stage"] != events.ProgressQueued {
+			t.Fatalf("Expected the request to stay queued until cancelled, got %v", msg)
+		}
+	}
+}
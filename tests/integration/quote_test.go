@@ -0,0 +1,85 @@
+// tests/integration/quote_test.go
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// TestQuote checks /api/quote consolidates the book, live ticks and trade
+// prints, ignoring a stale book published late
+func TestQuote(t *testing.T) {
+	ticker := fmt.Sprintf("QT%d", time.Now().UnixNano()%1000000)
+	nats := natsURL(t)
+	gateway := startGateway(t, nats, startTradingService(t).Addr)
+	url := gateway + "/api/quote?ticker=" + ticker
+
+	getJSON(t, url, http.StatusNotFound, nil)
+
+	client, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	book := func(bid, ask float64, at time.Time) *market.OrderBook {
+		b := &market.OrderBook{
+			Ticker:    ticker,
+			Timestamp: at,
+			Bids:      []market.BookLevel{{Price: bid, Size: 300}},
+			Asks:      []market.BookLevel{{Price: ask, Size: 200}},
+		}
+		b.Summarize()
+		return b
+	}
+	if err := client.PublishOrderBook(ctx, ticker, book(99.9, 100.1, now)); err != nil {
+		t.Fatalf("Failed to publish book: %v", err)
+	}
+	if err := client.PublishOrderBook(ctx, ticker, book(98, 99, now.Add(-time.Minute))); err != nil {
+		t.Fatalf("Failed to publish book: %v", err)
+	}
+	tick := map[string]interface{}{"ticker": ticker, "price": 100.0, "volume": 1500, "timestamp": now}
+	if err := client.PublishMarketLiveData(ctx, ticker, tick); err != nil {
+		t.Fatalf("Failed to publish tick: %v", err)
+	}
+
+	var quote map[string]interface{}
+	fetch := func() bool {
+		resp, err := http.Get(url)
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		quote = nil
+		return json.NewDecoder(resp.Body).Decode(&quote) == nil
+	}
+	waitFor(t, 10*time.Second, "quote from the book and tick", func() bool {
+		return fetch() && quote["last"] == 100.0 && quote["bid"] != nil
+	})
+
+	// A later trade print moves the last price but keeps the tick's volume
+	trade := market.Trade{Ticker: ticker, Timestamp: now.Add(time.Second), Price: 100.05, Size: 40}
+	if err := client.PublishTrade(ctx, ticker, trade); err != nil {
+		t.Fatalf("Failed to publish trade: %v", err)
+	}
+	waitFor(t, 10*time.Second, "quote from the trade print", func() bool {
+		return fetch() && quote["last"] == 100.05
+	})
+
+	if quote["bid"] != 99.9 || quote["ask"] != 100.1 || quote["bid_size"] != 300.0 {
+		t.Errorf("Expected the newer book's top of book, got %v", quote)
+	}
+	if quote["volume"] != 1500.0 || quote["last_size"] != 40.0 {
+		t.Errorf("Expected volume from the tick and size from the trade, got %v", quote)
+	}
+}
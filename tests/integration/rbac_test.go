@@ -0,0 +1,92 @@
+// tests/integration/rbac_test.go
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRoleBasedAccess checks viewers can read but not manage alerts, traders
+// can manage alerts but not operate streams, and /api/me reports each
+// caller's allowed actions
+func TestRoleBasedAccess(t *testing.T) {
+	tokens := filepath.Join(t.TempDir(), "tokens.json")
+	if err := os.WriteFile(tokens, []byte(`[
+		{"token": "viewer-token", "user": "viewer@example.com", "role": "viewer"},
+		{"token": "trader-token", "user": "trader@example.com", "role": "trader"}
+	]`), 0600); err != nil {
+		t.Fatalf("Failed to write tokens file: %v", err)
+	}
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"API_TOKENS_FILE="+tokens,
+		"AUTH_ANONYMOUS_ROLE=none",
+	)
+
+	do := func(method, path, token string, payload interface{}, v interface{}) int {
+		t.Helper()
+		var body bytes.Buffer
+		if payload != nil {
+			json.NewEncoder(&body).Encode(payload)
+		}
+		req, _ := http.NewRequest(method, gateway+path, &body)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		if v != nil {
+			json.NewDecoder(resp.Body).Decode(v)
+		}
+		return resp.StatusCode
+	}
+
+	var me struct {
+		User          string   `json:"user"`
+		Role          string   `json:"role"`
+		Authenticated bool     `json:"authenticated"`
+		Permissions   []string `json:"permissions"`
+	}
+	if status := do(http.MethodGet, "/api/me", "viewer-token", nil, &me); status != http.StatusOK {
+		t.Fatalf("Expected 200 from /api/me, got %d", status)
+	}
+	if me.User != "viewer@example.com" || me.Role != "viewer" || !me.Authenticated ||
+		len(me.Permissions) != 1 || me.Permissions[0] != "read" {
+		t.Errorf("Unexpected viewer identity %+v", me)
+	}
+
+	rule := map[string]interface{}{"ticker": "SPY", "condition": "above", "price": 500}
+	if status := do(http.MethodGet, "/api/alerts/rules", "viewer-token", nil, nil); status != http.StatusOK {
+		t.Errorf("Expected viewers to list alert rules, got %d", status)
+	}
+	if status := do(http.MethodPost, "/api/alerts/rules", "viewer-token", rule, nil); status != http.StatusForbidden {
+		t.Errorf("Expected 403 creating an alert rule as a viewer, got %d", status)
+	}
+	if status := do(http.MethodPost, "/api/alerts/rules", "", rule, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 creating an alert rule anonymously, got %d", status)
+	}
+	if status := do(http.MethodGet, "/api/alerts/rules", "bogus-token", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with an unknown token, got %d", status)
+	}
+
+	// Traders manage alerts, but only admins operate streams
+	if status := do(http.MethodPost, "/api/alerts/subscribers", "trader-token", map[string]interface{}{
+		"email": "trader@example.com", "watchlist": []string{"SPY"},
+	}, nil); status != http.StatusOK {
+		t.Errorf("Expected traders to subscribe, got %d", status)
+	}
+	if status := do(http.MethodPost, "/api/ops/consumers/prune", "trader-token", nil, nil); status != http.StatusForbidden {
+		t.Errorf("Expected 403 pruning consumers as a trader, got %d", status)
+	}
+	if status := do(http.MethodGet, "/api/me", "trader-token", nil, &me); status != http.StatusOK || me.Role != "trader" {
+		t.Errorf("Expected the trader role from /api/me, got %d %+v", status, me)
+	}
+}
@@ -0,0 +1,53 @@
+// tests/integration/recommendations_test.go
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/nats-io/nats.go"
+)
+
+// TestRecommendationsPublishedOnce checks a recommendation is published when
+// first seen and not again when later requests return it
+func TestRecommendationsPublishedOnce(t *testing.T) {
+	natsAddr := natsURL(t)
+	ticker := fmt.Sprintf("RC%d", time.Now().UnixNano()%1000000)
+
+	client, err := events.NewEventClient(natsAddr)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+	published := make(chan *nats.Msg, 8)
+	sub, err := client.GetNATS().ChanSubscribe(fmt.Sprintf(events.SubjectRecommendationsTicker, ticker), published)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to recommendations: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	trading := startTradingService(t)
+	gateway := startGateway(t, natsAddr, trading.Addr)
+
+	for i := 0; i < 3; i++ {
+		var recs []map[string]interface{}
+		getJSON(t, gateway+"/api/recommendations?ticker="+ticker+"&days=15&interval=1day&strategy=RedCandle", http.StatusOK, &recs)
+		if len(recs) != 1 || recs[0]["id"] == nil {
+			t.Fatalf("Expected one tracked recommendation, got %v", recs)
+		}
+	}
+
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the recommendation to be published")
+	}
+	select {
+	case msg := <-published:
+		t.Errorf("Recommendation published again on %s", msg.Subject)
+	case <-time.After(time.Second):
+	}
+}
@@ -0,0 +1,55 @@
+// tests/integration/recovery_test.go
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestGatewayRecoversByProbing checks a degraded gateway stays degraded while
+// the trading service is down and returns to normal once its probes succeed,
+// without waiting for another user request
+func TestGatewayRecoversByProbing(t *testing.T) {
+	trading := startTradingService(t)
+	gateway := startGateway(t, natsURL(t), trading.Addr,
+		"SERVICE_PROBE_INTERVAL=200ms",
+		"SERVICE_MIN_MODE_DURATION=1s",
+		"SERVICE_RECOVERY_SUCCESSES=2",
+	)
+
+	type recoveryStatus struct {
+		Mode     string `json:"mode"`
+		Recovery struct {
+			Successes      int    `json:"successes"`
+			LastProbeError string `json:"last_probe_error"`
+		} `json:"recovery"`
+	}
+	status := func() recoveryStatus {
+		var s recoveryStatus
+		getJSON(t, gateway+"/api/status", http.StatusOK, &s)
+		return s
+	}
+
+	trading.Fail.Store(true)
+	getJSON(t, gateway+"/api/historical-data?ticker=SPY&days=5&interval=1day", http.StatusInternalServerError, nil)
+	if s := status(); s.Mode != "degraded" {
+		t.Fatalf("Expected degraded mode after failed calls, got %q", s.Mode)
+	}
+
+	// Failing probes keep it degraded
+	waitFor(t, 5*time.Second, "a failed probe", func() bool {
+		return status().Recovery.LastProbeError != ""
+	})
+	if s := status(); s.Mode != "degraded" || s.Recovery.Successes != 0 {
+		t.Errorf("Expected degraded with no successes while the service is down, got %+v", s)
+	}
+
+	trading.Fail.Store(false)
+	waitFor(t, 10*time.Second, "recovery to normal mode", func() bool {
+		return status().Mode == "normal"
+	})
+	if calls := len(trading.Calls("GetHistoricalData")); calls != 3 {
+		t.Errorf("Expected recovery without further requests, trading service saw %d calls", calls)
+	}
+}
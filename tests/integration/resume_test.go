@@ -0,0 +1,80 @@
+// tests/integration/resume_test.go
+package integration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestWebSocketResume disconnects a signals subscriber, publishes while it is
+// away and checks that resuming from its last sequence replays the missed
+// signals in order before live ones
+func TestWebSocketResume(t *testing.T) {
+	ticker := fmt.Sprintf("RS%d", time.Now().UnixNano()%1000000)
+	nats := natsURL(t)
+	gateway := startGateway(t, nats, startTradingService(t).Addr)
+	wsURL := "ws" + strings.TrimPrefix(gateway, "http") + "/api/ws"
+
+	client, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	publish := func(n int) {
+		t.Helper()
+		if err := client.PublishSignal(ctx, ticker, map[string]interface{}{"ticker": ticker, "n": n}); err != nil {
+			t.Fatalf("Failed to publish signal %d: %v", n, err)
+		}
+	}
+	dial := func(request map[string]interface{}) *websocket.Conn {
+		t.Helper()
+		conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+		if err != nil {
+			t.Fatalf("Failed to connect to gateway websocket: %v", err)
+		}
+		if err := conn.WriteJSON(request); err != nil {
+			t.Fatalf("Failed to send %v: %v", request, err)
+		}
+		if msg := readWS(t, conn); msg["event"] != "subscribed" {
+			t.Fatalf("Expected subscription confirmation, got %v", msg)
+		}
+		return conn
+	}
+
+	// First session sees signal 1 and records its sequence
+	conn := dial(map[string]interface{}{"action": "subscribe", "type": "signals", "ticker": ticker})
+	publish(1)
+	msg := readWS(t, conn)
+	seq, ok := msg["stream_seq"].(float64)
+	if msg["n"] != 1.0 || !ok || seq == 0 {
+		t.Fatalf("Expected signal 1 with a stream sequence, got %v", msg)
+	}
+	conn.Close()
+
+	// Missed while disconnected
+	publish(2)
+	publish(3)
+
+	subject := "signals." + ticker
+	conn = dial(map[string]interface{}{"action": "resume", "positions": map[string]uint64{subject: uint64(seq)}})
+	defer conn.Close()
+
+	for _, want := range []float64{2, 3} {
+		if msg := readWS(t, conn); msg["n"] != want {
+			t.Fatalf("Expected replayed signal %v, got %v", want, msg)
+		}
+	}
+	publish(4)
+	if msg := readWS(t, conn); msg["n"] != 4.0 {
+		t.Fatalf("Expected live signal 4, got %v", msg)
+	}
+}
@@ -0,0 +1,86 @@
+// tests/integration/retention_test.go
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestDataRetention checks a retention run purges minute data past its
+// configured max age while keeping daily data for the same ticker
+func TestDataRetention(t *testing.T) {
+	ticker := fmt.Sprintf("RT%d", time.Now().UnixNano()%1000000)
+	policyPath := filepath.Join(t.TempDir(), "retention.json")
+	if err := os.WriteFile(policyPath, []byte(`{"us_equity": {"1min": "1ms"}}`), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+
+	nats := natsURL(t)
+	gateway := startGateway(t, nats, startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"ADMIN_TOKEN=retention-admin",
+		"RETENTION_CONFIG_PATH="+policyPath,
+	)
+
+	client, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	bars := []map[string]interface{}{{"date": "2024-01-02", "close": 10.0}}
+	for _, interval := range []string{"1min", "1day"} {
+		if err := client.PublishHistoricalSync(ctx, ticker, interval, bars); err != nil {
+			t.Fatalf("Failed to publish %s sync: %v", interval, err)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	call := func(method string, status int, v interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest(method, gateway+"/api/ops/retention", nil)
+		req.Header.Set("Authorization", "Bearer retention-admin")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s retention failed: %v", method, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("%s retention: expected %d, got %d", method, status, resp.StatusCode)
+		}
+		json.NewDecoder(resp.Body).Decode(v)
+	}
+
+	var policy struct {
+		Policy map[string]map[string]string `json:"policy"`
+	}
+	call(http.MethodGet, http.StatusOK, &policy)
+	if policy.Policy["us_equity"]["1min"] != "1ms" || policy.Policy["*"]["1day"] != "43800h0m0s" {
+		t.Fatalf("Expected the configured policy over the defaults, got %v", policy.Policy)
+	}
+
+	var summary events.RetentionSummary
+	call(http.MethodPost, http.StatusOK, &summary)
+	if summary.StreamMaxAge[events.StreamMarketHistorical] != "43800h0m0s" {
+		t.Errorf("Expected the historical stream kept as long as daily data, got %v", summary.StreamMaxAge)
+	}
+	purged := make(map[string]uint64)
+	for _, p := range summary.Purged {
+		purged[p.Subject] = p.Purged
+	}
+	if purged["market.historical.sync."+ticker+".1min"] != 1 {
+		t.Errorf("Expected the minute sync purged, got %+v", summary.Purged)
+	}
+	if _, ok := purged["market.historical.sync."+ticker+".1day"]; ok {
+		t.Errorf("Expected the daily sync kept, got %+v", summary.Purged)
+	}
+}
@@ -0,0 +1,55 @@
+// tests/integration/risksectors_test.go
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestRiskSectors checks positions are bucketed by the sector the gateway
+// knows for their ticker, so a client cannot relabel a ticker to escape the
+// sector limit, and that a sector given for an unknown ticker is only kept
+// once a position is accepted
+func TestRiskSectors(t *testing.T) {
+	prefix := fmt.Sprintf("RS%d", time.Now().UnixNano()%100000)
+	first, second, unknown := prefix+"A", prefix+"B", prefix+"C"
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr,
+		"RISK_SECTORS="+first+":Technology,"+second+":Technology",
+		"RISK_MAX_SECTOR_EXPOSURE=15000",
+		"RISK_MAX_TICKER_EXPOSURE=15000")
+
+	open := func(ticker, sector string, quantity int, status int) map[string]interface{} {
+		t.Helper()
+		body, _ := json.Marshal(map[string]interface{}{
+			"ticker": ticker, "sector": sector, "direction": "LONG", "quantity": quantity, "entry_price": 100.0, "simulated": true,
+		})
+		resp, err := http.Post(gateway+"/api/risk/positions", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to open position: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("Opening %d %s in %q: expected %d, got %d", quantity, ticker, sector, status, resp.StatusCode)
+		}
+		var pos map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&pos)
+		return pos
+	}
+
+	if pos := open(first, "", 100, http.StatusCreated); pos["sector"] != "Technology" {
+		t.Errorf("Expected the known sector, got %v", pos["sector"])
+	}
+	open(second, "misc", 100, http.StatusBadRequest)
+	open(second, "", 100, http.StatusConflict)
+
+	// A rejected position does not label its ticker
+	open(unknown, "Energy", 200, http.StatusConflict)
+	if pos := open(unknown, "Utilities", 100, http.StatusCreated); pos["sector"] != "Utilities" {
+		t.Errorf("Expected the accepted position's sector, got %v", pos["sector"])
+	}
+	open(unknown, "Energy", 10, http.StatusBadRequest)
+}
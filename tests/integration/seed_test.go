@@ -0,0 +1,59 @@
+// tests/integration/seed_test.go
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestSimulationSeed checks simulated candles are reproducible from the
+// seed the gateway reports, and differ between seeds
+func TestSimulationSeed(t *testing.T) {
+	trading := startTradingService(t)
+	trading.Fail.Store(true)
+	gateway := startGateway(t, natsURL(t), trading.Addr,
+		"FALLBACK_DATA_POLICY=labeled-sample",
+		"SIMULATION_SEED=7")
+	ticker := fmt.Sprintf("SS%d", time.Now().UnixNano()%1000000)
+
+	closes := func(query string) ([]float64, string) {
+		t.Helper()
+		resp, err := http.Get(gateway + "/api/historical-data?ticker=" + ticker + "&days=5&interval=1day" + query)
+		if err != nil {
+			t.Fatalf("Failed to get historical data: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected simulated candles, got %d", resp.StatusCode)
+		}
+		var candles []map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&candles); err != nil {
+			t.Fatalf("Invalid candles: %v", err)
+		}
+		values := make([]float64, 0, len(candles))
+		for _, c := range candles {
+			values = append(values, c["close"].(float64))
+		}
+		return values, resp.Header.Get("X-Simulation-Seed")
+	}
+
+	first, seed := closes("&seed=42")
+	again, _ := closes("&seed=42")
+	if seed != "42" || len(first) == 0 || !reflect.DeepEqual(first, again) {
+		t.Errorf("Expected the same candles for seed 42, got %v and %v (seed %q)", first, again, seed)
+	}
+	if other, _ := closes("&seed=43"); reflect.DeepEqual(first, other) {
+		t.Errorf("Expected different candles for another seed")
+	}
+
+	// Without a seed parameter the configured seed is used
+	if _, seed := closes(""); seed != "7" {
+		t.Errorf("Expected SIMULATION_SEED reported, got %q", seed)
+	}
+
+	getJSON(t, gateway+"/api/historical-data?ticker="+ticker+"&days=5&interval=1day&seed=abc", http.StatusBadRequest, nil)
+}
@@ -0,0 +1,102 @@
+// tests/integration/sessions_test.go
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/myapp/tradinglab/proto"
+)
+
+// TestSessionFilters checks strategies configured with trading hours drop
+// signals outside them on intraday bars, and that backtests are sent the
+// entry window of each session, shortened on half days
+func TestSessionFilters(t *testing.T) {
+	sessionsPath := filepath.Join(t.TempDir(), "sessions.json")
+	if err := os.WriteFile(sessionsPath, []byte(`{"RedCandle": {"start": "10:15", "skip_close_minutes": 30}}`), 0644); err != nil {
+		t.Fatalf("Failed to write strategy sessions: %v", err)
+	}
+	trading := startTradingService(t)
+	gateway := startGateway(t, natsURL(t), trading.Addr, "STRATEGY_SESSIONS_PATH="+sessionsPath)
+	ticker := fmt.Sprintf("SF%d", time.Now().UnixNano()%1000000)
+
+	// The fake trading service signals at 10:00, before the window opens
+	var signals []map[string]interface{}
+	getJSON(t, gateway+"/api/signals?ticker="+ticker+"&interval=15min", http.StatusOK, &signals)
+	if len(signals) != 0 {
+		t.Errorf("Expected the 10:00 signal dropped, got %v", signals)
+	}
+
+	// Daily bars have no time of day to filter on
+	getJSON(t, gateway+"/api/signals?ticker="+ticker+"&interval=1day", http.StatusOK, &signals)
+	if len(signals) != 1 {
+		t.Errorf("Expected the daily signal kept, got %v", signals)
+	}
+
+	// User strategies carry their own filter
+	define := func(name string, session map[string]interface{}) {
+		t.Helper()
+		body, _ := json.Marshal(map[string]interface{}{
+			"name":    name,
+			"rules":   map[string]string{"long": "close > sma(20)"},
+			"session": session,
+		})
+		resp, err := http.Post(gateway+"/api/strategies", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to define strategy: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected 201 defining %s, got %d", name, resp.StatusCode)
+		}
+	}
+	define("EarlyBird", map[string]interface{}{"end": "10:30"})
+	define("LateStart", map[string]interface{}{"skip_open_minutes": 45})
+	for name, want := range map[string]int{"EarlyBird": 1, "LateStart": 0} {
+		getJSON(t, gateway+"/api/signals?ticker="+ticker+"&interval=15min&strategy="+name, http.StatusOK, &signals)
+		if len(signals) != want {
+			t.Errorf("Expected %d signals from %s, got %v", want, name, signals)
+		}
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"name": "Never", "rules": map[string]string{"long": "close > open"}, "session": map[string]string{"start": "16:30"},
+	})
+	resp, err := http.Post(gateway+"/api/strategies", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to define strategy: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a filter outside the session rejected, got %d", resp.StatusCode)
+	}
+
+	// Backtests get a window per trading day, closing 30 minutes before the
+	// early close on half days
+	getJSON(t, gateway+"/api/backtest?ticker="+ticker+"&days=30&interval=15min", http.StatusOK, nil)
+	req := onlyCall(t, trading, "RunBacktest", 30*time.Second).(*pb.BacktestRequest)
+	if !req.SessionFiltered || len(req.SessionWindows) < 15 || len(req.SessionWindows) > 23 {
+		t.Fatalf("Expected a window per trading day over 30 days, got %d", len(req.SessionWindows))
+	}
+	for _, w := range req.SessionWindows {
+		day, err := time.Parse("2006-01-02", w.Date)
+		if err != nil || day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			t.Errorf("Unexpected session date %q", w.Date)
+		}
+		if w.StartMinute != 615 || (w.EndMinute != 930 && w.EndMinute != 750) {
+			t.Errorf("Unexpected window on %s: %d to %d", w.Date, w.StartMinute, w.EndMinute)
+		}
+	}
+
+	trading.Reset()
+	getJSON(t, gateway+"/api/backtest?ticker="+ticker+"&days=30&interval=1day", http.StatusOK, nil)
+	if req := onlyCall(t, trading, "RunBacktest", 30*time.Second).(*pb.BacktestRequest); req.SessionFiltered {
+		t.Errorf("Expected daily backtests unfiltered, got %d windows", len(req.SessionWindows))
+	}
+}
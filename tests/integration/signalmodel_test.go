@@ -0,0 +1,163 @@
+// tests/integration/signalmodel_test.go
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestSignalModelScoring checks published signals are scored by the
+// configured model, published unscored when it fails, and only alert rules
+// whose minimum confidence they reach fire
+func TestSignalModelScoring(t *testing.T) {
+	ticker := fmt.Sprintf("SM%d", time.Now().UnixNano()%1000000)
+	natsAddr := natsURL(t)
+
+	// The model is confident in longs only
+	var failing atomic.Bool
+	var received atomic.Value
+	model := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Signals []map[string]interface{} `json:"signals"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		received.Store(req.Signals)
+		if failing.Load() {
+			http.Error(w, "model unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		scores := make([]float64, len(req.Signals))
+		for i, s := range req.Signals {
+			scores[i] = 0.3
+			if s["signal_type"] == "LONG" {
+				scores[i] = 0.9
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"confidence": scores})
+	}))
+	defer model.Close()
+
+	gateway := startGateway(t, natsAddr, startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"TRADINGVIEW_WEBHOOK_SECRET=s3cret",
+		"SIGNAL_MODEL_URL="+model.URL,
+	)
+
+	post := func(path string, payload interface{}, status int, v interface{}) {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		resp, err := http.Post(gateway+path, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to post %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("Expected %d from %s, got %d", status, path, resp.StatusCode)
+		}
+		if v != nil {
+			json.NewDecoder(resp.Body).Decode(v)
+		}
+	}
+
+	var sub struct {
+		ID string `json:"id"`
+	}
+	post("/api/alerts/subscribers", map[string]interface{}{"email": "model@example.com", "watchlist": []string{ticker}}, http.StatusOK, &sub)
+	rule := map[string]interface{}{"subscriber_id": sub.ID, "kind": "signal", "ticker": ticker, "min_confidence": 1.5}
+	post("/api/alerts/rules", rule, http.StatusBadRequest, nil)
+	rule["min_confidence"] = 0.6
+	post("/api/alerts/rules", rule, http.StatusCreated, nil)
+
+	client, err := events.NewEventClient(natsAddr)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+	signals := make(chan *nats.Msg, 8)
+	signalSub, err := client.GetNATS().ChanSubscribe(fmt.Sprintf(events.SubjectSignalsTicker, ticker), signals)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to signals: %v", err)
+	}
+	defer signalSub.Unsubscribe()
+	alerts := make(chan *nats.Msg, 8)
+	alertSub, err := client.GetNATS().ChanSubscribe(fmt.Sprintf(events.SubjectAlertSubscriber, sub.ID), alerts)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to alerts: %v", err)
+	}
+	defer alertSub.Unsubscribe()
+
+	// publish posts a TradingView alert and returns the signal published for it
+	publish := func(action string) map[string]interface{} {
+		t.Helper()
+		post("/api/webhooks/tradingview", map[string]interface{}{
+			"secret": "s3cret", "ticker": ticker, "action": action, "price": 50, "stoploss": 49,
+		}, http.StatusAccepted, nil)
+		select {
+		case msg := <-signals:
+			data, err := events.Decode(msg)
+			if err != nil {
+				t.Fatalf("Failed to decode signal: %v", err)
+			}
+			var signal map[string]interface{}
+			json.Unmarshal(data, &signal)
+			return signal
+		case <-time.After(10 * time.Second):
+			t.Fatal("Timed out waiting for the published signal")
+		}
+		return nil
+	}
+	expectAlert := func(want bool) {
+		t.Helper()
+		select {
+		case msg := <-alerts:
+			if !want {
+				t.Errorf("Unexpected alert: %s", msg.Data)
+				return
+			}
+			data, _ := events.Decode(msg)
+			var alert events.Alert
+			json.Unmarshal(data, &alert)
+			if alert.Data["confidence"] != "0.9" {
+				t.Errorf("Expected the alert to carry its confidence, got %v", alert.Data)
+			}
+		case <-time.After(time.Second):
+			if want {
+				t.Error("Timed out waiting for the alert")
+			}
+		}
+	}
+
+	signal := publish("buy")
+	if signal["confidence"] != 0.9 {
+		t.Errorf("Expected the long scored 0.9, got %v", signal["confidence"])
+	}
+	sent, _ := received.Load().([]map[string]interface{})
+	if len(sent) != 1 || sent[0]["ticker"] != ticker || sent[0]["entry_price"] != 50.0 || sent[0]["stoploss"] != 49.0 {
+		t.Errorf("Unexpected signals sent to the model: %v", sent)
+	}
+	expectAlert(true)
+
+	// A short scores below the rule's minimum
+	if signal = publish("sell"); signal["confidence"] != 0.3 {
+		t.Errorf("Expected the short scored 0.3, got %v", signal["confidence"])
+	}
+	expectAlert(false)
+
+	// Signals are still published when the model fails, but unscored
+	// signals do not fire rules that need a confidence
+	failing.Store(true)
+	if signal = publish("buy"); signal == nil || signal["confidence"] != nil {
+		t.Errorf("Expected the signal published unscored, got %v", signal)
+	}
+	expectAlert(false)
+}
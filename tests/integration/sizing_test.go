@@ -0,0 +1,31 @@
+// tests/integration/sizing_test.go
+package integration
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestPositionSizeStopSide checks a position is only sized when its stop is
+// on the losing side of entry
+func TestPositionSizeStopSide(t *testing.T) {
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr)
+	url := gateway + "/api/position-size?account_size=10000&risk_pct=1&entry=100"
+
+	var size struct {
+		Shares       int     `json:"shares"`
+		RiskPerShare float64 `json:"risk_per_share"`
+	}
+	getJSON(t, url+"&stoploss=95&direction=LONG", http.StatusOK, &size)
+	if size.Shares != 20 || size.RiskPerShare != 5 {
+		t.Errorf("Expected 20 shares risking $5 each, got %+v", size)
+	}
+	getJSON(t, url+"&stoploss=105&direction=SHORT", http.StatusOK, &size)
+	if size.Shares != 20 {
+		t.Errorf("Expected 20 shares short, got %+v", size)
+	}
+
+	getJSON(t, url+"&stoploss=105&direction=LONG", http.StatusBadRequest, nil)
+	getJSON(t, url+"&stoploss=95&direction=SHORT", http.StatusBadRequest, nil)
+	getJSON(t, url+"&stoploss=100", http.StatusBadRequest, nil)
+}
@@ -0,0 +1,115 @@
+// tests/integration/slack_test.go
+package integration
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestSlackCommands runs signed slash commands against the gateway and
+// checks a channel subscription posts published signals through the Web API
+func TestSlackCommands(t *testing.T) {
+	const secret = "slack-signing-secret"
+	ticker := fmt.Sprintf("SL%d", time.Now().UnixNano()%1000000)
+
+	var mu sync.Mutex
+	var posted []map[string]interface{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&msg)
+		if r.URL.Path != "/chat.postMessage" || r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			t.Errorf("Unexpected Slack API call %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		mu.Lock()
+		posted = append(posted, msg)
+		mu.Unlock()
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer api.Close()
+
+	nats := natsURL(t)
+	gateway := startGateway(t, nats, startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"SLACK_SIGNING_SECRET="+secret,
+		"SLACK_BOT_TOKEN=xoxb-test",
+		"SLACK_API_URL="+api.URL,
+	)
+
+	command := func(text, signature string) (int, map[string]interface{}) {
+		t.Helper()
+		body := url.Values{"command": {"/tl"}, "text": {text}, "channel_id": {"C123"}, "channel_name": {"trading"}}.Encode()
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		if signature == "" {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte("v0:" + timestamp + ":" + body))
+			signature = "v0=" + hex.EncodeToString(mac.Sum(nil))
+		}
+		req, _ := http.NewRequest(http.MethodPost, gateway+"/api/slack/commands", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+		req.Header.Set("X-Slack-Signature", signature)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to run command %q: %v", text, err)
+		}
+		defer resp.Body.Close()
+		var reply map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&reply)
+		return resp.StatusCode, reply
+	}
+
+	if status, _ := command("help", "v0=forged"); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a forged signature, got %d", status)
+	}
+	status, reply := command("help", "")
+	if status != http.StatusOK || reply["response_type"] != "ephemeral" || !strings.Contains(reply["text"].(string), "subscribe") {
+		t.Fatalf("Unexpected help reply %d: %v", status, reply)
+	}
+
+	status, reply = command("subscribe "+strings.ToLower(ticker), "")
+	if status != http.StatusOK || reply["response_type"] != "in_channel" || !strings.Contains(reply["text"].(string), ticker) {
+		t.Fatalf("Unexpected subscribe reply %d: %v", status, reply)
+	}
+
+	client, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	signal := map[string]interface{}{"ticker": ticker, "strategy": "RedCandle", "signal_type": "SHORT", "entry_price": 42.5, "stoploss": 43.0}
+	if err := client.PublishSignal(ctx, ticker, signal); err != nil {
+		t.Fatalf("Failed to publish signal: %v", err)
+	}
+
+	waitFor(t, 10*time.Second, "slack post", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(posted) > 0
+	})
+	mu.Lock()
+	msg := posted[0]
+	mu.Unlock()
+	if msg["channel"] != "C123" || !strings.Contains(msg["text"].(string), "SHORT "+ticker) {
+		t.Errorf("Unexpected Slack message %v", msg)
+	}
+
+	if _, reply = command("unsubscribe", ""); !strings.Contains(reply["text"].(string), "no longer") {
+		t.Errorf("Unexpected unsubscribe reply %v", reply)
+	}
+}
@@ -0,0 +1,128 @@
+// tests/integration/snapshots_test.go
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// TestSnapshotRestore checks a snapshot captures a stream and the reports
+// directory, and that restoring it replaces the stream's contents
+func TestSnapshotRestore(t *testing.T) {
+	ticker := fmt.Sprintf("SN%d", time.Now().UnixNano()%1000000)
+	reports := t.TempDir()
+	if err := os.WriteFile(filepath.Join(reports, "daily-2024-01-02.json"), []byte(`{"date":"2024-01-02"}`), 0644); err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+
+	nats := natsURL(t)
+	gateway := startGateway(t, nats, startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"ADMIN_TOKEN=snapshot-admin",
+		"ARTIFACTS_DIR="+t.TempDir(),
+		"REPORTS_DIR="+reports,
+	)
+
+	client, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		trade := market.Trade{Ticker: ticker, Timestamp: time.Now().UTC(), Price: 50 + float64(i), Size: 10}
+		if err := client.PublishTrade(ctx, ticker, trade); err != nil {
+			t.Fatalf("Failed to publish trade: %v", err)
+		}
+	}
+	trades := func() uint64 {
+		usage, err := client.StreamUsage()
+		if err != nil {
+			t.Fatalf("Failed to get stream usage: %v", err)
+		}
+		for _, u := range usage {
+			if u.Name == events.StreamTrades {
+				return u.Messages
+			}
+		}
+		t.Fatalf("No %s stream", events.StreamTrades)
+		return 0
+	}
+	stored := trades()
+
+	call := func(method, path string, status int, v interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest(method, gateway+path, nil)
+		req.Header.Set("Authorization", "Bearer snapshot-admin")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("%s %s: expected %d, got %d", method, path, status, resp.StatusCode)
+		}
+		if v != nil {
+			json.NewDecoder(resp.Body).Decode(v)
+		}
+	}
+
+	var manifest struct {
+		ID      string `json:"id"`
+		Streams []struct {
+			Name     string `json:"name"`
+			Messages uint64 `json:"messages"`
+		} `json:"streams"`
+		Store []struct {
+			Name  string `json:"name"`
+			Files int    `json:"files"`
+		} `json:"store"`
+	}
+	call(http.MethodPost, "/api/ops/snapshots?streams="+events.StreamTrades, http.StatusCreated, &manifest)
+	if len(manifest.Streams) != 1 || manifest.Streams[0].Messages != stored {
+		t.Fatalf("Expected %d trades in the snapshot, got %+v", stored, manifest.Streams)
+	}
+	found := false
+	for _, entry := range manifest.Store {
+		found = found || entry.Name == "reports" && entry.Files == 1
+	}
+	if !found {
+		t.Errorf("Expected the reports directory in the snapshot, got %+v", manifest.Store)
+	}
+
+	var list struct {
+		Snapshots []struct {
+			ID string `json:"id"`
+		} `json:"snapshots"`
+	}
+	call(http.MethodGet, "/api/ops/snapshots", http.StatusOK, &list)
+	if len(list.Snapshots) != 1 || list.Snapshots[0].ID != manifest.ID {
+		t.Fatalf("Expected snapshot %s listed, got %+v", manifest.ID, list.Snapshots)
+	}
+	call(http.MethodGet, "/api/ops/snapshots/missing", http.StatusNotFound, nil)
+
+	// Restoring over a non-empty stream needs replace
+	call(http.MethodPost, "/api/ops/snapshots/"+manifest.ID+"/restore", http.StatusConflict, nil)
+	call(http.MethodPost, "/api/ops/snapshots/"+manifest.ID+"/restore?store=true", http.StatusBadRequest, nil)
+
+	var result struct {
+		Streams map[string]uint64 `json:"streams"`
+	}
+	call(http.MethodPost, "/api/ops/snapshots/"+manifest.ID+"/restore?replace=true", http.StatusOK, &result)
+	if result.Streams[events.StreamTrades] != stored {
+		t.Errorf("Expected %d trades restored, got %v", stored, result.Streams)
+	}
+	if got := trades(); got != stored {
+		t.Errorf("Expected %d trades in the stream after restore, got %d", stored, got)
+	}
+}
@@ -0,0 +1,115 @@
+// tests/integration/stored_test.go
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// TestHistoricalFallsBackToStoredChunks checks the gateway serves historical
+// data persisted in JetStream while the trading service is down: the last
+// complete set of chunks for the request, or else bars from incremental sync
+func TestHistoricalFallsBackToStoredChunks(t *testing.T) {
+	natsAddr := natsURL(t)
+	ctx := context.Background()
+	chunked := fmt.Sprintf("RT%d", time.Now().UnixNano()%1000000)
+	synced := chunked + "S"
+
+	client, err := events.NewEventClient(natsAddr)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+
+	start := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -4)
+	bars := func(ticker string, n int, price float64) []*market.MarketData {
+		var out []*market.MarketData
+		for i := 0; i < n; i++ {
+			out = append(out, &market.MarketData{
+				Ticker: ticker, Timestamp: start.AddDate(0, 0, i),
+				Open: price, High: price + 1, Low: price - 1, Close: price, Volume: 1000,
+			})
+		}
+		return out
+	}
+	chunk := func(ticker string, data []*market.MarketData, n, total int) market.ChunkData {
+		return market.ChunkData{Data: data, Metadata: market.ChunkMetadata{
+			Ticker: ticker, Timeframe: "1day", Days: 5, Chunk: n, TotalChunks: total, DataType: "historical",
+		}}
+	}
+	publish := func(payload interface{}) {
+		t.Helper()
+		if err := client.PublishHistoricalData(ctx, chunked, "1day", 5, payload); err != nil {
+			t.Fatalf("Failed to publish historical chunk: %v", err)
+		}
+	}
+
+	// An older set, the set to serve in two chunks, an as-of chunk and a set
+	// that never completed
+	publish(chunk(chunked, bars(chunked, 5, 50), 1, 1))
+	latest := bars(chunked, 5, 100)
+	publish(chunk(chunked, latest[:3], 1, 2))
+	publish(chunk(chunked, latest[3:], 2, 2))
+	publish(events.AsOfChunk{ChunkData: chunk(chunked, bars(chunked, 5, 70), 1, 1), AsOf: market.FormatTimestamp(start)})
+	publish(chunk(chunked, bars(chunked, 3, 80), 1, 2))
+
+	// Sync republishes its last bar, so the later version should win
+	syncBars := bars(synced, 3, 200)
+	for _, data := range [][]*market.MarketData{syncBars[:2], {syncBars[1], syncBars[2]}} {
+		copied := make([]*market.MarketData, len(data))
+		for i, bar := range data {
+			b := *bar
+			copied[i] = &b
+		}
+		copied[len(copied)-1].Close++
+		if err := client.PublishHistoricalSync(ctx, synced, "1day", market.ChunkData{Data: copied}); err != nil {
+			t.Fatalf("Failed to publish sync chunk: %v", err)
+		}
+	}
+
+	trading := startTradingService(t)
+	trading.Fail.Store(true)
+	gateway := startGateway(t, natsAddr, trading.Addr, "FALLBACK_DATA_POLICY=cached-only")
+
+	fetch := func(ticker string) ([]map[string]interface{}, string) {
+		t.Helper()
+		resp, err := http.Get(gateway + "/api/historical-data?ticker=" + ticker + "&days=5&interval=1day")
+		if err != nil {
+			t.Fatalf("Historical request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected stored data for %s, got %d", ticker, resp.StatusCode)
+		}
+		var candles []map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&candles); err != nil {
+			t.Fatalf("Invalid historical response: %v", err)
+		}
+		return candles, resp.Header.Get("X-Data-Source")
+	}
+
+	candles, source := fetch(chunked)
+	if source != events.StoredSourceChunks || len(candles) != 5 {
+		t.Fatalf("Expected 5 candles from the stream, got %d from %q", len(candles), source)
+	}
+	for _, c := range candles {
+		if c["close"] != 100.0 {
+			t.Errorf("Expected candles from the last complete set, got %v", c)
+		}
+	}
+
+	candles, source = fetch(synced)
+	if source != events.StoredSourceSync || len(candles) != 3 {
+		t.Fatalf("Expected 3 candles from sync, got %d from %q", len(candles), source)
+	}
+	if candles[1]["close"] != 200.0 || candles[2]["close"] != 201.0 {
+		t.Errorf("Expected the latest version of each synced bar, got %v", candles)
+	}
+}
@@ -0,0 +1,89 @@
+// tests/integration/summary_test.go
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestDailySummary checks the hub's daily summary ranks watched tickers by
+// their move, counts signals and reports watched tickers without data, and
+// that the gateway serves the published summary
+func TestDailySummary(t *testing.T) {
+	suffix := time.Now().UnixNano() % 100000
+	up, down, flat, missing := fmt.Sprintf("UP%d", suffix), fmt.Sprintf("DN%d", suffix), fmt.Sprintf("FL%d", suffix), fmt.Sprintf("MS%d", suffix)
+	h := NewHarness(t, up, down, flat, missing)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	publisher, err := events.NewEventClient(h.NATSURL)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer publisher.Close()
+
+	prices := map[string][]float64{
+		up:   {100, 104, 110},
+		down: {50, 48, 45},
+		flat: {20, 21, 20},
+	}
+	for ticker, series := range prices {
+		for _, price := range series {
+			tick := map[string]interface{}{"ticker": ticker, "price": price, "volume": 100.0}
+			if err := publisher.PublishMarketLiveData(ctx, ticker, tick); err != nil {
+				t.Fatalf("Failed to publish tick: %v", err)
+			}
+		}
+	}
+	for _, signalType := range []string{"BUY", "BUY", "SELL"} {
+		if err := publisher.PublishSignal(ctx, up, map[string]interface{}{"ticker": up, "signal_type": signalType}); err != nil {
+			t.Fatalf("Failed to publish signal: %v", err)
+		}
+	}
+	waitFor(t, 10*time.Second, "the hub to see the session", func() bool {
+		summary := h.Hub.DailySummary()
+		return summary.Quality.LiveEvents >= 9 && summary.Signals.Total >= 3
+	})
+
+	published, err := h.Hub.PublishDailySummary(ctx)
+	if err != nil {
+		t.Fatalf("Failed to publish daily summary: %v", err)
+	}
+
+	var summary events.DailySummary
+	getJSON(t, h.GatewayURL+"/api/summary/daily", http.StatusOK, &summary)
+	if summary.Date != published.Date {
+		t.Fatalf("Expected the summary for %s, got %s", published.Date, summary.Date)
+	}
+	if len(summary.Gainers) != 1 || summary.Gainers[0].Ticker != up || summary.Gainers[0].ChangePercent != 10 {
+		t.Errorf("Expected %s as the only gainer, up 10%%, got %+v", up, summary.Gainers)
+	}
+	if len(summary.Losers) != 1 || summary.Losers[0].Ticker != down || summary.Losers[0].ChangePercent != -10 {
+		t.Errorf("Expected %s as the only loser, down 10%%, got %+v", down, summary.Losers)
+	}
+	if len(summary.Tickers) != 3 {
+		t.Errorf("Expected the three tickers with data, got %+v", summary.Tickers)
+	}
+	for _, move := range summary.Tickers {
+		if move.Ticker == up && (move.Open != 100 || move.High != 110 || move.Low != 100 || move.Bars != 3 || move.Volume != 300) {
+			t.Errorf("Expected %s to open at 100 and reach 110 over 3 bars, got %+v", up, move)
+		}
+	}
+	if summary.Signals.Total != 3 || summary.Signals.ByType["BUY"] != 2 || summary.Signals.ByTicker[up] != 3 {
+		t.Errorf("Expected 3 signals for %s, 2 of them buys, got %+v", up, summary.Signals)
+	}
+	if summary.Quality.TickersWatched != 4 || summary.Quality.TickersWithData != 3 ||
+		len(summary.Quality.Missing) != 1 || summary.Quality.Missing[0] != missing {
+		t.Errorf("Expected %s reported missing, got %+v", missing, summary.Quality)
+	}
+
+	// A date is looked up as given
+	getJSON(t, h.GatewayURL+"/api/summary/daily?date="+summary.Date, http.StatusOK, nil)
+	getJSON(t, h.GatewayURL+"/api/summary/daily?date=2001-01-01", http.StatusNotFound, nil)
+	getJSON(t, h.GatewayURL+"/api/summary/daily?date=yesterday", http.StatusBadRequest, nil)
+}
@@ -0,0 +1,85 @@
+// tests/integration/usage_test.go
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// TestUsageAccounting checks candles fetched and backtests run are charged
+// to the caller, reported at /api/usage, and limited per day when a limit
+// is configured
+func TestUsageAccounting(t *testing.T) {
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr,
+		"ADMIN_TOKEN=usage-admin", "USAGE_DAILY_BACKTESTS=1")
+
+	type counts struct {
+		Candles   int64 `json:"candles"`
+		Backtests int64 `json:"backtests"`
+	}
+	type report struct {
+		User   string `json:"user"`
+		Today  counts `json:"today"`
+		Total  counts `json:"total"`
+		Limits counts `json:"daily_limits"`
+	}
+	call := func(user, path string, status int, v interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, gateway+path, nil)
+		if user == "admin" {
+			req.Header.Set("Authorization", "Bearer usage-admin")
+		} else {
+			req.Header.Set("X-Forwarded-User", user)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("GET %s as %s: expected %d, got %d", path, user, status, resp.StatusCode)
+		}
+		if v != nil {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("GET %s: invalid JSON: %v", path, err)
+			}
+		}
+	}
+
+	// The fake trading service returns a candle per day
+	call("alice", "/api/historical-data?ticker=AAPL&interval=1day&days=5", http.StatusOK, nil)
+	call("alice", "/api/historical-data?ticker=MSFT&interval=1day&days=3", http.StatusOK, nil)
+	call("alice", "/api/backtest?ticker=AAPL&interval=1day&days=5", http.StatusOK, nil)
+	call("bob", "/api/historical-data?ticker=AAPL&interval=1day&days=2", http.StatusOK, nil)
+
+	var alice report
+	call("alice", "/api/usage", http.StatusOK, &alice)
+	if alice.User != "alice" || alice.Today.Candles != 8 || alice.Today.Backtests != 1 || alice.Total != alice.Today {
+		t.Errorf("Expected alice to be charged 8 candles and 1 backtest, got %+v", alice)
+	}
+	if alice.Limits.Backtests != 1 || alice.Limits.Candles != 0 {
+		t.Errorf("Expected a daily limit of 1 backtest and no candle limit, got %+v", alice.Limits)
+	}
+
+	// Alice has used up the day's backtests; Bob has not
+	call("alice", "/api/backtest?ticker=AAPL&interval=1day&days=5", http.StatusTooManyRequests, nil)
+	call("bob", "/api/backtest?ticker=AAPL&interval=1day&days=5", http.StatusOK, nil)
+
+	// Only operators see everyone's usage
+	call("bob", "/api/ops/usage", http.StatusUnauthorized, nil)
+	var all struct {
+		Users []report `json:"users"`
+	}
+	call("admin", "/api/ops/usage", http.StatusOK, &all)
+	byUser := make(map[string]report)
+	for _, r := range all.Users {
+		byUser[r.User] = r
+	}
+	if byUser["alice"].Total.Backtests != 1 {
+		t.Errorf("Expected the rejected backtest not to be charged, got %+v", byUser["alice"])
+	}
+	if bob := byUser["bob"]; bob.Total.Candles != 2 || bob.Total.Backtests != 1 {
+		t.Errorf("Expected bob to be charged 2 candles and 1 backtest, got %+v", bob)
+	}
+}
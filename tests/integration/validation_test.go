@@ -0,0 +1,91 @@
+// tests/integration/validation_test.go
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestSymbolValidation checks tickers that would change NATS subject
+// semantics are rejected with 400 before reaching NATS or a provider
+func TestSymbolValidation(t *testing.T) {
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr, "ALERT_DIGEST_SCHEDULE=off")
+
+	for _, ticker := range []string{"AAPL.*", "SPY.>", "A B", "TOOLONGTICKER1"} {
+		for _, path := range []string{"/api/historical-data", "/api/signals", "/api/book", "/api/signals/history"} {
+			resp, err := http.Get(gateway + path + "?ticker=" + url.QueryEscape(ticker))
+			if err != nil {
+				t.Fatalf("GET %s failed: %v", path, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected 400 from %s for ticker %q, got %d", path, ticker, resp.StatusCode)
+			}
+		}
+	}
+
+	resp, err := http.Post(gateway+"/api/alerts/rules", "application/json",
+		bytes.NewBufferString(`{"subscriber_id": "sub-1", "ticker": "SPY.*", "kind": "price", "condition": "above", "price": 1}`))
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 creating a rule for an invalid ticker, got %d", resp.StatusCode)
+	}
+
+	// Valid symbols in any spelling still work
+	resp, err = http.Get(gateway + "/api/signals/history?ticker=" + url.QueryEscape(" spy "))
+	if err != nil {
+		t.Fatalf("Failed to read signal history: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for a valid ticker, got %d", resp.StatusCode)
+	}
+}
+
+// TestRequestBounds checks oversized lookbacks, target lists and bodies are
+// rejected before any provider call or simulation
+func TestRequestBounds(t *testing.T) {
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"MAX_REQUEST_BYTES=1024",
+	)
+
+	targets := strings.TrimSuffix(strings.Repeat("1,", 11), ",")
+	for _, path := range []string{
+		"/api/historical-data?ticker=SPY&interval=1min&days=31",
+		"/api/backtest?ticker=SPY&interval=5min&days=91",
+		"/api/backtest?ticker=SPY&interval=15min&days=20&profit_targets=" + targets,
+		"/api/backtest/options?ticker=SPY&interval=15min&days=20&dte=1000",
+	} {
+		resp, err := http.Get(gateway + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 from %s, got %d", path, resp.StatusCode)
+		}
+	}
+
+	watchlist := make([]string, 0, 200)
+	for i := 0; i < 200; i++ {
+		watchlist = append(watchlist, fmt.Sprintf("T%d", i))
+	}
+	body, _ := json.Marshal(map[string]interface{}{"email": "bounds@example.com", "watchlist": watchlist})
+	resp, err := http.Post(gateway+"/api/alerts/subscribers", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a body over MAX_REQUEST_BYTES, got %d", resp.StatusCode)
+	}
+}
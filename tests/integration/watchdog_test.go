@@ -0,0 +1,125 @@
+// tests/integration/watchdog_test.go
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/myapp/tradinglab/pkg/events"
+	eventhub "github.com/myapp/tradinglab/pkg/hub"
+	"github.com/myapp/tradinglab/pkg/market"
+)
+
+// TestStaleDataWatchdog checks the hub reports a watched ticker whose live
+// data stops, asks for its stream to be restarted, and reports it resolved
+// once data resumes
+func TestStaleDataWatchdog(t *testing.T) {
+	natsAddr := natsURL(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	quiet := fmt.Sprintf("SW%d", time.Now().UnixNano()%1000000)
+	busy := quiet + "B"
+
+	client, err := events.NewEventClient(natsAddr)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+
+	alerts := make(chan events.OpsAlert, 8)
+	alertSub, err := client.SubscribeOpsAlerts(func(alert events.OpsAlert) { alerts <- alert })
+	if err != nil {
+		t.Fatalf("Failed to subscribe to ops alerts: %v", err)
+	}
+	defer alertSub.Unsubscribe()
+	restarts := make(chan events.StreamRestart, 8)
+	restartSub, err := client.SubscribeStreamRestarts(func(restart events.StreamRestart) { restarts <- restart })
+	if err != nil {
+		t.Fatalf("Failed to subscribe to stream restarts: %v", err)
+	}
+	defer restartSub.Unsubscribe()
+
+	hubClient, err := events.NewEventClient(natsAddr)
+	if err != nil {
+		t.Fatalf("Failed to create hub client: %v", err)
+	}
+	defer hubClient.Close()
+	hub := eventhub.NewEventHub(hubClient)
+	hub.SetWatchedTickers([]string{quiet, busy})
+	hub.SetReorderWindow(0)
+	hub.SetStaleConfig(eventhub.StaleConfig{Threshold: time.Second, Restart: true, AllHours: true})
+	if err := hub.Start(ctx); err != nil {
+		t.Fatalf("Failed to start event hub: %v", err)
+	}
+	defer hub.Close()
+
+	price := 100.0
+	publish := func(ticker string) {
+		t.Helper()
+		price += 0.1
+		bar := &market.MarketData{Ticker: ticker, Timestamp: time.Now(), Price: price, Close: price, Volume: 100, DataType: "live"}
+		if err := client.PublishMarketLiveData(ctx, ticker, bar); err != nil {
+			t.Fatalf("Failed to publish live data: %v", err)
+		}
+	}
+
+	// The busy ticker keeps publishing; only the quiet one goes stale
+	stopBusy := make(chan struct{})
+	go func() {
+		tick := time.NewTicker(200 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-stopBusy:
+				return
+			case <-tick.C:
+				publish(busy)
+			}
+		}
+	}()
+	defer close(stopBusy)
+
+	select {
+	case alert := <-alerts:
+		if alert.Kind != events.OpsAlertStaleData || alert.Ticker != quiet || alert.Resolved || alert.Service != "event-hub" {
+			t.Fatalf("Expected a stale data alert for %s, got %+v", quiet, alert)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the stale data alert")
+	}
+	select {
+	case restart := <-restarts:
+		if restart.Ticker != quiet {
+			t.Errorf("Expected a restart of %s, got %+v", quiet, restart)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Timed out waiting for the stream restart")
+	}
+	if stale := hub.StaleTickers(); len(stale) != 1 || stale[0] != quiet {
+		t.Errorf("Expected only %s stale, got %v", quiet, stale)
+	}
+
+	// Staying stale is not reported again
+	select {
+	case alert := <-alerts:
+		t.Errorf("Unexpected repeat alert: %+v", alert)
+	case <-time.After(1500 * time.Millisecond):
+	}
+
+	// Data resuming resolves the alert
+	publish(quiet)
+	select {
+	case alert := <-alerts:
+		if alert.Ticker != quiet || !alert.Resolved {
+			t.Errorf("Expected the alert for %s resolved, got %+v", quiet, alert)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the alert to resolve")
+	}
+	if n := hub.GetStats().TickerStats[quiet].StaleAlerts; n != 1 {
+		t.Errorf("Expected one stale alert counted, got %d", n)
+	}
+}
@@ -0,0 +1,94 @@
+// tests/integration/webhook_test.go
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestTradingViewWebhook posts TradingView alerts to the gateway and checks
+// verified alerts are published as signals
+func TestTradingViewWebhook(t *testing.T) {
+	ticker := fmt.Sprintf("TV%d", time.Now().UnixNano()%1000000)
+	natsAddr := natsURL(t)
+	gateway := startGateway(t, natsAddr, startTradingService(t).Addr, "TRADINGVIEW_WEBHOOK_SECRET=s3cret")
+
+	client, err := events.NewEventClient(natsAddr)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer client.Close()
+	msgs := make(chan *nats.Msg, 4)
+	sub, err := client.GetNATS().ChanSubscribe(fmt.Sprintf(events.SubjectSignalsTicker, ticker), msgs)
+	if err != nil {
+		t.Fatalf("Failed to subscribe to signals: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	post := func(alert map[string]interface{}) int {
+		body, _ := json.Marshal(alert)
+		resp, err := http.Post(gateway+"/api/webhooks/tradingview", "text/plain", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to post alert: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post(map[string]interface{}{"secret": "wrong", "ticker": ticker, "action": "buy", "price": 10}); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong secret, got %d", status)
+	}
+	if status := post(map[string]interface{}{"secret": "s3cret", "ticker": ticker, "action": "hold", "price": 10}); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown action, got %d", status)
+	}
+
+	status := post(map[string]interface{}{
+		"secret":   "s3cret",
+		"ticker":   "NASDAQ:" + ticker,
+		"action":   "sell",
+		"price":    "101.25",
+		"stoploss": 103,
+		"interval": "15",
+		"time":     "2024-03-04T15:00:00Z",
+		"message":  "breakdown",
+	})
+	if status != http.StatusAccepted {
+		t.Fatalf("Expected 202 for a valid alert, got %d", status)
+	}
+
+	select {
+	case msg := <-msgs:
+		data, err := events.Decode(msg)
+		if err != nil {
+			t.Fatalf("Failed to decode signal: %v", err)
+		}
+		var signal map[string]interface{}
+		if err := json.Unmarshal(data, &signal); err != nil {
+			t.Fatalf("Invalid signal JSON: %v", err)
+		}
+		if signal["ticker"] != ticker || signal["signal_type"] != "SHORT" || signal["entry_price"] != 101.25 ||
+			signal["stoploss"] != 103.0 || signal["interval"] != "15min" || signal["source"] != "tradingview" {
+			t.Errorf("Unexpected signal: %v", signal)
+		}
+		if signal["timestamp"] != "2024-03-04T15:00:00Z" {
+			t.Errorf("Unexpected signal timestamp: %v", signal["timestamp"])
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the published signal")
+	}
+
+	// Rejected alerts must not have been published
+	select {
+	case msg := <-msgs:
+		t.Errorf("Unexpected extra signal: %s", msg.Data)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
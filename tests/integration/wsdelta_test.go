@@ -0,0 +1,89 @@
+// tests/integration/wsdelta_test.go
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/myapp/tradinglab/pkg/client"
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestWSDelta checks a delta connection receives a snapshot, then diffs of
+// the changed fields, then a fresh snapshot, and that the client decoder
+// rebuilds every tick
+func TestWSDelta(t *testing.T) {
+	ticker := fmt.Sprintf("DL%d", time.Now().UnixNano()%1000000)
+	nats := natsURL(t)
+	gateway := startGateway(t, nats, startTradingService(t).Addr, "WS_DELTA_SNAPSHOT_EVERY=3")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, "ws"+strings.TrimPrefix(gateway, "http")+"/api/ws?delta=true", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to gateway websocket: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.WriteJSON(map[string]string{"action": "subscribe", "type": "market", "ticker": ticker}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	decoder := client.NewDeltaDecoder()
+	read := func() (client.DeltaFrame, map[string]interface{}) {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		var frame client.DeltaFrame
+		json.Unmarshal(data, &frame)
+		_, payload, err := decoder.Decode(data)
+		if err != nil {
+			t.Fatalf("Failed to decode %s: %v", data, err)
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			t.Fatalf("Failed to parse payload %s: %v", payload, err)
+		}
+		return frame, msg
+	}
+
+	if _, msg := read(); msg["event"] != "subscribed" {
+		t.Fatalf("Expected subscription confirmation, got %v", msg)
+	}
+
+	publisher, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer publisher.Close()
+
+	prices := []float64{100, 100.5, 101, 101.5}
+	for _, price := range prices {
+		tick := map[string]interface{}{"ticker": ticker, "price": price, "volume": 500, "exchange": "V"}
+		if err := publisher.PublishMarketLiveData(ctx, ticker, tick); err != nil {
+			t.Fatalf("Failed to publish tick: %v", err)
+		}
+	}
+
+	kinds := []string{client.DeltaSnapshot, client.DeltaDiff, client.DeltaDiff, client.DeltaSnapshot}
+	for i, price := range prices {
+		frame, msg := read()
+		if frame.Delta != kinds[i] {
+			t.Errorf("Tick %d: expected a %s frame, got %q", i, kinds[i], frame.Delta)
+		}
+		if frame.Delta == client.DeltaDiff && len(frame.Data) != 1 {
+			t.Errorf("Tick %d: expected only the price in the diff, got %v", i, frame.Data)
+		}
+		if msg["price"] != price || msg["ticker"] != ticker || msg["exchange"] != "V" {
+			t.Errorf("Tick %d: decoded %v, expected price %v", i, msg, price)
+		}
+	}
+}
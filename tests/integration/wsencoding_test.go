@@ -0,0 +1,71 @@
+// tests/integration/wsencoding_test.go
+package integration
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/myapp/tradinglab/pkg/msgpack"
+)
+
+// TestWSMessagePack checks a client negotiating MessagePack, by subprotocol
+// or query parameter, receives binary frames and may send binary requests
+func TestWSMessagePack(t *testing.T) {
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr, "ALERT_DIGEST_SCHEDULE=off")
+	wsURL := "ws" + strings.TrimPrefix(gateway, "http") + "/api/ws"
+
+	readFrame := func(conn *websocket.Conn) map[string]interface{} {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read frame: %v", err)
+		}
+		if messageType != websocket.BinaryMessage {
+			t.Fatalf("Expected a binary frame, got type %d: %s", messageType, data)
+		}
+		v, err := msgpack.Unmarshal(data)
+		if err != nil {
+			t.Fatalf("Failed to decode MessagePack frame: %v", err)
+		}
+		msg, ok := v.(map[string]interface{})
+		if !ok {
+			t.Fatalf("Expected a map, got %T", v)
+		}
+		return msg
+	}
+
+	// Subprotocol negotiation, JSON request
+	dialer := websocket.Dialer{Subprotocols: []string{"msgpack"}}
+	conn, _, err := dialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != "msgpack" {
+		t.Fatalf("Expected the msgpack subprotocol, got %q", conn.Subprotocol())
+	}
+	if err := conn.WriteJSON(map[string]string{"action": "subscribe", "type": "market", "ticker": "SPY"}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if msg := readFrame(conn); msg["event"] != "subscribed" || msg["subject"] != "market.live.SPY" {
+		t.Errorf("Expected a subscription confirmation, got %v", msg)
+	}
+
+	// Query parameter negotiation, MessagePack request
+	conn2, _, err := websocket.DefaultDialer.Dial(wsURL+"?encoding=msgpack", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn2.Close()
+	request, _ := msgpack.Marshal(map[string]interface{}{"action": "subscribe", "type": "signals", "ticker": "QQQ"})
+	if err := conn2.WriteMessage(websocket.BinaryMessage, request); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if msg := readFrame(conn2); msg["event"] != "subscribed" || msg["subject"] != "signals.QQQ" {
+		t.Errorf("Expected a subscription confirmation, got %v", msg)
+	}
+}
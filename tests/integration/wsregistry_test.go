@@ -0,0 +1,63 @@
+// tests/integration/wsregistry_test.go
+package integration
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestWSRegistry checks WebSocket connections, goroutines and subscriptions
+// are counted in /metrics and released after an abnormal close
+func TestWSRegistry(t *testing.T) {
+	gateway := startGateway(t, natsURL(t), startTradingService(t).Addr,
+		"ALERT_DIGEST_SCHEDULE=off",
+		"WS_RECONCILE_INTERVAL=1s",
+	)
+
+	scrape := func() string {
+		resp, err := http.Get(gateway + "/metrics")
+		if err != nil {
+			t.Fatalf("Failed to scrape metrics: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(gateway, "http") + "/api/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to gateway websocket: %v", err)
+	}
+	for _, ticker := range []string{"SPY", "QQQ"} {
+		if err := conn.WriteJSON(map[string]string{"action": "subscribe", "type": "market", "ticker": ticker}); err != nil {
+			t.Fatalf("Failed to send subscription: %v", err)
+		}
+		if msg := readWS(t, conn); msg["event"] != "subscribed" {
+			t.Fatalf("Expected subscription confirmation, got %v", msg)
+		}
+	}
+
+	body := scrape()
+	for _, line := range []string{`gateway_ws_connections{state="open"} 1`, "gateway_ws_subscriptions 2", "gateway_ws_goroutines 2"} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected %q in metrics output:\n%s", line, body)
+		}
+	}
+
+	// Drop the TCP connection without a close handshake
+	conn.UnderlyingConn().Close()
+
+	waitFor(t, 15*time.Second, "WebSocket resources released", func() bool {
+		body = scrape()
+		return strings.Contains(body, `gateway_ws_connections{state="open"} 0`) &&
+			strings.Contains(body, `gateway_ws_connections{state="closing"} 0`) &&
+			strings.Contains(body, "gateway_ws_subscriptions 0") &&
+			strings.Contains(body, "gateway_ws_goroutines 0")
+	})
+}
@@ -0,0 +1,146 @@
+// tests/integration/wswatchlist_test.go
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/myapp/tradinglab/pkg/events"
+)
+
+// TestWatchlistStream checks one watchlist subscription delivers live data
+// and alerts for the subscriber's tickers, and that the stream follows the
+// watchlist as it is edited and ends when the subscriber is removed
+func TestWatchlistStream(t *testing.T) {
+	suffix := time.Now().UnixNano() % 100000
+	first, second := fmt.Sprintf("WA%d", suffix), fmt.Sprintf("WB%d", suffix)
+	email := fmt.Sprintf("watch%d@example.com", suffix)
+	nats := natsURL(t)
+	gateway := startGateway(t, nats, startTradingService(t).Addr, "ALERT_DIGEST_SCHEDULE=off")
+
+	post := func(path string, payload interface{}, status int, v interface{}) {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		resp, err := http.Post(gateway+path, "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to post %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Fatalf("Expected %d from %s, got %d", status, path, resp.StatusCode)
+		}
+		if v != nil {
+			json.NewDecoder(resp.Body).Decode(v)
+		}
+	}
+
+	var sub struct {
+		ID string `json:"id"`
+	}
+	post("/api/alerts/subscribers", map[string]interface{}{"email": email, "watchlist": []string{first}}, http.StatusOK, &sub)
+	post("/api/alerts/rules", map[string]interface{}{
+		"subscriber_id": sub.ID, "kind": "price", "ticker": first, "condition": "above", "price": 100, "channels": []string{"push"},
+	}, http.StatusCreated, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, "ws"+strings.TrimPrefix(gateway, "http")+"/api/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to gateway websocket: %v", err)
+	}
+	defer conn.Close()
+
+	// nextWatchlistEvent skips to the next watchlist event
+	nextWatchlistEvent := func() map[string]interface{} {
+		t.Helper()
+		for {
+			if msg := readWS(t, conn); msg["event"] == "watchlist" {
+				return msg
+			}
+		}
+	}
+	subjects := func(v interface{}) string {
+		var list []string
+		for _, s := range v.([]interface{}) {
+			list = append(list, s.(string))
+		}
+		sort.Strings(list)
+		return strings.Join(list, ",")
+	}
+
+	// Unknown subscribers have no watchlist
+	conn.WriteJSON(map[string]string{"action": "subscribe", "type": "watchlist", "subscriber": "nobody@example.com"})
+	if msg := readWS(t, conn); msg["error"] == nil {
+		t.Fatalf("Expected an error for an unknown subscriber, got %v", msg)
+	}
+
+	conn.WriteJSON(map[string]string{"action": "subscribe", "type": "watchlist", "subscriber": email})
+	msg := nextWatchlistEvent()
+	alertSubject, _ := events.AlertSubject(sub.ID, first)
+	want := strings.Join([]string{alertSubject, "market.live." + first, "signals." + first}, ",")
+	if msg["subscriber"] != sub.ID || subjects(msg["added"]) != want {
+		t.Fatalf("Expected %s subscribed for %s, got %v", want, sub.ID, msg)
+	}
+
+	// A tick above the rule's level arrives as live data and as an alert
+	publisher, err := events.NewEventClient(nats)
+	if err != nil {
+		t.Fatalf("Failed to create event client: %v", err)
+	}
+	defer publisher.Close()
+	if err := publisher.PublishMarketLiveData(ctx, first, map[string]interface{}{"ticker": first, "price": 101.0}); err != nil {
+		t.Fatalf("Failed to publish tick: %v", err)
+	}
+	var gotTick, gotAlert bool
+	for !gotTick || !gotAlert {
+		msg := readWS(t, conn)
+		switch {
+		case msg["subscriber_id"] == sub.ID && msg["kind"] == "price" && msg["ticker"] == first:
+			gotAlert = true
+		case msg["ticker"] == first && msg["price"] == 101.0:
+			gotTick = true
+		}
+	}
+
+	// Editing the watchlist moves the stream to the new tickers
+	post("/api/alerts/subscribers", map[string]interface{}{"email": email, "watchlist": []string{second}}, http.StatusOK, nil)
+	msg = nextWatchlistEvent()
+	secondAlerts, _ := events.AlertSubject(sub.ID, second)
+	if subjects(msg["added"]) != strings.Join([]string{secondAlerts, "market.live." + second, "signals." + second}, ",") ||
+		subjects(msg["removed"]) != want {
+		t.Fatalf("Expected the stream to move from %s to %s, got %v", first, second, msg)
+	}
+	if err := publisher.PublishMarketLiveData(ctx, second, map[string]interface{}{"ticker": second, "price": 5.0}); err != nil {
+		t.Fatalf("Failed to publish tick: %v", err)
+	}
+	for {
+		msg := readWS(t, conn)
+		if msg["ticker"] == first {
+			t.Fatalf("Expected nothing more for %s once it left the watchlist, got %v", first, msg)
+		}
+		if msg["ticker"] == second {
+			break
+		}
+	}
+
+	// Removing the subscriber ends the stream
+	req, _ := http.NewRequest(http.MethodDelete, gateway+"/api/alerts/subscribers/"+sub.ID, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to delete subscriber: %v", err)
+	}
+	resp.Body.Close()
+	msg = nextWatchlistEvent()
+	if msg["deleted"] != true || len(msg["removed"].([]interface{})) != 3 {
+		t.Fatalf("Expected the stream to end with the subscriber, got %v", msg)
+	}
+}